@@ -9,7 +9,9 @@ import (
 	"os/signal"
 	"syscall"
 	"time"
+	_ "time/tzdata" // embed IANA timezones for schedule parsing in minimal containers
 
+	"github.com/google/uuid"
 	"github.com/redis/go-redis/v9"
 	"github.com/shridarpatil/whatomate/internal/calling"
 	"github.com/shridarpatil/whatomate/internal/config"
@@ -19,13 +21,16 @@ import (
 	"github.com/shridarpatil/whatomate/internal/frontend"
 	"github.com/shridarpatil/whatomate/internal/handlers"
 	"github.com/shridarpatil/whatomate/internal/middleware"
+	"github.com/shridarpatil/whatomate/internal/models"
 	"github.com/shridarpatil/whatomate/internal/queue"
+	"github.com/shridarpatil/whatomate/internal/webhookutil"
 	"github.com/shridarpatil/whatomate/internal/websocket"
 	"github.com/shridarpatil/whatomate/internal/worker"
 	"github.com/shridarpatil/whatomate/pkg/whatsapp"
 	"github.com/valyala/fasthttp"
 	"github.com/zerodha/fastglue"
 	"github.com/zerodha/logf"
+	"gorm.io/gorm"
 )
 
 var (
@@ -44,6 +49,8 @@ func main() {
 		runServer(os.Args[2:])
 	case "worker":
 		runWorker(os.Args[2:])
+	case "migrate-storage":
+		runMigrateStorage(os.Args[2:])
 	case "version":
 		fmt.Printf("Whatomate %s (built %s)\n", Version, BuildTime)
 	case "help", "-h", "--help":
@@ -62,10 +69,11 @@ Usage:
   whatomate <command> [options]
 
 Commands:
-  server    Start the API server (with optional embedded workers)
-  worker    Start background workers only (no API server)
-  version   Show version information
-  help      Show this help message
+  server           Start the API server (with optional embedded workers)
+  worker           Start background workers only (no API server)
+  migrate-storage  Copy local media and audio files to the configured S3 bucket
+  version          Show version information
+  help             Show this help message
 
 Server Options:
   -config string    Path to config file (default "config.toml")
@@ -76,12 +84,18 @@ Worker Options:
   -config string    Path to config file (default "config.toml")
   -workers int      Number of workers to run (default 1)
 
+Migrate Storage Options:
+  -config string    Path to config file (default "config.toml")
+  -dry-run          List files that would be copied without uploading
+  -delete           Delete local files after they are copied
+
 Examples:
   whatomate server                     # API + 1 embedded worker
   whatomate server -workers 0          # API only (no workers)
   whatomate server -workers 4          # API + 4 embedded workers
   whatomate server -migrate            # Run migrations and start server
   whatomate worker -workers 4          # 4 workers only (no API)
+  whatomate migrate-storage -dry-run   # Preview moving local media to S3
 
 Deployment Scenarios:
   All-in-one:    whatomate server
@@ -198,6 +212,14 @@ func runServer(args []string) {
 		HTTPClient: httpClient,
 	}
 
+	// Initialize media storage backend
+	mediaStorage, err := storage.New(&cfg.Storage)
+	if err != nil {
+		lo.Fatal("Failed to initialize media storage", "error", err)
+	}
+	app.Storage = mediaStorage
+	lo.Info("Media storage initialized", "type", cfg.Storage.Type)
+
 	// Initialize S3 client for call recordings (optional)
 	var s3Client *storage.S3Client
 	if cfg.Calling.RecordingEnabled && cfg.Storage.S3Bucket != "" {
@@ -212,6 +234,15 @@ func runServer(args []string) {
 
 	// Initialize CallManager (per-org calling_enabled DB setting controls access)
 	app.CallManager = calling.NewManager(&cfg.Calling, s3Client, db, waClient, wsHub, lo)
+	app.CallManager.OnCallStarted = func(callLogID uuid.UUID) {
+		app.DispatchCallWebhook(models.WebhookEventCallStarted, callLogID)
+	}
+	app.CallManager.OnCallEnded = func(callLogID uuid.UUID) {
+		app.DispatchCallWebhook(models.WebhookEventCallEnded, callLogID)
+	}
+	if _, isLocal := mediaStorage.(*storage.Local); !isLocal {
+		app.CallManager.AudioStorage = mediaStorage
+	}
 	app.S3Client = s3Client
 	lo.Info("Call manager initialized")
 
@@ -267,6 +298,10 @@ func runServer(args []string) {
 	go slaProcessor.Start(slaCtx)
 	lo.Info("SLA processor started")
 
+	// Start notification rule scheduler (cron triggers have minute resolution)
+	notificationScheduler := handlers.NewNotificationScheduler(app, 30*time.Second)
+	go notificationScheduler.Start(slaCtx)
+
 	// Start embedded workers
 	var workers []*worker.Worker
 	var workerCancel context.CancelFunc
@@ -290,6 +325,14 @@ func runServer(args []string) {
 			}()
 		}
 		lo.Info("Embedded workers started", "count", *numWorkers)
+
+		if !cfg.Scheduler.Disabled {
+			scheduler := worker.NewCampaignScheduler(db, rdb, lo, time.Duration(cfg.Scheduler.IntervalSeconds)*time.Second)
+			go scheduler.Run(workerCtx)
+		}
+
+		// Retry failed webhook deliveries
+		go newWebhookProcessor(cfg, db, rdb, lo).Run(workerCtx)
 	} else {
 		lo.Info("Embedded workers disabled, run workers separately")
 	}
@@ -310,6 +353,7 @@ func runServer(args []string) {
 	lo.Info("Stopping SLA processor...")
 	slaCancel()
 	slaProcessor.Stop()
+	notificationScheduler.Stop()
 	lo.Info("SLA processor stopped")
 
 	// Stop workers first
@@ -407,6 +451,15 @@ func runWorker(args []string) {
 
 	lo.Info("Workers started", "count", *workerCount)
 
+	// Start campaign scheduler (only the Redis-elected leader dispatches)
+	if !cfg.Scheduler.Disabled {
+		scheduler := worker.NewCampaignScheduler(db, rdb, lo, time.Duration(cfg.Scheduler.IntervalSeconds)*time.Second)
+		go scheduler.Run(ctx)
+	}
+
+	// Retry failed webhook deliveries
+	go newWebhookProcessor(cfg, db, rdb, lo).Run(ctx)
+
 	// Wait for shutdown signal or error
 	select {
 	case sig := <-quit:
@@ -431,10 +484,144 @@ func runWorker(args []string) {
 	lo.Info("Workers stopped")
 }
 
+// ============================================================================
+// MIGRATE STORAGE COMMAND
+// ============================================================================
+
+func runMigrateStorage(args []string) {
+	migrateFlags := flag.NewFlagSet("migrate-storage", flag.ExitOnError)
+	configPath := migrateFlags.String("config", "config.toml", "Path to config file")
+	dryRun := migrateFlags.Bool("dry-run", false, "List files that would be copied without uploading")
+	deleteLocal := migrateFlags.Bool("delete", false, "Delete local files after they are copied")
+	_ = migrateFlags.Parse(args)
+
+	lo := logf.New(logf.Opts{
+		EnableColor:     true,
+		Level:           logf.InfoLevel,
+		TimestampFormat: "2006-01-02 15:04:05",
+		DefaultFields:   []any{"app", "whatomate-migrate-storage"},
+	})
+
+	cfg, err := config.Load(*configPath)
+	if err != nil {
+		lo.Fatal("Failed to load config", "error", err)
+	}
+	if cfg.Storage.Type != "s3" {
+		lo.Fatal("storage.type must be \"s3\" to migrate local files", "type", cfg.Storage.Type)
+	}
+
+	dest, err := storage.NewS3Client(&cfg.Storage)
+	if err != nil {
+		lo.Fatal("Failed to initialize S3 client", "error", err)
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer cancel()
+
+	audioDir := cfg.Calling.AudioDir
+	if audioDir == "" {
+		audioDir = "./audio"
+	}
+
+	// Media keys are kept as-is so paths stored in the database stay valid;
+	// IVR and org audio files go under the prefix the call manager reads from.
+	sources := []struct {
+		local  *storage.Local
+		keyFor func(string) string
+	}{
+		{storage.NewLocal(cfg.Storage.LocalPath), func(key string) string { return key }},
+		{storage.NewLocal(audioDir), calling.AudioStorageKey},
+	}
+
+	var copied, skipped, failed int
+	for _, src := range sources {
+		lo.Info("Migrating files", "dir", src.local.Root(), "bucket", cfg.Storage.S3Bucket, "dry_run", *dryRun)
+
+		err := src.local.Walk(func(key string) error {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			destKey := src.keyFor(key)
+
+			exists, err := dest.Exists(ctx, destKey)
+			if err != nil {
+				lo.Error("Failed to check object", "key", destKey, "error", err)
+				failed++
+				return nil
+			}
+			switch {
+			case exists:
+				skipped++
+			case *dryRun:
+				lo.Info("Would copy", "key", destKey)
+				copied++
+				return nil
+			default:
+				if err := copyObject(ctx, src.local, dest, key, destKey); err != nil {
+					lo.Error("Failed to copy file", "key", key, "error", err)
+					failed++
+					return nil
+				}
+				copied++
+			}
+
+			if *deleteLocal {
+				if err := src.local.Delete(ctx, key); err != nil {
+					lo.Error("Failed to delete local file", "key", key, "error", err)
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			lo.Fatal("Storage migration aborted", "error", err)
+		}
+	}
+
+	lo.Info("Storage migration complete", "copied", copied, "skipped", skipped, "failed", failed)
+	if failed > 0 {
+		os.Exit(1)
+	}
+}
+
+// copyObject copies a single object between storage backends
+func copyObject(ctx context.Context, src, dest storage.Backend, srcKey, destKey string) error {
+	body, info, err := src.Open(ctx, srcKey)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = body.Close() }()
+	return dest.Upload(ctx, destKey, body, info.ContentType)
+}
+
 // ============================================================================
 // ROUTES
 // ============================================================================
 
+// newWebhookProcessor creates the worker-side webhook retry processor
+func newWebhookProcessor(cfg *config.Config, db *gorm.DB, rdb *redis.Client, lo logf.Logger) *worker.WebhookProcessor {
+	policy, err := webhookutil.NewPolicy(cfg.Webhooks)
+	if err != nil {
+		lo.Warn("Invalid webhook retry schedule, using defaults", "error", err)
+	}
+
+	deliverer := &webhookutil.Deliverer{
+		DB:    db,
+		Redis: rdb,
+		HTTPClient: &http.Client{
+			Timeout: 30 * time.Second,
+			Transport: &http.Transport{
+				DialContext:         handlers.SSRFSafeDialer(),
+				MaxIdleConns:        100,
+				MaxIdleConnsPerHost: 10,
+				IdleConnTimeout:     90 * time.Second,
+			},
+		},
+		Log:    lo,
+		Policy: policy,
+	}
+	return worker.NewWebhookProcessor(deliverer, cfg.Webhooks.RetentionDays)
+}
+
 func setupRoutes(g *fastglue.Fastglue, app *handlers.App, lo logf.Logger, basePath string, rdb *redis.Client, cfg *config.Config) {
 	// Health check
 	g.GET("/health", app.HealthCheck)
@@ -512,6 +699,10 @@ func setupRoutes(g *fastglue.Fastglue, app *handlers.App, lo logf.Logger, basePa
 		if len(path) >= 28 && path[:28] == "/api/custom-actions/redirect" {
 			return r
 		}
+		// Skip auth for notification rule triggers (uses the rule's trigger key)
+		if len(path) >= 27 && path[:27] == "/api/notifications/trigger/" {
+			return r
+		}
 		// Apply auth for all other /api routes (supports both JWT and API key)
 		if len(path) > 4 && path[:4] == "/api" {
 			return middleware.AuthWithDB(app.Config.JWT.Secret, app.DB)(r)
@@ -546,6 +737,8 @@ func setupRoutes(g *fastglue.Fastglue, app *handlers.App, lo logf.Logger, basePa
 	g.GET("/api/users/{id}", app.GetUser)
 	g.PUT("/api/users/{id}", app.UpdateUser)
 	g.DELETE("/api/users/{id}", app.DeleteUser)
+	g.GET("/api/users/{id}/routing", app.GetAgentRouting)
+	g.PUT("/api/users/{id}/routing", app.UpdateAgentRouting)
 
 	// Roles & Permissions (admin only - enforced by middleware)
 	g.GET("/api/roles", app.ListRoles)
@@ -581,6 +774,10 @@ func setupRoutes(g *fastglue.Fastglue, app *handlers.App, lo logf.Logger, basePa
 	g.PUT("/api/contacts/{id}/assign", app.AssignContact)
 	g.PUT("/api/contacts/{id}/tags", app.UpdateContactTags)
 	g.GET("/api/contacts/{id}/session-data", app.GetContactSessionData)
+	g.POST("/api/contacts/{id}/ai/summary", app.SummarizeConversation)
+	g.POST("/api/contacts/{id}/ai/suggestions", app.SuggestReplies)
+	g.GET("/api/contacts/{id}/consent", app.GetContactConsent)
+	g.PUT("/api/contacts/{id}/consent", app.UpdateContactConsent)
 
 	// Generic Import/Export
 	g.POST("/api/export", app.ExportData)
@@ -603,6 +800,12 @@ func setupRoutes(g *fastglue.Fastglue, app *handlers.App, lo logf.Logger, basePa
 	g.POST("/api/messages/media", app.SendMediaMessage)
 	g.PUT("/api/messages/{id}/read", app.MarkMessageRead)
 
+	// Conversations
+	g.GET("/api/contacts/{id}/conversations", app.ListContactConversations)
+	g.POST("/api/contacts/{id}/conversations", app.OpenContactConversation)
+	g.GET("/api/conversations/{id}", app.GetConversation)
+	g.PUT("/api/conversations/{id}/status", app.UpdateConversationStatus)
+
 	// Conversation Notes
 	g.GET("/api/contacts/{id}/notes", app.ListConversationNotes)
 	g.POST("/api/contacts/{id}/notes", app.CreateConversationNote)
@@ -641,9 +844,13 @@ func setupRoutes(g *fastglue.Fastglue, app *handlers.App, lo logf.Logger, basePa
 	g.PUT("/api/campaigns/{id}", app.UpdateCampaign)
 	g.DELETE("/api/campaigns/{id}", app.DeleteCampaign)
 	g.POST("/api/campaigns/{id}/start", app.StartCampaign)
+	g.POST("/api/campaigns/{id}/schedule", app.ScheduleCampaign)
+	g.DELETE("/api/campaigns/{id}/schedule", app.UnscheduleCampaign)
 	g.POST("/api/campaigns/{id}/pause", app.PauseCampaign)
 	g.POST("/api/campaigns/{id}/cancel", app.CancelCampaign)
 	g.POST("/api/campaigns/{id}/retry-failed", app.RetryFailed)
+	g.GET("/api/campaigns/{id}/dead-letters", app.ListCampaignDeadLetters)
+	g.POST("/api/campaigns/{id}/dead-letters/replay", app.ReplayCampaignDeadLetters)
 	g.GET("/api/campaigns/{id}/progress", app.GetCampaign)
 	g.POST("/api/campaigns/{id}/recipients/import", app.ImportRecipients)
 	g.GET("/api/campaigns/{id}/recipients", app.GetCampaignRecipients)
@@ -668,6 +875,13 @@ func setupRoutes(g *fastglue.Fastglue, app *handlers.App, lo logf.Logger, basePa
 	g.GET("/api/chatbot/flows/{id}", app.GetChatbotFlow)
 	g.PUT("/api/chatbot/flows/{id}", app.UpdateChatbotFlow)
 	g.DELETE("/api/chatbot/flows/{id}", app.DeleteChatbotFlow)
+	g.POST("/api/chatbot/flows/{id}/publish", app.PublishChatbotFlow)
+	g.GET("/api/chatbot/flows/{id}/versions", app.ListChatbotFlowVersions)
+	g.GET("/api/chatbot/flows/{id}/versions/diff", app.DiffChatbotFlowVersions)
+	g.GET("/api/chatbot/flows/{id}/versions/{version}", app.GetChatbotFlowVersion)
+	g.POST("/api/chatbot/flows/{id}/versions/{version}/rollback", app.RollbackChatbotFlow)
+	g.POST("/api/chatbot/flows/{id}/test", app.RunChatbotFlowTests)
+	g.POST("/api/chatbot/simulate", app.SimulateChatbot)
 
 	// AI Contexts
 	g.GET("/api/chatbot/ai-contexts", app.ListAIContexts)
@@ -676,6 +890,15 @@ func setupRoutes(g *fastglue.Fastglue, app *handlers.App, lo logf.Logger, basePa
 	g.PUT("/api/chatbot/ai-contexts/{id}", app.UpdateAIContext)
 	g.DELETE("/api/chatbot/ai-contexts/{id}", app.DeleteAIContext)
 
+	// Knowledge Base
+	g.GET("/api/chatbot/knowledge", app.ListKnowledgeDocuments)
+	g.POST("/api/chatbot/knowledge", app.UploadKnowledgeDocument)
+	g.POST("/api/chatbot/knowledge/search", app.SearchKnowledge)
+	g.GET("/api/chatbot/knowledge/{id}", app.GetKnowledgeDocument)
+	g.PUT("/api/chatbot/knowledge/{id}", app.UpdateKnowledgeDocument)
+	g.DELETE("/api/chatbot/knowledge/{id}", app.DeleteKnowledgeDocument)
+	g.POST("/api/chatbot/knowledge/{id}/reindex", app.ReindexKnowledgeDocument)
+
 	// Agent Transfers
 	g.GET("/api/chatbot/transfers", app.ListAgentTransfers)
 	g.POST("/api/chatbot/transfers", app.CreateAgentTransfer)
@@ -693,6 +916,21 @@ func setupRoutes(g *fastglue.Fastglue, app *handlers.App, lo logf.Logger, basePa
 	g.POST("/api/teams/{id}/members", app.AddTeamMember)
 	g.DELETE("/api/teams/{id}/members/{member_user_id}", app.RemoveTeamMember)
 
+	// Business Hours Schedules
+	g.GET("/api/business-hours", app.ListBusinessHoursSchedules)
+	g.POST("/api/business-hours", app.CreateBusinessHoursSchedule)
+	g.GET("/api/business-hours/{id}", app.GetBusinessHoursSchedule)
+	g.PUT("/api/business-hours/{id}", app.UpdateBusinessHoursSchedule)
+	g.DELETE("/api/business-hours/{id}", app.DeleteBusinessHoursSchedule)
+	g.POST("/api/business-hours/{id}/holidays/import", app.ImportBusinessHoursHolidays)
+
+	// SLA Policies
+	g.GET("/api/sla-policies", app.ListSLAPolicies)
+	g.POST("/api/sla-policies", app.CreateSLAPolicy)
+	g.GET("/api/sla-policies/{id}", app.GetSLAPolicy)
+	g.PUT("/api/sla-policies/{id}", app.UpdateSLAPolicy)
+	g.DELETE("/api/sla-policies/{id}", app.DeleteSLAPolicy)
+
 	// Canned Responses
 	g.GET("/api/canned-responses", app.ListCannedResponses)
 	g.POST("/api/canned-responses", app.CreateCannedResponse)
@@ -712,6 +950,7 @@ func setupRoutes(g *fastglue.Fastglue, app *handlers.App, lo logf.Logger, basePa
 	g.GET("/api/analytics/agents", app.GetAgentAnalytics)
 	g.GET("/api/analytics/agents/{id}", app.GetAgentDetails)
 	g.GET("/api/analytics/agents/comparison", app.GetAgentComparison)
+	g.GET("/api/analytics/sla", app.GetSLAComplianceReport)
 
 	// Meta WhatsApp Analytics
 	g.GET("/api/analytics/meta", app.GetMetaAnalytics)
@@ -755,6 +994,9 @@ func setupRoutes(g *fastglue.Fastglue, app *handlers.App, lo logf.Logger, basePa
 	g.PUT("/api/webhooks/{id}", app.UpdateWebhook)
 	g.DELETE("/api/webhooks/{id}", app.DeleteWebhook)
 	g.POST("/api/webhooks/{id}/test", app.TestWebhook)
+	g.GET("/api/webhooks/{id}/deliveries", app.ListWebhookDeliveries)
+	g.GET("/api/webhooks/{id}/deliveries/{delivery_id}", app.GetWebhookDelivery)
+	g.POST("/api/webhooks/{id}/deliveries/{delivery_id}/redeliver", app.RedeliverWebhookDelivery)
 
 	// Custom Actions
 	g.GET("/api/custom-actions", app.ListCustomActions)
@@ -765,6 +1007,17 @@ func setupRoutes(g *fastglue.Fastglue, app *handlers.App, lo logf.Logger, basePa
 	g.POST("/api/custom-actions/{id}/execute", app.ExecuteCustomAction)
 	g.GET("/api/custom-actions/redirect/{token}", app.CustomActionRedirect)
 
+	// Notification Rules
+	g.GET("/api/notification-rules", app.ListNotificationRules)
+	g.POST("/api/notification-rules", app.CreateNotificationRule)
+	g.GET("/api/notification-rules/{id}", app.GetNotificationRule)
+	g.PUT("/api/notification-rules/{id}", app.UpdateNotificationRule)
+	g.DELETE("/api/notification-rules/{id}", app.DeleteNotificationRule)
+	g.POST("/api/notification-rules/{id}/rotate-key", app.RotateNotificationRuleKey)
+	g.POST("/api/notification-rules/{id}/execute", app.ExecuteNotificationRule)
+	g.GET("/api/notification-rules/{id}/executions", app.ListNotificationRuleExecutions)
+	g.POST("/api/notifications/trigger/{id}", app.TriggerNotificationRule)
+
 	// IVR Flows
 	g.GET("/api/ivr-flows", app.ListIVRFlows)
 	g.GET("/api/ivr-flows/{id}", app.GetIVRFlow)
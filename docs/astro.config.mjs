@@ -45,11 +45,15 @@ export default defineConfig({
             { label: 'Roles', slug: 'api-reference/roles' },
             { label: 'Accounts', slug: 'api-reference/accounts' },
             { label: 'Contacts', slug: 'api-reference/contacts' },
+            { label: 'Conversations', slug: 'api-reference/conversations' },
             { label: 'Messages', slug: 'api-reference/messages' },
             { label: 'Templates', slug: 'api-reference/templates' },
             { label: 'Flows', slug: 'api-reference/flows' },
             { label: 'Campaigns', slug: 'api-reference/campaigns' },
+            { label: 'Notification Rules', slug: 'api-reference/notification-rules' },
             { label: 'Chatbot', slug: 'api-reference/chatbot' },
+            { label: 'Business Hours', slug: 'api-reference/business-hours' },
+            { label: 'SLA Policies', slug: 'api-reference/sla-policies' },
             { label: 'Canned Responses', slug: 'api-reference/canned-responses' },
             { label: 'Custom Actions', slug: 'api-reference/custom-actions' },
             { label: 'Webhooks', slug: 'api-reference/webhooks' },
@@ -15,6 +15,7 @@ export const CANNED_RESPONSE_CATEGORIES = [
 export const ASSIGNMENT_STRATEGIES = [
   { value: 'round_robin', label: 'Round Robin', description: 'Distribute evenly to all team members' },
   { value: 'load_balanced', label: 'Load Balanced', description: 'Assign to agent with least open conversations' },
+  { value: 'skills_based', label: 'Skills Based', description: 'Assign to the most proficient agent with the required skills' },
   { value: 'manual', label: 'Manual Queue', description: 'Agents manually pick up conversations' },
 ] as const
 
@@ -1,4 +1,5 @@
 import axios, { type AxiosInstance, type AxiosError, type InternalAxiosRequestConfig } from 'axios'
+import type { AssignmentStrategy } from '@/lib/constants'
 
 // Get base path from server-injected config or fallback
 const basePath = ((window as any).__BASE_PATH__ ?? '').replace(/\/$/, '')
@@ -95,6 +96,21 @@ export const usersService = {
   updateAvailability: (isAvailable: boolean) =>
     api.put('/me/availability', { is_available: isAvailable }),
   listMyOrganizations: () => api.get('/me/organizations'),
+  getRouting: (id: string) => api.get<AgentRouting>(`/users/${id}/routing`),
+  updateRouting: (id: string, data: { skills?: AgentSkill[]; max_concurrent_conversations?: number }) =>
+    api.put<AgentRouting>(`/users/${id}/routing`, data),
+}
+
+export interface AgentSkill {
+  skill: string
+  proficiency: number // 1 (basic) to 5 (expert)
+}
+
+export interface AgentRouting {
+  user_id: string
+  skills: AgentSkill[]
+  max_concurrent_conversations: number // 0 = unlimited
+  active_conversations: number
 }
 
 export const apiKeysService = {
@@ -110,7 +126,7 @@ export const accountsService = {
 }
 
 export const contactsService = {
-  list: (params?: { search?: string; page?: number; limit?: number; tags?: string }) =>
+  list: (params?: { search?: string; page?: number; limit?: number; tags?: string; conversation_status?: string }) =>
     api.get('/contacts', { params }),
   get: (id: string) => api.get(`/contacts/${id}`),
   create: (data: any) => api.post('/contacts', data),
@@ -120,7 +136,43 @@ export const contactsService = {
     api.put(`/contacts/${id}/assign`, { user_id: userId }),
   updateTags: (id: string, tags: string[]) =>
     api.put(`/contacts/${id}/tags`, { tags }),
-  getSessionData: (id: string) => api.get(`/contacts/${id}/session-data`)
+  getSessionData: (id: string) => api.get(`/contacts/${id}/session-data`),
+  summarize: (id: string) => api.post(`/contacts/${id}/ai/summary`),
+  suggestReplies: (id: string) => api.post(`/contacts/${id}/ai/suggestions`)
+}
+
+// Conversations
+export type ConversationStatus = 'open' | 'pending' | 'snoozed' | 'resolved'
+export type ConversationResolutionReason = 'solved' | 'no_response' | 'spam' | 'duplicate' | 'other' | 'auto_closed'
+
+export interface Conversation {
+  id: string
+  contact_id: string
+  whatsapp_account: string
+  status: ConversationStatus
+  status_changed_at: string
+  snoozed_until?: string
+  reopen_count: number
+  resolution_reason?: ConversationResolutionReason
+  resolution_note?: string
+  resolved_at?: string
+  resolved_by_id?: string
+  resolved_by_name?: string
+  created_at: string
+  updated_at: string
+}
+
+export const conversationsService = {
+  list: (contactId: string, params?: { page?: number; limit?: number }) =>
+    api.get<{ conversations: Conversation[]; total: number }>(`/contacts/${contactId}/conversations`, { params }),
+  open: (contactId: string) => api.post<Conversation>(`/contacts/${contactId}/conversations`),
+  get: (id: string) => api.get<Conversation>(`/conversations/${id}`),
+  updateStatus: (id: string, data: {
+    status: ConversationStatus
+    snoozed_until?: string
+    resolution_reason?: Exclude<ConversationResolutionReason, 'auto_closed'>
+    resolution_note?: string
+  }) => api.put<Conversation>(`/conversations/${id}/status`, data)
 }
 
 // Generic Import/Export Service
@@ -278,6 +330,26 @@ export const chatbotService = {
   updateAIContext: (id: string, data: any) => api.put(`/chatbot/ai-contexts/${id}`, data),
   deleteAIContext: (id: string) => api.delete(`/chatbot/ai-contexts/${id}`),
 
+  // Knowledge Base
+  listKnowledgeDocuments: (params?: { search?: string; status?: string; whatsapp_account?: string; page?: number; limit?: number }) =>
+    api.get<{ documents: any[]; total?: number }>('/chatbot/knowledge', { params }),
+  uploadKnowledgeDocument: (file: File, data?: { name?: string; whatsapp_account?: string }) => {
+    const formData = new FormData()
+    formData.append('file', file)
+    if (data?.name) formData.append('name', data.name)
+    if (data?.whatsapp_account) formData.append('whatsapp_account', data.whatsapp_account)
+    return api.post('/chatbot/knowledge', formData, {
+      headers: { 'Content-Type': 'multipart/form-data' }
+    })
+  },
+  getKnowledgeDocument: (id: string) => api.get(`/chatbot/knowledge/${id}`),
+  updateKnowledgeDocument: (id: string, data: { name?: string; is_enabled?: boolean; whatsapp_account?: string }) =>
+    api.put(`/chatbot/knowledge/${id}`, data),
+  deleteKnowledgeDocument: (id: string) => api.delete(`/chatbot/knowledge/${id}`),
+  reindexKnowledgeDocument: (id: string) => api.post(`/chatbot/knowledge/${id}/reindex`),
+  searchKnowledge: (data: { query: string; whatsapp_account?: string; limit?: number }) =>
+    api.post('/chatbot/knowledge/search', data),
+
   // Agent Transfers
   listTransfers: (params?: {
     status?: string
@@ -291,8 +363,10 @@ export const chatbotService = {
     contact_id: string
     whatsapp_account: string
     agent_id?: string
+    team_id?: string
     notes?: string
     source?: string
+    required_skills?: string[]
   }) => api.post('/chatbot/transfers', data),
   pickNextTransfer: () => api.post('/chatbot/transfers/pick'),
   resumeTransfer: (id: string) => api.put(`/chatbot/transfers/${id}/resume`),
@@ -308,16 +382,17 @@ export interface CannedResponse {
   category: string
   is_active: boolean
   usage_count: number
+  translations?: Record<string, { content?: string }>
   created_at: string
   updated_at: string
 }
 
 export const cannedResponsesService = {
-  list: (params?: { category?: string; search?: string; active_only?: string; page?: number; limit?: number }) =>
+  list: (params?: { category?: string; search?: string; active_only?: string; language?: string; page?: number; limit?: number }) =>
     api.get<{ canned_responses: CannedResponse[]; total?: number }>('/canned-responses', { params }),
-  create: (data: { name: string; shortcut?: string; content: string; category?: string }) =>
+  create: (data: { name: string; shortcut?: string; content: string; category?: string; translations?: Record<string, { content?: string }> }) =>
     api.post('/canned-responses', data),
-  update: (id: string, data: { name?: string; shortcut?: string; content?: string; category?: string; is_active?: boolean }) =>
+  update: (id: string, data: { name?: string; shortcut?: string; content?: string; category?: string; is_active?: boolean; translations?: Record<string, { content?: string }> }) =>
     api.put(`/canned-responses/${id}`, data),
   delete: (id: string) => api.delete(`/canned-responses/${id}`),
   use: (id: string) => api.post(`/canned-responses/${id}/use`)
@@ -621,8 +696,11 @@ export interface Team {
   id: string
   name: string
   description: string
-  assignment_strategy: 'round_robin' | 'load_balanced' | 'manual'
+  assignment_strategy: AssignmentStrategy
   is_active: boolean
+  business_hours_schedule_id?: string | null
+  overflow_team_id?: string | null
+  overflow_after_minutes: number
   member_count: number
   created_at: string
   updated_at: string
@@ -654,13 +732,19 @@ export const teamsService = {
   create: (data: {
     name: string
     description?: string
-    assignment_strategy?: 'round_robin' | 'load_balanced' | 'manual'
+    assignment_strategy?: AssignmentStrategy
+    business_hours_schedule_id?: string
+    overflow_team_id?: string
+    overflow_after_minutes?: number
   }) => api.post<{ team: Team }>('/teams', data),
   update: (id: string, data: {
     name?: string
     description?: string
-    assignment_strategy?: 'round_robin' | 'load_balanced' | 'manual'
+    assignment_strategy?: AssignmentStrategy
     is_active?: boolean
+    business_hours_schedule_id?: string
+    overflow_team_id?: string
+    overflow_after_minutes?: number
   }) => api.put<{ team: Team }>(`/teams/${id}`, data),
   delete: (id: string) => api.delete(`/teams/${id}`),
   // Members
@@ -671,6 +755,127 @@ export const teamsService = {
     api.delete(`/teams/${teamId}/members/${userId}`)
 }
 
+// Business Hours
+export interface BusinessHoursInterval {
+  start_time: string
+  end_time: string
+}
+
+export interface BusinessHoursDay {
+  day: number // 0 = Sunday
+  enabled: boolean
+  start_time?: string
+  end_time?: string
+  intervals?: BusinessHoursInterval[]
+}
+
+export interface BusinessHoursHoliday {
+  name: string
+  date: string
+  end_date?: string
+  recurring?: boolean
+  intervals?: BusinessHoursInterval[]
+}
+
+export interface BusinessHoursSchedule {
+  id: string
+  name: string
+  description: string
+  timezone: string
+  hours: BusinessHoursDay[]
+  holidays: BusinessHoursHoliday[]
+  is_open: boolean
+  next_opening: string | null
+  created_at: string
+  updated_at: string
+}
+
+export const businessHoursService = {
+  list: (params?: { search?: string; page?: number; limit?: number }) =>
+    api.get<{ schedules: BusinessHoursSchedule[]; total: number }>('/business-hours', { params }),
+  get: (id: string, params?: { at?: string }) =>
+    api.get<BusinessHoursSchedule>(`/business-hours/${id}`, { params }),
+  create: (data: {
+    name: string
+    description?: string
+    timezone?: string
+    hours?: BusinessHoursDay[]
+    holidays?: BusinessHoursHoliday[]
+  }) => api.post<BusinessHoursSchedule>('/business-hours', data),
+  update: (id: string, data: {
+    name?: string
+    description?: string
+    timezone?: string
+    hours?: BusinessHoursDay[]
+    holidays?: BusinessHoursHoliday[]
+  }) => api.put<BusinessHoursSchedule>(`/business-hours/${id}`, data),
+  delete: (id: string) => api.delete(`/business-hours/${id}`),
+  importHolidays: (id: string, file: File, replace = false) => {
+    const formData = new FormData()
+    formData.append('file', file)
+    return api.post<{ imported: number; skipped: number; schedule: BusinessHoursSchedule }>(
+      `/business-hours/${id}/holidays/import`, formData,
+      { params: replace ? { replace: true } : undefined, headers: { 'Content-Type': 'multipart/form-data' } }
+    )
+  }
+}
+
+// SLA Policies
+export type SLAEscalationAction = 'reassign' | 'notify_manager' | 'webhook'
+
+export interface SLAEscalationTier {
+  after_minutes: number
+  notify_user_ids?: string[]
+  actions?: SLAEscalationAction[]
+}
+
+export interface SLAPolicy {
+  id: string
+  name: string
+  description: string
+  is_active: boolean
+  priority: number
+  team_id: string | null
+  min_transfer_priority: number // 0 = any
+  tags: string[]
+  response_minutes: number
+  resolution_minutes: number
+  business_hours_only: boolean
+  business_hours_schedule_id: string | null
+  pause_on_customer: boolean
+  escalation_tiers: SLAEscalationTier[]
+  created_at: string
+  updated_at: string
+}
+
+export interface SLAComplianceEntry {
+  policy_id: string
+  policy_name: string
+  deleted: boolean
+  transfers: number
+  active: number
+  escalated: number
+  response_met: number
+  response_breached: number
+  response_compliance: number | null
+  resolution_met: number
+  resolution_breached: number
+  resolution_compliance: number | null
+}
+
+export type SLAPolicyInput = Partial<Omit<SLAPolicy, 'id' | 'created_at' | 'updated_at'>>
+
+export const slaPoliciesService = {
+  list: (params?: { search?: string; page?: number; limit?: number }) =>
+    api.get<{ policies: SLAPolicy[]; total: number }>('/sla-policies', { params }),
+  get: (id: string) => api.get<SLAPolicy>(`/sla-policies/${id}`),
+  create: (data: SLAPolicyInput & { name: string }) => api.post<SLAPolicy>('/sla-policies', data),
+  update: (id: string, data: SLAPolicyInput) => api.put<SLAPolicy>(`/sla-policies/${id}`, data),
+  delete: (id: string) => api.delete(`/sla-policies/${id}`),
+  getComplianceReport: (params?: { from?: string; to?: string }) =>
+    api.get<{ policies: SLAComplianceEntry[]; from: string; to: string }>('/analytics/sla', { params })
+}
+
 export const webhooksService = {
   list: (params?: { search?: string; page?: number; limit?: number }) =>
     api.get<{ webhooks: Webhook[]; available_events: WebhookEvent[]; total?: number }>('/webhooks', { params }),
@@ -916,6 +1121,7 @@ export interface CallTransfer {
   whatsapp_account: string
   status: 'waiting' | 'connected' | 'completed' | 'abandoned' | 'no_answer'
   team_id?: string
+  required_skills: string[]
   agent_id?: string
   initiating_agent_id?: string
   transferred_at: string
@@ -336,6 +336,7 @@ class WebSocketService {
       whatsapp_account: payload.whatsapp_account,
       status: payload.status,
       source: payload.source || 'manual',
+      priority: payload.priority || 2,
       agent_id: payload.agent_id,
       team_id: payload.team_id,
       notes: payload.notes,
@@ -388,7 +389,8 @@ class WebSocketService {
     // Try to update existing transfer
     transfersStore.updateTransfer(payload.id, {
       agent_id: payload.agent_id,
-      team_id: payload.team_id
+      team_id: payload.team_id,
+      priority: payload.priority
     })
 
     // Always refresh to ensure UI is in sync (queue counts, etc.)
@@ -1,6 +1,6 @@
 import { defineStore } from 'pinia'
 import { ref, computed } from 'vue'
-import { contactsService, messagesService } from '@/services/api'
+import { contactsService, messagesService, type ConversationStatus } from '@/services/api'
 
 export interface Contact {
   id: string
@@ -17,6 +17,8 @@ export interface Contact {
   unread_count: number
   assigned_user_id?: string
   whatsapp_account?: string
+  conversation_id?: string
+  conversation_status?: ConversationStatus
   created_at: string
   updated_at: string
 }
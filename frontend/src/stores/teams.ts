@@ -1,17 +1,18 @@
 import { defineStore } from 'pinia'
 import { ref } from 'vue'
 import { teamsService, type Team, type TeamMember } from '@/services/api'
+import type { AssignmentStrategy } from '@/lib/constants'
 
 export interface CreateTeamData {
   name: string
   description?: string
-  assignment_strategy?: 'round_robin' | 'load_balanced' | 'manual'
+  assignment_strategy?: AssignmentStrategy
 }
 
 export interface UpdateTeamData {
   name?: string
   description?: string
-  assignment_strategy?: 'round_robin' | 'load_balanced' | 'manual'
+  assignment_strategy?: AssignmentStrategy
   is_active?: boolean
 }
 
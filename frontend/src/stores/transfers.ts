@@ -10,6 +10,7 @@ export interface AgentTransfer {
   whatsapp_account: string
   status: 'active' | 'resumed' | 'expired'
   source: 'manual' | 'flow' | 'keyword'
+  priority: number // 1 (low) to 4 (VIP)
   agent_id?: string
   agent_name?: string
   team_id?: string
@@ -30,6 +31,9 @@ export interface AgentTransfer {
   escalated_at?: string
   picked_up_at?: string
   expires_at?: string
+  sla_policy_id?: string
+  sla_paused: boolean
+  sla_resolution_breached: boolean
 }
 
 // Helper to determine SLA status
@@ -0,0 +1,149 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+const anthropicVersion = "2023-06-01"
+
+// anthropicProvider talks to the Anthropic messages API
+type anthropicProvider struct {
+	baseProvider
+}
+
+func (p *anthropicProvider) Name() string {
+	return ProviderAnthropic
+}
+
+func (p *anthropicProvider) Generate(ctx context.Context, req Request) (*Response, error) {
+	// Build messages array. Tool calls are content blocks of the assistant
+	// message and their results are blocks of the following user message.
+	messages := []map[string]interface{}{}
+	for _, msg := range req.Messages {
+		switch {
+		case msg.Role == RoleTool:
+			block := map[string]interface{}{
+				"type":        "tool_result",
+				"tool_use_id": msg.ToolCallID,
+				"content":     msg.Content,
+			}
+			// Results of parallel calls share one user message
+			if n := len(messages); n > 0 && messages[n-1]["role"] == RoleUser {
+				if blocks, ok := messages[n-1]["content"].([]map[string]interface{}); ok {
+					messages[n-1]["content"] = append(blocks, block)
+					continue
+				}
+			}
+			messages = append(messages, map[string]interface{}{
+				"role":    RoleUser,
+				"content": []map[string]interface{}{block},
+			})
+		case len(msg.ToolCalls) > 0:
+			blocks := []map[string]interface{}{}
+			if msg.Content != "" {
+				blocks = append(blocks, map[string]interface{}{"type": "text", "text": msg.Content})
+			}
+			for _, call := range msg.ToolCalls {
+				blocks = append(blocks, map[string]interface{}{
+					"type":  "tool_use",
+					"id":    call.ID,
+					"name":  call.Name,
+					"input": toolArguments(call.Arguments),
+				})
+			}
+			messages = append(messages, map[string]interface{}{
+				"role":    msg.Role,
+				"content": blocks,
+			})
+		default:
+			messages = append(messages, map[string]interface{}{
+				"role":    msg.Role,
+				"content": msg.Content,
+			})
+		}
+	}
+
+	// max_tokens is required by the messages API
+	maxTokens := req.MaxTokens
+	if maxTokens <= 0 {
+		maxTokens = 500
+	}
+	payload := map[string]interface{}{
+		"model":      p.cfg.Model,
+		"messages":   messages,
+		"max_tokens": maxTokens,
+	}
+	if req.SystemPrompt != "" {
+		payload["system"] = req.SystemPrompt
+	}
+	if req.Temperature > 0 {
+		payload["temperature"] = req.Temperature
+	}
+	if len(req.Tools) > 0 {
+		tools := make([]map[string]interface{}, len(req.Tools))
+		for i, tool := range req.Tools {
+			tools[i] = map[string]interface{}{
+				"name":         tool.Name,
+				"description":  tool.Description,
+				"input_schema": toolParameters(tool.Parameters),
+			}
+		}
+		payload["tools"] = tools
+	}
+
+	body, err := p.post(ctx, p.url("https://api.anthropic.com", "/v1/messages"), payload, "anthropic", func(r *http.Request) {
+		r.Header.Set("x-api-key", p.cfg.APIKey)
+		r.Header.Set("anthropic-version", anthropicVersion)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var result struct {
+		Model   string `json:"model"`
+		Content []struct {
+			Type  string          `json:"type"`
+			Text  string          `json:"text"`
+			ID    string          `json:"id"`
+			Name  string          `json:"name"`
+			Input json.RawMessage `json:"input"`
+		} `json:"content"`
+		Usage struct {
+			InputTokens  int `json:"input_tokens"`
+			OutputTokens int `json:"output_tokens"`
+		} `json:"usage"`
+	}
+	if err := json.Unmarshal(body, &result); err != nil {
+		return nil, fmt.Errorf("failed to parse response: %w", err)
+	}
+
+	var text []string
+	var toolCalls []ToolCall
+	for _, content := range result.Content {
+		switch content.Type {
+		case "text":
+			text = append(text, content.Text)
+		case "tool_use":
+			toolCalls = append(toolCalls, ToolCall{ID: content.ID, Name: content.Name, Arguments: toolArguments(content.Input)})
+		}
+	}
+	if len(text) == 0 && len(toolCalls) == 0 {
+		return nil, fmt.Errorf("no text response from Anthropic")
+	}
+
+	model := result.Model
+	if model == "" {
+		model = p.cfg.Model
+	}
+	return &Response{
+		Content:   strings.TrimSpace(strings.Join(text, "\n")),
+		ToolCalls: toolCalls,
+		Usage:     Usage{PromptTokens: result.Usage.InputTokens, CompletionTokens: result.Usage.OutputTokens},
+		Provider:  ProviderAnthropic,
+		Model:     model,
+	}, nil
+}
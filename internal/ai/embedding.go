@@ -0,0 +1,169 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"net/url"
+)
+
+// Embeddings are the vectors for a batch of texts, in input order
+type Embeddings struct {
+	Vectors [][]float32
+	Usage   Usage
+	Model   string
+}
+
+// Embedder turns texts into embedding vectors
+type Embedder interface {
+	Embed(ctx context.Context, texts []string) (*Embeddings, error)
+}
+
+// SupportsEmbeddings reports whether the provider has an embeddings API
+func SupportsEmbeddings(provider string) bool {
+	return provider != ProviderAnthropic
+}
+
+// DefaultEmbeddingModel returns the embedding model used when none is
+// configured, or "" when the provider has no sensible default
+func DefaultEmbeddingModel(provider string) string {
+	switch provider {
+	case ProviderOpenAI:
+		return "text-embedding-3-small"
+	case ProviderGoogle:
+		return "text-embedding-004"
+	default:
+		return ""
+	}
+}
+
+// NewEmbedder creates an embedder for the provider described by cfg.
+// cfg.Model is the embedding model and defaults to DefaultEmbeddingModel.
+func NewEmbedder(cfg Config, client *http.Client) (Embedder, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	if !SupportsEmbeddings(cfg.Provider) {
+		return nil, fmt.Errorf("%s does not provide an embeddings API", cfg.Provider)
+	}
+	if cfg.Model == "" {
+		cfg.Model = DefaultEmbeddingModel(cfg.Provider)
+	}
+	if cfg.Model == "" {
+		return nil, errors.New("embedding model is required")
+	}
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	base := baseProvider{cfg: cfg, client: client}
+	if cfg.Provider == ProviderGoogle {
+		return &googleProvider{baseProvider: base}, nil
+	}
+	p := &openAIProvider{baseProvider: base, name: cfg.Provider}
+	if cfg.Provider == ProviderOpenAI {
+		p.defaultURL = "https://api.openai.com/v1"
+	}
+	return p, nil
+}
+
+func (p *openAIProvider) Embed(ctx context.Context, texts []string) (*Embeddings, error) {
+	payload := map[string]interface{}{
+		"model": p.cfg.Model,
+		"input": texts,
+	}
+	body, err := p.post(ctx, p.url(p.defaultURL, "/embeddings"), payload, "embeddings", func(r *http.Request) {
+		if p.cfg.APIKey != "" {
+			r.Header.Set("Authorization", "Bearer "+p.cfg.APIKey)
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var result struct {
+		Data []struct {
+			Index     int       `json:"index"`
+			Embedding []float32 `json:"embedding"`
+		} `json:"data"`
+		Usage struct {
+			PromptTokens int `json:"prompt_tokens"`
+		} `json:"usage"`
+	}
+	if err := json.Unmarshal(body, &result); err != nil {
+		return nil, fmt.Errorf("failed to parse response: %w", err)
+	}
+	if len(result.Data) != len(texts) {
+		return nil, fmt.Errorf("expected %d embeddings, got %d", len(texts), len(result.Data))
+	}
+
+	vectors := make([][]float32, len(texts))
+	for _, d := range result.Data {
+		if d.Index < 0 || d.Index >= len(vectors) {
+			return nil, fmt.Errorf("embedding index %d out of range", d.Index)
+		}
+		vectors[d.Index] = d.Embedding
+	}
+	return &Embeddings{Vectors: vectors, Usage: Usage{PromptTokens: result.Usage.PromptTokens}, Model: p.cfg.Model}, nil
+}
+
+func (p *googleProvider) Embed(ctx context.Context, texts []string) (*Embeddings, error) {
+	model := "models/" + p.cfg.Model
+	requests := make([]map[string]interface{}, len(texts))
+	for i, text := range texts {
+		requests[i] = map[string]interface{}{
+			"model": model,
+			"content": map[string]interface{}{
+				"parts": []map[string]string{{"text": text}},
+			},
+		}
+	}
+
+	endpoint := p.url("https://generativelanguage.googleapis.com/v1beta",
+		fmt.Sprintf("/models/%s:batchEmbedContents", url.PathEscape(p.cfg.Model)))
+	body, err := p.post(ctx, endpoint, map[string]interface{}{"requests": requests}, "google AI embeddings", func(r *http.Request) {
+		r.Header.Set("x-goog-api-key", p.cfg.APIKey)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var result struct {
+		Embeddings []struct {
+			Values []float32 `json:"values"`
+		} `json:"embeddings"`
+	}
+	if err := json.Unmarshal(body, &result); err != nil {
+		return nil, fmt.Errorf("failed to parse response: %w", err)
+	}
+	if len(result.Embeddings) != len(texts) {
+		return nil, fmt.Errorf("expected %d embeddings, got %d", len(texts), len(result.Embeddings))
+	}
+
+	vectors := make([][]float32, len(texts))
+	for i, e := range result.Embeddings {
+		vectors[i] = e.Values
+	}
+	return &Embeddings{Vectors: vectors, Model: p.cfg.Model}, nil
+}
+
+// CosineSimilarity returns the cosine similarity of two vectors, or 0 when
+// their lengths differ or either is all zeros
+func CosineSimilarity(a, b []float32) float64 {
+	if len(a) != len(b) || len(a) == 0 {
+		return 0
+	}
+	var dot, normA, normB float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+		normA += float64(a[i]) * float64(a[i])
+		normB += float64(b[i]) * float64(b[i])
+	}
+	if normA == 0 || normB == 0 {
+		return 0
+	}
+	return dot / (math.Sqrt(normA) * math.Sqrt(normB))
+}
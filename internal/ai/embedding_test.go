@@ -0,0 +1,78 @@
+package ai
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewEmbedder(t *testing.T) {
+	_, err := NewEmbedder(Config{Provider: ProviderAnthropic, APIKey: "key"}, nil)
+	assert.ErrorContains(t, err, "does not provide an embeddings API")
+
+	_, err = NewEmbedder(Config{Provider: ProviderOpenAICompatible, BaseURL: "http://localhost:11434/v1"}, nil)
+	assert.ErrorContains(t, err, "embedding model is required")
+
+	_, err = NewEmbedder(Config{Provider: ProviderOpenAI, APIKey: "key"}, nil)
+	assert.NoError(t, err, "OpenAI has a default embedding model")
+}
+
+func TestOpenAIEmbed(t *testing.T) {
+	var payload map[string]interface{}
+	var headers http.Header
+	srv := newTestServer(t, http.StatusOK,
+		`{"data":[{"index":1,"embedding":[0,1]},{"index":0,"embedding":[1,0]}],"usage":{"prompt_tokens":6}}`,
+		&payload, &headers)
+
+	e, err := NewEmbedder(Config{Provider: ProviderOpenAI, APIKey: "key", BaseURL: srv.URL}, srv.Client())
+	require.NoError(t, err)
+
+	result, err := e.Embed(context.Background(), []string{"first", "second"})
+	require.NoError(t, err)
+	assert.Equal(t, [][]float32{{1, 0}, {0, 1}}, result.Vectors, "vectors are returned in input order")
+	assert.Equal(t, 6, result.Usage.PromptTokens)
+	assert.Equal(t, "text-embedding-3-small", result.Model)
+
+	assert.Equal(t, "/embeddings", headers.Get("X-Path"))
+	assert.Equal(t, "Bearer key", headers.Get("Authorization"))
+	assert.Equal(t, "text-embedding-3-small", payload["model"])
+	assert.Equal(t, []interface{}{"first", "second"}, payload["input"])
+}
+
+func TestGoogleEmbed(t *testing.T) {
+	var payload map[string]interface{}
+	var headers http.Header
+	srv := newTestServer(t, http.StatusOK,
+		`{"embeddings":[{"values":[0.5,0.5]},{"values":[1,0]}]}`,
+		&payload, &headers)
+
+	e, err := NewEmbedder(Config{Provider: ProviderGoogle, APIKey: "key", BaseURL: srv.URL}, srv.Client())
+	require.NoError(t, err)
+
+	result, err := e.Embed(context.Background(), []string{"first", "second"})
+	require.NoError(t, err)
+	assert.Equal(t, [][]float32{{0.5, 0.5}, {1, 0}}, result.Vectors)
+
+	assert.Equal(t, "/models/text-embedding-004:batchEmbedContents", headers.Get("X-Path"))
+	assert.Len(t, payload["requests"], 2)
+}
+
+func TestEmbedCountMismatch(t *testing.T) {
+	srv := newTestServer(t, http.StatusOK, `{"data":[{"index":0,"embedding":[1]}]}`, nil, nil)
+	e, err := NewEmbedder(Config{Provider: ProviderOpenAI, APIKey: "key", BaseURL: srv.URL}, srv.Client())
+	require.NoError(t, err)
+
+	_, err = e.Embed(context.Background(), []string{"a", "b"})
+	assert.ErrorContains(t, err, "expected 2 embeddings, got 1")
+}
+
+func TestCosineSimilarity(t *testing.T) {
+	assert.InDelta(t, 1.0, CosineSimilarity([]float32{1, 2}, []float32{2, 4}), 1e-9)
+	assert.InDelta(t, 0.0, CosineSimilarity([]float32{1, 0}, []float32{0, 1}), 1e-9)
+	assert.InDelta(t, -1.0, CosineSimilarity([]float32{1, 0}, []float32{-1, 0}), 1e-9)
+	assert.Zero(t, CosineSimilarity([]float32{1}, []float32{1, 0}))
+	assert.Zero(t, CosineSimilarity([]float32{0, 0}, []float32{1, 0}))
+}
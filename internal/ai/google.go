@@ -0,0 +1,152 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// googleProvider talks to the Google Gemini generateContent API
+type googleProvider struct {
+	baseProvider
+}
+
+func (p *googleProvider) Name() string {
+	return ProviderGoogle
+}
+
+func (p *googleProvider) Generate(ctx context.Context, req Request) (*Response, error) {
+	// Build contents array; Gemini calls the assistant "model" and returns
+	// tool results as functionResponse parts of a user turn
+	contents := []map[string]interface{}{}
+	for _, msg := range req.Messages {
+		role := msg.Role
+		parts := []map[string]interface{}{}
+		switch {
+		case role == RoleTool:
+			role = RoleUser
+			var response interface{}
+			if err := json.Unmarshal([]byte(msg.Content), &response); err != nil {
+				response = msg.Content
+			}
+			// The response must be an object
+			if _, ok := response.(map[string]interface{}); !ok {
+				response = map[string]interface{}{"result": response}
+			}
+			parts = append(parts, map[string]interface{}{
+				"functionResponse": map[string]interface{}{"name": msg.ToolName, "response": response},
+			})
+		case role == RoleAssistant:
+			role = "model"
+			if msg.Content != "" {
+				parts = append(parts, map[string]interface{}{"text": msg.Content})
+			}
+			for _, call := range msg.ToolCalls {
+				parts = append(parts, map[string]interface{}{
+					"functionCall": map[string]interface{}{"name": call.Name, "args": toolArguments(call.Arguments)},
+				})
+			}
+		default:
+			parts = append(parts, map[string]interface{}{"text": msg.Content})
+		}
+		contents = append(contents, map[string]interface{}{
+			"role":  role,
+			"parts": parts,
+		})
+	}
+
+	generationConfig := map[string]interface{}{}
+	if req.MaxTokens > 0 {
+		generationConfig["maxOutputTokens"] = req.MaxTokens
+	}
+	if req.Temperature > 0 {
+		generationConfig["temperature"] = req.Temperature
+	}
+	payload := map[string]interface{}{
+		"contents":         contents,
+		"generationConfig": generationConfig,
+	}
+	if len(req.Tools) > 0 {
+		declarations := make([]map[string]interface{}, len(req.Tools))
+		for i, tool := range req.Tools {
+			declarations[i] = map[string]interface{}{
+				"name":        tool.Name,
+				"description": tool.Description,
+				"parameters":  toolParameters(tool.Parameters),
+			}
+		}
+		payload["tools"] = []map[string]interface{}{{"functionDeclarations": declarations}}
+	}
+	if req.SystemPrompt != "" {
+		payload["systemInstruction"] = map[string]interface{}{
+			"parts": []map[string]string{
+				{"text": req.SystemPrompt},
+			},
+		}
+	}
+
+	endpoint := p.url("https://generativelanguage.googleapis.com/v1beta",
+		fmt.Sprintf("/models/%s:generateContent", url.PathEscape(p.cfg.Model)))
+	body, err := p.post(ctx, endpoint, payload, "google AI", func(r *http.Request) {
+		r.Header.Set("x-goog-api-key", p.cfg.APIKey)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var result struct {
+		Candidates []struct {
+			Content struct {
+				Parts []struct {
+					Text         string `json:"text"`
+					FunctionCall *struct {
+						Name string          `json:"name"`
+						Args json.RawMessage `json:"args"`
+					} `json:"functionCall"`
+				} `json:"parts"`
+			} `json:"content"`
+		} `json:"candidates"`
+		UsageMetadata struct {
+			PromptTokenCount     int `json:"promptTokenCount"`
+			CandidatesTokenCount int `json:"candidatesTokenCount"`
+		} `json:"usageMetadata"`
+		ModelVersion string `json:"modelVersion"`
+	}
+	if err := json.Unmarshal(body, &result); err != nil {
+		return nil, fmt.Errorf("failed to parse response: %w", err)
+	}
+
+	if len(result.Candidates) == 0 || len(result.Candidates[0].Content.Parts) == 0 {
+		return nil, fmt.Errorf("no response from Google AI")
+	}
+
+	// Gemini doesn't identify calls, so they are numbered
+	var text []string
+	var toolCalls []ToolCall
+	for _, part := range result.Candidates[0].Content.Parts {
+		if part.FunctionCall != nil {
+			toolCalls = append(toolCalls, ToolCall{
+				ID:        fmt.Sprintf("call_%d", len(toolCalls)),
+				Name:      part.FunctionCall.Name,
+				Arguments: toolArguments(part.FunctionCall.Args),
+			})
+		} else if part.Text != "" {
+			text = append(text, part.Text)
+		}
+	}
+
+	model := result.ModelVersion
+	if model == "" {
+		model = p.cfg.Model
+	}
+	return &Response{
+		Content:   strings.TrimSpace(strings.Join(text, "")),
+		ToolCalls: toolCalls,
+		Usage:     Usage{PromptTokens: result.UsageMetadata.PromptTokenCount, CompletionTokens: result.UsageMetadata.CandidatesTokenCount},
+		Provider:  ProviderGoogle,
+		Model:     model,
+	}, nil
+}
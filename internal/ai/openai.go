@@ -0,0 +1,145 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// openAIProvider talks to the OpenAI chat completions API, or to any server
+// that implements it when a base URL is configured
+type openAIProvider struct {
+	baseProvider
+	name       string
+	defaultURL string
+}
+
+func (p *openAIProvider) Name() string {
+	return p.name
+}
+
+func (p *openAIProvider) Generate(ctx context.Context, req Request) (*Response, error) {
+	// Build messages array
+	messages := []map[string]interface{}{}
+	if req.SystemPrompt != "" {
+		messages = append(messages, map[string]interface{}{
+			"role":    "system",
+			"content": req.SystemPrompt,
+		})
+	}
+	for _, msg := range req.Messages {
+		m := map[string]interface{}{
+			"role":    msg.Role,
+			"content": msg.Content,
+		}
+		if msg.Role == RoleTool {
+			m["tool_call_id"] = msg.ToolCallID
+		}
+		if len(msg.ToolCalls) > 0 {
+			calls := make([]map[string]interface{}, len(msg.ToolCalls))
+			for i, call := range msg.ToolCalls {
+				calls[i] = map[string]interface{}{
+					"id":   call.ID,
+					"type": "function",
+					"function": map[string]string{
+						"name":      call.Name,
+						"arguments": string(toolArguments(call.Arguments)),
+					},
+				}
+			}
+			m["tool_calls"] = calls
+		}
+		messages = append(messages, m)
+	}
+
+	payload := map[string]interface{}{
+		"model":    p.cfg.Model,
+		"messages": messages,
+	}
+	if len(req.Tools) > 0 {
+		tools := make([]map[string]interface{}, len(req.Tools))
+		for i, tool := range req.Tools {
+			tools[i] = map[string]interface{}{
+				"type": "function",
+				"function": map[string]interface{}{
+					"name":        tool.Name,
+					"description": tool.Description,
+					"parameters":  toolParameters(tool.Parameters),
+				},
+			}
+		}
+		payload["tools"] = tools
+	}
+	if req.MaxTokens > 0 {
+		payload["max_tokens"] = req.MaxTokens
+	}
+	if req.Temperature > 0 {
+		payload["temperature"] = req.Temperature
+	}
+
+	label := "OpenAI"
+	if p.name == ProviderOpenAICompatible {
+		label = "OpenAI-compatible"
+	}
+	body, err := p.post(ctx, p.url(p.defaultURL, "/chat/completions"), payload, label, func(r *http.Request) {
+		if p.cfg.APIKey != "" {
+			r.Header.Set("Authorization", "Bearer "+p.cfg.APIKey)
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var result struct {
+		Model   string `json:"model"`
+		Choices []struct {
+			Message struct {
+				Content   string `json:"content"`
+				ToolCalls []struct {
+					ID       string `json:"id"`
+					Function struct {
+						Name      string `json:"name"`
+						Arguments string `json:"arguments"`
+					} `json:"function"`
+				} `json:"tool_calls"`
+			} `json:"message"`
+		} `json:"choices"`
+		Usage struct {
+			PromptTokens     int `json:"prompt_tokens"`
+			CompletionTokens int `json:"completion_tokens"`
+		} `json:"usage"`
+	}
+	if err := json.Unmarshal(body, &result); err != nil {
+		return nil, fmt.Errorf("failed to parse response: %w", err)
+	}
+	if len(result.Choices) == 0 {
+		return nil, fmt.Errorf("no response from %s", label)
+	}
+
+	var toolCalls []ToolCall
+	for i, call := range result.Choices[0].Message.ToolCalls {
+		id := call.ID
+		if id == "" {
+			id = fmt.Sprintf("call_%d", i)
+		}
+		toolCalls = append(toolCalls, ToolCall{
+			ID:        id,
+			Name:      call.Function.Name,
+			Arguments: toolArguments(json.RawMessage(call.Function.Arguments)),
+		})
+	}
+
+	model := result.Model
+	if model == "" {
+		model = p.cfg.Model
+	}
+	return &Response{
+		Content:   strings.TrimSpace(result.Choices[0].Message.Content),
+		ToolCalls: toolCalls,
+		Usage:     Usage{PromptTokens: result.Usage.PromptTokens, CompletionTokens: result.Usage.CompletionTokens},
+		Provider:  p.name,
+		Model:     model,
+	}, nil
+}
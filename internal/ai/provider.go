@@ -0,0 +1,260 @@
+// Package ai generates chatbot replies and text embeddings through pluggable LLM providers.
+// OpenAI, Anthropic and Google Gemini are supported natively, and any server
+// that speaks the OpenAI chat completions API (Ollama, vLLM, LiteLLM, ...) can
+// be used through the OpenAI-compatible provider. Providers can be chained so
+// that a failing provider falls back to the next one.
+package ai
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// Provider names
+const (
+	ProviderOpenAI           = "openai"
+	ProviderAnthropic        = "anthropic"
+	ProviderGoogle           = "google"
+	ProviderOpenAICompatible = "openai_compatible"
+)
+
+// Message roles
+const (
+	RoleUser      = "user"
+	RoleAssistant = "assistant"
+	RoleTool      = "tool" // The result of a tool call
+)
+
+// Message is a single turn of the conversation sent to the model
+type Message struct {
+	Role    string
+	Content string
+
+	// ToolCalls are the tools an assistant message called
+	ToolCalls []ToolCall
+	// ToolCallID and ToolName identify the call a RoleTool message answers
+	ToolCallID string
+	ToolName   string
+}
+
+// Tool is a function the model may call instead of replying
+type Tool struct {
+	Name        string
+	Description string
+	Parameters  map[string]interface{} // JSON schema of the arguments object
+}
+
+// ToolCall is a model's request to call a tool
+type ToolCall struct {
+	ID        string
+	Name      string
+	Arguments json.RawMessage // JSON object
+}
+
+// Request is a provider-neutral completion request
+type Request struct {
+	SystemPrompt string
+	Messages     []Message
+	Tools        []Tool
+	MaxTokens    int
+	Temperature  float64 // 0 uses the provider's default
+}
+
+// Usage reports the tokens consumed by a request
+type Usage struct {
+	PromptTokens     int `json:"prompt_tokens"`
+	CompletionTokens int `json:"completion_tokens"`
+}
+
+// Total returns the total number of tokens used
+func (u Usage) Total() int {
+	return u.PromptTokens + u.CompletionTokens
+}
+
+// Response is the model's reply. When ToolCalls is set the model expects
+// their results, as RoleTool messages, before it continues.
+type Response struct {
+	Content   string
+	ToolCalls []ToolCall
+	Usage     Usage
+	Provider  string // Provider that produced the reply (differs from the primary after a fallback)
+	Model     string
+}
+
+// Provider generates replies from a model
+type Provider interface {
+	// Name returns the provider name, e.g. "openai"
+	Name() string
+	// Generate returns the model's reply to the request
+	Generate(ctx context.Context, req Request) (*Response, error)
+}
+
+// Config configures a provider
+type Config struct {
+	Provider string
+	Model    string
+	APIKey   string
+	BaseURL  string            // Overrides the provider's default API URL; required for openai_compatible
+	Headers  map[string]string // Extra HTTP headers sent with every request
+}
+
+// Validate checks that the provider is supported and its base URL is usable
+func (c Config) Validate() error {
+	switch c.Provider {
+	case ProviderOpenAI, ProviderAnthropic, ProviderGoogle:
+	case ProviderOpenAICompatible:
+		if c.BaseURL == "" {
+			return errors.New("base URL is required for OpenAI-compatible providers")
+		}
+	default:
+		return fmt.Errorf("unsupported AI provider: %s", c.Provider)
+	}
+	if c.BaseURL != "" {
+		u, err := url.Parse(c.BaseURL)
+		if err != nil || u.Host == "" || (u.Scheme != "http" && u.Scheme != "https") {
+			return fmt.Errorf("invalid base URL %q: must be an http or https URL", c.BaseURL)
+		}
+	}
+	return nil
+}
+
+// New creates the provider described by cfg. HTTP requests are made with
+// client, or http.DefaultClient when it is nil.
+func New(cfg Config, client *http.Client) (Provider, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	if cfg.Model == "" {
+		return nil, errors.New("model is required")
+	}
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	base := baseProvider{cfg: cfg, client: client}
+	switch cfg.Provider {
+	case ProviderOpenAI:
+		return &openAIProvider{baseProvider: base, name: ProviderOpenAI, defaultURL: "https://api.openai.com/v1"}, nil
+	case ProviderOpenAICompatible:
+		return &openAIProvider{baseProvider: base, name: ProviderOpenAICompatible}, nil
+	case ProviderAnthropic:
+		return &anthropicProvider{baseProvider: base}, nil
+	default:
+		return &googleProvider{baseProvider: base}, nil
+	}
+}
+
+// RequiresAPIKey reports whether the provider can't be used without an API key.
+// Self-hosted OpenAI-compatible servers often don't need one.
+func RequiresAPIKey(provider string) bool {
+	return provider != ProviderOpenAICompatible
+}
+
+// fallbackProvider tries its providers in order until one succeeds
+type fallbackProvider struct {
+	providers []Provider
+}
+
+// WithFallback returns a provider that tries each provider in order and
+// returns the first successful reply. Errors from all providers are joined.
+func WithFallback(providers ...Provider) Provider {
+	if len(providers) == 1 {
+		return providers[0]
+	}
+	return &fallbackProvider{providers: providers}
+}
+
+func (f *fallbackProvider) Name() string {
+	if len(f.providers) == 0 {
+		return ""
+	}
+	return f.providers[0].Name()
+}
+
+func (f *fallbackProvider) Generate(ctx context.Context, req Request) (*Response, error) {
+	if len(f.providers) == 0 {
+		return nil, errors.New("no AI providers configured")
+	}
+	var errs []error
+	for _, p := range f.providers {
+		resp, err := p.Generate(ctx, req)
+		if err == nil {
+			return resp, nil
+		}
+		errs = append(errs, fmt.Errorf("%s: %w", p.Name(), err))
+		if ctx.Err() != nil {
+			break
+		}
+	}
+	return nil, errors.Join(errs...)
+}
+
+// baseProvider holds what every HTTP provider needs
+type baseProvider struct {
+	cfg    Config
+	client *http.Client
+}
+
+// url joins the configured base URL (or the default) with path
+func (b *baseProvider) url(defaultBase, path string) string {
+	base := b.cfg.BaseURL
+	if base == "" {
+		base = defaultBase
+	}
+	return strings.TrimRight(base, "/") + path
+}
+
+// setHeaders applies the JSON content type and the configured extra headers
+func (b *baseProvider) setHeaders(req *http.Request) {
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range b.cfg.Headers {
+		req.Header.Set(k, v)
+	}
+}
+
+// post sends payload as JSON and returns the response body. Non-200 responses
+// are turned into errors using the provider's error message when present.
+func (b *baseProvider) post(ctx context.Context, endpoint string, payload interface{}, label string, setAuth func(*http.Request)) ([]byte, error) {
+	jsonPayload, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal payload: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewBuffer(jsonPayload))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	if setAuth != nil {
+		setAuth(req)
+	}
+	b.setHeaders(req)
+
+	resp, err := b.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("request failed: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	body, _ := io.ReadAll(resp.Body)
+
+	if resp.StatusCode != http.StatusOK {
+		var errResp struct {
+			Error struct {
+				Message string `json:"message"`
+			} `json:"error"`
+		}
+		_ = json.Unmarshal(body, &errResp)
+		if errResp.Error.Message == "" {
+			errResp.Error.Message = fmt.Sprintf("status %d", resp.StatusCode)
+		}
+		return nil, fmt.Errorf("%s API error: %s", label, errResp.Error.Message)
+	}
+	return body, nil
+}
@@ -0,0 +1,161 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestServer serves body for every request and records the last request
+func newTestServer(t *testing.T, status int, body string, captured *map[string]interface{}, headers *http.Header) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if captured != nil {
+			_ = json.NewDecoder(r.Body).Decode(captured)
+		}
+		if headers != nil {
+			*headers = r.Header.Clone()
+			headers.Set("X-Path", r.URL.Path)
+		}
+		w.WriteHeader(status)
+		_, _ = w.Write([]byte(body))
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func testRequest() Request {
+	return Request{
+		SystemPrompt: "Be brief.",
+		Messages: []Message{
+			{Role: RoleUser, Content: "Hi"},
+			{Role: RoleAssistant, Content: "Hello!"},
+			{Role: RoleUser, Content: "Opening hours?"},
+		},
+		MaxTokens:   100,
+		Temperature: 0.5,
+	}
+}
+
+func TestConfigValidate(t *testing.T) {
+	assert.NoError(t, Config{Provider: ProviderOpenAI}.Validate())
+	assert.NoError(t, Config{Provider: ProviderOpenAICompatible, BaseURL: "http://localhost:11434/v1"}.Validate())
+	assert.ErrorContains(t, Config{Provider: ProviderOpenAICompatible}.Validate(), "base URL is required")
+	assert.ErrorContains(t, Config{Provider: ProviderAnthropic, BaseURL: "ftp://example.com"}.Validate(), "invalid base URL")
+	assert.ErrorContains(t, Config{Provider: "acme"}.Validate(), "unsupported AI provider")
+
+	_, err := New(Config{Provider: ProviderOpenAI}, nil)
+	assert.ErrorContains(t, err, "model is required")
+}
+
+func TestOpenAICompatibleProvider(t *testing.T) {
+	var payload map[string]interface{}
+	var headers http.Header
+	srv := newTestServer(t, http.StatusOK,
+		`{"model":"llama3:8b","choices":[{"message":{"content":" We open at 9. "}}],"usage":{"prompt_tokens":42,"completion_tokens":7}}`,
+		&payload, &headers)
+
+	p, err := New(Config{
+		Provider: ProviderOpenAICompatible,
+		Model:    "llama3",
+		BaseURL:  srv.URL + "/v1/",
+		Headers:  map[string]string{"X-Tenant": "acme"},
+	}, srv.Client())
+	require.NoError(t, err)
+
+	resp, err := p.Generate(context.Background(), testRequest())
+	require.NoError(t, err)
+	assert.Equal(t, "We open at 9.", resp.Content)
+	assert.Equal(t, Usage{PromptTokens: 42, CompletionTokens: 7}, resp.Usage)
+	assert.Equal(t, 49, resp.Usage.Total())
+	assert.Equal(t, ProviderOpenAICompatible, resp.Provider)
+	assert.Equal(t, "llama3:8b", resp.Model)
+
+	assert.Equal(t, "/v1/chat/completions", headers.Get("X-Path"))
+	assert.Equal(t, "acme", headers.Get("X-Tenant"))
+	assert.Empty(t, headers.Get("Authorization"), "no API key is sent when none is configured")
+	assert.Equal(t, "llama3", payload["model"])
+	messages := payload["messages"].([]interface{})
+	require.Len(t, messages, 4)
+	assert.Equal(t, map[string]interface{}{"role": "system", "content": "Be brief."}, messages[0])
+}
+
+func TestAnthropicProvider(t *testing.T) {
+	var payload map[string]interface{}
+	var headers http.Header
+	srv := newTestServer(t, http.StatusOK,
+		`{"content":[{"type":"text","text":"9 to 5"}],"usage":{"input_tokens":30,"output_tokens":4}}`,
+		&payload, &headers)
+
+	p, err := New(Config{Provider: ProviderAnthropic, Model: "claude", APIKey: "key", BaseURL: srv.URL}, srv.Client())
+	require.NoError(t, err)
+
+	resp, err := p.Generate(context.Background(), testRequest())
+	require.NoError(t, err)
+	assert.Equal(t, "9 to 5", resp.Content)
+	assert.Equal(t, Usage{PromptTokens: 30, CompletionTokens: 4}, resp.Usage)
+	assert.Equal(t, "claude", resp.Model)
+
+	assert.Equal(t, "/v1/messages", headers.Get("X-Path"))
+	assert.Equal(t, "key", headers.Get("X-Api-Key"))
+	assert.Equal(t, "Be brief.", payload["system"])
+	assert.Len(t, payload["messages"], 3)
+}
+
+func TestGoogleProvider(t *testing.T) {
+	var payload map[string]interface{}
+	var headers http.Header
+	srv := newTestServer(t, http.StatusOK,
+		`{"candidates":[{"content":{"parts":[{"text":"9 to 5"}]}}],"usageMetadata":{"promptTokenCount":25,"candidatesTokenCount":3}}`,
+		&payload, &headers)
+
+	p, err := New(Config{Provider: ProviderGoogle, Model: "gemini-pro", APIKey: "key", BaseURL: srv.URL}, srv.Client())
+	require.NoError(t, err)
+
+	resp, err := p.Generate(context.Background(), testRequest())
+	require.NoError(t, err)
+	assert.Equal(t, "9 to 5", resp.Content)
+	assert.Equal(t, Usage{PromptTokens: 25, CompletionTokens: 3}, resp.Usage)
+
+	assert.Equal(t, "/models/gemini-pro:generateContent", headers.Get("X-Path"))
+	assert.Equal(t, "key", headers.Get("X-Goog-Api-Key"))
+	contents := payload["contents"].([]interface{})
+	require.Len(t, contents, 3)
+	assert.Equal(t, "model", contents[1].(map[string]interface{})["role"])
+}
+
+func TestProviderError(t *testing.T) {
+	srv := newTestServer(t, http.StatusUnauthorized, `{"error":{"message":"invalid api key"}}`, nil, nil)
+	p, err := New(Config{Provider: ProviderOpenAI, Model: "gpt-4o", APIKey: "bad", BaseURL: srv.URL}, srv.Client())
+	require.NoError(t, err)
+
+	_, err = p.Generate(context.Background(), testRequest())
+	assert.EqualError(t, err, "OpenAI API error: invalid api key")
+}
+
+func TestWithFallback(t *testing.T) {
+	failing := newTestServer(t, http.StatusInternalServerError, `{}`, nil, nil)
+	working := newTestServer(t, http.StatusOK, `{"choices":[{"message":{"content":"ok"}}]}`, nil, nil)
+
+	primary, err := New(Config{Provider: ProviderOpenAI, Model: "gpt-4o", APIKey: "key", BaseURL: failing.URL}, nil)
+	require.NoError(t, err)
+	backup, err := New(Config{Provider: ProviderOpenAICompatible, Model: "llama3", BaseURL: working.URL}, nil)
+	require.NoError(t, err)
+
+	p := WithFallback(primary, backup)
+	assert.Equal(t, ProviderOpenAI, p.Name())
+	resp, err := p.Generate(context.Background(), testRequest())
+	require.NoError(t, err)
+	assert.Equal(t, "ok", resp.Content)
+	assert.Equal(t, ProviderOpenAICompatible, resp.Provider)
+
+	_, err = WithFallback(primary, primary).Generate(context.Background(), testRequest())
+	assert.ErrorContains(t, err, "openai: OpenAI API error: status 500")
+
+	assert.Same(t, primary, WithFallback(primary), "a single provider is not wrapped")
+}
@@ -0,0 +1,24 @@
+package ai
+
+import (
+	"encoding/json"
+)
+
+// toolArguments returns args if it is a JSON object, or an empty object.
+// Models occasionally send empty or malformed arguments.
+func toolArguments(args json.RawMessage) json.RawMessage {
+	var obj map[string]interface{}
+	if len(args) == 0 || json.Unmarshal(args, &obj) != nil || obj == nil {
+		return json.RawMessage("{}")
+	}
+	return args
+}
+
+// toolParameters returns the JSON schema for a tool's arguments, defaulting
+// to an object without properties
+func toolParameters(schema map[string]interface{}) map[string]interface{} {
+	if schema == nil {
+		return map[string]interface{}{"type": "object", "properties": map[string]interface{}{}}
+	}
+	return schema
+}
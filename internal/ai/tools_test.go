@@ -0,0 +1,112 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// toolRequest is a conversation where the model already called a tool once
+func toolRequest() Request {
+	return Request{
+		Messages: []Message{
+			{Role: RoleUser, Content: "Where is my order?"},
+			{Role: RoleAssistant, ToolCalls: []ToolCall{{ID: "call_1", Name: "add_tag", Arguments: json.RawMessage(`{"tag":"order"}`)}}},
+			{Role: RoleTool, ToolCallID: "call_1", ToolName: "add_tag", Content: `{"ok":true}`},
+		},
+		Tools: []Tool{{
+			Name:        "transfer_to_team",
+			Description: "Hand the conversation to a team",
+			Parameters: map[string]interface{}{
+				"type":       "object",
+				"properties": map[string]interface{}{"team": map[string]interface{}{"type": "string"}},
+				"required":   []string{"team"},
+			},
+		}},
+	}
+}
+
+func TestOpenAIToolCalls(t *testing.T) {
+	var payload map[string]interface{}
+	srv := newTestServer(t, http.StatusOK,
+		`{"choices":[{"message":{"content":null,"tool_calls":[{"id":"call_2","type":"function","function":{"name":"transfer_to_team","arguments":"{\"team\":\"Sales\"}"}}]}}]}`,
+		&payload, nil)
+	p, err := New(Config{Provider: ProviderOpenAI, Model: "gpt-4o", APIKey: "key", BaseURL: srv.URL}, srv.Client())
+	require.NoError(t, err)
+
+	resp, err := p.Generate(context.Background(), toolRequest())
+	require.NoError(t, err)
+	assert.Empty(t, resp.Content)
+	require.Len(t, resp.ToolCalls, 1)
+	assert.Equal(t, "call_2", resp.ToolCalls[0].ID)
+	assert.Equal(t, "transfer_to_team", resp.ToolCalls[0].Name)
+	assert.JSONEq(t, `{"team":"Sales"}`, string(resp.ToolCalls[0].Arguments))
+
+	tools := payload["tools"].([]interface{})
+	require.Len(t, tools, 1)
+	assert.Equal(t, "transfer_to_team", tools[0].(map[string]interface{})["function"].(map[string]interface{})["name"])
+	messages := payload["messages"].([]interface{})
+	require.Len(t, messages, 3)
+	call := messages[1].(map[string]interface{})["tool_calls"].([]interface{})[0].(map[string]interface{})
+	assert.Equal(t, `{"tag":"order"}`, call["function"].(map[string]interface{})["arguments"])
+	assert.Equal(t, "call_1", messages[2].(map[string]interface{})["tool_call_id"])
+}
+
+func TestAnthropicToolCalls(t *testing.T) {
+	var payload map[string]interface{}
+	srv := newTestServer(t, http.StatusOK,
+		`{"content":[{"type":"text","text":"Connecting you."},{"type":"tool_use","id":"toolu_2","name":"transfer_to_team","input":{"team":"Sales"}}]}`,
+		&payload, nil)
+	p, err := New(Config{Provider: ProviderAnthropic, Model: "claude", APIKey: "key", BaseURL: srv.URL}, srv.Client())
+	require.NoError(t, err)
+
+	resp, err := p.Generate(context.Background(), toolRequest())
+	require.NoError(t, err)
+	assert.Equal(t, "Connecting you.", resp.Content)
+	require.Len(t, resp.ToolCalls, 1)
+	assert.Equal(t, "toolu_2", resp.ToolCalls[0].ID)
+	assert.JSONEq(t, `{"team":"Sales"}`, string(resp.ToolCalls[0].Arguments))
+
+	assert.Equal(t, "transfer_to_team", payload["tools"].([]interface{})[0].(map[string]interface{})["name"])
+	messages := payload["messages"].([]interface{})
+	require.Len(t, messages, 3)
+	use := messages[1].(map[string]interface{})["content"].([]interface{})[0].(map[string]interface{})
+	assert.Equal(t, "tool_use", use["type"])
+	result := messages[2].(map[string]interface{})
+	assert.Equal(t, "user", result["role"])
+	assert.Equal(t, "call_1", result["content"].([]interface{})[0].(map[string]interface{})["tool_use_id"])
+}
+
+func TestGoogleToolCalls(t *testing.T) {
+	var payload map[string]interface{}
+	srv := newTestServer(t, http.StatusOK,
+		`{"candidates":[{"content":{"parts":[{"functionCall":{"name":"transfer_to_team","args":{"team":"Sales"}}}]}}]}`,
+		&payload, nil)
+	p, err := New(Config{Provider: ProviderGoogle, Model: "gemini-pro", APIKey: "key", BaseURL: srv.URL}, srv.Client())
+	require.NoError(t, err)
+
+	resp, err := p.Generate(context.Background(), toolRequest())
+	require.NoError(t, err)
+	require.Len(t, resp.ToolCalls, 1)
+	assert.Equal(t, "call_0", resp.ToolCalls[0].ID)
+	assert.JSONEq(t, `{"team":"Sales"}`, string(resp.ToolCalls[0].Arguments))
+
+	declarations := payload["tools"].([]interface{})[0].(map[string]interface{})["functionDeclarations"].([]interface{})
+	assert.Len(t, declarations, 1)
+	contents := payload["contents"].([]interface{})
+	require.Len(t, contents, 3)
+	fnResponse := contents[2].(map[string]interface{})["parts"].([]interface{})[0].(map[string]interface{})["functionResponse"].(map[string]interface{})
+	assert.Equal(t, "add_tag", fnResponse["name"])
+	assert.Equal(t, map[string]interface{}{"ok": true}, fnResponse["response"])
+}
+
+func TestToolArguments(t *testing.T) {
+	assert.Equal(t, `{}`, string(toolArguments(nil)))
+	assert.Equal(t, `{}`, string(toolArguments(json.RawMessage(`not json`))))
+	assert.Equal(t, `{}`, string(toolArguments(json.RawMessage(`null`))))
+	assert.Equal(t, `{"a":1}`, string(toolArguments(json.RawMessage(`{"a":1}`))))
+}
@@ -0,0 +1,60 @@
+package calling
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// AudioStorageKey returns the media storage key of an IVR or org audio file.
+func AudioStorageKey(filename string) string {
+	return "ivr-audio/" + filename
+}
+
+// audioPath returns the local path of an audio file in the audio directory.
+// When the file is missing locally and AudioStorage is set, it is fetched
+// from media storage first, so audio uploaded on another host still plays.
+func (m *Manager) audioPath(filename string) string {
+	fullPath := filepath.Join(m.config.AudioDir, filename)
+	if m.AudioStorage == nil || filepath.Base(filename) != filename {
+		return fullPath
+	}
+	if _, err := os.Stat(fullPath); err == nil {
+		return fullPath
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	body, _, err := m.AudioStorage.Open(ctx, AudioStorageKey(filename))
+	if err != nil {
+		m.log.Warn("Audio file not found in storage", "file", filename, "error", err)
+		return fullPath
+	}
+	defer body.Close() //nolint:errcheck
+
+	if err := os.MkdirAll(m.config.AudioDir, 0755); err != nil {
+		m.log.Error("Failed to create audio directory", "error", err)
+		return fullPath
+	}
+	tmp, err := os.CreateTemp(m.config.AudioDir, ".fetch-*")
+	if err != nil {
+		m.log.Error("Failed to cache audio file", "file", filename, "error", err)
+		return fullPath
+	}
+	defer func() { _ = os.Remove(tmp.Name()) }()
+
+	_, err = io.Copy(tmp, body)
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), fullPath)
+	}
+	if err != nil {
+		m.log.Error("Failed to cache audio file", "file", filename, "error", err)
+	}
+	return fullPath
+}
@@ -3,13 +3,14 @@ package calling
 import (
 	"encoding/json"
 	"fmt"
-	"path/filepath"
+	"maps"
 	"strings"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/pion/webrtc/v4"
 	"github.com/shridarpatil/whatomate/internal/models"
+	"github.com/shridarpatil/whatomate/internal/schedule"
 	"github.com/shridarpatil/whatomate/pkg/whatsapp"
 )
 
@@ -236,7 +237,7 @@ func (m *Manager) executeGreeting(session *CallSession, node *IVRNode, player *A
 	interruptible, _ := node.Config["interruptible"].(bool)
 
 	if audioFile != "" && m.config.AudioDir != "" {
-		fullPath := filepath.Join(m.config.AudioDir, audioFile)
+		fullPath := m.audioPath(audioFile)
 		m.drainDTMF(session)
 
 		if interruptible {
@@ -274,7 +275,7 @@ func (m *Manager) executeMenu(session *CallSession, node *IVRNode, ctx *IVRConte
 
 	var fullPath string
 	if audioFile != "" && m.config.AudioDir != "" {
-		fullPath = filepath.Join(m.config.AudioDir, audioFile)
+		fullPath = m.audioPath(audioFile)
 	}
 
 	for attempt := 0; attempt < maxRetries; attempt++ {
@@ -347,7 +348,7 @@ func (m *Manager) executeGather(session *CallSession, node *IVRNode, ctx *IVRCon
 
 	// Play prompt (non-interruptible for gather — we need all digits)
 	if audioFile != "" && m.config.AudioDir != "" {
-		fullPath := filepath.Join(m.config.AudioDir, audioFile)
+		fullPath := m.audioPath(audioFile)
 		if _, err := player.PlayFile(fullPath); err != nil {
 			m.log.Error("Failed to play gather audio", "error", err, "call_id", session.ID)
 		}
@@ -441,12 +442,13 @@ func (m *Manager) executeHTTPCallback(session *CallSession, node *IVRNode, ctx *
 // returns "").
 func (m *Manager) executeTransfer(session *CallSession, node *IVRNode, ctx *IVRContext, graph *IVRFlowGraph) string {
 	teamID, _ := node.Config["team_id"].(string)
+	requiredSkills := models.SkillsFromConfig(node.Config)
 	m.saveIVRPath(session, ctx.Path)
 
 	// Check if this transfer node has any outgoing edges — if not, terminal.
 	edges := graph.edgeMap[node.ID]
 	if len(edges) == 0 {
-		m.initiateTransfer(session, session.AccountName, teamID, ctx.Path)
+		m.initiateTransfer(session, session.AccountName, teamID, requiredSkills, ctx.Path)
 		return "" // terminal
 	}
 
@@ -457,7 +459,7 @@ func (m *Manager) executeTransfer(session *CallSession, node *IVRNode, ctx *IVRC
 	session.TransferDone = transferDone
 	session.mu.Unlock()
 
-	m.initiateTransfer(session, session.AccountName, teamID, ctx.Path)
+	m.initiateTransfer(session, session.AccountName, teamID, requiredSkills, ctx.Path)
 
 	// Block until the transfer completes (or the channel is closed during cleanup).
 	outcome, ok := <-transferDone
@@ -530,54 +532,71 @@ func (m *Manager) executeGotoFlow(session *CallSession, node *IVRNode, ctx *IVRC
 	m.runIVRFlow(session, waAccount)
 }
 
-// executeTiming branches based on business hours schedule.
+// executeTiming branches based on business hours. The node either references
+// a named business hours schedule (config.schedule_id) or has its own weekly
+// schedule (config.schedule, with an optional config.timezone).
 func (m *Manager) executeTiming(session *CallSession, node *IVRNode) string {
-	now := time.Now()
-	dayName := strings.ToLower(now.Weekday().String())
+	sched, err := m.timingSchedule(session, node)
+	if err != nil {
+		m.log.Error("Invalid IVR timing schedule", "error", err, "call_id", session.ID)
+		return "out_of_hours"
+	}
+	if sched.IsOpen(time.Now()) {
+		return "in_hours"
+	}
+	return "out_of_hours"
+}
 
+// timingSchedule returns the schedule of a timing node
+func (m *Manager) timingSchedule(session *CallSession, node *IVRNode) (*schedule.Schedule, error) {
+	if idStr, _ := node.Config["schedule_id"].(string); idStr != "" {
+		var stored models.BusinessHoursSchedule
+		if err := m.db.Where("id = ? AND organization_id = ?", idStr, session.OrganizationID).
+			First(&stored).Error; err != nil {
+			return nil, fmt.Errorf("business hours schedule %s: %w", idStr, err)
+		}
+		return stored.Schedule()
+	}
+
+	timezone, _ := node.Config["timezone"].(string)
+	sched := &schedule.Schedule{Timezone: timezone}
 	scheduleRaw, _ := node.Config["schedule"].([]interface{})
 	for _, item := range scheduleRaw {
 		entry, ok := item.(map[string]interface{})
 		if !ok {
 			continue
 		}
-		day, _ := entry["day"].(string)
-		if strings.ToLower(day) != dayName {
-			continue
+		var day schedule.Day
+		if err := schedule.Decode(ivrScheduleEntry(entry), &day); err != nil {
+			return nil, err
 		}
-		enabled, _ := entry["enabled"].(bool)
-		if !enabled {
-			return "out_of_hours"
-		}
-		startStr, _ := entry["start_time"].(string)
-		endStr, _ := entry["end_time"].(string)
-
-		startTime, err1 := time.Parse("15:04", startStr)
-		endTime, err2 := time.Parse("15:04", endStr)
-		if err1 != nil || err2 != nil {
-			m.log.Error("Invalid schedule time format", "call_id", session.ID, "start", startStr, "end", endStr)
-			return "out_of_hours"
+		if day.Day < 0 {
+			continue
 		}
+		sched.Days = append(sched.Days, day)
+	}
+	return sched, sched.Validate()
+}
 
-		nowMinutes := now.Hour()*60 + now.Minute()
-		startMinutes := startTime.Hour()*60 + startTime.Minute()
-		endMinutes := endTime.Hour()*60 + endTime.Minute()
-
-		if nowMinutes >= startMinutes && nowMinutes < endMinutes {
-			return "in_hours"
+// ivrScheduleEntry converts the day name of an IVR schedule entry ("monday")
+// to the weekday number used by business hours schedules, or -1 when unknown
+func ivrScheduleEntry(entry map[string]interface{}) map[string]interface{} {
+	converted := maps.Clone(entry)
+	name, _ := entry["day"].(string)
+	converted["day"] = -1
+	for d := time.Sunday; d <= time.Saturday; d++ {
+		if strings.EqualFold(name, d.String()) {
+			converted["day"] = int(d)
 		}
-		return "out_of_hours"
 	}
-
-	// Day not found in schedule — treat as out of hours
-	return "out_of_hours"
+	return converted
 }
 
 // executeHangup plays optional goodbye audio and terminates the call. Terminal.
 func (m *Manager) executeHangup(session *CallSession, node *IVRNode, ctx *IVRContext, waAccount *whatsapp.Account, player *AudioPlayer) {
 	audioFile, _ := node.Config["audio_file"].(string)
 	if audioFile != "" && m.config.AudioDir != "" {
-		fullPath := filepath.Join(m.config.AudioDir, audioFile)
+		fullPath := m.audioPath(audioFile)
 		if _, err := player.PlayFile(fullPath); err != nil {
 			m.log.Error("Failed to play hangup audio", "error", err, "call_id", session.ID)
 		}
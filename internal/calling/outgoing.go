@@ -239,6 +239,10 @@ func (m *Manager) InitiateOutgoingCall(
 		"started_at":     now.Format(time.RFC3339),
 	})
 
+	if m.OnCallStarted != nil {
+		m.OnCallStarted(callLog.ID)
+	}
+
 	return callLog.ID, agentSDP.SDP, nil
 }
 
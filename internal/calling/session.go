@@ -5,7 +5,6 @@ import (
 	"fmt"
 	"os"
 	"os/exec"
-	"path/filepath"
 	"sync"
 	"time"
 
@@ -172,6 +171,16 @@ type Manager struct {
 	wsHub    *websocket.Hub
 	config   *config.CallingConfig
 	s3       *storage.S3Client // nil when recording is disabled
+
+	// OnCallStarted and OnCallEnded, when set, are called with the call log ID
+	// when an outgoing call is placed and when a call session is torn down.
+	// OnCallEnded runs after the recording (if any) has been uploaded.
+	OnCallStarted func(callLogID uuid.UUID)
+	OnCallEnded   func(callLogID uuid.UUID)
+
+	// AudioStorage, when set, is where audio files missing from AudioDir are
+	// fetched from (see AudioStorageKey)
+	AudioStorage storage.Backend
 }
 
 // NewManager creates a new call session manager
@@ -317,10 +326,10 @@ type orgCallingSettings struct {
 func (m *Manager) getOrgCallingSettings(orgID uuid.UUID) orgCallingSettings {
 	s := orgCallingSettings{
 		TransferTimeoutSecs: m.config.TransferTimeoutSecs,
-		HoldMusicFile:       filepath.Join(m.config.AudioDir, m.config.HoldMusicFile),
+		HoldMusicFile:       m.audioPath(m.config.HoldMusicFile),
 	}
 	if m.config.RingbackFile != "" {
-		s.RingbackFile = filepath.Join(m.config.AudioDir, m.config.RingbackFile)
+		s.RingbackFile = m.audioPath(m.config.RingbackFile)
 	}
 
 	var org models.Organization
@@ -332,10 +341,10 @@ func (m *Manager) getOrgCallingSettings(orgID uuid.UUID) orgCallingSettings {
 		s.TransferTimeoutSecs = int(v)
 	}
 	if v, ok := org.Settings["hold_music_file"].(string); ok && v != "" {
-		s.HoldMusicFile = filepath.Join(m.config.AudioDir, v)
+		s.HoldMusicFile = m.audioPath(v)
 	}
 	if v, ok := org.Settings["ringback_file"].(string); ok && v != "" {
-		s.RingbackFile = filepath.Join(m.config.AudioDir, v)
+		s.RingbackFile = m.audioPath(v)
 	}
 
 	return s
@@ -476,10 +485,15 @@ func (m *Manager) cleanupSession(callID string) {
 		close(dtmfBuffer)
 	}
 
-	// Finalize recording (async — don't block cleanup)
-	if callerRec != nil || agentRec != nil {
-		go m.finalizeRecording(orgID, callLogID, callerRec, agentRec)
-	}
+	// Finalize recording and report the call as ended (async — don't block cleanup)
+	go func() {
+		if callerRec != nil || agentRec != nil {
+			m.finalizeRecording(orgID, callLogID, callerRec, agentRec)
+		}
+		if m.OnCallEnded != nil {
+			m.OnCallEnded(callLogID)
+		}
+	}()
 
 	m.log.Info("Call session cleaned up", "call_id", callID)
 }
@@ -12,7 +12,8 @@ import (
 )
 
 // initiateTransfer starts the transfer flow: puts caller on hold, notifies agents via WebSocket.
-func (m *Manager) initiateTransfer(session *CallSession, waAccount string, teamTarget string, ivrPath []map[string]string) {
+// requiredSkills tells agents which skills the caller needs.
+func (m *Manager) initiateTransfer(session *CallSession, waAccount string, teamTarget string, requiredSkills models.StringArray, ivrPath []map[string]string) {
 	// Load org-level calling overrides once
 	orgSettings := m.getOrgCallingSettings(session.OrganizationID)
 
@@ -49,6 +50,7 @@ func (m *Manager) initiateTransfer(session *CallSession, waAccount string, teamT
 		WhatsAppAccount: waAccount,
 		Status:          models.CallTransferStatusWaiting,
 		TeamID:          teamID,
+		RequiredSkills:  requiredSkills,
 		TransferredAt:   time.Now(),
 	}
 
@@ -98,6 +100,7 @@ func (m *Manager) initiateTransfer(session *CallSession, waAccount string, teamT
 		"contact_id":       transfer.ContactID.String(),
 		"whatsapp_account": transfer.WhatsAppAccount,
 		"team_id":          teamIDStr,
+		"required_skills":  requiredSkills,
 		"transferred_at":   transfer.TransferredAt.Format(time.RFC3339),
 	})
 
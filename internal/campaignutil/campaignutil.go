@@ -0,0 +1,217 @@
+package campaignutil
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shridarpatil/whatomate/internal/contactutil"
+	"github.com/shridarpatil/whatomate/internal/models"
+	"github.com/shridarpatil/whatomate/internal/queue"
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrNotStartable is returned when the campaign is not in a startable state
+	// (or another process started it first).
+	ErrNotStartable = errors.New("campaign cannot be started in current state")
+	// ErrNoPendingRecipients is returned when there is nothing to send.
+	ErrNoPendingRecipients = errors.New("campaign has no pending recipients")
+	// ErrTemplateNotFound is returned when the campaign template was deleted.
+	ErrTemplateNotFound = errors.New("campaign template no longer exists")
+	// ErrEnqueueFailed is returned when recipient jobs could not be queued.
+	ErrEnqueueFailed = errors.New("failed to queue recipients")
+)
+
+// scheduleLayouts are the accepted formats for naive (zone-less) schedule times.
+var scheduleLayouts = []string{
+	"2006-01-02T15:04:05",
+	"2006-01-02T15:04",
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04",
+}
+
+// CanStart reports whether a campaign in the given status may be started.
+func CanStart(status models.CampaignStatus) bool {
+	return status == models.CampaignStatusDraft ||
+		status == models.CampaignStatusScheduled ||
+		status == models.CampaignStatusPaused
+}
+
+// Start validates a campaign and enqueues all of its pending recipients.
+// It is shared by the StartCampaign API handler and the campaign scheduler.
+//
+// The status transition to processing is guarded by the status the campaign
+// was loaded with, so two callers racing to start the same campaign will
+// never both enqueue it. startedBy is the user who started the campaign and
+// is nil for automatic triggers.
+//
+// Returns the number of recipients enqueued.
+func Start(ctx context.Context, db *gorm.DB, q queue.Queue, campaign *models.BulkMessageCampaign, trigger models.CampaignTrigger, startedBy *uuid.UUID) (int, error) {
+	if !CanStart(campaign.Status) {
+		return 0, ErrNotStartable
+	}
+
+	// Get all pending recipients
+	var recipients []models.BulkMessageRecipient
+	if err := db.Where("campaign_id = ? AND status = ?", campaign.ID, models.MessageStatusPending).Find(&recipients).Error; err != nil {
+		return 0, fmt.Errorf("failed to load recipients: %w", err)
+	}
+	if len(recipients) == 0 {
+		return 0, ErrNoPendingRecipients
+	}
+
+	// Validate template still exists
+	if campaign.TemplateID != uuid.Nil {
+		var template models.Template
+		if err := db.Where("id = ? AND organization_id = ?", campaign.TemplateID, campaign.OrganizationID).First(&template).Error; err != nil {
+			return 0, ErrTemplateNotFound
+		}
+
+		// Marketing templates are never sent to contacts that opted out
+		if template.IsMarketing() {
+			var err error
+			if recipients, err = ExcludeOptedOut(db, campaign, recipients); err != nil {
+				return 0, err
+			}
+			if len(recipients) == 0 {
+				return 0, ErrNoPendingRecipients
+			}
+		}
+	}
+
+	// Update status to processing, only if nobody else changed it meanwhile
+	previousStatus := campaign.Status
+	now := time.Now()
+	updates := map[string]interface{}{
+		"status":        models.CampaignStatusProcessing,
+		"started_at":    now,
+		"start_trigger": trigger,
+		"started_by":    gorm.Expr("NULL"),
+	}
+	if startedBy != nil {
+		updates["started_by"] = *startedBy
+	}
+	result := db.Model(&models.BulkMessageCampaign{}).
+		Where("id = ? AND status = ?", campaign.ID, previousStatus).
+		Updates(updates)
+	if result.Error != nil {
+		return 0, fmt.Errorf("failed to start campaign: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return 0, ErrNotStartable
+	}
+	campaign.Status = models.CampaignStatusProcessing
+	campaign.StartedAt = &now
+	campaign.StartedBy = startedBy
+	campaign.StartTrigger = trigger
+
+	// Enqueue all recipients as individual jobs for parallel processing
+	jobs := make([]*queue.RecipientJob, len(recipients))
+	for i, recipient := range recipients {
+		jobs[i] = &queue.RecipientJob{
+			CampaignID:     campaign.ID,
+			RecipientID:    recipient.ID,
+			OrganizationID: campaign.OrganizationID,
+			PhoneNumber:    recipient.PhoneNumber,
+			RecipientName:  recipient.RecipientName,
+			TemplateParams: recipient.TemplateParams,
+		}
+	}
+
+	if err := q.EnqueueRecipients(ctx, jobs); err != nil {
+		// Revert status on failure, unless the campaign was paused or cancelled meanwhile
+		result := db.Model(&models.BulkMessageCampaign{}).
+			Where("id = ? AND status = ?", campaign.ID, models.CampaignStatusProcessing).
+			Update("status", previousStatus)
+		if result.Error == nil && result.RowsAffected > 0 {
+			campaign.Status = previousStatus
+		}
+		return 0, fmt.Errorf("%w: %v", ErrEnqueueFailed, err)
+	}
+
+	return len(jobs), nil
+}
+
+// ExcludeOptedOut marks recipients whose contacts opted out of marketing
+// messages as failed and returns the remaining recipients.
+func ExcludeOptedOut(db *gorm.DB, campaign *models.BulkMessageCampaign, recipients []models.BulkMessageRecipient) ([]models.BulkMessageRecipient, error) {
+	phones := make([]string, len(recipients))
+	for i, recipient := range recipients {
+		phones[i] = recipient.PhoneNumber
+	}
+	optedOut, err := contactutil.OptedOutPhones(db, campaign.OrganizationID, phones)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check opt-outs: %w", err)
+	}
+	if len(optedOut) == 0 {
+		return recipients, nil
+	}
+
+	remaining := make([]models.BulkMessageRecipient, 0, len(recipients))
+	var excludedIDs []uuid.UUID
+	for _, recipient := range recipients {
+		if optedOut[recipient.PhoneNumber] {
+			excludedIDs = append(excludedIDs, recipient.ID)
+		} else {
+			remaining = append(remaining, recipient)
+		}
+	}
+
+	// Only pending rows are updated, so a concurrent start cannot count them twice
+	result := db.Model(&models.BulkMessageRecipient{}).
+		Where("id IN ? AND status = ?", excludedIDs, models.MessageStatusPending).
+		Updates(map[string]interface{}{
+			"status":        models.MessageStatusFailed,
+			"error_message": contactutil.OptedOutError,
+		})
+	if result.Error != nil {
+		return nil, fmt.Errorf("failed to exclude opted-out recipients: %w", result.Error)
+	}
+	if result.RowsAffected > 0 {
+		db.Model(&models.BulkMessageCampaign{}).Where("id = ?", campaign.ID).
+			UpdateColumn("failed_count", gorm.Expr("failed_count + ?", result.RowsAffected))
+		campaign.FailedCount += int(result.RowsAffected)
+	}
+
+	return remaining, nil
+}
+
+// ParseScheduleTime parses a campaign schedule time.
+//
+// Values carrying their own offset (RFC 3339) are used as-is. Zone-less
+// values such as "2026-03-01T09:30" are interpreted as wall-clock time in
+// the IANA timezone tz, defaulting to UTC when tz is empty.
+// Returns nil for an empty value.
+func ParseScheduleTime(value, tz string) (*time.Time, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return nil, nil
+	}
+
+	loc := time.UTC
+	if tz != "" {
+		l, err := time.LoadLocation(tz)
+		if err != nil {
+			return nil, fmt.Errorf("invalid timezone %q", tz)
+		}
+		loc = l
+	}
+
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		t = t.UTC()
+		return &t, nil
+	}
+
+	for _, layout := range scheduleLayouts {
+		if t, err := time.ParseInLocation(layout, value, loc); err == nil {
+			t = t.UTC()
+			return &t, nil
+		}
+	}
+
+	return nil, fmt.Errorf("invalid scheduled_at %q", value)
+}
@@ -0,0 +1,199 @@
+package campaignutil
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shridarpatil/whatomate/internal/models"
+	"github.com/shridarpatil/whatomate/internal/queue"
+	"github.com/shridarpatil/whatomate/test/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseScheduleTime_Empty(t *testing.T) {
+	ts, err := ParseScheduleTime("", "Asia/Kolkata")
+	require.NoError(t, err)
+	assert.Nil(t, ts)
+}
+
+func TestParseScheduleTime_RFC3339KeepsOffset(t *testing.T) {
+	ts, err := ParseScheduleTime("2026-03-01T09:30:00+05:30", "America/New_York")
+	require.NoError(t, err)
+	require.NotNil(t, ts)
+	assert.Equal(t, time.Date(2026, 3, 1, 4, 0, 0, 0, time.UTC), *ts)
+}
+
+func TestParseScheduleTime_LocalInTimezone(t *testing.T) {
+	ts, err := ParseScheduleTime("2026-03-01T09:30", "Asia/Kolkata")
+	require.NoError(t, err)
+	require.NotNil(t, ts)
+	assert.Equal(t, time.Date(2026, 3, 1, 4, 0, 0, 0, time.UTC), *ts)
+}
+
+func TestParseScheduleTime_LocalDefaultsToUTC(t *testing.T) {
+	ts, err := ParseScheduleTime("2026-03-01 09:30:15", "")
+	require.NoError(t, err)
+	require.NotNil(t, ts)
+	assert.Equal(t, time.Date(2026, 3, 1, 9, 30, 15, 0, time.UTC), *ts)
+}
+
+func TestParseScheduleTime_InvalidTimezone(t *testing.T) {
+	_, err := ParseScheduleTime("2026-03-01T09:30", "Mars/Olympus")
+	assert.Error(t, err)
+}
+
+func TestParseScheduleTime_InvalidValue(t *testing.T) {
+	_, err := ParseScheduleTime("next tuesday", "")
+	assert.Error(t, err)
+}
+
+func TestCanStart(t *testing.T) {
+	assert.True(t, CanStart(models.CampaignStatusDraft))
+	assert.True(t, CanStart(models.CampaignStatusScheduled))
+	assert.True(t, CanStart(models.CampaignStatusPaused))
+	assert.False(t, CanStart(models.CampaignStatusProcessing))
+	assert.False(t, CanStart(models.CampaignStatusCompleted))
+	assert.False(t, CanStart(models.CampaignStatusCancelled))
+}
+
+func createCampaign(t *testing.T, status models.CampaignStatus) (*models.BulkMessageCampaign, func(*testing.T) *models.BulkMessageCampaign) {
+	t.Helper()
+	db := testutil.SetupTestDB(t)
+	org := testutil.CreateTestOrganization(t, db)
+	user := testutil.CreateTestUser(t, db, org.ID)
+	account := testutil.CreateTestWhatsAppAccount(t, db, org.ID)
+	template := testutil.CreateTestTemplate(t, db, org.ID, account.Name)
+
+	campaign := &models.BulkMessageCampaign{
+		BaseModel:       models.BaseModel{ID: uuid.New()},
+		OrganizationID:  org.ID,
+		Name:            "Campaign " + uuid.New().String()[:8],
+		WhatsAppAccount: account.Name,
+		TemplateID:      template.ID,
+		Status:          status,
+		CreatedBy:       user.ID,
+	}
+	require.NoError(t, db.Create(campaign).Error)
+
+	reload := func(t *testing.T) *models.BulkMessageCampaign {
+		var c models.BulkMessageCampaign
+		require.NoError(t, db.Where("id = ?", campaign.ID).First(&c).Error)
+		return &c
+	}
+	return campaign, reload
+}
+
+func TestStart_EnqueuesPendingRecipients(t *testing.T) {
+	db := testutil.SetupTestDB(t)
+	campaign, reload := createCampaign(t, models.CampaignStatusScheduled)
+	for _, phone := range []string{"15550000001", "15550000002"} {
+		require.NoError(t, db.Create(&models.BulkMessageRecipient{
+			CampaignID:  campaign.ID,
+			PhoneNumber: phone,
+			Status:      models.MessageStatusPending,
+		}).Error)
+	}
+
+	q := testutil.NewMockQueue()
+	count, err := Start(context.Background(), db, q, campaign, models.CampaignTriggerScheduler, nil)
+	require.NoError(t, err)
+	assert.Equal(t, 2, count)
+	assert.Len(t, q.Jobs, 2)
+
+	updated := reload(t)
+	assert.Equal(t, models.CampaignStatusProcessing, updated.Status)
+	assert.Equal(t, models.CampaignTriggerScheduler, updated.StartTrigger)
+	assert.Nil(t, updated.StartedBy)
+	assert.NotNil(t, updated.StartedAt)
+}
+
+func TestStart_DoesNotDoubleStart(t *testing.T) {
+	db := testutil.SetupTestDB(t)
+	campaign, _ := createCampaign(t, models.CampaignStatusScheduled)
+	require.NoError(t, db.Create(&models.BulkMessageRecipient{
+		CampaignID:  campaign.ID,
+		PhoneNumber: "15550000003",
+		Status:      models.MessageStatusPending,
+	}).Error)
+
+	// A second copy loaded before the first start still thinks it is scheduled
+	stale := *campaign
+
+	q := testutil.NewMockQueue()
+	_, err := Start(context.Background(), db, q, campaign, models.CampaignTriggerScheduler, nil)
+	require.NoError(t, err)
+
+	_, err = Start(context.Background(), db, q, &stale, models.CampaignTriggerScheduler, nil)
+	assert.True(t, errors.Is(err, ErrNotStartable))
+	assert.Len(t, q.Jobs, 1)
+}
+
+func TestStart_NoPendingRecipients(t *testing.T) {
+	db := testutil.SetupTestDB(t)
+	campaign, reload := createCampaign(t, models.CampaignStatusScheduled)
+
+	_, err := Start(context.Background(), db, testutil.NewMockQueue(), campaign, models.CampaignTriggerScheduler, nil)
+	assert.True(t, errors.Is(err, ErrNoPendingRecipients))
+	assert.Equal(t, models.CampaignStatusScheduled, reload(t).Status)
+}
+
+func TestStart_RecordsManualUser(t *testing.T) {
+	db := testutil.SetupTestDB(t)
+	campaign, reload := createCampaign(t, models.CampaignStatusDraft)
+	require.NoError(t, db.Create(&models.BulkMessageRecipient{
+		CampaignID:  campaign.ID,
+		PhoneNumber: "15550000004",
+		Status:      models.MessageStatusPending,
+	}).Error)
+
+	userID := campaign.CreatedBy
+	_, err := Start(context.Background(), db, testutil.NewMockQueue(), campaign, models.CampaignTriggerManual, &userID)
+	require.NoError(t, err)
+
+	updated := reload(t)
+	assert.Equal(t, models.CampaignTriggerManual, updated.StartTrigger)
+	require.NotNil(t, updated.StartedBy)
+	assert.Equal(t, userID, *updated.StartedBy)
+}
+
+func TestStart_EnqueueFailureRevertsStatus(t *testing.T) {
+	db := testutil.SetupTestDB(t)
+	campaign, reload := createCampaign(t, models.CampaignStatusScheduled)
+	require.NoError(t, db.Create(&models.BulkMessageRecipient{
+		CampaignID:  campaign.ID,
+		PhoneNumber: "15550000005",
+		Status:      models.MessageStatusPending,
+	}).Error)
+
+	q := testutil.NewMockQueue()
+	q.Error = errors.New("redis down")
+	_, err := Start(context.Background(), db, q, campaign, models.CampaignTriggerScheduler, nil)
+	assert.True(t, errors.Is(err, ErrEnqueueFailed))
+	assert.Equal(t, models.CampaignStatusScheduled, campaign.Status)
+	assert.Equal(t, models.CampaignStatusScheduled, reload(t).Status)
+}
+
+func TestStart_EnqueueFailureKeepsConcurrentCancel(t *testing.T) {
+	db := testutil.SetupTestDB(t)
+	campaign, reload := createCampaign(t, models.CampaignStatusDraft)
+	require.NoError(t, db.Create(&models.BulkMessageRecipient{
+		CampaignID:  campaign.ID,
+		PhoneNumber: "15550000006",
+		Status:      models.MessageStatusPending,
+	}).Error)
+
+	// The campaign is cancelled while its recipients are being queued
+	q := testutil.NewMockQueue()
+	q.EnqueuesFunc = func(ctx context.Context, jobs []*queue.RecipientJob) error {
+		require.NoError(t, db.Model(&models.BulkMessageCampaign{}).Where("id = ?", campaign.ID).
+			Update("status", models.CampaignStatusCancelled).Error)
+		return errors.New("redis down")
+	}
+	_, err := Start(context.Background(), db, q, campaign, models.CampaignTriggerManual, nil)
+	assert.True(t, errors.Is(err, ErrEnqueueFailed))
+	assert.Equal(t, models.CampaignStatusCancelled, reload(t).Status)
+}
@@ -24,6 +24,31 @@ type Config struct {
 	Cookie        CookieConfig        `koanf:"cookie"`
 	Calling       CallingConfig       `koanf:"calling"`
 	TTS           TTSConfig           `koanf:"tts"`
+	Scheduler     SchedulerConfig     `koanf:"scheduler"`
+	Campaign      CampaignConfig      `koanf:"campaign"`
+	Webhooks      WebhooksConfig      `koanf:"webhooks"`
+}
+
+// CampaignConfig controls how fast campaign workers send messages
+type CampaignConfig struct {
+	MessagesPerSecond int `koanf:"messages_per_second"` // Default per-account send rate (accounts can override)
+	Burst             int `koanf:"burst"`               // Token bucket size; defaults to messages_per_second
+	MaxRetries        int `koanf:"max_retries"`         // Retries for transient Meta API errors; -1 disables
+	RetryBaseSeconds  int `koanf:"retry_base_seconds"`  // First retry delay; doubles on each attempt
+	RetryMaxSeconds   int `koanf:"retry_max_seconds"`   // Upper bound for the retry delay
+}
+
+// WebhooksConfig controls outbound webhook delivery
+type WebhooksConfig struct {
+	RetrySchedule        []string `koanf:"retry_schedule"`         // Delays before each retry, e.g. ["1m", "5m", "30m"]
+	DisableAfterFailures int      `koanf:"disable_after_failures"` // Consecutive failed deliveries before an endpoint is disabled; -1 never
+	RetentionDays        int      `koanf:"retention_days"`         // How long delivery history is kept
+}
+
+// SchedulerConfig controls the background scheduler that runs in workers
+type SchedulerConfig struct {
+	Disabled        bool `koanf:"disabled"`         // Disable the scheduler on this process
+	IntervalSeconds int  `koanf:"interval_seconds"` // How often to check for due scheduled campaigns
 }
 
 type TTSConfig struct {
@@ -106,12 +131,16 @@ type AIConfig struct {
 }
 
 type StorageConfig struct {
-	Type      string `koanf:"type"` // local, s3
-	LocalPath string `koanf:"local_path"`
-	S3Bucket  string `koanf:"s3_bucket"`
-	S3Region  string `koanf:"s3_region"`
-	S3Key     string `koanf:"s3_key"`
-	S3Secret  string `koanf:"s3_secret"`
+	Type           string `koanf:"type"` // local, s3
+	LocalPath      string `koanf:"local_path"`
+	S3Bucket       string `koanf:"s3_bucket"`
+	S3Region       string `koanf:"s3_region"`
+	S3Key          string `koanf:"s3_key"`
+	S3Secret       string `koanf:"s3_secret"`
+	S3Endpoint     string `koanf:"s3_endpoint"`       // Custom endpoint for S3-compatible stores (e.g. MinIO)
+	S3UsePathStyle bool   `koanf:"s3_use_path_style"` // Path-style bucket addressing (required by MinIO)
+	ServeMode      string `koanf:"serve_mode"`        // redirect (presigned URL), stream
+	PresignExpiry  int    `koanf:"presign_expiry"`    // Presigned URL lifetime in seconds
 }
 
 type DefaultAdminConfig struct {
@@ -220,6 +249,12 @@ func setDefaults(cfg *Config) {
 	if cfg.Storage.LocalPath == "" {
 		cfg.Storage.LocalPath = "./uploads"
 	}
+	if cfg.Storage.ServeMode == "" {
+		cfg.Storage.ServeMode = "redirect"
+	}
+	if cfg.Storage.PresignExpiry <= 0 {
+		cfg.Storage.PresignExpiry = 900
+	}
 	// Default admin credentials (only used during initial setup)
 	if cfg.DefaultAdmin.Email == "" {
 		cfg.DefaultAdmin.Email = "admin@admin.com"
@@ -263,4 +298,33 @@ func setDefaults(cfg *Config) {
 	if cfg.Calling.TransferTimeoutSecs == 0 {
 		cfg.Calling.TransferTimeoutSecs = 120
 	}
+	// Scheduler defaults
+	if cfg.Scheduler.IntervalSeconds == 0 {
+		cfg.Scheduler.IntervalSeconds = 30
+	}
+
+	// Campaign throttling defaults
+	if cfg.Campaign.MessagesPerSecond == 0 {
+		cfg.Campaign.MessagesPerSecond = 20
+	}
+	if cfg.Campaign.MaxRetries == 0 {
+		cfg.Campaign.MaxRetries = 5
+	}
+	if cfg.Campaign.RetryBaseSeconds == 0 {
+		cfg.Campaign.RetryBaseSeconds = 30
+	}
+	if cfg.Campaign.RetryMaxSeconds == 0 {
+		cfg.Campaign.RetryMaxSeconds = 3600
+	}
+
+	// Webhook delivery defaults
+	if len(cfg.Webhooks.RetrySchedule) == 0 {
+		cfg.Webhooks.RetrySchedule = []string{"1m", "5m", "30m", "2h", "6h", "12h"}
+	}
+	if cfg.Webhooks.DisableAfterFailures == 0 {
+		cfg.Webhooks.DisableAfterFailures = 5
+	}
+	if cfg.Webhooks.RetentionDays == 0 {
+		cfg.Webhooks.RetentionDays = 30
+	}
 }
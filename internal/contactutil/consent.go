@@ -0,0 +1,110 @@
+package contactutil
+
+import (
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shridarpatil/whatomate/internal/models"
+	"gorm.io/gorm"
+)
+
+// OptedOutError is the recipient error recorded when a marketing message is
+// not sent because the contact opted out.
+const OptedOutError = "Recipient has opted out of marketing messages"
+
+// optedOutLookupBatch bounds the number of phone numbers per IN query.
+const optedOutLookupBatch = 1000
+
+// ConsentChange describes a consent update and the context recorded in the
+// audit trail.
+type ConsentChange struct {
+	Status          models.ConsentStatus
+	Source          models.ConsentSource
+	Keyword         string
+	Reason          string
+	MessageID       *uuid.UUID
+	UserID          *uuid.UUID
+	WhatsAppAccount string
+}
+
+// SetConsent updates the contact's marketing consent and appends a
+// ConsentEvent. Returns false without recording anything when the contact
+// already has the requested status.
+func SetConsent(db *gorm.DB, contact *models.Contact, change ConsentChange) (bool, error) {
+	previous := contact.ConsentStatus
+	if previous == "" {
+		previous = models.ConsentStatusUnknown
+	}
+	if previous == change.Status {
+		return false, nil
+	}
+
+	now := time.Now()
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.Contact{}).Where("id = ?", contact.ID).Updates(map[string]interface{}{
+			"consent_status":     change.Status,
+			"consent_source":     change.Source,
+			"consent_updated_at": now,
+		}).Error; err != nil {
+			return err
+		}
+		return tx.Create(&models.ConsentEvent{
+			OrganizationID:  contact.OrganizationID,
+			ContactID:       contact.ID,
+			PhoneNumber:     contact.PhoneNumber,
+			Status:          change.Status,
+			PreviousStatus:  previous,
+			Source:          change.Source,
+			Keyword:         change.Keyword,
+			Reason:          change.Reason,
+			MessageID:       change.MessageID,
+			UserID:          change.UserID,
+			WhatsAppAccount: change.WhatsAppAccount,
+		}).Error
+	})
+	if err != nil {
+		return false, err
+	}
+
+	contact.ConsentStatus = change.Status
+	contact.ConsentSource = change.Source
+	contact.ConsentUpdatedAt = &now
+	return true, nil
+}
+
+// OptedOutPhones returns the subset of phone numbers whose contacts have opted
+// out of marketing messages. Numbers match with or without a leading "+" and
+// are returned as given.
+func OptedOutPhones(db *gorm.DB, orgID uuid.UUID, phones []string) (map[string]bool, error) {
+	byNormalized := make(map[string][]string, len(phones))
+	lookup := make([]string, 0, 2*len(phones))
+	for _, phone := range phones {
+		normalized := strings.TrimPrefix(strings.TrimSpace(phone), "+")
+		if normalized == "" {
+			continue
+		}
+		if _, seen := byNormalized[normalized]; !seen {
+			lookup = append(lookup, normalized, "+"+normalized)
+		}
+		byNormalized[normalized] = append(byNormalized[normalized], phone)
+	}
+
+	optedOut := make(map[string]bool)
+	for start := 0; start < len(lookup); start += optedOutLookupBatch {
+		end := min(start+optedOutLookupBatch, len(lookup))
+
+		var matched []string
+		if err := db.Model(&models.Contact{}).
+			Where("organization_id = ? AND consent_status = ? AND phone_number IN ?", orgID, models.ConsentStatusOptedOut, lookup[start:end]).
+			Pluck("phone_number", &matched).Error; err != nil {
+			return nil, err
+		}
+		for _, phone := range matched {
+			for _, original := range byNormalized[strings.TrimPrefix(phone, "+")] {
+				optedOut[original] = true
+			}
+		}
+	}
+	return optedOut, nil
+}
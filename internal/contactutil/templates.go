@@ -0,0 +1,38 @@
+package contactutil
+
+import (
+	"github.com/shridarpatil/whatomate/internal/language"
+	"github.com/shridarpatil/whatomate/internal/models"
+	"gorm.io/gorm"
+)
+
+// TemplateInLanguage returns the approved variant of template in lang: a
+// template with the same name and account whose language matches lang
+// exactly or by base language. It returns template itself when lang is empty,
+// already matches, or no variant exists.
+func TemplateInLanguage(db *gorm.DB, template *models.Template, lang string) *models.Template {
+	if template == nil || lang == "" || language.Normalize(template.Language) == language.Normalize(lang) {
+		return template
+	}
+
+	var variants []models.Template
+	if err := db.Where("organization_id = ? AND whats_app_account = ? AND name = ? AND status = ?",
+		template.OrganizationID, template.WhatsAppAccount, template.Name, string(models.TemplateStatusApproved)).
+		Order("language ASC").Find(&variants).Error; err != nil || len(variants) == 0 {
+		return template
+	}
+	languages := make([]string, len(variants))
+	for i, v := range variants {
+		languages[i] = v.Language
+	}
+	match := language.Match(lang, languages)
+	if match == "" || language.Normalize(match) == language.Normalize(template.Language) {
+		return template
+	}
+	for i := range variants {
+		if variants[i].Language == match {
+			return &variants[i]
+		}
+	}
+	return template
+}
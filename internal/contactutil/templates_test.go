@@ -0,0 +1,39 @@
+package contactutil
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/shridarpatil/whatomate/internal/models"
+	"github.com/shridarpatil/whatomate/test/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTemplateInLanguage(t *testing.T) {
+	db := testutil.SetupTestDB(t)
+	org := testutil.CreateTestOrganization(t, db)
+	account := testutil.CreateTestWhatsAppAccount(t, db, org.ID)
+	english := testutil.CreateTestTemplate(t, db, org.ID, account.Name)
+
+	variant := func(lang, status string) *models.Template {
+		tmpl := *english
+		tmpl.ID = uuid.New()
+		tmpl.Language = lang
+		tmpl.Status = status
+		tmpl.BodyContent = "Body in " + lang
+		require.NoError(t, db.Create(&tmpl).Error)
+		return &tmpl
+	}
+	spanish := variant("es", string(models.TemplateStatusApproved))
+	brazilian := variant("pt_BR", string(models.TemplateStatusApproved))
+	variant("fr", string(models.TemplateStatusPending))
+
+	assert.Equal(t, spanish.ID, TemplateInLanguage(db, english, "es").ID)
+	assert.Equal(t, spanish.ID, TemplateInLanguage(db, english, "es-MX").ID, "falls back to the base language")
+	assert.Equal(t, brazilian.ID, TemplateInLanguage(db, english, "pt").ID)
+	assert.Equal(t, english.ID, TemplateInLanguage(db, spanish, "en_US").ID)
+	assert.Equal(t, english.ID, TemplateInLanguage(db, english, "fr").ID, "unapproved variants are not used")
+	assert.Equal(t, english.ID, TemplateInLanguage(db, english, "").ID)
+	assert.Nil(t, TemplateInLanguage(db, nil, "es"))
+}
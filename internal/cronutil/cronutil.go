@@ -0,0 +1,206 @@
+package cronutil
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxSearchYears bounds how far ahead Next looks for a matching time, so that
+// impossible expressions like "0 0 30 2 *" terminate.
+const maxSearchYears = 5
+
+// Schedule is a parsed standard 5-field cron expression:
+// minute hour day-of-month month day-of-week.
+type Schedule struct {
+	minute [60]bool
+	hour   [24]bool
+	dom    [32]bool
+	month  [13]bool
+	dow    [7]bool
+
+	// Per cron convention, when both day fields are restricted a time matches
+	// if either one does.
+	domRestricted bool
+	dowRestricted bool
+}
+
+type field struct {
+	name  string
+	min   int
+	max   int
+	names map[string]int
+}
+
+var (
+	minuteField = field{name: "minute", min: 0, max: 59}
+	hourField   = field{name: "hour", min: 0, max: 23}
+	domField    = field{name: "day of month", min: 1, max: 31}
+	monthField  = field{name: "month", min: 1, max: 12, names: map[string]int{
+		"jan": 1, "feb": 2, "mar": 3, "apr": 4, "may": 5, "jun": 6,
+		"jul": 7, "aug": 8, "sep": 9, "oct": 10, "nov": 11, "dec": 12,
+	}}
+	dowField = field{name: "day of week", min: 0, max: 7, names: map[string]int{
+		"sun": 0, "mon": 1, "tue": 2, "wed": 3, "thu": 4, "fri": 5, "sat": 6,
+	}}
+)
+
+// macros maps the common @-shorthands to their 5-field equivalents
+var macros = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+// Parse parses a 5-field cron expression. Each field supports "*", single
+// values, ranges ("1-5"), steps ("*/15", "0-30/10") and comma lists. Month and
+// day-of-week also accept three-letter names; day-of-week 7 is Sunday.
+func Parse(expr string) (*Schedule, error) {
+	expr = strings.TrimSpace(expr)
+	if m, ok := macros[strings.ToLower(expr)]; ok {
+		expr = m
+	}
+
+	parts := strings.Fields(expr)
+	if len(parts) != 5 {
+		return nil, fmt.Errorf("expected 5 fields, got %d", len(parts))
+	}
+
+	s := &Schedule{}
+	if err := parseField(parts[0], minuteField, s.minute[:]); err != nil {
+		return nil, err
+	}
+	if err := parseField(parts[1], hourField, s.hour[:]); err != nil {
+		return nil, err
+	}
+	if err := parseField(parts[2], domField, s.dom[:]); err != nil {
+		return nil, err
+	}
+	if err := parseField(parts[3], monthField, s.month[:]); err != nil {
+		return nil, err
+	}
+
+	var dow [8]bool
+	if err := parseField(parts[4], dowField, dow[:]); err != nil {
+		return nil, err
+	}
+	copy(s.dow[:], dow[:7])
+	if dow[7] {
+		s.dow[0] = true
+	}
+
+	s.domRestricted = parts[2] != "*"
+	s.dowRestricted = parts[4] != "*"
+	return s, nil
+}
+
+// parseField sets bits[v] for every value matched by a single cron field
+func parseField(spec string, f field, bits []bool) error {
+	for _, part := range strings.Split(spec, ",") {
+		if part == "" {
+			return fmt.Errorf("invalid %s: empty list item", f.name)
+		}
+
+		rangePart, step := part, 1
+		if i := strings.IndexByte(part, '/'); i >= 0 {
+			n, err := strconv.Atoi(part[i+1:])
+			if err != nil || n <= 0 {
+				return fmt.Errorf("invalid %s step: %q", f.name, part)
+			}
+			rangePart, step = part[:i], n
+		}
+
+		lo, hi := f.min, f.max
+		switch {
+		case rangePart == "*":
+			if f.name == dowField.name {
+				hi = 6
+			}
+		case strings.Contains(rangePart, "-"):
+			bounds := strings.SplitN(rangePart, "-", 2)
+			var err error
+			if lo, err = f.value(bounds[0]); err != nil {
+				return err
+			}
+			if hi, err = f.value(bounds[1]); err != nil {
+				return err
+			}
+			if lo > hi {
+				return fmt.Errorf("invalid %s range: %q", f.name, rangePart)
+			}
+		default:
+			v, err := f.value(rangePart)
+			if err != nil {
+				return err
+			}
+			lo = v
+			// "5/15" means every 15 starting at 5
+			if step > 1 {
+				hi = f.max
+			} else {
+				hi = v
+			}
+		}
+
+		for v := lo; v <= hi; v += step {
+			bits[v] = true
+		}
+	}
+	return nil
+}
+
+// value parses a single numeric or named value within the field's bounds
+func (f field) value(s string) (int, error) {
+	if v, ok := f.names[strings.ToLower(s)]; ok {
+		return v, nil
+	}
+	v, err := strconv.Atoi(s)
+	if err != nil || v < f.min || v > f.max {
+		return 0, fmt.Errorf("invalid %s: %q", f.name, s)
+	}
+	return v, nil
+}
+
+// Next returns the first matching time strictly after t, in t's location.
+// It returns the zero time if nothing matches within the search horizon.
+func (s *Schedule) Next(t time.Time) time.Time {
+	loc := t.Location()
+	t = t.Truncate(time.Minute).Add(time.Minute)
+	limit := t.AddDate(maxSearchYears, 0, 0)
+
+	for t.Before(limit) {
+		if !s.month[t.Month()] {
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, loc)
+			continue
+		}
+		if !s.dayMatches(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, loc)
+			continue
+		}
+		if !s.hour[t.Hour()] {
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, loc)
+			continue
+		}
+		if !s.minute[t.Minute()] {
+			t = t.Add(time.Minute)
+			continue
+		}
+		return t
+	}
+	return time.Time{}
+}
+
+// dayMatches applies the day-of-month / day-of-week rules
+func (s *Schedule) dayMatches(t time.Time) bool {
+	domMatch := s.dom[t.Day()]
+	dowMatch := s.dow[t.Weekday()]
+	if s.domRestricted && s.dowRestricted {
+		return domMatch || dowMatch
+	}
+	return domMatch && dowMatch
+}
@@ -0,0 +1,74 @@
+package cronutil
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParse_InvalidExpressions(t *testing.T) {
+	for _, expr := range []string{
+		"",
+		"* * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * * 13 *",
+		"* * * * 8",
+		"*/0 * * * *",
+		"5-1 * * * *",
+		"1,,2 * * * *",
+		"* * * foo *",
+	} {
+		_, err := Parse(expr)
+		assert.Error(t, err, "expected error for %q", expr)
+	}
+}
+
+func TestSchedule_Next(t *testing.T) {
+	base := time.Date(2026, 3, 4, 10, 17, 30, 0, time.UTC) // Wednesday
+
+	tests := []struct {
+		expr string
+		want time.Time
+	}{
+		{"* * * * *", time.Date(2026, 3, 4, 10, 18, 0, 0, time.UTC)},
+		{"*/15 * * * *", time.Date(2026, 3, 4, 10, 30, 0, 0, time.UTC)},
+		{"0 9 * * *", time.Date(2026, 3, 5, 9, 0, 0, 0, time.UTC)},
+		{"30 10-12 * * *", time.Date(2026, 3, 4, 10, 30, 0, 0, time.UTC)},
+		{"0 9 * * mon-fri", time.Date(2026, 3, 5, 9, 0, 0, 0, time.UTC)},
+		{"0 9 * * 7", time.Date(2026, 3, 8, 9, 0, 0, 0, time.UTC)},
+		{"0 0 1 * *", time.Date(2026, 4, 1, 0, 0, 0, 0, time.UTC)},
+		{"0 0 1 jan *", time.Date(2027, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{"@hourly", time.Date(2026, 3, 4, 11, 0, 0, 0, time.UTC)},
+		// Both day fields restricted: either matches (the 10th or any Friday)
+		{"0 8 10 * 5", time.Date(2026, 3, 6, 8, 0, 0, 0, time.UTC)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			s, err := Parse(tt.expr)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, s.Next(base))
+		})
+	}
+}
+
+func TestSchedule_NextRespectsLocation(t *testing.T) {
+	loc, err := time.LoadLocation("Asia/Kolkata")
+	require.NoError(t, err)
+
+	s, err := Parse("0 9 * * *")
+	require.NoError(t, err)
+
+	next := s.Next(time.Date(2026, 3, 4, 4, 0, 0, 0, time.UTC).In(loc))
+	assert.Equal(t, time.Date(2026, 3, 5, 3, 30, 0, 0, time.UTC), next.UTC())
+}
+
+func TestSchedule_NextImpossible(t *testing.T) {
+	s, err := Parse("0 0 30 2 *")
+	require.NoError(t, err)
+	assert.True(t, s.Next(time.Now()).IsZero())
+}
@@ -66,6 +66,7 @@ func GetMigrationModels() []MigrationModel {
 		{"APIKey", &models.APIKey{}},
 		{"SSOProvider", &models.SSOProvider{}},
 		{"Webhook", &models.Webhook{}},
+		{"WebhookDelivery", &models.WebhookDelivery{}},
 		{"CustomAction", &models.CustomAction{}},
 		{"WhatsAppAccount", &models.WhatsAppAccount{}},
 		{"Contact", &models.Contact{}},
@@ -78,16 +79,21 @@ func GetMigrationModels() []MigrationModel {
 		{"BulkMessageCampaign", &models.BulkMessageCampaign{}},
 		{"BulkMessageRecipient", &models.BulkMessageRecipient{}},
 		{"NotificationRule", &models.NotificationRule{}},
+		{"NotificationRuleExecution", &models.NotificationRuleExecution{}},
 
 		// Chatbot models
 		{"ChatbotSettings", &models.ChatbotSettings{}},
 		{"KeywordRule", &models.KeywordRule{}},
 		{"ChatbotFlow", &models.ChatbotFlow{}},
 		{"ChatbotFlowStep", &models.ChatbotFlowStep{}},
+		{"ChatbotFlowVersion", &models.ChatbotFlowVersion{}},
 		{"ChatbotSession", &models.ChatbotSession{}},
 		{"ChatbotSessionMessage", &models.ChatbotSessionMessage{}},
 		{"AIContext", &models.AIContext{}},
 		{"AgentTransfer", &models.AgentTransfer{}},
+		{"BusinessHoursSchedule", &models.BusinessHoursSchedule{}},
+		{"SLAPolicy", &models.SLAPolicy{}},
+		{"AgentSkill", &models.AgentSkill{}},
 
 		// User tracking
 		{"UserAvailabilityLog", &models.UserAvailabilityLog{}},
@@ -95,6 +101,10 @@ func GetMigrationModels() []MigrationModel {
 		// Canned responses
 		{"CannedResponse", &models.CannedResponse{}},
 
+		// Knowledge base
+		{"KnowledgeDocument", &models.KnowledgeDocument{}},
+		{"KnowledgeChunk", &models.KnowledgeChunk{}},
+
 		// Catalogs
 		{"Catalog", &models.Catalog{}},
 		{"CatalogProduct", &models.CatalogProduct{}},
@@ -102,8 +112,11 @@ func GetMigrationModels() []MigrationModel {
 		// Dashboard
 		{"Widget", &models.Widget{}},
 
-		// Conversation Notes
+		// Conversations
+		{"Conversation", &models.Conversation{}},
 		{"ConversationNote", &models.ConversationNote{}},
+		{"ConversationReadCursor", &models.ConversationReadCursor{}},
+		{"ConsentEvent", &models.ConsentEvent{}},
 
 		// Calling / IVR
 		{"CallLog", &models.CallLog{}},
@@ -169,6 +182,9 @@ func RunMigrationWithProgress(db *gorm.DB, adminCfg *config.DefaultAdminConfig)
 		currentStep++
 	}
 
+	// Vector search is optional; without pgvector similarity is computed in Go
+	_ = EnableVectorSearch(silentDB)
+
 	// Seed permissions (always run, will skip if already seeded)
 	printProgress(currentStep, totalSteps)
 	if err := SeedPermissionsAndRoles(silentDB); err != nil {
@@ -209,6 +225,12 @@ func RunMigrationWithProgress(db *gorm.DB, adminCfg *config.DefaultAdminConfig)
 		return err
 	}
 
+	// Open conversations for contacts with active agent transfers
+	if err := BackfillConversations(silentDB); err != nil {
+		fmt.Printf("\n  \033[31m✗ Failed to backfill conversations\033[0m\n\n")
+		return err
+	}
+
 	printProgress(currentStep, totalSteps)
 	fmt.Printf("\n  \033[32m✓ Migration completed\033[0m\n\n")
 
@@ -238,11 +260,14 @@ func getIndexes() []string {
 		`CREATE UNIQUE INDEX IF NOT EXISTS idx_contacts_org_phone ON contacts(organization_id, phone_number)`,
 		`CREATE INDEX IF NOT EXISTS idx_contacts_assigned_read ON contacts(assigned_user_id, is_read)`,
 		`CREATE INDEX IF NOT EXISTS idx_sessions_phone_status ON chatbot_sessions(organization_id, phone_number, status)`,
+		`CREATE INDEX IF NOT EXISTS idx_sessions_flow_version ON chatbot_sessions(current_flow_id, flow_version, status)`,
 		`CREATE INDEX IF NOT EXISTS idx_keyword_rules_priority ON keyword_rules(organization_id, is_enabled, priority DESC)`,
 		`CREATE INDEX IF NOT EXISTS idx_agent_transfers_active ON agent_transfers(organization_id, phone_number, status)`,
 		`CREATE INDEX IF NOT EXISTS idx_agent_transfers_org_contact ON agent_transfers(organization_id, contact_id, status)`,
 		`CREATE INDEX IF NOT EXISTS idx_agent_transfers_agent_active ON agent_transfers(agent_id, status) WHERE status = 'active'`,
+		`CREATE INDEX IF NOT EXISTS idx_campaigns_scheduled_due ON bulk_message_campaigns(scheduled_at) WHERE status = 'scheduled'`,
 		`CREATE INDEX IF NOT EXISTS idx_agent_transfers_team ON agent_transfers(team_id, status) WHERE team_id IS NOT NULL`,
+		`CREATE INDEX IF NOT EXISTS idx_agent_transfers_queue ON agent_transfers(organization_id, priority DESC, transferred_at) WHERE status = 'active' AND agent_id IS NULL`,
 		`CREATE UNIQUE INDEX IF NOT EXISTS idx_whatsapp_accounts_org_phone ON whatsapp_accounts(organization_id, phone_id)`,
 		`CREATE UNIQUE INDEX IF NOT EXISTS idx_templates_account_name_lang ON templates(whats_app_account, name, language)`,
 		`CREATE INDEX IF NOT EXISTS idx_keyword_rules_account ON keyword_rules(whats_app_account, is_enabled, priority DESC)`,
@@ -250,11 +275,17 @@ func getIndexes() []string {
 		`CREATE INDEX IF NOT EXISTS idx_ai_contexts_account ON ai_contexts(whats_app_account, is_enabled, priority DESC)`,
 		`CREATE INDEX IF NOT EXISTS idx_bulk_campaigns_account ON bulk_message_campaigns(whats_app_account, status)`,
 		`CREATE INDEX IF NOT EXISTS idx_notification_rules_account ON notification_rules(whats_app_account, is_enabled)`,
+		`CREATE INDEX IF NOT EXISTS idx_notification_rules_due ON notification_rules(next_run_at) WHERE trigger_type = 'scheduler' AND is_enabled = true`,
+		`CREATE INDEX IF NOT EXISTS idx_notification_executions_rule ON notification_rule_executions(rule_id, created_at DESC)`,
 		`CREATE INDEX IF NOT EXISTS idx_messages_account ON messages(whats_app_account, created_at DESC)`,
 		`CREATE INDEX IF NOT EXISTS idx_contacts_account ON contacts(whats_app_account)`,
 		`CREATE UNIQUE INDEX IF NOT EXISTS idx_canned_responses_org_name ON canned_responses(organization_id, name)`,
 		`CREATE INDEX IF NOT EXISTS idx_canned_responses_active ON canned_responses(organization_id, is_active, usage_count DESC)`,
+		`CREATE INDEX IF NOT EXISTS idx_knowledge_documents_org ON knowledge_documents(organization_id, whats_app_account, status)`,
+		`CREATE INDEX IF NOT EXISTS idx_knowledge_chunks_document ON knowledge_chunks(document_id, chunk_index)`,
 		`CREATE INDEX IF NOT EXISTS idx_webhooks_org_active ON webhooks(organization_id, is_active)`,
+		`CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_webhook ON webhook_deliveries(webhook_id, created_at DESC)`,
+		`CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_pending ON webhook_deliveries(next_attempt_at) WHERE status = 'pending'`,
 		`CREATE INDEX IF NOT EXISTS idx_availability_logs_user_time ON user_availability_logs(user_id, started_at DESC)`,
 		`CREATE INDEX IF NOT EXISTS idx_availability_logs_org_time ON user_availability_logs(organization_id, started_at DESC)`,
 		`CREATE UNIQUE INDEX IF NOT EXISTS idx_sso_providers_org_provider ON sso_providers(organization_id, provider)`,
@@ -271,8 +302,22 @@ func getIndexes() []string {
 		`CREATE INDEX IF NOT EXISTS idx_contacts_tags ON contacts USING GIN (tags)`,
 		// User organizations
 		`CREATE UNIQUE INDEX IF NOT EXISTS idx_user_org_unique ON user_organizations(user_id, organization_id) WHERE deleted_at IS NULL`,
+		// Business hours schedules
+		`CREATE UNIQUE INDEX IF NOT EXISTS idx_business_hours_schedules_name ON business_hours_schedules(organization_id, name) WHERE deleted_at IS NULL`,
+		// SLA policies
+		`CREATE UNIQUE INDEX IF NOT EXISTS idx_sla_policies_name ON sla_policies(organization_id, name) WHERE deleted_at IS NULL`,
+		// Agent skills
+		`CREATE UNIQUE INDEX IF NOT EXISTS idx_agent_skills_unique ON agent_skills(organization_id, user_id, skill) WHERE deleted_at IS NULL`,
+		// Conversations: at most one unresolved conversation per contact
+		`CREATE UNIQUE INDEX IF NOT EXISTS idx_conversations_contact_unresolved ON conversations(contact_id) WHERE status <> 'resolved' AND deleted_at IS NULL`,
+		`CREATE INDEX IF NOT EXISTS idx_conversations_contact ON conversations(contact_id, created_at DESC)`,
+		`CREATE INDEX IF NOT EXISTS idx_conversations_snoozed ON conversations(snoozed_until) WHERE status = 'snoozed' AND deleted_at IS NULL`,
+		`CREATE INDEX IF NOT EXISTS idx_contacts_conversation_status ON contacts(organization_id, conversation_status)`,
 		// Conversation notes
 		`CREATE INDEX IF NOT EXISTS idx_conversation_notes_contact ON conversation_notes(organization_id, contact_id, created_at DESC)`,
+		// Consent audit trail
+		`CREATE INDEX IF NOT EXISTS idx_consent_events_org_created ON consent_events(organization_id, created_at DESC)`,
+		`CREATE INDEX IF NOT EXISTS idx_consent_events_contact ON consent_events(contact_id, created_at DESC)`,
 		// Call logs
 		`CREATE INDEX IF NOT EXISTS idx_call_logs_org_status ON call_logs(organization_id, status, created_at DESC)`,
 		`CREATE INDEX IF NOT EXISTS idx_call_logs_contact ON call_logs(contact_id, created_at DESC)`,
@@ -398,6 +443,45 @@ func BackfillLastInboundAt(db *gorm.DB) error {
 	`).Error
 }
 
+// BackfillConversations opens a conversation for every contact with an active
+// agent transfer and no unresolved conversation, so contacts that were handed
+// to agents before conversations existed show up as open.
+func BackfillConversations(db *gorm.DB) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec(`
+			INSERT INTO conversations (id, organization_id, contact_id, whats_app_account, status, status_changed_at, reopen_count, created_at, updated_at)
+			SELECT DISTINCT ON (t.contact_id) gen_random_uuid(), t.organization_id, t.contact_id, t.whats_app_account, 'open', t.transferred_at, 0, NOW(), NOW()
+			FROM agent_transfers t
+			WHERE t.status = 'active' AND t.deleted_at IS NULL
+			AND NOT EXISTS (
+				SELECT 1 FROM conversations c
+				WHERE c.contact_id = t.contact_id AND c.status <> 'resolved' AND c.deleted_at IS NULL
+			)
+			ORDER BY t.contact_id, t.transferred_at
+		`).Error; err != nil {
+			return err
+		}
+		return tx.Exec(`
+			UPDATE contacts ct
+			SET conversation_id = c.id, conversation_status = c.status
+			FROM conversations c
+			WHERE c.contact_id = ct.id AND c.status <> 'resolved' AND c.deleted_at IS NULL
+			AND ct.conversation_id IS NULL
+		`).Error
+	})
+}
+
+// EnableVectorSearch installs the pgvector extension and adds a vector column
+// for knowledge chunk embeddings. It fails harmlessly where the extension is
+// not available (or the database user may not create it), in which case
+// knowledge search falls back to comparing the bytea embeddings in Go.
+func EnableVectorSearch(db *gorm.DB) error {
+	if err := db.Exec(`CREATE EXTENSION IF NOT EXISTS vector`).Error; err != nil {
+		return err
+	}
+	return db.Exec(`ALTER TABLE knowledge_chunks ADD COLUMN IF NOT EXISTS embedding_vector vector`).Error
+}
+
 // SeedPermissionsAndRoles seeds the default permissions and system roles
 func SeedPermissionsAndRoles(db *gorm.DB) error {
 	// Get all default permissions
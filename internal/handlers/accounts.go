@@ -18,38 +18,44 @@ import (
 
 // AccountRequest represents the request body for creating/updating an account
 type AccountRequest struct {
-	Name               string `json:"name" validate:"required"`
-	AppID              string `json:"app_id"`
-	PhoneID            string `json:"phone_id" validate:"required"`
-	BusinessID         string `json:"business_id" validate:"required"`
-	AccessToken        string `json:"access_token" validate:"required"`
-	AppSecret          string `json:"app_secret"` // Meta App Secret for webhook signature verification
-	WebhookVerifyToken string `json:"webhook_verify_token"`
-	APIVersion         string `json:"api_version"`
-	IsDefaultIncoming  bool   `json:"is_default_incoming"`
-	IsDefaultOutgoing  bool   `json:"is_default_outgoing"`
-	AutoReadReceipt    bool   `json:"auto_read_receipt"`
+	Name                string `json:"name" validate:"required"`
+	AppID               string `json:"app_id"`
+	PhoneID             string `json:"phone_id" validate:"required"`
+	BusinessID          string `json:"business_id" validate:"required"`
+	AccessToken         string `json:"access_token" validate:"required"`
+	AppSecret           string `json:"app_secret"` // Meta App Secret for webhook signature verification
+	WebhookVerifyToken  string `json:"webhook_verify_token"`
+	APIVersion          string `json:"api_version"`
+	IsDefaultIncoming   bool   `json:"is_default_incoming"`
+	IsDefaultOutgoing   bool   `json:"is_default_outgoing"`
+	AutoReadReceipt     bool   `json:"auto_read_receipt"`
+	MessagesPerSecond   int    `json:"messages_per_second"`   // Campaign send rate; 0 = server default
+	DailyRecipientLimit int    `json:"daily_recipient_limit"` // Overrides the Meta tier cap; 0 = use tier
 }
 
 // AccountResponse represents the response for an account (without sensitive data)
 type AccountResponse struct {
-	ID                 uuid.UUID `json:"id"`
-	Name               string    `json:"name"`
-	AppID              string    `json:"app_id"`
-	PhoneID            string    `json:"phone_id"`
-	BusinessID         string    `json:"business_id"`
-	WebhookVerifyToken string    `json:"webhook_verify_token"`
-	APIVersion         string    `json:"api_version"`
-	IsDefaultIncoming  bool      `json:"is_default_incoming"`
-	IsDefaultOutgoing  bool      `json:"is_default_outgoing"`
-	AutoReadReceipt    bool      `json:"auto_read_receipt"`
-	Status             string    `json:"status"`
-	HasAccessToken     bool      `json:"has_access_token"`
-	HasAppSecret       bool      `json:"has_app_secret"`
-	PhoneNumber        string    `json:"phone_number,omitempty"`
-	DisplayName        string    `json:"display_name,omitempty"`
-	CreatedAt          string    `json:"created_at"`
-	UpdatedAt          string    `json:"updated_at"`
+	ID                  uuid.UUID `json:"id"`
+	Name                string    `json:"name"`
+	AppID               string    `json:"app_id"`
+	PhoneID             string    `json:"phone_id"`
+	BusinessID          string    `json:"business_id"`
+	WebhookVerifyToken  string    `json:"webhook_verify_token"`
+	APIVersion          string    `json:"api_version"`
+	IsDefaultIncoming   bool      `json:"is_default_incoming"`
+	IsDefaultOutgoing   bool      `json:"is_default_outgoing"`
+	AutoReadReceipt     bool      `json:"auto_read_receipt"`
+	MessagesPerSecond   int       `json:"messages_per_second"`
+	MessagingLimitTier  string    `json:"messaging_limit_tier,omitempty"`
+	DailyRecipientLimit int       `json:"daily_recipient_limit"`
+	DailyRecipientCap   int       `json:"daily_recipient_cap"` // Effective cap (override or tier); 0 = uncapped
+	Status              string    `json:"status"`
+	HasAccessToken      bool      `json:"has_access_token"`
+	HasAppSecret        bool      `json:"has_app_secret"`
+	PhoneNumber         string    `json:"phone_number,omitempty"`
+	DisplayName         string    `json:"display_name,omitempty"`
+	CreatedAt           string    `json:"created_at"`
+	UpdatedAt           string    `json:"updated_at"`
 }
 
 // ListAccounts returns all WhatsApp accounts for the organization
@@ -118,19 +124,21 @@ func (a *App) CreateAccount(r *fastglue.Request) error {
 	}
 
 	account := models.WhatsAppAccount{
-		OrganizationID:     orgID,
-		Name:               req.Name,
-		AppID:              req.AppID,
-		PhoneID:            req.PhoneID,
-		BusinessID:         req.BusinessID,
-		AccessToken:        encAccessToken,
-		AppSecret:          encAppSecret,
-		WebhookVerifyToken: webhookVerifyToken,
-		APIVersion:         apiVersion,
-		IsDefaultIncoming:  req.IsDefaultIncoming,
-		IsDefaultOutgoing:  req.IsDefaultOutgoing,
-		AutoReadReceipt:    req.AutoReadReceipt,
-		Status:             "active",
+		OrganizationID:      orgID,
+		Name:                req.Name,
+		AppID:               req.AppID,
+		PhoneID:             req.PhoneID,
+		BusinessID:          req.BusinessID,
+		AccessToken:         encAccessToken,
+		AppSecret:           encAppSecret,
+		WebhookVerifyToken:  webhookVerifyToken,
+		APIVersion:          apiVersion,
+		IsDefaultIncoming:   req.IsDefaultIncoming,
+		IsDefaultOutgoing:   req.IsDefaultOutgoing,
+		AutoReadReceipt:     req.AutoReadReceipt,
+		MessagesPerSecond:   req.MessagesPerSecond,
+		DailyRecipientLimit: req.DailyRecipientLimit,
+		Status:              "active",
 	}
 
 	// If this is set as default, unset other defaults
@@ -231,6 +239,8 @@ func (a *App) UpdateAccount(r *fastglue.Request) error {
 		account.APIVersion = req.APIVersion
 	}
 	account.AutoReadReceipt = req.AutoReadReceipt
+	account.MessagesPerSecond = req.MessagesPerSecond
+	account.DailyRecipientLimit = req.DailyRecipientLimit
 
 	// Handle default flags
 	if req.IsDefaultIncoming && !account.IsDefaultIncoming {
@@ -349,6 +359,13 @@ func (a *App) TestAccountConnection(r *fastglue.Request) error {
 	var result map[string]interface{}
 	_ = json.Unmarshal(body, &result)
 
+	// Persist the messaging limit tier so campaign workers can enforce the daily cap
+	if tier, ok := result["messaging_limit_tier"].(string); ok && tier != "" && tier != account.MessagingLimitTier {
+		if err := a.DB.Model(account).Update("messaging_limit_tier", tier).Error; err != nil {
+			a.Log.Error("Failed to save messaging limit tier", "error", err, "account", account.Name)
+		}
+	}
+
 	// Check if this is a test/sandbox number
 	accountMode, _ := result["account_mode"].(string)
 	isTestNumber := accountMode == "SANDBOX"
@@ -379,21 +396,25 @@ func (a *App) TestAccountConnection(r *fastglue.Request) error {
 
 func accountToResponse(acc models.WhatsAppAccount) AccountResponse {
 	return AccountResponse{
-		ID:                 acc.ID,
-		Name:               acc.Name,
-		AppID:              acc.AppID,
-		PhoneID:            acc.PhoneID,
-		BusinessID:         acc.BusinessID,
-		WebhookVerifyToken: acc.WebhookVerifyToken,
-		APIVersion:         acc.APIVersion,
-		IsDefaultIncoming:  acc.IsDefaultIncoming,
-		IsDefaultOutgoing:  acc.IsDefaultOutgoing,
-		AutoReadReceipt:    acc.AutoReadReceipt,
-		Status:             acc.Status,
-		HasAccessToken:     acc.AccessToken != "",
-		HasAppSecret:       acc.AppSecret != "",
-		CreatedAt:          acc.CreatedAt.Format("2006-01-02T15:04:05Z"),
-		UpdatedAt:          acc.UpdatedAt.Format("2006-01-02T15:04:05Z"),
+		ID:                  acc.ID,
+		Name:                acc.Name,
+		AppID:               acc.AppID,
+		PhoneID:             acc.PhoneID,
+		BusinessID:          acc.BusinessID,
+		WebhookVerifyToken:  acc.WebhookVerifyToken,
+		APIVersion:          acc.APIVersion,
+		IsDefaultIncoming:   acc.IsDefaultIncoming,
+		IsDefaultOutgoing:   acc.IsDefaultOutgoing,
+		AutoReadReceipt:     acc.AutoReadReceipt,
+		MessagesPerSecond:   acc.MessagesPerSecond,
+		MessagingLimitTier:  acc.MessagingLimitTier,
+		DailyRecipientLimit: acc.DailyRecipientLimit,
+		DailyRecipientCap:   acc.DailyRecipientCap(),
+		Status:              acc.Status,
+		HasAccessToken:      acc.AccessToken != "",
+		HasAppSecret:        acc.AppSecret != "",
+		CreatedAt:           acc.CreatedAt.Format("2006-01-02T15:04:05Z"),
+		UpdatedAt:           acc.UpdatedAt.Format("2006-01-02T15:04:05Z"),
 	}
 }
 
@@ -0,0 +1,415 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/shridarpatil/whatomate/internal/ai"
+	"github.com/shridarpatil/whatomate/internal/models"
+	"github.com/valyala/fasthttp"
+	"github.com/zerodha/fastglue"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	// agentAssistMessageLimit is how many recent messages the AI reads
+	agentAssistMessageLimit = 30
+	// agentAssistCannedLimit is how many canned responses the AI chooses from
+	agentAssistCannedLimit = 30
+	// maxSuggestedReplies is how many replies are suggested to the agent
+	maxSuggestedReplies = 3
+	// agentAssistMaxTokens limits the length of summaries and suggestions
+	agentAssistMaxTokens = 600
+	// transferSummaryStart and transferSummaryEnd enclose the AI summary in a
+	// transfer's notes, so it can be replaced without touching the agent's text
+	transferSummaryStart = "[AI summary]"
+	transferSummaryEnd   = "[/AI summary]"
+)
+
+const conversationSummaryPrompt = `You summarize customer conversations for support agents taking over from a chatbot.
+Write 2 to 4 short sentences in the language of the conversation: what the customer wants, the details they gave, and what is still unresolved.
+Only use facts from the conversation and collected data. Reply with the summary only.`
+
+const suggestedRepliesPrompt = `You suggest replies a support agent could send next in the conversation below.
+Suggest up to 3 short, distinct replies in the language of the customer. When a canned response fits, adapt it and give its id.
+Only state facts found in the conversation, canned responses or context information.
+Reply with a JSON array only, for example: [{"content": "...", "canned_response_id": "..."}]. Use an empty canned_response_id when none was used.`
+
+// ConversationSummaryResponse is the AI summary of a contact's conversation
+type ConversationSummaryResponse struct {
+	Summary    string     `json:"summary"`
+	TransferID *uuid.UUID `json:"transfer_id,omitempty"` // Active transfer whose notes were updated
+}
+
+// SuggestedReply is a reply the AI suggests to an agent
+type SuggestedReply struct {
+	Content          string     `json:"content"`
+	CannedResponseID *uuid.UUID `json:"canned_response_id,omitempty"` // Canned response the reply is based on
+}
+
+var (
+	// errAINotConfigured is returned when agent assist is used without an AI provider
+	errAINotConfigured = errors.New("AI is not configured for this WhatsApp account")
+	// errNoConversation is returned when a contact has no messages to work from
+	errNoConversation = errors.New("contact has no messages")
+)
+
+// SummarizeConversation generates a summary of a contact's conversation and
+// stores it on the contact's active transfer
+func (a *App) SummarizeConversation(r *fastglue.Request) error {
+	contact, settings, ok := a.agentAssistContact(r)
+	if !ok {
+		return nil
+	}
+
+	summary, err := a.summarizeConversation(settings, contact)
+	if err != nil {
+		return a.sendAgentAssistError(r, err, "Failed to summarize conversation")
+	}
+
+	resp := ConversationSummaryResponse{Summary: summary}
+	var transfer models.AgentTransfer
+	if err := a.DB.Where("organization_id = ? AND contact_id = ? AND status = ?", contact.OrganizationID, contact.ID, models.TransferStatusActive).
+		First(&transfer).Error; err == nil {
+		if err := a.saveTransferSummary(transfer.ID, summary); err != nil {
+			a.Log.Error("Failed to save transfer summary", "error", err, "transfer_id", transfer.ID)
+		} else {
+			resp.TransferID = &transfer.ID
+		}
+	}
+	return r.SendEnvelope(resp)
+}
+
+// SuggestReplies returns replies an agent could send next, based on the
+// conversation, canned responses and AI contexts
+func (a *App) SuggestReplies(r *fastglue.Request) error {
+	contact, settings, ok := a.agentAssistContact(r)
+	if !ok {
+		return nil
+	}
+
+	suggestions, err := a.suggestReplies(settings, contact)
+	if err != nil {
+		return a.sendAgentAssistError(r, err, "Failed to suggest replies")
+	}
+	return r.SendEnvelope(map[string]any{"suggestions": suggestions})
+}
+
+// agentAssistContact loads the contact of an agent assist request and the
+// chatbot settings of its WhatsApp account, sending the error response itself
+func (a *App) agentAssistContact(r *fastglue.Request) (*models.Contact, *models.ChatbotSettings, bool) {
+	orgID, userID, err := a.getOrgAndUserID(r)
+	if err != nil {
+		_ = r.SendErrorEnvelope(fasthttp.StatusUnauthorized, "Unauthorized", nil, "")
+		return nil, nil, false
+	}
+	if err := a.requirePermission(r, userID, models.ResourceChat, models.ActionRead); err != nil {
+		return nil, nil, false
+	}
+	contactID, err := parsePathUUID(r, "id", "contact")
+	if err != nil {
+		return nil, nil, false
+	}
+
+	// Users without contacts:read permission can only access their assigned contacts
+	var contact models.Contact
+	query := a.DB.Where("id = ? AND organization_id = ?", contactID, orgID)
+	if !a.HasPermission(userID, models.ResourceContacts, models.ActionRead, orgID) {
+		query = query.Where("assigned_user_id = ?", userID)
+	}
+	if err := query.First(&contact).Error; err != nil {
+		_ = r.SendErrorEnvelope(fasthttp.StatusNotFound, "Contact not found", nil, "")
+		return nil, nil, false
+	}
+
+	settings, err := a.getChatbotSettingsCached(orgID, contact.WhatsAppAccount)
+	if err != nil || !isAIConfigured(settings.AI) {
+		_ = r.SendErrorEnvelope(fasthttp.StatusBadRequest, errAINotConfigured.Error(), nil, "")
+		return nil, nil, false
+	}
+	return &contact, settings, true
+}
+
+// sendAgentAssistError responds to a failed summary or suggestion request
+func (a *App) sendAgentAssistError(r *fastglue.Request, err error, message string) error {
+	if errors.Is(err, errNoConversation) {
+		return r.SendErrorEnvelope(fasthttp.StatusBadRequest, err.Error(), nil, "")
+	}
+	a.Log.Error(message, "error", err)
+	return r.SendErrorEnvelope(fasthttp.StatusBadGateway, message, nil, "")
+}
+
+// summarizeConversation summarizes the contact's recent messages and the data
+// collected by the chatbot
+func (a *App) summarizeConversation(settings *models.ChatbotSettings, contact *models.Contact) (string, error) {
+	transcript := a.conversationTranscript(contact)
+	if transcript == "" {
+		return "", errNoConversation
+	}
+	session := a.latestChatbotSession(contact)
+
+	content := "Conversation:\n" + transcript
+	if data := formatSessionData(session.SessionData); data != "" {
+		content += "\n\nCollected data:\n" + data
+	}
+
+	resp, err := a.generateAgentAssist(settings, session, conversationSummaryPrompt, content)
+	if err != nil {
+		return "", err
+	}
+	summary := strings.TrimSpace(resp.Content)
+	if summary == "" {
+		return "", errors.New("AI returned an empty summary")
+	}
+	return summary, nil
+}
+
+// suggestReplies asks the AI for replies to the contact's conversation
+func (a *App) suggestReplies(settings *models.ChatbotSettings, contact *models.Contact) ([]SuggestedReply, error) {
+	transcript := a.conversationTranscript(contact)
+	if transcript == "" {
+		return nil, errNoConversation
+	}
+	session := a.latestChatbotSession(contact)
+
+	var canned []models.CannedResponse
+	if err := a.DB.Where("organization_id = ? AND is_active = ?", contact.OrganizationID, true).
+		Order("usage_count DESC, name ASC").Limit(agentAssistCannedLimit).
+		Find(&canned).Error; err != nil {
+		a.Log.Error("Failed to load canned responses", "error", err)
+	}
+	for i := range canned {
+		localizeCannedResponse(&canned[i], contact.PreferredLanguage())
+	}
+
+	var sections []string
+	if len(canned) > 0 {
+		lines := make([]string, len(canned))
+		for i, c := range canned {
+			lines[i] = fmt.Sprintf("[%s] %s: %s", c.ID, c.Name, c.Content)
+		}
+		sections = append(sections, "## Canned Responses\n"+strings.Join(lines, "\n"))
+	}
+	// AI contexts and knowledge base chunks relevant to the customer's last message
+	if contextData, _ := a.buildAIContext(settings, session, a.lastIncomingMessage(contact)); contextData != "" {
+		sections = append(sections, contextData)
+	}
+	systemPrompt := suggestedRepliesPrompt
+	if len(sections) > 0 {
+		systemPrompt += "\n\n" + strings.Join(sections, "\n\n")
+	}
+
+	resp, err := a.generateAgentAssist(settings, session, systemPrompt, "Conversation:\n"+transcript)
+	if err != nil {
+		return nil, err
+	}
+	suggestions := parseSuggestedReplies(resp.Content, canned)
+	if len(suggestions) == 0 {
+		return nil, errors.New("AI returned no suggestions")
+	}
+	return suggestions, nil
+}
+
+// generateAgentAssist runs a single prompt against the configured provider,
+// adding the token usage to the contact's latest chatbot session
+func (a *App) generateAgentAssist(settings *models.ChatbotSettings, session *models.ChatbotSession, systemPrompt, content string) (*ai.Response, error) {
+	provider, err := a.newAIProvider(settings.AI)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := provider.Generate(context.Background(), ai.Request{
+		SystemPrompt: systemPrompt,
+		Messages:     []ai.Message{{Role: ai.RoleUser, Content: content}},
+		MaxTokens:    agentAssistMaxTokens,
+		Temperature:  settings.AI.Temperature,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if session.ID != uuid.Nil {
+		a.recordAIUsage(session, resp.Usage)
+	}
+	return resp, nil
+}
+
+// conversationTranscript formats the contact's recent messages, oldest first
+func (a *App) conversationTranscript(contact *models.Contact) string {
+	var messages []models.Message
+	if err := a.DB.Where("organization_id = ? AND contact_id = ? AND message_type <> ?", contact.OrganizationID, contact.ID, models.MessageTypeReaction).
+		Order("created_at DESC").Limit(agentAssistMessageLimit).
+		Find(&messages).Error; err != nil {
+		a.Log.Error("Failed to load messages for AI summary", "error", err, "contact_id", contact.ID)
+		return ""
+	}
+	slices.Reverse(messages)
+
+	lines := make([]string, 0, len(messages))
+	for _, msg := range messages {
+		speaker := "Customer"
+		if msg.Direction == models.DirectionOutgoing {
+			speaker = "Bot"
+			if msg.SentByUserID != nil {
+				speaker = "Agent"
+			}
+		}
+		lines = append(lines, speaker+": "+transcriptContent(msg))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// transcriptContent is a message's text, or its type for media without a caption
+func transcriptContent(msg models.Message) string {
+	content := strings.TrimSpace(msg.Content)
+	switch msg.MessageType {
+	case models.MessageTypeText, models.MessageTypeInteractive:
+		return content
+	case models.MessageTypeTemplate:
+		if content == "" {
+			content = msg.TemplateName
+		}
+		return "[template] " + content
+	}
+	if content == "" {
+		return "[" + string(msg.MessageType) + "]"
+	}
+	return "[" + string(msg.MessageType) + "] " + content
+}
+
+// lastIncomingMessage returns the text of the contact's last message
+func (a *App) lastIncomingMessage(contact *models.Contact) string {
+	var msg models.Message
+	if err := a.DB.Where("organization_id = ? AND contact_id = ? AND direction = ?", contact.OrganizationID, contact.ID, models.DirectionIncoming).
+		Order("created_at DESC").First(&msg).Error; err != nil {
+		return ""
+	}
+	return msg.Content
+}
+
+// latestChatbotSession returns the contact's most recent chatbot session,
+// or an unsaved one for the contact's account when there is none
+func (a *App) latestChatbotSession(contact *models.Contact) *models.ChatbotSession {
+	var session models.ChatbotSession
+	if err := a.DB.Where("organization_id = ? AND contact_id = ?", contact.OrganizationID, contact.ID).
+		Order("created_at DESC").First(&session).Error; err != nil {
+		return &models.ChatbotSession{
+			OrganizationID:  contact.OrganizationID,
+			ContactID:       contact.ID,
+			WhatsAppAccount: contact.WhatsAppAccount,
+			PhoneNumber:     contact.PhoneNumber,
+		}
+	}
+	return &session
+}
+
+// formatSessionData lists the data a chatbot session collected, leaving out
+// internal keys
+func formatSessionData(data models.JSONB) string {
+	keys := make([]string, 0, len(data))
+	for key := range data {
+		if !strings.HasPrefix(key, "_") {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	lines := make([]string, len(keys))
+	for i, key := range keys {
+		lines[i] = fmt.Sprintf("%s: %s", key, formatValue(data[key]))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// parseSuggestedReplies reads the JSON array of suggestions from the model's
+// answer, keeping only canned response ids that were offered
+func parseSuggestedReplies(content string, canned []models.CannedResponse) []SuggestedReply {
+	start, end := strings.Index(content, "["), strings.LastIndex(content, "]")
+	if start < 0 || end <= start {
+		return nil
+	}
+	var raw []struct {
+		Content          string `json:"content"`
+		CannedResponseID string `json:"canned_response_id"`
+	}
+	if err := json.Unmarshal([]byte(content[start:end+1]), &raw); err != nil {
+		return nil
+	}
+
+	suggestions := []SuggestedReply{}
+	for _, s := range raw {
+		text := strings.TrimSpace(s.Content)
+		if text == "" {
+			continue
+		}
+		reply := SuggestedReply{Content: text}
+		if id, err := uuid.Parse(s.CannedResponseID); err == nil &&
+			slices.ContainsFunc(canned, func(c models.CannedResponse) bool { return c.ID == id }) {
+			reply.CannedResponseID = &id
+		}
+		suggestions = append(suggestions, reply)
+		if len(suggestions) == maxSuggestedReplies {
+			break
+		}
+	}
+	return suggestions
+}
+
+// withTransferSummary adds an AI summary to transfer notes, replacing the block
+// of an earlier summary. Notes around that block are kept as they are.
+func withTransferSummary(notes, summary string) string {
+	summary = strings.NewReplacer(transferSummaryStart, "", transferSummaryEnd, "").Replace(summary)
+	block := transferSummaryStart + "\n" + strings.TrimSpace(summary) + "\n" + transferSummaryEnd
+	if start := strings.Index(notes, transferSummaryStart); start >= 0 {
+		if end := strings.Index(notes[start:], transferSummaryEnd); end >= 0 {
+			return notes[:start] + block + notes[start+end+len(transferSummaryEnd):]
+		}
+	}
+	notes = strings.TrimSpace(notes)
+	if notes == "" {
+		return block
+	}
+	return notes + "\n\n" + block
+}
+
+// saveTransferSummary stores an AI summary in a transfer's notes
+func (a *App) saveTransferSummary(transferID uuid.UUID, summary string) error {
+	// Locked so that notes written meanwhile aren't overwritten
+	return a.DB.Transaction(func(tx *gorm.DB) error {
+		var transfer models.AgentTransfer
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Select("id", "notes").
+			Where("id = ?", transferID).First(&transfer).Error; err != nil {
+			return err
+		}
+		return tx.Model(&transfer).Update("notes", withTransferSummary(transfer.Notes, summary)).Error
+	})
+}
+
+// summarizeTransferAsync summarizes the conversation of a new transfer in
+// the background when transfer summaries are enabled
+func (a *App) summarizeTransferAsync(transfer *models.AgentTransfer, contact *models.Contact, settings *models.ChatbotSettings) {
+	if settings == nil || !settings.AI.TransferSummary || !isAIConfigured(settings.AI) || a.simulation != nil {
+		return
+	}
+	transferID := transfer.ID
+	c := *contact
+	a.wg.Add(1)
+	go func() {
+		defer a.wg.Done()
+		summary, err := a.summarizeConversation(settings, &c)
+		if err != nil {
+			if !errors.Is(err, errNoConversation) {
+				a.Log.Error("Failed to summarize transfer", "error", err, "transfer_id", transferID)
+			}
+			return
+		}
+		if err := a.saveTransferSummary(transferID, summary); err != nil {
+			a.Log.Error("Failed to save transfer summary", "error", err, "transfer_id", transferID)
+		}
+	}()
+}
@@ -0,0 +1,112 @@
+package handlers
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/shridarpatil/whatomate/internal/models"
+	"github.com/shridarpatil/whatomate/test/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseSuggestedReplies(t *testing.T) {
+	canned := []models.CannedResponse{{BaseModel: models.BaseModel{ID: uuid.New()}, Name: "Refunds"}}
+	unknown := uuid.New()
+
+	content := "Here you go:\n```json\n[" +
+		`{"content": "Refunds take 5 days.", "canned_response_id": "` + canned[0].ID.String() + `"},` +
+		`{"content": "  ", "canned_response_id": ""},` +
+		`{"content": "Could you share your order number?", "canned_response_id": "` + unknown.String() + `"},` +
+		`{"content": "Anything else?"},` +
+		`{"content": "One too many"}` +
+		"]\n```"
+	suggestions := parseSuggestedReplies(content, canned)
+	require.Len(t, suggestions, maxSuggestedReplies)
+	assert.Equal(t, "Refunds take 5 days.", suggestions[0].Content)
+	require.NotNil(t, suggestions[0].CannedResponseID)
+	assert.Equal(t, canned[0].ID, *suggestions[0].CannedResponseID)
+	assert.Nil(t, suggestions[1].CannedResponseID, "ids that were not offered are dropped")
+	assert.Equal(t, "Anything else?", suggestions[2].Content)
+
+	assert.Empty(t, parseSuggestedReplies("I cannot help with that.", canned))
+	assert.Empty(t, parseSuggestedReplies("[not json]", canned))
+}
+
+func TestWithTransferSummary(t *testing.T) {
+	assert.Equal(t, "[AI summary]\nWants a refund.\n[/AI summary]", withTransferSummary("", "Wants a refund."))
+	notes := withTransferSummary("Transferred from flow Returns", "Wants a refund.")
+	assert.Equal(t, "Transferred from flow Returns\n\n[AI summary]\nWants a refund.\n[/AI summary]", notes)
+	assert.Equal(t, "Transferred from flow Returns\n\n[AI summary]\nWants a replacement.\n[/AI summary]", withTransferSummary(notes, "Wants a replacement."),
+		"an earlier summary is replaced")
+
+	notes += "\n\nCalled back, customer prefers a refund."
+	assert.Equal(t, "Transferred from flow Returns\n\n[AI summary]\nWants a replacement.\n[/AI summary]\n\nCalled back, customer prefers a refund.",
+		withTransferSummary(notes, "Wants a replacement."), "notes added after the summary are kept")
+
+	assert.Equal(t, "[AI summary]\nWants a refund.\n[/AI summary]", withTransferSummary("", "Wants a refund.\n[/AI summary]"),
+		"the summary can't end its own block")
+}
+
+func TestFormatSessionData(t *testing.T) {
+	data := models.JSONB{"_flow_id": "x", "order_id": "A-12", "email": "jo@example.com"}
+	assert.Equal(t, "email: jo@example.com\norder_id: A-12", formatSessionData(data))
+	assert.Empty(t, formatSessionData(nil))
+}
+
+func TestTranscriptContent(t *testing.T) {
+	assert.Equal(t, "Hello", transcriptContent(models.Message{MessageType: models.MessageTypeText, Content: " Hello "}))
+	assert.Equal(t, "[image]", transcriptContent(models.Message{MessageType: models.MessageTypeImage}))
+	assert.Equal(t, "[image] my receipt", transcriptContent(models.Message{MessageType: models.MessageTypeImage, Content: "my receipt"}))
+	assert.Equal(t, "[template] order_update", transcriptContent(models.Message{MessageType: models.MessageTypeTemplate, TemplateName: "order_update"}))
+}
+
+func TestSummarizeConversation_SavesSummaryOnTransfer(t *testing.T) {
+	app := newProcessorTestApp(t)
+	org, account := createProcessorTestOrg(t, app)
+	contact := testutil.CreateTestContactWith(t, app.DB, org.ID, testutil.WithContactAccount(account.Name))
+
+	for _, m := range []struct {
+		direction models.Direction
+		content   string
+	}{
+		{models.DirectionIncoming, "My order A-12 arrived broken"},
+		{models.DirectionOutgoing, "Sorry to hear that! Connecting you to an agent."},
+	} {
+		require.NoError(t, app.DB.Create(&models.Message{
+			OrganizationID:  org.ID,
+			WhatsAppAccount: account.Name,
+			ContactID:       contact.ID,
+			Direction:       m.direction,
+			MessageType:     models.MessageTypeText,
+			Content:         m.content,
+		}).Error)
+	}
+	transfer := models.AgentTransfer{
+		OrganizationID:  org.ID,
+		ContactID:       contact.ID,
+		WhatsAppAccount: account.Name,
+		PhoneNumber:     contact.PhoneNumber,
+		Status:          models.TransferStatusActive,
+		Notes:           "From keyword",
+	}
+	require.NoError(t, app.DB.Create(&transfer).Error)
+
+	var requests []map[string]any
+	srv := newToolCallingServer(t, []string{`{"choices":[{"message":{"content":"Order A-12 arrived broken; customer wants a fix."}}]}`}, &requests)
+	settings := &models.ChatbotSettings{
+		OrganizationID: org.ID,
+		AI:             models.AIConfig{Enabled: true, Provider: models.AIProviderOpenAICompatible, BaseURL: srv.URL, Model: "llama3"},
+	}
+
+	summary, err := app.summarizeConversation(settings, contact)
+	require.NoError(t, err)
+	assert.Equal(t, "Order A-12 arrived broken; customer wants a fix.", summary)
+	require.Len(t, requests, 1)
+	user := requests[0]["messages"].([]any)[1].(map[string]any)
+	assert.Equal(t, "Conversation:\nCustomer: My order A-12 arrived broken\nBot: Sorry to hear that! Connecting you to an agent.", user["content"])
+
+	require.NoError(t, app.saveTransferSummary(transfer.ID, summary))
+	require.NoError(t, app.DB.First(&transfer, transfer.ID).Error)
+	assert.Equal(t, "From keyword\n\n[AI summary]\nOrder A-12 arrived broken; customer wants a fix.\n[/AI summary]", transfer.Notes)
+}
@@ -8,6 +8,7 @@ import (
 
 	"github.com/google/uuid"
 	"github.com/shridarpatil/whatomate/internal/models"
+	"github.com/shridarpatil/whatomate/internal/webhookutil"
 	"github.com/shridarpatil/whatomate/internal/websocket"
 	"github.com/valyala/fasthttp"
 	"github.com/zerodha/fastglue"
@@ -24,10 +25,12 @@ type agentTransferRow struct {
 	PhoneNumber           string                `gorm:"column:phone_number"`
 	Status                models.TransferStatus `gorm:"column:status"`
 	Source                models.TransferSource `gorm:"column:source"`
+	Priority              models.TransferPriority `gorm:"column:priority"`
 	AgentID               *uuid.UUID `gorm:"column:agent_id"`
 	TeamID                *uuid.UUID `gorm:"column:team_id"`
 	TransferredByUserID   *uuid.UUID `gorm:"column:transferred_by_user_id"`
 	Notes                 string     `gorm:"column:notes"`
+	RequiredSkills        models.StringArray `gorm:"column:required_skills"`
 	TransferredAt         time.Time  `gorm:"column:transferred_at"`
 	ResumedAt             *time.Time `gorm:"column:resumed_at"`
 	ResumedBy             *uuid.UUID `gorm:"column:resumed_by"`
@@ -39,6 +42,9 @@ type agentTransferRow struct {
 	EscalatedAt           *time.Time `gorm:"column:escalated_at"`
 	PickedUpAt            *time.Time `gorm:"column:picked_up_at"`
 	ExpiresAt             *time.Time `gorm:"column:expires_at"`
+	SLAPolicyID           *uuid.UUID `gorm:"column:sla_policy_id"`
+	SLAPaused             bool       `gorm:"column:sla_paused"`
+	SLAResolutionBreached bool       `gorm:"column:sla_resolution_breached"`
 
 	// Joined fields
 	ContactName       *string `gorm:"column:contact_name"`
@@ -55,7 +61,9 @@ type CreateAgentTransferRequest struct {
 	AgentID         *string              `json:"agent_id"`
 	TeamID          *string              `json:"team_id"` // Optional team queue
 	Notes           string               `json:"notes"`
+	RequiredSkills  []string             `json:"required_skills"` // Skills the agent needs, used by skills-based teams
 	Source          models.TransferSource `json:"source"` // manual, flow, keyword
+	Priority        *models.TransferPriority `json:"priority"` // Overrides the priority rules
 }
 
 // AssignTransferRequest represents the request to assign a transfer to an agent
@@ -73,6 +81,7 @@ type AgentTransferResponse struct {
 	WhatsAppAccount   string               `json:"whatsapp_account"`
 	Status            models.TransferStatus `json:"status"`
 	Source            models.TransferSource `json:"source"`
+	Priority          models.TransferPriority `json:"priority"`
 	AgentID           *string              `json:"agent_id,omitempty"`
 	AgentName         *string              `json:"agent_name,omitempty"`
 	TeamID            *string              `json:"team_id,omitempty"`
@@ -80,6 +89,7 @@ type AgentTransferResponse struct {
 	TransferredBy     *string              `json:"transferred_by,omitempty"`
 	TransferredByName *string              `json:"transferred_by_name,omitempty"`
 	Notes             string               `json:"notes"`
+	RequiredSkills    []string             `json:"required_skills"`
 	TransferredAt     string               `json:"transferred_at"`
 	ResumedAt         *string              `json:"resumed_at,omitempty"`
 	ResumedBy         *string              `json:"resumed_by,omitempty"`
@@ -94,6 +104,9 @@ type AgentTransferResponse struct {
 	EscalatedAt           *string `json:"escalated_at,omitempty"`
 	PickedUpAt            *string `json:"picked_up_at,omitempty"`
 	ExpiresAt             *string `json:"expires_at,omitempty"`
+	SLAPolicyID           *string `json:"sla_policy_id,omitempty"`
+	SLAPaused             bool    `json:"sla_paused"`
+	SLAResolutionBreached bool    `json:"sla_resolution_breached"`
 }
 
 // ListAgentTransfers lists agent transfers for the organization
@@ -158,7 +171,7 @@ func (a *App) ListAgentTransfers(r *fastglue.Request) error {
 	query := a.DB.Table("agent_transfers").
 		Select(strings.Join(selectCols, ", ")).
 		Where("agent_transfers.organization_id = ?", orgID).
-		Order("agent_transfers.transferred_at ASC") // FIFO
+		Order("agent_transfers.priority DESC, agent_transfers.transferred_at ASC") // Highest priority first, then FIFO
 
 	// Only add JOINs for requested relations (lazy loading)
 	if includeAll || includeSet["contact"] {
@@ -303,7 +316,9 @@ func (a *App) ListAgentTransfers(r *fastglue.Request) error {
 			WhatsAppAccount: t.WhatsAppAccount,
 			Status:          t.Status,
 			Source:          t.Source,
+			Priority:        t.Priority,
 			Notes:           t.Notes,
+			RequiredSkills:  requiredSkillsList(t.RequiredSkills),
 			TransferredAt:   t.TransferredAt.Format(time.RFC3339),
 		}
 
@@ -371,6 +386,12 @@ func (a *App) ListAgentTransfers(r *fastglue.Request) error {
 			expiresAt := t.ExpiresAt.Format(time.RFC3339)
 			resp.ExpiresAt = &expiresAt
 		}
+		if t.SLAPolicyID != nil {
+			policyID := t.SLAPolicyID.String()
+			resp.SLAPolicyID = &policyID
+		}
+		resp.SLAPaused = t.SLAPaused
+		resp.SLAResolutionBreached = t.SLAResolutionBreached
 
 		response[i] = resp
 	}
@@ -401,6 +422,10 @@ func (a *App) CreateAgentTransfer(r *fastglue.Request) error {
 		return r.SendErrorEnvelope(fasthttp.StatusBadRequest, "contact_id is required", nil, "")
 	}
 
+	if req.Priority != nil && !isValidTransferPriority(*req.Priority) {
+		return r.SendErrorEnvelope(fasthttp.StatusBadRequest, "priority must be between 1 (low) and 4 (VIP)", nil, "")
+	}
+
 	contactID, err := uuid.Parse(req.ContactID)
 	if err != nil {
 		return r.SendErrorEnvelope(fasthttp.StatusBadRequest, "Invalid contact_id", nil, "")
@@ -440,6 +465,8 @@ func (a *App) CreateAgentTransfer(r *fastglue.Request) error {
 		teamID = &parsedTeamID
 	}
 
+	requiredSkills := models.NormalizeSkills(req.RequiredSkills)
+
 	// Determine agent assignment
 	var agentID *uuid.UUID
 
@@ -459,8 +486,11 @@ func (a *App) CreateAgentTransfer(r *fastglue.Request) error {
 		}
 		agentID = &parsedAgentID
 	} else if teamID != nil {
-		// Apply team's assignment strategy
-		agentID = a.assignToTeam(*teamID, orgID)
+		// Apply team's assignment strategy, unless the team is outside its
+		// business hours and the transfer has to wait in its queue
+		if closed, _ := a.teamOutsideBusinessHours(orgID, *teamID); !closed {
+			agentID = a.assignToTeam(*teamID, orgID, requiredSkills)
+		}
 	} else if settings != nil && settings.AgentAssignment.AssignToSameAgent && contact.AssignedUserID != nil {
 		// Auto-assign to contact's existing assigned agent (if setting enabled and agent is available)
 		var assignedAgent models.User
@@ -477,6 +507,17 @@ func (a *App) CreateAgentTransfer(r *fastglue.Request) error {
 		source = models.TransferSourceManual
 	}
 
+	// Determine priority from the priority rules, unless given explicitly
+	priority := transferPriority(a.transferPriorityRules(settings), contact, source, 0)
+	if req.Priority != nil {
+		priority = *req.Priority
+	}
+
+	// VIP transfers that would wait in a queue are assigned preemptively
+	if agentID == nil && priority == models.TransferPriorityVIP {
+		agentID = a.preemptiveAgent(orgID, teamID)
+	}
+
 	// Create transfer
 	transfer := models.AgentTransfer{
 		BaseModel:           models.BaseModel{ID: uuid.New()},
@@ -486,10 +527,12 @@ func (a *App) CreateAgentTransfer(r *fastglue.Request) error {
 		PhoneNumber:         contact.PhoneNumber,
 		Status:              models.TransferStatusActive,
 		Source:              source,
+		Priority:            priority,
 		AgentID:             agentID,
 		TeamID:              teamID,
 		TransferredByUserID: &userID,
 		Notes:               req.Notes,
+		RequiredSkills:      requiredSkills,
 		TransferredAt:       time.Now(),
 	}
 
@@ -513,6 +556,8 @@ func (a *App) CreateAgentTransfer(r *fastglue.Request) error {
 		a.DB.Model(contact).Update("assigned_user_id", agentID)
 	}
 
+	a.openConversation(contact, &userID, transfer.TransferredAt)
+
 	// End any active chatbot session
 	a.DB.Model(&models.ChatbotSession{}).
 		Where("organization_id = ? AND contact_id = ? AND status = ?", orgID, contactID, models.SessionStatusActive).
@@ -531,12 +576,13 @@ func (a *App) CreateAgentTransfer(r *fastglue.Request) error {
 		idStr := transfer.AgentID.String()
 		agentIDStr = &idStr
 	}
-	a.DispatchWebhook(orgID, models.WebhookEventTransferCreated, TransferEventData{
+	a.DispatchWebhook(orgID, models.WebhookEventTransferCreated, webhookutil.TransferEventData{
 		TransferID:      transfer.ID.String(),
 		ContactID:       contact.ID.String(),
 		ContactPhone:    contact.PhoneNumber,
 		ContactName:     contact.ProfileName,
 		Source:          transfer.Source,
+		Priority:        transfer.Priority,
 		Reason:          transfer.Notes,
 		AgentID:         agentIDStr,
 		AgentName:       agentName,
@@ -557,7 +603,9 @@ func (a *App) CreateAgentTransfer(r *fastglue.Request) error {
 		WhatsAppAccount: transfer.WhatsAppAccount,
 		Status:          transfer.Status,
 		Source:          transfer.Source,
+		Priority:        transfer.Priority,
 		Notes:           transfer.Notes,
+		RequiredSkills:  requiredSkillsList(transfer.RequiredSkills),
 		TransferredAt:   transfer.TransferredAt.Format(time.RFC3339),
 	}
 
@@ -604,6 +652,12 @@ func (a *App) CreateAgentTransfer(r *fastglue.Request) error {
 		expiresAt := transfer.SLA.ExpiresAt.Format(time.RFC3339)
 		resp.ExpiresAt = &expiresAt
 	}
+	if transfer.SLA.PolicyID != nil {
+		policyID := transfer.SLA.PolicyID.String()
+		resp.SLAPolicyID = &policyID
+	}
+	resp.SLAPaused = transfer.SLA.Paused
+	resp.SLAResolutionBreached = transfer.SLA.ResolutionBreached
 
 	return r.SendEnvelope(map[string]any{
 		"transfer": resp,
@@ -632,22 +686,37 @@ func (a *App) ResumeFromTransfer(r *fastglue.Request) error {
 		return r.SendErrorEnvelope(fasthttp.StatusBadRequest, "Transfer is not active", nil, "")
 	}
 
-	// Update transfer
+	if err := a.resumeTransfer(transfer, userID); err != nil {
+		return r.SendErrorEnvelope(fasthttp.StatusInternalServerError, "Failed to resume transfer", nil, "")
+	}
+
+	// The agent is done with the contact, so their conversation is resolved
+	a.resolveLatestConversation(transfer.ContactID, models.ConversationResolutionSolved, "", &userID, time.Now())
+
+	return r.SendEnvelope(map[string]any{
+		"message": "Transfer resumed, chatbot is now active for this contact",
+	})
+}
+
+// resumeTransfer closes an active transfer and hands the contact back to the
+// chatbot
+func (a *App) resumeTransfer(transfer *models.AgentTransfer, resumedBy uuid.UUID) error {
 	now := time.Now()
 	transfer.Status = models.TransferStatusResumed
 	transfer.ResumedAt = &now
-	transfer.ResumedBy = &userID
+	a.stopSLAPolicyClock(transfer, now)
+	transfer.ResumedBy = &resumedBy
 
 	if err := a.DB.Save(transfer).Error; err != nil {
 		a.Log.Error("Failed to resume transfer", "error", err, "transfer_id", transfer.ID)
-		return r.SendErrorEnvelope(fasthttp.StatusInternalServerError, "Failed to resume transfer", nil, "")
+		return err
 	}
 
 	// Clear chatbot tracking so client inactivity SLA doesn't trigger after transfer is closed
 	a.ClearContactChatbotTracking(transfer.ContactID)
 
 	// Get chatbot settings to check AssignToSameAgent (use cache)
-	settings, _ := a.getChatbotSettingsCached(orgID, transfer.WhatsAppAccount)
+	settings, _ := a.getChatbotSettingsCached(transfer.OrganizationID, transfer.WhatsAppAccount)
 
 	// If AssignToSameAgent is disabled, unassign the contact
 	if settings != nil && !settings.AgentAssignment.AssignToSameAgent {
@@ -664,18 +733,17 @@ func (a *App) ResumeFromTransfer(r *fastglue.Request) error {
 	a.DB.Where("id = ?", transfer.ContactID).First(&contact)
 
 	// Dispatch webhook for transfer resumed
-	a.DispatchWebhook(orgID, models.WebhookEventTransferResumed, TransferEventData{
+	a.DispatchWebhook(transfer.OrganizationID, models.WebhookEventTransferResumed, webhookutil.TransferEventData{
 		TransferID:      transfer.ID.String(),
 		ContactID:       contact.ID.String(),
 		ContactPhone:    contact.PhoneNumber,
 		ContactName:     contact.ProfileName,
 		Source:          transfer.Source,
+		Priority:        transfer.Priority,
 		WhatsAppAccount: transfer.WhatsAppAccount,
 	})
 
-	return r.SendEnvelope(map[string]any{
-		"message": "Transfer resumed, chatbot is now active for this contact",
-	})
+	return nil
 }
 
 // AssignAgentTransfer assigns a transfer to a specific agent
@@ -758,6 +826,9 @@ func (a *App) AssignAgentTransfer(r *fastglue.Request) error {
 			}
 			transfer.TeamID = &parsedTeamID
 		}
+		// The overflow wait starts over in the new queue
+		now := time.Now()
+		transfer.TeamQueuedAt = &now
 	}
 
 	// Update transfer
@@ -802,12 +873,13 @@ func (a *App) AssignAgentTransfer(r *fastglue.Request) error {
 		contactPhone = transfer.Contact.PhoneNumber
 		contactName = transfer.Contact.ProfileName
 	}
-	a.DispatchWebhook(orgID, models.WebhookEventTransferAssigned, TransferEventData{
+	a.DispatchWebhook(orgID, models.WebhookEventTransferAssigned, webhookutil.TransferEventData{
 		TransferID:      transfer.ID.String(),
 		ContactID:       transfer.ContactID.String(),
 		ContactPhone:    contactPhone,
 		ContactName:     contactName,
 		Source:          transfer.Source,
+		Priority:        transfer.Priority,
 		AgentID:         agentIDStr,
 		AgentName:       agentName,
 		WhatsAppAccount: transfer.WhatsAppAccount,
@@ -871,7 +943,7 @@ func (a *App) PickNextTransfer(r *fastglue.Request) error {
 	// Build query for picking transfer with row-level locking
 	query := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
 		Where("organization_id = ? AND status = ? AND agent_id IS NULL", orgID, models.TransferStatusActive).
-		Order("transferred_at ASC")
+		Order("priority DESC, transferred_at ASC")
 
 	if teamIDStr != "" {
 		// Pick from specific team
@@ -907,7 +979,7 @@ func (a *App) PickNextTransfer(r *fastglue.Request) error {
 	}
 	// Users with full access can pick from any queue if no team_id specified
 
-	// Find oldest unassigned active transfer (FIFO) - locked row
+	// Find the highest priority, oldest unassigned active transfer - locked row
 	var transfer models.AgentTransfer
 	result := query.First(&transfer)
 
@@ -975,7 +1047,9 @@ func (a *App) PickNextTransfer(r *fastglue.Request) error {
 		WhatsAppAccount: transfer.WhatsAppAccount,
 		Status:          transfer.Status,
 		Source:          transfer.Source,
+		Priority:        transfer.Priority,
 		Notes:           transfer.Notes,
+		RequiredSkills:  requiredSkillsList(transfer.RequiredSkills),
 		TransferredAt:   transfer.TransferredAt.Format(time.RFC3339),
 	}
 
@@ -1029,6 +1103,12 @@ func (a *App) PickNextTransfer(r *fastglue.Request) error {
 		expiresAt := transfer.SLA.ExpiresAt.Format(time.RFC3339)
 		resp.ExpiresAt = &expiresAt
 	}
+	if transfer.SLA.PolicyID != nil {
+		policyID := transfer.SLA.PolicyID.String()
+		resp.SLAPolicyID = &policyID
+	}
+	resp.SLAPaused = transfer.SLA.Paused
+	resp.SLAResolutionBreached = transfer.SLA.ResolutionBreached
 
 	return r.SendEnvelope(map[string]any{
 		"message":  "Transfer picked successfully",
@@ -1062,7 +1142,9 @@ func (a *App) broadcastTransferCreated(transfer *models.AgentTransfer, contact *
 		"whatsapp_account": transfer.WhatsAppAccount,
 		"status":           transfer.Status,
 		"source":           transfer.Source,
+		"priority":         transfer.Priority,
 		"notes":            transfer.Notes,
+		"required_skills":  requiredSkillsList(transfer.RequiredSkills),
 		"transferred_at":   transfer.TransferredAt.Format(time.RFC3339),
 	}
 
@@ -1113,6 +1195,7 @@ func (a *App) broadcastTransferAssigned(transfer *models.AgentTransfer) {
 		"id":         transfer.ID.String(),
 		"contact_id": transfer.ContactID.String(),
 		"status":     transfer.Status,
+		"priority":   transfer.Priority,
 	}
 
 	if transfer.AgentID != nil {
@@ -1134,8 +1217,18 @@ func (a *App) broadcastTransferAssigned(transfer *models.AgentTransfer) {
 }
 
 // saveAndFinalizeTransfer handles the common post-creation steps for agent transfers:
-// sets SLA deadlines, saves to DB, updates contact assignment, optionally ends chatbot sessions, and broadcasts.
+// sets the priority and SLA deadlines, saves to DB, updates contact assignment, optionally ends chatbot sessions, and broadcasts.
 func (a *App) saveAndFinalizeTransfer(transfer *models.AgentTransfer, account *models.WhatsAppAccount, contact *models.Contact, settings *models.ChatbotSettings, endChatbotSession bool) error {
+	// Set priority from the priority rules
+	if transfer.Priority == 0 {
+		transfer.Priority = transferPriority(a.transferPriorityRules(settings), contact, transfer.Source, 0)
+	}
+
+	// VIP transfers that would wait in a queue are assigned preemptively
+	if transfer.AgentID == nil && transfer.Priority == models.TransferPriorityVIP {
+		transfer.AgentID = a.preemptiveAgent(account.OrganizationID, transfer.TeamID)
+	}
+
 	// Set SLA deadlines
 	if settings != nil {
 		a.SetSLADeadlines(transfer, settings)
@@ -1155,6 +1248,8 @@ func (a *App) saveAndFinalizeTransfer(transfer *models.AgentTransfer, account *m
 		a.DB.Model(contact).Update("assigned_user_id", transfer.AgentID)
 	}
 
+	a.openConversation(contact, transfer.TransferredByUserID, transfer.TransferredAt)
+
 	// End any active chatbot session
 	if endChatbotSession {
 		a.DB.Model(&models.ChatbotSession{}).
@@ -1168,11 +1263,13 @@ func (a *App) saveAndFinalizeTransfer(transfer *models.AgentTransfer, account *m
 	// Broadcast to WebSocket
 	a.broadcastTransferCreated(transfer, contact)
 
+	a.summarizeTransferAsync(transfer, contact, settings)
+
 	return nil
 }
 
 // createTransferToQueue creates an unassigned agent transfer that goes to the queue
-func (a *App) createTransferToQueue(account *models.WhatsAppAccount, contact *models.Contact, source models.TransferSource) {
+func (a *App) createTransferToQueue(account *models.WhatsAppAccount, contact *models.Contact, source models.TransferSource, requiredSkills models.StringArray) {
 	if a.hasActiveAgentTransfer(account.OrganizationID, contact.ID) {
 		a.Log.Debug("Contact already has active transfer, skipping", "contact_id", contact.ID, "source", source)
 		return
@@ -1188,6 +1285,7 @@ func (a *App) createTransferToQueue(account *models.WhatsAppAccount, contact *mo
 		PhoneNumber:     contact.PhoneNumber,
 		Status:          models.TransferStatusActive,
 		Source:          source,
+		RequiredSkills:  requiredSkills,
 		TransferredAt:   time.Now(),
 	}
 
@@ -1199,26 +1297,36 @@ func (a *App) createTransferToQueue(account *models.WhatsAppAccount, contact *mo
 	a.Log.Info("Transfer created to agent queue", "transfer_id", transfer.ID, "contact_id", contact.ID, "source", source)
 }
 
-// createTransferFromKeyword creates an agent transfer triggered by a keyword rule
-func (a *App) createTransferFromKeyword(account *models.WhatsAppAccount, contact *models.Contact) {
+// createTransferFromKeyword creates an agent transfer triggered by a keyword
+// rule. The rule's response content may name a team_id and required_skills.
+func (a *App) createTransferFromKeyword(account *models.WhatsAppAccount, contact *models.Contact, content models.JSONB) {
 	if a.hasActiveAgentTransfer(account.OrganizationID, contact.ID) {
 		a.Log.Info("Contact already has active transfer, skipping keyword transfer", "contact_id", contact.ID)
 		return
 	}
 
+	requiredSkills := models.SkillsFromConfig(content)
+
 	settings, _ := a.getChatbotSettingsCached(account.OrganizationID, account.Name)
 
 	// Check business hours - if outside hours, send out of hours message instead of transfer
-	if settings != nil && settings.BusinessHours.Enabled && len(settings.BusinessHours.Hours) > 0 {
-		if !a.isWithinBusinessHours(settings.BusinessHours.Hours) {
+	if settings != nil {
+		if closed, message := a.outsideBusinessHours(account.OrganizationID, settings.BusinessHours); closed {
 			a.Log.Info("Outside business hours, sending out of hours message instead of transfer", "contact_id", contact.ID)
-			if settings.BusinessHours.OutOfHoursMessage != "" {
-				_ = a.sendAndSaveTextMessage(account, contact, settings.BusinessHours.OutOfHoursMessage)
+			if message != "" {
+				_ = a.sendAndSaveTextMessage(account, contact, message)
 			}
 			return
 		}
 	}
 
+	if teamIDStr, _ := content["team_id"].(string); teamIDStr != "" {
+		if teamID, err := uuid.Parse(teamIDStr); err == nil {
+			a.createTransferToTeam(account, contact, teamID, "", models.TransferSourceKeyword, requiredSkills)
+			return
+		}
+	}
+
 	// Determine agent assignment
 	var agentID *uuid.UUID
 	if settings != nil && settings.AgentAssignment.AssignToSameAgent && contact.AssignedUserID != nil {
@@ -1237,6 +1345,7 @@ func (a *App) createTransferFromKeyword(account *models.WhatsAppAccount, contact
 		Status:          models.TransferStatusActive,
 		Source:          models.TransferSourceKeyword,
 		AgentID:         agentID,
+		RequiredSkills:  requiredSkills,
 		TransferredAt:   time.Now(),
 	}
 
@@ -1246,8 +1355,8 @@ func (a *App) createTransferFromKeyword(account *models.WhatsAppAccount, contact
 	}
 
 	var agentIDStr string
-	if agentID != nil {
-		agentIDStr = agentID.String()
+	if transfer.AgentID != nil {
+		agentIDStr = transfer.AgentID.String()
 	}
 	a.Log.Info("Agent transfer created from keyword rule",
 		"transfer_id", transfer.ID,
@@ -1256,9 +1365,11 @@ func (a *App) createTransferFromKeyword(account *models.WhatsAppAccount, contact
 	)
 }
 
-// assignToTeam applies the team's assignment strategy to select an agent
+// assignToTeam applies the team's assignment strategy to select an agent.
+// Agents at their conversation capacity are skipped; requiredSkills is used
+// by the skills-based strategy.
 // Returns nil if manual strategy or no available agents
-func (a *App) assignToTeam(teamID uuid.UUID, orgID uuid.UUID) *uuid.UUID {
+func (a *App) assignToTeam(teamID uuid.UUID, orgID uuid.UUID, requiredSkills []string) *uuid.UUID {
 	// Get team and its assignment strategy
 	var team models.Team
 	if err := a.DB.Where("id = ? AND organization_id = ? AND is_active = ?", teamID, orgID, true).First(&team).Error; err != nil {
@@ -1271,6 +1382,8 @@ func (a *App) assignToTeam(teamID uuid.UUID, orgID uuid.UUID) *uuid.UUID {
 		return a.assignToTeamRoundRobin(teamID, orgID)
 	case models.AssignmentStrategyLoadBalanced:
 		return a.assignToTeamLoadBalanced(teamID, orgID)
+	case models.AssignmentStrategySkillsBased:
+		return a.assignToTeamSkillsBased(teamID, orgID, requiredSkills)
 	case models.AssignmentStrategyManual:
 		// Manual means no auto-assignment
 		return nil
@@ -1282,92 +1395,55 @@ func (a *App) assignToTeam(teamID uuid.UUID, orgID uuid.UUID) *uuid.UUID {
 
 // assignToTeamRoundRobin selects the next agent using round-robin
 func (a *App) assignToTeamRoundRobin(teamID uuid.UUID, orgID uuid.UUID) *uuid.UUID {
-	// Get team members who are available agents, ordered by last assigned time
-	var members []models.TeamMember
-	err := a.DB.
-		Joins("JOIN users ON users.id = team_members.user_id").
-		Where("team_members.team_id = ? AND team_members.role = ? AND users.is_available = ? AND users.is_active = ?",
-			teamID, models.TeamRoleAgent, true, true).
-		Order("team_members.last_assigned_at ASC NULLS FIRST").
-		Find(&members).Error
-
-	if err != nil || len(members) == 0 {
+	// Available agents under capacity, ordered by last assigned time
+	candidates := a.teamRoutingCandidates(teamID, orgID)
+	if len(candidates) == 0 {
 		a.Log.Debug("No available agents in team for round-robin", "team_id", teamID)
 		return nil
 	}
 
 	// Pick the first agent (least recently assigned)
-	selectedMember := members[0]
+	selected := &candidates[0]
+	a.markAssigned(selected)
 
-	// Update last_assigned_at
-	now := time.Now()
-	a.DB.Model(&selectedMember).Update("last_assigned_at", now)
-
-	a.Log.Debug("Round-robin assigned to agent", "team_id", teamID, "user_id", selectedMember.UserID)
-	return &selectedMember.UserID
+	a.Log.Debug("Round-robin assigned to agent", "team_id", teamID, "user_id", selected.UserID)
+	return &selected.UserID
 }
 
 // assignToTeamLoadBalanced selects the agent with fewest active transfers
 func (a *App) assignToTeamLoadBalanced(teamID uuid.UUID, orgID uuid.UUID) *uuid.UUID {
-	// Get team members who are available agents
-	var members []models.TeamMember
-	err := a.DB.
-		Joins("JOIN users ON users.id = team_members.user_id").
-		Where("team_members.team_id = ? AND team_members.role = ? AND users.is_available = ? AND users.is_active = ?",
-			teamID, models.TeamRoleAgent, true, true).
-		Find(&members).Error
-
-	if err != nil || len(members) == 0 {
+	candidates := a.teamRoutingCandidates(teamID, orgID)
+	if len(candidates) == 0 {
 		a.Log.Debug("No available agents in team for load-balanced", "team_id", teamID)
 		return nil
 	}
 
-	// Extract member user IDs
-	memberIDs := make([]uuid.UUID, len(members))
-	for i, m := range members {
-		memberIDs[i] = m.UserID
-	}
+	// Find agent with lowest load; ties go to the least recently assigned
+	selected := leastLoadedCandidate(candidates)
 
-	// Count active transfers for all members in a single query (optimized from N+1)
-	type AgentLoad struct {
-		AgentID uuid.UUID `gorm:"column:agent_id"`
-		Count   int64     `gorm:"column:count"`
-	}
-	var loads []AgentLoad
-	a.DB.Model(&models.AgentTransfer{}).
-		Select("agent_id, COUNT(*) as count").
-		Where("organization_id = ? AND agent_id IN ? AND status = ?", orgID, memberIDs, models.TransferStatusActive).
-		Group("agent_id").
-		Scan(&loads)
-
-	// Build a map of agent loads
-	loadMap := make(map[uuid.UUID]int64)
-	for _, l := range loads {
-		loadMap[l.AgentID] = l.Count
-	}
-
-	// Find agent with lowest load (agents with 0 transfers won't be in loadMap)
-	var lowestUserID *uuid.UUID
-	var lowestCount int64 = -1
-	for _, m := range members {
-		count := loadMap[m.UserID] // Will be 0 if not in map (no active transfers)
-		if lowestCount < 0 || count < lowestCount {
-			lowestCount = count
-			userID := m.UserID
-			lowestUserID = &userID
-		}
-	}
+	a.Log.Debug("Load-balanced assigned to agent", "team_id", teamID, "user_id", selected.UserID, "current_load", selected.Load)
+	return &selected.UserID
+}
+
+// assignToTeamSkillsBased selects the available agent that best matches the
+// required skills
+func (a *App) assignToTeamSkillsBased(teamID uuid.UUID, orgID uuid.UUID, requiredSkills []string) *uuid.UUID {
+	candidates := a.teamRoutingCandidates(teamID, orgID)
+	a.loadCandidateSkills(orgID, candidates)
 
-	if lowestUserID == nil {
+	selected := bestSkilledCandidate(candidates, requiredSkills)
+	if selected == nil {
+		a.Log.Debug("No available agents in team with the required skills", "team_id", teamID, "required_skills", requiredSkills)
 		return nil
 	}
+	a.markAssigned(selected)
 
-	a.Log.Debug("Load-balanced assigned to agent", "team_id", teamID, "user_id", *lowestUserID, "current_load", lowestCount)
-	return lowestUserID
+	a.Log.Debug("Skills-based assigned to agent", "team_id", teamID, "user_id", selected.UserID, "required_skills", requiredSkills, "current_load", selected.Load)
+	return &selected.UserID
 }
 
 // createTransferToTeam creates an agent transfer to a specific team with appropriate assignment
-func (a *App) createTransferToTeam(account *models.WhatsAppAccount, contact *models.Contact, teamID uuid.UUID, notes string, source models.TransferSource) {
+func (a *App) createTransferToTeam(account *models.WhatsAppAccount, contact *models.Contact, teamID uuid.UUID, notes string, source models.TransferSource, requiredSkills models.StringArray) {
 	if a.hasActiveAgentTransfer(account.OrganizationID, contact.ID) {
 		a.Log.Debug("Contact already has active transfer, skipping team transfer", "contact_id", contact.ID, "team_id", teamID)
 		return
@@ -1375,7 +1451,12 @@ func (a *App) createTransferToTeam(account *models.WhatsAppAccount, contact *mod
 
 	settings, _ := a.getChatbotSettingsCached(account.OrganizationID, account.Name)
 
-	agentID := a.assignToTeam(teamID, account.OrganizationID)
+	// Outside the team's business hours the transfer waits in the team queue
+	var agentID *uuid.UUID
+	teamClosed, teamSchedule := a.teamOutsideBusinessHours(account.OrganizationID, teamID)
+	if !teamClosed {
+		agentID = a.assignToTeam(teamID, account.OrganizationID, requiredSkills)
+	}
 
 	transfer := models.AgentTransfer{
 		BaseModel:       models.BaseModel{ID: uuid.New()},
@@ -1388,6 +1469,7 @@ func (a *App) createTransferToTeam(account *models.WhatsAppAccount, contact *mod
 		AgentID:         agentID,
 		TeamID:          &teamID,
 		Notes:           notes,
+		RequiredSkills:  requiredSkills,
 		TransferredAt:   time.Now(),
 	}
 
@@ -1396,9 +1478,19 @@ func (a *App) createTransferToTeam(account *models.WhatsAppAccount, contact *mod
 		return
 	}
 
+	if teamClosed && settings != nil {
+		// Tell the contact when the team is back, in their language
+		localized := localizeChatbotSettings(settings, a.resolveContactLanguage(settings, contact, "", false))
+		if message := outOfHoursText(localized.BusinessHours.OutOfHoursMessage, teamSchedule, a.now()); message != "" {
+			if err := a.sendAndSaveTextMessage(account, contact, message); err != nil {
+				a.Log.Error("Failed to send team out of hours message", "error", err, "contact", contact.PhoneNumber)
+			}
+		}
+	}
+
 	var agentIDStrLog string
-	if agentID != nil {
-		agentIDStrLog = agentID.String()
+	if transfer.AgentID != nil {
+		agentIDStrLog = transfer.AgentID.String()
 	}
 	a.Log.Info("Agent transfer created to team",
 		"transfer_id", transfer.ID,
@@ -1406,6 +1498,7 @@ func (a *App) createTransferToTeam(account *models.WhatsAppAccount, contact *mod
 		"team_id", teamID,
 		"agent_id", agentIDStrLog,
 		"source", source,
+		"priority", transfer.Priority,
 	)
 }
 
@@ -39,6 +39,15 @@ type App struct {
 	TTS *tts.PiperTTS
 	// S3Client for serving call recording presigned URLs (nil when not configured)
 	S3Client *storage.S3Client
+	// Storage is the media storage backend (local directory or S3-compatible bucket)
+	Storage storage.Backend
+	// simulation captures outbound side effects when the App is a chatbot
+	// simulator sandbox (nil for the real App)
+	simulation *chatbotSimulation
+	// vectorSearch records whether knowledge chunks can be searched with
+	// pgvector, detected once on first use
+	vectorSearchOnce sync.Once
+	vectorSearch     bool
 	// wg tracks background goroutines for graceful shutdown
 	wg sync.WaitGroup
 }
@@ -0,0 +1,528 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shridarpatil/whatomate/internal/models"
+	"github.com/shridarpatil/whatomate/internal/schedule"
+	"github.com/valyala/fasthttp"
+	"github.com/zerodha/fastglue"
+)
+
+const (
+	// nextOpeningPlaceholder is replaced with the next opening time in
+	// out-of-hours messages
+	nextOpeningPlaceholder = "{{next_opening}}"
+	// nextOpeningLayout formats the next opening time in messages
+	nextOpeningLayout = "Monday, 2 January 15:04 MST"
+	// maxICalFileSize limits imported holiday calendars
+	maxICalFileSize = 1 << 20 // 1MB
+)
+
+// BusinessHoursScheduleRequest represents the request body for creating a
+// business hours schedule
+type BusinessHoursScheduleRequest struct {
+	Name        string             `json:"name"`
+	Description string             `json:"description"`
+	Timezone    string             `json:"timezone"`
+	Hours       []schedule.Day     `json:"hours"`
+	Holidays    []schedule.Holiday `json:"holidays"`
+}
+
+// BusinessHoursScheduleResponse represents a business hours schedule in API
+// responses, with whether it is open now and when it next opens
+type BusinessHoursScheduleResponse struct {
+	ID          uuid.UUID          `json:"id"`
+	Name        string             `json:"name"`
+	Description string             `json:"description"`
+	Timezone    string             `json:"timezone"`
+	Hours       []schedule.Day     `json:"hours"`
+	Holidays    []schedule.Holiday `json:"holidays"`
+	IsOpen      bool               `json:"is_open"`
+	NextOpening *time.Time         `json:"next_opening"`
+	CreatedAt   time.Time          `json:"created_at"`
+	UpdatedAt   time.Time          `json:"updated_at"`
+}
+
+// ListBusinessHoursSchedules returns the organization's business hours schedules
+func (a *App) ListBusinessHoursSchedules(r *fastglue.Request) error {
+	orgID, userID, err := a.getOrgAndUserID(r)
+	if err != nil {
+		return r.SendErrorEnvelope(fasthttp.StatusUnauthorized, "Unauthorized", nil, "")
+	}
+	if err := a.requirePermission(r, userID, models.ResourceSettingsGeneral, models.ActionRead); err != nil {
+		return nil
+	}
+
+	pg := parsePagination(r)
+	search := string(r.RequestCtx.QueryArgs().Peek("search"))
+
+	query := a.DB.Model(&models.BusinessHoursSchedule{}).Where("organization_id = ?", orgID)
+	if search != "" {
+		query = query.Where("name ILIKE ?", "%"+search+"%")
+	}
+
+	var total int64
+	query.Count(&total)
+
+	var schedules []models.BusinessHoursSchedule
+	if err := pg.Apply(query.Order("name ASC")).Find(&schedules).Error; err != nil {
+		a.Log.Error("Failed to list business hours schedules", "error", err)
+		return r.SendErrorEnvelope(fasthttp.StatusInternalServerError, "Failed to list business hours schedules", nil, "")
+	}
+
+	now := a.now()
+	result := make([]BusinessHoursScheduleResponse, len(schedules))
+	for i := range schedules {
+		result[i] = businessHoursScheduleToResponse(&schedules[i], now)
+	}
+
+	return r.SendEnvelope(map[string]any{
+		"schedules": result,
+		"total":     total,
+		"page":      pg.Page,
+		"limit":     pg.Limit,
+	})
+}
+
+// GetBusinessHoursSchedule returns a business hours schedule. The optional
+// "at" query parameter (RFC 3339) evaluates is_open and next_opening at that
+// time instead of now.
+func (a *App) GetBusinessHoursSchedule(r *fastglue.Request) error {
+	orgID, userID, err := a.getOrgAndUserID(r)
+	if err != nil {
+		return r.SendErrorEnvelope(fasthttp.StatusUnauthorized, "Unauthorized", nil, "")
+	}
+	if err := a.requirePermission(r, userID, models.ResourceSettingsGeneral, models.ActionRead); err != nil {
+		return nil
+	}
+
+	id, err := parsePathUUID(r, "id", "business hours schedule")
+	if err != nil {
+		return nil
+	}
+	sched, err := findByIDAndOrg[models.BusinessHoursSchedule](a.DB, r, id, orgID, "Business hours schedule")
+	if err != nil {
+		return nil
+	}
+
+	at := a.now()
+	if s := string(r.RequestCtx.QueryArgs().Peek("at")); s != "" {
+		if at, err = time.Parse(time.RFC3339, s); err != nil {
+			return r.SendErrorEnvelope(fasthttp.StatusBadRequest, "Invalid at, expected RFC 3339 time", nil, "")
+		}
+	}
+
+	return r.SendEnvelope(businessHoursScheduleToResponse(sched, at))
+}
+
+// CreateBusinessHoursSchedule creates a business hours schedule
+func (a *App) CreateBusinessHoursSchedule(r *fastglue.Request) error {
+	orgID, userID, err := a.getOrgAndUserID(r)
+	if err != nil {
+		return r.SendErrorEnvelope(fasthttp.StatusUnauthorized, "Unauthorized", nil, "")
+	}
+	if err := a.requirePermission(r, userID, models.ResourceSettingsGeneral, models.ActionWrite); err != nil {
+		return nil
+	}
+
+	var req BusinessHoursScheduleRequest
+	if err := a.decodeRequest(r, &req); err != nil {
+		return nil
+	}
+
+	sched := models.BusinessHoursSchedule{
+		BaseModel:      models.BaseModel{ID: uuid.New()},
+		OrganizationID: orgID,
+		Name:           strings.TrimSpace(req.Name),
+		Description:    req.Description,
+		Timezone:       strings.TrimSpace(req.Timezone),
+	}
+	if err := a.applyScheduleHours(&sched, req.Hours, req.Holidays); err != nil {
+		return r.SendErrorEnvelope(fasthttp.StatusBadRequest, err.Error(), nil, "")
+	}
+	if err := a.validateBusinessHoursScheduleName(orgID, sched.ID, sched.Name); err != nil {
+		return r.SendErrorEnvelope(fasthttp.StatusBadRequest, err.Error(), nil, "")
+	}
+
+	if err := a.DB.Create(&sched).Error; err != nil {
+		a.Log.Error("Failed to create business hours schedule", "error", err)
+		return r.SendErrorEnvelope(fasthttp.StatusInternalServerError, "Failed to create business hours schedule", nil, "")
+	}
+
+	return r.SendEnvelope(businessHoursScheduleToResponse(&sched, a.now()))
+}
+
+// UpdateBusinessHoursSchedule updates the fields of a business hours schedule
+// present in the request. Hours and holidays are replaced as a whole.
+func (a *App) UpdateBusinessHoursSchedule(r *fastglue.Request) error {
+	orgID, userID, err := a.getOrgAndUserID(r)
+	if err != nil {
+		return r.SendErrorEnvelope(fasthttp.StatusUnauthorized, "Unauthorized", nil, "")
+	}
+	if err := a.requirePermission(r, userID, models.ResourceSettingsGeneral, models.ActionWrite); err != nil {
+		return nil
+	}
+
+	id, err := parsePathUUID(r, "id", "business hours schedule")
+	if err != nil {
+		return nil
+	}
+	sched, err := findByIDAndOrg[models.BusinessHoursSchedule](a.DB, r, id, orgID, "Business hours schedule")
+	if err != nil {
+		return nil
+	}
+
+	var req struct {
+		Name        *string             `json:"name"`
+		Description *string             `json:"description"`
+		Timezone    *string             `json:"timezone"`
+		Hours       *[]schedule.Day     `json:"hours"`
+		Holidays    *[]schedule.Holiday `json:"holidays"`
+	}
+	if err := a.decodeRequest(r, &req); err != nil {
+		return nil
+	}
+
+	if req.Name != nil {
+		sched.Name = strings.TrimSpace(*req.Name)
+		if err := a.validateBusinessHoursScheduleName(orgID, sched.ID, sched.Name); err != nil {
+			return r.SendErrorEnvelope(fasthttp.StatusBadRequest, err.Error(), nil, "")
+		}
+	}
+	if req.Description != nil {
+		sched.Description = *req.Description
+	}
+	if req.Timezone != nil {
+		sched.Timezone = strings.TrimSpace(*req.Timezone)
+	}
+	current, err := sched.Schedule()
+	if err != nil {
+		a.Log.Error("Failed to decode business hours schedule", "error", err, "schedule_id", sched.ID)
+		return r.SendErrorEnvelope(fasthttp.StatusInternalServerError, "Failed to update business hours schedule", nil, "")
+	}
+	hours, holidays := current.Days, current.Holidays
+	if req.Hours != nil {
+		hours = *req.Hours
+	}
+	if req.Holidays != nil {
+		holidays = *req.Holidays
+	}
+	if err := a.applyScheduleHours(sched, hours, holidays); err != nil {
+		return r.SendErrorEnvelope(fasthttp.StatusBadRequest, err.Error(), nil, "")
+	}
+
+	if err := a.DB.Save(sched).Error; err != nil {
+		a.Log.Error("Failed to update business hours schedule", "error", err, "schedule_id", sched.ID)
+		return r.SendErrorEnvelope(fasthttp.StatusInternalServerError, "Failed to update business hours schedule", nil, "")
+	}
+
+	return r.SendEnvelope(businessHoursScheduleToResponse(sched, a.now()))
+}
+
+// DeleteBusinessHoursSchedule deletes a business hours schedule. Chatbot
+// settings that used it fall back to their own hours and teams that used it
+// are always open.
+func (a *App) DeleteBusinessHoursSchedule(r *fastglue.Request) error {
+	orgID, userID, err := a.getOrgAndUserID(r)
+	if err != nil {
+		return r.SendErrorEnvelope(fasthttp.StatusUnauthorized, "Unauthorized", nil, "")
+	}
+	if err := a.requirePermission(r, userID, models.ResourceSettingsGeneral, models.ActionWrite); err != nil {
+		return nil
+	}
+
+	id, err := parsePathUUID(r, "id", "business hours schedule")
+	if err != nil {
+		return nil
+	}
+	sched, err := findByIDAndOrg[models.BusinessHoursSchedule](a.DB, r, id, orgID, "Business hours schedule")
+	if err != nil {
+		return nil
+	}
+
+	if err := a.DB.Delete(sched).Error; err != nil {
+		a.Log.Error("Failed to delete business hours schedule", "error", err, "schedule_id", sched.ID)
+		return r.SendErrorEnvelope(fasthttp.StatusInternalServerError, "Failed to delete business hours schedule", nil, "")
+	}
+
+	// Drop references so nothing follows a deleted schedule
+	a.DB.Model(&models.Team{}).Where("organization_id = ? AND business_hours_schedule_id = ?", orgID, sched.ID).
+		Update("business_hours_schedule_id", nil)
+	a.DB.Model(&models.ChatbotSettings{}).Where("organization_id = ? AND business_hours_schedule_id = ?", orgID, sched.ID).
+		Update("business_hours_schedule_id", nil)
+	a.InvalidateChatbotSettingsCache(orgID)
+
+	return r.SendEnvelope(map[string]string{"message": "Business hours schedule deleted"})
+}
+
+// ImportBusinessHoursHolidays adds the events of an iCalendar (.ics) file to
+// a schedule's holidays. The file is sent as the "file" field of a multipart
+// form or as the raw request body. With replace=true the imported holidays
+// replace the existing ones; otherwise holidays already on the schedule for
+// the same dates are skipped.
+func (a *App) ImportBusinessHoursHolidays(r *fastglue.Request) error {
+	orgID, userID, err := a.getOrgAndUserID(r)
+	if err != nil {
+		return r.SendErrorEnvelope(fasthttp.StatusUnauthorized, "Unauthorized", nil, "")
+	}
+	if err := a.requirePermission(r, userID, models.ResourceSettingsGeneral, models.ActionWrite); err != nil {
+		return nil
+	}
+
+	id, err := parsePathUUID(r, "id", "business hours schedule")
+	if err != nil {
+		return nil
+	}
+	sched, err := findByIDAndOrg[models.BusinessHoursSchedule](a.DB, r, id, orgID, "Business hours schedule")
+	if err != nil {
+		return nil
+	}
+
+	data, err := readICalUpload(r)
+	if err != nil {
+		return r.SendErrorEnvelope(fasthttp.StatusBadRequest, err.Error(), nil, "")
+	}
+	imported, err := schedule.ParseICalendar(data)
+	if err != nil {
+		return r.SendErrorEnvelope(fasthttp.StatusBadRequest, "Invalid calendar: "+err.Error(), nil, "")
+	}
+
+	current, err := sched.Schedule()
+	if err != nil {
+		a.Log.Error("Failed to decode business hours schedule", "error", err, "schedule_id", sched.ID)
+		return r.SendErrorEnvelope(fasthttp.StatusInternalServerError, "Failed to import holidays", nil, "")
+	}
+	holidays := current.Holidays
+	if string(r.RequestCtx.QueryArgs().Peek("replace")) == "true" {
+		holidays = nil
+	}
+	added := 0
+	for _, h := range imported {
+		duplicate := false
+		for _, existing := range holidays {
+			if existing.Date == h.Date && existing.EndDate == h.EndDate {
+				duplicate = true
+				break
+			}
+		}
+		if !duplicate {
+			holidays = append(holidays, h)
+			added++
+		}
+	}
+
+	if err := a.applyScheduleHours(sched, current.Days, holidays); err != nil {
+		return r.SendErrorEnvelope(fasthttp.StatusBadRequest, err.Error(), nil, "")
+	}
+	if err := a.DB.Model(sched).Update("holidays", sched.Holidays).Error; err != nil {
+		a.Log.Error("Failed to import holidays", "error", err, "schedule_id", sched.ID)
+		return r.SendErrorEnvelope(fasthttp.StatusInternalServerError, "Failed to import holidays", nil, "")
+	}
+
+	return r.SendEnvelope(map[string]any{
+		"imported": added,
+		"skipped":  len(imported) - added,
+		"schedule": businessHoursScheduleToResponse(sched, a.now()),
+	})
+}
+
+// readICalUpload reads an uploaded calendar from a multipart form or the body
+func readICalUpload(r *fastglue.Request) ([]byte, error) {
+	if form, err := r.RequestCtx.MultipartForm(); err == nil {
+		files := form.File["file"]
+		if len(files) == 0 {
+			return nil, errors.New("No file provided")
+		}
+		file, err := files[0].Open()
+		if err != nil {
+			return nil, errors.New("Failed to open file")
+		}
+		defer func() { _ = file.Close() }()
+		data, err := io.ReadAll(io.LimitReader(file, maxICalFileSize+1))
+		if err != nil {
+			return nil, errors.New("Failed to read file")
+		}
+		if len(data) > maxICalFileSize {
+			return nil, errors.New("File too large. Maximum size is 1MB")
+		}
+		return data, nil
+	}
+
+	data := r.RequestCtx.PostBody()
+	if len(data) == 0 {
+		return nil, errors.New("No file provided")
+	}
+	if len(data) > maxICalFileSize {
+		return nil, errors.New("File too large. Maximum size is 1MB")
+	}
+	return data, nil
+}
+
+// applyScheduleHours validates hours and holidays against the schedule's
+// timezone and stores them on the schedule
+func (a *App) applyScheduleHours(sched *models.BusinessHoursSchedule, hours []schedule.Day, holidays []schedule.Holiday) error {
+	s := schedule.Schedule{Timezone: sched.Timezone, Days: hours, Holidays: holidays}
+	if err := s.Validate(); err != nil {
+		return err
+	}
+	sched.Hours = models.JSONBArray{}
+	sched.Holidays = models.JSONBArray{}
+	if err := schedule.Decode(hours, &sched.Hours); err != nil {
+		return err
+	}
+	return schedule.Decode(holidays, &sched.Holidays)
+}
+
+// validateBusinessHoursScheduleName checks that the name is set and not used
+// by another schedule of the organization
+func (a *App) validateBusinessHoursScheduleName(orgID, id uuid.UUID, name string) error {
+	if name == "" {
+		return errors.New("name is required")
+	}
+	if len(name) > 100 {
+		return errors.New("name must be at most 100 characters")
+	}
+	var count int64
+	a.DB.Model(&models.BusinessHoursSchedule{}).
+		Where("organization_id = ? AND name = ? AND id != ?", orgID, name, id).Count(&count)
+	if count > 0 {
+		return fmt.Errorf("a business hours schedule named %q already exists", name)
+	}
+	return nil
+}
+
+// businessHoursScheduleExists reports whether the organization has the schedule
+func (a *App) businessHoursScheduleExists(orgID, id uuid.UUID) bool {
+	var count int64
+	a.DB.Model(&models.BusinessHoursSchedule{}).Where("id = ? AND organization_id = ?", id, orgID).Count(&count)
+	return count > 0
+}
+
+// loadBusinessHoursSchedule loads a named schedule of the organization
+func (a *App) loadBusinessHoursSchedule(orgID, id uuid.UUID) (*schedule.Schedule, error) {
+	var sched models.BusinessHoursSchedule
+	if err := a.DB.Where("id = ? AND organization_id = ?", id, orgID).First(&sched).Error; err != nil {
+		return nil, err
+	}
+	return sched.Schedule()
+}
+
+// inlineSchedule returns the schedule of the hours stored in chatbot
+// settings, or nil when there are none. A day's start_time/end_time pair has
+// always included its end minute, so it is converted to an exclusive end one
+// minute later; days given as intervals use exclusive ends like named
+// schedules.
+func inlineSchedule(cfg models.BusinessHoursConfig) *schedule.Schedule {
+	if len(cfg.Hours) == 0 {
+		return nil
+	}
+	var days []schedule.Day
+	if err := schedule.Decode(cfg.Hours, &days); err != nil {
+		return nil
+	}
+	for i := range days {
+		if len(days[i].Intervals) > 0 || days[i].EndTime == "" {
+			continue
+		}
+		if end, err := schedule.ParseClock(days[i].EndTime); err == nil && end < 24*60 {
+			end++
+			days[i].EndTime = fmt.Sprintf("%02d:%02d", end/60, end%60)
+		}
+	}
+	return &schedule.Schedule{Timezone: cfg.Timezone, Days: days}
+}
+
+// chatbotSchedule returns the schedule the chatbot's business hours follow:
+// the selected named schedule, else the hours in the settings. It returns nil
+// when business hours are off or there are no hours to check.
+func (a *App) chatbotSchedule(orgID uuid.UUID, cfg models.BusinessHoursConfig) *schedule.Schedule {
+	if !cfg.Enabled {
+		return nil
+	}
+	if cfg.ScheduleID != nil {
+		sched, err := a.loadBusinessHoursSchedule(orgID, *cfg.ScheduleID)
+		if err == nil {
+			return sched
+		}
+		a.Log.Error("Failed to load business hours schedule, using the chatbot's own hours", "error", err, "schedule_id", *cfg.ScheduleID)
+	}
+	return inlineSchedule(cfg)
+}
+
+// outsideBusinessHours reports whether the chatbot is outside its business
+// hours, and returns the out-of-hours message for that case with
+// {{next_opening}} filled in
+func (a *App) outsideBusinessHours(orgID uuid.UUID, cfg models.BusinessHoursConfig) (bool, string) {
+	sched := a.chatbotSchedule(orgID, cfg)
+	if sched == nil {
+		return false, ""
+	}
+	now := a.now()
+	if sched.IsOpen(now) {
+		return false, ""
+	}
+	return true, outOfHoursText(cfg.OutOfHoursMessage, sched, now)
+}
+
+// teamOutsideBusinessHours reports whether a team with a business hours
+// schedule is closed, and returns its schedule
+func (a *App) teamOutsideBusinessHours(orgID, teamID uuid.UUID) (bool, *schedule.Schedule) {
+	var team models.Team
+	if err := a.DB.Select("id", "business_hours_schedule_id").
+		Where("id = ? AND organization_id = ?", teamID, orgID).First(&team).Error; err != nil || team.BusinessHoursScheduleID == nil {
+		return false, nil
+	}
+	sched, err := a.loadBusinessHoursSchedule(orgID, *team.BusinessHoursScheduleID)
+	if err != nil {
+		a.Log.Error("Failed to load team business hours schedule", "error", err, "team_id", teamID)
+		return false, nil
+	}
+	return !sched.IsOpen(a.now()), sched
+}
+
+// outOfHoursText fills in {{next_opening}} with the time the schedule next
+// opens after now, in the schedule's timezone
+func outOfHoursText(message string, sched *schedule.Schedule, now time.Time) string {
+	if !strings.Contains(message, nextOpeningPlaceholder) {
+		return message
+	}
+	next := ""
+	if t, err := sched.NextOpening(now); err == nil {
+		next = t.Format(nextOpeningLayout)
+	}
+	return strings.TrimSpace(strings.ReplaceAll(message, nextOpeningPlaceholder, next))
+}
+
+func businessHoursScheduleToResponse(sched *models.BusinessHoursSchedule, at time.Time) BusinessHoursScheduleResponse {
+	resp := BusinessHoursScheduleResponse{
+		ID:          sched.ID,
+		Name:        sched.Name,
+		Description: sched.Description,
+		Timezone:    sched.Timezone,
+		Hours:       []schedule.Day{},
+		Holidays:    []schedule.Holiday{},
+		CreatedAt:   sched.CreatedAt,
+		UpdatedAt:   sched.UpdatedAt,
+	}
+	s, err := sched.Schedule()
+	if err != nil {
+		return resp
+	}
+	if s.Days != nil {
+		resp.Hours = s.Days
+	}
+	if s.Holidays != nil {
+		resp.Holidays = s.Holidays
+	}
+	resp.IsOpen = s.IsOpen(at)
+	if next, err := s.NextOpening(at); err == nil {
+		resp.NextOpening = &next
+	}
+	return resp
+}
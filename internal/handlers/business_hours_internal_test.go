@@ -0,0 +1,82 @@
+package handlers
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shridarpatil/whatomate/internal/models"
+	"github.com/shridarpatil/whatomate/internal/schedule"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInlineSchedule_TimezoneAndInclusiveEnd(t *testing.T) {
+	cfg := models.BusinessHoursConfig{
+		Timezone: "Asia/Tokyo",
+		Hours: models.JSONBArray{
+			map[string]interface{}{"day": float64(3), "enabled": true, "start_time": "09:00", "end_time": "17:00"},
+			map[string]interface{}{"day": float64(4), "enabled": true, "intervals": []interface{}{
+				map[string]interface{}{"start_time": "09:00", "end_time": "17:00"},
+			}},
+		},
+	}
+	sched := inlineSchedule(cfg)
+	require.NotNil(t, sched)
+	require.NoError(t, sched.Validate())
+
+	tokyo, _ := time.LoadLocation("Asia/Tokyo")
+	assert.True(t, sched.IsOpen(time.Date(2025, 1, 15, 17, 0, 30, 0, tokyo)), "start_time/end_time include the end minute")
+	assert.False(t, sched.IsOpen(time.Date(2025, 1, 16, 17, 0, 30, 0, tokyo)), "interval ends are exclusive")
+	assert.False(t, sched.IsOpen(time.Date(2025, 1, 15, 9, 0, 0, 0, time.UTC)), "hours are in the settings' timezone")
+}
+
+func TestOutOfHoursText(t *testing.T) {
+	sched := &schedule.Schedule{
+		Timezone: "UTC",
+		Days:     []schedule.Day{{Day: 1, Enabled: true, StartTime: "09:00", EndTime: "17:00"}},
+	}
+	saturday := time.Date(2025, 1, 18, 10, 0, 0, 0, time.UTC)
+
+	assert.Equal(t, "We're closed. Back on Monday, 20 January 09:00 UTC",
+		outOfHoursText("We're closed. Back on {{next_opening}}", sched, saturday))
+	assert.Equal(t, "We're closed", outOfHoursText("We're closed", sched, saturday))
+	assert.Equal(t, "Back", outOfHoursText("Back {{next_opening}}", &schedule.Schedule{}, saturday),
+		"the placeholder is dropped when the schedule never opens")
+}
+
+func TestOutsideBusinessHours_NamedSchedule(t *testing.T) {
+	app := newProcessorTestApp(t)
+	org, _ := createProcessorTestOrg(t, app)
+
+	stored := models.BusinessHoursSchedule{
+		BaseModel:      models.BaseModel{ID: uuid.New()},
+		OrganizationID: org.ID,
+		Name:           "Closed",
+		Timezone:       "UTC",
+		Hours:          models.JSONBArray{},
+		Holidays:       models.JSONBArray{},
+	}
+	require.NoError(t, app.DB.Create(&stored).Error)
+
+	cfg := models.BusinessHoursConfig{
+		Enabled:           true,
+		ScheduleID:        &stored.ID,
+		OutOfHoursMessage: "Closed until {{next_opening}}",
+		Hours: models.JSONBArray{
+			map[string]interface{}{"day": float64(app.now().Weekday()), "enabled": true, "start_time": "00:00", "end_time": "23:59"},
+		},
+	}
+	closed, message := app.outsideBusinessHours(org.ID, cfg)
+	assert.True(t, closed, "the named schedule replaces the settings' own hours")
+	assert.Equal(t, "Closed until", message)
+
+	cfg.ScheduleID = nil
+	closed, _ = app.outsideBusinessHours(org.ID, cfg)
+	assert.False(t, closed)
+
+	cfg.Enabled = false
+	cfg.ScheduleID = &stored.ID
+	closed, _ = app.outsideBusinessHours(org.ID, cfg)
+	assert.False(t, closed, "business hours are not checked when disabled")
+}
@@ -0,0 +1,157 @@
+package handlers_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shridarpatil/whatomate/internal/handlers"
+	"github.com/shridarpatil/whatomate/internal/models"
+	"github.com/shridarpatil/whatomate/internal/schedule"
+	"github.com/shridarpatil/whatomate/test/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/valyala/fasthttp"
+)
+
+func createBusinessHoursSchedule(t *testing.T, app *handlers.App, orgID uuid.UUID) handlers.BusinessHoursScheduleResponse {
+	t.Helper()
+
+	user := createAdminUser(t, app, orgID)
+	req := testutil.NewJSONRequest(t, map[string]any{
+		"name":     "Support hours",
+		"timezone": "Europe/Berlin",
+		"hours": []map[string]any{
+			{"day": 1, "enabled": true, "intervals": []map[string]string{
+				{"start_time": "09:00", "end_time": "12:00"},
+				{"start_time": "13:00", "end_time": "17:00"},
+			}},
+		},
+	})
+	testutil.SetAuthContext(req, orgID, user.ID)
+	require.NoError(t, app.CreateBusinessHoursSchedule(req))
+	require.Equal(t, fasthttp.StatusOK, testutil.GetResponseStatusCode(req))
+
+	var resp handlers.BusinessHoursScheduleResponse
+	testutil.ParseEnvelopeResponse(t, req, &resp)
+	return resp
+}
+
+func TestApp_CreateBusinessHoursSchedule(t *testing.T) {
+	t.Parallel()
+
+	t.Run("success", func(t *testing.T) {
+		app := newTestApp(t)
+		org := testutil.CreateTestOrganization(t, app.DB)
+
+		resp := createBusinessHoursSchedule(t, app, org.ID)
+		assert.Equal(t, "Support hours", resp.Name)
+		assert.Equal(t, "Europe/Berlin", resp.Timezone)
+		require.Len(t, resp.Hours, 1)
+		assert.Len(t, resp.Hours[0].Intervals, 2)
+		require.NotNil(t, resp.NextOpening)
+		assert.Equal(t, time.Monday, resp.NextOpening.Weekday())
+	})
+
+	t.Run("invalid timezone", func(t *testing.T) {
+		app := newTestApp(t)
+		org := testutil.CreateTestOrganization(t, app.DB)
+		user := createAdminUser(t, app, org.ID)
+
+		req := testutil.NewJSONRequest(t, map[string]any{"name": "Bad", "timezone": "Nowhere/City"})
+		testutil.SetAuthContext(req, org.ID, user.ID)
+		require.NoError(t, app.CreateBusinessHoursSchedule(req))
+		testutil.AssertErrorResponse(t, req, fasthttp.StatusBadRequest, `invalid timezone "Nowhere/City"`)
+	})
+
+	t.Run("duplicate name", func(t *testing.T) {
+		app := newTestApp(t)
+		org := testutil.CreateTestOrganization(t, app.DB)
+		createBusinessHoursSchedule(t, app, org.ID)
+		user := createAdminUser(t, app, org.ID)
+
+		req := testutil.NewJSONRequest(t, map[string]any{"name": "Support hours"})
+		testutil.SetAuthContext(req, org.ID, user.ID)
+		require.NoError(t, app.CreateBusinessHoursSchedule(req))
+		testutil.AssertErrorResponse(t, req, fasthttp.StatusBadRequest, "already exists")
+	})
+}
+
+func TestApp_GetBusinessHoursSchedule_At(t *testing.T) {
+	t.Parallel()
+
+	app := newTestApp(t)
+	org := testutil.CreateTestOrganization(t, app.DB)
+	created := createBusinessHoursSchedule(t, app, org.ID)
+	user := createAdminUser(t, app, org.ID)
+
+	// Monday 2025-01-13 12:30 in Berlin is the lunch break
+	req := testutil.NewGETRequest(t)
+	testutil.SetAuthContext(req, org.ID, user.ID)
+	testutil.SetPathParam(req, "id", created.ID.String())
+	testutil.SetQueryParam(req, "at", "2025-01-13T11:30:00Z")
+	require.NoError(t, app.GetBusinessHoursSchedule(req))
+	require.Equal(t, fasthttp.StatusOK, testutil.GetResponseStatusCode(req))
+
+	var resp handlers.BusinessHoursScheduleResponse
+	testutil.ParseEnvelopeResponse(t, req, &resp)
+	assert.False(t, resp.IsOpen)
+	require.NotNil(t, resp.NextOpening)
+	assert.True(t, resp.NextOpening.Equal(time.Date(2025, 1, 13, 12, 0, 0, 0, time.UTC)))
+}
+
+func TestApp_ImportBusinessHoursHolidays(t *testing.T) {
+	t.Parallel()
+
+	app := newTestApp(t)
+	org := testutil.CreateTestOrganization(t, app.DB)
+	created := createBusinessHoursSchedule(t, app, org.ID)
+	user := createAdminUser(t, app, org.ID)
+
+	ics := "BEGIN:VCALENDAR\r\nBEGIN:VEVENT\r\nDTSTART;VALUE=DATE:20251225\r\nDTEND;VALUE=DATE:20251227\r\n" +
+		"SUMMARY:Christmas\r\nEND:VEVENT\r\nEND:VCALENDAR\r\n"
+	importICal := func() map[string]any {
+		req := testutil.NewRequest(t)
+		req.RequestCtx.Request.SetBodyString(ics)
+		testutil.SetAuthContext(req, org.ID, user.ID)
+		testutil.SetPathParam(req, "id", created.ID.String())
+		require.NoError(t, app.ImportBusinessHoursHolidays(req))
+		require.Equal(t, fasthttp.StatusOK, testutil.GetResponseStatusCode(req))
+		var resp map[string]any
+		testutil.ParseEnvelopeResponse(t, req, &resp)
+		return resp
+	}
+
+	resp := importICal()
+	assert.Equal(t, float64(1), resp["imported"])
+	resp = importICal()
+	assert.Equal(t, float64(0), resp["imported"], "holidays already on the schedule are skipped")
+	assert.Equal(t, float64(1), resp["skipped"])
+
+	var stored models.BusinessHoursSchedule
+	require.NoError(t, app.DB.First(&stored, created.ID).Error)
+	sched, err := stored.Schedule()
+	require.NoError(t, err)
+	assert.Equal(t, []schedule.Holiday{{Name: "Christmas", Date: "2025-12-25", EndDate: "2025-12-26"}}, sched.Holidays)
+}
+
+func TestApp_DeleteBusinessHoursSchedule_ClearsReferences(t *testing.T) {
+	t.Parallel()
+
+	app := newTestApp(t)
+	org := testutil.CreateTestOrganization(t, app.DB)
+	created := createBusinessHoursSchedule(t, app, org.ID)
+	user := createAdminUser(t, app, org.ID)
+	team := createTeam(t, app, org.ID, "Night shift")
+	require.NoError(t, app.DB.Model(team).Update("business_hours_schedule_id", created.ID).Error)
+
+	req := testutil.NewRequest(t)
+	testutil.SetAuthContext(req, org.ID, user.ID)
+	testutil.SetPathParam(req, "id", created.ID.String())
+	require.NoError(t, app.DeleteBusinessHoursSchedule(req))
+	require.Equal(t, fasthttp.StatusOK, testutil.GetResponseStatusCode(req))
+
+	var reloaded models.Team
+	require.NoError(t, app.DB.First(&reloaded, team.ID).Error)
+	assert.Nil(t, reloaded.BusinessHoursScheduleID)
+}
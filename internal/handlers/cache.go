@@ -8,6 +8,7 @@ import (
 
 	"github.com/google/uuid"
 	"github.com/shridarpatil/whatomate/internal/models"
+	"github.com/shridarpatil/whatomate/internal/webhookutil"
 	"github.com/shridarpatil/whatomate/internal/websocket"
 	"gorm.io/gorm"
 )
@@ -30,7 +31,7 @@ const (
 	flowsCachePrefix           = "chatbot:flows:"
 	keywordRulesCachePrefix    = "chatbot:keywords:"
 	whatsappAccountCachePrefix = "whatsapp:account:"
-	webhooksCachePrefix        = "webhooks:"
+	webhooksCachePrefix        = webhookutil.CacheKeyPrefix // Shared with the delivery workers
 	slaSettingsCacheKey        = "chatbot:sla_enabled_settings"
 	aiContextsCachePrefix      = "chatbot:ai_contexts:"
 	userPermissionsCachePrefix = "permissions:user:"
@@ -38,10 +39,12 @@ const (
 	tagsCachePrefix            = "tags:"
 )
 
-// chatbotSettingsCache is used for caching since AI.APIKey has json:"-" tag
+// chatbotSettingsCache is used for caching since the AI credentials have json:"-" tags
 type chatbotSettingsCache struct {
 	models.ChatbotSettings
-	AIAPIKey string `json:"ai_api_key_cache"`
+	AIAPIKey    string            `json:"ai_api_key_cache"`
+	AIHeaders   models.JSONB      `json:"ai_headers_cache"`
+	AIFallbacks models.JSONBArray `json:"ai_fallbacks_cache"`
 }
 
 // getChatbotSettingsCached retrieves chatbot settings from cache or database
@@ -54,8 +57,10 @@ func (a *App) getChatbotSettingsCached(orgID uuid.UUID, whatsAppAccount string)
 	if err == nil && cached != "" {
 		var cacheData chatbotSettingsCache
 		if err := json.Unmarshal([]byte(cached), &cacheData); err == nil {
-			// Restore the API key from the cache wrapper
+			// Restore the AI credentials from the cache wrapper
 			cacheData.AI.APIKey = cacheData.AIAPIKey
+			cacheData.AI.Headers = cacheData.AIHeaders
+			cacheData.AI.Fallbacks = cacheData.AIFallbacks
 			return &cacheData.ChatbotSettings, nil
 		}
 	}
@@ -71,10 +76,12 @@ func (a *App) getChatbotSettingsCached(orgID uuid.UUID, whatsAppAccount string)
 		return nil, result.Error
 	}
 
-	// Cache the result (include AI APIKey explicitly since it has json:"-" tag)
+	// Cache the result (include the AI credentials explicitly since they have json:"-" tags)
 	cacheData := chatbotSettingsCache{
 		ChatbotSettings: settings,
 		AIAPIKey:        settings.AI.APIKey,
+		AIHeaders:       settings.AI.Headers,
+		AIFallbacks:     settings.AI.Fallbacks,
 	}
 	if data, err := json.Marshal(cacheData); err == nil {
 		a.Redis.Set(ctx, cacheKey, data, settingsCacheTTL)
@@ -85,6 +92,10 @@ func (a *App) getChatbotSettingsCached(orgID uuid.UUID, whatsAppAccount string)
 
 // getChatbotFlowsCached retrieves all enabled flows with steps from cache or database
 func (a *App) getChatbotFlowsCached(orgID uuid.UUID) ([]models.ChatbotFlow, error) {
+	if a.simulation != nil {
+		return a.getSimulationFlows(orgID)
+	}
+
 	ctx := context.Background()
 	cacheKey := fmt.Sprintf("%s%s", flowsCachePrefix, orgID.String())
 
@@ -107,6 +118,12 @@ func (a *App) getChatbotFlowsCached(orgID uuid.UUID) ([]models.ChatbotFlow, erro
 		return nil, err
 	}
 
+	// Published flows run their published version, not the draft
+	flows, err = a.applyPublishedFlowVersions(orgID, flows)
+	if err != nil {
+		return nil, err
+	}
+
 	// Cache the result
 	if data, err := json.Marshal(flows); err == nil {
 		a.Redis.Set(ctx, cacheKey, data, flowsCacheTTL)
@@ -7,6 +7,7 @@ import (
 	"github.com/google/uuid"
 	"github.com/shridarpatil/whatomate/internal/contactutil"
 	"github.com/shridarpatil/whatomate/internal/models"
+	"github.com/shridarpatil/whatomate/internal/webhookutil"
 	"github.com/shridarpatil/whatomate/internal/websocket"
 )
 
@@ -182,9 +183,12 @@ func (a *App) processCallWebhook(phoneNumberID string, call interface{}) {
 		}
 		a.DB.Model(callLog).Updates(updates)
 
-		// Notify CallManager to clean up
-		if a.CallManager != nil {
+		// Notify CallManager to clean up. Its cleanup reports the call as
+		// ended once any recording is uploaded; without a session report it here.
+		if a.CallManager != nil && a.CallManager.GetSession(ce.ID) != nil {
 			a.CallManager.EndCall(ce.ID)
+		} else {
+			a.DispatchCallWebhook(models.WebhookEventCallEnded, callLog.ID)
 		}
 
 		disconnectedBy := string(callLog.DisconnectedBy)
@@ -214,6 +218,8 @@ func (a *App) processCallWebhook(phoneNumberID string, call interface{}) {
 			"ended_at":   now.Format(time.RFC3339),
 		})
 
+		a.DispatchCallWebhook(models.WebhookEventCallEnded, callLog.ID)
+
 	default:
 		a.Log.Warn("Unknown call event", "event", ce.Event, "call_id", ce.ID)
 	}
@@ -267,9 +273,21 @@ func (a *App) getOrCreateCallLog(account *models.WhatsAppAccount, contact *model
 	}
 
 	a.Log.Info("Created call log", "call_id", callID, "call_log_id", callLog.ID)
+
+	a.DispatchWebhook(account.OrganizationID, models.WebhookEventCallStarted, webhookutil.NewCallEventData(&callLog))
 	return &callLog
 }
 
+// DispatchCallWebhook sends a call event with the current state of the call log
+func (a *App) DispatchCallWebhook(event models.WebhookEvent, callLogID uuid.UUID) {
+	var callLog models.CallLog
+	if err := a.DB.Where("id = ?", callLogID).First(&callLog).Error; err != nil {
+		a.Log.Error("Failed to load call log for webhook", "error", err, "call_log_id", callLogID)
+		return
+	}
+	a.DispatchWebhook(callLog.OrganizationID, event, webhookutil.NewCallEventData(&callLog))
+}
+
 // handleOrphanedOutgoingCallEvent handles business-initiated call webhooks
 // when the session has already been cleaned up (e.g., terminate arrives after
 // PeerConnection closed). Updates the call log and broadcasts WebSocket events.
@@ -1,17 +1,20 @@
 package handlers
 
 import (
-	"fmt"
+	"context"
+	"errors"
 	"io"
-	"os"
+	"path"
 	"path/filepath"
 	"regexp"
-	"strings"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/shridarpatil/whatomate/internal/campaignutil"
+	"github.com/shridarpatil/whatomate/internal/contactutil"
 	"github.com/shridarpatil/whatomate/internal/models"
 	"github.com/shridarpatil/whatomate/internal/queue"
+	"github.com/shridarpatil/whatomate/internal/webhookutil"
 	"github.com/shridarpatil/whatomate/internal/websocket"
 	"github.com/valyala/fasthttp"
 	"github.com/zerodha/fastglue"
@@ -25,7 +28,18 @@ type CampaignRequest struct {
 	WhatsAppAccount string     `json:"whatsapp_account" validate:"required"`
 	TemplateID      string     `json:"template_id" validate:"required"`
 	HeaderMediaID   string     `json:"header_media_id"`
-	ScheduledAt     *time.Time `json:"scheduled_at"`
+	ScheduledAt     string     `json:"scheduled_at"` // Rejected; campaigns are scheduled with ScheduleCampaign
+}
+
+// msgCampaignScheduledAt is the error returned when a campaign is created or
+// updated with a schedule. A new campaign has no recipients yet, and only draft
+// campaigns take recipients, so it is scheduled once they have been added.
+const msgCampaignScheduledAt = "scheduled_at is not accepted here; add recipients, then schedule the campaign with POST /api/campaigns/{id}/schedule"
+
+// ScheduleCampaignRequest represents a campaign schedule/reschedule request
+type ScheduleCampaignRequest struct {
+	ScheduledAt string `json:"scheduled_at" validate:"required"`
+	Timezone    string `json:"timezone"`
 }
 
 // CampaignResponse represents campaign in API responses
@@ -45,7 +59,10 @@ type CampaignResponse struct {
 	ReadCount       int                  `json:"read_count"`
 	FailedCount     int                  `json:"failed_count"`
 	ScheduledAt     *time.Time           `json:"scheduled_at,omitempty"`
+	ScheduleTimezone string              `json:"schedule_timezone,omitempty"`
 	StartedAt       *time.Time           `json:"started_at,omitempty"`
+	StartedBy       *uuid.UUID           `json:"started_by,omitempty"`
+	StartTrigger    models.CampaignTrigger `json:"start_trigger,omitempty"`
 	CompletedAt     *time.Time           `json:"completed_at,omitempty"`
 	CreatedAt       time.Time            `json:"created_at"`
 	UpdatedAt       time.Time            `json:"updated_at"`
@@ -122,7 +139,10 @@ func (a *App) ListCampaigns(r *fastglue.Request) error {
 			ReadCount:           c.ReadCount,
 			FailedCount:         c.FailedCount,
 			ScheduledAt:         c.ScheduledAt,
+			ScheduleTimezone:    c.ScheduleTimezone,
 			StartedAt:           c.StartedAt,
+			StartedBy:           c.StartedBy,
+			StartTrigger:        c.StartTrigger,
 			CompletedAt:         c.CompletedAt,
 			CreatedAt:           c.CreatedAt,
 			UpdatedAt:           c.UpdatedAt,
@@ -168,6 +188,10 @@ func (a *App) CreateCampaign(r *fastglue.Request) error {
 		return r.SendErrorEnvelope(fasthttp.StatusBadRequest, "WhatsApp account not found", nil, "")
 	}
 
+	if req.ScheduledAt != "" {
+		return r.SendErrorEnvelope(fasthttp.StatusBadRequest, msgCampaignScheduledAt, nil, "")
+	}
+
 	campaign := models.BulkMessageCampaign{
 		OrganizationID:  orgID,
 		WhatsAppAccount: req.WhatsAppAccount,
@@ -175,7 +199,6 @@ func (a *App) CreateCampaign(r *fastglue.Request) error {
 		TemplateID:      templateID,
 		HeaderMediaID:  req.HeaderMediaID,
 		Status:          models.CampaignStatusDraft,
-		ScheduledAt:     req.ScheduledAt,
 		CreatedBy:       userID,
 	}
 
@@ -201,6 +224,7 @@ func (a *App) CreateCampaign(r *fastglue.Request) error {
 		DeliveredCount:      campaign.DeliveredCount,
 		FailedCount:         campaign.FailedCount,
 		ScheduledAt:         campaign.ScheduledAt,
+		ScheduleTimezone:    campaign.ScheduleTimezone,
 		CreatedAt:           campaign.CreatedAt,
 		UpdatedAt:           campaign.UpdatedAt,
 	})
@@ -239,7 +263,10 @@ func (a *App) GetCampaign(r *fastglue.Request) error {
 		DeliveredCount:      campaign.DeliveredCount,
 		FailedCount:         campaign.FailedCount,
 		ScheduledAt:         campaign.ScheduledAt,
+		ScheduleTimezone:    campaign.ScheduleTimezone,
 		StartedAt:           campaign.StartedAt,
+		StartedBy:           campaign.StartedBy,
+		StartTrigger:        campaign.StartTrigger,
 		CompletedAt:         campaign.CompletedAt,
 		CreatedAt:           campaign.CreatedAt,
 		UpdatedAt:           campaign.UpdatedAt,
@@ -278,10 +305,13 @@ func (a *App) UpdateCampaign(r *fastglue.Request) error {
 		return nil
 	}
 
+	if req.ScheduledAt != "" {
+		return r.SendErrorEnvelope(fasthttp.StatusBadRequest, msgCampaignScheduledAt, nil, "")
+	}
+
 	// Update fields
 	updates := map[string]interface{}{
-		"name":         req.Name,
-		"scheduled_at": req.ScheduledAt,
+		"name": req.Name,
 	}
 
 	if req.TemplateID != "" {
@@ -318,6 +348,7 @@ func (a *App) UpdateCampaign(r *fastglue.Request) error {
 		DeliveredCount:      campaign.DeliveredCount,
 		FailedCount:         campaign.FailedCount,
 		ScheduledAt:         campaign.ScheduledAt,
+		ScheduleTimezone:    campaign.ScheduleTimezone,
 		CreatedAt:           campaign.CreatedAt,
 		UpdatedAt:           campaign.UpdatedAt,
 	}
@@ -371,6 +402,51 @@ func (a *App) DeleteCampaign(r *fastglue.Request) error {
 
 // StartCampaign implements starting a campaign
 func (a *App) StartCampaign(r *fastglue.Request) error {
+	orgID, userID, err := a.getOrgAndUserID(r)
+	if err != nil {
+		return r.SendErrorEnvelope(fasthttp.StatusUnauthorized, "Unauthorized", nil, "")
+	}
+
+	id, err := parsePathUUID(r, "id", "campaign")
+	if err != nil {
+		return nil
+	}
+
+	campaign, err := findByIDAndOrg[models.BulkMessageCampaign](a.DB, r, id, orgID, "Campaign")
+	if err != nil {
+		return nil
+	}
+
+	count, err := campaignutil.Start(r.RequestCtx, a.DB, a.Queue, campaign, models.CampaignTriggerManual, &userID)
+	if err != nil {
+		switch {
+		case errors.Is(err, campaignutil.ErrNotStartable):
+			return r.SendErrorEnvelope(fasthttp.StatusBadRequest, "Campaign cannot be started in current state", nil, "")
+		case errors.Is(err, campaignutil.ErrNoPendingRecipients):
+			return r.SendErrorEnvelope(fasthttp.StatusBadRequest, "Campaign has no pending recipients", nil, "")
+		case errors.Is(err, campaignutil.ErrTemplateNotFound):
+			return r.SendErrorEnvelope(fasthttp.StatusBadRequest, "Campaign template no longer exists", nil, "")
+		case errors.Is(err, campaignutil.ErrEnqueueFailed):
+			a.Log.Error("Failed to enqueue recipients", "error", err, "campaign_id", id)
+			return r.SendErrorEnvelope(fasthttp.StatusInternalServerError, "Failed to queue recipients", nil, "")
+		default:
+			a.Log.Error("Failed to start campaign", "error", err, "campaign_id", id)
+			return r.SendErrorEnvelope(fasthttp.StatusInternalServerError, "Failed to start campaign", nil, "")
+		}
+	}
+
+	a.Log.Info("Campaign started", "campaign_id", id, "recipients", count, "started_by", userID)
+
+	a.DispatchWebhook(orgID, models.WebhookEventCampaignStarted, webhookutil.NewCampaignEventData(campaign))
+
+	return r.SendEnvelope(map[string]interface{}{
+		"message": "Campaign started",
+		"status":  models.CampaignStatusProcessing,
+	})
+}
+
+// ScheduleCampaign schedules (or reschedules) a campaign to start automatically
+func (a *App) ScheduleCampaign(r *fastglue.Request) error {
 	orgID, err := a.getOrgID(r)
 	if err != nil {
 		return r.SendErrorEnvelope(fasthttp.StatusUnauthorized, "Unauthorized", nil, "")
@@ -386,69 +462,91 @@ func (a *App) StartCampaign(r *fastglue.Request) error {
 		return nil
 	}
 
-	// Check if campaign can be started
-	if campaign.Status != models.CampaignStatusDraft && campaign.Status != models.CampaignStatusScheduled && campaign.Status != models.CampaignStatusPaused {
-		return r.SendErrorEnvelope(fasthttp.StatusBadRequest, "Campaign cannot be started in current state", nil, "")
+	if campaign.Status != models.CampaignStatusDraft && campaign.Status != models.CampaignStatusScheduled {
+		return r.SendErrorEnvelope(fasthttp.StatusBadRequest, "Can only schedule draft or scheduled campaigns", nil, "")
 	}
 
-	// Get all pending recipients
-	var recipients []models.BulkMessageRecipient
-	if err := a.DB.Where("campaign_id = ? AND status = ?", id, models.MessageStatusPending).Find(&recipients).Error; err != nil {
-		a.Log.Error("Failed to load recipients", "error", err)
-		return r.SendErrorEnvelope(fasthttp.StatusInternalServerError, "Failed to load recipients", nil, "")
+	var req ScheduleCampaignRequest
+	if err := a.decodeRequest(r, &req); err != nil {
+		return nil
 	}
 
-	if len(recipients) == 0 {
-		return r.SendErrorEnvelope(fasthttp.StatusBadRequest, "Campaign has no pending recipients", nil, "")
+	scheduledAt, err := campaignutil.ParseScheduleTime(req.ScheduledAt, req.Timezone)
+	if err != nil {
+		return r.SendErrorEnvelope(fasthttp.StatusBadRequest, err.Error(), nil, "")
+	}
+	if scheduledAt == nil {
+		return r.SendErrorEnvelope(fasthttp.StatusBadRequest, "scheduled_at is required", nil, "")
+	}
+	if !scheduledAt.After(time.Now()) {
+		return r.SendErrorEnvelope(fasthttp.StatusBadRequest, "scheduled_at must be in the future", nil, "")
 	}
 
-	// Validate template still exists
-	if campaign.TemplateID != uuid.Nil {
-		var template models.Template
-		if err := a.DB.Where("id = ? AND organization_id = ?", campaign.TemplateID, orgID).First(&template).Error; err != nil {
-			return r.SendErrorEnvelope(fasthttp.StatusBadRequest, "Campaign template no longer exists", nil, "")
-		}
+	var pendingCount int64
+	a.DB.Model(&models.BulkMessageRecipient{}).
+		Where("campaign_id = ? AND status = ?", id, models.MessageStatusPending).
+		Count(&pendingCount)
+	if pendingCount == 0 {
+		return r.SendErrorEnvelope(fasthttp.StatusBadRequest, "Campaign has no pending recipients", nil, "")
 	}
 
-	// Update status to processing
-	now := time.Now()
-	updates := map[string]interface{}{
-		"status":     models.CampaignStatusProcessing,
-		"started_at": now,
+	if err := a.DB.Model(campaign).Updates(map[string]interface{}{
+		"status":            models.CampaignStatusScheduled,
+		"scheduled_at":      scheduledAt,
+		"schedule_timezone": req.Timezone,
+	}).Error; err != nil {
+		a.Log.Error("Failed to schedule campaign", "error", err)
+		return r.SendErrorEnvelope(fasthttp.StatusInternalServerError, "Failed to schedule campaign", nil, "")
 	}
 
-	if err := a.DB.Model(campaign).Updates(updates).Error; err != nil {
-		a.Log.Error("Failed to start campaign", "error", err)
-		return r.SendErrorEnvelope(fasthttp.StatusInternalServerError, "Failed to start campaign", nil, "")
+	a.Log.Info("Campaign scheduled", "campaign_id", id, "scheduled_at", scheduledAt, "timezone", req.Timezone)
+
+	return r.SendEnvelope(map[string]interface{}{
+		"message":           "Campaign scheduled",
+		"status":            models.CampaignStatusScheduled,
+		"scheduled_at":      scheduledAt,
+		"schedule_timezone": req.Timezone,
+	})
+}
+
+// UnscheduleCampaign cancels a pending schedule and returns the campaign to draft
+func (a *App) UnscheduleCampaign(r *fastglue.Request) error {
+	orgID, err := a.getOrgID(r)
+	if err != nil {
+		return r.SendErrorEnvelope(fasthttp.StatusUnauthorized, "Unauthorized", nil, "")
 	}
 
-	a.Log.Info("Campaign started", "campaign_id", id, "recipients", len(recipients))
+	id, err := parsePathUUID(r, "id", "campaign")
+	if err != nil {
+		return nil
+	}
 
-	// Enqueue all recipients as individual jobs for parallel processing
-	jobs := make([]*queue.RecipientJob, len(recipients))
-	for i, recipient := range recipients {
-		jobs[i] = &queue.RecipientJob{
-			CampaignID:     id,
-			RecipientID:    recipient.ID,
-			OrganizationID: orgID,
-			PhoneNumber:    recipient.PhoneNumber,
-			RecipientName:  recipient.RecipientName,
-			TemplateParams: recipient.TemplateParams,
-		}
+	campaign, err := findByIDAndOrg[models.BulkMessageCampaign](a.DB, r, id, orgID, "Campaign")
+	if err != nil {
+		return nil
 	}
 
-	if err := a.Queue.EnqueueRecipients(r.RequestCtx, jobs); err != nil {
-		a.Log.Error("Failed to enqueue recipients", "error", err)
-		// Revert status on failure
-		a.DB.Model(campaign).Update("status", models.CampaignStatusDraft)
-		return r.SendErrorEnvelope(fasthttp.StatusInternalServerError, "Failed to queue recipients", nil, "")
+	// Guard on status so we don't race with the scheduler picking it up
+	result := a.DB.Model(&models.BulkMessageCampaign{}).
+		Where("id = ? AND status = ?", campaign.ID, models.CampaignStatusScheduled).
+		Updates(map[string]interface{}{
+			"status":            models.CampaignStatusDraft,
+			"scheduled_at":      nil,
+			"schedule_timezone": "",
+		})
+	if result.Error != nil {
+		a.Log.Error("Failed to unschedule campaign", "error", result.Error)
+		return r.SendErrorEnvelope(fasthttp.StatusInternalServerError, "Failed to unschedule campaign", nil, "")
+	}
+	if result.RowsAffected == 0 {
+		return r.SendErrorEnvelope(fasthttp.StatusBadRequest, "Campaign is not scheduled", nil, "")
 	}
 
-	a.Log.Info("Recipients enqueued for processing", "campaign_id", id, "count", len(jobs))
+	a.Log.Info("Campaign unscheduled", "campaign_id", id)
 
 	return r.SendEnvelope(map[string]interface{}{
-		"message": "Campaign started",
-		"status":  models.CampaignStatusProcessing,
+		"message": "Campaign unscheduled",
+		"status":  models.CampaignStatusDraft,
 	})
 }
 
@@ -640,21 +738,40 @@ func (a *App) ImportRecipients(r *fastglue.Request) error {
 		return nil
 	}
 
+	// Numbers that opted out never receive marketing templates
+	optedOut := map[string]bool{}
+	var template models.Template
+	if err := a.DB.Where("id = ? AND organization_id = ?", campaign.TemplateID, orgID).First(&template).Error; err == nil && template.IsMarketing() {
+		phones := make([]string, len(req.Recipients))
+		for i, rec := range req.Recipients {
+			phones[i] = rec.PhoneNumber
+		}
+		if optedOut, err = contactutil.OptedOutPhones(a.DB, orgID, phones); err != nil {
+			a.Log.Error("Failed to check opted-out recipients", "error", err)
+			return r.SendErrorEnvelope(fasthttp.StatusInternalServerError, "Failed to add recipients", nil, "")
+		}
+	}
+
 	// Create recipients
-	recipients := make([]models.BulkMessageRecipient, len(req.Recipients))
-	for i, rec := range req.Recipients {
-		recipients[i] = models.BulkMessageRecipient{
+	recipients := make([]models.BulkMessageRecipient, 0, len(req.Recipients))
+	for _, rec := range req.Recipients {
+		if optedOut[rec.PhoneNumber] {
+			continue
+		}
+		recipients = append(recipients, models.BulkMessageRecipient{
 			CampaignID:     id,
 			PhoneNumber:    rec.PhoneNumber,
 			RecipientName:  rec.RecipientName,
 			TemplateParams: models.JSONB(rec.TemplateParams),
 			Status:         models.MessageStatusPending,
-		}
+		})
 	}
 
-	if err := a.DB.Create(&recipients).Error; err != nil {
-		a.Log.Error("Failed to add recipients", "error", err)
-		return r.SendErrorEnvelope(fasthttp.StatusInternalServerError, "Failed to add recipients", nil, "")
+	if len(recipients) > 0 {
+		if err := a.DB.Create(&recipients).Error; err != nil {
+			a.Log.Error("Failed to add recipients", "error", err)
+			return r.SendErrorEnvelope(fasthttp.StatusInternalServerError, "Failed to add recipients", nil, "")
+		}
 	}
 
 	// Update total recipients count
@@ -662,15 +779,179 @@ func (a *App) ImportRecipients(r *fastglue.Request) error {
 	a.DB.Model(&models.BulkMessageRecipient{}).Where("campaign_id = ?", id).Count(&totalCount)
 	a.DB.Model(campaign).Update("total_recipients", totalCount)
 
-	a.Log.Info("Recipients added to campaign", "campaign_id", id, "count", len(req.Recipients))
+	excludedCount := len(req.Recipients) - len(recipients)
+	a.Log.Info("Recipients added to campaign", "campaign_id", id, "count", len(recipients), "opted_out", excludedCount)
 
 	return r.SendEnvelope(map[string]interface{}{
 		"message":          "Recipients added successfully",
-		"added_count":      len(req.Recipients),
+		"added_count":      len(recipients),
+		"excluded_count":   excludedCount,
 		"total_recipients": totalCount,
 	})
 }
 
+// ReplayDeadLettersRequest selects dead-lettered jobs to replay; empty replays all
+type ReplayDeadLettersRequest struct {
+	IDs []string `json:"ids"`
+}
+
+// deadLetterListLimit caps how many dead letters are listed or replayed per request
+const deadLetterListLimit = 1000
+
+// ListCampaignDeadLetters returns recipient jobs that exhausted their send retries
+func (a *App) ListCampaignDeadLetters(r *fastglue.Request) error {
+	orgID, err := a.getOrgID(r)
+	if err != nil {
+		return r.SendErrorEnvelope(fasthttp.StatusUnauthorized, "Unauthorized", nil, "")
+	}
+
+	id, err := parsePathUUID(r, "id", "campaign")
+	if err != nil {
+		return nil
+	}
+
+	if _, err := findByIDAndOrg[models.BulkMessageCampaign](a.DB, r, id, orgID, "Campaign"); err != nil {
+		return nil
+	}
+
+	pg := parsePaginationWithDefaults(r, 100, deadLetterListLimit)
+	total, err := a.Queue.CountDeadLetters(r.RequestCtx, id)
+	if err != nil {
+		a.Log.Error("Failed to count dead letters", "error", err, "campaign_id", id)
+		return r.SendErrorEnvelope(fasthttp.StatusInternalServerError, "Failed to list dead letters", nil, "")
+	}
+	letters, err := a.Queue.ListDeadLetters(r.RequestCtx, id, pg.Offset, pg.Limit)
+	if err != nil {
+		a.Log.Error("Failed to list dead letters", "error", err, "campaign_id", id)
+		return r.SendErrorEnvelope(fasthttp.StatusInternalServerError, "Failed to list dead letters", nil, "")
+	}
+
+	if a.ShouldMaskPhoneNumbers(orgID) {
+		for i := range letters {
+			letters[i].Job.PhoneNumber = MaskPhoneNumber(letters[i].Job.PhoneNumber)
+			letters[i].Job.RecipientName = MaskIfPhoneNumber(letters[i].Job.RecipientName)
+		}
+	}
+
+	return r.SendEnvelope(map[string]interface{}{
+		"dead_letters": letters,
+		"total":        total,
+		"page":         pg.Page,
+		"limit":        pg.Limit,
+	})
+}
+
+// ReplayCampaignDeadLetters re-enqueues dead-lettered jobs with a fresh retry budget
+func (a *App) ReplayCampaignDeadLetters(r *fastglue.Request) error {
+	orgID, err := a.getOrgID(r)
+	if err != nil {
+		return r.SendErrorEnvelope(fasthttp.StatusUnauthorized, "Unauthorized", nil, "")
+	}
+
+	id, err := parsePathUUID(r, "id", "campaign")
+	if err != nil {
+		return nil
+	}
+
+	campaign, err := findByIDAndOrg[models.BulkMessageCampaign](a.DB, r, id, orgID, "Campaign")
+	if err != nil {
+		return nil
+	}
+
+	if campaign.Status == models.CampaignStatusCancelled {
+		return r.SendErrorEnvelope(fasthttp.StatusBadRequest, "Cannot replay messages for a cancelled campaign", nil, "")
+	}
+
+	var req ReplayDeadLettersRequest
+	if len(r.RequestCtx.PostBody()) > 0 {
+		if err := a.decodeRequest(r, &req); err != nil {
+			return nil
+		}
+	}
+
+	letters, err := a.Queue.ListDeadLetters(r.RequestCtx, id, 0, deadLetterListLimit)
+	if err != nil {
+		a.Log.Error("Failed to list dead letters", "error", err, "campaign_id", id)
+		return r.SendErrorEnvelope(fasthttp.StatusInternalServerError, "Failed to list dead letters", nil, "")
+	}
+
+	selected := make(map[string]bool, len(req.IDs))
+	for _, dlID := range req.IDs {
+		selected[dlID] = true
+	}
+
+	var (
+		jobs         []*queue.RecipientJob
+		ids          []string
+		recipientIDs []uuid.UUID
+	)
+	for _, dl := range letters {
+		if len(selected) > 0 && !selected[dl.ID] {
+			continue
+		}
+		job := *dl.Job
+		job.Attempt = 0
+		jobs = append(jobs, &job)
+		ids = append(ids, dl.ID)
+		recipientIDs = append(recipientIDs, job.RecipientID)
+	}
+
+	if len(jobs) == 0 {
+		return r.SendErrorEnvelope(fasthttp.StatusBadRequest, "No dead letters to replay", nil, "")
+	}
+
+	// Reset the recipients and their failed messages so stats reflect the replay
+	if err := a.DB.Model(&models.BulkMessageRecipient{}).
+		Where("campaign_id = ? AND id IN ?", id, recipientIDs).
+		Updates(map[string]interface{}{
+			"status":        models.MessageStatusPending,
+			"error_message": "",
+		}).Error; err != nil {
+		a.Log.Error("Failed to reset dead-lettered recipients", "error", err)
+		return r.SendErrorEnvelope(fasthttp.StatusInternalServerError, "Failed to reset recipients", nil, "")
+	}
+
+	recipientIDStrs := make([]string, len(recipientIDs))
+	for i, rid := range recipientIDs {
+		recipientIDStrs[i] = rid.String()
+	}
+	if err := a.DB.Model(&models.Message{}).
+		Where("metadata->>'campaign_id' = ? AND metadata->>'recipient_id' IN ? AND status = ?", id.String(), recipientIDStrs, models.MessageStatusFailed).
+		Updates(map[string]interface{}{
+			"status":        models.MessageStatusPending,
+			"error_message": "",
+		}).Error; err != nil {
+		a.Log.Error("Failed to reset failed messages", "error", err)
+	}
+
+	a.recalculateCampaignStats(id)
+
+	if campaign.Status == models.CampaignStatusCompleted || campaign.Status == models.CampaignStatusFailed {
+		if err := a.DB.Model(campaign).Update("status", models.CampaignStatusProcessing).Error; err != nil {
+			a.Log.Error("Failed to update campaign status", "error", err)
+			return r.SendErrorEnvelope(fasthttp.StatusInternalServerError, "Failed to update campaign", nil, "")
+		}
+		campaign.Status = models.CampaignStatusProcessing
+	}
+
+	if err := a.Queue.EnqueueRecipients(r.RequestCtx, jobs); err != nil {
+		a.Log.Error("Failed to enqueue dead letters for replay", "error", err)
+		return r.SendErrorEnvelope(fasthttp.StatusInternalServerError, "Failed to queue recipients", nil, "")
+	}
+
+	if err := a.Queue.RemoveDeadLetters(r.RequestCtx, id, ids...); err != nil {
+		a.Log.Error("Failed to remove replayed dead letters", "error", err, "campaign_id", id)
+	}
+
+	a.Log.Info("Dead letters replayed", "campaign_id", id, "count", len(jobs))
+
+	return r.SendEnvelope(map[string]interface{}{
+		"message":      "Replaying dead-lettered messages",
+		"replay_count": len(jobs),
+		"status":       campaign.Status,
+	})
+}
+
 // GetCampaignRecipients implements listing campaign recipients
 func (a *App) GetCampaignRecipients(r *fastglue.Request) error {
 	orgID, err := a.getOrgID(r)
@@ -847,7 +1128,7 @@ func (a *App) UploadCampaignMedia(r *fastglue.Request) error {
 	}
 
 	// Save file locally for preview
-	localPath, err := a.saveCampaignMedia(campaignUUID.String(), data, mimeType)
+	localPath, err := a.saveCampaignMedia(ctx, campaignUUID.String(), data, mimeType)
 	if err != nil {
 		a.Log.Error("Failed to save media locally", "error", err)
 		// Don't fail the request, just log the error - preview won't work
@@ -876,34 +1157,23 @@ func (a *App) UploadCampaignMedia(r *fastglue.Request) error {
 	})
 }
 
-// saveCampaignMedia saves uploaded media locally for preview
-func (a *App) saveCampaignMedia(campaignID string, data []byte, mimeType string) (string, error) {
+// saveCampaignMedia saves uploaded media to media storage for preview
+func (a *App) saveCampaignMedia(ctx context.Context, campaignID string, data []byte, mimeType string) (string, error) {
 	// Determine file extension
 	ext := getExtensionFromMimeType(mimeType)
 	if ext == "" {
 		ext = ".bin"
 	}
 
-	// Create campaigns media directory
-	subdir := "campaigns"
-	if err := a.ensureMediaDir(subdir); err != nil {
-		return "", fmt.Errorf("failed to create media directory: %w", err)
+	// Store under campaigns/ using the campaign ID as filename
+	key := path.Join("campaigns", campaignID+ext)
+	if err := a.storeMedia(ctx, key, data, mimeType); err != nil {
+		return "", err
 	}
 
-	// Generate filename using campaign ID
-	filename := campaignID + ext
-	filePath := filepath.Join(a.getMediaStoragePath(), subdir, filename)
+	a.Log.Info("Campaign media saved", "path", key, "size", len(data))
 
-	// Save file
-	if err := os.WriteFile(filePath, data, 0644); err != nil {
-		return "", fmt.Errorf("failed to save media file: %w", err)
-	}
-
-	// Return relative path for storage
-	relativePath := filepath.Join(subdir, filename)
-	a.Log.Info("Campaign media saved locally", "path", relativePath, "size", len(data))
-
-	return relativePath, nil
+	return key, nil
 }
 
 // ServeCampaignMedia serves campaign media files for preview
@@ -931,46 +1201,8 @@ func (a *App) ServeCampaignMedia(r *fastglue.Request) error {
 		return r.SendErrorEnvelope(fasthttp.StatusNotFound, "No media found", nil, "")
 	}
 
-	// Security: prevent directory traversal and symlink attacks
-	filePath := filepath.Clean(campaign.HeaderMediaLocalPath)
-	baseDir, err := filepath.Abs(a.getMediaStoragePath())
-	if err != nil {
-		a.Log.Error("Storage configuration error", "error", err)
-		return r.SendErrorEnvelope(fasthttp.StatusInternalServerError, "Storage configuration error", nil, "")
-	}
-	fullPath, err := filepath.Abs(filepath.Join(baseDir, filePath))
-	if err != nil || !strings.HasPrefix(fullPath, baseDir+string(os.PathSeparator)) {
-		return r.SendErrorEnvelope(fasthttp.StatusBadRequest, "Invalid file path", nil, "")
-	}
-
-	// Reject symlinks
-	info, err := os.Lstat(fullPath)
-	if err != nil {
-		return r.SendErrorEnvelope(fasthttp.StatusNotFound, "File not found", nil, "")
-	}
-	if info.Mode()&os.ModeSymlink != 0 {
-		return r.SendErrorEnvelope(fasthttp.StatusBadRequest, "Invalid file path", nil, "")
-	}
-
-	// Read file
-	data, err := os.ReadFile(fullPath)
-	if err != nil {
-		a.Log.Error("Failed to read media file", "path", fullPath, "error", err)
-		return r.SendErrorEnvelope(fasthttp.StatusInternalServerError, "Failed to read file", nil, "")
-	}
-
-	// Use stored mime type or determine from extension
-	contentType := campaign.HeaderMediaMimeType
-	if contentType == "" {
-		ext := strings.ToLower(filepath.Ext(filePath))
-		contentType = getMimeTypeFromExtension(ext)
-	}
-
-	r.RequestCtx.Response.Header.Set("Content-Type", contentType)
-	r.RequestCtx.Response.Header.Set("Cache-Control", "private, max-age=3600")
-	r.RequestCtx.SetBody(data)
-
-	return nil
+	// Use stored mime type, otherwise it is determined from the extension
+	return a.serveStoredMedia(r, campaign.HeaderMediaLocalPath, campaign.HeaderMediaMimeType)
 }
 
 // getMimeTypeFromExtension returns MIME type from file extension
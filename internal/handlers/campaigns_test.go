@@ -1,6 +1,7 @@
 package handlers_test
 
 import (
+	"context"
 	"encoding/json"
 	"testing"
 	"time"
@@ -8,6 +9,7 @@ import (
 	"github.com/google/uuid"
 	"github.com/shridarpatil/whatomate/internal/handlers"
 	"github.com/shridarpatil/whatomate/internal/models"
+	"github.com/shridarpatil/whatomate/internal/queue"
 	"github.com/shridarpatil/whatomate/test/testutil"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -171,16 +173,15 @@ func TestApp_CreateCampaign_WithScheduledAt(t *testing.T) {
 	})
 	testutil.SetAuthContext(req, org.ID, user.ID)
 
+	// A draft with a schedule would never be dispatched, so it is scheduled
+	// separately once it has recipients
 	err := app.CreateCampaign(req)
 	require.NoError(t, err)
-	assert.Equal(t, fasthttp.StatusOK, testutil.GetResponseStatusCode(req))
+	assert.Equal(t, fasthttp.StatusBadRequest, testutil.GetResponseStatusCode(req))
 
-	var resp struct {
-		Data handlers.CampaignResponse `json:"data"`
-	}
-	err = json.Unmarshal(testutil.GetResponseBody(req), &resp)
-	require.NoError(t, err)
-	assert.NotNil(t, resp.Data.ScheduledAt)
+	var count int64
+	app.DB.Model(&models.BulkMessageCampaign{}).Where("organization_id = ?", org.ID).Count(&count)
+	assert.Zero(t, count)
 }
 
 func TestApp_CreateCampaign_InvalidTemplateID(t *testing.T) {
@@ -366,6 +367,33 @@ func TestApp_UpdateCampaign_NotDraft(t *testing.T) {
 	assert.Equal(t, fasthttp.StatusBadRequest, testutil.GetResponseStatusCode(req))
 }
 
+func TestApp_UpdateCampaign_RejectsScheduledAt(t *testing.T) {
+	app := newTestApp(t, withQueue(testutil.NewMockQueue()))
+	org := testutil.CreateTestOrganization(t, app.DB)
+	user := testutil.CreateTestUser(t, app.DB, org.ID, testutil.WithEmail(testutil.UniqueEmail("update-scheduled")), testutil.WithPassword("password"))
+	account := testutil.CreateTestWhatsAppAccountWith(t, app.DB, org.ID, testutil.WithAccountName("update-scheduled-account"))
+	template := testutil.CreateTestTemplate(t, app.DB, org.ID, account.Name)
+	campaign := createTestCampaign(t, app, org.ID, template.ID, user.ID, account.Name, models.CampaignStatusDraft)
+
+	req := testutil.NewJSONRequest(t, map[string]interface{}{
+		"name":             "Updated Name",
+		"whatsapp_account": account.Name,
+		"template_id":      template.ID.String(),
+		"scheduled_at":     time.Now().Add(time.Hour).Format(time.RFC3339),
+	})
+	testutil.SetAuthContext(req, org.ID, user.ID)
+	testutil.SetPathParam(req, "id", campaign.ID.String())
+
+	err := app.UpdateCampaign(req)
+	require.NoError(t, err)
+	assert.Equal(t, fasthttp.StatusBadRequest, testutil.GetResponseStatusCode(req))
+
+	var updated models.BulkMessageCampaign
+	require.NoError(t, app.DB.Where("id = ?", campaign.ID).First(&updated).Error)
+	assert.Equal(t, models.CampaignStatusDraft, updated.Status)
+	assert.Nil(t, updated.ScheduledAt)
+}
+
 func TestApp_UpdateCampaign_NotFound(t *testing.T) {
 	mockQueue := testutil.NewMockQueue()
 	app := newTestApp(t, withQueue(mockQueue))
@@ -889,3 +917,259 @@ func TestApp_Campaign_CrossOrgIsolation(t *testing.T) {
 	require.NoError(t, err)
 	assert.Equal(t, fasthttp.StatusNotFound, testutil.GetResponseStatusCode(req))
 }
+
+// --- Schedule / Unschedule Tests ---
+
+func TestApp_ScheduleCampaign_Success(t *testing.T) {
+	app := newTestApp(t, withQueue(testutil.NewMockQueue()))
+	org := testutil.CreateTestOrganization(t, app.DB)
+	user := testutil.CreateTestUser(t, app.DB, org.ID, testutil.WithEmail(testutil.UniqueEmail("schedule-campaign")), testutil.WithPassword("password"))
+	account := testutil.CreateTestWhatsAppAccountWith(t, app.DB, org.ID, testutil.WithAccountName("schedule-account"))
+	template := testutil.CreateTestTemplate(t, app.DB, org.ID, account.Name)
+	campaign := createTestCampaign(t, app, org.ID, template.ID, user.ID, account.Name, models.CampaignStatusDraft)
+	createTestRecipient(t, app, campaign.ID, "+1234567890", models.MessageStatusPending)
+
+	local := time.Now().Add(48 * time.Hour).Format("2006-01-02T15:04")
+	req := testutil.NewJSONRequest(t, map[string]interface{}{
+		"scheduled_at": local,
+		"timezone":     "Asia/Kolkata",
+	})
+	testutil.SetAuthContext(req, org.ID, user.ID)
+	testutil.SetPathParam(req, "id", campaign.ID.String())
+
+	err := app.ScheduleCampaign(req)
+	require.NoError(t, err)
+	assert.Equal(t, fasthttp.StatusOK, testutil.GetResponseStatusCode(req))
+
+	var updated models.BulkMessageCampaign
+	app.DB.Where("id = ?", campaign.ID).First(&updated)
+	assert.Equal(t, models.CampaignStatusScheduled, updated.Status)
+	assert.Equal(t, "Asia/Kolkata", updated.ScheduleTimezone)
+	require.NotNil(t, updated.ScheduledAt)
+
+	loc, _ := time.LoadLocation("Asia/Kolkata")
+	assert.Equal(t, local, updated.ScheduledAt.In(loc).Format("2006-01-02T15:04"))
+}
+
+func TestApp_ScheduleCampaign_PastTime(t *testing.T) {
+	app := newTestApp(t, withQueue(testutil.NewMockQueue()))
+	org := testutil.CreateTestOrganization(t, app.DB)
+	user := testutil.CreateTestUser(t, app.DB, org.ID, testutil.WithEmail(testutil.UniqueEmail("schedule-past")), testutil.WithPassword("password"))
+	account := testutil.CreateTestWhatsAppAccountWith(t, app.DB, org.ID, testutil.WithAccountName("schedule-past-account"))
+	template := testutil.CreateTestTemplate(t, app.DB, org.ID, account.Name)
+	campaign := createTestCampaign(t, app, org.ID, template.ID, user.ID, account.Name, models.CampaignStatusDraft)
+	createTestRecipient(t, app, campaign.ID, "+1234567890", models.MessageStatusPending)
+
+	req := testutil.NewJSONRequest(t, map[string]interface{}{
+		"scheduled_at": time.Now().Add(-time.Hour).Format(time.RFC3339),
+	})
+	testutil.SetAuthContext(req, org.ID, user.ID)
+	testutil.SetPathParam(req, "id", campaign.ID.String())
+
+	err := app.ScheduleCampaign(req)
+	require.NoError(t, err)
+	assert.Equal(t, fasthttp.StatusBadRequest, testutil.GetResponseStatusCode(req))
+}
+
+func TestApp_ScheduleCampaign_NoRecipients(t *testing.T) {
+	app := newTestApp(t, withQueue(testutil.NewMockQueue()))
+	org := testutil.CreateTestOrganization(t, app.DB)
+	user := testutil.CreateTestUser(t, app.DB, org.ID, testutil.WithEmail(testutil.UniqueEmail("schedule-empty")), testutil.WithPassword("password"))
+	account := testutil.CreateTestWhatsAppAccountWith(t, app.DB, org.ID, testutil.WithAccountName("schedule-empty-account"))
+	template := testutil.CreateTestTemplate(t, app.DB, org.ID, account.Name)
+	campaign := createTestCampaign(t, app, org.ID, template.ID, user.ID, account.Name, models.CampaignStatusDraft)
+
+	req := testutil.NewJSONRequest(t, map[string]interface{}{
+		"scheduled_at": time.Now().Add(time.Hour).Format(time.RFC3339),
+	})
+	testutil.SetAuthContext(req, org.ID, user.ID)
+	testutil.SetPathParam(req, "id", campaign.ID.String())
+
+	err := app.ScheduleCampaign(req)
+	require.NoError(t, err)
+	assert.Equal(t, fasthttp.StatusBadRequest, testutil.GetResponseStatusCode(req))
+}
+
+func TestApp_UnscheduleCampaign_Success(t *testing.T) {
+	app := newTestApp(t, withQueue(testutil.NewMockQueue()))
+	org := testutil.CreateTestOrganization(t, app.DB)
+	user := testutil.CreateTestUser(t, app.DB, org.ID, testutil.WithEmail(testutil.UniqueEmail("unschedule")), testutil.WithPassword("password"))
+	account := testutil.CreateTestWhatsAppAccountWith(t, app.DB, org.ID, testutil.WithAccountName("unschedule-account"))
+	template := testutil.CreateTestTemplate(t, app.DB, org.ID, account.Name)
+	campaign := createTestCampaign(t, app, org.ID, template.ID, user.ID, account.Name, models.CampaignStatusScheduled)
+	app.DB.Model(campaign).Update("scheduled_at", time.Now().Add(time.Hour))
+
+	req := testutil.NewJSONRequest(t, nil)
+	testutil.SetAuthContext(req, org.ID, user.ID)
+	testutil.SetPathParam(req, "id", campaign.ID.String())
+
+	err := app.UnscheduleCampaign(req)
+	require.NoError(t, err)
+	assert.Equal(t, fasthttp.StatusOK, testutil.GetResponseStatusCode(req))
+
+	var updated models.BulkMessageCampaign
+	app.DB.Where("id = ?", campaign.ID).First(&updated)
+	assert.Equal(t, models.CampaignStatusDraft, updated.Status)
+	assert.Nil(t, updated.ScheduledAt)
+}
+
+func TestApp_UnscheduleCampaign_NotScheduled(t *testing.T) {
+	app := newTestApp(t, withQueue(testutil.NewMockQueue()))
+	org := testutil.CreateTestOrganization(t, app.DB)
+	user := testutil.CreateTestUser(t, app.DB, org.ID, testutil.WithEmail(testutil.UniqueEmail("unschedule-draft")), testutil.WithPassword("password"))
+	account := testutil.CreateTestWhatsAppAccountWith(t, app.DB, org.ID, testutil.WithAccountName("unschedule-draft-account"))
+	template := testutil.CreateTestTemplate(t, app.DB, org.ID, account.Name)
+	campaign := createTestCampaign(t, app, org.ID, template.ID, user.ID, account.Name, models.CampaignStatusDraft)
+
+	req := testutil.NewJSONRequest(t, nil)
+	testutil.SetAuthContext(req, org.ID, user.ID)
+	testutil.SetPathParam(req, "id", campaign.ID.String())
+
+	err := app.UnscheduleCampaign(req)
+	require.NoError(t, err)
+	assert.Equal(t, fasthttp.StatusBadRequest, testutil.GetResponseStatusCode(req))
+}
+
+func TestApp_ListCampaignDeadLetters_Success(t *testing.T) {
+	mockQueue := testutil.NewMockQueue()
+	app := newTestApp(t, withQueue(mockQueue))
+	org := testutil.CreateTestOrganization(t, app.DB)
+	user := testutil.CreateTestUser(t, app.DB, org.ID, testutil.WithEmail(testutil.UniqueEmail("dlq-list")), testutil.WithPassword("password"))
+	account := testutil.CreateTestWhatsAppAccountWith(t, app.DB, org.ID, testutil.WithAccountName("dlq-list-account"))
+	template := testutil.CreateTestTemplate(t, app.DB, org.ID, account.Name)
+	campaign := createTestCampaign(t, app, org.ID, template.ID, user.ID, account.Name, models.CampaignStatusCompleted)
+	recipient := createTestRecipient(t, app, campaign.ID, "+1234567890", models.MessageStatusFailed)
+
+	require.NoError(t, mockQueue.DeadLetterRecipient(context.Background(), &queue.RecipientJob{
+		CampaignID:  campaign.ID,
+		RecipientID: recipient.ID,
+		PhoneNumber: recipient.PhoneNumber,
+		Attempt:     6,
+	}, "API error 130429: Rate limit hit"))
+	// Another campaign's dead letter must not be listed
+	require.NoError(t, mockQueue.DeadLetterRecipient(context.Background(), &queue.RecipientJob{CampaignID: uuid.New()}, "other"))
+
+	req := testutil.NewJSONRequest(t, nil)
+	testutil.SetAuthContext(req, org.ID, user.ID)
+	testutil.SetPathParam(req, "id", campaign.ID.String())
+
+	require.NoError(t, app.ListCampaignDeadLetters(req))
+	assert.Equal(t, fasthttp.StatusOK, testutil.GetResponseStatusCode(req))
+
+	var resp struct {
+		Data struct {
+			DeadLetters []queue.DeadLetter `json:"dead_letters"`
+			Total       int                `json:"total"`
+		} `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(testutil.GetResponseBody(req), &resp))
+	require.Equal(t, 1, resp.Data.Total)
+	assert.Equal(t, recipient.ID, resp.Data.DeadLetters[0].Job.RecipientID)
+	assert.Equal(t, 6, resp.Data.DeadLetters[0].Job.Attempt)
+	assert.Contains(t, resp.Data.DeadLetters[0].Error, "Rate limit")
+}
+
+func TestApp_ListCampaignDeadLetters_Pagination(t *testing.T) {
+	mockQueue := testutil.NewMockQueue()
+	app := newTestApp(t, withQueue(mockQueue))
+	org := testutil.CreateTestOrganization(t, app.DB)
+	user := testutil.CreateTestUser(t, app.DB, org.ID, testutil.WithEmail(testutil.UniqueEmail("dlq-page")), testutil.WithPassword("password"))
+	account := testutil.CreateTestWhatsAppAccountWith(t, app.DB, org.ID, testutil.WithAccountName("dlq-page-account"))
+	template := testutil.CreateTestTemplate(t, app.DB, org.ID, account.Name)
+	campaign := createTestCampaign(t, app, org.ID, template.ID, user.ID, account.Name, models.CampaignStatusCompleted)
+
+	for i := 0; i < 3; i++ {
+		require.NoError(t, mockQueue.DeadLetterRecipient(context.Background(), &queue.RecipientJob{
+			CampaignID:  campaign.ID,
+			RecipientID: uuid.New(),
+		}, "failed"))
+	}
+
+	req := testutil.NewGETRequest(t)
+	testutil.SetAuthContext(req, org.ID, user.ID)
+	testutil.SetPathParam(req, "id", campaign.ID.String())
+	testutil.SetQueryParam(req, "page", 2)
+	testutil.SetQueryParam(req, "limit", 2)
+
+	require.NoError(t, app.ListCampaignDeadLetters(req))
+	assert.Equal(t, fasthttp.StatusOK, testutil.GetResponseStatusCode(req))
+
+	var resp struct {
+		Data struct {
+			DeadLetters []queue.DeadLetter `json:"dead_letters"`
+			Total       int                `json:"total"`
+			Page        int                `json:"page"`
+			Limit       int                `json:"limit"`
+		} `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(testutil.GetResponseBody(req), &resp))
+	assert.Equal(t, 3, resp.Data.Total, "total is the stream length, not the page size")
+	require.Len(t, resp.Data.DeadLetters, 1)
+	assert.Equal(t, mockQueue.DeadLetters[2].ID, resp.Data.DeadLetters[0].ID)
+	assert.Equal(t, 2, resp.Data.Page)
+	assert.Equal(t, 2, resp.Data.Limit)
+}
+
+func TestApp_ReplayCampaignDeadLetters_Success(t *testing.T) {
+	mockQueue := testutil.NewMockQueue()
+	app := newTestApp(t, withQueue(mockQueue))
+	org := testutil.CreateTestOrganization(t, app.DB)
+	user := testutil.CreateTestUser(t, app.DB, org.ID, testutil.WithEmail(testutil.UniqueEmail("dlq-replay")), testutil.WithPassword("password"))
+	account := testutil.CreateTestWhatsAppAccountWith(t, app.DB, org.ID, testutil.WithAccountName("dlq-replay-account"))
+	template := testutil.CreateTestTemplate(t, app.DB, org.ID, account.Name)
+	campaign := createTestCampaign(t, app, org.ID, template.ID, user.ID, account.Name, models.CampaignStatusCompleted)
+	first := createTestRecipient(t, app, campaign.ID, "+1111111111", models.MessageStatusFailed)
+	second := createTestRecipient(t, app, campaign.ID, "+2222222222", models.MessageStatusFailed)
+
+	for _, rcpt := range []*models.BulkMessageRecipient{first, second} {
+		require.NoError(t, mockQueue.DeadLetterRecipient(context.Background(), &queue.RecipientJob{
+			CampaignID:     campaign.ID,
+			RecipientID:    rcpt.ID,
+			OrganizationID: org.ID,
+			PhoneNumber:    rcpt.PhoneNumber,
+			Attempt:        6,
+		}, "API returned status 503"))
+	}
+	firstID := mockQueue.DeadLetters[0].ID
+
+	req := testutil.NewJSONRequest(t, map[string]interface{}{"ids": []string{firstID}})
+	testutil.SetAuthContext(req, org.ID, user.ID)
+	testutil.SetPathParam(req, "id", campaign.ID.String())
+
+	require.NoError(t, app.ReplayCampaignDeadLetters(req))
+	assert.Equal(t, fasthttp.StatusOK, testutil.GetResponseStatusCode(req))
+
+	// Only the selected job is re-enqueued, with a fresh retry budget
+	require.Len(t, mockQueue.Jobs, 1)
+	assert.Equal(t, first.ID, mockQueue.Jobs[0].RecipientID)
+	assert.Equal(t, 0, mockQueue.Jobs[0].Attempt)
+
+	require.Len(t, mockQueue.DeadLetters, 1)
+	assert.Equal(t, second.ID, mockQueue.DeadLetters[0].Job.RecipientID)
+
+	var updated models.BulkMessageRecipient
+	require.NoError(t, app.DB.First(&updated, first.ID).Error)
+	assert.Equal(t, models.MessageStatusPending, updated.Status)
+
+	var updatedCampaign models.BulkMessageCampaign
+	require.NoError(t, app.DB.First(&updatedCampaign, campaign.ID).Error)
+	assert.Equal(t, models.CampaignStatusProcessing, updatedCampaign.Status)
+}
+
+func TestApp_ReplayCampaignDeadLetters_Empty(t *testing.T) {
+	mockQueue := testutil.NewMockQueue()
+	app := newTestApp(t, withQueue(mockQueue))
+	org := testutil.CreateTestOrganization(t, app.DB)
+	user := testutil.CreateTestUser(t, app.DB, org.ID, testutil.WithEmail(testutil.UniqueEmail("dlq-empty")), testutil.WithPassword("password"))
+	account := testutil.CreateTestWhatsAppAccountWith(t, app.DB, org.ID, testutil.WithAccountName("dlq-empty-account"))
+	template := testutil.CreateTestTemplate(t, app.DB, org.ID, account.Name)
+	campaign := createTestCampaign(t, app, org.ID, template.ID, user.ID, account.Name, models.CampaignStatusCompleted)
+
+	req := testutil.NewJSONRequest(t, nil)
+	testutil.SetAuthContext(req, org.ID, user.ID)
+	testutil.SetPathParam(req, "id", campaign.ID.String())
+
+	require.NoError(t, app.ReplayCampaignDeadLetters(req))
+	assert.Equal(t, fasthttp.StatusBadRequest, testutil.GetResponseStatusCode(req))
+	assert.Empty(t, mockQueue.Jobs)
+}
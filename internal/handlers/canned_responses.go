@@ -15,19 +15,23 @@ type CannedResponseRequest struct {
 	Content  string `json:"content"`
 	Category string `json:"category"`
 	IsActive bool   `json:"is_active"`
+	// Per-language content, e.g. {"es": {"content": "..."}}. Omit to keep the
+	// stored translations on update.
+	Translations map[string]interface{} `json:"translations"`
 }
 
 // CannedResponseResponse represents the API response for a canned response
 type CannedResponseResponse struct {
-	ID         uuid.UUID `json:"id"`
-	Name       string    `json:"name"`
-	Shortcut   string    `json:"shortcut"`
-	Content    string    `json:"content"`
-	Category   string    `json:"category"`
-	IsActive   bool      `json:"is_active"`
-	UsageCount int       `json:"usage_count"`
-	CreatedAt  string    `json:"created_at"`
-	UpdatedAt  string    `json:"updated_at"`
+	ID           uuid.UUID    `json:"id"`
+	Name         string       `json:"name"`
+	Shortcut     string       `json:"shortcut"`
+	Content      string       `json:"content"`
+	Category     string       `json:"category"`
+	IsActive     bool         `json:"is_active"`
+	UsageCount   int          `json:"usage_count"`
+	CreatedAt    string       `json:"created_at"`
+	UpdatedAt    string       `json:"updated_at"`
+	Translations models.JSONB `json:"translations"`
 }
 
 // ListCannedResponses returns all canned responses for the organization
@@ -43,6 +47,7 @@ func (a *App) ListCannedResponses(r *fastglue.Request) error {
 	category := string(r.RequestCtx.QueryArgs().Peek("category"))
 	search := string(r.RequestCtx.QueryArgs().Peek("search"))
 	activeOnly := string(r.RequestCtx.QueryArgs().Peek("active_only"))
+	lang := string(r.RequestCtx.QueryArgs().Peek("language")) // Return content in this language where translated
 
 	query := a.DB.Where("organization_id = ?", orgID)
 
@@ -73,6 +78,7 @@ func (a *App) ListCannedResponses(r *fastglue.Request) error {
 
 	result := make([]CannedResponseResponse, len(responses))
 	for i, cr := range responses {
+		localizeCannedResponse(&cr, lang)
 		result[i] = cannedResponseToResponse(cr)
 	}
 
@@ -100,6 +106,9 @@ func (a *App) CreateCannedResponse(r *fastglue.Request) error {
 		return r.SendErrorEnvelope(fasthttp.StatusBadRequest,
 			"name and content are required", nil, "")
 	}
+	if err := validateTranslations(req.Translations); err != nil {
+		return r.SendErrorEnvelope(fasthttp.StatusBadRequest, err.Error(), nil, "")
+	}
 
 	// Check for duplicate name
 	var existing models.CannedResponse
@@ -116,6 +125,7 @@ func (a *App) CreateCannedResponse(r *fastglue.Request) error {
 		Content:        req.Content,
 		Category:       req.Category,
 		IsActive:       true,
+		Translations:   models.JSONB(req.Translations),
 		CreatedByID:    userID,
 	}
 
@@ -173,6 +183,9 @@ func (a *App) UpdateCannedResponse(r *fastglue.Request) error {
 	if err := a.decodeRequest(r, &req); err != nil {
 		return nil
 	}
+	if err := validateTranslations(req.Translations); err != nil {
+		return r.SendErrorEnvelope(fasthttp.StatusBadRequest, err.Error(), nil, "")
+	}
 
 	// Update fields
 	if req.Name != "" {
@@ -184,6 +197,9 @@ func (a *App) UpdateCannedResponse(r *fastglue.Request) error {
 	}
 	cannedResponse.Category = req.Category
 	cannedResponse.IsActive = req.IsActive
+	if req.Translations != nil {
+		cannedResponse.Translations = models.JSONB(req.Translations)
+	}
 
 	if err := a.DB.Save(&cannedResponse).Error; err != nil {
 		a.Log.Error("Failed to update canned response", "error", err)
@@ -247,14 +263,15 @@ func (a *App) IncrementCannedResponseUsage(r *fastglue.Request) error {
 
 func cannedResponseToResponse(cr models.CannedResponse) CannedResponseResponse {
 	return CannedResponseResponse{
-		ID:         cr.ID,
-		Name:       cr.Name,
-		Shortcut:   cr.Shortcut,
-		Content:    cr.Content,
-		Category:   cr.Category,
-		IsActive:   cr.IsActive,
-		UsageCount: cr.UsageCount,
-		CreatedAt:  cr.CreatedAt.Format("2006-01-02T15:04:05Z"),
-		UpdatedAt:  cr.UpdatedAt.Format("2006-01-02T15:04:05Z"),
+		ID:           cr.ID,
+		Name:         cr.Name,
+		Shortcut:     cr.Shortcut,
+		Content:      cr.Content,
+		Category:     cr.Category,
+		IsActive:     cr.IsActive,
+		UsageCount:   cr.UsageCount,
+		CreatedAt:    cr.CreatedAt.Format("2006-01-02T15:04:05Z"),
+		UpdatedAt:    cr.UpdatedAt.Format("2006-01-02T15:04:05Z"),
+		Translations: cr.Translations,
 	}
 }
@@ -2,10 +2,16 @@ package handlers
 
 import (
 	"encoding/json"
+	"fmt"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/shridarpatil/whatomate/internal/models"
+	"github.com/shridarpatil/whatomate/internal/schedule"
 	"github.com/valyala/fasthttp"
 	"github.com/zerodha/fastglue"
 	"gorm.io/gorm"
@@ -21,16 +27,34 @@ type ChatbotSettingsResponse struct {
 	SessionTimeoutMinutes int                      `json:"session_timeout_minutes"`
 	BusinessHoursEnabled       bool                     `json:"business_hours_enabled"`
 	BusinessHours              []map[string]interface{} `json:"business_hours"`
+	BusinessHoursTimezone      string                   `json:"business_hours_timezone"`
+	BusinessHoursScheduleID    *uuid.UUID               `json:"business_hours_schedule_id"`
 	OutOfHoursMessage          string                   `json:"out_of_hours_message"`
 	AllowAutomatedOutsideHours bool                     `json:"allow_automated_outside_hours"`
 	AllowAgentQueuePickup        bool                     `json:"allow_agent_queue_pickup"`
 	AssignToSameAgent            bool                     `json:"assign_to_same_agent"`
 	AgentCurrentConversationOnly bool                     `json:"agent_current_conversation_only"`
+	TransferPriorityRules        []models.TransferPriorityRule `json:"transfer_priority_rules"`
 	AIEnabled                    bool                     `json:"ai_enabled"`
 	AIProvider            models.AIProvider        `json:"ai_provider"`
 	AIModel               string                   `json:"ai_model"`
 	AIMaxTokens           int                      `json:"ai_max_tokens"`
 	AISystemPrompt        string                   `json:"ai_system_prompt"`
+	AIBaseURL             string                   `json:"ai_base_url"`
+	AIHeaderNames         []string                 `json:"ai_header_names"` // Header values may hold credentials and are never returned
+	AIFallbacks           []AIFallbackResponse     `json:"ai_fallbacks"`
+	AIEmbeddingModel      string                   `json:"ai_embedding_model"`
+	AIKnowledgeTopK       int                      `json:"ai_knowledge_top_k"`
+	AIKnowledgeMinScore   float64                  `json:"ai_knowledge_min_score"`
+	AITools               []string                 `json:"ai_tools"`
+	AITransferSummary     bool                     `json:"ai_transfer_summary"`
+	// Languages
+	DefaultLanguage        string                 `json:"default_language"`
+	Languages              []string               `json:"languages"`
+	DetectLanguage         bool                   `json:"detect_language"`
+	LanguageSwitchKeywords []string               `json:"language_switch_keywords"`
+	LanguageSwitchPrompt   string                 `json:"language_switch_prompt"`
+	Translations           map[string]interface{} `json:"translations"`
 	// SLA Settings
 	SLAEnabled             bool     `json:"sla_enabled"`
 	SLAResponseMinutes     int      `json:"sla_response_minutes"`
@@ -48,6 +72,15 @@ type ChatbotSettingsResponse struct {
 	ClientAutoCloseMessage string `json:"client_auto_close_message"`
 }
 
+// AIFallbackResponse represents a fallback AI provider without its credentials
+type AIFallbackResponse struct {
+	Provider    models.AIProvider `json:"provider"`
+	Model       string            `json:"model"`
+	BaseURL     string            `json:"base_url"`
+	HasAPIKey   bool              `json:"has_api_key"`
+	HeaderNames []string          `json:"header_names"`
+}
+
 // ChatbotStatsResponse represents chatbot statistics
 type ChatbotStatsResponse struct {
 	TotalSessions   int64 `json:"total_sessions"`
@@ -58,6 +91,7 @@ type ChatbotStatsResponse struct {
 	KeywordsCount   int64 `json:"keywords_count"`
 	FlowsCount      int64 `json:"flows_count"`
 	AIContextsCount int64 `json:"ai_contexts_count"`
+	AITokensUsed    int64 `json:"ai_tokens_used"`
 }
 
 // KeywordRuleResponse represents a keyword rule for API response
@@ -68,6 +102,9 @@ type KeywordRuleResponse struct {
 	MatchType       models.MatchType   `json:"match_type"`
 	ResponseType    models.ResponseType `json:"response_type"`
 	ResponseContent json.RawMessage    `json:"response_content"`
+	Conditions      string             `json:"conditions"`
+	ActiveFrom      *time.Time         `json:"active_from"`
+	ActiveUntil     *time.Time         `json:"active_until"`
 	Priority        int                `json:"priority"`
 	Enabled         bool               `json:"enabled"`
 	CreatedAt       string             `json:"created_at"`
@@ -75,13 +112,14 @@ type KeywordRuleResponse struct {
 
 // ChatbotFlowResponse represents a chatbot flow for API response
 type ChatbotFlowResponse struct {
-	ID              string   `json:"id"`
-	Name            string   `json:"name"`
-	Description     string   `json:"description"`
-	TriggerKeywords []string `json:"trigger_keywords"`
-	Enabled         bool     `json:"enabled"`
-	StepsCount      int      `json:"steps_count"`
-	CreatedAt       string   `json:"created_at"`
+	ID               string   `json:"id"`
+	Name             string   `json:"name"`
+	Description      string   `json:"description"`
+	TriggerKeywords  []string `json:"trigger_keywords"`
+	Enabled          bool     `json:"enabled"`
+	StepsCount       int      `json:"steps_count"`
+	PublishedVersion int      `json:"published_version"`
+	CreatedAt        string   `json:"created_at"`
 }
 
 // AIContextResponse represents an AI context for API response
@@ -148,6 +186,12 @@ func (a *App) GetChatbotSettings(r *fastglue.Request) error {
 		}
 	}
 
+	priorityRules, err := parseTransferPriorityRules(settings.AgentAssignment.PriorityRules)
+	if err != nil {
+		a.Log.Error("Failed to parse transfer priority rules", "error", err, "org_id", orgID)
+		priorityRules = []models.TransferPriorityRule{}
+	}
+
 	settingsResp := ChatbotSettingsResponse{
 		Enabled:               settings.IsEnabled,
 		GreetingMessage:       settings.DefaultResponse,
@@ -158,18 +202,36 @@ func (a *App) GetChatbotSettings(r *fastglue.Request) error {
 		// Business Hours
 		BusinessHoursEnabled:       settings.BusinessHours.Enabled,
 		BusinessHours:              businessHours,
+		BusinessHoursTimezone:      settings.BusinessHours.Timezone,
+		BusinessHoursScheduleID:    settings.BusinessHours.ScheduleID,
 		OutOfHoursMessage:          settings.BusinessHours.OutOfHoursMessage,
 		AllowAutomatedOutsideHours: settings.BusinessHours.AllowAutomatedOutside,
 		// Agent Assignment
 		AllowAgentQueuePickup:        settings.AgentAssignment.AllowQueuePickup,
 		AssignToSameAgent:            settings.AgentAssignment.AssignToSameAgent,
 		AgentCurrentConversationOnly: settings.AgentAssignment.CurrentConversationOnly,
+		TransferPriorityRules:        priorityRules,
 		// AI
 		AIEnabled:      settings.AI.Enabled,
 		AIProvider:     settings.AI.Provider,
 		AIModel:        settings.AI.Model,
 		AIMaxTokens:    settings.AI.MaxTokens,
 		AISystemPrompt: settings.AI.SystemPrompt,
+		AIBaseURL:      settings.AI.BaseURL,
+		AIHeaderNames:  slices.Sorted(maps.Keys(settings.AI.Headers)),
+		AIFallbacks:    aiFallbackResponses(settings.AI.Fallbacks),
+		AIEmbeddingModel:    settings.AI.EmbeddingModel,
+		AIKnowledgeTopK:     settings.AI.KnowledgeTopK,
+		AIKnowledgeMinScore: settings.AI.KnowledgeMinScore,
+		AITools:             settings.AI.Tools,
+		AITransferSummary:   settings.AI.TransferSummary,
+		// Languages
+		DefaultLanguage:        settings.Language.DefaultLanguage,
+		Languages:              settings.Language.Languages,
+		DetectLanguage:         settings.Language.DetectLanguage,
+		LanguageSwitchKeywords: settings.Language.SwitchKeywords,
+		LanguageSwitchPrompt:   settings.Language.SwitchPrompt,
+		Translations:           settings.Translations,
 		// SLA Settings
 		SLAEnabled:             settings.SLA.Enabled,
 		SLAResponseMinutes:     settings.SLA.ResponseMinutes,
@@ -209,17 +271,35 @@ func (a *App) UpdateChatbotSettings(r *fastglue.Request) error {
 		SessionTimeoutMinutes      *int                       `json:"session_timeout_minutes"`
 		BusinessHoursEnabled       *bool                      `json:"business_hours_enabled"`
 		BusinessHours              *[]map[string]interface{}  `json:"business_hours"`
+		BusinessHoursTimezone      *string                    `json:"business_hours_timezone"`
+		BusinessHoursScheduleID    *string                    `json:"business_hours_schedule_id"` // Empty clears the schedule
 		OutOfHoursMessage          *string                    `json:"out_of_hours_message"`
 		AllowAutomatedOutsideHours *bool                      `json:"allow_automated_outside_hours"`
 		AllowAgentQueuePickup        *bool                      `json:"allow_agent_queue_pickup"`
 		AssignToSameAgent            *bool                      `json:"assign_to_same_agent"`
 		AgentCurrentConversationOnly *bool                      `json:"agent_current_conversation_only"`
+		TransferPriorityRules        *[]models.TransferPriorityRule `json:"transfer_priority_rules"` // Replaces all rules
 		AIEnabled                    *bool                      `json:"ai_enabled"`
 		AIProvider                 *models.AIProvider         `json:"ai_provider"`
 		AIAPIKey                   *string                    `json:"ai_api_key"`
 		AIModel                    *string                    `json:"ai_model"`
 		AIMaxTokens                *int                       `json:"ai_max_tokens"`
 		AISystemPrompt             *string                    `json:"ai_system_prompt"`
+		AIBaseURL                  *string                    `json:"ai_base_url"`
+		AIHeaders                  *map[string]string         `json:"ai_headers"`   // Replaces all headers
+		AIFallbacks                *[]models.AIFallback       `json:"ai_fallbacks"` // An empty api_key keeps the key of the same provider and base URL
+		AIEmbeddingModel           *string                    `json:"ai_embedding_model"`
+		AIKnowledgeTopK            *int                       `json:"ai_knowledge_top_k"`
+		AIKnowledgeMinScore        *float64                   `json:"ai_knowledge_min_score"`
+		AITools                    *[]string                  `json:"ai_tools"` // Replaces the allow-list
+		AITransferSummary          *bool                      `json:"ai_transfer_summary"`
+		// Languages
+		DefaultLanguage        *string                 `json:"default_language"`
+		Languages              *[]string               `json:"languages"`
+		DetectLanguage         *bool                   `json:"detect_language"`
+		LanguageSwitchKeywords *[]string               `json:"language_switch_keywords"`
+		LanguageSwitchPrompt   *string                 `json:"language_switch_prompt"`
+		Translations           *map[string]interface{} `json:"translations"` // Replaces all translations
 		// SLA Settings
 		SLAEnabled             *bool     `json:"sla_enabled"`
 		SLAResponseMinutes     *int      `json:"sla_response_minutes"`
@@ -292,6 +372,28 @@ func (a *App) UpdateChatbotSettings(r *fastglue.Request) error {
 		}
 		settings.BusinessHours.Hours = hours
 	}
+	if req.BusinessHoursTimezone != nil {
+		settings.BusinessHours.Timezone = strings.TrimSpace(*req.BusinessHoursTimezone)
+	}
+	if req.BusinessHours != nil || req.BusinessHoursTimezone != nil {
+		sched := inlineSchedule(settings.BusinessHours)
+		if sched == nil {
+			sched = &schedule.Schedule{Timezone: settings.BusinessHours.Timezone}
+		}
+		if err := sched.Validate(); err != nil {
+			return r.SendErrorEnvelope(fasthttp.StatusBadRequest, "business_hours: "+err.Error(), nil, "")
+		}
+	}
+	if req.BusinessHoursScheduleID != nil {
+		settings.BusinessHours.ScheduleID = nil
+		if *req.BusinessHoursScheduleID != "" {
+			scheduleID, err := uuid.Parse(*req.BusinessHoursScheduleID)
+			if err != nil || !a.businessHoursScheduleExists(orgID, scheduleID) {
+				return r.SendErrorEnvelope(fasthttp.StatusBadRequest, "Business hours schedule not found", nil, "")
+			}
+			settings.BusinessHours.ScheduleID = &scheduleID
+		}
+	}
 	if req.OutOfHoursMessage != nil {
 		settings.BusinessHours.OutOfHoursMessage = *req.OutOfHoursMessage
 	}
@@ -309,6 +411,13 @@ func (a *App) UpdateChatbotSettings(r *fastglue.Request) error {
 	if req.AgentCurrentConversationOnly != nil {
 		settings.AgentAssignment.CurrentConversationOnly = *req.AgentCurrentConversationOnly
 	}
+	if req.TransferPriorityRules != nil {
+		rules, err := transferPriorityRulesToJSONB(*req.TransferPriorityRules)
+		if err != nil {
+			return r.SendErrorEnvelope(fasthttp.StatusBadRequest, err.Error(), nil, "")
+		}
+		settings.AgentAssignment.PriorityRules = rules
+	}
 
 	// AI Settings
 	if req.AIEnabled != nil {
@@ -329,6 +438,78 @@ func (a *App) UpdateChatbotSettings(r *fastglue.Request) error {
 	if req.AISystemPrompt != nil {
 		settings.AI.SystemPrompt = *req.AISystemPrompt
 	}
+	if req.AIBaseURL != nil {
+		settings.AI.BaseURL = strings.TrimSpace(*req.AIBaseURL)
+	}
+	if req.AIHeaders != nil {
+		headers := models.JSONB{}
+		for k, v := range *req.AIHeaders {
+			headers[k] = v
+		}
+		settings.AI.Headers = headers
+	}
+	if req.AIFallbacks != nil {
+		fallbacks, err := mergeAIFallbacks(settings.AI.Fallbacks, *req.AIFallbacks)
+		if err != nil {
+			return r.SendErrorEnvelope(fasthttp.StatusBadRequest, err.Error(), nil, "")
+		}
+		settings.AI.Fallbacks = fallbacks
+	}
+	if req.AIEmbeddingModel != nil {
+		settings.AI.EmbeddingModel = strings.TrimSpace(*req.AIEmbeddingModel)
+	}
+	if req.AIKnowledgeTopK != nil {
+		if *req.AIKnowledgeTopK < 0 || *req.AIKnowledgeTopK > maxKnowledgeTopK {
+			return r.SendErrorEnvelope(fasthttp.StatusBadRequest, fmt.Sprintf("ai_knowledge_top_k must be between 0 and %d", maxKnowledgeTopK), nil, "")
+		}
+		settings.AI.KnowledgeTopK = *req.AIKnowledgeTopK
+	}
+	if req.AIKnowledgeMinScore != nil {
+		if *req.AIKnowledgeMinScore < 0 || *req.AIKnowledgeMinScore > 1 {
+			return r.SendErrorEnvelope(fasthttp.StatusBadRequest, "ai_knowledge_min_score must be between 0 and 1", nil, "")
+		}
+		settings.AI.KnowledgeMinScore = *req.AIKnowledgeMinScore
+	}
+	if req.AITools != nil {
+		if err := validateAITools(*req.AITools); err != nil {
+			return r.SendErrorEnvelope(fasthttp.StatusBadRequest, err.Error(), nil, "")
+		}
+		settings.AI.Tools = slices.Compact(slices.Sorted(slices.Values(*req.AITools)))
+	}
+	if req.AITransferSummary != nil {
+		settings.AI.TransferSummary = *req.AITransferSummary
+	}
+	if settings.AI.Enabled {
+		if err := validateAIConfig(settings.AI); err != nil {
+			return r.SendErrorEnvelope(fasthttp.StatusBadRequest, err.Error(), nil, "")
+		}
+	}
+
+	// Languages
+	if req.DefaultLanguage != nil {
+		settings.Language.DefaultLanguage = strings.TrimSpace(*req.DefaultLanguage)
+	}
+	if req.Languages != nil {
+		settings.Language.Languages = *req.Languages
+	}
+	if req.DetectLanguage != nil {
+		settings.Language.DetectLanguage = *req.DetectLanguage
+	}
+	if req.LanguageSwitchKeywords != nil {
+		settings.Language.SwitchKeywords = *req.LanguageSwitchKeywords
+	}
+	if req.LanguageSwitchPrompt != nil {
+		settings.Language.SwitchPrompt = *req.LanguageSwitchPrompt
+	}
+	if req.Translations != nil {
+		if err := validateTranslations(*req.Translations); err != nil {
+			return r.SendErrorEnvelope(fasthttp.StatusBadRequest, err.Error(), nil, "")
+		}
+		settings.Translations = *req.Translations
+	}
+	if err := validateLanguageConfig(settings.Language); err != nil {
+		return r.SendErrorEnvelope(fasthttp.StatusBadRequest, err.Error(), nil, "")
+	}
 
 	// SLA Settings
 	if req.SLAEnabled != nil {
@@ -449,6 +630,9 @@ func (a *App) ListKeywordRules(r *fastglue.Request) error {
 			MatchType:       rule.MatchType,
 			ResponseType:    rule.ResponseType,
 			ResponseContent: responseContent,
+			Conditions:      rule.Conditions,
+			ActiveFrom:      rule.ActiveFrom,
+			ActiveUntil:     rule.ActiveUntil,
 			Priority:        rule.Priority,
 			Enabled:         rule.IsEnabled,
 			CreatedAt:       rule.CreatedAt.Format(time.RFC3339),
@@ -476,6 +660,9 @@ func (a *App) CreateKeywordRule(r *fastglue.Request) error {
 		MatchType       models.MatchType       `json:"match_type"`
 		ResponseType    models.ResponseType    `json:"response_type"`
 		ResponseContent map[string]interface{} `json:"response_content"`
+		Conditions      string                 `json:"conditions"`
+		ActiveFrom      *time.Time             `json:"active_from"`
+		ActiveUntil     *time.Time             `json:"active_until"`
 		Priority        int                    `json:"priority"`
 		Enabled         bool                   `json:"enabled"`
 	}
@@ -498,6 +685,9 @@ func (a *App) CreateKeywordRule(r *fastglue.Request) error {
 	if req.Name == "" {
 		req.Name = req.Keywords[0]
 	}
+	if err := validateKeywordRule(req.ResponseType, req.ResponseContent, req.ActiveFrom, req.ActiveUntil); err != nil {
+		return r.SendErrorEnvelope(fasthttp.StatusBadRequest, err.Error(), nil, "")
+	}
 
 	rule := models.KeywordRule{
 		BaseModel:       models.BaseModel{ID: uuid.New()},
@@ -507,6 +697,9 @@ func (a *App) CreateKeywordRule(r *fastglue.Request) error {
 		MatchType:       req.MatchType,
 		ResponseType:    req.ResponseType,
 		ResponseContent: models.JSONB(req.ResponseContent),
+		Conditions:      strings.TrimSpace(req.Conditions),
+		ActiveFrom:      req.ActiveFrom,
+		ActiveUntil:     req.ActiveUntil,
 		Priority:        req.Priority,
 		IsEnabled:       req.Enabled,
 	}
@@ -550,6 +743,9 @@ func (a *App) GetKeywordRule(r *fastglue.Request) error {
 		MatchType:       rule.MatchType,
 		ResponseType:    rule.ResponseType,
 		ResponseContent: responseContent,
+		Conditions:      rule.Conditions,
+		ActiveFrom:      rule.ActiveFrom,
+		ActiveUntil:     rule.ActiveUntil,
 		Priority:        rule.Priority,
 		Enabled:         rule.IsEnabled,
 		CreatedAt:       rule.CreatedAt.Format(time.RFC3339),
@@ -581,6 +777,9 @@ func (a *App) UpdateKeywordRule(r *fastglue.Request) error {
 		MatchType       *models.MatchType       `json:"match_type"`
 		ResponseType    *models.ResponseType    `json:"response_type"`
 		ResponseContent map[string]interface{}  `json:"response_content"`
+		Conditions      *string                 `json:"conditions"`
+		ActiveFrom      json.RawMessage         `json:"active_from"`  // null clears the bound
+		ActiveUntil     json.RawMessage         `json:"active_until"` // null clears the bound
 		Priority        *int                    `json:"priority"`
 		Enabled         *bool                   `json:"enabled"`
 	}
@@ -611,6 +810,22 @@ func (a *App) UpdateKeywordRule(r *fastglue.Request) error {
 	if req.Enabled != nil {
 		rule.IsEnabled = *req.Enabled
 	}
+	if req.Conditions != nil {
+		rule.Conditions = strings.TrimSpace(*req.Conditions)
+	}
+	if req.ActiveFrom != nil {
+		if rule.ActiveFrom, err = parseOptionalTime(req.ActiveFrom); err != nil {
+			return r.SendErrorEnvelope(fasthttp.StatusBadRequest, "Invalid active_from", nil, "")
+		}
+	}
+	if req.ActiveUntil != nil {
+		if rule.ActiveUntil, err = parseOptionalTime(req.ActiveUntil); err != nil {
+			return r.SendErrorEnvelope(fasthttp.StatusBadRequest, "Invalid active_until", nil, "")
+		}
+	}
+	if err := validateKeywordRule(rule.ResponseType, rule.ResponseContent, rule.ActiveFrom, rule.ActiveUntil); err != nil {
+		return r.SendErrorEnvelope(fasthttp.StatusBadRequest, err.Error(), nil, "")
+	}
 
 	if err := a.DB.Save(rule).Error; err != nil {
 		a.Log.Error("Failed to update keyword rule", "error", err)
@@ -689,13 +904,14 @@ func (a *App) ListChatbotFlows(r *fastglue.Request) error {
 	response := make([]ChatbotFlowResponse, len(flows))
 	for i, flow := range flows {
 		response[i] = ChatbotFlowResponse{
-			ID:              flow.ID.String(),
-			Name:            flow.Name,
-			Description:     flow.Description,
-			TriggerKeywords: flow.TriggerKeywords,
-			Enabled:         flow.IsEnabled,
-			StepsCount:      len(flow.Steps),
-			CreatedAt:       flow.CreatedAt.Format(time.RFC3339),
+			ID:               flow.ID.String(),
+			Name:             flow.Name,
+			Description:      flow.Description,
+			TriggerKeywords:  flow.TriggerKeywords,
+			Enabled:          flow.IsEnabled,
+			StepsCount:       len(flow.Steps),
+			PublishedVersion: flow.PublishedVersion,
+			CreatedAt:        flow.CreatedAt.Format(time.RFC3339),
 		}
 	}
 
@@ -713,11 +929,14 @@ type FlowStepRequest struct {
 	StepOrder       int                      `json:"step_order"`
 	Message         string                   `json:"message"`
 	MessageType     models.FlowStepType      `json:"message_type"`
+	TemplateID      *uuid.UUID               `json:"template_id"`
+	TemplateConfig  map[string]interface{}   `json:"template_config"`
 	InputType       models.InputType         `json:"input_type"`
 	InputConfig     map[string]interface{}   `json:"input_config"`
 	ApiConfig       map[string]interface{}   `json:"api_config"`
 	Buttons         []map[string]interface{} `json:"buttons"`
 	TransferConfig  map[string]interface{}   `json:"transfer_config"`
+	ScriptConfig    map[string]interface{}   `json:"script_config"`
 	ValidationRegex string                   `json:"validation_regex"`
 	ValidationError string                   `json:"validation_error"`
 	StoreAs         string                   `json:"store_as"`
@@ -726,6 +945,39 @@ type FlowStepRequest struct {
 	SkipCondition   string                   `json:"skip_condition"`
 	RetryOnInvalid  bool                     `json:"retry_on_invalid"`
 	MaxRetries      int                      `json:"max_retries"`
+	Translations    map[string]interface{}   `json:"translations"`
+}
+
+// validateFlowSteps checks step settings that would otherwise only fail when the flow runs
+func (a *App) validateFlowSteps(orgID uuid.UUID, steps []FlowStepRequest) error {
+	for _, step := range steps {
+		if err := validateTranslations(step.Translations); err != nil {
+			return fmt.Errorf("step %q: %w", step.StepName, err)
+		}
+		switch step.MessageType {
+		case models.FlowStepTypeScript:
+			if err := validateScriptConfig(step.ScriptConfig); err != nil {
+				return fmt.Errorf("step %q: %w", step.StepName, err)
+			}
+		case models.FlowStepTypeTemplate:
+			if step.TemplateID == nil {
+				return fmt.Errorf("step %q: template_id is required", step.StepName)
+			}
+			var count int64
+			a.DB.Model(&models.Template{}).Where("id = ? AND organization_id = ?", *step.TemplateID, orgID).Count(&count)
+			if count == 0 {
+				return fmt.Errorf("step %q: template not found", step.StepName)
+			}
+			if params, ok := step.TemplateConfig["button_params"].(map[string]interface{}); ok {
+				for key := range params {
+					if _, err := strconv.Atoi(key); err != nil {
+						return fmt.Errorf("step %q: button_params keys must be button indexes", step.StepName)
+					}
+				}
+			}
+		}
+	}
+	return nil
 }
 
 // CreateChatbotFlow creates a new chatbot flow
@@ -750,6 +1002,8 @@ func (a *App) CreateChatbotFlow(r *fastglue.Request) error {
 		PanelConfig       map[string]interface{} `json:"panel_config"`
 		Enabled           bool                   `json:"enabled"`
 		Steps             []FlowStepRequest      `json:"steps"`
+		TestCases         []ChatbotFlowTestCase  `json:"test_cases"`
+		Translations      map[string]interface{} `json:"translations"`
 	}
 
 	if err := json.Unmarshal(r.RequestCtx.PostBody(), &req); err != nil {
@@ -759,6 +1013,16 @@ func (a *App) CreateChatbotFlow(r *fastglue.Request) error {
 	if req.Name == "" {
 		return r.SendErrorEnvelope(fasthttp.StatusBadRequest, "Name is required", nil, "")
 	}
+	if err := a.validateFlowSteps(orgID, req.Steps); err != nil {
+		return r.SendErrorEnvelope(fasthttp.StatusBadRequest, err.Error(), nil, "")
+	}
+	if err := validateTranslations(req.Translations); err != nil {
+		return r.SendErrorEnvelope(fasthttp.StatusBadRequest, err.Error(), nil, "")
+	}
+	testCases, err := flowTestCasesToJSONB(req.TestCases)
+	if err != nil {
+		return r.SendErrorEnvelope(fasthttp.StatusBadRequest, err.Error(), nil, "")
+	}
 
 	// Use transaction for flow + steps
 	tx := a.DB.Begin()
@@ -775,6 +1039,8 @@ func (a *App) CreateChatbotFlow(r *fastglue.Request) error {
 		OnCompleteAction:  req.OnCompleteAction,
 		CompletionConfig:  models.JSONB(req.CompletionConfig),
 		PanelConfig:       models.JSONB(req.PanelConfig),
+		TestCases:         testCases,
+		Translations:      models.JSONB(req.Translations),
 		IsEnabled:         req.Enabled,
 	}
 
@@ -804,6 +1070,9 @@ func (a *App) CreateChatbotFlow(r *fastglue.Request) error {
 			ApiConfig:       models.JSONB(stepReq.ApiConfig),
 			Buttons:         buttons,
 			TransferConfig:  models.JSONB(stepReq.TransferConfig),
+			ScriptConfig:    models.JSONB(stepReq.ScriptConfig),
+			TemplateID:      stepReq.TemplateID,
+			TemplateConfig:  models.JSONB(stepReq.TemplateConfig),
 			ValidationRegex: stepReq.ValidationRegex,
 			ValidationError: stepReq.ValidationError,
 			StoreAs:         stepReq.StoreAs,
@@ -812,6 +1081,7 @@ func (a *App) CreateChatbotFlow(r *fastglue.Request) error {
 			SkipCondition:   stepReq.SkipCondition,
 			RetryOnInvalid:  stepReq.RetryOnInvalid,
 			MaxRetries:      stepReq.MaxRetries,
+			Translations:    models.JSONB(stepReq.Translations),
 		}
 		if step.MessageType == "" {
 			step.MessageType = models.FlowStepTypeText
@@ -831,10 +1101,12 @@ func (a *App) CreateChatbotFlow(r *fastglue.Request) error {
 	// Invalidate cache
 	a.InvalidateChatbotFlowsCache(orgID)
 
-	return r.SendEnvelope(map[string]interface{}{
+	resp := map[string]interface{}{
 		"id":      flow.ID.String(),
 		"message": "Flow created successfully",
-	})
+	}
+	a.addFlowTestResults(resp, flow.ID, req.TestCases)
+	return r.SendEnvelope(resp)
 }
 
 // GetChatbotFlow gets a single chatbot flow with steps
@@ -897,14 +1169,38 @@ func (a *App) UpdateChatbotFlow(r *fastglue.Request) error {
 		PanelConfig       map[string]interface{} `json:"panel_config"`
 		Enabled           *bool                  `json:"enabled"`
 		Steps             []FlowStepRequest      `json:"steps"`
+		TestCases         []ChatbotFlowTestCase  `json:"test_cases"`   // Replaces the stored test cases when present
+		Translations      map[string]interface{} `json:"translations"` // Replaces the flow's translations when present
 	}
 
 	if err := json.Unmarshal(r.RequestCtx.PostBody(), &req); err != nil {
 		return r.SendErrorEnvelope(fasthttp.StatusBadRequest, "Invalid request body", nil, "")
 	}
+	if err := a.validateFlowSteps(orgID, req.Steps); err != nil {
+		return r.SendErrorEnvelope(fasthttp.StatusBadRequest, err.Error(), nil, "")
+	}
+	if err := validateTranslations(req.Translations); err != nil {
+		return r.SendErrorEnvelope(fasthttp.StatusBadRequest, err.Error(), nil, "")
+	}
+	if req.TestCases != nil {
+		testCases, err := flowTestCasesToJSONB(req.TestCases)
+		if err != nil {
+			return r.SendErrorEnvelope(fasthttp.StatusBadRequest, err.Error(), nil, "")
+		}
+		flow.TestCases = testCases
+	}
 
 	tx := a.DB.Begin()
 
+	// Edits only change the draft, so keep what contacts are running as version 1
+	if flow.PublishedVersion == 0 {
+		if err := a.publishInitialFlowVersion(tx, flow, userID); err != nil {
+			tx.Rollback()
+			a.Log.Error("Failed to version flow", "error", err, "flow_id", flow.ID)
+			return r.SendErrorEnvelope(fasthttp.StatusInternalServerError, "Failed to update flow", nil, "")
+		}
+	}
+
 	if req.Name != nil {
 		flow.Name = *req.Name
 	}
@@ -929,6 +1225,9 @@ func (a *App) UpdateChatbotFlow(r *fastglue.Request) error {
 	if req.PanelConfig != nil {
 		flow.PanelConfig = models.JSONB(req.PanelConfig)
 	}
+	if req.Translations != nil {
+		flow.Translations = models.JSONB(req.Translations)
+	}
 	if req.Enabled != nil {
 		flow.IsEnabled = *req.Enabled
 	}
@@ -968,6 +1267,9 @@ func (a *App) UpdateChatbotFlow(r *fastglue.Request) error {
 				ApiConfig:       models.JSONB(stepReq.ApiConfig),
 				Buttons:         buttons,
 				TransferConfig:  models.JSONB(stepReq.TransferConfig),
+				ScriptConfig:    models.JSONB(stepReq.ScriptConfig),
+				TemplateID:      stepReq.TemplateID,
+				TemplateConfig:  models.JSONB(stepReq.TemplateConfig),
 				ValidationRegex: stepReq.ValidationRegex,
 				ValidationError: stepReq.ValidationError,
 				StoreAs:         stepReq.StoreAs,
@@ -976,6 +1278,7 @@ func (a *App) UpdateChatbotFlow(r *fastglue.Request) error {
 				SkipCondition:   stepReq.SkipCondition,
 				RetryOnInvalid:  stepReq.RetryOnInvalid,
 				MaxRetries:      stepReq.MaxRetries,
+				Translations:    models.JSONB(stepReq.Translations),
 			}
 			if step.MessageType == "" {
 				step.MessageType = models.FlowStepTypeText
@@ -996,9 +1299,13 @@ func (a *App) UpdateChatbotFlow(r *fastglue.Request) error {
 	// Invalidate cache
 	a.InvalidateChatbotFlowsCache(orgID)
 
-	return r.SendEnvelope(map[string]interface{}{
+	resp := map[string]interface{}{
 		"message": "Flow updated successfully",
-	})
+	}
+	if testCases, err := parseFlowTestCases(flow.TestCases); err == nil {
+		a.addFlowTestResults(resp, flow.ID, testCases)
+	}
+	return r.SendEnvelope(resp)
 }
 
 // DeleteChatbotFlow deletes a chatbot flow
@@ -1027,6 +1334,13 @@ func (a *App) DeleteChatbotFlow(r *fastglue.Request) error {
 		return r.SendErrorEnvelope(fasthttp.StatusInternalServerError, "Failed to delete flow steps", nil, "")
 	}
 
+	// Delete published versions
+	if err := tx.Where("flow_id = ? AND organization_id = ?", id, orgID).Delete(&models.ChatbotFlowVersion{}).Error; err != nil {
+		tx.Rollback()
+		a.Log.Error("Failed to delete flow versions", "error", err)
+		return r.SendErrorEnvelope(fasthttp.StatusInternalServerError, "Failed to delete flow versions", nil, "")
+	}
+
 	// Delete flow
 	result := tx.Where("id = ? AND organization_id = ?", id, orgID).Delete(&models.ChatbotFlow{})
 	if result.Error != nil {
@@ -1353,5 +1667,11 @@ func (a *App) getChatbotStats(orgID uuid.UUID) ChatbotStatsResponse {
 		Where("organization_id = ?", orgID).
 		Count(&stats.AIContextsCount)
 
+	// AI token usage across sessions
+	a.DB.Model(&models.ChatbotSession{}).
+		Where("organization_id = ?", orgID).
+		Select("COALESCE(SUM(ai_prompt_tokens + ai_completion_tokens), 0)").
+		Scan(&stats.AITokensUsed)
+
 	return stats
 }
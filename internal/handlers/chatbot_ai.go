@@ -0,0 +1,190 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+
+	"github.com/shridarpatil/whatomate/internal/ai"
+	"github.com/shridarpatil/whatomate/internal/models"
+	"gorm.io/gorm"
+)
+
+// newAIProvider builds the provider for an AI config, chained with its fallbacks
+func (a *App) newAIProvider(cfg models.AIConfig) (ai.Provider, error) {
+	primary, err := ai.New(ai.Config{
+		Provider: string(cfg.Provider),
+		Model:    cfg.Model,
+		APIKey:   cfg.APIKey,
+		BaseURL:  cfg.BaseURL,
+		Headers:  aiHeadersFromJSONB(cfg.Headers),
+	}, a.HTTPClient)
+	if err != nil {
+		return nil, err
+	}
+
+	providers := []ai.Provider{primary}
+	fallbacks, err := parseAIFallbacks(cfg.Fallbacks)
+	if err != nil {
+		a.Log.Error("Invalid AI fallbacks", "error", err)
+	}
+	for _, fb := range fallbacks {
+		p, err := ai.New(ai.Config{
+			Provider: string(fb.Provider),
+			Model:    fb.Model,
+			APIKey:   fb.APIKey,
+			BaseURL:  fb.BaseURL,
+			Headers:  fb.Headers,
+		}, a.HTTPClient)
+		if err != nil {
+			a.Log.Error("Skipping invalid AI fallback", "error", err, "provider", fb.Provider, "model", fb.Model)
+			continue
+		}
+		providers = append(providers, p)
+	}
+	return ai.WithFallback(providers...), nil
+}
+
+// sendAndSaveAIResponse sends an AI answer as a text message, storing the
+// knowledge base citations in the message metadata so agents can check the
+// sources the answer was based on
+func (a *App) sendAndSaveAIResponse(account *models.WhatsAppAccount, contact *models.Contact, message string, citations []KnowledgeCitation) error {
+	req := OutgoingMessageRequest{
+		Account: account,
+		Contact: contact,
+		Type:    models.MessageTypeText,
+		Content: message,
+	}
+	if len(citations) > 0 {
+		req.Metadata = models.JSONB{"ai_citations": citations}
+	}
+	_, err := a.SendOutgoingMessage(context.Background(), req, ChatbotSendOptions())
+	return err
+}
+
+// isAIConfigured reports whether AI responses are enabled and usable
+func isAIConfigured(cfg models.AIConfig) bool {
+	if !cfg.Enabled || cfg.Provider == "" {
+		return false
+	}
+	return cfg.APIKey != "" || !ai.RequiresAPIKey(string(cfg.Provider))
+}
+
+// recordAIUsage adds a response's token usage to the session
+func (a *App) recordAIUsage(session *models.ChatbotSession, usage ai.Usage) {
+	if usage.Total() == 0 {
+		return
+	}
+	if err := a.DB.Model(&models.ChatbotSession{}).Where("id = ?", session.ID).UpdateColumns(map[string]interface{}{
+		"ai_prompt_tokens":     gorm.Expr("ai_prompt_tokens + ?", usage.PromptTokens),
+		"ai_completion_tokens": gorm.Expr("ai_completion_tokens + ?", usage.CompletionTokens),
+	}).Error; err != nil {
+		a.Log.Error("Failed to record AI token usage", "error", err, "session_id", session.ID)
+		return
+	}
+	session.AIPromptTokens += usage.PromptTokens
+	session.AICompletionTokens += usage.CompletionTokens
+}
+
+// aiHeadersFromJSONB converts stored provider headers to a string map
+func aiHeadersFromJSONB(stored models.JSONB) map[string]string {
+	headers := make(map[string]string, len(stored))
+	for k, v := range stored {
+		if s, ok := v.(string); ok {
+			headers[k] = s
+		}
+	}
+	return headers
+}
+
+// parseAIFallbacks reads the fallback providers stored in an AI config
+func parseAIFallbacks(stored models.JSONBArray) ([]models.AIFallback, error) {
+	fallbacks := []models.AIFallback{}
+	if len(stored) == 0 {
+		return fallbacks, nil
+	}
+	data, err := json.Marshal(stored)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &fallbacks); err != nil {
+		return nil, fmt.Errorf("invalid AI fallbacks: %w", err)
+	}
+	return fallbacks, nil
+}
+
+// mergeAIFallbacks validates requested fallbacks and converts them for
+// storage. A fallback sent without an API key keeps the stored key of the
+// fallback with the same provider and base URL, so clients can edit
+// fallbacks without knowing their keys.
+func mergeAIFallbacks(stored models.JSONBArray, requested []models.AIFallback) (models.JSONBArray, error) {
+	existing, err := parseAIFallbacks(stored)
+	if err != nil {
+		existing = nil
+	}
+
+	result := models.JSONBArray{}
+	for i, fb := range requested {
+		if fb.APIKey == "" {
+			for _, old := range existing {
+				if old.Provider == fb.Provider && old.BaseURL == fb.BaseURL {
+					fb.APIKey = old.APIKey
+					break
+				}
+			}
+		}
+		if err := validateAIFallback(fb); err != nil {
+			return nil, fmt.Errorf("fallback %d: %w", i+1, err)
+		}
+
+		data, err := json.Marshal(fb)
+		if err != nil {
+			return nil, err
+		}
+		var value map[string]interface{}
+		if err := json.Unmarshal(data, &value); err != nil {
+			return nil, err
+		}
+		result = append(result, value)
+	}
+	return result, nil
+}
+
+// validateAIConfig checks the provider and base URL of an enabled AI config.
+// The model and API key may still be filled in later.
+func validateAIConfig(cfg models.AIConfig) error {
+	if cfg.Provider == "" {
+		return nil
+	}
+	return ai.Config{Provider: string(cfg.Provider), BaseURL: cfg.BaseURL}.Validate()
+}
+
+// validateAIFallback checks that a fallback provider is complete
+func validateAIFallback(fb models.AIFallback) error {
+	if _, err := ai.New(ai.Config{Provider: string(fb.Provider), Model: fb.Model, BaseURL: fb.BaseURL}, nil); err != nil {
+		return err
+	}
+	if fb.APIKey == "" && ai.RequiresAPIKey(string(fb.Provider)) {
+		return errors.New("API key is required")
+	}
+	return nil
+}
+
+// aiFallbackResponses converts stored fallbacks for API responses without their credentials
+func aiFallbackResponses(stored models.JSONBArray) []AIFallbackResponse {
+	fallbacks, _ := parseAIFallbacks(stored)
+	resp := make([]AIFallbackResponse, 0, len(fallbacks))
+	for _, fb := range fallbacks {
+		resp = append(resp, AIFallbackResponse{
+			Provider:    fb.Provider,
+			Model:       fb.Model,
+			BaseURL:     fb.BaseURL,
+			HasAPIKey:   fb.APIKey != "",
+			HeaderNames: slices.Sorted(maps.Keys(fb.Headers)),
+		})
+	}
+	return resp
+}
@@ -0,0 +1,47 @@
+package handlers
+
+import (
+	"testing"
+
+	"github.com/shridarpatil/whatomate/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIsAIConfigured(t *testing.T) {
+	assert.False(t, isAIConfigured(models.AIConfig{Provider: models.AIProviderOpenAI, APIKey: "key"}), "disabled")
+	assert.False(t, isAIConfigured(models.AIConfig{Enabled: true, Provider: models.AIProviderOpenAI}), "missing API key")
+	assert.True(t, isAIConfigured(models.AIConfig{Enabled: true, Provider: models.AIProviderOpenAI, APIKey: "key"}))
+	assert.True(t, isAIConfigured(models.AIConfig{Enabled: true, Provider: models.AIProviderOpenAICompatible}), "self-hosted models need no key")
+}
+
+func TestMergeAIFallbacks(t *testing.T) {
+	stored, err := mergeAIFallbacks(nil, []models.AIFallback{
+		{Provider: models.AIProviderAnthropic, Model: "claude-3-haiku", APIKey: "sk-ant"},
+		{Provider: models.AIProviderOpenAICompatible, Model: "llama3", BaseURL: "http://ollama:11434/v1"},
+	})
+	require.NoError(t, err)
+	require.Len(t, stored, 2)
+
+	// The stored key is kept when the same provider is sent without one
+	merged, err := mergeAIFallbacks(stored, []models.AIFallback{
+		{Provider: models.AIProviderAnthropic, Model: "claude-3-5-haiku"},
+	})
+	require.NoError(t, err)
+	fallbacks, err := parseAIFallbacks(merged)
+	require.NoError(t, err)
+	require.Len(t, fallbacks, 1)
+	assert.Equal(t, "claude-3-5-haiku", fallbacks[0].Model)
+	assert.Equal(t, "sk-ant", fallbacks[0].APIKey)
+
+	_, err = mergeAIFallbacks(nil, []models.AIFallback{{Provider: models.AIProviderGoogle, Model: "gemini-pro"}})
+	assert.EqualError(t, err, "fallback 1: API key is required")
+	_, err = mergeAIFallbacks(nil, []models.AIFallback{{Provider: models.AIProviderOpenAICompatible, Model: "llama3"}})
+	assert.ErrorContains(t, err, "base URL is required")
+
+	resp := aiFallbackResponses(stored)
+	require.Len(t, resp, 2)
+	assert.True(t, resp[0].HasAPIKey)
+	assert.False(t, resp[1].HasAPIKey)
+	assert.Equal(t, "http://ollama:11434/v1", resp[1].BaseURL)
+}
@@ -0,0 +1,388 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/shridarpatil/whatomate/internal/ai"
+	"github.com/shridarpatil/whatomate/internal/models"
+	"gorm.io/gorm"
+)
+
+const (
+	// maxAIToolRounds is how many times the model may call tools before it
+	// has to answer
+	maxAIToolRounds = 3
+	// maxAIToolProducts limits the products returned by lookup_product
+	maxAIToolProducts = 5
+	// aiToolStepName is the step name of tool invocations in the session log
+	aiToolStepName = "ai_tool"
+)
+
+// contactFieldKeyPattern matches the metadata keys set_contact_field may write
+var contactFieldKeyPattern = regexp.MustCompile(`^[a-zA-Z][a-zA-Z0-9_]{0,49}$`)
+
+// aiToolOptions holds the names the model may choose from, so that it can
+// only reference teams, flows, tags and contexts that exist
+type aiToolOptions struct {
+	Teams    []string
+	Flows    []string
+	Tags     []string
+	Contexts []string
+}
+
+// aiToolRun is the conversation an AI turn's tool calls act on
+type aiToolRun struct {
+	settings    *models.ChatbotSettings
+	account     *models.WhatsAppAccount
+	contact     *models.Contact
+	session     *models.ChatbotSession
+	userMessage string
+}
+
+// aiToolAllowed reports whether a tool is in the allow-list of the AI settings
+func aiToolAllowed(cfg models.AIConfig, tool models.AITool) bool {
+	return slices.Contains(cfg.Tools, string(tool))
+}
+
+// validateAITools checks that every allow-listed tool exists
+func validateAITools(tools []string) error {
+	for _, name := range tools {
+		if !slices.Contains(models.AITools, models.AITool(name)) {
+			return fmt.Errorf("unknown AI tool: %s", name)
+		}
+	}
+	return nil
+}
+
+// stringProperty is a JSON schema string property
+func stringProperty(description string, enum []string) map[string]interface{} {
+	prop := map[string]interface{}{"type": "string", "description": description}
+	if len(enum) > 0 {
+		prop["enum"] = enum
+	}
+	return prop
+}
+
+// objectSchema is a JSON schema object with required string properties
+func objectSchema(properties map[string]interface{}, required ...string) map[string]interface{} {
+	schema := map[string]interface{}{"type": "object", "properties": properties}
+	if len(required) > 0 {
+		schema["required"] = required
+	}
+	return schema
+}
+
+// aiToolDefinitions returns the allow-listed tools. Tools that pick from a
+// list (teams, flows, tags, contexts) are left out when the list is empty.
+func aiToolDefinitions(allowed []string, opts aiToolOptions) []ai.Tool {
+	var tools []ai.Tool
+	for _, tool := range models.AITools {
+		if !slices.Contains(allowed, string(tool)) {
+			continue
+		}
+		switch tool {
+		case models.AIToolTransferToTeam:
+			if len(opts.Teams) == 0 {
+				continue
+			}
+			tools = append(tools, ai.Tool{
+				Name:        string(tool),
+				Description: "Hand the conversation over to a team of human agents. Use it when the customer asks for a person or you cannot help. This ends your turn.",
+				Parameters: objectSchema(map[string]interface{}{
+					"team":  stringProperty("Team to transfer to", opts.Teams),
+					"notes": stringProperty("Short summary of the conversation for the agent", nil),
+				}, "team"),
+			})
+		case models.AIToolAddTag:
+			if len(opts.Tags) == 0 {
+				continue
+			}
+			tools = append(tools, ai.Tool{
+				Name:        string(tool),
+				Description: "Add a tag to the contact.",
+				Parameters: objectSchema(map[string]interface{}{
+					"tag": stringProperty("Tag to add", opts.Tags),
+				}, "tag"),
+			})
+		case models.AIToolSetContactField:
+			tools = append(tools, ai.Tool{
+				Name:        string(tool),
+				Description: "Save a piece of information about the contact, such as their email address or order number.",
+				Parameters: objectSchema(map[string]interface{}{
+					"field": stringProperty("Field name: letters, digits and underscores, starting with a letter", nil),
+					"value": stringProperty("Value to save", nil),
+				}, "field", "value"),
+			})
+		case models.AIToolStartFlow:
+			if len(opts.Flows) == 0 {
+				continue
+			}
+			tools = append(tools, ai.Tool{
+				Name:        string(tool),
+				Description: "Start a guided chatbot flow with the customer. This ends your turn.",
+				Parameters: objectSchema(map[string]interface{}{
+					"flow": stringProperty("Flow to start", opts.Flows),
+				}, "flow"),
+			})
+		case models.AIToolLookupProduct:
+			tools = append(tools, ai.Tool{
+				Name:        string(tool),
+				Description: "Search the product catalog by product name or SKU.",
+				Parameters: objectSchema(map[string]interface{}{
+					"query": stringProperty("Product name or SKU", nil),
+				}, "query"),
+			})
+		case models.AIToolCallContextAPI:
+			if len(opts.Contexts) == 0 {
+				continue
+			}
+			tools = append(tools, ai.Tool{
+				Name:        string(tool),
+				Description: "Fetch live data from a configured API.",
+				Parameters: objectSchema(map[string]interface{}{
+					"context": stringProperty("API to call", opts.Contexts),
+					"query":   stringProperty("Search text passed to the API as user_message; defaults to the customer's message", nil),
+				}, "context"),
+			})
+		}
+	}
+	return tools
+}
+
+// buildAITools loads the names the allow-listed tools choose from and returns
+// their definitions
+func (a *App) buildAITools(run *aiToolRun) []ai.Tool {
+	cfg := run.settings.AI
+	if len(cfg.Tools) == 0 || run.session == nil {
+		return nil
+	}
+
+	var opts aiToolOptions
+	if aiToolAllowed(cfg, models.AIToolTransferToTeam) {
+		if err := a.DB.Model(&models.Team{}).
+			Where("organization_id = ? AND is_active = ?", run.settings.OrganizationID, true).
+			Order("name ASC").Pluck("name", &opts.Teams).Error; err != nil {
+			a.Log.Error("Failed to load teams for AI tools", "error", err)
+		}
+	}
+	if aiToolAllowed(cfg, models.AIToolStartFlow) {
+		flows, err := a.getChatbotFlowsCached(run.settings.OrganizationID)
+		if err != nil {
+			a.Log.Error("Failed to load flows for AI tools", "error", err)
+		}
+		for _, flow := range flows {
+			opts.Flows = append(opts.Flows, flow.Name)
+		}
+	}
+	if aiToolAllowed(cfg, models.AIToolAddTag) {
+		tags, err := a.getTagsCached(run.settings.OrganizationID)
+		if err != nil {
+			a.Log.Error("Failed to load tags for AI tools", "error", err)
+		}
+		for _, tag := range tags {
+			opts.Tags = append(opts.Tags, tag.Name)
+		}
+	}
+	if aiToolAllowed(cfg, models.AIToolCallContextAPI) {
+		contexts, err := a.getAIContextsCached(run.settings.OrganizationID, run.session.WhatsAppAccount)
+		if err != nil {
+			a.Log.Error("Failed to load AI contexts for AI tools", "error", err)
+		}
+		for _, c := range contexts {
+			if c.ContextType == models.ContextTypeAPI {
+				opts.Contexts = append(opts.Contexts, c.Name)
+			}
+		}
+	}
+	return aiToolDefinitions(cfg.Tools, opts)
+}
+
+// runAITool executes a tool call and returns the result for the model. Tools
+// that hand the conversation over (transfers and flows) return a handoff to
+// run once the AI's reply has been sent, and end the AI turn.
+func (a *App) runAITool(run *aiToolRun, call ai.ToolCall) (result map[string]interface{}, handoff func()) {
+	if !aiToolAllowed(run.settings.AI, models.AITool(call.Name)) {
+		return map[string]interface{}{"error": "tool is not enabled"}, nil
+	}
+
+	var args map[string]string
+	if err := json.Unmarshal(call.Arguments, &args); err != nil {
+		return map[string]interface{}{"error": "arguments must be an object of strings"}, nil
+	}
+
+	var err error
+	switch models.AITool(call.Name) {
+	case models.AIToolTransferToTeam:
+		handoff, err = a.aiToolTransferToTeam(run, args["team"], args["notes"])
+		result = map[string]interface{}{"transferred": true}
+	case models.AIToolAddTag:
+		err = a.aiToolAddTag(run, args["tag"])
+		result = map[string]interface{}{"tagged": true}
+	case models.AIToolSetContactField:
+		err = a.aiToolSetContactField(run, args["field"], args["value"])
+		result = map[string]interface{}{"saved": true}
+	case models.AIToolStartFlow:
+		handoff, err = a.aiToolStartFlow(run, args["flow"])
+		result = map[string]interface{}{"started": true}
+	case models.AIToolLookupProduct:
+		result, err = a.aiToolLookupProduct(run, args["query"])
+	case models.AIToolCallContextAPI:
+		result, err = a.aiToolCallContextAPI(run, args["context"], args["query"])
+	default:
+		err = fmt.Errorf("unknown tool")
+	}
+	if err != nil {
+		return map[string]interface{}{"error": err.Error()}, nil
+	}
+	return result, handoff
+}
+
+func (a *App) aiToolTransferToTeam(run *aiToolRun, name, notes string) (func(), error) {
+	var team models.Team
+	if err := a.DB.Where("organization_id = ? AND is_active = ? AND LOWER(name) = LOWER(?)", run.settings.OrganizationID, true, name).
+		First(&team).Error; err != nil {
+		return nil, fmt.Errorf("team not found: %s", name)
+	}
+	return func() {
+		a.createTransferToTeam(run.account, run.contact, team.ID, notes, models.TransferSourceAI, nil)
+	}, nil
+}
+
+func (a *App) aiToolAddTag(run *aiToolRun, name string) error {
+	tags, err := a.getTagsCached(run.settings.OrganizationID)
+	if err != nil {
+		return fmt.Errorf("failed to load tags")
+	}
+	idx := slices.IndexFunc(tags, func(t models.Tag) bool { return strings.EqualFold(t.Name, name) })
+	if idx < 0 {
+		return fmt.Errorf("tag not found: %s", name)
+	}
+
+	previous := contactTagNames(run.contact.Tags)
+	if slices.Contains(previous, tags[idx].Name) {
+		return nil
+	}
+	updated := append(slices.Clone(run.contact.Tags), tags[idx].Name)
+	if err := a.DB.Model(run.contact).Update("tags", updated).Error; err != nil {
+		a.Log.Error("Failed to add contact tag", "error", err, "contact_id", run.contact.ID)
+		return fmt.Errorf("failed to add tag")
+	}
+	run.contact.Tags = updated
+	a.dispatchContactTagged(run.settings.OrganizationID, uuid.Nil, run.contact, previous)
+	return nil
+}
+
+func (a *App) aiToolSetContactField(run *aiToolRun, field, value string) error {
+	if !contactFieldKeyPattern.MatchString(field) {
+		return fmt.Errorf("invalid field name: %s", field)
+	}
+	patch, _ := json.Marshal(map[string]string{field: value})
+	if err := a.DB.Model(run.contact).
+		Update("metadata", gorm.Expr("COALESCE(metadata, '{}'::jsonb) || ?::jsonb", string(patch))).Error; err != nil {
+		a.Log.Error("Failed to set contact field", "error", err, "contact_id", run.contact.ID)
+		return fmt.Errorf("failed to save field")
+	}
+	if run.contact.Metadata == nil {
+		run.contact.Metadata = models.JSONB{}
+	}
+	run.contact.Metadata[field] = value
+	return nil
+}
+
+func (a *App) aiToolStartFlow(run *aiToolRun, name string) (func(), error) {
+	flows, err := a.getChatbotFlowsCached(run.settings.OrganizationID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load flows")
+	}
+	idx := slices.IndexFunc(flows, func(f models.ChatbotFlow) bool { return strings.EqualFold(f.Name, name) })
+	if idx < 0 {
+		return nil, fmt.Errorf("flow not found: %s", name)
+	}
+	flow := flows[idx]
+	return func() {
+		a.startFlow(run.account, run.session, run.contact, &flow)
+	}, nil
+}
+
+func (a *App) aiToolLookupProduct(run *aiToolRun, query string) (map[string]interface{}, error) {
+	query = strings.TrimSpace(query)
+	if query == "" {
+		return nil, fmt.Errorf("query is required")
+	}
+
+	var products []models.CatalogProduct
+	pattern := "%" + query + "%"
+	if err := a.DB.Where("organization_id = ? AND is_active = ?", run.settings.OrganizationID, true).
+		Where("catalog_id IN (?)", a.DB.Model(&models.Catalog{}).Select("id").
+			Where("organization_id = ? AND whatsapp_account = ? AND is_active = ?", run.settings.OrganizationID, run.account.Name, true)).
+		Where("name ILIKE ? OR retailer_id ILIKE ?", pattern, pattern).
+		Order("name ASC").Limit(maxAIToolProducts).
+		Find(&products).Error; err != nil {
+		a.Log.Error("Failed to look up products", "error", err)
+		return nil, fmt.Errorf("failed to search products")
+	}
+
+	items := make([]map[string]interface{}, len(products))
+	for i, p := range products {
+		items[i] = map[string]interface{}{
+			"name":        p.Name,
+			"description": p.Description,
+			"price":       float64(p.Price) / 100,
+			"currency":    p.Currency,
+			"sku":         p.RetailerID,
+			"url":         p.URL,
+		}
+	}
+	return map[string]interface{}{"products": items}, nil
+}
+
+func (a *App) aiToolCallContextAPI(run *aiToolRun, name, query string) (map[string]interface{}, error) {
+	contexts, err := a.getAIContextsCached(run.settings.OrganizationID, run.session.WhatsAppAccount)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load contexts")
+	}
+	idx := slices.IndexFunc(contexts, func(c models.AIContext) bool {
+		return c.ContextType == models.ContextTypeAPI && strings.EqualFold(c.Name, name)
+	})
+	if idx < 0 {
+		return nil, fmt.Errorf("context not found: %s", name)
+	}
+
+	if query == "" {
+		query = run.userMessage
+	}
+	data, err := a.fetchAPIContext(contexts[idx].ApiConfig, run.session, query)
+	if err != nil {
+		a.Log.Error("Failed to fetch API context", "context_name", contexts[idx].Name, "error", err)
+		return nil, fmt.Errorf("API request failed")
+	}
+	return map[string]interface{}{"data": data}, nil
+}
+
+// logAIToolCall records a tool invocation in the chatbot session
+func (a *App) logAIToolCall(sessionID uuid.UUID, call ai.ToolCall, result map[string]interface{}) {
+	var args map[string]interface{}
+	_ = json.Unmarshal(call.Arguments, &args)
+
+	msg := models.ChatbotSessionMessage{
+		BaseModel: models.BaseModel{ID: uuid.New()},
+		SessionID: sessionID,
+		Direction: models.DirectionOutgoing,
+		Message:   fmt.Sprintf("%s(%s)", call.Name, call.Arguments),
+		StepName:  aiToolStepName,
+		ToolCall: models.JSONB{
+			"id":        call.ID,
+			"name":      call.Name,
+			"arguments": args,
+			"result":    result,
+		},
+	}
+	if err := a.DB.Create(&msg).Error; err != nil {
+		a.Log.Error("Failed to log AI tool call", "error", err)
+	}
+}
@@ -0,0 +1,196 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/shridarpatil/whatomate/internal/ai"
+	"github.com/shridarpatil/whatomate/internal/models"
+	"github.com/shridarpatil/whatomate/test/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func toolNames(tools []ai.Tool) []string {
+	names := make([]string, len(tools))
+	for i, tool := range tools {
+		names[i] = tool.Name
+	}
+	return names
+}
+
+func TestAIToolDefinitions(t *testing.T) {
+	all := make([]string, len(models.AITools))
+	for i, tool := range models.AITools {
+		all[i] = string(tool)
+	}
+
+	// Tools choosing from an empty list are left out
+	tools := aiToolDefinitions(all, aiToolOptions{})
+	assert.Equal(t, []string{"set_contact_field", "lookup_product"}, toolNames(tools))
+
+	tools = aiToolDefinitions(all, aiToolOptions{
+		Teams:    []string{"Sales"},
+		Flows:    []string{"Onboarding"},
+		Tags:     []string{"vip"},
+		Contexts: []string{"Orders"},
+	})
+	assert.Len(t, tools, len(models.AITools))
+	team := tools[0].Parameters["properties"].(map[string]interface{})["team"].(map[string]interface{})
+	assert.Equal(t, []string{"Sales"}, team["enum"])
+	assert.Equal(t, []string{"team"}, tools[0].Parameters["required"])
+
+	// Only allow-listed tools are offered
+	tools = aiToolDefinitions([]string{"add_tag"}, aiToolOptions{Teams: []string{"Sales"}, Tags: []string{"vip"}})
+	assert.Equal(t, []string{"add_tag"}, toolNames(tools))
+	assert.Empty(t, aiToolDefinitions(nil, aiToolOptions{Teams: []string{"Sales"}}))
+}
+
+func TestValidateAITools(t *testing.T) {
+	assert.NoError(t, validateAITools([]string{"transfer_to_team", "lookup_product"}))
+	assert.NoError(t, validateAITools(nil))
+	assert.EqualError(t, validateAITools([]string{"add_tag", "delete_contact"}), "unknown AI tool: delete_contact")
+}
+
+func TestRunAITool_RejectsInvalidCalls(t *testing.T) {
+	app := &App{Log: testutil.NopLogger()}
+	run := &aiToolRun{settings: &models.ChatbotSettings{AI: models.AIConfig{Tools: models.StringArray{"set_contact_field"}}}}
+
+	result, handoff := app.runAITool(run, ai.ToolCall{Name: "add_tag", Arguments: json.RawMessage(`{"tag":"vip"}`)})
+	assert.Equal(t, "tool is not enabled", result["error"])
+	assert.Nil(t, handoff)
+
+	result, _ = app.runAITool(run, ai.ToolCall{Name: "set_contact_field", Arguments: json.RawMessage(`{"field":1}`)})
+	assert.Equal(t, "arguments must be an object of strings", result["error"])
+
+	result, _ = app.runAITool(run, ai.ToolCall{Name: "set_contact_field", Arguments: json.RawMessage(`{"field":"bad key","value":"x"}`)})
+	assert.Equal(t, "invalid field name: bad key", result["error"])
+}
+
+// newToolCallingServer returns an OpenAI-compatible endpoint that answers
+// with the given responses in turn, capturing the requests it received
+func newToolCallingServer(t *testing.T, responses []string, requests *[]map[string]any) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payload map[string]any
+		_ = json.NewDecoder(r.Body).Decode(&payload)
+		*requests = append(*requests, payload)
+		_, _ = w.Write([]byte(responses[min(len(*requests), len(responses))-1]))
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestGenerateAIResponse_CallsToolsAndLogsThem(t *testing.T) {
+	app := newProcessorTestApp(t)
+	org, account := createProcessorTestOrg(t, app)
+	contact := testutil.CreateTestContactWith(t, app.DB, org.ID, testutil.WithContactAccount(account.Name))
+	team := models.Team{BaseModel: models.BaseModel{ID: uuid.New()}, OrganizationID: org.ID, Name: "Sales", IsActive: true}
+	require.NoError(t, app.DB.Create(&team).Error)
+	session := &models.ChatbotSession{
+		BaseModel:       models.BaseModel{ID: uuid.New()},
+		OrganizationID:  org.ID,
+		ContactID:       contact.ID,
+		WhatsAppAccount: account.Name,
+		PhoneNumber:     contact.PhoneNumber,
+		Status:          models.SessionStatusActive,
+	}
+	require.NoError(t, app.DB.Create(session).Error)
+
+	var requests []map[string]any
+	srv := newToolCallingServer(t, []string{
+		`{"choices":[{"message":{"tool_calls":[{"id":"call_1","function":{"name":"set_contact_field","arguments":"{\"field\":\"email\",\"value\":\"jo@example.com\"}"}}]}}]}`,
+		`{"choices":[{"message":{"content":"Connecting you to sales.","tool_calls":[{"id":"call_2","function":{"name":"transfer_to_team","arguments":"{\"team\":\"sales\"}"}}]}}]}`,
+	}, &requests)
+
+	settings := &models.ChatbotSettings{
+		OrganizationID: org.ID,
+		AI: models.AIConfig{
+			Enabled:  true,
+			Provider: models.AIProviderOpenAICompatible,
+			BaseURL:  srv.URL,
+			Model:    "llama3",
+			Tools:    models.StringArray{"set_contact_field", "transfer_to_team"},
+		},
+	}
+	reply, err := app.generateAIResponse(settings, account, contact, session, "I want to buy, my email is jo@example.com")
+	require.NoError(t, err)
+	assert.Equal(t, "Connecting you to sales.", reply.Content)
+	assert.NotNil(t, reply.Handoff, "the transfer runs after the reply is sent")
+
+	require.Len(t, requests, 2)
+	assert.Len(t, requests[0]["tools"], 2)
+	messages := requests[1]["messages"].([]any)
+	toolResult := messages[len(messages)-1].(map[string]any)
+	assert.Equal(t, "call_1", toolResult["tool_call_id"])
+	assert.JSONEq(t, `{"saved":true}`, toolResult["content"].(string))
+
+	var updated models.Contact
+	require.NoError(t, app.DB.First(&updated, contact.ID).Error)
+	assert.Equal(t, "jo@example.com", updated.Metadata["email"])
+
+	var logged []models.ChatbotSessionMessage
+	require.NoError(t, app.DB.Where("session_id = ? AND step_name = ?", session.ID, aiToolStepName).Order("created_at ASC").Find(&logged).Error)
+	require.Len(t, logged, 2)
+	assert.Equal(t, "set_contact_field", logged[0].ToolCall["name"])
+	assert.Equal(t, "transfer_to_team", logged[1].ToolCall["name"])
+	assert.Empty(t, app.getSessionHistory(session.ID, 10), "tool calls are not replayed as conversation history")
+}
+
+func TestGenerateAIResponse_RunsAllToolCallsBeforeHandoff(t *testing.T) {
+	app := newProcessorTestApp(t)
+	org, account := createProcessorTestOrg(t, app)
+	contact := testutil.CreateTestContactWith(t, app.DB, org.ID, testutil.WithContactAccount(account.Name))
+	for _, name := range []string{"Sales", "Support"} {
+		team := models.Team{BaseModel: models.BaseModel{ID: uuid.New()}, OrganizationID: org.ID, Name: name, IsActive: true}
+		require.NoError(t, app.DB.Create(&team).Error)
+	}
+	session := &models.ChatbotSession{
+		BaseModel:       models.BaseModel{ID: uuid.New()},
+		OrganizationID:  org.ID,
+		ContactID:       contact.ID,
+		WhatsAppAccount: account.Name,
+		PhoneNumber:     contact.PhoneNumber,
+		Status:          models.SessionStatusActive,
+	}
+	require.NoError(t, app.DB.Create(session).Error)
+
+	// The handoff comes first, followed by a field update and a second handoff
+	var requests []map[string]any
+	srv := newToolCallingServer(t, []string{
+		`{"choices":[{"message":{"content":"Connecting you to sales.","tool_calls":[` +
+			`{"id":"call_1","function":{"name":"transfer_to_team","arguments":"{\"team\":\"sales\"}"}},` +
+			`{"id":"call_2","function":{"name":"set_contact_field","arguments":"{\"field\":\"email\",\"value\":\"jo@example.com\"}"}},` +
+			`{"id":"call_3","function":{"name":"transfer_to_team","arguments":"{\"team\":\"support\"}"}}]}}]}`,
+	}, &requests)
+
+	settings := &models.ChatbotSettings{
+		OrganizationID: org.ID,
+		AI: models.AIConfig{
+			Enabled:  true,
+			Provider: models.AIProviderOpenAICompatible,
+			BaseURL:  srv.URL,
+			Model:    "llama3",
+			Tools:    models.StringArray{"set_contact_field", "transfer_to_team"},
+		},
+	}
+	reply, err := app.generateAIResponse(settings, account, contact, session, "Sales please, my email is jo@example.com")
+	require.NoError(t, err)
+	assert.Equal(t, "Connecting you to sales.", reply.Content)
+	assert.NotNil(t, reply.Handoff)
+	assert.Len(t, requests, 1, "the model is not called again after a handoff")
+
+	var updated models.Contact
+	require.NoError(t, app.DB.First(&updated, contact.ID).Error)
+	assert.Equal(t, "jo@example.com", updated.Metadata["email"], "calls after the handoff still run")
+
+	var logged []models.ChatbotSessionMessage
+	require.NoError(t, app.DB.Where("session_id = ? AND step_name = ?", session.ID, aiToolStepName).Order("created_at ASC").Find(&logged).Error)
+	require.Len(t, logged, 3)
+	assert.Equal(t, "call_3", logged[2].ToolCall["id"])
+	result, _ := logged[2].ToolCall["result"].(map[string]interface{})
+	assert.Contains(t, result["error"], "already handed off", "only the first handoff is kept")
+}
@@ -0,0 +1,289 @@
+package handlers
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shridarpatil/whatomate/internal/models"
+	"github.com/valyala/fasthttp"
+	"github.com/zerodha/fastglue"
+	"gorm.io/gorm"
+)
+
+// FlowStepFunnel is one step of a flow funnel. Reached is the number of
+// sessions the step was sent to; DroppedOff is the number of sessions that
+// never completed the flow and stopped at this step.
+type FlowStepFunnel struct {
+	StepName    string  `json:"step_name"`
+	StepOrder   int     `json:"step_order"`
+	Reached     int64   `json:"reached"`
+	DroppedOff  int64   `json:"dropped_off"`
+	DropOffRate float64 `json:"drop_off_rate"`
+}
+
+// FlowFunnel is the funnel of a chatbot flow
+type FlowFunnel struct {
+	FlowID         string           `json:"flow_id"`
+	FlowName       string           `json:"flow_name"`
+	Started        int64            `json:"started"`
+	Completed      int64            `json:"completed"`
+	CompletionRate float64          `json:"completion_rate"`
+	Steps          []FlowStepFunnel `json:"steps"`
+}
+
+// KeywordRuleHits is the number of times a keyword rule responded
+type KeywordRuleHits struct {
+	RuleID   string `json:"rule_id"`
+	RuleName string `json:"rule_name"`
+	Hits     int64  `json:"hits"`
+}
+
+// ChatbotAnalyticsResponse is the response of GetChatbotAnalytics
+type ChatbotAnalyticsResponse struct {
+	TotalSessions     int64             `json:"total_sessions"`
+	SessionsByStatus  map[string]int64  `json:"sessions_by_status"`
+	Flows             []FlowFunnel      `json:"flows"`
+	KeywordHits       []KeywordRuleHits `json:"keyword_hits"`
+	MessagesHandled   int64             `json:"messages_handled"`
+	AIResponses       int64             `json:"ai_responses"`
+	AIResponseRate    float64           `json:"ai_response_rate"`
+	AgentTransfers    int64             `json:"agent_transfers"`
+	TransfersBySource map[string]int64  `json:"transfers_by_source"`
+	TransferRate      float64           `json:"transfer_rate"`
+	From              string            `json:"from"`
+	To                string            `json:"to"`
+}
+
+// GetChatbotAnalytics returns session outcomes, flow funnels, keyword rule
+// hits, the AI response rate and the bot-to-agent transfer rate
+func (a *App) GetChatbotAnalytics(r *fastglue.Request) error {
+	orgID, userID, err := a.getOrgAndUserID(r)
+	if err != nil {
+		return r.SendErrorEnvelope(fasthttp.StatusUnauthorized, "Unauthorized", nil, "")
+	}
+
+	if err := a.requirePermission(r, userID, models.ResourceAnalytics, models.ActionRead); err != nil {
+		return nil
+	}
+
+	periodStart, periodEnd, err := parseAnalyticsPeriod(r)
+	if err != nil {
+		return nil
+	}
+	account := string(r.RequestCtx.QueryArgs().Peek("whatsapp_account"))
+
+	sessions := func() *gorm.DB {
+		q := a.DB.Model(&models.ChatbotSession{}).
+			Where("chatbot_sessions.organization_id = ? AND chatbot_sessions.created_at >= ? AND chatbot_sessions.created_at <= ?",
+				orgID, periodStart, periodEnd)
+		if account != "" {
+			q = q.Where("chatbot_sessions.whats_app_account = ?", account)
+		}
+		return q
+	}
+	sessionMessages := func() *gorm.DB {
+		q := a.DB.Model(&models.ChatbotSessionMessage{}).
+			Joins("JOIN chatbot_sessions ON chatbot_sessions.id = chatbot_session_messages.session_id").
+			Where("chatbot_sessions.organization_id = ? AND chatbot_session_messages.created_at >= ? AND chatbot_session_messages.created_at <= ?",
+				orgID, periodStart, periodEnd)
+		if account != "" {
+			q = q.Where("chatbot_sessions.whats_app_account = ?", account)
+		}
+		return q
+	}
+
+	response := ChatbotAnalyticsResponse{
+		SessionsByStatus:  map[string]int64{},
+		Flows:             []FlowFunnel{},
+		KeywordHits:       []KeywordRuleHits{},
+		TransfersBySource: map[string]int64{},
+		From:              periodStart.Format(time.RFC3339),
+		To:                periodEnd.Format(time.RFC3339),
+	}
+
+	// Sessions by outcome
+	type countRow struct {
+		Key   string
+		Count int64
+	}
+	var statusRows []countRow
+	if err := sessions().Select("status AS key, COUNT(*) AS count").Group("status").Scan(&statusRows).Error; err != nil {
+		a.Log.Error("Failed to load chatbot analytics", "error", err)
+		return r.SendErrorEnvelope(fasthttp.StatusInternalServerError, "Failed to load chatbot analytics", nil, "")
+	}
+	for _, row := range statusRows {
+		response.SessionsByStatus[row.Key] = row.Count
+		response.TotalSessions += row.Count
+	}
+
+	flows, err := a.calculateFlowFunnels(orgID, sessions, sessionMessages)
+	if err != nil {
+		a.Log.Error("Failed to load chatbot analytics", "error", err)
+		return r.SendErrorEnvelope(fasthttp.StatusInternalServerError, "Failed to load chatbot analytics", nil, "")
+	}
+	response.Flows = flows
+
+	// Keyword rule hits
+	type hitRow struct {
+		RuleID uuid.UUID
+		Name   string
+		Hits   int64
+	}
+	var hits []hitRow
+	if err := sessionMessages().
+		Select("chatbot_session_messages.keyword_rule_id AS rule_id, keyword_rules.name AS name, COUNT(*) AS hits").
+		Joins("JOIN keyword_rules ON keyword_rules.id = chatbot_session_messages.keyword_rule_id").
+		Group("chatbot_session_messages.keyword_rule_id, keyword_rules.name").
+		Order("hits DESC").
+		Scan(&hits).Error; err != nil {
+		a.Log.Error("Failed to load chatbot analytics", "error", err)
+		return r.SendErrorEnvelope(fasthttp.StatusInternalServerError, "Failed to load chatbot analytics", nil, "")
+	}
+	for _, h := range hits {
+		response.KeywordHits = append(response.KeywordHits, KeywordRuleHits{
+			RuleID:   h.RuleID.String(),
+			RuleName: h.Name,
+			Hits:     h.Hits,
+		})
+	}
+
+	// AI response rate over the incoming messages handled by the chatbot
+	if err := sessionMessages().
+		Where("chatbot_session_messages.direction = ? AND chatbot_session_messages.step_name = ?", models.DirectionIncoming, "keyword_check").
+		Count(&response.MessagesHandled).Error; err != nil {
+		a.Log.Error("Failed to load chatbot analytics", "error", err)
+		return r.SendErrorEnvelope(fasthttp.StatusInternalServerError, "Failed to load chatbot analytics", nil, "")
+	}
+	if err := sessionMessages().
+		Where("chatbot_session_messages.direction = ? AND chatbot_session_messages.step_name = ?", models.DirectionOutgoing, "ai_response").
+		Count(&response.AIResponses).Error; err != nil {
+		a.Log.Error("Failed to load chatbot analytics", "error", err)
+		return r.SendErrorEnvelope(fasthttp.StatusInternalServerError, "Failed to load chatbot analytics", nil, "")
+	}
+	response.AIResponseRate = percentage(response.AIResponses, response.MessagesHandled)
+
+	// Bot-to-agent transfers
+	transfers := a.DB.Model(&models.AgentTransfer{}).
+		Where("organization_id = ? AND transferred_at >= ? AND transferred_at <= ? AND source IN ?",
+			orgID, periodStart, periodEnd, []models.TransferSource{models.TransferSourceFlow, models.TransferSourceKeyword})
+	if account != "" {
+		transfers = transfers.Where("whats_app_account = ?", account)
+	}
+	var transferRows []countRow
+	if err := transfers.Select("source AS key, COUNT(*) AS count").Group("source").Scan(&transferRows).Error; err != nil {
+		a.Log.Error("Failed to load chatbot analytics", "error", err)
+		return r.SendErrorEnvelope(fasthttp.StatusInternalServerError, "Failed to load chatbot analytics", nil, "")
+	}
+	for _, row := range transferRows {
+		response.TransfersBySource[row.Key] = row.Count
+		response.AgentTransfers += row.Count
+	}
+	response.TransferRate = percentage(response.AgentTransfers, response.TotalSessions)
+
+	return r.SendEnvelope(response)
+}
+
+// calculateFlowFunnels builds the funnel of every flow with sessions in the
+// period. Steps are ordered by step_order.
+func (a *App) calculateFlowFunnels(orgID uuid.UUID, sessions, sessionMessages func() *gorm.DB) ([]FlowFunnel, error) {
+	type flowRow struct {
+		FlowID    uuid.UUID
+		Started   int64
+		Completed int64
+	}
+	var flowRows []flowRow
+	if err := sessions().
+		Select("current_flow_id AS flow_id, COUNT(*) AS started, COUNT(*) FILTER (WHERE status = ?) AS completed", models.SessionStatusCompleted).
+		Where("current_flow_id IS NOT NULL").
+		Group("current_flow_id").
+		Order("started DESC").
+		Scan(&flowRows).Error; err != nil {
+		return nil, err
+	}
+	if len(flowRows) == 0 {
+		return []FlowFunnel{}, nil
+	}
+
+	flowIDs := make([]uuid.UUID, len(flowRows))
+	for i, row := range flowRows {
+		flowIDs[i] = row.FlowID
+	}
+	var flows []models.ChatbotFlow
+	if err := a.DB.Where("organization_id = ? AND id IN ?", orgID, flowIDs).
+		Preload("Steps", func(db *gorm.DB) *gorm.DB {
+			return db.Order("step_order ASC")
+		}).
+		Find(&flows).Error; err != nil {
+		return nil, err
+	}
+	flowsByID := make(map[uuid.UUID]*models.ChatbotFlow, len(flows))
+	for i := range flows {
+		flowsByID[flows[i].ID] = &flows[i]
+	}
+
+	// Distinct sessions each step was sent to
+	type stepRow struct {
+		FlowID   uuid.UUID
+		StepName string
+		Count    int64
+	}
+	var reachedRows []stepRow
+	if err := sessionMessages().
+		Select("chatbot_sessions.current_flow_id AS flow_id, chatbot_session_messages.step_name AS step_name, COUNT(DISTINCT chatbot_session_messages.session_id) AS count").
+		Where("chatbot_sessions.current_flow_id IN ? AND chatbot_session_messages.direction = ?", flowIDs, models.DirectionOutgoing).
+		Group("chatbot_sessions.current_flow_id, chatbot_session_messages.step_name").
+		Scan(&reachedRows).Error; err != nil {
+		return nil, err
+	}
+
+	// Sessions that did not complete, by the step they stopped at
+	var droppedRows []stepRow
+	if err := sessions().
+		Select("current_flow_id AS flow_id, current_step AS step_name, COUNT(*) AS count").
+		Where("current_flow_id IN ? AND status <> ? AND current_step <> ''", flowIDs, models.SessionStatusCompleted).
+		Group("current_flow_id, current_step").
+		Scan(&droppedRows).Error; err != nil {
+		return nil, err
+	}
+
+	type stepKey struct {
+		flowID uuid.UUID
+		step   string
+	}
+	reached := make(map[stepKey]int64, len(reachedRows))
+	for _, row := range reachedRows {
+		reached[stepKey{row.FlowID, row.StepName}] = row.Count
+	}
+	dropped := make(map[stepKey]int64, len(droppedRows))
+	for _, row := range droppedRows {
+		dropped[stepKey{row.FlowID, row.StepName}] = row.Count
+	}
+
+	funnels := make([]FlowFunnel, 0, len(flowRows))
+	for _, row := range flowRows {
+		flow, ok := flowsByID[row.FlowID]
+		if !ok {
+			continue // Flow was deleted
+		}
+		funnel := FlowFunnel{
+			FlowID:         flow.ID.String(),
+			FlowName:       flow.Name,
+			Started:        row.Started,
+			Completed:      row.Completed,
+			CompletionRate: percentage(row.Completed, row.Started),
+			Steps:          make([]FlowStepFunnel, 0, len(flow.Steps)),
+		}
+		for _, step := range flow.Steps {
+			key := stepKey{flow.ID, step.StepName}
+			funnel.Steps = append(funnel.Steps, FlowStepFunnel{
+				StepName:    step.StepName,
+				StepOrder:   step.StepOrder,
+				Reached:     reached[key],
+				DroppedOff:  dropped[key],
+				DropOffRate: percentage(dropped[key], reached[key]),
+			})
+		}
+		funnels = append(funnels, funnel)
+	}
+	return funnels, nil
+}
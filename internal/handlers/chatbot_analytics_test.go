@@ -0,0 +1,133 @@
+package handlers_test
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shridarpatil/whatomate/internal/handlers"
+	"github.com/shridarpatil/whatomate/internal/models"
+	"github.com/shridarpatil/whatomate/test/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/valyala/fasthttp"
+)
+
+// logTestSessionMessage adds a message to a chatbot session
+func logTestSessionMessage(t *testing.T, app *handlers.App, sessionID uuid.UUID, direction models.Direction, stepName string, ruleID *uuid.UUID) {
+	t.Helper()
+	require.NoError(t, app.DB.Create(&models.ChatbotSessionMessage{
+		SessionID:     sessionID,
+		Direction:     direction,
+		Message:       "msg",
+		StepName:      stepName,
+		KeywordRuleID: ruleID,
+	}).Error)
+}
+
+func TestApp_GetChatbotAnalytics_Success(t *testing.T) {
+	app := newTestApp(t)
+	org := testutil.CreateTestOrganization(t, app.DB)
+	adminRole := testutil.CreateAdminRole(t, app.DB, org.ID)
+	user := testutil.CreateTestUser(t, app.DB, org.ID, testutil.WithRoleID(&adminRole.ID))
+	contact := testutil.CreateTestContact(t, app.DB, org.ID)
+	now := time.Now().UTC()
+
+	flow := &models.ChatbotFlow{
+		OrganizationID:  org.ID,
+		WhatsAppAccount: "test-account",
+		Name:            "Signup",
+		IsEnabled:       true,
+		Steps: []models.ChatbotFlowStep{
+			{StepName: "ask_name", StepOrder: 1, Message: "Name?"},
+			{StepName: "ask_email", StepOrder: 2, Message: "Email?"},
+		},
+	}
+	require.NoError(t, app.DB.Create(flow).Error)
+
+	rule := &models.KeywordRule{
+		OrganizationID:  org.ID,
+		WhatsAppAccount: "test-account",
+		Name:            "Pricing",
+		Keywords:        models.StringArray{"price"},
+		ResponseType:    models.ResponseTypeText,
+		ResponseContent: models.JSONB{"body": "See our pricing page"},
+	}
+	require.NoError(t, app.DB.Create(rule).Error)
+
+	// Completed the flow
+	completed := createTestChatbotSession(t, app, org.ID, contact.ID, now.Add(-time.Hour))
+	require.NoError(t, app.DB.Model(completed).Updates(map[string]any{"status": models.SessionStatusCompleted, "current_flow_id": flow.ID}).Error)
+	logTestSessionMessage(t, app, completed.ID, models.DirectionOutgoing, "ask_name", nil)
+	logTestSessionMessage(t, app, completed.ID, models.DirectionOutgoing, "ask_email", nil)
+
+	// Stopped at the first step
+	dropped := createTestChatbotSession(t, app, org.ID, contact.ID, now.Add(-time.Hour))
+	require.NoError(t, app.DB.Model(dropped).Updates(map[string]any{"status": models.SessionStatusCancelled, "current_flow_id": flow.ID, "current_step": "ask_name"}).Error)
+	logTestSessionMessage(t, app, dropped.ID, models.DirectionOutgoing, "ask_name", nil)
+
+	// Keyword and AI responses outside of a flow
+	other := createTestChatbotSession(t, app, org.ID, contact.ID, now.Add(-time.Hour))
+	logTestSessionMessage(t, app, other.ID, models.DirectionIncoming, "keyword_check", nil)
+	logTestSessionMessage(t, app, other.ID, models.DirectionOutgoing, "keyword_response", &rule.ID)
+	logTestSessionMessage(t, app, other.ID, models.DirectionIncoming, "keyword_check", nil)
+	logTestSessionMessage(t, app, other.ID, models.DirectionOutgoing, "ai_response", nil)
+
+	createTestAgentTransfer(t, app, org.ID, contact.ID, nil, models.TransferStatusActive, models.TransferSourceKeyword, now.Add(-time.Hour), nil)
+	createTestAgentTransfer(t, app, org.ID, contact.ID, nil, models.TransferStatusActive, models.TransferSourceManual, now.Add(-time.Hour), nil)
+
+	req := testutil.NewGETRequest(t)
+	testutil.SetAuthContext(req, org.ID, user.ID)
+
+	require.NoError(t, app.GetChatbotAnalytics(req))
+	require.Equal(t, fasthttp.StatusOK, testutil.GetResponseStatusCode(req))
+
+	var resp struct {
+		Data handlers.ChatbotAnalyticsResponse `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(testutil.GetResponseBody(req), &resp))
+
+	assert.Equal(t, int64(3), resp.Data.TotalSessions)
+	assert.Equal(t, int64(1), resp.Data.SessionsByStatus["completed"])
+	assert.Equal(t, int64(1), resp.Data.SessionsByStatus["cancelled"])
+	assert.Equal(t, int64(1), resp.Data.SessionsByStatus["active"])
+
+	require.Len(t, resp.Data.Flows, 1)
+	funnel := resp.Data.Flows[0]
+	assert.Equal(t, "Signup", funnel.FlowName)
+	assert.Equal(t, int64(2), funnel.Started)
+	assert.Equal(t, int64(1), funnel.Completed)
+	require.Len(t, funnel.Steps, 2)
+	assert.Equal(t, "ask_name", funnel.Steps[0].StepName)
+	assert.Equal(t, int64(2), funnel.Steps[0].Reached)
+	assert.Equal(t, int64(1), funnel.Steps[0].DroppedOff)
+	assert.Equal(t, 50.0, funnel.Steps[0].DropOffRate)
+	assert.Equal(t, int64(1), funnel.Steps[1].Reached)
+	assert.Equal(t, int64(0), funnel.Steps[1].DroppedOff)
+
+	require.Len(t, resp.Data.KeywordHits, 1)
+	assert.Equal(t, rule.ID.String(), resp.Data.KeywordHits[0].RuleID)
+	assert.Equal(t, "Pricing", resp.Data.KeywordHits[0].RuleName)
+	assert.Equal(t, int64(1), resp.Data.KeywordHits[0].Hits)
+
+	assert.Equal(t, int64(2), resp.Data.MessagesHandled)
+	assert.Equal(t, int64(1), resp.Data.AIResponses)
+	assert.Equal(t, 50.0, resp.Data.AIResponseRate)
+
+	// Manual transfers are not bot-to-agent transfers
+	assert.Equal(t, int64(1), resp.Data.AgentTransfers)
+	assert.Equal(t, int64(1), resp.Data.TransfersBySource["keyword"])
+}
+
+func TestApp_GetChatbotAnalytics_NoPermission(t *testing.T) {
+	app := newTestApp(t)
+	org := testutil.CreateTestOrganization(t, app.DB)
+	user := testutil.CreateTestUser(t, app.DB, org.ID)
+
+	req := testutil.NewGETRequest(t)
+	testutil.SetAuthContext(req, org.ID, user.ID)
+
+	require.NoError(t, app.GetChatbotAnalytics(req))
+	assert.Equal(t, fasthttp.StatusForbidden, testutil.GetResponseStatusCode(req))
+}
@@ -0,0 +1,692 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"reflect"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shridarpatil/whatomate/internal/models"
+	"github.com/valyala/fasthttp"
+	"github.com/zerodha/fastglue"
+	"gorm.io/gorm"
+)
+
+// flowDraftRef refers to the editable draft in flow version diffs
+const flowDraftRef = "draft"
+
+// flowSnapshotIgnoredFields are flow and step fields that are not part of a
+// flow's versioned definition
+var flowSnapshotIgnoredFields = map[string]bool{
+	"id":                true,
+	"created_at":        true,
+	"updated_at":        true,
+	"organization_id":   true,
+	"whatsapp_account":  true,
+	"is_enabled":        true,
+	"published_version": true,
+	"test_cases":        true,
+	"flow_id":           true,
+	"steps":             true,
+	"organization":      true,
+	"initial_template":  true,
+	"flow":              true,
+	"template":          true,
+}
+
+// ChatbotFlowVersionResponse describes a published flow version
+type ChatbotFlowVersionResponse struct {
+	Version        int                 `json:"version"`
+	Notes          string              `json:"notes"`
+	RolledBackFrom *int                `json:"rolled_back_from,omitempty"`
+	PublishedByID  *uuid.UUID          `json:"published_by_id,omitempty"`
+	PublishedBy    string              `json:"published_by,omitempty"`
+	PublishedAt    time.Time           `json:"published_at"`
+	IsPublished    bool                `json:"is_published"`    // Version new sessions start on
+	ActiveSessions int64               `json:"active_sessions"` // Sessions currently pinned to this version
+	Flow           *models.ChatbotFlow `json:"flow,omitempty"`
+}
+
+// FlowFieldChange is a changed field in a flow version diff
+type FlowFieldChange struct {
+	Field string      `json:"field"`
+	From  interface{} `json:"from"`
+	To    interface{} `json:"to"`
+}
+
+// FlowStepChange lists the changed fields of a step present in both versions
+type FlowStepChange struct {
+	StepName string            `json:"step_name"`
+	Fields   []FlowFieldChange `json:"fields"`
+}
+
+// FlowVersionDiff describes the changes between two versions of a flow
+type FlowVersionDiff struct {
+	From         string            `json:"from"`
+	To           string            `json:"to"`
+	Fields       []FlowFieldChange `json:"fields"`
+	StepsAdded   []string          `json:"steps_added"`
+	StepsRemoved []string          `json:"steps_removed"`
+	StepsChanged []FlowStepChange  `json:"steps_changed"`
+}
+
+// snapshotFlow serializes a flow and its steps for storing as a version
+func snapshotFlow(flow *models.ChatbotFlow) (models.JSONB, error) {
+	data, err := json.Marshal(flow)
+	if err != nil {
+		return nil, err
+	}
+	var snapshot models.JSONB
+	if err := json.Unmarshal(data, &snapshot); err != nil {
+		return nil, err
+	}
+	return snapshot, nil
+}
+
+// flowFromSnapshot rebuilds a flow from a version snapshot. The identity and
+// enabled state come from the live flow, since they are not versioned.
+func flowFromSnapshot(live *models.ChatbotFlow, snapshot models.JSONB, version int) (*models.ChatbotFlow, error) {
+	data, err := json.Marshal(snapshot)
+	if err != nil {
+		return nil, err
+	}
+	var flow models.ChatbotFlow
+	if err := json.Unmarshal(data, &flow); err != nil {
+		return nil, fmt.Errorf("invalid flow snapshot: %w", err)
+	}
+	flow.BaseModel = live.BaseModel
+	flow.OrganizationID = live.OrganizationID
+	flow.WhatsAppAccount = live.WhatsAppAccount
+	flow.IsEnabled = live.IsEnabled
+	flow.PublishedVersion = version
+	for i := range flow.Steps {
+		flow.Steps[i].FlowID = live.ID
+	}
+	sort.SliceStable(flow.Steps, func(i, j int) bool { return flow.Steps[i].StepOrder < flow.Steps[j].StepOrder })
+	return &flow, nil
+}
+
+// applyPublishedFlowVersions replaces flows that have a published version with
+// that version, so the runtime never sees unpublished draft edits
+func (a *App) applyPublishedFlowVersions(orgID uuid.UUID, flows []models.ChatbotFlow) ([]models.ChatbotFlow, error) {
+	if !slices.ContainsFunc(flows, func(f models.ChatbotFlow) bool { return f.PublishedVersion > 0 }) {
+		return flows, nil
+	}
+
+	var versions []models.ChatbotFlowVersion
+	if err := a.DB.Joins("JOIN chatbot_flows ON chatbot_flows.id = chatbot_flow_versions.flow_id AND chatbot_flows.published_version = chatbot_flow_versions.version").
+		Where("chatbot_flow_versions.organization_id = ?", orgID).
+		Find(&versions).Error; err != nil {
+		return nil, err
+	}
+	byFlow := make(map[uuid.UUID]*models.ChatbotFlowVersion, len(versions))
+	for i := range versions {
+		byFlow[versions[i].FlowID] = &versions[i]
+	}
+
+	for i := range flows {
+		if flows[i].PublishedVersion == 0 {
+			continue
+		}
+		v, ok := byFlow[flows[i].ID]
+		if !ok {
+			a.Log.Warn("Published flow version missing, using draft", "flow_id", flows[i].ID, "version", flows[i].PublishedVersion)
+			continue
+		}
+		published, err := flowFromSnapshot(&flows[i], v.Snapshot, v.Version)
+		if err != nil {
+			return nil, err
+		}
+		flows[i] = *published
+	}
+	return flows, nil
+}
+
+// getSessionFlow loads the flow a session is running, at the version the
+// session started on, so that publishing changes never moves contacts that are
+// mid-flow onto renamed or deleted steps. Texts are in the session's language.
+func (a *App) getSessionFlow(session *models.ChatbotSession) (*models.ChatbotFlow, error) {
+	flow, err := a.getChatbotFlowByIDCached(session.OrganizationID, *session.CurrentFlowID)
+	if err != nil {
+		return nil, err
+	}
+	if session.FlowVersion != 0 && session.FlowVersion != flow.PublishedVersion {
+		var version models.ChatbotFlowVersion
+		if err := a.DB.Where("flow_id = ? AND version = ?", flow.ID, session.FlowVersion).First(&version).Error; err != nil {
+			return nil, fmt.Errorf("flow version %d not found: %w", session.FlowVersion, err)
+		}
+		if flow, err = flowFromSnapshot(flow, version.Snapshot, version.Version); err != nil {
+			return nil, err
+		}
+	}
+	return localizeFlow(flow, session.Language), nil
+}
+
+// validateFlowStepReferences checks that next_step and conditional_next only
+// point to steps that exist in the flow
+func validateFlowStepReferences(steps []models.ChatbotFlowStep) error {
+	names := make(map[string]bool, len(steps))
+	for _, step := range steps {
+		names[step.StepName] = true
+	}
+	for _, step := range steps {
+		if step.NextStep != "" && !names[step.NextStep] {
+			return fmt.Errorf("step %q: next_step %q does not exist", step.StepName, step.NextStep)
+		}
+		for _, key := range slices.Sorted(maps.Keys(step.ConditionalNext)) {
+			if next, ok := step.ConditionalNext[key].(string); ok && next != "" && !names[next] {
+				return fmt.Errorf("step %q: conditional_next %q points to missing step %q", step.StepName, key, next)
+			}
+		}
+	}
+	return nil
+}
+
+// diffFlows compares the versioned definition of two flows. Steps are matched by name.
+func diffFlows(from, to *models.ChatbotFlow) FlowVersionDiff {
+	diff := FlowVersionDiff{
+		Fields:       diffFlowFields(from, to),
+		StepsAdded:   []string{},
+		StepsRemoved: []string{},
+		StepsChanged: []FlowStepChange{},
+	}
+
+	fromSteps := make(map[string]*models.ChatbotFlowStep, len(from.Steps))
+	for i := range from.Steps {
+		fromSteps[from.Steps[i].StepName] = &from.Steps[i]
+	}
+	toSteps := make(map[string]bool, len(to.Steps))
+	for i := range to.Steps {
+		step := &to.Steps[i]
+		toSteps[step.StepName] = true
+		prev, ok := fromSteps[step.StepName]
+		if !ok {
+			diff.StepsAdded = append(diff.StepsAdded, step.StepName)
+			continue
+		}
+		if changes := diffFlowFields(prev, step); len(changes) > 0 {
+			diff.StepsChanged = append(diff.StepsChanged, FlowStepChange{StepName: step.StepName, Fields: changes})
+		}
+	}
+	for i := range from.Steps {
+		if !toSteps[from.Steps[i].StepName] {
+			diff.StepsRemoved = append(diff.StepsRemoved, from.Steps[i].StepName)
+		}
+	}
+	return diff
+}
+
+// diffFlowFields lists the versioned JSON fields that differ between two values
+func diffFlowFields(from, to interface{}) []FlowFieldChange {
+	fromMap, toMap := toJSONMap(from), toJSONMap(to)
+	keys := make(map[string]bool, len(fromMap)+len(toMap))
+	for k := range fromMap {
+		keys[k] = true
+	}
+	for k := range toMap {
+		keys[k] = true
+	}
+
+	changes := []FlowFieldChange{}
+	for _, k := range slices.Sorted(maps.Keys(keys)) {
+		if flowSnapshotIgnoredFields[k] || isEmptyJSONValue(fromMap[k]) && isEmptyJSONValue(toMap[k]) {
+			continue
+		}
+		if !reflect.DeepEqual(fromMap[k], toMap[k]) {
+			changes = append(changes, FlowFieldChange{Field: k, From: fromMap[k], To: toMap[k]})
+		}
+	}
+	return changes
+}
+
+// toJSONMap converts a value to its JSON object representation
+func toJSONMap(v interface{}) map[string]interface{} {
+	out := map[string]interface{}{}
+	if data, err := json.Marshal(v); err == nil {
+		_ = json.Unmarshal(data, &out)
+	}
+	return out
+}
+
+// isEmptyJSONValue reports whether a decoded JSON value is null or empty, so
+// that e.g. a null and an empty list are not reported as a change
+func isEmptyJSONValue(v interface{}) bool {
+	switch val := v.(type) {
+	case nil:
+		return true
+	case string:
+		return val == ""
+	case []interface{}:
+		return len(val) == 0
+	case map[string]interface{}:
+		return len(val) == 0
+	}
+	return false
+}
+
+// loadFlowDraft loads a flow with its draft steps in order
+func (a *App) loadFlowDraft(r *fastglue.Request, flowID, orgID uuid.UUID) (*models.ChatbotFlow, error) {
+	var flow models.ChatbotFlow
+	if err := a.DB.Where("id = ? AND organization_id = ?", flowID, orgID).
+		Preload("Steps", func(db *gorm.DB) *gorm.DB {
+			return db.Order("step_order ASC")
+		}).
+		First(&flow).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			_ = r.SendErrorEnvelope(fasthttp.StatusNotFound, "Flow not found", nil, "")
+		} else {
+			a.Log.Error("Failed to load flow", "error", err, "flow_id", flowID)
+			_ = r.SendErrorEnvelope(fasthttp.StatusInternalServerError, "Failed to load flow", nil, "")
+		}
+		return nil, errEnvelopeSent
+	}
+	return &flow, nil
+}
+
+// findFlowVersion loads a published version of a flow, sending a 404 if it doesn't exist
+func (a *App) findFlowVersion(r *fastglue.Request, flowID uuid.UUID, version int) (*models.ChatbotFlowVersion, error) {
+	var v models.ChatbotFlowVersion
+	if err := a.DB.Preload("PublishedBy").Where("flow_id = ? AND version = ?", flowID, version).First(&v).Error; err != nil {
+		_ = r.SendErrorEnvelope(fasthttp.StatusNotFound, "Flow version not found", nil, "")
+		return nil, errEnvelopeSent
+	}
+	return &v, nil
+}
+
+// parseFlowVersionParam parses a version number path or query parameter
+func parseFlowVersionParam(r *fastglue.Request, raw string) (int, error) {
+	version, err := strconv.Atoi(raw)
+	if err != nil || version < 1 {
+		_ = r.SendErrorEnvelope(fasthttp.StatusBadRequest, "Invalid version", nil, "")
+		return 0, errEnvelopeSent
+	}
+	return version, nil
+}
+
+// createFlowVersion snapshots flow as the next version and makes it the published one
+func (a *App) createFlowVersion(tx *gorm.DB, flow *models.ChatbotFlow, userID uuid.UUID, notes string, rolledBackFrom *int) (*models.ChatbotFlowVersion, error) {
+	snapshot, err := snapshotFlow(flow)
+	if err != nil {
+		return nil, fmt.Errorf("failed to snapshot flow: %w", err)
+	}
+
+	var latest int
+	if err := tx.Model(&models.ChatbotFlowVersion{}).Where("flow_id = ?", flow.ID).
+		Select("COALESCE(MAX(version), 0)").Scan(&latest).Error; err != nil {
+		return nil, err
+	}
+
+	version := &models.ChatbotFlowVersion{
+		BaseModel:      models.BaseModel{ID: uuid.New()},
+		OrganizationID: flow.OrganizationID,
+		FlowID:         flow.ID,
+		Version:        latest + 1,
+		Snapshot:       snapshot,
+		Notes:          notes,
+		RolledBackFrom: rolledBackFrom,
+		PublishedByID:  &userID,
+	}
+	if err := tx.Create(version).Error; err != nil {
+		return nil, err
+	}
+	if err := tx.Model(&models.ChatbotFlow{}).Where("id = ?", flow.ID).
+		Update("published_version", version.Version).Error; err != nil {
+		return nil, err
+	}
+	// Sessions started before the flow was first published ran the draft that
+	// is being published, so pin them to it
+	if flow.PublishedVersion == 0 {
+		if err := tx.Model(&models.ChatbotSession{}).
+			Where("current_flow_id = ? AND flow_version = 0 AND status = ?", flow.ID, models.SessionStatusActive).
+			Update("flow_version", version.Version).Error; err != nil {
+			return nil, err
+		}
+	}
+	return version, nil
+}
+
+// publishInitialFlowVersion publishes the draft of a flow that has never been
+// published as version 1 before its first edit. Contacts keep running what was
+// live, pinned to that version, instead of having steps rewritten under them.
+func (a *App) publishInitialFlowVersion(tx *gorm.DB, flow *models.ChatbotFlow, userID uuid.UUID) error {
+	if err := tx.Exec("SELECT id FROM chatbot_flows WHERE id = ? FOR UPDATE", flow.ID).Error; err != nil {
+		return err
+	}
+	var current models.ChatbotFlow
+	if err := tx.Where("id = ?", flow.ID).
+		Preload("Steps", func(db *gorm.DB) *gorm.DB {
+			return db.Order("step_order ASC")
+		}).
+		First(&current).Error; err != nil {
+		return err
+	}
+	if current.PublishedVersion == 0 {
+		version, err := a.createFlowVersion(tx, &current, userID, "Initial version", nil)
+		if err != nil {
+			return err
+		}
+		current.PublishedVersion = version.Version
+	}
+	flow.PublishedVersion = current.PublishedVersion
+	return nil
+}
+
+// flowVersionResponse builds the API response for a version
+func flowVersionResponse(v *models.ChatbotFlowVersion, publishedVersion int, activeSessions int64) ChatbotFlowVersionResponse {
+	resp := ChatbotFlowVersionResponse{
+		Version:        v.Version,
+		Notes:          v.Notes,
+		RolledBackFrom: v.RolledBackFrom,
+		PublishedByID:  v.PublishedByID,
+		PublishedAt:    v.CreatedAt,
+		IsPublished:    v.Version == publishedVersion,
+		ActiveSessions: activeSessions,
+	}
+	if v.PublishedBy != nil {
+		resp.PublishedBy = v.PublishedBy.FullName
+	}
+	return resp
+}
+
+// ListChatbotFlowVersions lists the published versions of a flow, newest first
+func (a *App) ListChatbotFlowVersions(r *fastglue.Request) error {
+	orgID, userID, err := a.getOrgAndUserID(r)
+	if err != nil {
+		return r.SendErrorEnvelope(fasthttp.StatusUnauthorized, "Unauthorized", nil, "")
+	}
+	if err := a.requirePermission(r, userID, models.ResourceFlowsChatbot, models.ActionRead); err != nil {
+		return nil
+	}
+
+	id, err := parsePathUUID(r, "id", "flow")
+	if err != nil {
+		return nil
+	}
+	flow, err := findByIDAndOrg[models.ChatbotFlow](a.DB, r, id, orgID, "Flow")
+	if err != nil {
+		return nil
+	}
+
+	var versions []models.ChatbotFlowVersion
+	if err := a.DB.Preload("PublishedBy").Where("flow_id = ?", flow.ID).
+		Order("version DESC").Find(&versions).Error; err != nil {
+		a.Log.Error("Failed to list flow versions", "error", err, "flow_id", flow.ID)
+		return r.SendErrorEnvelope(fasthttp.StatusInternalServerError, "Failed to list flow versions", nil, "")
+	}
+
+	var counts []struct {
+		FlowVersion int
+		Count       int64
+	}
+	a.DB.Model(&models.ChatbotSession{}).
+		Select("flow_version, COUNT(*) AS count").
+		Where("current_flow_id = ? AND status = ?", flow.ID, models.SessionStatusActive).
+		Group("flow_version").Scan(&counts)
+	active := make(map[int]int64, len(counts))
+	for _, c := range counts {
+		active[c.FlowVersion] = c.Count
+	}
+
+	resp := make([]ChatbotFlowVersionResponse, len(versions))
+	for i := range versions {
+		resp[i] = flowVersionResponse(&versions[i], flow.PublishedVersion, active[versions[i].Version])
+	}
+
+	return r.SendEnvelope(map[string]interface{}{
+		"versions":          resp,
+		"published_version": flow.PublishedVersion,
+	})
+}
+
+// GetChatbotFlowVersion returns a published version of a flow with its steps
+func (a *App) GetChatbotFlowVersion(r *fastglue.Request) error {
+	orgID, userID, err := a.getOrgAndUserID(r)
+	if err != nil {
+		return r.SendErrorEnvelope(fasthttp.StatusUnauthorized, "Unauthorized", nil, "")
+	}
+	if err := a.requirePermission(r, userID, models.ResourceFlowsChatbot, models.ActionRead); err != nil {
+		return nil
+	}
+
+	id, err := parsePathUUID(r, "id", "flow")
+	if err != nil {
+		return nil
+	}
+	versionStr, _ := r.RequestCtx.UserValue("version").(string)
+	versionNum, err := parseFlowVersionParam(r, versionStr)
+	if err != nil {
+		return nil
+	}
+	flow, err := findByIDAndOrg[models.ChatbotFlow](a.DB, r, id, orgID, "Flow")
+	if err != nil {
+		return nil
+	}
+	version, err := a.findFlowVersion(r, flow.ID, versionNum)
+	if err != nil {
+		return nil
+	}
+	snapshot, err := flowFromSnapshot(flow, version.Snapshot, version.Version)
+	if err != nil {
+		a.Log.Error("Failed to read flow version", "error", err, "flow_id", flow.ID, "version", versionNum)
+		return r.SendErrorEnvelope(fasthttp.StatusInternalServerError, "Failed to read flow version", nil, "")
+	}
+
+	var activeSessions int64
+	a.DB.Model(&models.ChatbotSession{}).
+		Where("current_flow_id = ? AND flow_version = ? AND status = ?", flow.ID, version.Version, models.SessionStatusActive).
+		Count(&activeSessions)
+
+	resp := flowVersionResponse(version, flow.PublishedVersion, activeSessions)
+	resp.Flow = snapshot
+	return r.SendEnvelope(resp)
+}
+
+// PublishChatbotFlow publishes the flow's draft as a new immutable version.
+// New sessions start on it; sessions already in the flow stay on their version.
+func (a *App) PublishChatbotFlow(r *fastglue.Request) error {
+	orgID, userID, err := a.getOrgAndUserID(r)
+	if err != nil {
+		return r.SendErrorEnvelope(fasthttp.StatusUnauthorized, "Unauthorized", nil, "")
+	}
+	if err := a.requirePermission(r, userID, models.ResourceFlowsChatbot, models.ActionWrite); err != nil {
+		return nil
+	}
+
+	id, err := parsePathUUID(r, "id", "flow")
+	if err != nil {
+		return nil
+	}
+
+	var req struct {
+		Notes string `json:"notes"`
+	}
+	if len(r.RequestCtx.PostBody()) > 0 {
+		if err := a.decodeRequest(r, &req); err != nil {
+			return nil
+		}
+	}
+
+	flow, err := a.loadFlowDraft(r, id, orgID)
+	if err != nil {
+		return nil
+	}
+	if err := validateFlowStepReferences(flow.Steps); err != nil {
+		return r.SendErrorEnvelope(fasthttp.StatusBadRequest, err.Error(), nil, "")
+	}
+
+	var version *models.ChatbotFlowVersion
+	if err := a.DB.Transaction(func(tx *gorm.DB) error {
+		// Serialize publishes of the same flow so version numbers don't collide
+		if err := tx.Exec("SELECT id FROM chatbot_flows WHERE id = ? FOR UPDATE", flow.ID).Error; err != nil {
+			return err
+		}
+		version, err = a.createFlowVersion(tx, flow, userID, strings.TrimSpace(req.Notes), nil)
+		return err
+	}); err != nil {
+		a.Log.Error("Failed to publish flow", "error", err, "flow_id", flow.ID)
+		return r.SendErrorEnvelope(fasthttp.StatusInternalServerError, "Failed to publish flow", nil, "")
+	}
+
+	a.InvalidateChatbotFlowsCache(orgID)
+	a.Log.Info("Chatbot flow published", "flow_id", flow.ID, "version", version.Version, "user_id", userID)
+
+	return r.SendEnvelope(flowVersionResponse(version, version.Version, 0))
+}
+
+// RollbackChatbotFlow republishes a previous version as a new version and
+// resets the draft to it. Sessions already in the flow stay on their version.
+func (a *App) RollbackChatbotFlow(r *fastglue.Request) error {
+	orgID, userID, err := a.getOrgAndUserID(r)
+	if err != nil {
+		return r.SendErrorEnvelope(fasthttp.StatusUnauthorized, "Unauthorized", nil, "")
+	}
+	if err := a.requirePermission(r, userID, models.ResourceFlowsChatbot, models.ActionWrite); err != nil {
+		return nil
+	}
+
+	id, err := parsePathUUID(r, "id", "flow")
+	if err != nil {
+		return nil
+	}
+	versionStr, _ := r.RequestCtx.UserValue("version").(string)
+	versionNum, err := parseFlowVersionParam(r, versionStr)
+	if err != nil {
+		return nil
+	}
+
+	var req struct {
+		Notes string `json:"notes"`
+	}
+	if len(r.RequestCtx.PostBody()) > 0 {
+		if err := a.decodeRequest(r, &req); err != nil {
+			return nil
+		}
+	}
+
+	flow, err := findByIDAndOrg[models.ChatbotFlow](a.DB, r, id, orgID, "Flow")
+	if err != nil {
+		return nil
+	}
+	target, err := a.findFlowVersion(r, flow.ID, versionNum)
+	if err != nil {
+		return nil
+	}
+	restored, err := flowFromSnapshot(flow, target.Snapshot, 0)
+	if err != nil {
+		a.Log.Error("Failed to read flow version", "error", err, "flow_id", flow.ID, "version", versionNum)
+		return r.SendErrorEnvelope(fasthttp.StatusInternalServerError, "Failed to read flow version", nil, "")
+	}
+	restored.PublishedVersion = flow.PublishedVersion
+
+	notes := strings.TrimSpace(req.Notes)
+	if notes == "" {
+		notes = fmt.Sprintf("Rollback to version %d", versionNum)
+	}
+
+	var version *models.ChatbotFlowVersion
+	if err := a.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec("SELECT id FROM chatbot_flows WHERE id = ? FOR UPDATE", flow.ID).Error; err != nil {
+			return err
+		}
+
+		// Reset the draft to the restored version
+		steps := restored.Steps
+		restored.Steps = nil
+		if err := tx.Omit("Steps", "Organization", "InitialTemplate").Save(restored).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("flow_id = ?", flow.ID).Delete(&models.ChatbotFlowStep{}).Error; err != nil {
+			return err
+		}
+		for i := range steps {
+			steps[i].ID = uuid.New()
+			steps[i].FlowID = flow.ID
+			if err := tx.Omit("Flow", "Template").Create(&steps[i]).Error; err != nil {
+				return err
+			}
+		}
+		restored.Steps = steps
+
+		version, err = a.createFlowVersion(tx, restored, userID, notes, &versionNum)
+		return err
+	}); err != nil {
+		a.Log.Error("Failed to roll back flow", "error", err, "flow_id", flow.ID, "version", versionNum)
+		return r.SendErrorEnvelope(fasthttp.StatusInternalServerError, "Failed to roll back flow", nil, "")
+	}
+
+	a.InvalidateChatbotFlowsCache(orgID)
+	a.Log.Info("Chatbot flow rolled back", "flow_id", flow.ID, "to_version", versionNum, "new_version", version.Version, "user_id", userID)
+
+	return r.SendEnvelope(flowVersionResponse(version, version.Version, 0))
+}
+
+// DiffChatbotFlowVersions compares two versions of a flow. The from and to
+// query parameters take a version number or "draft"; they default to the
+// published version and the draft.
+func (a *App) DiffChatbotFlowVersions(r *fastglue.Request) error {
+	orgID, userID, err := a.getOrgAndUserID(r)
+	if err != nil {
+		return r.SendErrorEnvelope(fasthttp.StatusUnauthorized, "Unauthorized", nil, "")
+	}
+	if err := a.requirePermission(r, userID, models.ResourceFlowsChatbot, models.ActionRead); err != nil {
+		return nil
+	}
+
+	id, err := parsePathUUID(r, "id", "flow")
+	if err != nil {
+		return nil
+	}
+	draft, err := a.loadFlowDraft(r, id, orgID)
+	if err != nil {
+		return nil
+	}
+
+	fromRef := string(r.RequestCtx.QueryArgs().Peek("from"))
+	if fromRef == "" {
+		fromRef = strconv.Itoa(draft.PublishedVersion)
+	}
+	toRef := string(r.RequestCtx.QueryArgs().Peek("to"))
+	if toRef == "" {
+		toRef = flowDraftRef
+	}
+
+	resolve := func(ref string) (*models.ChatbotFlow, error) {
+		if ref == flowDraftRef {
+			return draft, nil
+		}
+		versionNum, err := parseFlowVersionParam(r, ref)
+		if err != nil {
+			return nil, err
+		}
+		version, err := a.findFlowVersion(r, draft.ID, versionNum)
+		if err != nil {
+			return nil, err
+		}
+		flow, err := flowFromSnapshot(draft, version.Snapshot, version.Version)
+		if err != nil {
+			a.Log.Error("Failed to read flow version", "error", err, "flow_id", draft.ID, "version", versionNum)
+			_ = r.SendErrorEnvelope(fasthttp.StatusInternalServerError, "Failed to read flow version", nil, "")
+		}
+		return flow, err
+	}
+
+	from, err := resolve(fromRef)
+	if err != nil {
+		return nil
+	}
+	to, err := resolve(toRef)
+	if err != nil {
+		return nil
+	}
+
+	diff := diffFlows(from, to)
+	diff.From, diff.To = fromRef, toRef
+	return r.SendEnvelope(diff)
+}
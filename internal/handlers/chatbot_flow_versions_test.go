@@ -0,0 +1,161 @@
+package handlers
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shridarpatil/whatomate/internal/models"
+	"github.com/shridarpatil/whatomate/test/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFlowSnapshotRoundTrip(t *testing.T) {
+	live := &models.ChatbotFlow{
+		BaseModel:      models.BaseModel{ID: uuid.New()},
+		OrganizationID: uuid.New(),
+		Name:           "Support",
+		IsEnabled:      true,
+		Steps: []models.ChatbotFlowStep{
+			{StepName: "second", StepOrder: 2, Message: "Two"},
+			{StepName: "first", StepOrder: 1, Message: "One"},
+		},
+	}
+	snapshot, err := snapshotFlow(live)
+	require.NoError(t, err)
+
+	live.Name = "Support (edited)"
+	live.IsEnabled = false
+	flow, err := flowFromSnapshot(live, snapshot, 3)
+	require.NoError(t, err)
+	assert.Equal(t, "Support", flow.Name, "the definition comes from the snapshot")
+	assert.False(t, flow.IsEnabled, "the enabled state comes from the live flow")
+	assert.Equal(t, live.ID, flow.ID)
+	assert.Equal(t, 3, flow.PublishedVersion)
+	require.Len(t, flow.Steps, 2)
+	assert.Equal(t, "first", flow.Steps[0].StepName)
+	assert.Equal(t, live.ID, flow.Steps[0].FlowID)
+}
+
+func TestValidateFlowStepReferences(t *testing.T) {
+	steps := []models.ChatbotFlowStep{
+		{StepName: "ask", NextStep: "done", ConditionalNext: models.JSONB{"yes": "done", "default": ""}},
+		{StepName: "done"},
+	}
+	assert.NoError(t, validateFlowStepReferences(steps))
+
+	steps[0].NextStep = "gone"
+	assert.ErrorContains(t, validateFlowStepReferences(steps), `next_step "gone"`)
+
+	steps[0].NextStep = ""
+	steps[0].ConditionalNext["no"] = "missing"
+	assert.ErrorContains(t, validateFlowStepReferences(steps), `missing step "missing"`)
+}
+
+func TestDiffFlows(t *testing.T) {
+	from := &models.ChatbotFlow{
+		BaseModel:       models.BaseModel{ID: uuid.New()},
+		Name:            "Orders",
+		TriggerKeywords: models.StringArray{"order"},
+		Steps: []models.ChatbotFlowStep{
+			{BaseModel: models.BaseModel{ID: uuid.New()}, StepName: "ask", StepOrder: 1, Message: "Order number?"},
+			{BaseModel: models.BaseModel{ID: uuid.New()}, StepName: "old", StepOrder: 2, Message: "Bye"},
+		},
+	}
+	to := &models.ChatbotFlow{
+		BaseModel:       models.BaseModel{ID: uuid.New()},
+		Name:            "Orders",
+		TriggerKeywords: models.StringArray{"order", "track"},
+		CancelKeywords:  models.StringArray{},
+		IsEnabled:       true,
+		Steps: []models.ChatbotFlowStep{
+			{BaseModel: models.BaseModel{ID: uuid.New()}, StepName: "ask", StepOrder: 1, Message: "Your order number?"},
+			{BaseModel: models.BaseModel{ID: uuid.New()}, StepName: "new", StepOrder: 2, Message: "Thanks"},
+		},
+	}
+
+	diff := diffFlows(from, to)
+	require.Len(t, diff.Fields, 1, "ids, enabled state and empty values are not reported")
+	assert.Equal(t, "trigger_keywords", diff.Fields[0].Field)
+	assert.Equal(t, []string{"new"}, diff.StepsAdded)
+	assert.Equal(t, []string{"old"}, diff.StepsRemoved)
+	require.Len(t, diff.StepsChanged, 1)
+	assert.Equal(t, "ask", diff.StepsChanged[0].StepName)
+	assert.Equal(t, []FlowFieldChange{{Field: "message", From: "Order number?", To: "Your order number?"}}, diff.StepsChanged[0].Fields)
+}
+
+func TestProcessFlowResponse_SessionPinnedToFlowVersion(t *testing.T) {
+	app := newProcessorTestApp(t)
+	org, account := createProcessorTestOrg(t, app)
+	contact := testutil.CreateTestContact(t, app.DB, org.ID)
+	user := testutil.CreateTestUser(t, app.DB, org.ID)
+
+	flowID := uuid.New()
+	draft := &models.ChatbotFlow{
+		BaseModel:       models.BaseModel{ID: flowID},
+		OrganizationID:  org.ID,
+		WhatsAppAccount: account.Name,
+		Name:            "Versioned Flow",
+		IsEnabled:       true,
+		Steps: []models.ChatbotFlowStep{
+			{BaseModel: models.BaseModel{ID: uuid.New()}, FlowID: flowID, StepName: "ask_name", StepOrder: 1, Message: "Name?", MessageType: models.FlowStepTypeText, InputType: models.InputTypeText, NextStep: "done"},
+			{BaseModel: models.BaseModel{ID: uuid.New()}, FlowID: flowID, StepName: "done", StepOrder: 2, Message: "Thanks!", MessageType: models.FlowStepTypeText, InputType: models.InputTypeText},
+		},
+	}
+	require.NoError(t, app.DB.Create(draft).Error)
+
+	v1, err := app.createFlowVersion(app.DB, draft, user.ID, "first", nil)
+	require.NoError(t, err)
+	assert.Equal(t, 1, v1.Version)
+	app.InvalidateChatbotFlowsCache(org.ID)
+
+	newSession := func() *models.ChatbotSession {
+		session := &models.ChatbotSession{
+			BaseModel:       models.BaseModel{ID: uuid.New()},
+			OrganizationID:  org.ID,
+			ContactID:       contact.ID,
+			WhatsAppAccount: account.Name,
+			PhoneNumber:     contact.PhoneNumber,
+			Status:          models.SessionStatusActive,
+			SessionData:     models.JSONB{},
+			StartedAt:       time.Now(),
+			LastActivityAt:  time.Now(),
+		}
+		require.NoError(t, app.DB.Create(session).Error)
+		return session
+	}
+
+	flow, err := app.getChatbotFlowByIDCached(org.ID, flowID)
+	require.NoError(t, err)
+	pinned := newSession()
+	app.startFlow(account, pinned, contact, flow)
+	assert.Equal(t, 1, pinned.FlowVersion)
+
+	// Rename the first step in the draft and publish it as version 2
+	require.NoError(t, app.DB.Model(&models.ChatbotFlowStep{}).
+		Where("flow_id = ? AND step_name = ?", flowID, "ask_name").
+		Update("step_name", "ask_full_name").Error)
+	var edited models.ChatbotFlow
+	require.NoError(t, app.DB.Preload("Steps").First(&edited, "id = ?", flowID).Error)
+	v2, err := app.createFlowVersion(app.DB, &edited, user.ID, "rename", nil)
+	require.NoError(t, err)
+	assert.Equal(t, 2, v2.Version)
+	app.InvalidateChatbotFlowsCache(org.ID)
+
+	// The pinned session still runs version 1, where its step exists
+	app.processFlowResponse(account, pinned, contact, "Alice", "", nil)
+	var dbSession models.ChatbotSession
+	require.NoError(t, app.DB.First(&dbSession, pinned.ID).Error)
+	assert.Equal(t, "done", dbSession.CurrentStep)
+	assert.Equal(t, 1, dbSession.FlowVersion)
+
+	// New sessions start on version 2
+	flow, err = app.getChatbotFlowByIDCached(org.ID, flowID)
+	require.NoError(t, err)
+	fresh := newSession()
+	app.startFlow(account, fresh, contact, flow)
+	require.NoError(t, app.DB.First(&dbSession, fresh.ID).Error)
+	assert.Equal(t, 2, dbSession.FlowVersion)
+	assert.Equal(t, "ask_full_name", dbSession.CurrentStep)
+}
@@ -0,0 +1,283 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/shridarpatil/whatomate/internal/language"
+	"github.com/shridarpatil/whatomate/internal/models"
+	"gorm.io/gorm"
+)
+
+// languageButtonPrefix prefixes the ids of language picker buttons
+const languageButtonPrefix = "lang:"
+
+// defaultLanguageSwitchPrompt is the body of the language picker when the
+// settings have no prompt
+const defaultLanguageSwitchPrompt = "Please choose your language"
+
+// translationFields returns the fields translated into lang, picking the
+// closest translation when there is none for lang exactly
+func translationFields(translations models.JSONB, lang string) map[string]interface{} {
+	if lang == "" || len(translations) == 0 {
+		return nil
+	}
+	key := language.Match(lang, slices.Sorted(maps.Keys(translations)))
+	if key == "" {
+		return nil
+	}
+	fields, _ := translations[key].(map[string]interface{})
+	return fields
+}
+
+// translatedText returns the translation of a text field, or text when the
+// field is not translated
+func translatedText(fields map[string]interface{}, field, text string) string {
+	if s, ok := fields[field].(string); ok && strings.TrimSpace(s) != "" {
+		return s
+	}
+	return text
+}
+
+// translateButtons returns a copy of buttons with the titles found in titles,
+// keyed by button id (or by title for buttons without an id). Ids are kept so
+// replies route the same way in every language.
+func translateButtons(buttons models.JSONBArray, titles interface{}) models.JSONBArray {
+	byKey, _ := titles.(map[string]interface{})
+	if len(byKey) == 0 || len(buttons) == 0 {
+		return buttons
+	}
+	translated := make(models.JSONBArray, len(buttons))
+	for i, b := range buttons {
+		btn, ok := b.(map[string]interface{})
+		if !ok {
+			translated[i] = b
+			continue
+		}
+		key, _ := btn["id"].(string)
+		if key == "" {
+			key, _ = btn["title"].(string)
+		}
+		if title, ok := byKey[key].(string); ok && strings.TrimSpace(title) != "" {
+			btn = maps.Clone(btn)
+			btn["title"] = title
+		}
+		translated[i] = btn
+	}
+	return translated
+}
+
+// localizeChatbotSettings returns a copy of settings with the response texts
+// in lang, or settings itself when they have no translation for lang
+func localizeChatbotSettings(settings *models.ChatbotSettings, lang string) *models.ChatbotSettings {
+	fields := translationFields(settings.Translations, lang)
+	if fields == nil {
+		return settings
+	}
+	localized := *settings
+	localized.DefaultResponse = translatedText(fields, "greeting_message", settings.DefaultResponse)
+	localized.FallbackMessage = translatedText(fields, "fallback_message", settings.FallbackMessage)
+	localized.BusinessHours.OutOfHoursMessage = translatedText(fields, "out_of_hours_message", settings.BusinessHours.OutOfHoursMessage)
+	localized.Language.SwitchPrompt = translatedText(fields, "language_switch_prompt", settings.Language.SwitchPrompt)
+	localized.GreetingButtons = translateButtons(settings.GreetingButtons, fields["greeting_buttons"])
+	localized.FallbackButtons = translateButtons(settings.FallbackButtons, fields["fallback_buttons"])
+	return &localized
+}
+
+// localizeFlow returns a copy of flow with its messages, step messages and
+// step buttons in lang. Step names, ids and routing are unchanged.
+func localizeFlow(flow *models.ChatbotFlow, lang string) *models.ChatbotFlow {
+	if lang == "" {
+		return flow
+	}
+	localized := *flow
+	if fields := translationFields(flow.Translations, lang); fields != nil {
+		localized.InitialMessage = translatedText(fields, "initial_message", flow.InitialMessage)
+		localized.CompletionMessage = translatedText(fields, "completion_message", flow.CompletionMessage)
+	}
+	localized.Steps = slices.Clone(flow.Steps)
+	for i := range localized.Steps {
+		step := &localized.Steps[i]
+		fields := translationFields(step.Translations, lang)
+		if fields == nil {
+			continue
+		}
+		step.Message = translatedText(fields, "message", step.Message)
+		step.ValidationError = translatedText(fields, "validation_error", step.ValidationError)
+		step.Buttons = translateButtons(step.Buttons, fields["buttons"])
+	}
+	return &localized
+}
+
+// localizeCannedResponse replaces the content of a canned response with its
+// translation into lang, if there is one
+func localizeCannedResponse(response *models.CannedResponse, lang string) {
+	if fields := translationFields(response.Translations, lang); fields != nil {
+		response.Content = translatedText(fields, "content", response.Content)
+	}
+}
+
+// validateTranslations checks that translations map language codes to objects
+// of translated fields
+func validateTranslations(translations map[string]interface{}) error {
+	for code, fields := range translations {
+		if language.Normalize(code) == "" || strings.ContainsAny(code, " ") {
+			return fmt.Errorf("invalid language code: %q", code)
+		}
+		if _, ok := fields.(map[string]interface{}); !ok {
+			return fmt.Errorf("translations for %s must be an object", code)
+		}
+	}
+	return nil
+}
+
+// validateLanguageConfig checks that the chatbot languages are distinct codes
+// and that the default language is one of them
+func validateLanguageConfig(cfg models.LanguageConfig) error {
+	seen := make(map[string]bool, len(cfg.Languages))
+	for _, code := range cfg.Languages {
+		norm := language.Normalize(code)
+		if norm == "" || strings.ContainsAny(norm, " ") {
+			return fmt.Errorf("invalid language code: %q", code)
+		}
+		if seen[norm] {
+			return fmt.Errorf("duplicate language: %s", code)
+		}
+		seen[norm] = true
+	}
+	if cfg.DefaultLanguage != "" && len(cfg.Languages) > 0 && !seen[language.Normalize(cfg.DefaultLanguage)] {
+		return fmt.Errorf("default_language %s is not one of the languages", cfg.DefaultLanguage)
+	}
+	return nil
+}
+
+// languageInstruction tells the AI which language to answer in
+func languageInstruction(lang string) string {
+	return fmt.Sprintf("Always reply in %s (%s), whatever language the context is written in.", language.Name(lang), lang)
+}
+
+// parseLanguageSwitch checks whether a message changes the contact's language.
+// A language picker button or "<keyword> <language>" returns the chosen
+// language; a switch keyword alone asks for the picker.
+func parseLanguageSwitch(cfg models.LanguageConfig, messageText, buttonID string) (lang string, showPicker bool) {
+	if len(cfg.Languages) == 0 {
+		return "", false
+	}
+	if code, ok := strings.CutPrefix(buttonID, languageButtonPrefix); ok {
+		return language.Match(code, cfg.Languages), false
+	}
+
+	text := strings.TrimSpace(messageText)
+	for _, keyword := range cfg.SwitchKeywords {
+		keyword = strings.TrimSpace(keyword)
+		if keyword == "" || len(text) < len(keyword) || !strings.EqualFold(text[:len(keyword)], keyword) {
+			continue
+		}
+		rest := strings.TrimSpace(text[len(keyword):])
+		if rest == "" {
+			return "", true
+		}
+		if text[len(keyword)] != ' ' {
+			continue // the keyword is only the start of a longer word
+		}
+		if match := language.Match(rest, cfg.Languages); match != "" {
+			return match, false
+		}
+		for _, code := range cfg.Languages {
+			if strings.EqualFold(language.Name(code), rest) {
+				return code, false
+			}
+		}
+		return "", true
+	}
+	return "", false
+}
+
+// resolveContactLanguage returns the language the chatbot talks to the contact
+// in: the language they picked, else the language detected from text (when
+// detect is set and detection is enabled), else the default language. It
+// returns "" when the chatbot is not multilingual.
+func (a *App) resolveContactLanguage(settings *models.ChatbotSettings, contact *models.Contact, text string, detect bool) string {
+	cfg := settings.Language
+	if len(cfg.Languages) == 0 {
+		return ""
+	}
+
+	preferred := contact.PreferredLanguage()
+	source, _ := contact.Metadata[models.ContactMetadataLanguageSource].(string)
+	if detect && cfg.DetectLanguage && source != models.LanguageSourceSelected {
+		if detected := language.Detect(text, cfg.Languages); detected != "" && detected != preferred {
+			a.setContactLanguage(contact, detected, models.LanguageSourceDetected)
+			preferred = detected
+		}
+	}
+
+	if match := language.Match(preferred, cfg.Languages); match != "" {
+		return match
+	}
+	if match := language.Match(cfg.DefaultLanguage, cfg.Languages); match != "" {
+		return match
+	}
+	return cfg.Languages[0]
+}
+
+// setContactLanguage saves the contact's preferred language in its metadata
+func (a *App) setContactLanguage(contact *models.Contact, lang, source string) {
+	patch, _ := json.Marshal(map[string]string{
+		models.ContactMetadataLanguage:       lang,
+		models.ContactMetadataLanguageSource: source,
+	})
+	if err := a.DB.Model(contact).
+		Update("metadata", gorm.Expr("COALESCE(metadata, '{}'::jsonb) || ?::jsonb", string(patch))).Error; err != nil {
+		a.Log.Error("Failed to save contact language", "error", err, "contact_id", contact.ID)
+		return
+	}
+	if contact.Metadata == nil {
+		contact.Metadata = models.JSONB{}
+	}
+	contact.Metadata[models.ContactMetadataLanguage] = lang
+	contact.Metadata[models.ContactMetadataLanguageSource] = source
+}
+
+// handleLanguageSwitch answers a language switch: it shows the language
+// picker, or after a switch repeats the current flow step (or the greeting)
+// in the new language
+func (a *App) handleLanguageSwitch(account *models.WhatsAppAccount, settings *models.ChatbotSettings, session *models.ChatbotSession, contact *models.Contact, showPicker bool) {
+	if showPicker {
+		prompt := settings.Language.SwitchPrompt
+		if prompt == "" {
+			prompt = defaultLanguageSwitchPrompt
+		}
+		buttons := make([]map[string]interface{}, 0, len(settings.Language.Languages))
+		for _, code := range settings.Language.Languages {
+			buttons = append(buttons, map[string]interface{}{"id": languageButtonPrefix + code, "title": language.Name(code)})
+		}
+		if err := a.sendAndSaveInteractiveButtons(account, contact, prompt, buttons); err != nil {
+			a.Log.Error("Failed to send language picker", "error", err, "contact", contact.PhoneNumber)
+		}
+		a.logSessionMessage(session.ID, models.DirectionOutgoing, prompt, "language_picker")
+		return
+	}
+
+	a.Log.Info("Contact switched language", "contact_id", contact.ID, "language", session.Language)
+	if session.CurrentFlowID != nil && session.CurrentStep != "" {
+		flow, err := a.getSessionFlow(session)
+		if err != nil {
+			a.Log.Error("Failed to load flow", "error", err)
+			return
+		}
+		for i := range flow.Steps {
+			if flow.Steps[i].StepName == session.CurrentStep {
+				a.sendStepMessage(account, session, contact, &flow.Steps[i])
+				return
+			}
+		}
+		return
+	}
+	if settings.DefaultResponse != "" {
+		a.sendGreeting(account, session, contact, settings)
+	}
+}
@@ -0,0 +1,149 @@
+package handlers
+
+import (
+	"testing"
+
+	"github.com/shridarpatil/whatomate/internal/models"
+	"github.com/shridarpatil/whatomate/test/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTranslateButtons(t *testing.T) {
+	buttons := models.JSONBArray{
+		map[string]interface{}{"id": "sales", "title": "Sales"},
+		map[string]interface{}{"title": "Support"},
+		map[string]interface{}{"id": "web", "title": "Website", "type": "url", "url": "https://example.com"},
+	}
+	titles := map[string]interface{}{"sales": "Ventas", "Support": "Soporte", "web": "  "}
+
+	translated := translateButtons(buttons, titles)
+	assert.Equal(t, "Ventas", translated[0].(map[string]interface{})["title"])
+	assert.Equal(t, "sales", translated[0].(map[string]interface{})["id"], "ids are kept")
+	assert.Equal(t, "Soporte", translated[1].(map[string]interface{})["title"], "buttons without id are keyed by title")
+	assert.Equal(t, "Website", translated[2].(map[string]interface{})["title"], "blank translations are ignored")
+	assert.Equal(t, "Sales", buttons[0].(map[string]interface{})["title"], "the original buttons are not modified")
+
+	assert.Equal(t, buttons, translateButtons(buttons, nil))
+}
+
+func TestLocalizeChatbotSettings(t *testing.T) {
+	settings := &models.ChatbotSettings{
+		DefaultResponse: "Hello!",
+		FallbackMessage: "Sorry?",
+		GreetingButtons: models.JSONBArray{map[string]interface{}{"id": "menu", "title": "Menu"}},
+		BusinessHours:   models.BusinessHoursConfig{OutOfHoursMessage: "We are closed"},
+		Translations: models.JSONB{
+			"es": map[string]interface{}{
+				"greeting_message":     "¡Hola!",
+				"out_of_hours_message": "Estamos cerrados",
+				"greeting_buttons":     map[string]interface{}{"menu": "Menú"},
+			},
+		},
+	}
+
+	localized := localizeChatbotSettings(settings, "es_MX")
+	assert.Equal(t, "¡Hola!", localized.DefaultResponse)
+	assert.Equal(t, "Sorry?", localized.FallbackMessage, "untranslated fields keep the default text")
+	assert.Equal(t, "Estamos cerrados", localized.BusinessHours.OutOfHoursMessage)
+	assert.Equal(t, "Menú", localized.GreetingButtons[0].(map[string]interface{})["title"])
+	assert.Equal(t, "Hello!", settings.DefaultResponse, "the cached settings are not modified")
+
+	assert.Same(t, settings, localizeChatbotSettings(settings, "fr"))
+	assert.Same(t, settings, localizeChatbotSettings(settings, ""))
+}
+
+func TestLocalizeFlow(t *testing.T) {
+	flow := &models.ChatbotFlow{
+		InitialMessage: "Let's start",
+		Translations:   models.JSONB{"pt_BR": map[string]interface{}{"initial_message": "Vamos começar"}},
+		Steps: []models.ChatbotFlowStep{
+			{
+				StepName:        "ask_size",
+				Message:         "Which size?",
+				ValidationError: "Pick a size",
+				Buttons:         models.JSONBArray{map[string]interface{}{"id": "s", "title": "Small"}},
+				Translations: models.JSONB{"pt": map[string]interface{}{
+					"message": "Qual tamanho?",
+					"buttons": map[string]interface{}{"s": "Pequeno"},
+				}},
+			},
+			{StepName: "done", Message: "Thanks"},
+		},
+	}
+
+	localized := localizeFlow(flow, "pt")
+	assert.Equal(t, "Vamos começar", localized.InitialMessage, "a base language matches a regional translation")
+	assert.Equal(t, "Qual tamanho?", localized.Steps[0].Message)
+	assert.Equal(t, "Pick a size", localized.Steps[0].ValidationError)
+	assert.Equal(t, "Pequeno", localized.Steps[0].Buttons[0].(map[string]interface{})["title"])
+	assert.Equal(t, "ask_size", localized.Steps[0].StepName)
+	assert.Equal(t, "Thanks", localized.Steps[1].Message)
+	assert.Equal(t, "Which size?", flow.Steps[0].Message, "the cached flow is not modified")
+}
+
+func TestParseLanguageSwitch(t *testing.T) {
+	cfg := models.LanguageConfig{
+		Languages:      models.StringArray{"en", "es", "pt_BR"},
+		SwitchKeywords: models.StringArray{"language", "idioma"},
+	}
+
+	tests := []struct {
+		text, buttonID string
+		lang           string
+		picker         bool
+	}{
+		{"Language", "", "", true},
+		{"idioma español", "", "es", false},
+		{"language pt", "", "pt_BR", false},
+		{"language klingon", "", "", true},
+		{"languages are fun", "", "", false},
+		{"Español", "lang:es", "es", false},
+		{"Français", "lang:fr", "", false},
+		{"hello", "", "", false},
+	}
+	for _, tt := range tests {
+		lang, picker := parseLanguageSwitch(cfg, tt.text, tt.buttonID)
+		assert.Equal(t, tt.lang, lang, tt.text)
+		assert.Equal(t, tt.picker, picker, tt.text)
+	}
+
+	lang, picker := parseLanguageSwitch(models.LanguageConfig{SwitchKeywords: models.StringArray{"language"}}, "language", "")
+	assert.Empty(t, lang)
+	assert.False(t, picker, "switching is off when the chatbot is not multilingual")
+}
+
+func TestValidateLanguageConfig(t *testing.T) {
+	assert.NoError(t, validateLanguageConfig(models.LanguageConfig{}))
+	assert.NoError(t, validateLanguageConfig(models.LanguageConfig{DefaultLanguage: "en", Languages: models.StringArray{"en", "es"}}))
+	assert.EqualError(t, validateLanguageConfig(models.LanguageConfig{DefaultLanguage: "fr", Languages: models.StringArray{"en"}}),
+		"default_language fr is not one of the languages")
+	assert.EqualError(t, validateLanguageConfig(models.LanguageConfig{Languages: models.StringArray{"pt-BR", "pt_br"}}), "duplicate language: pt_br")
+	assert.EqualError(t, validateTranslations(map[string]interface{}{"es": "hola"}), "translations for es must be an object")
+}
+
+func TestResolveContactLanguage(t *testing.T) {
+	app := newProcessorTestApp(t)
+	org, account := createProcessorTestOrg(t, app)
+	contact := testutil.CreateTestContactWith(t, app.DB, org.ID, testutil.WithContactAccount(account.Name))
+	settings := &models.ChatbotSettings{Language: models.LanguageConfig{
+		DefaultLanguage: "en",
+		Languages:       models.StringArray{"en", "es"},
+		DetectLanguage:  true,
+	}}
+
+	assert.Equal(t, "en", app.resolveContactLanguage(settings, contact, "ok", true), "undetected text uses the default language")
+	assert.Equal(t, "es", app.resolveContactLanguage(settings, contact, "Hola, quiero hacer un pedido", true))
+	assert.Equal(t, "es", app.resolveContactLanguage(settings, contact, "ok", true), "the detected language is remembered")
+
+	var saved models.Contact
+	require.NoError(t, app.DB.First(&saved, contact.ID).Error)
+	assert.Equal(t, "es", saved.PreferredLanguage())
+	assert.Equal(t, models.LanguageSourceDetected, saved.Metadata[models.ContactMetadataLanguageSource])
+
+	// A picked language is not overridden by detection
+	app.setContactLanguage(contact, "en", models.LanguageSourceSelected)
+	assert.Equal(t, "en", app.resolveContactLanguage(settings, contact, "Hola, quiero hacer un pedido", true))
+
+	assert.Empty(t, app.resolveContactLanguage(&models.ChatbotSettings{}, contact, "Hola", true))
+}
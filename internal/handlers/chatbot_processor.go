@@ -12,8 +12,10 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/shridarpatil/whatomate/internal/ai"
 	"github.com/shridarpatil/whatomate/internal/contactutil"
 	"github.com/shridarpatil/whatomate/internal/models"
+	"github.com/shridarpatil/whatomate/internal/webhookutil"
 	"github.com/shridarpatil/whatomate/pkg/whatsapp"
 )
 
@@ -43,6 +45,10 @@ type IncomingTextMessage struct {
 			Name         string `json:"name"`
 		} `json:"nfm_reply,omitempty"`
 	} `json:"interactive,omitempty"`
+	Button *struct {
+		Payload string `json:"payload"`
+		Text    string `json:"text"`
+	} `json:"button,omitempty"`
 	Image *struct {
 		ID       string `json:"id"`
 		MimeType string `json:"mime_type"`
@@ -126,7 +132,7 @@ func (a *App) processIncomingMessageFull(phoneNumberID string, msg IncomingTextM
 
 	// Dispatch webhook if new contact was created
 	if isNewContact {
-		a.DispatchWebhook(account.OrganizationID, models.WebhookEventContactCreated, ContactEventData{
+		a.DispatchWebhook(account.OrganizationID, models.WebhookEventContactCreated, webhookutil.ContactEventData{
 			ContactID:       contact.ID.String(),
 			ContactPhone:    contact.PhoneNumber,
 			ContactName:     contact.ProfileName,
@@ -173,6 +179,11 @@ func (a *App) processIncomingMessageFull(phoneNumberID string, msg IncomingTextM
 				}
 			}
 		}
+	} else if msg.Type == "button" && msg.Button != nil {
+		// Quick reply button on a template message
+		messageText = msg.Button.Text
+		buttonID = msg.Button.Payload
+		messageType = "button_reply"
 	} else if msg.Type == "image" && msg.Image != nil {
 		// Handle image message
 		messageText = msg.Image.Caption
@@ -278,11 +289,19 @@ func (a *App) processIncomingMessageFull(phoneNumberID string, msg IncomingTextM
 	if msg.Context != nil && msg.Context.ID != "" {
 		replyToWAMID = msg.Context.ID
 	}
-	a.saveIncomingMessage(account, contact, msg.ID, messageType, messageText, mediaInfo, replyToWAMID)
+	savedMsg := a.saveIncomingMessage(account, contact, msg.ID, messageType, messageText, mediaInfo, replyToWAMID)
 
 	// Clear chatbot tracking since client has replied
 	a.ClearContactChatbotTracking(contact.ID)
 
+	// Opt-out/opt-in keywords are handled before agent routing and the chatbot.
+	// Of the button replies only template quick replies are matched, since
+	// Meta's marketing templates carry a "Stop promotions" button.
+	if (messageType == "text" || msg.Type == "button") && a.consentKeywordsApply(account, contact) &&
+		a.handleConsentKeyword(account, contact, messageText, savedMsg) {
+		return
+	}
+
 	// Check for active agent transfer - skip chatbot processing if transferred
 	if a.hasActiveAgentTransfer(account.OrganizationID, contact.ID) {
 		a.Log.Info("Contact has active agent transfer, skipping chatbot processing",
@@ -300,27 +319,36 @@ func (a *App) processIncomingMessageFull(phoneNumberID string, msg IncomingTextM
 	if !settings.IsEnabled {
 		a.Log.Debug("Chatbot not enabled for this account, creating transfer for agent queue", "account", account.Name, "settings_id", settings.ID)
 		// Create transfer to agent queue when chatbot is disabled
-		a.createTransferToQueue(account, contact, models.TransferSourceChatbotDisabled)
+		a.createTransferToQueue(account, contact, models.TransferSourceChatbotDisabled, nil)
 		return
 	}
 	a.Log.Info("Chatbot settings loaded", "settings_id", settings.ID, "is_enabled", settings.IsEnabled, "ai_enabled", settings.AI.Enabled, "ai_provider", settings.AI.Provider, "default_response", settings.DefaultResponse)
 
+	// Talk to the contact in their language. A language switch keyword or a
+	// language picker reply changes it; otherwise typed messages may be detected.
+	switchLanguage, showLanguagePicker := parseLanguageSwitch(settings.Language, messageText, buttonID)
+	if switchLanguage != "" {
+		a.setContactLanguage(contact, switchLanguage, models.LanguageSourceSelected)
+	}
+	detectLanguage := messageType == "text" && switchLanguage == "" && !showLanguagePicker
+	contactLanguage := a.resolveContactLanguage(settings, contact, messageText, detectLanguage)
+	settings = localizeChatbotSettings(settings, contactLanguage)
+
 	// Check business hours if enabled
-	if settings.BusinessHours.Enabled && len(settings.BusinessHours.Hours) > 0 {
-		if !a.isWithinBusinessHours(settings.BusinessHours.Hours) {
-			// If automated responses are not allowed outside hours, send out-of-hours message and stop
-			if !settings.BusinessHours.AllowAutomatedOutside {
-				a.Log.Info("Outside business hours, sending out of hours message")
-				if settings.BusinessHours.OutOfHoursMessage != "" {
-					if err := a.sendAndSaveTextMessage(account, contact, settings.BusinessHours.OutOfHoursMessage); err != nil {
-						a.Log.Error("Failed to send out of hours message", "error", err, "contact", contact.PhoneNumber)
-					}
+	outsideHours, outOfHoursMessage := a.outsideBusinessHours(account.OrganizationID, settings.BusinessHours)
+	if outsideHours {
+		// If automated responses are not allowed outside hours, send out-of-hours message and stop
+		if !settings.BusinessHours.AllowAutomatedOutside {
+			a.Log.Info("Outside business hours, sending out of hours message")
+			if outOfHoursMessage != "" {
+				if err := a.sendAndSaveTextMessage(account, contact, outOfHoursMessage); err != nil {
+					a.Log.Error("Failed to send out of hours message", "error", err, "contact", contact.PhoneNumber)
 				}
-				return
 			}
-			// AllowAutomatedOutsideHours is true, continue processing flows/keywords/AI
-			a.Log.Info("Outside business hours but automated responses allowed, continuing")
+			return
 		}
+		// AllowAutomatedOutsideHours is true, continue processing flows/keywords/AI
+		a.Log.Info("Outside business hours but automated responses allowed, continuing")
 	}
 
 	// Only process text and interactive messages for chatbot
@@ -337,21 +365,29 @@ func (a *App) processIncomingMessageFull(phoneNumberID string, msg IncomingTextM
 	// Log incoming message to session
 	a.logSessionMessage(session.ID, models.DirectionIncoming, messageText, "keyword_check")
 
+	if session.Language != contactLanguage {
+		session.Language = contactLanguage
+		a.DB.Model(session).Update("language", contactLanguage)
+	}
+	if switchLanguage != "" || showLanguagePicker {
+		a.handleLanguageSwitch(account, settings, session, contact, showLanguagePicker)
+		return
+	}
+
 	// Check for transfer keyword BEFORE sending greeting (transfer takes priority)
-	keywordResponse, keywordMatched := a.matchKeywordRules(account.OrganizationID, account.Name, messageText)
+	conditionData := keywordConditionData(contact, session)
+	keywordResponse, keywordMatched := a.matchKeywordRules(account.OrganizationID, account.Name, messageText, conditionData)
 	if keywordMatched && keywordResponse.ResponseType == models.ResponseTypeTransfer {
 		a.Log.Info("Transfer keyword matched", "response", keywordResponse.Body)
 		// Check business hours - if outside hours, send out of hours message instead
-		if settings.BusinessHours.Enabled && len(settings.BusinessHours.Hours) > 0 {
-			if !a.isWithinBusinessHours(settings.BusinessHours.Hours) {
-				a.Log.Info("Outside business hours, sending out of hours message instead of transfer")
-				if settings.BusinessHours.OutOfHoursMessage != "" {
-					if err := a.sendAndSaveTextMessage(account, contact, settings.BusinessHours.OutOfHoursMessage); err != nil {
-						a.Log.Error("Failed to send out of hours message", "error", err, "contact", contact.PhoneNumber)
-					}
+		if outsideHours {
+			a.Log.Info("Outside business hours, sending out of hours message instead of transfer")
+			if outOfHoursMessage != "" {
+				if err := a.sendAndSaveTextMessage(account, contact, outOfHoursMessage); err != nil {
+					a.Log.Error("Failed to send out of hours message", "error", err, "contact", contact.PhoneNumber)
 				}
-				return
 			}
+			return
 		}
 		// Within business hours - send transfer message and create transfer
 		if keywordResponse.Body != "" {
@@ -359,7 +395,8 @@ func (a *App) processIncomingMessageFull(phoneNumberID string, msg IncomingTextM
 				a.Log.Error("Failed to send transfer message", "error", err, "contact", contact.PhoneNumber)
 			}
 		}
-		a.createTransferFromKeyword(account, contact)
+		a.logKeywordSessionMessage(session.ID, keywordResponse, "keyword_transfer")
+		a.createTransferFromKeyword(account, contact, keywordResponse.Content)
 		return
 	}
 
@@ -378,63 +415,35 @@ func (a *App) processIncomingMessageFull(phoneNumberID string, msg IncomingTextM
 	// Send greeting message for new sessions (only if no flow was triggered)
 	if isNewSession && settings.DefaultResponse != "" {
 		a.Log.Info("New session - sending greeting message", "contact", contact.PhoneNumber)
-		if len(settings.GreetingButtons) > 0 {
-			greetingButtons := make([]map[string]interface{}, 0)
-			for _, btn := range settings.GreetingButtons {
-				if btnMap, ok := btn.(map[string]interface{}); ok {
-					greetingButtons = append(greetingButtons, btnMap)
-				}
-			}
-			if len(greetingButtons) > 0 {
-				if err := a.sendAndSaveInteractiveButtons(account, contact, settings.DefaultResponse, greetingButtons); err != nil {
-					a.Log.Error("Failed to send greeting buttons", "error", err, "contact", contact.PhoneNumber)
-				}
-			} else {
-				if err := a.sendAndSaveTextMessage(account, contact, settings.DefaultResponse); err != nil {
-					a.Log.Error("Failed to send greeting message", "error", err, "contact", contact.PhoneNumber)
-				}
-			}
-		} else {
-			if err := a.sendAndSaveTextMessage(account, contact, settings.DefaultResponse); err != nil {
-				a.Log.Error("Failed to send greeting message", "error", err, "contact", contact.PhoneNumber)
-			}
-		}
-		a.logSessionMessage(session.ID, models.DirectionOutgoing, settings.DefaultResponse, "greeting")
+		a.sendGreeting(account, session, contact, settings)
 		return // After greeting, don't process further for new sessions
 	}
 
 	// Handle non-transfer keyword matches (transfer was already handled above)
 	if keywordMatched && keywordResponse.ResponseType != models.ResponseTypeTransfer {
 		a.Log.Info("Keyword rule matched", "response_type", keywordResponse.ResponseType, "response", keywordResponse.Body)
-
-		// Handle regular text response
-		if len(keywordResponse.Buttons) > 0 {
-			if err := a.sendAndSaveInteractiveButtons(account, contact, keywordResponse.Body, keywordResponse.Buttons); err != nil {
-				a.Log.Error("Failed to send interactive buttons", "error", err, "contact", contact.PhoneNumber)
-			}
-		} else {
-			if err := a.sendAndSaveTextMessage(account, contact, keywordResponse.Body); err != nil {
-				a.Log.Error("Failed to send text message", "error", err, "contact", contact.PhoneNumber)
-			}
-		}
-		// Log outgoing message
-		a.logSessionMessage(session.ID, models.DirectionOutgoing, keywordResponse.Body, "keyword_response")
+		a.sendKeywordResponse(account, session, contact, keywordResponse, messageText, conditionData)
 		return
 	}
 
 	// If no keyword matched, try AI response if enabled
-	if settings.AI.Enabled && settings.AI.Provider != "" && settings.AI.APIKey != "" {
+	if isAIConfigured(settings.AI) {
 		a.Log.Info("Attempting AI response", "provider", settings.AI.Provider, "model", settings.AI.Model)
-		aiResponse, err := a.generateAIResponse(settings, session, messageText)
+		reply, err := a.generateAIResponse(settings, account, contact, session, messageText)
 		if err != nil {
 			a.Log.Error("AI response failed", "error", err, "provider", settings.AI.Provider, "model", settings.AI.Model)
 			// Fall through to default response
-		} else if aiResponse != "" {
-			a.Log.Info("AI response generated successfully", "response_length", len(aiResponse))
-			if err := a.sendAndSaveTextMessage(account, contact, aiResponse); err != nil {
-				a.Log.Error("Failed to send AI response", "error", err, "contact", contact.PhoneNumber)
+		} else if reply.Content != "" || reply.Handoff != nil {
+			if reply.Content != "" {
+				a.Log.Info("AI response generated successfully", "response_length", len(reply.Content))
+				if err := a.sendAndSaveAIResponse(account, contact, reply.Content, reply.Citations); err != nil {
+					a.Log.Error("Failed to send AI response", "error", err, "contact", contact.PhoneNumber)
+				}
+				a.logSessionMessage(session.ID, models.DirectionOutgoing, reply.Content, "ai_response")
+			}
+			if reply.Handoff != nil {
+				reply.Handoff()
 			}
-			a.logSessionMessage(session.ID, models.DirectionOutgoing, aiResponse, "ai_response")
 			return
 		} else {
 			a.Log.Warn("AI returned empty response")
@@ -474,15 +483,45 @@ func (a *App) processIncomingMessageFull(phoneNumberID string, msg IncomingTextM
 	}
 }
 
+// sendGreeting sends the greeting message, with the greeting buttons if any
+func (a *App) sendGreeting(account *models.WhatsAppAccount, session *models.ChatbotSession, contact *models.Contact, settings *models.ChatbotSettings) {
+	if len(settings.GreetingButtons) > 0 {
+		greetingButtons := make([]map[string]interface{}, 0)
+		for _, btn := range settings.GreetingButtons {
+			if btnMap, ok := btn.(map[string]interface{}); ok {
+				greetingButtons = append(greetingButtons, btnMap)
+			}
+		}
+		if len(greetingButtons) > 0 {
+			if err := a.sendAndSaveInteractiveButtons(account, contact, settings.DefaultResponse, greetingButtons); err != nil {
+				a.Log.Error("Failed to send greeting buttons", "error", err, "contact", contact.PhoneNumber)
+			}
+		} else {
+			if err := a.sendAndSaveTextMessage(account, contact, settings.DefaultResponse); err != nil {
+				a.Log.Error("Failed to send greeting message", "error", err, "contact", contact.PhoneNumber)
+			}
+		}
+	} else {
+		if err := a.sendAndSaveTextMessage(account, contact, settings.DefaultResponse); err != nil {
+			a.Log.Error("Failed to send greeting message", "error", err, "contact", contact.PhoneNumber)
+		}
+	}
+	a.logSessionMessage(session.ID, models.DirectionOutgoing, settings.DefaultResponse, "greeting")
+}
+
 // KeywordResponse holds the response content and optional buttons
 type KeywordResponse struct {
+	RuleID       uuid.UUID
 	Body         string
 	Buttons      []map[string]interface{}
-	ResponseType models.ResponseType // text, transfer
+	ResponseType models.ResponseType // text, transfer, template, media, flow, script
+	Content      models.JSONB        // Rule response content, used by non-text response types
 }
 
-// matchKeywordRules checks if the message matches any keyword rules
-func (a *App) matchKeywordRules(orgID uuid.UUID, accountName, messageText string) (*KeywordResponse, bool) {
+// matchKeywordRules checks if the message matches any keyword rules. Rules
+// outside their active window, or whose conditions are not met by data, are
+// skipped.
+func (a *App) matchKeywordRules(orgID uuid.UUID, accountName, messageText string, data map[string]interface{}) (*KeywordResponse, bool) {
 	// Use cached keyword rules (includes both account-specific and global rules)
 	rules, err := a.getKeywordRulesCached(orgID, accountName)
 	if err != nil {
@@ -491,8 +530,13 @@ func (a *App) matchKeywordRules(orgID uuid.UUID, accountName, messageText string
 	}
 
 	messageLower := strings.ToLower(messageText)
+	now := a.now()
 
 	for _, rule := range rules {
+		if !isKeywordRuleActive(&rule, now) {
+			continue
+		}
+		conditionChecked, conditionMet := false, false
 		for _, keyword := range rule.Keywords {
 			keywordLower := strings.ToLower(keyword)
 			matched := false
@@ -527,8 +571,19 @@ func (a *App) matchKeywordRules(orgID uuid.UUID, accountName, messageText string
 			}
 
 			if matched {
+				// Conditions are evaluated once per rule, on its first keyword match
+				if !conditionChecked {
+					conditionChecked = true
+					conditionMet = strings.TrimSpace(rule.Conditions) == "" || evaluateExpression(rule.Conditions, data)
+				}
+				if !conditionMet {
+					break
+				}
+
 				response := &KeywordResponse{
+					RuleID:       rule.ID,
 					ResponseType: rule.ResponseType,
+					Content:      rule.ResponseContent,
 				}
 
 				// For transfer type, use body as the transfer message
@@ -539,6 +594,12 @@ func (a *App) matchKeywordRules(orgID uuid.UUID, accountName, messageText string
 					return response, true
 				}
 
+				// Template, media, flow and script responses are executed from Content
+				switch rule.ResponseType {
+				case models.ResponseTypeTemplate, models.ResponseTypeMedia, models.ResponseTypeFlow, models.ResponseTypeScript:
+					return response, true
+				}
+
 				// Get response body
 				if body, ok := rule.ResponseContent["body"].(string); ok {
 					response.Body = body
@@ -761,6 +822,22 @@ func (a *App) logSessionMessage(sessionID uuid.UUID, direction models.Direction,
 	}
 }
 
+// logKeywordSessionMessage logs a keyword rule response, recording the rule
+// so that rule hits can be counted
+func (a *App) logKeywordSessionMessage(sessionID uuid.UUID, response *KeywordResponse, stepName string) {
+	msg := models.ChatbotSessionMessage{
+		BaseModel:     models.BaseModel{ID: uuid.New()},
+		SessionID:     sessionID,
+		Direction:     models.DirectionOutgoing,
+		Message:       response.Body,
+		StepName:      stepName,
+		KeywordRuleID: &response.RuleID,
+	}
+	if err := a.DB.Create(&msg).Error; err != nil {
+		a.Log.Error("Failed to log session message", "error", err)
+	}
+}
+
 // matchFlowTrigger checks if the message triggers any flow
 func (a *App) matchFlowTrigger(orgID uuid.UUID, accountName, messageText string) *models.ChatbotFlow {
 	// Use cached flows (includes steps)
@@ -791,9 +868,12 @@ func (a *App) startFlow(account *models.WhatsAppAccount, session *models.Chatbot
 		a.Log.Info("Flow step", "index", i, "step_name", step.StepName, "step_order", step.StepOrder, "message_type", step.MessageType)
 	}
 
+	flow = localizeFlow(flow, session.Language)
+
 	// Update session with flow info
 	session.CurrentFlowID = &flow.ID
 	session.CurrentStep = ""
+	session.FlowVersion = flow.PublishedVersion
 	session.StepRetries = 0
 	session.SessionData = models.JSONB{
 		"_flow_id":   flow.ID.String(),
@@ -825,8 +905,8 @@ func (a *App) startFlow(account *models.WhatsAppAccount, session *models.Chatbot
 
 // processFlowResponse handles user response within a flow
 func (a *App) processFlowResponse(account *models.WhatsAppAccount, session *models.ChatbotSession, contact *models.Contact, userInput string, buttonID string, flowResponseData map[string]interface{}) {
-	// Load the current flow from cache
-	flow, err := a.getChatbotFlowByIDCached(account.OrganizationID, *session.CurrentFlowID)
+	// Load the flow at the version the session started on
+	flow, err := a.getSessionFlow(session)
 	if err != nil {
 		a.Log.Error("Failed to load flow", "error", err)
 		a.exitFlow(session)
@@ -842,6 +922,7 @@ func (a *App) processFlowResponse(account *models.WhatsAppAccount, session *mode
 			}
 			a.logSessionMessage(session.ID, models.DirectionOutgoing, "Flow cancelled.", "flow_cancel")
 			a.exitFlow(session)
+			a.resolveChatbotConversation(contact.ID, "Chatbot flow cancelled")
 			return
 		}
 	}
@@ -886,9 +967,15 @@ func (a *App) processFlowResponse(account *models.WhatsAppAccount, session *mode
 		}
 	}
 
+	// Template steps accept the template's quick reply buttons
+	stepButtons := currentStep.Buttons
+	if currentStep.MessageType == models.FlowStepTypeTemplate {
+		stepButtons = a.templateStepButtons(account, session, contact, currentStep)
+	}
+
 	// Auto-validate button responses when step expects button/select input
 	// Only validate if InputType is button/select, or if buttons are configured and user clicked a button
-	shouldValidateButtons := len(currentStep.Buttons) > 0 &&
+	shouldValidateButtons := len(stepButtons) > 0 &&
 		(currentStep.InputType == models.InputTypeButton || currentStep.InputType == models.InputTypeSelect || buttonID != "")
 
 	if shouldValidateButtons {
@@ -896,7 +983,7 @@ func (a *App) processFlowResponse(account *models.WhatsAppAccount, session *mode
 		userInputLower := strings.ToLower(userInput)
 
 		// Check if buttonID or userInput matches any configured button
-		for i, btn := range currentStep.Buttons {
+		for i, btn := range stepButtons {
 			if btnMap, ok := btn.(map[string]interface{}); ok {
 				btnID, _ := btnMap["id"].(string)
 				btnTitle, _ := btnMap["title"].(string)
@@ -950,6 +1037,13 @@ func (a *App) processFlowResponse(account *models.WhatsAppAccount, session *mode
 		}
 	}
 
+	// Keep the latest reply available to script steps
+	if session.SessionData == nil {
+		session.SessionData = models.JSONB{}
+	}
+	session.SessionData["_last_input"] = userInput
+	session.SessionData["_last_button_id"] = buttonID
+
 	// Store the user's response (use buttonID if available, otherwise userInput)
 	if currentStep.StoreAs != "" {
 		sessionData := session.SessionData
@@ -1058,7 +1152,11 @@ func (a *App) completeFlow(account *models.WhatsAppAccount, session *models.Chat
 
 	// Execute on-complete action
 	if flow.OnCompleteAction == "webhook" && len(flow.CompletionConfig) > 0 {
-		go a.sendFlowCompletionWebhook(flow, session, contact)
+		if a.simulation != nil {
+			a.simulation.captureWebhook("flow.completion_webhook", flow.CompletionConfig)
+		} else {
+			go a.sendFlowCompletionWebhook(flow, session, contact)
+		}
 	}
 
 	// Update session (keep current_flow_id for panel config reference)
@@ -1071,6 +1169,21 @@ func (a *App) completeFlow(account *models.WhatsAppAccount, session *models.Chat
 
 	// Clear chatbot tracking so SLA doesn't fire after flow completion
 	a.ClearContactChatbotTracking(contact.ID)
+
+	a.resolveChatbotConversation(contact.ID, "Chatbot flow completed")
+
+	a.DispatchWebhook(account.OrganizationID, models.WebhookEventChatbotSessionCompleted, webhookutil.ChatbotSessionEventData{
+		SessionID:       session.ID.String(),
+		ContactID:       contact.ID.String(),
+		ContactPhone:    contact.PhoneNumber,
+		FlowID:          flow.ID.String(),
+		FlowName:        flow.Name,
+		Status:          models.SessionStatusCompleted,
+		SessionData:     session.SessionData,
+		StartedAt:       session.StartedAt,
+		CompletedAt:     &now,
+		WhatsAppAccount: account.Name,
+	})
 }
 
 // sendFlowCompletionWebhook sends session data to configured webhook URL
@@ -1257,8 +1370,17 @@ func (a *App) sendStepWithSkipCheck(account *models.WhatsAppAccount, session *mo
 		return
 	}
 
-	// Not skipping - send the step message normally
-	a.sendStepMessage(account, session, contact, step)
+	// Script steps may choose the next step themselves
+	if step.MessageType == models.FlowStepTypeScript {
+		if nextStepName := a.runScriptStep(account, session, contact, step); nextStepName != "" {
+			skippedSteps[step.StepName] = true
+			a.goToFlowStep(account, session, contact, flow, nextStepName, skippedSteps)
+			return
+		}
+	} else {
+		// Not skipping - send the step message normally
+		a.sendStepMessage(account, session, contact, step)
+	}
 
 	// If input type is "none", automatically advance to next step without waiting for user input
 	if step.InputType == models.InputTypeNone {
@@ -1305,6 +1427,32 @@ func (a *App) sendStepWithSkipCheck(account *models.WhatsAppAccount, session *mo
 	}
 }
 
+// goToFlowStep moves the session to the named step and sends it, completing
+// the flow if the step does not exist
+func (a *App) goToFlowStep(account *models.WhatsAppAccount, session *models.ChatbotSession, contact *models.Contact, flow *models.ChatbotFlow, stepName string, skippedSteps map[string]bool) {
+	var nextStep *models.ChatbotFlowStep
+	for i := range flow.Steps {
+		if flow.Steps[i].StepName == stepName {
+			nextStep = &flow.Steps[i]
+			break
+		}
+	}
+	if nextStep == nil {
+		a.Log.Warn("Next step not found, completing flow", "next_step", stepName)
+		a.completeFlow(account, session, contact, flow)
+		return
+	}
+
+	session.CurrentStep = nextStep.StepName
+	session.StepRetries = 0
+	a.DB.Model(session).Updates(map[string]interface{}{
+		"current_step": nextStep.StepName,
+		"step_retries": 0,
+	})
+
+	a.sendStepWithSkipCheck(account, session, contact, nextStep, flow, skippedSteps)
+}
+
 // sendStepMessage sends the appropriate message based on step message_type
 func (a *App) sendStepMessage(account *models.WhatsAppAccount, session *models.ChatbotSession, contact *models.Contact, step *models.ChatbotFlowStep) {
 	var message string
@@ -1353,6 +1501,27 @@ func (a *App) sendStepMessage(account *models.WhatsAppAccount, session *models.C
 		}
 		a.logSessionMessage(session.ID, models.DirectionOutgoing, message, step.StepName)
 
+	case models.FlowStepTypeScript:
+		// Flow routing of script steps happens in sendStepWithSkipCheck; here the
+		// script only runs and sends its message
+		a.runScriptStep(account, session, contact, step)
+
+	case models.FlowStepTypeTemplate:
+		// Send the linked template, which also works outside the 24-hour window
+		body, err := a.sendTemplateStep(account, session, contact, step)
+		if err != nil {
+			a.Log.Error("Failed to send template step", "error", err, "step", step.StepName, "contact", contact.PhoneNumber)
+			fallback, _ := step.TemplateConfig["fallback_message"].(string)
+			if fallback == "" {
+				return
+			}
+			body = processTemplate(fallback, session.SessionData)
+			if err := a.sendAndSaveTextMessage(account, contact, body); err != nil {
+				a.Log.Error("Failed to send template fallback message", "error", err, "contact", contact.PhoneNumber)
+			}
+		}
+		a.logSessionMessage(session.ID, models.DirectionOutgoing, body, step.StepName)
+
 	case models.FlowStepTypeButtons:
 		// Send interactive buttons message
 		message = processTemplate(step.Message, session.SessionData)
@@ -1387,6 +1556,7 @@ func (a *App) sendStepMessage(account *models.WhatsAppAccount, session *models.C
 		// Get transfer configuration
 		var teamID *uuid.UUID
 		var notes string
+		var requiredSkills models.StringArray
 		if step.TransferConfig != nil {
 			if teamIDStr, ok := step.TransferConfig["team_id"].(string); ok && teamIDStr != "" && teamIDStr != "_general" {
 				if parsedID, err := uuid.Parse(teamIDStr); err == nil {
@@ -1396,14 +1566,15 @@ func (a *App) sendStepMessage(account *models.WhatsAppAccount, session *models.C
 			if n, ok := step.TransferConfig["notes"].(string); ok {
 				notes = processTemplate(n, session.SessionData)
 			}
+			requiredSkills = models.SkillsFromConfig(step.TransferConfig)
 		}
 
 		// Create the transfer
 		if teamID != nil {
-			a.createTransferToTeam(account, contact, *teamID, notes, models.TransferSourceFlow)
+			a.createTransferToTeam(account, contact, *teamID, notes, models.TransferSourceFlow, requiredSkills)
 		} else {
 			// General queue transfer
-			a.createTransferToQueue(account, contact, models.TransferSourceFlow)
+			a.createTransferToQueue(account, contact, models.TransferSourceFlow, requiredSkills)
 		}
 
 		// End the flow session (transfer takes over)
@@ -1631,35 +1802,127 @@ func (a *App) fetchApiResponse(apiConfig models.JSONB, sessionData models.JSONB,
 	return result, nil
 }
 
-// generateAIResponse generates a response using the configured AI provider
-func (a *App) generateAIResponse(settings *models.ChatbotSettings, session *models.ChatbotSession, userMessage string) (string, error) {
-	// Build context from AIContext entries
-	contextData := a.buildAIContext(settings.OrganizationID, session, userMessage)
-
-	switch settings.AI.Provider {
-	case models.AIProviderOpenAI:
-		return a.generateOpenAIResponse(settings, session, userMessage, contextData)
-	case models.AIProviderAnthropic:
-		return a.generateAnthropicResponse(settings, session, userMessage, contextData)
-	case models.AIProviderGoogle:
-		return a.generateGoogleResponse(settings, session, userMessage, contextData)
-	default:
-		return "", fmt.Errorf("unsupported AI provider: %s", settings.AI.Provider)
+// aiReply is the outcome of an AI turn
+type aiReply struct {
+	Content   string
+	Citations []KnowledgeCitation
+	// Handoff transfers the conversation or starts a flow when the AI called
+	// such a tool; it runs after Content has been sent
+	Handoff func()
+}
+
+// generateAIResponse generates a response using the configured AI provider,
+// falling back to the configured fallback providers when it fails. Token
+// usage is added to the session. The knowledge base chunks the answer was
+// grounded on are returned as citations. The model may call the allow-listed
+// tools before answering; each call is logged to the session.
+func (a *App) generateAIResponse(settings *models.ChatbotSettings, account *models.WhatsAppAccount, contact *models.Contact, session *models.ChatbotSession, userMessage string) (*aiReply, error) {
+	provider, err := a.newAIProvider(settings.AI)
+	if err != nil {
+		return nil, err
+	}
+
+	// Build context from AIContext entries and the knowledge base
+	contextData, citations := a.buildAIContext(settings, session, userMessage)
+
+	// Build system prompt with context
+	systemPrompt := settings.AI.SystemPrompt
+	if contextData != "" {
+		if systemPrompt != "" {
+			systemPrompt = systemPrompt + "\n\n" + contextData
+		} else {
+			systemPrompt = contextData
+		}
+	}
+	if session != nil && session.Language != "" {
+		systemPrompt = strings.TrimSpace(systemPrompt + "\n\n" + languageInstruction(session.Language))
+	}
+
+	// Add conversation history if enabled
+	var messages []ai.Message
+	if settings.AI.IncludeHistory && session != nil {
+		history := a.getSessionHistory(session.ID, settings.AI.HistoryLimit)
+		for _, msg := range history {
+			role := ai.RoleUser
+			if msg.Direction == models.DirectionOutgoing {
+				role = ai.RoleAssistant
+			}
+			messages = append(messages, ai.Message{Role: role, Content: msg.Message})
+		}
+	}
+
+	// Add current user message
+	messages = append(messages, ai.Message{Role: ai.RoleUser, Content: userMessage})
+
+	run := &aiToolRun{settings: settings, account: account, contact: contact, session: session, userMessage: userMessage}
+	tools := a.buildAITools(run)
+
+	for round := 0; ; round++ {
+		req := ai.Request{
+			SystemPrompt: systemPrompt,
+			Messages:     messages,
+			MaxTokens:    settings.AI.MaxTokens,
+			Temperature:  settings.AI.Temperature,
+		}
+		// The last round has no tools so the model has to answer
+		if round < maxAIToolRounds {
+			req.Tools = tools
+		}
+		resp, err := provider.Generate(context.Background(), req)
+		if err != nil {
+			return nil, err
+		}
+
+		if resp.Provider != provider.Name() {
+			a.Log.Warn("AI response generated by fallback provider", "provider", resp.Provider, "model", resp.Model)
+		}
+		if session != nil {
+			a.recordAIUsage(session, resp.Usage)
+		}
+
+		reply := &aiReply{Content: resp.Content, Citations: citations}
+		if len(resp.ToolCalls) == 0 || len(req.Tools) == 0 {
+			return reply, nil
+		}
+
+		// Every call of the response runs; a handoff waits until the others are
+		// done, and only the first one is kept
+		messages = append(messages, ai.Message{Role: ai.RoleAssistant, Content: resp.Content, ToolCalls: resp.ToolCalls})
+		for _, call := range resp.ToolCalls {
+			result, handoff := a.runAITool(run, call)
+			if handoff != nil {
+				if reply.Handoff != nil {
+					result = map[string]interface{}{"error": "the conversation was already handed off by another tool call"}
+				} else {
+					reply.Handoff = handoff
+				}
+			}
+			a.Log.Info("AI tool called", "tool", call.Name, "session_id", session.ID)
+			a.logAIToolCall(session.ID, call, result)
+			content, _ := json.Marshal(result)
+			messages = append(messages, ai.Message{Role: ai.RoleTool, ToolCallID: call.ID, ToolName: call.Name, Content: string(content)})
+		}
+		if reply.Handoff != nil {
+			return reply, nil
+		}
 	}
 }
 
-// buildAIContext fetches and combines all AI context data
-func (a *App) buildAIContext(orgID uuid.UUID, session *models.ChatbotSession, userMessage string) string {
+// buildAIContext fetches and combines all AI context data and the knowledge
+// base chunks relevant to the user message
+func (a *App) buildAIContext(settings *models.ChatbotSettings, session *models.ChatbotSession, userMessage string) (string, []KnowledgeCitation) {
 	// Get WhatsApp account for cache key
 	whatsAppAccount := ""
 	if session != nil {
 		whatsAppAccount = session.WhatsAppAccount
 	}
 
+	var sections []string
+
 	// Use cached AI contexts
-	contexts, err := a.getAIContextsCached(orgID, whatsAppAccount)
-	if err != nil || len(contexts) == 0 {
-		return ""
+	contexts, err := a.getAIContextsCached(settings.OrganizationID, whatsAppAccount)
+	if err != nil {
+		a.Log.Error("Failed to load AI contexts", "error", err, "organization_id", settings.OrganizationID)
 	}
 
 	var contextParts []string
@@ -1675,6 +1938,11 @@ func (a *App) buildAIContext(orgID uuid.UUID, session *models.ChatbotSession, us
 			// Start with static content/prompt if provided
 			content = ctx.StaticContent
 
+			// The AI calls the API on demand when it may use call_context_api
+			if aiToolAllowed(settings.AI, models.AIToolCallContextAPI) {
+				break
+			}
+
 			// Fetch data from external API and append
 			apiContent, err := a.fetchAPIContext(ctx.ApiConfig, session, userMessage)
 			if err != nil {
@@ -1694,11 +1962,16 @@ func (a *App) buildAIContext(orgID uuid.UUID, session *models.ChatbotSession, us
 		}
 	}
 
-	if len(contextParts) == 0 {
-		return ""
+	if len(contextParts) > 0 {
+		sections = append(sections, "## Context Information\n\n"+strings.Join(contextParts, "\n\n"))
 	}
 
-	return "## Context Information\n\n" + strings.Join(contextParts, "\n\n")
+	knowledgeContext, citations := a.buildKnowledgeContext(settings, session, userMessage)
+	if knowledgeContext != "" {
+		sections = append(sections, knowledgeContext)
+	}
+
+	return strings.Join(sections, "\n\n"), citations
 }
 
 // fetchAPIContext fetches context data from an external API
@@ -1741,332 +2014,10 @@ func (a *App) fetchAPIContext(apiConfig models.JSONB, session *models.ChatbotSes
 	return string(respBody), nil
 }
 
-// generateOpenAIResponse generates a response using OpenAI API
-func (a *App) generateOpenAIResponse(settings *models.ChatbotSettings, session *models.ChatbotSession, userMessage string, contextData string) (string, error) {
-	url := "https://api.openai.com/v1/chat/completions"
-
-	// Build messages array
-	messages := []map[string]string{}
-
-	// Build system prompt with context
-	systemPrompt := settings.AI.SystemPrompt
-	if contextData != "" {
-		if systemPrompt != "" {
-			systemPrompt = systemPrompt + "\n\n" + contextData
-		} else {
-			systemPrompt = contextData
-		}
-	}
-
-	// Add system prompt if configured
-	if systemPrompt != "" {
-		messages = append(messages, map[string]string{
-			"role":    "system",
-			"content": systemPrompt,
-		})
-	}
-
-	// Add conversation history if enabled
-	if settings.AI.IncludeHistory && session != nil {
-		history := a.getSessionHistory(session.ID, settings.AI.HistoryLimit)
-		for _, msg := range history {
-			role := "user"
-			if msg.Direction == models.DirectionOutgoing {
-				role = "assistant"
-			}
-			messages = append(messages, map[string]string{
-				"role":    role,
-				"content": msg.Message,
-			})
-		}
-	}
-
-	// Add current user message
-	messages = append(messages, map[string]string{
-		"role":    "user",
-		"content": userMessage,
-	})
-
-	payload := map[string]interface{}{
-		"model":      settings.AI.Model,
-		"messages":   messages,
-		"max_tokens": settings.AI.MaxTokens,
-	}
-
-	if settings.AI.Temperature > 0 {
-		payload["temperature"] = settings.AI.Temperature
-	}
-
-	jsonPayload, err := json.Marshal(payload)
-	if err != nil {
-		return "", fmt.Errorf("failed to marshal payload: %w", err)
-	}
-
-	req, err := http.NewRequest("POST", url, bytes.NewBuffer(jsonPayload))
-	if err != nil {
-		return "", fmt.Errorf("failed to create request: %w", err)
-	}
-
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Authorization", "Bearer "+settings.AI.APIKey)
-
-	resp, err := a.HTTPClient.Do(req)
-	if err != nil {
-		return "", fmt.Errorf("request failed: %w", err)
-	}
-	defer func() { _ = resp.Body.Close() }()
-
-	body, _ := io.ReadAll(resp.Body)
-
-	if resp.StatusCode != 200 {
-		var errResp struct {
-			Error struct {
-				Message string `json:"message"`
-			} `json:"error"`
-		}
-		_ = json.Unmarshal(body, &errResp)
-		return "", fmt.Errorf("OpenAI API error: %s", errResp.Error.Message)
-	}
-
-	var result struct {
-		Choices []struct {
-			Message struct {
-				Content string `json:"content"`
-			} `json:"message"`
-		} `json:"choices"`
-	}
-	if err := json.Unmarshal(body, &result); err != nil {
-		return "", fmt.Errorf("failed to parse response: %w", err)
-	}
-
-	if len(result.Choices) > 0 {
-		return strings.TrimSpace(result.Choices[0].Message.Content), nil
-	}
-
-	return "", fmt.Errorf("no response from OpenAI")
-}
-
-// generateAnthropicResponse generates a response using Anthropic API
-func (a *App) generateAnthropicResponse(settings *models.ChatbotSettings, session *models.ChatbotSession, userMessage string, contextData string) (string, error) {
-	url := "https://api.anthropic.com/v1/messages"
-
-	// Build messages array
-	messages := []map[string]string{}
-
-	// Add conversation history if enabled
-	if settings.AI.IncludeHistory && session != nil {
-		history := a.getSessionHistory(session.ID, settings.AI.HistoryLimit)
-		for _, msg := range history {
-			role := "user"
-			if msg.Direction == models.DirectionOutgoing {
-				role = "assistant"
-			}
-			messages = append(messages, map[string]string{
-				"role":    role,
-				"content": msg.Message,
-			})
-		}
-	}
-
-	// Add current user message
-	messages = append(messages, map[string]string{
-		"role":    "user",
-		"content": userMessage,
-	})
-
-	payload := map[string]interface{}{
-		"model":      settings.AI.Model,
-		"messages":   messages,
-		"max_tokens": settings.AI.MaxTokens,
-	}
-
-	// Build system prompt with context
-	systemPrompt := settings.AI.SystemPrompt
-	if contextData != "" {
-		if systemPrompt != "" {
-			systemPrompt = systemPrompt + "\n\n" + contextData
-		} else {
-			systemPrompt = contextData
-		}
-	}
-
-	// Add system prompt if configured
-	if systemPrompt != "" {
-		payload["system"] = systemPrompt
-	}
-
-	if settings.AI.Temperature > 0 {
-		payload["temperature"] = settings.AI.Temperature
-	}
-
-	jsonPayload, err := json.Marshal(payload)
-	if err != nil {
-		return "", fmt.Errorf("failed to marshal payload: %w", err)
-	}
-
-	req, err := http.NewRequest("POST", url, bytes.NewBuffer(jsonPayload))
-	if err != nil {
-		return "", fmt.Errorf("failed to create request: %w", err)
-	}
-
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("x-api-key", settings.AI.APIKey)
-	req.Header.Set("anthropic-version", "2023-06-01")
-
-	resp, err := a.HTTPClient.Do(req)
-	if err != nil {
-		return "", fmt.Errorf("request failed: %w", err)
-	}
-	defer func() { _ = resp.Body.Close() }()
-
-	body, _ := io.ReadAll(resp.Body)
-
-	if resp.StatusCode != 200 {
-		var errResp struct {
-			Error struct {
-				Message string `json:"message"`
-			} `json:"error"`
-		}
-		_ = json.Unmarshal(body, &errResp)
-		return "", fmt.Errorf("anthropic API error: %s", errResp.Error.Message)
-	}
-
-	var result struct {
-		Content []struct {
-			Type string `json:"type"`
-			Text string `json:"text"`
-		} `json:"content"`
-	}
-	if err := json.Unmarshal(body, &result); err != nil {
-		return "", fmt.Errorf("failed to parse response: %w", err)
-	}
-
-	for _, content := range result.Content {
-		if content.Type == "text" {
-			return strings.TrimSpace(content.Text), nil
-		}
-	}
-
-	return "", fmt.Errorf("no text response from Anthropic")
-}
-
-// generateGoogleResponse generates a response using Google Gemini API
-func (a *App) generateGoogleResponse(settings *models.ChatbotSettings, session *models.ChatbotSession, userMessage string, contextData string) (string, error) {
-	url := fmt.Sprintf("https://generativelanguage.googleapis.com/v1beta/models/%s:generateContent?key=%s",
-		settings.AI.Model, settings.AI.APIKey)
-
-	// Build contents array
-	contents := []map[string]interface{}{}
-
-	// Add conversation history if enabled
-	if settings.AI.IncludeHistory && session != nil {
-		history := a.getSessionHistory(session.ID, settings.AI.HistoryLimit)
-		for _, msg := range history {
-			role := "user"
-			if msg.Direction == models.DirectionOutgoing {
-				role = "model"
-			}
-			contents = append(contents, map[string]interface{}{
-				"role": role,
-				"parts": []map[string]string{
-					{"text": msg.Message},
-				},
-			})
-		}
-	}
-
-	// Add current user message
-	contents = append(contents, map[string]interface{}{
-		"role": "user",
-		"parts": []map[string]string{
-			{"text": userMessage},
-		},
-	})
-
-	payload := map[string]interface{}{
-		"contents": contents,
-		"generationConfig": map[string]interface{}{
-			"maxOutputTokens": settings.AI.MaxTokens,
-		},
-	}
-
-	// Build system prompt with context
-	systemPrompt := settings.AI.SystemPrompt
-	if contextData != "" {
-		if systemPrompt != "" {
-			systemPrompt = systemPrompt + "\n\n" + contextData
-		} else {
-			systemPrompt = contextData
-		}
-	}
-
-	// Add system instruction if configured
-	if systemPrompt != "" {
-		payload["systemInstruction"] = map[string]interface{}{
-			"parts": []map[string]string{
-				{"text": systemPrompt},
-			},
-		}
-	}
-
-	if settings.AI.Temperature > 0 {
-		payload["generationConfig"].(map[string]interface{})["temperature"] = settings.AI.Temperature
-	}
-
-	jsonPayload, err := json.Marshal(payload)
-	if err != nil {
-		return "", fmt.Errorf("failed to marshal payload: %w", err)
-	}
-
-	req, err := http.NewRequest("POST", url, bytes.NewBuffer(jsonPayload))
-	if err != nil {
-		return "", fmt.Errorf("failed to create request: %w", err)
-	}
-
-	req.Header.Set("Content-Type", "application/json")
-
-	resp, err := a.HTTPClient.Do(req)
-	if err != nil {
-		return "", fmt.Errorf("request failed: %w", err)
-	}
-	defer func() { _ = resp.Body.Close() }()
-
-	body, _ := io.ReadAll(resp.Body)
-
-	if resp.StatusCode != 200 {
-		var errResp struct {
-			Error struct {
-				Message string `json:"message"`
-			} `json:"error"`
-		}
-		_ = json.Unmarshal(body, &errResp)
-		return "", fmt.Errorf("google AI API error: %s", errResp.Error.Message)
-	}
-
-	var result struct {
-		Candidates []struct {
-			Content struct {
-				Parts []struct {
-					Text string `json:"text"`
-				} `json:"parts"`
-			} `json:"content"`
-		} `json:"candidates"`
-	}
-	if err := json.Unmarshal(body, &result); err != nil {
-		return "", fmt.Errorf("failed to parse response: %w", err)
-	}
-
-	if len(result.Candidates) > 0 && len(result.Candidates[0].Content.Parts) > 0 {
-		return strings.TrimSpace(result.Candidates[0].Content.Parts[0].Text), nil
-	}
-
-	return "", fmt.Errorf("no response from Google AI")
-}
-
 // getSessionHistory retrieves recent messages from the session
 func (a *App) getSessionHistory(sessionID uuid.UUID, limit int) []models.ChatbotSessionMessage {
 	var messages []models.ChatbotSessionMessage
-	a.DB.Where("session_id = ?", sessionID).
+	a.DB.Where("session_id = ? AND step_name <> ?", sessionID, aiToolStepName).
 		Order("created_at DESC").
 		Limit(limit).
 		Find(&messages)
@@ -2197,7 +2148,7 @@ type MediaInfo struct {
 }
 
 // saveIncomingMessage saves an incoming message to the messages table
-func (a *App) saveIncomingMessage(account *models.WhatsAppAccount, contact *models.Contact, whatsappMsgID, msgType, content string, mediaInfo *MediaInfo, replyToWAMID string) {
+func (a *App) saveIncomingMessage(account *models.WhatsAppAccount, contact *models.Contact, whatsappMsgID, msgType, content string, mediaInfo *MediaInfo, replyToWAMID string) *models.Message {
 	now := time.Now()
 
 	message := models.Message{
@@ -2232,7 +2183,7 @@ func (a *App) saveIncomingMessage(account *models.WhatsAppAccount, contact *mode
 
 	if err := a.DB.Create(&message).Error; err != nil {
 		a.Log.Error("Failed to save incoming message", "error", err)
-		return
+		return nil
 	}
 
 	// Update contact's last message info
@@ -2252,13 +2203,16 @@ func (a *App) saveIncomingMessage(account *models.WhatsAppAccount, contact *mode
 		"last_inbound_at":      now,
 	})
 
+	// A message from the customer opens or reopens their conversation
+	a.openConversation(contact, nil, now)
+
 	a.Log.Info("Saved incoming message", "message_id", message.ID, "contact_id", contact.ID, "media_url", message.MediaURL)
 
 	// Broadcast new message via WebSocket
 	a.broadcastNewMessage(account.OrganizationID, &message, contact)
 
 	// Dispatch webhook for incoming message
-	a.DispatchWebhook(account.OrganizationID, models.WebhookEventMessageIncoming, MessageEventData{
+	a.DispatchWebhook(account.OrganizationID, models.WebhookEventMessageIncoming, webhookutil.MessageEventData{
 		MessageID:       message.ID.String(),
 		ContactID:       contact.ID.String(),
 		ContactPhone:    contact.PhoneNumber,
@@ -2268,55 +2222,8 @@ func (a *App) saveIncomingMessage(account *models.WhatsAppAccount, contact *mode
 		WhatsAppAccount: account.Name,
 		Direction:       models.DirectionIncoming,
 	})
-}
 
-// isWithinBusinessHours checks if current time is within configured business hours
-func (a *App) isWithinBusinessHours(businessHours models.JSONBArray) bool {
-	now := time.Now()
-	currentDay := int(now.Weekday()) // 0 = Sunday, 1 = Monday, etc.
-	currentTime := now.Format("15:04")
-
-	for _, bh := range businessHours {
-		bhMap, ok := bh.(map[string]interface{})
-		if !ok {
-			continue
-		}
-
-		// Get day (0-6, Sunday-Saturday)
-		day, ok := bhMap["day"].(float64)
-		if !ok {
-			continue
-		}
-
-		if int(day) != currentDay {
-			continue
-		}
-
-		// Check if enabled for this day
-		enabled, ok := bhMap["enabled"].(bool)
-		if !ok || !enabled {
-			return false // Day exists but is disabled
-		}
-
-		// Get start and end times
-		startTime, ok := bhMap["start_time"].(string)
-		if !ok {
-			continue
-		}
-		endTime, ok := bhMap["end_time"].(string)
-		if !ok {
-			continue
-		}
-
-		// Compare times (simple string comparison works for HH:MM format)
-		if currentTime >= startTime && currentTime <= endTime {
-			return true
-		}
-		return false // Found the day but outside hours
-	}
-
-	// If no matching day found, assume outside business hours
-	return false
+	return &message
 }
 
 // shouldSkipStep evaluates a text expression like "(status == 'vip' OR amount > 100) AND name != ”"
@@ -2435,6 +2342,9 @@ func evaluateSingleCondition(expr string, data map[string]interface{}) bool {
 				actualValue := ""
 				if val, exists := data[varName]; exists && val != nil {
 					actualValue = fmt.Sprintf("%v", val)
+				} else if val := getNestedValue(data, varName); val != nil {
+					// Dotted paths such as contact.metadata.plan
+					actualValue = fmt.Sprintf("%v", val)
 				}
 
 				return compareValues(actualValue, op, expectedValue)
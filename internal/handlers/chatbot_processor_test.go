@@ -73,19 +73,19 @@ func TestMatchKeywordRules_ExactMatch(t *testing.T) {
 	}
 	require.NoError(t, app.DB.Create(rule).Error)
 
-	resp, matched := app.matchKeywordRules(org.ID, account.Name, "hello")
+	resp, matched := app.matchKeywordRules(org.ID, account.Name, "hello", nil)
 	assert.True(t, matched)
 	require.NotNil(t, resp)
 	assert.Equal(t, "Hello response", resp.Body)
 
 	// Different case should also match (case insensitive by default)
-	resp2, matched2 := app.matchKeywordRules(org.ID, account.Name, "HELLO")
+	resp2, matched2 := app.matchKeywordRules(org.ID, account.Name, "HELLO", nil)
 	assert.True(t, matched2)
 	require.NotNil(t, resp2)
 	assert.Equal(t, "Hello response", resp2.Body)
 
 	// Partial should NOT match exact
-	_, matched3 := app.matchKeywordRules(org.ID, account.Name, "hello world")
+	_, matched3 := app.matchKeywordRules(org.ID, account.Name, "hello world", nil)
 	assert.False(t, matched3)
 }
 
@@ -108,10 +108,10 @@ func TestMatchKeywordRules_ExactMatch_CaseSensitive(t *testing.T) {
 	}
 	require.NoError(t, app.DB.Create(rule).Error)
 
-	_, matched := app.matchKeywordRules(org.ID, account.Name, "Hello")
+	_, matched := app.matchKeywordRules(org.ID, account.Name, "Hello", nil)
 	assert.True(t, matched)
 
-	_, matched2 := app.matchKeywordRules(org.ID, account.Name, "hello")
+	_, matched2 := app.matchKeywordRules(org.ID, account.Name, "hello", nil)
 	assert.False(t, matched2)
 }
 
@@ -133,15 +133,15 @@ func TestMatchKeywordRules_ContainsMatch(t *testing.T) {
 	}
 	require.NoError(t, app.DB.Create(rule).Error)
 
-	resp, matched := app.matchKeywordRules(org.ID, account.Name, "I need help please")
+	resp, matched := app.matchKeywordRules(org.ID, account.Name, "I need help please", nil)
 	assert.True(t, matched)
 	require.NotNil(t, resp)
 	assert.Equal(t, "Help response", resp.Body)
 
-	_, matched2 := app.matchKeywordRules(org.ID, account.Name, "HELP ME")
+	_, matched2 := app.matchKeywordRules(org.ID, account.Name, "HELP ME", nil)
 	assert.True(t, matched2)
 
-	_, matched3 := app.matchKeywordRules(org.ID, account.Name, "goodbye")
+	_, matched3 := app.matchKeywordRules(org.ID, account.Name, "goodbye", nil)
 	assert.False(t, matched3)
 }
 
@@ -163,12 +163,12 @@ func TestMatchKeywordRules_StartsWithMatch(t *testing.T) {
 	}
 	require.NoError(t, app.DB.Create(rule).Error)
 
-	resp, matched := app.matchKeywordRules(org.ID, account.Name, "hi there")
+	resp, matched := app.matchKeywordRules(org.ID, account.Name, "hi there", nil)
 	assert.True(t, matched)
 	require.NotNil(t, resp)
 	assert.Equal(t, "Hi response", resp.Body)
 
-	_, matched2 := app.matchKeywordRules(org.ID, account.Name, "say hi")
+	_, matched2 := app.matchKeywordRules(org.ID, account.Name, "say hi", nil)
 	assert.False(t, matched2)
 }
 
@@ -190,12 +190,12 @@ func TestMatchKeywordRules_RegexMatch(t *testing.T) {
 	}
 	require.NoError(t, app.DB.Create(rule).Error)
 
-	resp, matched := app.matchKeywordRules(org.ID, account.Name, "I have order #12345")
+	resp, matched := app.matchKeywordRules(org.ID, account.Name, "I have order #12345", nil)
 	assert.True(t, matched)
 	require.NotNil(t, resp)
 	assert.Equal(t, "Order lookup", resp.Body)
 
-	_, matched2 := app.matchKeywordRules(org.ID, account.Name, "where is my package")
+	_, matched2 := app.matchKeywordRules(org.ID, account.Name, "where is my package", nil)
 	assert.False(t, matched2)
 }
 
@@ -217,7 +217,7 @@ func TestMatchKeywordRules_NoMatch(t *testing.T) {
 	}
 	require.NoError(t, app.DB.Create(rule).Error)
 
-	resp, matched := app.matchKeywordRules(org.ID, account.Name, "random message")
+	resp, matched := app.matchKeywordRules(org.ID, account.Name, "random message", nil)
 	assert.False(t, matched)
 	assert.Nil(t, resp)
 }
@@ -257,7 +257,7 @@ func TestMatchKeywordRules_Priority(t *testing.T) {
 	require.NoError(t, app.DB.Create(highRule).Error)
 
 	// The higher priority rule should be returned (rules are ORDER BY priority DESC)
-	resp, matched := app.matchKeywordRules(org.ID, account.Name, "this is a test")
+	resp, matched := app.matchKeywordRules(org.ID, account.Name, "this is a test", nil)
 	assert.True(t, matched)
 	require.NotNil(t, resp)
 	assert.Equal(t, "High priority", resp.Body)
@@ -283,7 +283,7 @@ func TestMatchKeywordRules_DisabledRuleIgnored(t *testing.T) {
 	// Explicitly disable: GORM skips zero-value bools with default:true on INSERT.
 	require.NoError(t, app.DB.Model(rule).Update("is_enabled", false).Error)
 
-	_, matched := app.matchKeywordRules(org.ID, account.Name, "disabled")
+	_, matched := app.matchKeywordRules(org.ID, account.Name, "disabled", nil)
 	assert.False(t, matched)
 }
 
@@ -305,7 +305,7 @@ func TestMatchKeywordRules_TransferType(t *testing.T) {
 	}
 	require.NoError(t, app.DB.Create(rule).Error)
 
-	resp, matched := app.matchKeywordRules(org.ID, account.Name, "agent")
+	resp, matched := app.matchKeywordRules(org.ID, account.Name, "agent", nil)
 	assert.True(t, matched)
 	require.NotNil(t, resp)
 	assert.Equal(t, models.ResponseTypeTransfer, resp.ResponseType)
@@ -336,7 +336,7 @@ func TestMatchKeywordRules_WithButtons(t *testing.T) {
 	}
 	require.NoError(t, app.DB.Create(rule).Error)
 
-	resp, matched := app.matchKeywordRules(org.ID, account.Name, "menu")
+	resp, matched := app.matchKeywordRules(org.ID, account.Name, "menu", nil)
 	assert.True(t, matched)
 	require.NotNil(t, resp)
 	assert.Equal(t, "Choose an option:", resp.Body)
@@ -417,11 +417,10 @@ func TestGetOrCreateSession_ExpiredSession(t *testing.T) {
 }
 
 // =============================================================================
-// isWithinBusinessHours
+// inlineSchedule (chatbot settings business hours)
 // =============================================================================
 
-func TestIsWithinBusinessHours_WithinHours(t *testing.T) {
-	app := newProcessorTestApp(t)
+func TestInlineSchedule_WithinHours(t *testing.T) {
 	now := time.Now()
 	dayOfWeek := float64(now.Weekday())
 
@@ -434,12 +433,11 @@ func TestIsWithinBusinessHours_WithinHours(t *testing.T) {
 		},
 	}
 
-	result := app.isWithinBusinessHours(hours)
+	result := inlineSchedule(models.BusinessHoursConfig{Hours: hours}).IsOpen(now)
 	assert.True(t, result)
 }
 
-func TestIsWithinBusinessHours_OutsideHours(t *testing.T) {
-	app := newProcessorTestApp(t)
+func TestInlineSchedule_OutsideHours(t *testing.T) {
 	now := time.Now()
 	dayOfWeek := float64(now.Weekday())
 
@@ -457,13 +455,12 @@ func TestIsWithinBusinessHours_OutsideHours(t *testing.T) {
 	// This will only be true if running at midnight; for all practical purposes it tests false
 	currentTime := now.Format("15:04")
 	if currentTime > "00:01" {
-		result := app.isWithinBusinessHours(hours)
+		result := inlineSchedule(models.BusinessHoursConfig{Hours: hours}).IsOpen(now)
 		assert.False(t, result)
 	}
 }
 
-func TestIsWithinBusinessHours_DayDisabled(t *testing.T) {
-	app := newProcessorTestApp(t)
+func TestInlineSchedule_DayDisabled(t *testing.T) {
 	now := time.Now()
 	dayOfWeek := float64(now.Weekday())
 
@@ -476,12 +473,11 @@ func TestIsWithinBusinessHours_DayDisabled(t *testing.T) {
 		},
 	}
 
-	result := app.isWithinBusinessHours(hours)
+	result := inlineSchedule(models.BusinessHoursConfig{Hours: hours}).IsOpen(now)
 	assert.False(t, result)
 }
 
-func TestIsWithinBusinessHours_NoMatchingDay(t *testing.T) {
-	app := newProcessorTestApp(t)
+func TestInlineSchedule_NoMatchingDay(t *testing.T) {
 	now := time.Now()
 	// Use a different day of the week
 	otherDay := float64((int(now.Weekday()) + 1) % 7)
@@ -495,15 +491,13 @@ func TestIsWithinBusinessHours_NoMatchingDay(t *testing.T) {
 		},
 	}
 
-	result := app.isWithinBusinessHours(hours)
+	result := inlineSchedule(models.BusinessHoursConfig{Hours: hours}).IsOpen(now)
 	assert.False(t, result)
 }
 
-func TestIsWithinBusinessHours_EmptyHours(t *testing.T) {
-	app := newProcessorTestApp(t)
-
-	result := app.isWithinBusinessHours(models.JSONBArray{})
-	assert.False(t, result)
+func TestInlineSchedule_EmptyHours(t *testing.T) {
+	// No hours means business hours are not checked
+	assert.Nil(t, inlineSchedule(models.BusinessHoursConfig{Hours: models.JSONBArray{}}))
 }
 
 // =============================================================================
@@ -0,0 +1,459 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"net/http"
+	"net/url"
+	"runtime/metrics"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/dop251/goja"
+	"github.com/shridarpatil/whatomate/internal/models"
+)
+
+// chatbotScriptTimeout is the default run time of a chatbot script; scripts may
+// configure up to chatbotScriptMaxTimeout
+var (
+	chatbotScriptTimeout    = 2 * time.Second
+	chatbotScriptMaxTimeout = 10 * time.Second
+)
+
+const (
+	chatbotScriptMaxCallStack   = 1000
+	chatbotScriptMaxFetches     = 5
+	chatbotScriptMaxFetchBody   = 1 << 20
+	chatbotScriptMaxRedirects   = 3
+	chatbotScriptMemoryInterval = 5 * time.Millisecond
+	chatbotScriptHeapMetric     = "/gc/heap/live:bytes"
+)
+
+var (
+	// errScriptTimeout is returned when a chatbot script exceeds its time budget
+	errScriptTimeout = errors.New("script exceeded time limit")
+	// errScriptMemory is returned when a chatbot script exceeds its memory budget
+	errScriptMemory = errors.New("script exceeded memory limit")
+)
+
+// chatbotScriptResult is the reply produced by a chatbot script
+type chatbotScriptResult struct {
+	Message  string
+	Buttons  []map[string]interface{}
+	NextStep string
+}
+
+// chatbotScriptOptions bounds a chatbot script run. Zero values use the defaults.
+type chatbotScriptOptions struct {
+	Timeout      time.Duration
+	MemoryLimit  int64    // Bytes the script may allocate
+	AllowedHosts []string // Hosts fetch() may call; "*.example.com" matches subdomains. Empty disables fetch.
+	HTTPClient   *http.Client
+}
+
+// scriptOptions reads the limits of a script config
+// ({code, timeout_ms, memory_limit_mb, allowed_hosts}), capping them at the maximums
+func (a *App) scriptOptions(config map[string]interface{}) chatbotScriptOptions {
+	opts := chatbotScriptOptions{HTTPClient: a.HTTPClient}
+	if ms, ok := config["timeout_ms"].(float64); ok && ms > 0 {
+		opts.Timeout = min(time.Duration(ms)*time.Millisecond, chatbotScriptMaxTimeout)
+	}
+	if mb, ok := config["memory_limit_mb"].(float64); ok && mb > 0 {
+		opts.MemoryLimit = min(int64(mb*(1<<20)), chatbotScriptMaxMemoryLimit)
+	}
+	if hosts, ok := settingsStringSlice(config["allowed_hosts"]); ok {
+		for _, h := range hosts {
+			if h = strings.ToLower(strings.TrimSpace(h)); h != "" {
+				opts.AllowedHosts = append(opts.AllowedHosts, h)
+			}
+		}
+	}
+	return opts
+}
+
+// validateScriptConfig checks that a script config has code that compiles and valid limits
+func validateScriptConfig(config map[string]interface{}) error {
+	code, _ := config["code"].(string)
+	if strings.TrimSpace(code) == "" {
+		return errors.New("script code is required")
+	}
+	if _, err := goja.Compile("", "(function() {\n"+code+"\n})", false); err != nil {
+		return fmt.Errorf("script does not compile: %w", err)
+	}
+	if v, ok := config["timeout_ms"]; ok && v != nil {
+		if n, ok := v.(float64); !ok || n <= 0 {
+			return errors.New("timeout_ms must be a positive number")
+		}
+	}
+	if v, ok := config["memory_limit_mb"]; ok && v != nil {
+		if n, ok := v.(float64); !ok || n <= 0 {
+			return errors.New("memory_limit_mb must be a positive number")
+		}
+	}
+	if v, ok := config["allowed_hosts"]; ok && v != nil {
+		hosts, ok := v.([]interface{})
+		if !ok {
+			return errors.New("allowed_hosts must be a list of host names")
+		}
+		for _, h := range hosts {
+			s, ok := h.(string)
+			if !ok || strings.TrimPrefix(strings.TrimSpace(s), "*.") == "" || strings.ContainsAny(s, "/:@ ") {
+				return fmt.Errorf("invalid allowed host %v", h)
+			}
+		}
+	}
+	return nil
+}
+
+// runChatbotScript runs code in a sandboxed goja VM. The code is the body of a
+// function whose parameters are the keys of vars, in sorted order, and may
+// return either a string or an object with "message", "buttons" and
+// "next_step". Maps in vars are shared with the script, so its writes to them
+// are visible to the caller. The VM has no filesystem access, reaches the
+// network only through fetch() to allow-listed hosts, and is interrupted when
+// it exceeds its time or memory budget.
+func runChatbotScript(code string, vars map[string]interface{}, opts chatbotScriptOptions) (*chatbotScriptResult, error) {
+	if opts.Timeout <= 0 {
+		opts.Timeout = chatbotScriptTimeout
+	}
+	if opts.MemoryLimit <= 0 {
+		opts.MemoryLimit = chatbotScriptMemoryLimit
+	}
+
+	vm := goja.New()
+	vm.SetFieldNameMapper(goja.TagFieldNameMapper("json", true))
+	vm.SetMaxCallStackSize(chatbotScriptMaxCallStack)
+	if err := installScriptMemoryLimits(vm, opts.MemoryLimit); err != nil {
+		return nil, fmt.Errorf("failed to set up script sandbox: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), opts.Timeout)
+	defer cancel()
+
+	names := slices.Sorted(maps.Keys(vars))
+	for _, name := range names {
+		if err := vm.Set(name, vars[name]); err != nil {
+			return nil, fmt.Errorf("failed to set %s: %w", name, err)
+		}
+	}
+	if err := vm.Set("fetch", newScriptFetch(ctx, vm, opts)); err != nil {
+		return nil, fmt.Errorf("failed to set fetch: %w", err)
+	}
+
+	done := make(chan struct{})
+	defer close(done)
+	go watchScriptLimits(ctx, vm, uint64(opts.MemoryLimit), done)
+
+	params := strings.Join(names, ", ")
+	wrapped := fmt.Sprintf("(function(%s) {\n%s\n})(%s)", params, code, params)
+	val, err := vm.RunString(wrapped)
+	if err != nil {
+		var interrupted *goja.InterruptedError
+		if errors.As(err, &interrupted) {
+			if cause, ok := interrupted.Value().(error); ok {
+				return nil, cause
+			}
+			return nil, errScriptTimeout
+		}
+		if errors.Is(err, errScriptMemory) {
+			return nil, errScriptMemory
+		}
+		var overflow *goja.StackOverflowError
+		if errors.As(err, &overflow) {
+			return nil, errors.New("script exceeded call stack limit")
+		}
+		return nil, fmt.Errorf("script error: %w", err)
+	}
+
+	result := &chatbotScriptResult{}
+	if val == nil || goja.IsUndefined(val) || goja.IsNull(val) {
+		return result, nil
+	}
+	switch v := val.Export().(type) {
+	case string:
+		result.Message = v
+	case map[string]interface{}:
+		if msg, ok := v["message"].(string); ok {
+			result.Message = msg
+		}
+		if next, ok := v["next_step"].(string); ok {
+			result.NextStep = next
+		}
+		if buttons, ok := v["buttons"].([]interface{}); ok {
+			for _, btn := range buttons {
+				if btnMap, ok := btn.(map[string]interface{}); ok {
+					result.Buttons = append(result.Buttons, btnMap)
+				}
+			}
+		}
+	default:
+		result.Message = fmt.Sprintf("%v", v)
+	}
+	return result, nil
+}
+
+// watchScriptLimits interrupts the VM when ctx expires or the live heap grows
+// by more than memoryLimit bytes while the script runs. This catches growth
+// the memory budget can't see, such as strings built with + and arrays filled
+// one element at a time. The live heap is shared with the rest of the
+// process, so a script can also be stopped when other work retains a lot of
+// memory while it runs; it never lets a script keep more than its limit.
+func watchScriptLimits(ctx context.Context, vm *goja.Runtime, memoryLimit uint64, done <-chan struct{}) {
+	sample := []metrics.Sample{{Name: chatbotScriptHeapMetric}}
+	heap := func() uint64 {
+		metrics.Read(sample)
+		if sample[0].Value.Kind() != metrics.KindUint64 {
+			return 0
+		}
+		return sample[0].Value.Uint64()
+	}
+	baseline := heap()
+
+	ticker := time.NewTicker(chatbotScriptMemoryInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-done:
+			return
+		case <-ctx.Done():
+			vm.Interrupt(errScriptTimeout)
+			return
+		case <-ticker.C:
+			if current := heap(); current > baseline && current-baseline > memoryLimit {
+				vm.Interrupt(errScriptMemory)
+				return
+			}
+		}
+	}
+}
+
+// newScriptFetch returns the fetch(url, {method, headers, body}) helper exposed
+// to scripts. Unlike the browser API it is synchronous and returns
+// {status, ok, headers, body, json}. Only public URLs on allow-listed hosts can
+// be called, at most chatbotScriptMaxFetches times per run, and responses are
+// limited to chatbotScriptMaxFetchBody bytes. Failures throw in the script.
+func newScriptFetch(ctx context.Context, vm *goja.Runtime, opts chatbotScriptOptions) func(goja.FunctionCall) goja.Value {
+	client := &http.Client{Timeout: opts.Timeout}
+	if opts.HTTPClient != nil {
+		c := *opts.HTTPClient
+		client = &c
+	}
+	client.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		if len(via) >= chatbotScriptMaxRedirects {
+			return errors.New("too many redirects")
+		}
+		return checkScriptFetchURL(req.URL.String(), opts.AllowedHosts)
+	}
+
+	calls := 0
+	return func(call goja.FunctionCall) goja.Value {
+		if len(opts.AllowedHosts) == 0 {
+			panic(vm.NewGoError(errors.New("fetch is not enabled for this script")))
+		}
+		calls++
+		if calls > chatbotScriptMaxFetches {
+			panic(vm.NewGoError(fmt.Errorf("fetch is limited to %d calls per run", chatbotScriptMaxFetches)))
+		}
+		result, err := scriptFetch(ctx, client, call.Argument(0).String(), call.Argument(1).Export(), opts.AllowedHosts)
+		if err != nil {
+			panic(vm.NewGoError(err))
+		}
+		return vm.ToValue(result)
+	}
+}
+
+// scriptFetch performs a fetch() request for a chatbot script
+func scriptFetch(ctx context.Context, client *http.Client, rawURL string, options interface{}, allowedHosts []string) (map[string]interface{}, error) {
+	if err := checkScriptFetchURL(rawURL, allowedHosts); err != nil {
+		return nil, err
+	}
+
+	method := http.MethodGet
+	headers := map[string]string{}
+	var body io.Reader
+	if o, ok := options.(map[string]interface{}); ok {
+		if m, ok := o["method"].(string); ok && m != "" {
+			method = strings.ToUpper(m)
+		}
+		if h, ok := o["headers"].(map[string]interface{}); ok {
+			for k, v := range h {
+				headers[k] = fmt.Sprintf("%v", v)
+			}
+		}
+		switch b := o["body"].(type) {
+		case nil:
+		case string:
+			body = strings.NewReader(b)
+		default:
+			encoded, err := json.Marshal(b)
+			if err != nil {
+				return nil, fmt.Errorf("invalid fetch body: %w", err)
+			}
+			body = bytes.NewReader(encoded)
+			if _, ok := headers["Content-Type"]; !ok {
+				headers["Content-Type"] = "application/json"
+			}
+		}
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, rawURL, body)
+	if err != nil {
+		return nil, fmt.Errorf("invalid fetch request: %w", err)
+	}
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("fetch failed: %w", err)
+	}
+	defer resp.Body.Close() //nolint:errcheck
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, chatbotScriptMaxFetchBody+1))
+	if err != nil {
+		return nil, fmt.Errorf("fetch failed: %w", err)
+	}
+	if len(data) > chatbotScriptMaxFetchBody {
+		return nil, fmt.Errorf("fetch response exceeds %d bytes", chatbotScriptMaxFetchBody)
+	}
+
+	respHeaders := make(map[string]interface{}, len(resp.Header))
+	for k := range resp.Header {
+		respHeaders[strings.ToLower(k)] = resp.Header.Get(k)
+	}
+	result := map[string]interface{}{
+		"status":  resp.StatusCode,
+		"ok":      resp.StatusCode >= 200 && resp.StatusCode < 300,
+		"headers": respHeaders,
+		"body":    string(data),
+		"json":    nil,
+	}
+	var parsed interface{}
+	if json.Unmarshal(data, &parsed) == nil {
+		result["json"] = parsed
+	}
+	return result, nil
+}
+
+// checkScriptFetchURL rejects URLs that are not public http(s) URLs on an allowed host
+func checkScriptFetchURL(rawURL string, allowedHosts []string) error {
+	if err := validateWebhookURL(rawURL); err != nil {
+		return err
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return err
+	}
+	if !scriptHostAllowed(u.Hostname(), allowedHosts) {
+		return fmt.Errorf("host %q is not in the script's allowed hosts", u.Hostname())
+	}
+	return nil
+}
+
+// scriptHostAllowed reports whether host matches an allow-list entry, either
+// exactly or, for "*.example.com" entries, as a subdomain
+func scriptHostAllowed(host string, allowedHosts []string) bool {
+	host = strings.ToLower(host)
+	for _, allowed := range allowedHosts {
+		if host == allowed {
+			return true
+		}
+		if suffix, ok := strings.CutPrefix(allowed, "*."); ok && strings.HasSuffix(host, "."+suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+// runScriptStep runs a flow script step. The script can read and write
+// `session` (the session data), read `contact`, `tag`, the user's last reply as
+// `input` and its button id as `button_id`, and call fetch(). Session changes
+// are saved only when the script succeeds. The message the script returns, or
+// else the step message, is sent; on failure the configured fallback_message
+// is sent instead. Returns the step the script chose to go to next, if any.
+func (a *App) runScriptStep(account *models.WhatsAppAccount, session *models.ChatbotSession, contact *models.Contact, step *models.ChatbotFlowStep) string {
+	config := map[string]interface{}(step.ScriptConfig)
+	code, _ := config["code"].(string)
+
+	sessionData := copyScriptSessionData(session.SessionData)
+	contactData := keywordConditionData(contact, nil)
+	input, _ := sessionData["_last_input"].(string)
+	buttonID, _ := sessionData["_last_button_id"].(string)
+
+	result, err := runChatbotScript(code, map[string]interface{}{
+		"session":   sessionData,
+		"contact":   contactData["contact"],
+		"tag":       contactData["tag"],
+		"input":     input,
+		"button_id": buttonID,
+	}, a.scriptOptions(config))
+	if err == nil {
+		err = a.saveScriptSessionData(session, sessionData)
+	}
+	if err != nil {
+		a.Log.Error("Chatbot script step failed", "error", err, "step", step.StepName, "session_id", session.ID)
+		if fallback, _ := config["fallback_message"].(string); fallback != "" {
+			message := processTemplate(fallback, session.SessionData)
+			if err := a.sendAndSaveTextMessage(account, contact, message); err != nil {
+				a.Log.Error("Failed to send script fallback message", "error", err, "contact", contact.PhoneNumber)
+			}
+			a.logSessionMessage(session.ID, models.DirectionOutgoing, message, step.StepName)
+		}
+		return ""
+	}
+
+	message := result.Message
+	if message == "" && step.Message != "" {
+		message = processTemplate(step.Message, session.SessionData)
+	}
+	if message != "" {
+		var sendErr error
+		if len(result.Buttons) > 0 {
+			sendErr = a.sendAndSaveInteractiveButtons(account, contact, message, result.Buttons)
+		} else {
+			sendErr = a.sendAndSaveTextMessage(account, contact, message)
+		}
+		if sendErr != nil {
+			a.Log.Error("Failed to send script message", "error", sendErr, "contact", contact.PhoneNumber)
+		}
+		a.logSessionMessage(session.ID, models.DirectionOutgoing, message, step.StepName)
+	}
+	return result.NextStep
+}
+
+// saveScriptSessionData stores the session data written by a script, dropping
+// values that can't be stored as JSON such as functions
+func (a *App) saveScriptSessionData(session *models.ChatbotSession, data map[string]interface{}) error {
+	encoded, err := json.Marshal(data)
+	if err != nil {
+		return fmt.Errorf("invalid session data: %w", err)
+	}
+	var saved models.JSONB
+	if err := json.Unmarshal(encoded, &saved); err != nil {
+		return fmt.Errorf("invalid session data: %w", err)
+	}
+	if err := a.DB.Model(session).Update("session_data", saved).Error; err != nil {
+		return fmt.Errorf("failed to save session data: %w", err)
+	}
+	session.SessionData = saved
+	return nil
+}
+
+// copyScriptSessionData deep-copies session data so a failed script leaves it unchanged
+func copyScriptSessionData(data models.JSONB) map[string]interface{} {
+	out := map[string]interface{}{}
+	if encoded, err := json.Marshal(data); err == nil {
+		_ = json.Unmarshal(encoded, &out)
+	}
+	if out == nil {
+		out = map[string]interface{}{}
+	}
+	return out
+}
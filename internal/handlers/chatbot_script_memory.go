@@ -0,0 +1,215 @@
+package handlers
+
+import (
+	"strings"
+
+	"github.com/dop251/goja"
+)
+
+// chatbotScriptMemoryLimit is the default memory budget of a chatbot script
+// run; scripts may configure up to chatbotScriptMaxMemoryLimit
+const (
+	chatbotScriptMemoryLimit    = 32 << 20
+	chatbotScriptMaxMemoryLimit = 128 << 20
+)
+
+// scriptValueOverhead is the size charged for a value that is not a string
+const scriptValueOverhead = 16
+
+// scriptBinaryGlobals are the buffer types removed from the script sandbox,
+// since they allocate their whole size up front in a single native call
+var scriptBinaryGlobals = []string{
+	"ArrayBuffer", "SharedArrayBuffer", "DataView",
+	"Int8Array", "Uint8Array", "Uint8ClampedArray", "Int16Array", "Uint16Array",
+	"Int32Array", "Uint32Array", "Float32Array", "Float64Array", "BigInt64Array", "BigUint64Array",
+}
+
+// scriptMemoryBudget is the memory a chatbot script run may allocate through
+// the built-ins that build large strings in one native call. Those calls can't
+// be interrupted, so they are checked against the budget before they run and
+// charged with what they produced.
+type scriptMemoryBudget struct {
+	vm    *goja.Runtime
+	limit int64
+	used  int64
+}
+
+// reserve stops the script when size more bytes would exceed the budget. The
+// VM is interrupted as well, so the script can't catch the error and go on.
+func (b *scriptMemoryBudget) reserve(size int64) {
+	if size < 0 || b.used+size > b.limit {
+		b.vm.Interrupt(errScriptMemory)
+		panic(b.vm.NewGoError(errScriptMemory))
+	}
+}
+
+// charge adds the size of a value a built-in produced to the budget
+func (b *scriptMemoryBudget) charge(v goja.Value) {
+	if s, ok := v.(goja.String); ok {
+		size := int64(s.Length()) * 2
+		b.reserve(size)
+		b.used += size
+	}
+}
+
+// scriptValueSize estimates the size of v as a string, following arrays and
+// objects the way join and JSON.stringify do. It stops counting once limit is
+// exceeded, so shared or cyclic references can't make it loop for long.
+func scriptValueSize(v goja.Value, limit int64) int64 {
+	var size int64
+	var walk func(v goja.Value, depth int)
+	walk = func(v goja.Value, depth int) {
+		if size > limit {
+			return
+		}
+		switch val := v.(type) {
+		case goja.String:
+			size += int64(val.Length()) * 2
+		case *goja.Object:
+			size += scriptValueOverhead
+			if depth > chatbotScriptMaxCallStack {
+				return
+			}
+			for _, key := range val.Keys() {
+				size += int64(len(key)) * 2
+				walk(val.Get(key), depth+1)
+				if size > limit {
+					return
+				}
+			}
+		default:
+			size += scriptValueOverhead
+		}
+	}
+	walk(v, 0)
+	return size
+}
+
+// stringLength returns the length of v converted to a string, without
+// converting objects
+func stringLength(v goja.Value) int64 {
+	if s, ok := v.(goja.String); ok {
+		return int64(s.Length())
+	}
+	if goja.IsUndefined(v) || goja.IsNull(v) || v == nil {
+		return 0
+	}
+	if _, ok := v.(*goja.Object); ok {
+		return scriptValueOverhead
+	}
+	return int64(len(v.String()))
+}
+
+// installScriptMemoryLimits removes the buffer types from vm and wraps the
+// built-ins that can build large strings in one call with checks against the
+// run's memory budget
+func installScriptMemoryLimits(vm *goja.Runtime, limit int64) error {
+	budget := &scriptMemoryBudget{vm: vm, limit: limit}
+	global := vm.GlobalObject()
+	for _, name := range scriptBinaryGlobals {
+		if err := global.Delete(name); err != nil {
+			return err
+		}
+	}
+
+	remaining := func() int64 { return budget.limit - budget.used }
+	count := func(call goja.FunctionCall, i int) int64 {
+		return max(call.Argument(i).ToInteger(), 0)
+	}
+
+	stringProto := global.Get("String").ToObject(vm).Get("prototype").ToObject(vm)
+	arrayProto := global.Get("Array").ToObject(vm).Get("prototype").ToObject(vm)
+	jsonObj := global.Get("JSON").ToObject(vm)
+
+	guards := []struct {
+		obj      *goja.Object
+		name     string
+		estimate func(call goja.FunctionCall) int64
+	}{
+		{stringProto, "repeat", func(call goja.FunctionCall) int64 {
+			n, c := stringLength(call.This), count(call, 0)
+			if n > 0 && c > remaining()/(2*n) {
+				return -1
+			}
+			return 2 * n * c
+		}},
+		{stringProto, "padStart", func(call goja.FunctionCall) int64 { return 2 * count(call, 0) }},
+		{stringProto, "padEnd", func(call goja.FunctionCall) int64 { return 2 * count(call, 0) }},
+		{stringProto, "concat", func(call goja.FunctionCall) int64 {
+			size := 2 * stringLength(call.This)
+			for _, arg := range call.Arguments {
+				size += 2 * stringLength(arg)
+			}
+			return size
+		}},
+		{stringProto, "replace", func(call goja.FunctionCall) int64 { return replaceSize(call, remaining()) }},
+		{stringProto, "replaceAll", func(call goja.FunctionCall) int64 { return replaceSize(call, remaining()) }},
+		{arrayProto, "join", func(call goja.FunctionCall) int64 {
+			obj, ok := call.This.(*goja.Object)
+			if !ok {
+				return 0
+			}
+			sep := int64(1)
+			if !goja.IsUndefined(call.Argument(0)) {
+				sep = stringLength(call.Argument(0))
+			}
+			n := max(obj.Get("length").ToInteger(), 0)
+			if sep > 0 && n > remaining()/(2*sep) {
+				return -1
+			}
+			return scriptValueSize(obj, remaining()) + 2*sep*n
+		}},
+		{jsonObj, "stringify", func(call goja.FunctionCall) int64 {
+			size := scriptValueSize(call.Argument(0), remaining())
+			// Indentation is repeated for every nested value
+			if indent := call.Argument(2); !goja.IsUndefined(indent) {
+				size *= 1 + min(max(stringLength(indent), indent.ToInteger()), 10)
+			}
+			return size
+		}},
+	}
+
+	for _, g := range guards {
+		original, ok := goja.AssertFunction(g.obj.Get(g.name))
+		if !ok {
+			continue
+		}
+		estimate := g.estimate
+		wrapped := func(call goja.FunctionCall) goja.Value {
+			budget.reserve(estimate(call))
+			result, err := original(call.This, call.Arguments...)
+			if err != nil {
+				panic(err)
+			}
+			budget.charge(result)
+			return result
+		}
+		if err := g.obj.Set(g.name, wrapped); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// replaceSize estimates the result of String.prototype.replace(All). With a
+// string pattern the matches are counted; a regular expression may match at
+// every position, so each one is assumed to.
+func replaceSize(call goja.FunctionCall, remaining int64) int64 {
+	n := stringLength(call.This)
+	repl := stringLength(call.Argument(1))
+	if _, ok := call.Argument(1).(*goja.Object); ok {
+		// A replacer function's results are built by the script and counted there
+		repl = 0
+	}
+	if repl == 0 {
+		return 2 * n
+	}
+	matches := n + 1
+	if pattern, ok := call.Argument(0).(goja.String); ok {
+		matches = int64(strings.Count(call.This.String(), pattern.String()))
+	}
+	if matches > remaining/(2*repl) {
+		return -1
+	}
+	return 2 * (n + matches*repl)
+}
@@ -0,0 +1,245 @@
+package handlers
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shridarpatil/whatomate/internal/models"
+	"github.com/shridarpatil/whatomate/test/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunChatbotScript(t *testing.T) {
+	result, err := runChatbotScript(`return "Hi " + contact.name + ", you said " + message`, map[string]interface{}{
+		"message": "hello",
+		"contact": map[string]interface{}{"name": "Alice"},
+	}, chatbotScriptOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, "Hi Alice, you said hello", result.Message)
+
+	result, err = runChatbotScript(`return {message: "Pick one", buttons: [{id: "a", title: "A"}]}`, nil, chatbotScriptOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, "Pick one", result.Message)
+	require.Len(t, result.Buttons, 1)
+	assert.Equal(t, "a", result.Buttons[0]["id"])
+
+	_, err = runChatbotScript(`throw new Error("boom")`, nil, chatbotScriptOptions{})
+	assert.ErrorContains(t, err, "boom")
+}
+
+func TestRunChatbotScript_SessionWritesAndNextStep(t *testing.T) {
+	session := map[string]interface{}{"visits": float64(1)}
+	result, err := runChatbotScript(`
+		session.visits += 1;
+		session.answer = input.toUpperCase();
+		return {message: "Thanks", next_step: session.visits > 1 ? "returning" : "welcome"};
+	`, map[string]interface{}{"session": session, "input": "yes"}, chatbotScriptOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, "Thanks", result.Message)
+	assert.Equal(t, "returning", result.NextStep)
+	assert.EqualValues(t, 2, session["visits"])
+	assert.Equal(t, "YES", session["answer"])
+}
+
+func TestRunChatbotScript_Timeout(t *testing.T) {
+	_, err := runChatbotScript(`while (true) {}`, nil, chatbotScriptOptions{Timeout: 50 * time.Millisecond})
+	assert.ErrorIs(t, err, errScriptTimeout)
+}
+
+func TestRunChatbotScript_MemoryLimit(t *testing.T) {
+	opts := chatbotScriptOptions{Timeout: 5 * time.Second, MemoryLimit: 16 << 20}
+
+	t.Run("large strings are refused before they are built", func(t *testing.T) {
+		for _, code := range []string{
+			`return "x".repeat(1 << 30)`,
+			`return "".padStart(1 << 30, "x")`,
+			`var s = "x".repeat(8192), a = []; for (var i = 0; i < 2000; i++) a.push(s); return a.join("")`,
+			`var s = "x".repeat(8192), a = []; for (var i = 0; i < 2000; i++) a.push(s); return JSON.stringify(a)`,
+			`var s = "x".repeat(8192); return s.replaceAll("x", s)`,
+		} {
+			_, err := runChatbotScript(code, nil, opts)
+			assert.ErrorIs(t, err, errScriptMemory, code)
+		}
+	})
+
+	t.Run("the error can't be caught", func(t *testing.T) {
+		_, err := runChatbotScript(`try { "x".repeat(1 << 30) } catch (e) {} return "caught"`, nil, opts)
+		assert.ErrorIs(t, err, errScriptMemory)
+	})
+
+	t.Run("growth one value at a time is stopped", func(t *testing.T) {
+		_, err := runChatbotScript(`var a = []; while (true) { a.push({n: a.length, s: "x" + a.length}) }`, nil, opts)
+		assert.ErrorIs(t, err, errScriptMemory)
+	})
+
+	t.Run("buffers are not available", func(t *testing.T) {
+		result, err := runChatbotScript(`return typeof ArrayBuffer + " " + typeof Uint8Array`, nil, opts)
+		require.NoError(t, err)
+		assert.Equal(t, "undefined undefined", result.Message)
+	})
+
+	t.Run("ordinary scripts fit", func(t *testing.T) {
+		result, err := runChatbotScript(`return ["a", "b"].join("-").padEnd(5, ".") + JSON.stringify({n: 1}).replace("1", "2")`, nil, opts)
+		require.NoError(t, err)
+		assert.Equal(t, `a-b..{"n":2}`, result.Message)
+	})
+}
+
+func TestRunChatbotScript_CallStackLimit(t *testing.T) {
+	_, err := runChatbotScript(`function f() { return f() } f()`, nil, chatbotScriptOptions{})
+	assert.ErrorContains(t, err, "call stack")
+}
+
+func TestRunChatbotScript_Fetch(t *testing.T) {
+	_, err := runChatbotScript(`fetch("https://api.example.com/")`, nil, chatbotScriptOptions{})
+	assert.ErrorContains(t, err, "fetch is not enabled")
+
+	_, err = runChatbotScript(`fetch("https://evil.example.org/")`, nil, chatbotScriptOptions{AllowedHosts: []string{"api.example.com"}})
+	assert.ErrorContains(t, err, "not in the script's allowed hosts")
+
+	_, err = runChatbotScript(`fetch("http://127.0.0.1/")`, nil, chatbotScriptOptions{AllowedHosts: []string{"127.0.0.1"}})
+	assert.Error(t, err, "private addresses are rejected even when allow-listed")
+
+	// Errors can be caught by the script
+	result, err := runChatbotScript(`try { fetch("https://evil.example.org/") } catch (e) { return "caught" }`, nil,
+		chatbotScriptOptions{AllowedHosts: []string{"api.example.com"}})
+	require.NoError(t, err)
+	assert.Equal(t, "caught", result.Message)
+}
+
+func TestScriptHostAllowed(t *testing.T) {
+	allowed := []string{"api.example.com", "*.shop.test"}
+	assert.True(t, scriptHostAllowed("api.example.com", allowed))
+	assert.True(t, scriptHostAllowed("API.example.com", allowed))
+	assert.True(t, scriptHostAllowed("eu.shop.test", allowed))
+	assert.False(t, scriptHostAllowed("shop.test", allowed))
+	assert.False(t, scriptHostAllowed("example.com", allowed))
+	assert.False(t, scriptHostAllowed("api.example.com.evil.test", allowed))
+}
+
+func TestValidateScriptConfig(t *testing.T) {
+	assert.NoError(t, validateScriptConfig(map[string]interface{}{
+		"code":          "return 'hi'",
+		"timeout_ms":    float64(500),
+		"allowed_hosts": []interface{}{"api.example.com", "*.shop.test"},
+	}))
+	assert.Error(t, validateScriptConfig(nil))
+	assert.Error(t, validateScriptConfig(map[string]interface{}{"code": "return ("}))
+	assert.Error(t, validateScriptConfig(map[string]interface{}{"code": "return 1", "timeout_ms": float64(-1)}))
+	assert.Error(t, validateScriptConfig(map[string]interface{}{"code": "return 1", "memory_limit_mb": "64"}))
+	assert.Error(t, validateScriptConfig(map[string]interface{}{"code": "return 1", "allowed_hosts": "api.example.com"}))
+	assert.Error(t, validateScriptConfig(map[string]interface{}{"code": "return 1", "allowed_hosts": []interface{}{"https://api.example.com"}}))
+}
+
+func TestScriptOptions(t *testing.T) {
+	app := &App{}
+	opts := app.scriptOptions(map[string]interface{}{
+		"timeout_ms":      float64(60000),
+		"memory_limit_mb": float64(4096),
+		"allowed_hosts":   []interface{}{" API.example.com ", ""},
+	})
+	assert.Equal(t, chatbotScriptMaxTimeout, opts.Timeout)
+	assert.EqualValues(t, chatbotScriptMaxMemoryLimit, opts.MemoryLimit)
+	assert.Equal(t, []string{"api.example.com"}, opts.AllowedHosts)
+
+	defaults := app.scriptOptions(nil)
+	assert.Zero(t, defaults.Timeout)
+	assert.Zero(t, defaults.MemoryLimit)
+	assert.Empty(t, defaults.AllowedHosts)
+}
+
+func TestProcessFlowResponse_ScriptStepChoosesNextStep(t *testing.T) {
+	app := newProcessorTestApp(t)
+	org, account := createProcessorTestOrg(t, app)
+
+	flowID := uuid.New()
+	flow := &models.ChatbotFlow{
+		BaseModel:       models.BaseModel{ID: flowID},
+		OrganizationID:  org.ID,
+		WhatsAppAccount: account.Name,
+		Name:            "Script Flow",
+		IsEnabled:       true,
+		Steps: []models.ChatbotFlowStep{
+			{
+				BaseModel:   models.BaseModel{ID: uuid.New()},
+				FlowID:      flowID,
+				StepName:    "ask_orders",
+				StepOrder:   1,
+				Message:     "How many orders have you placed?",
+				MessageType: models.FlowStepTypeText,
+				InputType:   models.InputTypeNumber,
+				StoreAs:     "orders",
+				NextStep:    "classify",
+			},
+			{
+				BaseModel:   models.BaseModel{ID: uuid.New()},
+				FlowID:      flowID,
+				StepName:    "classify",
+				StepOrder:   2,
+				MessageType: models.FlowStepTypeScript,
+				InputType:   models.InputTypeNone,
+				ScriptConfig: models.JSONB{
+					"code": `session.tier = Number(session.orders) > 10 ? "gold" : "standard";
+						return {next_step: session.tier};`,
+				},
+			},
+			{
+				BaseModel:   models.BaseModel{ID: uuid.New()},
+				FlowID:      flowID,
+				StepName:    "standard",
+				StepOrder:   3,
+				Message:     "Welcome!",
+				MessageType: models.FlowStepTypeText,
+				InputType:   models.InputTypeText,
+			},
+			{
+				BaseModel:   models.BaseModel{ID: uuid.New()},
+				FlowID:      flowID,
+				StepName:    "gold",
+				StepOrder:   4,
+				Message:     "Welcome back, {{tier}} member!",
+				MessageType: models.FlowStepTypeText,
+				InputType:   models.InputTypeText,
+			},
+		},
+	}
+	require.NoError(t, app.DB.Create(flow).Error)
+
+	for _, tc := range []struct {
+		orders string
+		tier   string
+	}{
+		{"12", "gold"},
+		{"3", "standard"},
+	} {
+		t.Run(tc.tier, func(t *testing.T) {
+			contact := testutil.CreateTestContact(t, app.DB, org.ID)
+			session := &models.ChatbotSession{
+				BaseModel:       models.BaseModel{ID: uuid.New()},
+				OrganizationID:  org.ID,
+				ContactID:       contact.ID,
+				WhatsAppAccount: account.Name,
+				PhoneNumber:     contact.PhoneNumber,
+				Status:          models.SessionStatusActive,
+				SessionData:     models.JSONB{},
+				StartedAt:       time.Now(),
+				LastActivityAt:  time.Now(),
+			}
+			require.NoError(t, app.DB.Create(session).Error)
+
+			app.startFlow(account, session, contact, flow)
+			require.Equal(t, "ask_orders", session.CurrentStep)
+
+			// The script reads the answer stored by the previous step
+			app.processFlowResponse(account, session, contact, tc.orders, "", nil)
+
+			var dbSession models.ChatbotSession
+			require.NoError(t, app.DB.First(&dbSession, session.ID).Error)
+			assert.Equal(t, tc.tier, dbSession.CurrentStep)
+			assert.Equal(t, tc.orders, dbSession.SessionData["orders"])
+			assert.Equal(t, tc.tier, dbSession.SessionData["tier"])
+		})
+	}
+}
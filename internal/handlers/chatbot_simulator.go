@@ -0,0 +1,673 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shridarpatil/whatomate/internal/contactutil"
+	"github.com/shridarpatil/whatomate/internal/models"
+	"github.com/shridarpatil/whatomate/pkg/whatsapp"
+	"github.com/valyala/fasthttp"
+	"github.com/zerodha/fastglue"
+	"gorm.io/gorm"
+)
+
+const (
+	// chatbotSimulationMaxMessages limits the inbound messages in one simulation
+	chatbotSimulationMaxMessages = 50
+	// chatbotFlowMaxTestCases limits the test cases stored with a flow
+	chatbotFlowMaxTestCases = 20
+)
+
+// errSimulationDone rolls back the simulation's transaction once it has finished
+var errSimulationDone = errors.New("simulation finished")
+
+// chatbotSimulation captures the outbound side effects of a simulated
+// conversation. It is also the HTTP transport of the simulator's WhatsApp
+// client, so API calls are recorded and answered locally instead of being sent.
+type chatbotSimulation struct {
+	now      time.Time  // Clock for business hours and keyword schedules; zero uses the real time
+	useDraft bool       // Run flow drafts instead of their published versions
+	flowID   *uuid.UUID // Flow that can be started even when it is disabled
+
+	mu       sync.Mutex
+	seq      int
+	requests []SimulatedRequest
+	webhooks []SimulatedWebhook
+}
+
+// SimulatedContact describes the virtual contact a simulation runs as
+type SimulatedContact struct {
+	Name        string                 `json:"name"`
+	PhoneNumber string                 `json:"phone_number"` // Optional; a random number that matches no real contact is used by default
+	Tags        []string               `json:"tags"`
+	Metadata    map[string]interface{} `json:"metadata"`
+}
+
+// SimulatedInput is a message sent by the virtual contact
+type SimulatedInput struct {
+	Text     string `json:"text"`
+	ButtonID string `json:"button_id,omitempty"` // Set for button and list replies; Text is then the button title
+}
+
+// SimulateChatbotRequest is the request body of the chatbot simulator
+type SimulateChatbotRequest struct {
+	WhatsAppAccount string           `json:"whatsapp_account"` // Defaults to the organization's default incoming account
+	FlowID          *uuid.UUID       `json:"flow_id"`          // Start this flow before sending the messages
+	UseDraft        bool             `json:"use_draft"`        // Run flow drafts instead of published versions
+	Time            *time.Time       `json:"time"`             // Simulated time for business hours and keyword schedules
+	Contact         SimulatedContact `json:"contact"`
+	Messages        []SimulatedInput `json:"messages"`
+}
+
+// SimulatedMessage is a message the chatbot would have sent
+type SimulatedMessage struct {
+	Type            models.MessageType `json:"type"`
+	Content         string             `json:"content"`
+	InteractiveData models.JSONB       `json:"interactive_data,omitempty"`
+	TemplateName    string             `json:"template_name,omitempty"`
+	MediaURL        string             `json:"media_url,omitempty"`
+	Error           string             `json:"error,omitempty"`
+}
+
+// SimulatedRequest is a WhatsApp Cloud API call captured by the simulator
+type SimulatedRequest struct {
+	Method  string      `json:"method"`
+	Path    string      `json:"path"`
+	Payload interface{} `json:"payload,omitempty"`
+}
+
+// SimulatedWebhook is a webhook the simulator captured instead of delivering
+type SimulatedWebhook struct {
+	Event string      `json:"event"`
+	Data  interface{} `json:"data"`
+}
+
+// SimulatedTurn is the outcome of one inbound message, or of starting the flow
+type SimulatedTurn struct {
+	Input            *SimulatedInput      `json:"input,omitempty"` // Not set for the flow start
+	Messages         []SimulatedMessage   `json:"messages"`
+	WhatsAppRequests []SimulatedRequest   `json:"whatsapp_requests"`
+	Webhooks         []SimulatedWebhook   `json:"webhooks"`
+	FlowID           *uuid.UUID           `json:"flow_id,omitempty"`
+	CurrentStep      string               `json:"current_step"`
+	SessionStatus    models.SessionStatus `json:"session_status,omitempty"`
+	SessionData      models.JSONB         `json:"session_data"`
+	Transferred      bool                 `json:"transferred"`
+}
+
+// SimulateChatbotResponse is the result of a chatbot simulation
+type SimulateChatbotResponse struct {
+	Turns []SimulatedTurn `json:"turns"`
+}
+
+// ChatbotFlowTestCase is a scripted conversation stored with a flow and
+// replayed against its draft by the simulator
+type ChatbotFlowTestCase struct {
+	Name    string                `json:"name"`
+	Contact SimulatedContact      `json:"contact"`
+	Start   ChatbotFlowTestExpect `json:"start"` // Checked after the flow starts
+	Turns   []ChatbotFlowTestTurn `json:"turns"`
+}
+
+// ChatbotFlowTestTurn is a message in a flow test case and what should happen after it
+type ChatbotFlowTestTurn struct {
+	SimulatedInput
+	Expect ChatbotFlowTestExpect `json:"expect"`
+}
+
+// ChatbotFlowTestExpect lists the checks of a test case turn. Empty checks are skipped.
+type ChatbotFlowTestExpect struct {
+	ReplyContains string                 `json:"reply_contains,omitempty"` // Some message sent in the turn contains this text
+	Step          *string                `json:"step,omitempty"`           // Current step; "" once the flow has ended
+	Status        models.SessionStatus   `json:"status,omitempty"`
+	Transferred   *bool                  `json:"transferred,omitempty"`
+	SessionData   map[string]interface{} `json:"session_data,omitempty"` // Session variables and their expected values
+}
+
+// ChatbotFlowTestResult is the outcome of running a flow test case
+type ChatbotFlowTestResult struct {
+	Name     string          `json:"name"`
+	Passed   bool            `json:"passed"`
+	Failures []string        `json:"failures"`
+	Turns    []SimulatedTurn `json:"turns"`
+}
+
+// RoundTrip records a WhatsApp API call and answers it with a fake message or media ID
+func (s *chatbotSimulation) RoundTrip(req *http.Request) (*http.Response, error) {
+	var payload interface{}
+	if req.Body != nil {
+		body, err := io.ReadAll(req.Body)
+		_ = req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		// Media uploads are multipart and are recorded without their payload
+		if json.Unmarshal(body, &payload) != nil {
+			payload = nil
+		}
+	}
+
+	s.mu.Lock()
+	s.seq++
+	id := fmt.Sprintf("sim.%d", s.seq)
+	s.requests = append(s.requests, SimulatedRequest{Method: req.Method, Path: req.URL.Path, Payload: payload})
+	s.mu.Unlock()
+
+	body, err := json.Marshal(map[string]interface{}{
+		"id":       id,
+		"messages": []map[string]string{{"id": "wamid." + id}},
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &http.Response{
+		StatusCode: http.StatusOK,
+		Header:     http.Header{"Content-Type": []string{"application/json"}},
+		Body:       io.NopCloser(bytes.NewReader(body)),
+		Request:    req,
+	}, nil
+}
+
+// captureWebhook records a webhook the simulated conversation would have sent
+func (s *chatbotSimulation) captureWebhook(event string, data interface{}) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.webhooks = append(s.webhooks, SimulatedWebhook{Event: event, Data: data})
+}
+
+// drain returns and clears the captured API calls and webhooks
+func (s *chatbotSimulation) drain() ([]SimulatedRequest, []SimulatedWebhook) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	requests, webhooks := s.requests, s.webhooks
+	s.requests, s.webhooks = nil, nil
+	if requests == nil {
+		requests = []SimulatedRequest{}
+	}
+	if webhooks == nil {
+		webhooks = []SimulatedWebhook{}
+	}
+	return requests, webhooks
+}
+
+// now returns the current time, or the simulated time inside the chatbot simulator
+func (a *App) now() time.Time {
+	if a.simulation != nil && !a.simulation.now.IsZero() {
+		return a.simulation.now
+	}
+	return time.Now()
+}
+
+// newSimulationApp returns a sandbox App that writes to tx and captures
+// WhatsApp calls and webhooks. WebSocket broadcasts are disabled.
+func (a *App) newSimulationApp(tx *gorm.DB, sim *chatbotSimulation) *App {
+	wa := whatsapp.New(a.Log)
+	wa.HTTPClient = &http.Client{Transport: sim}
+	return &App{
+		Config:     a.Config,
+		DB:         tx,
+		Redis:      a.Redis,
+		Log:        a.Log,
+		WhatsApp:   wa,
+		HTTPClient: a.HTTPClient,
+		Storage:    a.Storage,
+		simulation: sim,
+	}
+}
+
+// getSimulationFlows loads flows for the simulator. Flows are read from the
+// simulation's transaction rather than the cache, the flow under test is
+// included even when disabled, and drafts are used if requested.
+func (a *App) getSimulationFlows(orgID uuid.UUID) ([]models.ChatbotFlow, error) {
+	query := a.DB.Where("organization_id = ? AND is_enabled = true", orgID)
+	if a.simulation.flowID != nil {
+		query = a.DB.Where("organization_id = ? AND (is_enabled = true OR id = ?)", orgID, *a.simulation.flowID)
+	}
+
+	var flows []models.ChatbotFlow
+	if err := query.Preload("Steps", func(db *gorm.DB) *gorm.DB {
+		return db.Order("step_order ASC")
+	}).Find(&flows).Error; err != nil {
+		return nil, err
+	}
+	if a.simulation.useDraft {
+		return flows, nil
+	}
+	return a.applyPublishedFlowVersions(orgID, flows)
+}
+
+// findSimulationAccount returns the named WhatsApp account, or the organization's
+// default incoming account when name is empty
+func (a *App) findSimulationAccount(orgID uuid.UUID, name string) (*models.WhatsAppAccount, error) {
+	var account models.WhatsAppAccount
+	query := a.DB.Where("organization_id = ?", orgID)
+	if name != "" {
+		query = query.Where("name = ?", name)
+	} else {
+		query = query.Order("is_default_incoming DESC, created_at ASC")
+	}
+	if err := query.First(&account).Error; err != nil {
+		return nil, err
+	}
+	return &account, nil
+}
+
+// validateSimulationInputs checks the messages of a simulation
+func validateSimulationInputs(inputs []SimulatedInput) error {
+	if len(inputs) > chatbotSimulationMaxMessages {
+		return fmt.Errorf("at most %d messages can be simulated", chatbotSimulationMaxMessages)
+	}
+	for i, input := range inputs {
+		if strings.TrimSpace(input.Text) == "" && input.ButtonID == "" {
+			return fmt.Errorf("message %d: text or button_id is required", i+1)
+		}
+	}
+	return nil
+}
+
+// simulatedIncomingMessage builds the webhook message the virtual contact would send
+func simulatedIncomingMessage(from string, n int, input SimulatedInput) (IncomingTextMessage, error) {
+	raw := map[string]interface{}{
+		"from":      from,
+		"id":        fmt.Sprintf("wamid.sim-in-%s-%d", uuid.New().String()[:8], n),
+		"timestamp": fmt.Sprintf("%d", time.Now().Unix()),
+		"type":      "text",
+		"text":      map[string]string{"body": input.Text},
+	}
+	if input.ButtonID != "" {
+		title := input.Text
+		if title == "" {
+			title = input.ButtonID
+		}
+		delete(raw, "text")
+		raw["type"] = "interactive"
+		raw["interactive"] = map[string]interface{}{
+			"type":         "button_reply",
+			"button_reply": map[string]string{"id": input.ButtonID, "title": title},
+		}
+	}
+
+	var msg IncomingTextMessage
+	data, err := json.Marshal(raw)
+	if err != nil {
+		return msg, err
+	}
+	err = json.Unmarshal(data, &msg)
+	return msg, err
+}
+
+// runChatbotSimulation runs a conversation through the chatbot against a
+// virtual contact inside a transaction that is rolled back afterwards, so
+// nothing it does is persisted. WhatsApp calls and webhooks are captured.
+// Flow API steps, scripts and AI providers are called as usual.
+func (a *App) runChatbotSimulation(account *models.WhatsAppAccount, req SimulateChatbotRequest) (*SimulateChatbotResponse, error) {
+	sim := &chatbotSimulation{useDraft: req.UseDraft, flowID: req.FlowID}
+	if req.Time != nil {
+		sim.now = req.Time.In(time.Local)
+	}
+
+	result := &SimulateChatbotResponse{Turns: []SimulatedTurn{}}
+	err := a.DB.Transaction(func(tx *gorm.DB) error {
+		sa := a.newSimulationApp(tx, sim)
+		defer sa.wg.Wait()
+
+		contact, err := sa.createSimulationContact(account.OrganizationID, req.Contact)
+		if err != nil {
+			return fmt.Errorf("failed to create simulated contact: %w", err)
+		}
+		var seen int64
+		tx.Model(&models.Message{}).Where("contact_id = ? AND direction = ?", contact.ID, models.DirectionOutgoing).Count(&seen)
+
+		if req.FlowID != nil {
+			flow, err := sa.getChatbotFlowByIDCached(account.OrganizationID, *req.FlowID)
+			if err != nil {
+				return fmt.Errorf("failed to load flow: %w", err)
+			}
+			timeoutMins := 30
+			if settings, err := sa.getChatbotSettingsCached(account.OrganizationID, account.Name); err == nil && settings.SessionTimeoutMins > 0 {
+				timeoutMins = settings.SessionTimeoutMins
+			}
+			session, _ := sa.getOrCreateSession(account.OrganizationID, contact.ID, account.Name, contact.PhoneNumber, timeoutMins)
+			sa.startFlow(account, session, contact, flow)
+			result.Turns = append(result.Turns, sa.simulationTurn(nil, contact, &seen))
+		}
+
+		for i := range req.Messages {
+			input := req.Messages[i]
+			msg, err := simulatedIncomingMessage(contact.PhoneNumber, i, input)
+			if err != nil {
+				return err
+			}
+			sa.processIncomingMessageFull(account.PhoneID, msg, contact.ProfileName)
+			sa.wg.Wait()
+			result.Turns = append(result.Turns, sa.simulationTurn(&input, contact, &seen))
+		}
+		return errSimulationDone
+	})
+	if !errors.Is(err, errSimulationDone) {
+		return nil, err
+	}
+	return result, nil
+}
+
+// createSimulationContact creates the virtual contact of a simulation
+func (a *App) createSimulationContact(orgID uuid.UUID, sc SimulatedContact) (*models.Contact, error) {
+	phone := strings.TrimPrefix(strings.TrimSpace(sc.PhoneNumber), "+")
+	if phone == "" {
+		// "000" is not a country code, so this never matches a real contact
+		phone = fmt.Sprintf("000%09d", rand.IntN(1_000_000_000))
+	}
+	name := sc.Name
+	if name == "" {
+		name = "Simulator"
+	}
+
+	contact, _, err := contactutil.GetOrCreateContact(a.DB, orgID, phone, name)
+	if err != nil {
+		return nil, err
+	}
+	updates := map[string]interface{}{}
+	if len(sc.Tags) > 0 {
+		tags := make(models.JSONBArray, len(sc.Tags))
+		for i, tag := range sc.Tags {
+			tags[i] = tag
+		}
+		contact.Tags = tags
+		updates["tags"] = tags
+	}
+	if len(sc.Metadata) > 0 {
+		contact.Metadata = models.JSONB(sc.Metadata)
+		updates["metadata"] = contact.Metadata
+	}
+	if len(updates) > 0 {
+		if err := a.DB.Model(contact).Updates(updates).Error; err != nil {
+			return nil, err
+		}
+	}
+	return contact, nil
+}
+
+// simulationTurn collects what happened since the previous turn. seen is the
+// number of outgoing messages already reported.
+func (a *App) simulationTurn(input *SimulatedInput, contact *models.Contact, seen *int64) SimulatedTurn {
+	turn := SimulatedTurn{Input: input, Messages: []SimulatedMessage{}, SessionData: models.JSONB{}}
+	turn.WhatsAppRequests, turn.Webhooks = a.simulation.drain()
+
+	var messages []models.Message
+	a.DB.Where("contact_id = ? AND direction = ?", contact.ID, models.DirectionOutgoing).
+		Order("created_at ASC").Offset(int(*seen)).Find(&messages)
+	*seen += int64(len(messages))
+	for _, msg := range messages {
+		turn.Messages = append(turn.Messages, SimulatedMessage{
+			Type:            msg.MessageType,
+			Content:         msg.Content,
+			InteractiveData: msg.InteractiveData,
+			TemplateName:    msg.TemplateName,
+			MediaURL:        msg.MediaURL,
+			Error:           msg.ErrorMessage,
+		})
+	}
+
+	var session models.ChatbotSession
+	if err := a.DB.Where("organization_id = ? AND contact_id = ?", contact.OrganizationID, contact.ID).
+		Order("created_at DESC").First(&session).Error; err == nil {
+		turn.FlowID = session.CurrentFlowID
+		turn.CurrentStep = session.CurrentStep
+		turn.SessionStatus = session.Status
+		if session.SessionData != nil {
+			turn.SessionData = session.SessionData
+		}
+	}
+	turn.Transferred = a.hasActiveAgentTransfer(contact.OrganizationID, contact.ID)
+	return turn
+}
+
+// check returns the expectations the turn does not meet
+func (e ChatbotFlowTestExpect) check(turn SimulatedTurn) []string {
+	var failures []string
+	if e.ReplyContains != "" {
+		found := false
+		for _, msg := range turn.Messages {
+			if strings.Contains(strings.ToLower(msg.Content), strings.ToLower(e.ReplyContains)) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			failures = append(failures, fmt.Sprintf("no reply contains %q", e.ReplyContains))
+		}
+	}
+	if e.Step != nil && turn.CurrentStep != *e.Step {
+		failures = append(failures, fmt.Sprintf("current step is %q, expected %q", turn.CurrentStep, *e.Step))
+	}
+	if e.Status != "" && turn.SessionStatus != e.Status {
+		failures = append(failures, fmt.Sprintf("session status is %q, expected %q", turn.SessionStatus, e.Status))
+	}
+	if e.Transferred != nil && turn.Transferred != *e.Transferred {
+		failures = append(failures, fmt.Sprintf("transferred is %t, expected %t", turn.Transferred, *e.Transferred))
+	}
+	for key, want := range e.SessionData {
+		got, ok := turn.SessionData[key]
+		if !ok {
+			failures = append(failures, fmt.Sprintf("session variable %q is not set", key))
+			continue
+		}
+		// Compare formatted values so that e.g. 2 and 2.0 are equal
+		if fmt.Sprintf("%v", got) != fmt.Sprintf("%v", want) {
+			failures = append(failures, fmt.Sprintf("session variable %q is %v, expected %v", key, got, want))
+		}
+	}
+	return failures
+}
+
+// parseFlowTestCases reads the test cases stored with a flow
+func parseFlowTestCases(stored models.JSONBArray) ([]ChatbotFlowTestCase, error) {
+	cases := []ChatbotFlowTestCase{}
+	if len(stored) == 0 {
+		return cases, nil
+	}
+	data, err := json.Marshal(stored)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &cases); err != nil {
+		return nil, fmt.Errorf("invalid test cases: %w", err)
+	}
+	return cases, nil
+}
+
+// flowTestCasesToJSONB validates test cases and converts them for storage
+func flowTestCasesToJSONB(cases []ChatbotFlowTestCase) (models.JSONBArray, error) {
+	if len(cases) > chatbotFlowMaxTestCases {
+		return nil, fmt.Errorf("a flow can have at most %d test cases", chatbotFlowMaxTestCases)
+	}
+	stored := models.JSONBArray{}
+	for i, tc := range cases {
+		if strings.TrimSpace(tc.Name) == "" {
+			return nil, fmt.Errorf("test case %d: name is required", i+1)
+		}
+		inputs := make([]SimulatedInput, len(tc.Turns))
+		for j, turn := range tc.Turns {
+			inputs[j] = turn.SimulatedInput
+		}
+		if err := validateSimulationInputs(inputs); err != nil {
+			return nil, fmt.Errorf("test case %q: %w", tc.Name, err)
+		}
+
+		data, err := json.Marshal(tc)
+		if err != nil {
+			return nil, err
+		}
+		var value map[string]interface{}
+		if err := json.Unmarshal(data, &value); err != nil {
+			return nil, err
+		}
+		stored = append(stored, value)
+	}
+	return stored, nil
+}
+
+// runFlowTests replays test cases against the flow's draft
+func (a *App) runFlowTests(flow *models.ChatbotFlow, cases []ChatbotFlowTestCase) []ChatbotFlowTestResult {
+	results := make([]ChatbotFlowTestResult, 0, len(cases))
+	account, accountErr := a.findSimulationAccount(flow.OrganizationID, flow.WhatsAppAccount)
+
+	for _, tc := range cases {
+		result := ChatbotFlowTestResult{Name: tc.Name, Failures: []string{}, Turns: []SimulatedTurn{}}
+		if accountErr != nil {
+			result.Failures = append(result.Failures, "no WhatsApp account to simulate with")
+			results = append(results, result)
+			continue
+		}
+
+		req := SimulateChatbotRequest{FlowID: &flow.ID, UseDraft: true, Contact: tc.Contact}
+		for _, turn := range tc.Turns {
+			req.Messages = append(req.Messages, turn.SimulatedInput)
+		}
+		sim, err := a.runChatbotSimulation(account, req)
+		if err != nil {
+			a.Log.Error("Flow test simulation failed", "error", err, "flow_id", flow.ID, "test", tc.Name)
+			result.Failures = append(result.Failures, "simulation failed: "+err.Error())
+			results = append(results, result)
+			continue
+		}
+
+		result.Turns = sim.Turns
+		if len(sim.Turns) > 0 {
+			for _, failure := range tc.Start.check(sim.Turns[0]) {
+				result.Failures = append(result.Failures, "start: "+failure)
+			}
+		}
+		for i, turn := range tc.Turns {
+			if i+1 >= len(sim.Turns) {
+				break
+			}
+			for _, failure := range turn.Expect.check(sim.Turns[i+1]) {
+				result.Failures = append(result.Failures, fmt.Sprintf("turn %d: %s", i+1, failure))
+			}
+		}
+		result.Passed = len(result.Failures) == 0
+		results = append(results, result)
+	}
+	return results
+}
+
+// flowTestsPassed reports whether all test results passed
+func flowTestsPassed(results []ChatbotFlowTestResult) bool {
+	for _, r := range results {
+		if !r.Passed {
+			return false
+		}
+	}
+	return true
+}
+
+// addFlowTestResults runs a saved flow's test cases, if it has any, and adds
+// the results to resp so regressions show up when the flow is saved
+func (a *App) addFlowTestResults(resp map[string]interface{}, flowID uuid.UUID, cases []ChatbotFlowTestCase) {
+	if len(cases) == 0 {
+		return
+	}
+	var flow models.ChatbotFlow
+	if err := a.DB.Where("id = ?", flowID).First(&flow).Error; err != nil {
+		return
+	}
+	results := a.runFlowTests(&flow, cases)
+	resp["tests_passed"] = flowTestsPassed(results)
+	resp["test_results"] = results
+}
+
+// SimulateChatbot runs messages from a virtual contact through the chatbot and
+// returns what it would have sent, without sending anything or saving any data
+func (a *App) SimulateChatbot(r *fastglue.Request) error {
+	orgID, userID, err := a.getOrgAndUserID(r)
+	if err != nil {
+		return r.SendErrorEnvelope(fasthttp.StatusUnauthorized, "Unauthorized", nil, "")
+	}
+	if err := a.requirePermission(r, userID, models.ResourceFlowsChatbot, models.ActionWrite); err != nil {
+		return nil
+	}
+
+	var req SimulateChatbotRequest
+	if err := a.decodeRequest(r, &req); err != nil {
+		return nil
+	}
+	if len(req.Messages) == 0 && req.FlowID == nil {
+		return r.SendErrorEnvelope(fasthttp.StatusBadRequest, "messages or flow_id is required", nil, "")
+	}
+	if err := validateSimulationInputs(req.Messages); err != nil {
+		return r.SendErrorEnvelope(fasthttp.StatusBadRequest, err.Error(), nil, "")
+	}
+
+	account, err := a.findSimulationAccount(orgID, req.WhatsAppAccount)
+	if err != nil {
+		return r.SendErrorEnvelope(fasthttp.StatusBadRequest, "WhatsApp account not found", nil, "")
+	}
+	if req.FlowID != nil {
+		if _, err := findByIDAndOrg[models.ChatbotFlow](a.DB, r, *req.FlowID, orgID, "Flow"); err != nil {
+			return nil
+		}
+	}
+
+	result, err := a.runChatbotSimulation(account, req)
+	if err != nil {
+		a.Log.Error("Chatbot simulation failed", "error", err)
+		return r.SendErrorEnvelope(fasthttp.StatusInternalServerError, "Simulation failed", nil, "")
+	}
+	return r.SendEnvelope(result)
+}
+
+// RunChatbotFlowTests replays a flow's test cases against its draft. Test
+// cases in the request body are run instead of the stored ones.
+func (a *App) RunChatbotFlowTests(r *fastglue.Request) error {
+	orgID, userID, err := a.getOrgAndUserID(r)
+	if err != nil {
+		return r.SendErrorEnvelope(fasthttp.StatusUnauthorized, "Unauthorized", nil, "")
+	}
+	if err := a.requirePermission(r, userID, models.ResourceFlowsChatbot, models.ActionWrite); err != nil {
+		return nil
+	}
+
+	id, err := parsePathUUID(r, "id", "flow")
+	if err != nil {
+		return nil
+	}
+	flow, err := findByIDAndOrg[models.ChatbotFlow](a.DB, r, id, orgID, "Flow")
+	if err != nil {
+		return nil
+	}
+
+	var req struct {
+		TestCases []ChatbotFlowTestCase `json:"test_cases"`
+	}
+	if len(r.RequestCtx.PostBody()) > 0 {
+		if err := a.decodeRequest(r, &req); err != nil {
+			return nil
+		}
+	}
+
+	cases := req.TestCases
+	if len(cases) > 0 {
+		if _, err := flowTestCasesToJSONB(cases); err != nil {
+			return r.SendErrorEnvelope(fasthttp.StatusBadRequest, err.Error(), nil, "")
+		}
+	} else if cases, err = parseFlowTestCases(flow.TestCases); err != nil {
+		return r.SendErrorEnvelope(fasthttp.StatusBadRequest, err.Error(), nil, "")
+	}
+
+	results := a.runFlowTests(flow, cases)
+	return r.SendEnvelope(map[string]interface{}{
+		"passed":  flowTestsPassed(results),
+		"results": results,
+	})
+}
@@ -0,0 +1,151 @@
+package handlers
+
+import (
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/shridarpatil/whatomate/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestChatbotSimulation_CapturesWhatsAppRequests(t *testing.T) {
+	sim := &chatbotSimulation{}
+	client := &http.Client{Transport: sim}
+
+	resp, err := client.Post("https://graph.facebook.com/v21.0/123/messages", "application/json",
+		strings.NewReader(`{"to":"15550001","type":"text","text":{"body":"Hi"}}`))
+	require.NoError(t, err)
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Contains(t, string(body), `"wamid.sim.1"`)
+
+	requests, webhooks := sim.drain()
+	require.Len(t, requests, 1)
+	assert.Equal(t, "/v21.0/123/messages", requests[0].Path)
+	assert.Equal(t, "Hi", requests[0].Payload.(map[string]interface{})["text"].(map[string]interface{})["body"])
+	assert.Empty(t, webhooks)
+
+	requests, _ = sim.drain()
+	assert.Empty(t, requests, "drain clears captured requests")
+}
+
+func TestSimulatedIncomingMessage(t *testing.T) {
+	msg, err := simulatedIncomingMessage("15550001", 0, SimulatedInput{Text: "hello"})
+	require.NoError(t, err)
+	assert.Equal(t, "text", msg.Type)
+	require.NotNil(t, msg.Text)
+	assert.Equal(t, "hello", msg.Text.Body)
+
+	msg, err = simulatedIncomingMessage("15550001", 1, SimulatedInput{Text: "Yes", ButtonID: "btn_yes"})
+	require.NoError(t, err)
+	assert.Equal(t, "interactive", msg.Type)
+	require.NotNil(t, msg.Interactive)
+	require.NotNil(t, msg.Interactive.ButtonReply)
+	assert.Equal(t, "btn_yes", msg.Interactive.ButtonReply.ID)
+	assert.Equal(t, "Yes", msg.Interactive.ButtonReply.Title)
+}
+
+func TestChatbotFlowTestExpect_Check(t *testing.T) {
+	step := "confirm"
+	transferred := false
+	expect := ChatbotFlowTestExpect{
+		ReplyContains: "order number",
+		Step:          &step,
+		Transferred:   &transferred,
+		SessionData:   map[string]interface{}{"qty": 2, "name": "Alice"},
+	}
+
+	turn := SimulatedTurn{
+		Messages:    []SimulatedMessage{{Content: "What is your Order Number?"}},
+		CurrentStep: "confirm",
+		SessionData: models.JSONB{"qty": float64(2), "name": "Alice"},
+	}
+	assert.Empty(t, expect.check(turn))
+
+	turn.Messages = nil
+	turn.CurrentStep = "ask"
+	turn.SessionData = models.JSONB{"qty": float64(3)}
+	turn.Transferred = true
+	assert.Len(t, expect.check(turn), 5)
+}
+
+func TestFlowTestCasesToJSONB(t *testing.T) {
+	stored, err := flowTestCasesToJSONB([]ChatbotFlowTestCase{{
+		Name:  "happy path",
+		Turns: []ChatbotFlowTestTurn{{SimulatedInput: SimulatedInput{Text: "Alice"}}},
+	}})
+	require.NoError(t, err)
+	cases, err := parseFlowTestCases(stored)
+	require.NoError(t, err)
+	require.Len(t, cases, 1)
+	assert.Equal(t, "Alice", cases[0].Turns[0].Text)
+
+	_, err = flowTestCasesToJSONB([]ChatbotFlowTestCase{{Name: " "}})
+	assert.ErrorContains(t, err, "name is required")
+
+	_, err = flowTestCasesToJSONB([]ChatbotFlowTestCase{{Name: "empty turn", Turns: []ChatbotFlowTestTurn{{}}}})
+	assert.ErrorContains(t, err, "text or button_id is required")
+}
+
+func TestRunFlowTests_SimulatesDraftWithoutSideEffects(t *testing.T) {
+	app := newProcessorTestApp(t)
+	org, account := createProcessorTestOrg(t, app)
+	require.NoError(t, app.DB.Create(&models.ChatbotSettings{
+		BaseModel:          models.BaseModel{ID: uuid.New()},
+		OrganizationID:     org.ID,
+		IsEnabled:          true,
+		SessionTimeoutMins: 30,
+	}).Error)
+
+	flowID := uuid.New()
+	flow := &models.ChatbotFlow{
+		BaseModel:         models.BaseModel{ID: flowID},
+		OrganizationID:    org.ID,
+		WhatsAppAccount:   account.Name,
+		Name:              "Simulated Flow",
+		CompletionMessage: "Bye {{name}}",
+		Steps: []models.ChatbotFlowStep{
+			{BaseModel: models.BaseModel{ID: uuid.New()}, FlowID: flowID, StepName: "ask_name", StepOrder: 1, Message: "What is your name?", MessageType: models.FlowStepTypeText, InputType: models.InputTypeText, StoreAs: "name", NextStep: "confirm"},
+			{BaseModel: models.BaseModel{ID: uuid.New()}, FlowID: flowID, StepName: "confirm", StepOrder: 2, Message: "Thanks {{name}}, anything else?", MessageType: models.FlowStepTypeText, InputType: models.InputTypeText},
+		},
+	}
+	require.NoError(t, app.DB.Create(flow).Error)
+	// Disabled flows can still be tested
+	require.NoError(t, app.DB.Model(flow).Update("is_enabled", false).Error)
+
+	confirm := "confirm"
+	results := app.runFlowTests(flow, []ChatbotFlowTestCase{
+		{
+			Name:  "collects the name",
+			Start: ChatbotFlowTestExpect{ReplyContains: "What is your name"},
+			Turns: []ChatbotFlowTestTurn{
+				{
+					SimulatedInput: SimulatedInput{Text: "Alice"},
+					Expect:         ChatbotFlowTestExpect{Step: &confirm, ReplyContains: "Thanks Alice", SessionData: map[string]interface{}{"name": "Alice"}},
+				},
+			},
+		},
+		{
+			Name:  "wrong expectation",
+			Turns: []ChatbotFlowTestTurn{{SimulatedInput: SimulatedInput{Text: "Bob"}, Expect: ChatbotFlowTestExpect{ReplyContains: "Thanks Alice"}}},
+		},
+	})
+	require.Len(t, results, 2)
+	assert.True(t, results[0].Passed, "failures: %v", results[0].Failures)
+	require.Len(t, results[0].Turns, 2)
+	assert.NotEmpty(t, results[0].Turns[0].WhatsAppRequests, "sends are captured")
+	assert.False(t, results[1].Passed)
+	assert.Equal(t, []string{`turn 1: no reply contains "Thanks Alice"`}, results[1].Failures)
+
+	// Nothing the simulation did was saved
+	var count int64
+	app.DB.Model(&models.Message{}).Where("organization_id = ?", org.ID).Count(&count)
+	assert.Zero(t, count)
+	app.DB.Model(&models.ChatbotSession{}).Where("organization_id = ?", org.ID).Count(&count)
+	assert.Zero(t, count)
+}
@@ -195,6 +195,71 @@ func TestApp_UpdateChatbotSettings(t *testing.T) {
 		assert.True(t, getResp.Data.Settings.SLAEnabled)
 		assert.Equal(t, 10, getResp.Data.Settings.SLAResponseMinutes)
 	})
+
+	t.Run("AI base URL, headers and fallbacks", func(t *testing.T) {
+		app := newTestApp(t)
+		org := testutil.CreateTestOrganization(t, app.DB)
+		user := testutil.CreateTestUser(t, app.DB, org.ID)
+
+		update := func(body map[string]any) int {
+			req := testutil.NewJSONRequest(t, body)
+			testutil.SetAuthContext(req, org.ID, user.ID)
+			require.NoError(t, app.UpdateChatbotSettings(req))
+			return testutil.GetResponseStatusCode(req)
+		}
+
+		status := update(map[string]any{
+			"ai_enabled":  true,
+			"ai_provider": "openai_compatible",
+			"ai_model":    "llama3",
+			"ai_base_url": "http://ollama:11434/v1",
+			"ai_headers":  map[string]string{"X-Tenant": "acme"},
+			"ai_fallbacks": []map[string]any{
+				{"provider": "openai", "model": "gpt-4o-mini", "api_key": "sk-fallback"},
+			},
+		})
+		assert.Equal(t, fasthttp.StatusOK, status)
+
+		// Editing the fallbacks without their keys keeps the stored keys
+		status = update(map[string]any{
+			"ai_fallbacks": []map[string]any{
+				{"provider": "openai", "model": "gpt-4o"},
+			},
+		})
+		assert.Equal(t, fasthttp.StatusOK, status)
+
+		var settings models.ChatbotSettings
+		require.NoError(t, app.DB.Where("organization_id = ?", org.ID).First(&settings).Error)
+		assert.Equal(t, "http://ollama:11434/v1", settings.AI.BaseURL)
+		assert.Equal(t, models.JSONB{"X-Tenant": "acme"}, settings.AI.Headers)
+		require.Len(t, settings.AI.Fallbacks, 1)
+		fallback := settings.AI.Fallbacks[0].(map[string]interface{})
+		assert.Equal(t, "gpt-4o", fallback["model"])
+		assert.Equal(t, "sk-fallback", fallback["api_key"])
+
+		getReq := testutil.NewGETRequest(t)
+		testutil.SetAuthContext(getReq, org.ID, user.ID)
+		require.NoError(t, app.GetChatbotSettings(getReq))
+		body := string(testutil.GetResponseBody(getReq))
+		assert.NotContains(t, body, "sk-fallback", "credentials are never returned")
+		assert.NotContains(t, body, "acme")
+
+		var getResp struct {
+			Data struct {
+				Settings handlers.ChatbotSettingsResponse `json:"settings"`
+			} `json:"data"`
+		}
+		require.NoError(t, json.Unmarshal(testutil.GetResponseBody(getReq), &getResp))
+		assert.Equal(t, []string{"X-Tenant"}, getResp.Data.Settings.AIHeaderNames)
+		require.Len(t, getResp.Data.Settings.AIFallbacks, 1)
+		assert.True(t, getResp.Data.Settings.AIFallbacks[0].HasAPIKey)
+
+		// Invalid configurations are rejected
+		assert.Equal(t, fasthttp.StatusBadRequest, update(map[string]any{"ai_base_url": ""}))
+		assert.Equal(t, fasthttp.StatusBadRequest, update(map[string]any{
+			"ai_fallbacks": []map[string]any{{"provider": "anthropic", "model": "claude"}},
+		}))
+	})
 }
 
 // =============================================================================
@@ -816,6 +881,213 @@ func TestApp_DeleteChatbotFlow(t *testing.T) {
 	})
 }
 
+// =============================================================================
+// Flow versions
+// =============================================================================
+
+func TestApp_PublishAndRollbackChatbotFlow(t *testing.T) {
+	t.Parallel()
+
+	app := newTestApp(t)
+	org := testutil.CreateTestOrganization(t, app.DB)
+	perms := getChatbotFlowPermissions(t, app)
+	role := testutil.CreateTestRole(t, app.DB, org.ID, "flow-admin", perms)
+	user := testutil.CreateTestUser(t, app.DB, org.ID,
+		testutil.WithEmail(testutil.UniqueEmail("publish-flow")),
+		testutil.WithRoleID(&role.ID),
+	)
+	flow := createTestChatbotFlow(t, app, org.ID, "Versioned Flow")
+	step := &models.ChatbotFlowStep{
+		BaseModel: models.BaseModel{ID: uuid.New()},
+		FlowID:    flow.ID,
+		StepName:  "ask",
+		StepOrder: 1,
+		Message:   "What is your name?",
+		InputType: models.InputTypeText,
+	}
+	require.NoError(t, app.DB.Create(step).Error)
+
+	publish := func(notes string) handlers.ChatbotFlowVersionResponse {
+		req := testutil.NewJSONRequest(t, map[string]any{"notes": notes})
+		testutil.SetAuthContext(req, org.ID, user.ID)
+		testutil.SetPathParam(req, "id", flow.ID.String())
+		require.NoError(t, app.PublishChatbotFlow(req))
+		require.Equal(t, fasthttp.StatusOK, testutil.GetResponseStatusCode(req))
+
+		var resp struct {
+			Data handlers.ChatbotFlowVersionResponse `json:"data"`
+		}
+		require.NoError(t, json.Unmarshal(testutil.GetResponseBody(req), &resp))
+		return resp.Data
+	}
+
+	v1 := publish("initial")
+	assert.Equal(t, 1, v1.Version)
+	assert.True(t, v1.IsPublished)
+
+	// Edit the draft; the diff against the published version shows the change
+	require.NoError(t, app.DB.Model(step).Update("message", "Your full name?").Error)
+
+	req := testutil.NewGETRequest(t)
+	testutil.SetAuthContext(req, org.ID, user.ID)
+	testutil.SetPathParam(req, "id", flow.ID.String())
+	require.NoError(t, app.DiffChatbotFlowVersions(req))
+	require.Equal(t, fasthttp.StatusOK, testutil.GetResponseStatusCode(req))
+	var diffResp struct {
+		Data handlers.FlowVersionDiff `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(testutil.GetResponseBody(req), &diffResp))
+	assert.Equal(t, "1", diffResp.Data.From)
+	assert.Equal(t, "draft", diffResp.Data.To)
+	require.Len(t, diffResp.Data.StepsChanged, 1)
+	assert.Equal(t, "ask", diffResp.Data.StepsChanged[0].StepName)
+
+	v2 := publish("longer question")
+	assert.Equal(t, 2, v2.Version)
+
+	// Roll back to version 1: a new version is published and the draft is reset
+	req = testutil.NewJSONRequest(t, map[string]any{})
+	testutil.SetAuthContext(req, org.ID, user.ID)
+	testutil.SetPathParam(req, "id", flow.ID.String())
+	testutil.SetPathParam(req, "version", "1")
+	require.NoError(t, app.RollbackChatbotFlow(req))
+	require.Equal(t, fasthttp.StatusOK, testutil.GetResponseStatusCode(req))
+	var rollbackResp struct {
+		Data handlers.ChatbotFlowVersionResponse `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(testutil.GetResponseBody(req), &rollbackResp))
+	assert.Equal(t, 3, rollbackResp.Data.Version)
+	require.NotNil(t, rollbackResp.Data.RolledBackFrom)
+	assert.Equal(t, 1, *rollbackResp.Data.RolledBackFrom)
+
+	var updated models.ChatbotFlow
+	require.NoError(t, app.DB.Preload("Steps").First(&updated, "id = ?", flow.ID).Error)
+	assert.Equal(t, 3, updated.PublishedVersion)
+	require.Len(t, updated.Steps, 1)
+	assert.Equal(t, "What is your name?", updated.Steps[0].Message)
+
+	// Versions are listed newest first
+	req = testutil.NewGETRequest(t)
+	testutil.SetAuthContext(req, org.ID, user.ID)
+	testutil.SetPathParam(req, "id", flow.ID.String())
+	require.NoError(t, app.ListChatbotFlowVersions(req))
+	require.Equal(t, fasthttp.StatusOK, testutil.GetResponseStatusCode(req))
+	var listResp struct {
+		Data struct {
+			Versions []handlers.ChatbotFlowVersionResponse `json:"versions"`
+		} `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(testutil.GetResponseBody(req), &listResp))
+	require.Len(t, listResp.Data.Versions, 3)
+	assert.Equal(t, 3, listResp.Data.Versions[0].Version)
+	assert.True(t, listResp.Data.Versions[0].IsPublished)
+	assert.False(t, listResp.Data.Versions[2].IsPublished)
+}
+
+func TestApp_PublishChatbotFlow_InvalidStepReference(t *testing.T) {
+	t.Parallel()
+
+	app := newTestApp(t)
+	org := testutil.CreateTestOrganization(t, app.DB)
+	perms := getChatbotFlowPermissions(t, app)
+	role := testutil.CreateTestRole(t, app.DB, org.ID, "flow-admin", perms)
+	user := testutil.CreateTestUser(t, app.DB, org.ID,
+		testutil.WithEmail(testutil.UniqueEmail("publish-flow-invalid")),
+		testutil.WithRoleID(&role.ID),
+	)
+	flow := createTestChatbotFlow(t, app, org.ID, "Broken Flow")
+	require.NoError(t, app.DB.Create(&models.ChatbotFlowStep{
+		BaseModel: models.BaseModel{ID: uuid.New()},
+		FlowID:    flow.ID,
+		StepName:  "ask",
+		StepOrder: 1,
+		NextStep:  "missing",
+	}).Error)
+
+	req := testutil.NewJSONRequest(t, map[string]any{})
+	testutil.SetAuthContext(req, org.ID, user.ID)
+	testutil.SetPathParam(req, "id", flow.ID.String())
+	require.NoError(t, app.PublishChatbotFlow(req))
+	assert.Equal(t, fasthttp.StatusBadRequest, testutil.GetResponseStatusCode(req))
+
+	var count int64
+	app.DB.Model(&models.ChatbotFlowVersion{}).Where("flow_id = ?", flow.ID).Count(&count)
+	assert.Zero(t, count)
+}
+
+func TestApp_UpdateChatbotFlow_VersionsUnpublishedFlow(t *testing.T) {
+	t.Parallel()
+
+	app := newTestApp(t)
+	org := testutil.CreateTestOrganization(t, app.DB)
+	perms := getChatbotFlowPermissions(t, app)
+	role := testutil.CreateTestRole(t, app.DB, org.ID, "flow-admin", perms)
+	user := testutil.CreateTestUser(t, app.DB, org.ID,
+		testutil.WithEmail(testutil.UniqueEmail("update-flow-version")),
+		testutil.WithRoleID(&role.ID),
+	)
+	contact := testutil.CreateTestContact(t, app.DB, org.ID)
+	flow := createTestChatbotFlow(t, app, org.ID, "Unpublished Flow")
+	require.NoError(t, app.DB.Create(&models.ChatbotFlowStep{
+		BaseModel: models.BaseModel{ID: uuid.New()},
+		FlowID:    flow.ID,
+		StepName:  "ask",
+		StepOrder: 1,
+		Message:   "What is your name?",
+		InputType: models.InputTypeText,
+	}).Error)
+
+	// A contact is mid-flow on the never-published draft
+	session := &models.ChatbotSession{
+		BaseModel:      models.BaseModel{ID: uuid.New()},
+		OrganizationID: org.ID,
+		ContactID:      contact.ID,
+		PhoneNumber:    contact.PhoneNumber,
+		Status:         models.SessionStatusActive,
+		CurrentFlowID:  &flow.ID,
+		CurrentStep:    "ask",
+		StartedAt:      time.Now(),
+		LastActivityAt: time.Now(),
+	}
+	require.NoError(t, app.DB.Create(session).Error)
+
+	update := func(message string) {
+		req := testutil.NewJSONRequest(t, map[string]any{
+			"steps": []map[string]any{
+				{"step_name": "ask_full_name", "message": message, "input_type": "text"},
+			},
+		})
+		testutil.SetAuthContext(req, org.ID, user.ID)
+		testutil.SetPathParam(req, "id", flow.ID.String())
+		require.NoError(t, app.UpdateChatbotFlow(req))
+		require.Equal(t, fasthttp.StatusOK, testutil.GetResponseStatusCode(req))
+	}
+	update("What is your full name?")
+
+	// The draft that was running is kept as version 1 and the session is pinned to it
+	var updated models.ChatbotFlow
+	require.NoError(t, app.DB.Preload("Steps").First(&updated, "id = ?", flow.ID).Error)
+	assert.Equal(t, 1, updated.PublishedVersion)
+	require.Len(t, updated.Steps, 1)
+	assert.Equal(t, "ask_full_name", updated.Steps[0].StepName)
+
+	var version models.ChatbotFlowVersion
+	require.NoError(t, app.DB.Where("flow_id = ? AND version = ?", flow.ID, 1).First(&version).Error)
+	steps, _ := version.Snapshot["steps"].([]interface{})
+	require.Len(t, steps, 1)
+	assert.Equal(t, "ask", steps[0].(map[string]interface{})["step_name"])
+
+	var pinned models.ChatbotSession
+	require.NoError(t, app.DB.Where("id = ?", session.ID).First(&pinned).Error)
+	assert.Equal(t, 1, pinned.FlowVersion)
+
+	// Later edits only change the draft
+	update("Your full name, please?")
+	var count int64
+	app.DB.Model(&models.ChatbotFlowVersion{}).Where("flow_id = ?", flow.ID).Count(&count)
+	assert.Equal(t, int64(1), count)
+}
+
 // =============================================================================
 // ListAIContexts
 // =============================================================================
@@ -1673,6 +1945,33 @@ func TestApp_CreateChatbotFlow_Additional(t *testing.T) {
 		assert.Equal(t, fasthttp.StatusBadRequest, testutil.GetResponseStatusCode(req))
 	})
 
+	t.Run("validation error invalid script step", func(t *testing.T) {
+		app := newTestApp(t)
+		org := testutil.CreateTestOrganization(t, app.DB)
+		perms := getChatbotFlowPermissions(t, app)
+		role := testutil.CreateTestRole(t, app.DB, org.ID, "flow-admin", perms)
+		user := testutil.CreateTestUser(t, app.DB, org.ID,
+			testutil.WithEmail(testutil.UniqueEmail("create-flow-script")),
+			testutil.WithRoleID(&role.ID),
+		)
+
+		req := testutil.NewJSONRequest(t, map[string]any{
+			"name": "Script Flow",
+			"steps": []map[string]any{
+				{
+					"step_name":     "compute",
+					"message_type":  "script",
+					"script_config": map[string]any{"code": "return ("},
+				},
+			},
+		})
+		testutil.SetAuthContext(req, org.ID, user.ID)
+
+		err := app.CreateChatbotFlow(req)
+		require.NoError(t, err)
+		assert.Equal(t, fasthttp.StatusBadRequest, testutil.GetResponseStatusCode(req))
+	})
+
 	t.Run("create flow without steps", func(t *testing.T) {
 		app := newTestApp(t)
 		org := testutil.CreateTestOrganization(t, app.DB)
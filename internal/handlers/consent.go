@@ -0,0 +1,330 @@
+package handlers
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shridarpatil/whatomate/internal/contactutil"
+	"github.com/shridarpatil/whatomate/internal/models"
+	"github.com/valyala/fasthttp"
+	"github.com/zerodha/fastglue"
+)
+
+// errContactOptedOut is returned when a marketing template is sent to a
+// contact that opted out of marketing messages
+var errContactOptedOut = errors.New("contact has opted out of marketing messages")
+
+// Default consent keywords, matched case-insensitively against the whole message.
+// "Stop promotions" / "Resume promotions" are the texts of Meta's marketing
+// template opt-out buttons. Words like "cancel" or "end" are left out since
+// contacts use them to leave flows.
+var (
+	defaultOptOutKeywords = []string{"STOP", "STOPALL", "UNSUBSCRIBE", "STOP PROMOTIONS"}
+	defaultOptInKeywords  = []string{"START", "SUBSCRIBE", "UNSTOP", "RESUME PROMOTIONS"}
+)
+
+const (
+	defaultOptOutReply = "You have been unsubscribed from marketing messages. Reply START to subscribe again."
+	defaultOptInReply  = "You are subscribed to marketing messages again. Reply STOP to unsubscribe."
+)
+
+// ConsentSettings configures the opt-out and opt-in keywords of an organization
+type ConsentSettings struct {
+	OptOutKeywords []string `json:"opt_out_keywords"`
+	OptInKeywords  []string `json:"opt_in_keywords"`
+	OptOutReply    string   `json:"opt_out_reply"`
+	OptInReply     string   `json:"opt_in_reply"`
+}
+
+// consentSettingsFromOrg reads consent settings from organization settings,
+// falling back to the defaults for unset values
+func consentSettingsFromOrg(settings models.JSONB) ConsentSettings {
+	cs := ConsentSettings{
+		OptOutKeywords: defaultOptOutKeywords,
+		OptInKeywords:  defaultOptInKeywords,
+		OptOutReply:    defaultOptOutReply,
+		OptInReply:     defaultOptInReply,
+	}
+	if settings == nil {
+		return cs
+	}
+	if v, ok := settingsStringSlice(settings["opt_out_keywords"]); ok {
+		cs.OptOutKeywords = v
+	}
+	if v, ok := settingsStringSlice(settings["opt_in_keywords"]); ok {
+		cs.OptInKeywords = v
+	}
+	// An empty reply is valid and disables the confirmation message
+	if v, ok := settings["opt_out_reply"].(string); ok {
+		cs.OptOutReply = v
+	}
+	if v, ok := settings["opt_in_reply"].(string); ok {
+		cs.OptInReply = v
+	}
+	return cs
+}
+
+// settingsStringSlice converts a JSONB array value to a string slice
+func settingsStringSlice(v interface{}) ([]string, bool) {
+	items, ok := v.([]interface{})
+	if !ok {
+		return nil, false
+	}
+	out := make([]string, 0, len(items))
+	for _, item := range items {
+		if s, ok := item.(string); ok {
+			out = append(out, s)
+		}
+	}
+	return out, true
+}
+
+// normalizeConsentKeywords trims keywords and drops empty and duplicate entries
+func normalizeConsentKeywords(keywords []string) []string {
+	out := make([]string, 0, len(keywords))
+	seen := make(map[string]bool, len(keywords))
+	for _, kw := range keywords {
+		kw = strings.TrimSpace(kw)
+		if kw == "" || seen[strings.ToUpper(kw)] {
+			continue
+		}
+		seen[strings.ToUpper(kw)] = true
+		out = append(out, kw)
+	}
+	return out
+}
+
+// matchConsentKeyword returns the keyword the whole message matches, ignoring
+// case, surrounding whitespace and trailing punctuation
+func matchConsentKeyword(text string, keywords []string) (string, bool) {
+	text = strings.TrimRight(strings.TrimSpace(text), ".!")
+	if text == "" {
+		return "", false
+	}
+	for _, kw := range keywords {
+		if strings.EqualFold(text, strings.TrimSpace(kw)) {
+			return kw, true
+		}
+	}
+	return "", false
+}
+
+// getConsentSettings loads the consent settings of an organization
+func (a *App) getConsentSettings(orgID uuid.UUID) ConsentSettings {
+	var org models.Organization
+	if err := a.DB.Select("settings").Where("id = ?", orgID).First(&org).Error; err != nil {
+		return consentSettingsFromOrg(nil)
+	}
+	return consentSettingsFromOrg(org.Settings)
+}
+
+// consentKeywordsApply reports whether the contact's messages are matched
+// against consent keywords. While the contact is in a chatbot flow or has an
+// active agent transfer, their replies are meant for the flow or the agent.
+func (a *App) consentKeywordsApply(account *models.WhatsAppAccount, contact *models.Contact) bool {
+	if a.hasActiveAgentTransfer(account.OrganizationID, contact.ID) {
+		return false
+	}
+
+	query := a.DB.Model(&models.ChatbotSession{}).
+		Where("organization_id = ? AND contact_id = ? AND whats_app_account = ? AND status = ? AND current_flow_id IS NOT NULL",
+			account.OrganizationID, contact.ID, account.Name, models.SessionStatusActive)
+	if settings, err := a.getChatbotSettingsCached(account.OrganizationID, account.Name); err == nil && settings.SessionTimeoutMins > 0 {
+		query = query.Where("last_activity_at > ?", time.Now().Add(-time.Duration(settings.SessionTimeoutMins)*time.Minute))
+	}
+	var inFlow int64
+	query.Count(&inFlow)
+	return inFlow == 0
+}
+
+// handleConsentKeyword updates the contact's marketing consent when an
+// inbound message is an opt-out or opt-in keyword, and confirms the change.
+// Returns true if the message was a consent keyword and needs no further processing.
+func (a *App) handleConsentKeyword(account *models.WhatsAppAccount, contact *models.Contact, text string, message *models.Message) bool {
+	if strings.TrimSpace(text) == "" {
+		return false
+	}
+
+	settings := a.getConsentSettings(account.OrganizationID)
+
+	var status models.ConsentStatus
+	var reply string
+	keyword, ok := matchConsentKeyword(text, settings.OptOutKeywords)
+	if ok {
+		status, reply = models.ConsentStatusOptedOut, settings.OptOutReply
+	} else if keyword, ok = matchConsentKeyword(text, settings.OptInKeywords); ok {
+		status, reply = models.ConsentStatusOptedIn, settings.OptInReply
+	} else {
+		return false
+	}
+
+	change := contactutil.ConsentChange{
+		Status:          status,
+		Source:          models.ConsentSourceKeyword,
+		Keyword:         keyword,
+		WhatsAppAccount: account.Name,
+	}
+	if message != nil {
+		change.MessageID = &message.ID
+	}
+
+	changed, err := contactutil.SetConsent(a.DB, contact, change)
+	if err != nil {
+		a.Log.Error("Failed to update contact consent", "error", err, "contact_id", contact.ID, "keyword", keyword)
+		return true
+	}
+	if changed {
+		a.Log.Info("Contact consent updated", "contact_id", contact.ID, "status", status, "keyword", keyword)
+	}
+
+	if reply != "" {
+		if err := a.sendAndSaveTextMessage(account, contact, reply); err != nil {
+			a.Log.Error("Failed to send consent confirmation", "error", err, "contact_id", contact.ID)
+		}
+	}
+	return true
+}
+
+// ConsentEventResponse represents a consent audit record in API responses
+type ConsentEventResponse struct {
+	ID              uuid.UUID            `json:"id"`
+	Status          models.ConsentStatus `json:"status"`
+	PreviousStatus  models.ConsentStatus `json:"previous_status"`
+	Source          models.ConsentSource `json:"source"`
+	Keyword         string               `json:"keyword,omitempty"`
+	Reason          string               `json:"reason,omitempty"`
+	MessageID       *uuid.UUID           `json:"message_id,omitempty"`
+	UserID          *uuid.UUID           `json:"user_id,omitempty"`
+	UserName        string               `json:"user_name,omitempty"`
+	WhatsAppAccount string               `json:"whatsapp_account,omitempty"`
+	CreatedAt       time.Time            `json:"created_at"`
+}
+
+// ContactConsentResponse is a contact's current consent state and its history
+type ContactConsentResponse struct {
+	ContactID        uuid.UUID              `json:"contact_id"`
+	ConsentStatus    models.ConsentStatus   `json:"consent_status"`
+	ConsentSource    models.ConsentSource   `json:"consent_source,omitempty"`
+	ConsentUpdatedAt *time.Time             `json:"consent_updated_at,omitempty"`
+	Events           []ConsentEventResponse `json:"events"`
+}
+
+// UpdateContactConsentRequest represents a manual consent change
+type UpdateContactConsentRequest struct {
+	Status models.ConsentStatus `json:"status"`
+	Reason string               `json:"reason"`
+}
+
+// GetContactConsent returns a contact's marketing consent and its audit trail
+func (a *App) GetContactConsent(r *fastglue.Request) error {
+	orgID, userID, err := a.getOrgAndUserID(r)
+	if err != nil {
+		return r.SendErrorEnvelope(fasthttp.StatusUnauthorized, "Unauthorized", nil, "")
+	}
+	if err := a.requirePermission(r, userID, models.ResourceContacts, models.ActionRead); err != nil {
+		return nil
+	}
+
+	contactID, err := parsePathUUID(r, "id", "contact")
+	if err != nil {
+		return nil
+	}
+	contact, err := findByIDAndOrg[models.Contact](a.DB, r, contactID, orgID, "Contact")
+	if err != nil {
+		return nil
+	}
+
+	return a.sendContactConsent(r, contact)
+}
+
+// UpdateContactConsent manually opts a contact in or out of marketing messages
+func (a *App) UpdateContactConsent(r *fastglue.Request) error {
+	orgID, userID, err := a.getOrgAndUserID(r)
+	if err != nil {
+		return r.SendErrorEnvelope(fasthttp.StatusUnauthorized, "Unauthorized", nil, "")
+	}
+	if err := a.requirePermission(r, userID, models.ResourceContacts, models.ActionWrite); err != nil {
+		return nil
+	}
+
+	contactID, err := parsePathUUID(r, "id", "contact")
+	if err != nil {
+		return nil
+	}
+
+	var req UpdateContactConsentRequest
+	if err := a.decodeRequest(r, &req); err != nil {
+		return nil
+	}
+	if req.Status != models.ConsentStatusOptedIn && req.Status != models.ConsentStatusOptedOut {
+		return r.SendErrorEnvelope(fasthttp.StatusBadRequest, "status must be 'opted_in' or 'opted_out'", nil, "")
+	}
+
+	contact, err := findByIDAndOrg[models.Contact](a.DB, r, contactID, orgID, "Contact")
+	if err != nil {
+		return nil
+	}
+
+	if _, err := contactutil.SetConsent(a.DB, contact, contactutil.ConsentChange{
+		Status: req.Status,
+		Source: models.ConsentSourceManual,
+		Reason: strings.TrimSpace(req.Reason),
+		UserID: &userID,
+	}); err != nil {
+		a.Log.Error("Failed to update contact consent", "error", err, "contact_id", contactID)
+		return r.SendErrorEnvelope(fasthttp.StatusInternalServerError, "Failed to update consent", nil, "")
+	}
+
+	a.Log.Info("Contact consent updated", "contact_id", contactID, "status", req.Status, "user_id", userID)
+
+	return a.sendContactConsent(r, contact)
+}
+
+// contactConsentEventLimit caps the audit events returned with a contact's consent
+const contactConsentEventLimit = 100
+
+// sendContactConsent responds with the contact's consent state and latest audit events
+func (a *App) sendContactConsent(r *fastglue.Request, contact *models.Contact) error {
+	var events []models.ConsentEvent
+	if err := a.DB.Preload("User").
+		Where("organization_id = ? AND contact_id = ?", contact.OrganizationID, contact.ID).
+		Order("created_at DESC").
+		Limit(contactConsentEventLimit).
+		Find(&events).Error; err != nil {
+		a.Log.Error("Failed to load consent events", "error", err, "contact_id", contact.ID)
+		return r.SendErrorEnvelope(fasthttp.StatusInternalServerError, "Failed to load consent history", nil, "")
+	}
+
+	status := contact.ConsentStatus
+	if status == "" {
+		status = models.ConsentStatusUnknown
+	}
+	resp := ContactConsentResponse{
+		ContactID:        contact.ID,
+		ConsentStatus:    status,
+		ConsentSource:    contact.ConsentSource,
+		ConsentUpdatedAt: contact.ConsentUpdatedAt,
+		Events:           make([]ConsentEventResponse, len(events)),
+	}
+	for i, e := range events {
+		resp.Events[i] = ConsentEventResponse{
+			ID:              e.ID,
+			Status:          e.Status,
+			PreviousStatus:  e.PreviousStatus,
+			Source:          e.Source,
+			Keyword:         e.Keyword,
+			Reason:          e.Reason,
+			MessageID:       e.MessageID,
+			UserID:          e.UserID,
+			WhatsAppAccount: e.WhatsAppAccount,
+			CreatedAt:       e.CreatedAt,
+		}
+		if e.User != nil {
+			resp.Events[i].UserName = e.User.FullName
+		}
+	}
+
+	return r.SendEnvelope(resp)
+}
@@ -0,0 +1,151 @@
+package handlers
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shridarpatil/whatomate/internal/models"
+	"github.com/shridarpatil/whatomate/test/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMatchConsentKeyword(t *testing.T) {
+	tests := []struct {
+		text    string
+		want    string
+		matched bool
+	}{
+		{"STOP", "STOP", true},
+		{"stop", "STOP", true},
+		{"  Stop!  ", "STOP", true},
+		{"stop promotions.", "STOP PROMOTIONS", true},
+		{"please stop", "", false},
+		{"cancel", "", false},
+		{"stopping", "", false},
+		{"", "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			got, ok := matchConsentKeyword(tt.text, defaultOptOutKeywords)
+			assert.Equal(t, tt.matched, ok)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestConsentSettingsFromOrg(t *testing.T) {
+	defaults := consentSettingsFromOrg(nil)
+	assert.Equal(t, defaultOptOutKeywords, defaults.OptOutKeywords)
+	assert.Equal(t, defaultOptInReply, defaults.OptInReply)
+
+	custom := consentSettingsFromOrg(models.JSONB{
+		"opt_out_keywords": []interface{}{"BAJA", "ALTO"},
+		"opt_out_reply":    "",
+	})
+	assert.Equal(t, []string{"BAJA", "ALTO"}, custom.OptOutKeywords)
+	assert.Equal(t, defaultOptInKeywords, custom.OptInKeywords)
+	assert.Empty(t, custom.OptOutReply, "an empty reply disables the confirmation")
+}
+
+func TestNormalizeConsentKeywords(t *testing.T) {
+	assert.Equal(t, []string{"STOP", "Baja"}, normalizeConsentKeywords([]string{" STOP ", "", "stop", "Baja"}))
+}
+
+func TestHandleConsentKeyword_OptOutAndBackIn(t *testing.T) {
+	app := newProcessorTestApp(t)
+	org, account := createProcessorTestOrg(t, app)
+	contact := testutil.CreateTestContact(t, app.DB, org.ID)
+
+	assert.False(t, app.handleConsentKeyword(account, contact, "hello", nil))
+
+	require.True(t, app.handleConsentKeyword(account, contact, "Stop", nil))
+
+	var dbContact models.Contact
+	require.NoError(t, app.DB.First(&dbContact, contact.ID).Error)
+	assert.Equal(t, models.ConsentStatusOptedOut, dbContact.ConsentStatus)
+	assert.Equal(t, models.ConsentSourceKeyword, dbContact.ConsentSource)
+	assert.NotNil(t, dbContact.ConsentUpdatedAt)
+	assert.True(t, dbContact.IsOptedOut())
+
+	// Repeating the keyword does not add another audit event
+	require.True(t, app.handleConsentKeyword(account, contact, "STOP", nil))
+
+	require.True(t, app.handleConsentKeyword(account, contact, "start", nil))
+
+	var events []models.ConsentEvent
+	require.NoError(t, app.DB.Where("contact_id = ?", contact.ID).Order("created_at ASC").Find(&events).Error)
+	require.Len(t, events, 2)
+	assert.Equal(t, models.ConsentStatusOptedOut, events[0].Status)
+	assert.Equal(t, models.ConsentStatusUnknown, events[0].PreviousStatus)
+	assert.Equal(t, "STOP", events[0].Keyword)
+	assert.Equal(t, account.Name, events[0].WhatsAppAccount)
+	assert.Equal(t, models.ConsentStatusOptedIn, events[1].Status)
+	assert.Equal(t, models.ConsentStatusOptedOut, events[1].PreviousStatus)
+
+	// Confirmations are sent and stored as outgoing messages
+	var outgoing int64
+	app.DB.Model(&models.Message{}).Where("contact_id = ? AND direction = ?", contact.ID, models.DirectionOutgoing).Count(&outgoing)
+	assert.Equal(t, int64(3), outgoing)
+}
+
+func TestProcessIncomingMessage_CancelInFlowDoesNotOptOut(t *testing.T) {
+	app := newProcessorTestApp(t)
+	org, account := createProcessorTestOrg(t, app)
+	contact := testutil.CreateTestContact(t, app.DB, org.ID)
+
+	require.NoError(t, app.DB.Create(&models.ChatbotSettings{
+		BaseModel:          models.BaseModel{ID: uuid.New()},
+		OrganizationID:     org.ID,
+		WhatsAppAccount:    account.Name,
+		IsEnabled:          true,
+		SessionTimeoutMins: 30,
+	}).Error)
+
+	// Opt-out words configured by the organization still do not apply while
+	// the contact is in a flow
+	require.NoError(t, app.DB.Model(&models.Organization{}).Where("id = ?", org.ID).
+		Update("settings", models.JSONB{"opt_out_keywords": []interface{}{"STOP", "CANCEL"}}).Error)
+
+	flowID := uuid.New()
+	require.NoError(t, app.DB.Create(&models.ChatbotFlow{
+		BaseModel:       models.BaseModel{ID: flowID},
+		OrganizationID:  org.ID,
+		WhatsAppAccount: account.Name,
+		Name:            "Booking",
+		IsEnabled:       true,
+		CancelKeywords:  models.StringArray{"cancel"},
+		Steps: []models.ChatbotFlowStep{
+			{BaseModel: models.BaseModel{ID: uuid.New()}, FlowID: flowID, StepName: "ask_date", StepOrder: 1, Message: "Which date?", MessageType: models.FlowStepTypeText, InputType: models.InputTypeText},
+		},
+	}).Error)
+	session := &models.ChatbotSession{
+		BaseModel:       models.BaseModel{ID: uuid.New()},
+		OrganizationID:  org.ID,
+		ContactID:       contact.ID,
+		WhatsAppAccount: account.Name,
+		PhoneNumber:     contact.PhoneNumber,
+		Status:          models.SessionStatusActive,
+		CurrentFlowID:   &flowID,
+		CurrentStep:     "ask_date",
+		SessionData:     models.JSONB{},
+		StartedAt:       time.Now(),
+		LastActivityAt:  time.Now(),
+	}
+	require.NoError(t, app.DB.Create(session).Error)
+
+	msg := IncomingTextMessage{From: contact.PhoneNumber, ID: "wamid.cancel", Type: "text"}
+	msg.Text = &struct {
+		Body string `json:"body"`
+	}{Body: "cancel"}
+	app.processIncomingMessageFull(account.PhoneID, msg, "")
+
+	var dbContact models.Contact
+	require.NoError(t, app.DB.First(&dbContact, contact.ID).Error)
+	assert.False(t, dbContact.IsOptedOut(), "cancel leaves the flow without unsubscribing")
+
+	var dbSession models.ChatbotSession
+	require.NoError(t, app.DB.First(&dbSession, session.ID).Error)
+	assert.Equal(t, models.SessionStatusCompleted, dbSession.Status, "the flow was cancelled")
+}
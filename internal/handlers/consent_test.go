@@ -0,0 +1,113 @@
+package handlers_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/shridarpatil/whatomate/internal/contactutil"
+	"github.com/shridarpatil/whatomate/internal/handlers"
+	"github.com/shridarpatil/whatomate/internal/models"
+	"github.com/shridarpatil/whatomate/test/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/valyala/fasthttp"
+)
+
+func TestApp_UpdateContactConsent(t *testing.T) {
+	app := newTestApp(t)
+	org := testutil.CreateTestOrganization(t, app.DB)
+	adminRole := testutil.CreateAdminRole(t, app.DB, org.ID)
+	user := testutil.CreateTestUser(t, app.DB, org.ID, testutil.WithRoleID(&adminRole.ID))
+	contact := testutil.CreateTestContact(t, app.DB, org.ID)
+
+	req := testutil.NewJSONRequest(t, map[string]interface{}{
+		"status": "opted_out",
+		"reason": "Requested by phone",
+	})
+	testutil.SetAuthContext(req, org.ID, user.ID)
+	testutil.SetPathParam(req, "id", contact.ID.String())
+
+	require.NoError(t, app.UpdateContactConsent(req))
+	assert.Equal(t, fasthttp.StatusOK, testutil.GetResponseStatusCode(req))
+
+	var resp struct {
+		Data handlers.ContactConsentResponse `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(testutil.GetResponseBody(req), &resp))
+	assert.Equal(t, models.ConsentStatusOptedOut, resp.Data.ConsentStatus)
+	assert.Equal(t, models.ConsentSourceManual, resp.Data.ConsentSource)
+	require.Len(t, resp.Data.Events, 1)
+	assert.Equal(t, "Requested by phone", resp.Data.Events[0].Reason)
+	assert.Equal(t, models.ConsentStatusUnknown, resp.Data.Events[0].PreviousStatus)
+	require.NotNil(t, resp.Data.Events[0].UserID)
+	assert.Equal(t, user.ID, *resp.Data.Events[0].UserID)
+
+	// The history is returned by the GET endpoint as well
+	getReq := testutil.NewGETRequest(t)
+	testutil.SetAuthContext(getReq, org.ID, user.ID)
+	testutil.SetPathParam(getReq, "id", contact.ID.String())
+
+	require.NoError(t, app.GetContactConsent(getReq))
+	assert.Equal(t, fasthttp.StatusOK, testutil.GetResponseStatusCode(getReq))
+	require.NoError(t, json.Unmarshal(testutil.GetResponseBody(getReq), &resp))
+	assert.Equal(t, models.ConsentStatusOptedOut, resp.Data.ConsentStatus)
+	assert.Len(t, resp.Data.Events, 1)
+}
+
+func TestApp_UpdateContactConsent_InvalidStatus(t *testing.T) {
+	app := newTestApp(t)
+	org := testutil.CreateTestOrganization(t, app.DB)
+	adminRole := testutil.CreateAdminRole(t, app.DB, org.ID)
+	user := testutil.CreateTestUser(t, app.DB, org.ID, testutil.WithRoleID(&adminRole.ID))
+	contact := testutil.CreateTestContact(t, app.DB, org.ID)
+
+	req := testutil.NewJSONRequest(t, map[string]interface{}{"status": "unknown"})
+	testutil.SetAuthContext(req, org.ID, user.ID)
+	testutil.SetPathParam(req, "id", contact.ID.String())
+
+	require.NoError(t, app.UpdateContactConsent(req))
+	assert.Equal(t, fasthttp.StatusBadRequest, testutil.GetResponseStatusCode(req))
+}
+
+func TestApp_ImportRecipients_ExcludesOptedOut(t *testing.T) {
+	app := newTestApp(t)
+	org := testutil.CreateTestOrganization(t, app.DB)
+	adminRole := testutil.CreateAdminRole(t, app.DB, org.ID)
+	user := testutil.CreateTestUser(t, app.DB, org.ID, testutil.WithRoleID(&adminRole.ID))
+	account := testutil.CreateTestWhatsAppAccount(t, app.DB, org.ID)
+	template := testutil.CreateTestTemplate(t, app.DB, org.ID, account.Name) // MARKETING
+	campaign := createTestCampaign(t, app, org.ID, template.ID, user.ID, account.Name, models.CampaignStatusDraft)
+
+	optedOut := testutil.CreateTestContactWith(t, app.DB, org.ID, testutil.WithPhoneNumber("+15550001111"))
+	_, err := contactutil.SetConsent(app.DB, optedOut, contactutil.ConsentChange{
+		Status: models.ConsentStatusOptedOut,
+		Source: models.ConsentSourceManual,
+	})
+	require.NoError(t, err)
+
+	req := testutil.NewJSONRequest(t, map[string]interface{}{
+		"recipients": []map[string]interface{}{
+			{"phone_number": "15550001111"},
+			{"phone_number": "+15550002222"},
+		},
+	})
+	testutil.SetAuthContext(req, org.ID, user.ID)
+	testutil.SetPathParam(req, "id", campaign.ID.String())
+
+	require.NoError(t, app.ImportRecipients(req))
+	assert.Equal(t, fasthttp.StatusOK, testutil.GetResponseStatusCode(req))
+
+	var resp struct {
+		Data struct {
+			AddedCount    int `json:"added_count"`
+			ExcludedCount int `json:"excluded_count"`
+		} `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(testutil.GetResponseBody(req), &resp))
+	assert.Equal(t, 1, resp.Data.AddedCount)
+	assert.Equal(t, 1, resp.Data.ExcludedCount)
+
+	var phones []string
+	app.DB.Model(&models.BulkMessageRecipient{}).Where("campaign_id = ?", campaign.ID).Pluck("phone_number", &phones)
+	assert.Equal(t, []string{"+15550002222"}, phones)
+}
@@ -0,0 +1,80 @@
+package handlers
+
+import (
+	"slices"
+
+	"github.com/google/uuid"
+	"github.com/shridarpatil/whatomate/internal/models"
+	"github.com/shridarpatil/whatomate/internal/webhookutil"
+)
+
+// contactTagNames returns the string tags of a contact
+func contactTagNames(tags models.JSONBArray) []string {
+	names := []string{}
+	for _, t := range tags {
+		if s, ok := t.(string); ok {
+			names = append(names, s)
+		}
+	}
+	return names
+}
+
+// dispatchContactTagged sends a contact.tagged webhook if the contact's tags
+// differ from the previous ones
+func (a *App) dispatchContactTagged(orgID, userID uuid.UUID, contact *models.Contact, previous []string) {
+	tags := contactTagNames(contact.Tags)
+
+	added := []string{}
+	for _, tag := range tags {
+		if !slices.Contains(previous, tag) {
+			added = append(added, tag)
+		}
+	}
+	removed := []string{}
+	for _, tag := range previous {
+		if !slices.Contains(tags, tag) {
+			removed = append(removed, tag)
+		}
+	}
+	if len(added) == 0 && len(removed) == 0 {
+		return
+	}
+
+	a.DispatchWebhook(orgID, models.WebhookEventContactTagged, webhookutil.ContactTaggedEventData{
+		ContactID:    contact.ID.String(),
+		ContactPhone: contact.PhoneNumber,
+		ContactName:  contact.ProfileName,
+		Tags:         tags,
+		Added:        added,
+		Removed:      removed,
+		UserID:       userID.String(),
+	})
+}
+
+// dispatchContactAssigned sends a contact.assigned webhook if the contact's
+// assignee differs from the previous one
+func (a *App) dispatchContactAssigned(orgID, userID uuid.UUID, contact *models.Contact, previous *uuid.UUID) {
+	if (previous == nil && contact.AssignedUserID == nil) ||
+		(previous != nil && contact.AssignedUserID != nil && *previous == *contact.AssignedUserID) {
+		return
+	}
+
+	data := webhookutil.ContactAssignedEventData{
+		ContactID:        contact.ID.String(),
+		ContactPhone:     contact.PhoneNumber,
+		ContactName:      contact.ProfileName,
+		AssignedByUserID: userID.String(),
+	}
+	if previous != nil {
+		data.PreviousUserID = previous.String()
+	}
+	if contact.AssignedUserID != nil {
+		data.AssignedUserID = contact.AssignedUserID.String()
+		var user models.User
+		if err := a.DB.Select("full_name").Where("id = ?", contact.AssignedUserID).First(&user).Error; err == nil {
+			data.AssignedUserName = user.FullName
+		}
+	}
+
+	a.DispatchWebhook(orgID, models.WebhookEventContactAssigned, data)
+}
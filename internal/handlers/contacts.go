@@ -7,8 +7,7 @@ import (
 	"fmt"
 	"io"
 	"net/http"
-	"os"
-	"path/filepath"
+	"path"
 	"strconv"
 	"strings"
 	"time"
@@ -22,23 +21,29 @@ import (
 
 // ContactResponse represents a contact with additional fields for the frontend
 type ContactResponse struct {
-	ID                 uuid.UUID  `json:"id"`
-	PhoneNumber        string     `json:"phone_number"`
-	Name               string     `json:"name"`
-	ProfileName        string     `json:"profile_name"`
-	AvatarURL          string     `json:"avatar_url"`
-	Status             string     `json:"status"`
-	Tags               []string   `json:"tags"`
-	Metadata           any        `json:"metadata"`
-	LastMessageAt      *time.Time `json:"last_message_at"`
-	LastMessagePreview string     `json:"last_message_preview"`
-	UnreadCount        int        `json:"unread_count"`
-	AssignedUserID     *uuid.UUID `json:"assigned_user_id,omitempty"`
-	WhatsAppAccount    string     `json:"whatsapp_account,omitempty"`
-	LastInboundAt      *time.Time `json:"last_inbound_at,omitempty"`
-	ServiceWindowOpen  bool       `json:"service_window_open"`
-	CreatedAt          time.Time  `json:"created_at"`
-	UpdatedAt          time.Time  `json:"updated_at"`
+	ID                 uuid.UUID            `json:"id"`
+	PhoneNumber        string               `json:"phone_number"`
+	Name               string               `json:"name"`
+	ProfileName        string               `json:"profile_name"`
+	AvatarURL          string               `json:"avatar_url"`
+	Status             string               `json:"status"`
+	Tags               []string             `json:"tags"`
+	Metadata           any                  `json:"metadata"`
+	LastMessageAt      *time.Time           `json:"last_message_at"`
+	LastMessagePreview string               `json:"last_message_preview"`
+	UnreadCount        int                  `json:"unread_count"`
+	AssignedUserID     *uuid.UUID           `json:"assigned_user_id,omitempty"`
+	WhatsAppAccount    string               `json:"whatsapp_account,omitempty"`
+	LastInboundAt      *time.Time           `json:"last_inbound_at,omitempty"`
+	ServiceWindowOpen  bool                 `json:"service_window_open"`
+	ConsentStatus      models.ConsentStatus `json:"consent_status"`
+	ConsentUpdatedAt   *time.Time           `json:"consent_updated_at,omitempty"`
+	CreatedAt          time.Time            `json:"created_at"`
+	UpdatedAt          time.Time            `json:"updated_at"`
+
+	// Latest conversation, empty before the contact's first conversation
+	ConversationID     *uuid.UUID                `json:"conversation_id,omitempty"`
+	ConversationStatus models.ConversationStatus `json:"conversation_status,omitempty"`
 }
 
 // MessageResponse represents a message for the frontend
@@ -91,12 +96,14 @@ func (a *App) ListContacts(r *fastglue.Request) error {
 	pg := parsePagination(r)
 	search := string(r.RequestCtx.QueryArgs().Peek("search"))
 	tagsParam := string(r.RequestCtx.QueryArgs().Peek("tags"))
+	conversationStatusParam := string(r.RequestCtx.QueryArgs().Peek("conversation_status"))
 
 	var contacts []models.Contact
 	query := a.ScopeToOrg(a.DB, userID, orgID)
 
 	// Users without contacts:read permission can only see contacts assigned to them
-	if !a.HasPermission(userID, models.ResourceContacts, models.ActionRead, orgID) {
+	hasContactsRead := a.HasPermission(userID, models.ResourceContacts, models.ActionRead, orgID)
+	if !hasContactsRead {
 		query = query.Where("assigned_user_id = ?", userID)
 	}
 
@@ -131,6 +138,19 @@ func (a *App) ListContacts(r *fastglue.Request) error {
 		}
 	}
 
+	// Filter by the status of the contact's latest conversation (comma-separated)
+	if conversationStatusParam != "" {
+		statuses := []models.ConversationStatus{}
+		for _, s := range strings.Split(conversationStatusParam, ",") {
+			status := models.ConversationStatus(strings.TrimSpace(s))
+			if !isValidConversationStatus(status) {
+				return r.SendErrorEnvelope(fasthttp.StatusBadRequest, "Invalid conversation_status", nil, "")
+			}
+			statuses = append(statuses, status)
+		}
+		query = query.Where("conversation_status IN ?", statuses)
+	}
+
 	// Order by last message time (most recent first)
 	query = query.Order("last_message_at DESC NULLS LAST, created_at DESC")
 
@@ -145,15 +165,16 @@ func (a *App) ListContacts(r *fastglue.Request) error {
 	// Check if phone masking is enabled
 	shouldMask := a.ShouldMaskPhoneNumbers(orgID)
 
+	// Count unread messages for the current user
+	contactIDs := make([]uuid.UUID, len(contacts))
+	for i, c := range contacts {
+		contactIDs[i] = c.ID
+	}
+	unreadCounts := a.unreadCounts(userID, contactIDs)
+
 	// Convert to response format
 	response := make([]ContactResponse, len(contacts))
 	for i, c := range contacts {
-		// Count unread messages
-		var unreadCount int64
-		a.DB.Model(&models.Message{}).
-			Where("contact_id = ? AND direction = ? AND status != ?", c.ID, models.DirectionIncoming, models.MessageStatusRead).
-			Count(&unreadCount)
-
 		tags := []string{}
 		if c.Tags != nil {
 			for _, t := range c.Tags {
@@ -182,11 +203,15 @@ func (a *App) ListContacts(r *fastglue.Request) error {
 			Metadata:           c.Metadata,
 			LastMessageAt:      c.LastMessageAt,
 			LastMessagePreview: c.LastMessagePreview,
-			UnreadCount:        int(unreadCount),
+			UnreadCount:        int(unreadCounts[c.ID]),
 			AssignedUserID:     c.AssignedUserID,
 			WhatsAppAccount:    c.WhatsAppAccount,
 			LastInboundAt:      c.LastInboundAt,
 			ServiceWindowOpen:  serviceWindowOpen,
+			ConsentStatus:      c.ConsentStatus,
+			ConsentUpdatedAt:   c.ConsentUpdatedAt,
+			ConversationID:     c.ConversationID,
+			ConversationStatus: c.ConversationStatus,
 			CreatedAt:          c.CreatedAt,
 			UpdatedAt:          c.UpdatedAt,
 		}
@@ -197,6 +222,7 @@ func (a *App) ListContacts(r *fastglue.Request) error {
 		"total":    total,
 		"page":     pg.Page,
 		"limit":    pg.Limit,
+		"unread":   a.inboxUnread(orgID, userID, !hasContactsRead),
 	})
 }
 
@@ -224,11 +250,8 @@ func (a *App) GetContact(r *fastglue.Request) error {
 		return r.SendErrorEnvelope(fasthttp.StatusNotFound, "Contact not found", nil, "")
 	}
 
-	// Count unread messages
-	var unreadCount int64
-	a.DB.Model(&models.Message{}).
-		Where("contact_id = ? AND direction = ? AND status != ?", contact.ID, models.DirectionIncoming, models.MessageStatusRead).
-		Count(&unreadCount)
+	// Count unread messages for the current user
+	unreadCount := a.unreadCount(userID, contact.ID)
 
 	tags := []string{}
 	if contact.Tags != nil {
@@ -260,6 +283,10 @@ func (a *App) GetContact(r *fastglue.Request) error {
 		UnreadCount:        int(unreadCount),
 		AssignedUserID:     contact.AssignedUserID,
 		WhatsAppAccount:    contact.WhatsAppAccount,
+		ConsentStatus:      contact.ConsentStatus,
+		ConsentUpdatedAt:   contact.ConsentUpdatedAt,
+		ConversationID:     contact.ConversationID,
+		ConversationStatus: contact.ConversationStatus,
 		CreatedAt:          contact.CreatedAt,
 		UpdatedAt:          contact.UpdatedAt,
 	}
@@ -383,7 +410,7 @@ func (a *App) GetMessages(r *fastglue.Request) error {
 	}
 
 	// Mark messages as read
-	a.markMessagesAsRead(orgID, contactID, &contact)
+	a.markMessagesAsRead(orgID, userID, contactID, &contact)
 
 	response := a.buildMessagesResponse(messages)
 	return r.SendEnvelope(map[string]any{
@@ -462,8 +489,11 @@ func (a *App) buildMessagesResponse(messages []models.Message) []MessageResponse
 	return response
 }
 
-// markMessagesAsRead marks messages as read and sends read receipts
-func (a *App) markMessagesAsRead(orgID uuid.UUID, contactID uuid.UUID, contact *models.Contact) {
+// markMessagesAsRead marks messages as read, advances the user's read cursor
+// and sends read receipts
+func (a *App) markMessagesAsRead(orgID, userID uuid.UUID, contactID uuid.UUID, contact *models.Contact) {
+	a.markConversationRead(orgID, userID, contactID)
+
 	var unreadMessages []models.Message
 	a.DB.Where("contact_id = ? AND direction = ? AND status != ?", contactID, models.DirectionIncoming, models.MessageStatusRead).
 		Find(&unreadMessages)
@@ -777,10 +807,10 @@ func (a *App) SendMediaMessage(r *fastglue.Request) error {
 		return r.SendErrorEnvelope(fasthttp.StatusBadRequest, err.Error(), nil, "")
 	}
 
-	// Save file locally first
-	localPath, err := a.saveMediaLocally(fileData, mimeType, fileHeader.Filename)
+	// Save file to media storage first
+	localPath, err := a.saveMedia(r.RequestCtx, fileData, mimeType, fileHeader.Filename)
 	if err != nil {
-		a.Log.Error("Failed to save media locally", "error", err)
+		a.Log.Error("Failed to save media", "error", err)
 		return r.SendErrorEnvelope(fasthttp.StatusInternalServerError, "Failed to save media", nil, "")
 	}
 
@@ -824,26 +854,8 @@ func (a *App) SendMediaMessage(r *fastglue.Request) error {
 	return r.SendEnvelope(response)
 }
 
-// saveMediaLocally saves media data to local storage and returns the relative path
-func (a *App) saveMediaLocally(data []byte, mimeType, filename string) (string, error) {
-	// Determine subdirectory based on MIME type
-	var subdir string
-	switch {
-	case strings.HasPrefix(mimeType, "image/"):
-		subdir = "images"
-	case strings.HasPrefix(mimeType, "video/"):
-		subdir = "videos"
-	case strings.HasPrefix(mimeType, "audio/"):
-		subdir = "audio"
-	default:
-		subdir = "documents"
-	}
-
-	// Ensure directory exists
-	if err := a.ensureMediaDir(subdir); err != nil {
-		return "", fmt.Errorf("failed to create media directory: %w", err)
-	}
-
+// saveMedia saves media data to media storage and returns the storage key
+func (a *App) saveMedia(ctx context.Context, data []byte, mimeType, filename string) (string, error) {
 	// Get extension from MIME type or filename
 	ext := getExtensionFromMimeType(mimeType)
 	if ext == "" {
@@ -855,20 +867,15 @@ func (a *App) saveMediaLocally(data []byte, mimeType, filename string) (string,
 		}
 	}
 
-	// Generate unique filename
-	newFilename := uuid.New().String() + ext
-	filePath := filepath.Join(a.getMediaStoragePath(), subdir, newFilename)
-
-	// Save file
-	if err := os.WriteFile(filePath, data, 0644); err != nil {
-		return "", fmt.Errorf("failed to save media file: %w", err)
+	// Generate unique filename under the subdirectory for the MIME type
+	key := path.Join(getMediaSubdir(mimeType), uuid.New().String()+ext)
+	if err := a.storeMedia(ctx, key, data, mimeType); err != nil {
+		return "", err
 	}
 
-	// Return relative path
-	relativePath := filepath.Join(subdir, newFilename)
-	a.Log.Info("Media saved locally", "path", relativePath, "size", len(data))
+	a.Log.Info("Media saved", "path", key, "size", len(data))
 
-	return relativePath, nil
+	return key, nil
 }
 
 // SendReactionRequest represents a request to send a reaction
@@ -1088,10 +1095,13 @@ func (a *App) AssignContact(r *fastglue.Request) error {
 	}
 
 	// Update contact assignment
+	previousUserID := contact.AssignedUserID
 	if err := a.DB.Model(contact).Update("assigned_user_id", req.UserID).Error; err != nil {
 		a.Log.Error("Failed to assign contact", "error", err)
 		return r.SendErrorEnvelope(fasthttp.StatusInternalServerError, "Failed to assign contact", nil, "")
 	}
+	contact.AssignedUserID = req.UserID
+	a.dispatchContactAssigned(orgID, userID, contact, previousUserID)
 
 	return r.SendEnvelope(map[string]any{
 		"message":          "Contact assigned successfully",
@@ -1241,6 +1251,7 @@ func (a *App) UpdateContactTags(r *fastglue.Request) error {
 	}
 
 	// Update contact tags
+	previousTags := contactTagNames(contact.Tags)
 	if err := a.DB.Model(contact).Update("tags", tagsArray).Error; err != nil {
 		a.Log.Error("Failed to update contact tags", "error", err)
 		return r.SendErrorEnvelope(fasthttp.StatusInternalServerError, "Failed to update contact tags", nil, "")
@@ -1251,15 +1262,10 @@ func (a *App) UpdateContactTags(r *fastglue.Request) error {
 		a.Log.Error("Failed to reload contact", "error", err)
 	}
 
+	a.dispatchContactTagged(orgID, userID, contact, previousTags)
+
 	// Build response with tag details
-	tags := []string{}
-	if contact.Tags != nil {
-		for _, t := range contact.Tags {
-			if s, ok := t.(string); ok {
-				tags = append(tags, s)
-			}
-		}
-	}
+	tags := contactTagNames(contact.Tags)
 
 	return r.SendEnvelope(map[string]any{
 		"message": "Contact tags updated",
@@ -1334,7 +1340,7 @@ func (a *App) CreateContact(r *fastglue.Request) error {
 			}
 			// Reload contact
 			a.DB.First(&existingContact, existingContact.ID)
-			return r.SendEnvelope(a.buildContactResponse(&existingContact, orgID))
+			return r.SendEnvelope(a.buildContactResponse(&existingContact, orgID, userID))
 		}
 		return r.SendErrorEnvelope(fasthttp.StatusConflict, "Contact with this phone number already exists", nil, "")
 	}
@@ -1365,7 +1371,7 @@ func (a *App) CreateContact(r *fastglue.Request) error {
 		return r.SendErrorEnvelope(fasthttp.StatusInternalServerError, "Failed to create contact", nil, "")
 	}
 
-	return r.SendEnvelope(a.buildContactResponse(&contact, orgID))
+	return r.SendEnvelope(a.buildContactResponse(&contact, orgID, userID))
 }
 
 // UpdateContactRequest represents the request body for updating a contact
@@ -1437,6 +1443,9 @@ func (a *App) UpdateContact(r *fastglue.Request) error {
 		return r.SendErrorEnvelope(fasthttp.StatusBadRequest, "No fields to update", nil, "")
 	}
 
+	previousTags := contactTagNames(contact.Tags)
+	previousUserID := contact.AssignedUserID
+
 	if err := a.DB.Model(contact).Updates(updates).Error; err != nil {
 		a.Log.Error("Failed to update contact", "error", err)
 		return r.SendErrorEnvelope(fasthttp.StatusInternalServerError, "Failed to update contact", nil, "")
@@ -1445,7 +1454,14 @@ func (a *App) UpdateContact(r *fastglue.Request) error {
 	// Reload contact
 	a.DB.First(contact, contactID)
 
-	return r.SendEnvelope(a.buildContactResponse(contact, orgID))
+	if req.Tags != nil {
+		a.dispatchContactTagged(orgID, userID, contact, previousTags)
+	}
+	if req.AssignedUserID != nil {
+		a.dispatchContactAssigned(orgID, userID, contact, previousUserID)
+	}
+
+	return r.SendEnvelope(a.buildContactResponse(contact, orgID, userID))
 }
 
 // DeleteContact soft-deletes a contact
@@ -1483,12 +1499,9 @@ func (a *App) DeleteContact(r *fastglue.Request) error {
 }
 
 // buildContactResponse creates a ContactResponse from a Contact model
-func (a *App) buildContactResponse(contact *models.Contact, orgID uuid.UUID) ContactResponse {
-	// Count unread messages
-	var unreadCount int64
-	a.DB.Model(&models.Message{}).
-		Where("contact_id = ? AND direction = ? AND status != ?", contact.ID, models.DirectionIncoming, models.MessageStatusRead).
-		Count(&unreadCount)
+func (a *App) buildContactResponse(contact *models.Contact, orgID, userID uuid.UUID) ContactResponse {
+	// Count unread messages for the current user
+	unreadCount := a.unreadCount(userID, contact.ID)
 
 	tags := []string{}
 	if contact.Tags != nil {
@@ -1525,6 +1538,10 @@ func (a *App) buildContactResponse(contact *models.Contact, orgID uuid.UUID) Con
 		WhatsAppAccount:    contact.WhatsAppAccount,
 		LastInboundAt:      contact.LastInboundAt,
 		ServiceWindowOpen:  serviceWindowOpen,
+		ConsentStatus:      contact.ConsentStatus,
+		ConsentUpdatedAt:   contact.ConsentUpdatedAt,
+		ConversationID:     contact.ConversationID,
+		ConversationStatus: contact.ConversationStatus,
 		CreatedAt:          contact.CreatedAt,
 		UpdatedAt:          contact.UpdatedAt,
 	}
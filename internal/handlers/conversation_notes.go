@@ -5,6 +5,7 @@ import (
 
 	"github.com/google/uuid"
 	"github.com/shridarpatil/whatomate/internal/models"
+	"github.com/shridarpatil/whatomate/internal/webhookutil"
 	"github.com/shridarpatil/whatomate/internal/websocket"
 	"github.com/valyala/fasthttp"
 	"github.com/zerodha/fastglue"
@@ -144,6 +145,21 @@ func (a *App) CreateConversationNote(r *fastglue.Request) error {
 		})
 	}
 
+	noteEvent := webhookutil.NoteEventData{
+		NoteID:        note.ID.String(),
+		ContactID:     contactID.String(),
+		Content:       note.Content,
+		CreatedByID:   userID.String(),
+		CreatedByName: user.FullName,
+	}
+	var contact models.Contact
+	if err := a.DB.Select("phone_number", "profile_name").Where("id = ? AND organization_id = ?", contactID, orgID).
+		First(&contact).Error; err == nil {
+		noteEvent.ContactPhone = contact.PhoneNumber
+		noteEvent.ContactName = contact.ProfileName
+	}
+	a.DispatchWebhook(orgID, models.WebhookEventNoteCreated, noteEvent)
+
 	return r.SendEnvelope(resp)
 }
 
@@ -0,0 +1,437 @@
+package handlers
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shridarpatil/whatomate/internal/models"
+	"github.com/shridarpatil/whatomate/internal/webhookutil"
+	"github.com/shridarpatil/whatomate/internal/websocket"
+	"github.com/valyala/fasthttp"
+	"github.com/zerodha/fastglue"
+	"gorm.io/gorm"
+)
+
+// conversationReopenWindow is how long after being resolved a conversation
+// is reopened by a new message from the customer. Later messages start a new
+// conversation. It matches WhatsApp's customer service window.
+const conversationReopenWindow = 24 * time.Hour
+
+// ConversationStatusRequest represents the request body for changing a
+// conversation's status
+type ConversationStatusRequest struct {
+	Status           models.ConversationStatus           `json:"status"`
+	SnoozedUntil     *time.Time                          `json:"snoozed_until"`     // Required for snoozed
+	ResolutionReason models.ConversationResolutionReason `json:"resolution_reason"` // For resolved, defaults to solved
+	ResolutionNote   string                              `json:"resolution_note"`
+}
+
+// ConversationResponse represents a conversation in API responses
+type ConversationResponse struct {
+	ID               uuid.UUID                           `json:"id"`
+	ContactID        uuid.UUID                           `json:"contact_id"`
+	WhatsAppAccount  string                              `json:"whatsapp_account"`
+	Status           models.ConversationStatus           `json:"status"`
+	StatusChangedAt  time.Time                           `json:"status_changed_at"`
+	SnoozedUntil     *time.Time                          `json:"snoozed_until,omitempty"`
+	ReopenCount      int                                 `json:"reopen_count"`
+	ResolutionReason models.ConversationResolutionReason `json:"resolution_reason,omitempty"`
+	ResolutionNote   string                              `json:"resolution_note,omitempty"`
+	ResolvedAt       *time.Time                          `json:"resolved_at,omitempty"`
+	ResolvedByID     *uuid.UUID                          `json:"resolved_by_id,omitempty"`
+	ResolvedByName   string                              `json:"resolved_by_name,omitempty"`
+	CreatedAt        time.Time                           `json:"created_at"`
+	UpdatedAt        time.Time                           `json:"updated_at"`
+}
+
+// ListContactConversations returns a contact's conversations, latest first
+func (a *App) ListContactConversations(r *fastglue.Request) error {
+	orgID, userID, err := a.getOrgAndUserID(r)
+	if err != nil {
+		return r.SendErrorEnvelope(fasthttp.StatusUnauthorized, "Unauthorized", nil, "")
+	}
+	if err := a.requirePermission(r, userID, models.ResourceChat, models.ActionRead); err != nil {
+		return nil
+	}
+
+	contactID, err := parsePathUUID(r, "id", "contact")
+	if err != nil {
+		return nil
+	}
+
+	pg := parsePagination(r)
+	query := a.DB.Model(&models.Conversation{}).Where("organization_id = ? AND contact_id = ?", orgID, contactID)
+
+	var total int64
+	query.Count(&total)
+
+	var conversations []models.Conversation
+	if err := pg.Apply(query.Preload("ResolvedBy").Order("created_at DESC")).Find(&conversations).Error; err != nil {
+		a.Log.Error("Failed to list conversations", "error", err)
+		return r.SendErrorEnvelope(fasthttp.StatusInternalServerError, "Failed to list conversations", nil, "")
+	}
+
+	result := make([]ConversationResponse, len(conversations))
+	for i := range conversations {
+		result[i] = conversationToResponse(&conversations[i])
+	}
+
+	return r.SendEnvelope(map[string]any{
+		"conversations": result,
+		"total":         total,
+		"page":          pg.Page,
+		"limit":         pg.Limit,
+	})
+}
+
+// OpenContactConversation opens a conversation with a contact, reopening the
+// latest one when it is not resolved or was resolved recently
+func (a *App) OpenContactConversation(r *fastglue.Request) error {
+	orgID, userID, err := a.getOrgAndUserID(r)
+	if err != nil {
+		return r.SendErrorEnvelope(fasthttp.StatusUnauthorized, "Unauthorized", nil, "")
+	}
+	if err := a.requirePermission(r, userID, models.ResourceChat, models.ActionWrite); err != nil {
+		return nil
+	}
+
+	contactID, err := parsePathUUID(r, "id", "contact")
+	if err != nil {
+		return nil
+	}
+	contact, err := findByIDAndOrg[models.Contact](a.DB, r, contactID, orgID, "Contact")
+	if err != nil {
+		return nil
+	}
+
+	conversation, err := a.openConversation(contact, &userID, time.Now())
+	if err != nil {
+		return r.SendErrorEnvelope(fasthttp.StatusInternalServerError, "Failed to open conversation", nil, "")
+	}
+
+	return r.SendEnvelope(conversationToResponse(conversation))
+}
+
+// GetConversation returns a conversation
+func (a *App) GetConversation(r *fastglue.Request) error {
+	orgID, userID, err := a.getOrgAndUserID(r)
+	if err != nil {
+		return r.SendErrorEnvelope(fasthttp.StatusUnauthorized, "Unauthorized", nil, "")
+	}
+	if err := a.requirePermission(r, userID, models.ResourceChat, models.ActionRead); err != nil {
+		return nil
+	}
+
+	id, err := parsePathUUID(r, "id", "conversation")
+	if err != nil {
+		return nil
+	}
+	conversation, err := findByIDAndOrg[models.Conversation](a.DB.Preload("ResolvedBy"), r, id, orgID, "Conversation")
+	if err != nil {
+		return nil
+	}
+
+	return r.SendEnvelope(conversationToResponse(conversation))
+}
+
+// UpdateConversationStatus opens, sets pending, snoozes or resolves a
+// conversation. Resolving a conversation also resumes the contact's active
+// agent transfer.
+func (a *App) UpdateConversationStatus(r *fastglue.Request) error {
+	orgID, userID, err := a.getOrgAndUserID(r)
+	if err != nil {
+		return r.SendErrorEnvelope(fasthttp.StatusUnauthorized, "Unauthorized", nil, "")
+	}
+	if err := a.requirePermission(r, userID, models.ResourceChat, models.ActionWrite); err != nil {
+		return nil
+	}
+
+	id, err := parsePathUUID(r, "id", "conversation")
+	if err != nil {
+		return nil
+	}
+	conversation, err := findByIDAndOrg[models.Conversation](a.DB, r, id, orgID, "Conversation")
+	if err != nil {
+		return nil
+	}
+
+	var req ConversationStatusRequest
+	if err := a.decodeRequest(r, &req); err != nil {
+		return nil
+	}
+
+	if !isValidConversationStatus(req.Status) {
+		return r.SendErrorEnvelope(fasthttp.StatusBadRequest, "status must be one of open, pending, snoozed or resolved", nil, "")
+	}
+	now := time.Now()
+	switch req.Status {
+	case models.ConversationStatusSnoozed:
+		if req.SnoozedUntil == nil || !req.SnoozedUntil.After(now) {
+			return r.SendErrorEnvelope(fasthttp.StatusBadRequest, "snoozed_until must be in the future", nil, "")
+		}
+		conversation.SnoozedUntil = req.SnoozedUntil
+	case models.ConversationStatusResolved:
+		if req.ResolutionReason == "" {
+			req.ResolutionReason = models.ConversationResolutionSolved
+		}
+		if !isValidResolutionReason(req.ResolutionReason) {
+			return r.SendErrorEnvelope(fasthttp.StatusBadRequest, "Invalid resolution_reason", nil, "")
+		}
+	}
+
+	// Only the latest conversation can be reopened, so a contact never has
+	// two unresolved conversations
+	if conversation.Status == models.ConversationStatusResolved && req.Status != models.ConversationStatusResolved {
+		var newer int64
+		a.DB.Model(&models.Conversation{}).
+			Where("contact_id = ? AND created_at > ?", conversation.ContactID, conversation.CreatedAt).Count(&newer)
+		if newer > 0 {
+			return r.SendErrorEnvelope(fasthttp.StatusBadRequest, "Only the contact's latest conversation can be reopened", nil, "")
+		}
+	}
+
+	if req.Status == models.ConversationStatusResolved {
+		if conversation.Status != models.ConversationStatusResolved {
+			conversation.ResolutionReason = req.ResolutionReason
+			conversation.ResolutionNote = req.ResolutionNote
+		}
+
+		// Hand the contact back to the chatbot. The conversation is only
+		// resolved once that worked, so a failure can be retried.
+		var transfer models.AgentTransfer
+		if err := a.DB.Where("organization_id = ? AND contact_id = ? AND status = ?",
+			orgID, conversation.ContactID, models.TransferStatusActive).First(&transfer).Error; err == nil {
+			if err := a.resumeTransfer(&transfer, userID); err != nil {
+				return r.SendErrorEnvelope(fasthttp.StatusInternalServerError, "Failed to resume transfer", nil, "")
+			}
+		}
+	}
+
+	if err := a.setConversationStatus(conversation, req.Status, &userID, now); err != nil {
+		return r.SendErrorEnvelope(fasthttp.StatusInternalServerError, "Failed to update conversation", nil, "")
+	}
+
+	if conversation.ResolvedByID != nil {
+		var user models.User
+		if err := a.DB.Select("id", "full_name").Where("id = ?", *conversation.ResolvedByID).First(&user).Error; err == nil {
+			conversation.ResolvedBy = &user
+		}
+	}
+	return r.SendEnvelope(conversationToResponse(conversation))
+}
+
+// isValidConversationStatus reports whether status is a conversation status
+func isValidConversationStatus(status models.ConversationStatus) bool {
+	switch status {
+	case models.ConversationStatusOpen, models.ConversationStatusPending,
+		models.ConversationStatusSnoozed, models.ConversationStatusResolved:
+		return true
+	}
+	return false
+}
+
+// isValidResolutionReason reports whether reason can be given when resolving
+// a conversation. auto_closed is only set by the auto-close settings.
+func isValidResolutionReason(reason models.ConversationResolutionReason) bool {
+	switch reason {
+	case models.ConversationResolutionSolved, models.ConversationResolutionNoResponse,
+		models.ConversationResolutionSpam, models.ConversationResolutionDuplicate,
+		models.ConversationResolutionOther:
+		return true
+	}
+	return false
+}
+
+// latestConversation returns the contact's most recent conversation, or nil
+func (a *App) latestConversation(contactID uuid.UUID) *models.Conversation {
+	var conversation models.Conversation
+	if err := a.DB.Where("contact_id = ?", contactID).Order("created_at DESC").First(&conversation).Error; err != nil {
+		return nil
+	}
+	return &conversation
+}
+
+// openConversation makes sure the contact has an open conversation. The
+// latest conversation is reopened when it is pending, snoozed or was
+// resolved within conversationReopenWindow; otherwise a new one starts.
+// userID is nil when the contact opens the conversation.
+func (a *App) openConversation(contact *models.Contact, userID *uuid.UUID, now time.Time) (*models.Conversation, error) {
+	if conversation := a.latestConversation(contact.ID); conversation != nil {
+		switch {
+		case conversation.Status == models.ConversationStatusOpen:
+			return conversation, nil
+		case conversation.Status != models.ConversationStatusResolved ||
+			(conversation.ResolvedAt != nil && now.Sub(*conversation.ResolvedAt) < conversationReopenWindow):
+			if err := a.setConversationStatus(conversation, models.ConversationStatusOpen, userID, now); err != nil {
+				return nil, err
+			}
+			return conversation, nil
+		}
+	}
+
+	conversation := &models.Conversation{
+		BaseModel:       models.BaseModel{ID: uuid.New()},
+		OrganizationID:  contact.OrganizationID,
+		ContactID:       contact.ID,
+		WhatsAppAccount: contact.WhatsAppAccount,
+		Status:          models.ConversationStatusOpen,
+		StatusChangedAt: now,
+	}
+	if err := a.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(conversation).Error; err != nil {
+			return err
+		}
+		return tx.Model(&models.Contact{}).Where("id = ?", contact.ID).Updates(map[string]any{
+			"conversation_id":     conversation.ID,
+			"conversation_status": conversation.Status,
+		}).Error
+	}); err != nil {
+		a.Log.Error("Failed to start conversation", "error", err, "contact_id", contact.ID)
+		return nil, err
+	}
+
+	a.notifyConversationStatus(conversation, "", userID)
+	return conversation, nil
+}
+
+// resolveConversationAutomatically resolves the contact's conversation with
+// the auto_closed reason, if it is not resolved yet
+func (a *App) resolveConversationAutomatically(contactID uuid.UUID, note string, now time.Time) {
+	a.resolveLatestConversation(contactID, models.ConversationResolutionAutoClosed, note, nil, now)
+}
+
+// resolveChatbotConversation resolves the contact's conversation when the
+// chatbot is done with them and no agent transfer is active, so conversations
+// the chatbot handled on its own don't stay open
+func (a *App) resolveChatbotConversation(contactID uuid.UUID, note string) {
+	var transfers int64
+	if err := a.DB.Model(&models.AgentTransfer{}).
+		Where("contact_id = ? AND status = ?", contactID, models.TransferStatusActive).
+		Count(&transfers).Error; err != nil || transfers > 0 {
+		return
+	}
+	a.resolveLatestConversation(contactID, models.ConversationResolutionSolved, note, nil, time.Now())
+}
+
+// resolveLatestConversation resolves the contact's latest conversation with
+// reason, if it is not resolved yet. userID is nil for automatic changes.
+func (a *App) resolveLatestConversation(contactID uuid.UUID, reason models.ConversationResolutionReason, note string, userID *uuid.UUID, now time.Time) {
+	conversation := a.latestConversation(contactID)
+	if conversation == nil || conversation.Status == models.ConversationStatusResolved {
+		return
+	}
+	conversation.ResolutionReason = reason
+	conversation.ResolutionNote = note
+	_ = a.setConversationStatus(conversation, models.ConversationStatusResolved, userID, now)
+}
+
+// setConversationStatus moves a conversation to status, keeps the contact's
+// conversation status in sync and notifies clients and webhooks. Callers set
+// SnoozedUntil before snoozing and the resolution reason before resolving;
+// reopening a resolved conversation clears its resolution. userID is nil for
+// automatic changes.
+func (a *App) setConversationStatus(conversation *models.Conversation, status models.ConversationStatus, userID *uuid.UUID, now time.Time) error {
+	previous := conversation.Status
+	if previous == status && status != models.ConversationStatusSnoozed {
+		return nil
+	}
+
+	if previous == models.ConversationStatusResolved {
+		conversation.ReopenCount++
+		conversation.ResolutionReason = ""
+		conversation.ResolutionNote = ""
+		conversation.ResolvedAt = nil
+		conversation.ResolvedByID = nil
+		conversation.ResolvedBy = nil
+	}
+	if status != models.ConversationStatusSnoozed {
+		conversation.SnoozedUntil = nil
+	}
+	if status == models.ConversationStatusResolved {
+		conversation.ResolvedAt = &now
+		conversation.ResolvedByID = userID
+	}
+	conversation.Status = status
+	conversation.StatusChangedAt = now
+
+	if err := a.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit("Organization", "Contact", "ResolvedBy").Save(conversation).Error; err != nil {
+			return err
+		}
+		return tx.Model(&models.Contact{}).Where("id = ?", conversation.ContactID).Updates(map[string]any{
+			"conversation_id":     conversation.ID,
+			"conversation_status": status,
+		}).Error
+	}); err != nil {
+		a.Log.Error("Failed to update conversation status", "error", err, "conversation_id", conversation.ID, "status", status)
+		return err
+	}
+
+	a.notifyConversationStatus(conversation, previous, userID)
+	return nil
+}
+
+// notifyConversationStatus broadcasts a conversation's new status and sends
+// the conversation.status_changed webhook event
+func (a *App) notifyConversationStatus(conversation *models.Conversation, previous models.ConversationStatus, userID *uuid.UUID) {
+	a.Log.Info("Conversation status changed",
+		"conversation_id", conversation.ID,
+		"contact_id", conversation.ContactID,
+		"status", conversation.Status,
+		"previous_status", previous,
+	)
+
+	if a.WSHub != nil {
+		a.WSHub.BroadcastToOrg(conversation.OrganizationID, websocket.WSMessage{
+			Type:    websocket.TypeConversationStatus,
+			Payload: conversationToResponse(conversation),
+		})
+	}
+
+	event := webhookutil.ConversationEventData{
+		ConversationID:   conversation.ID.String(),
+		ContactID:        conversation.ContactID.String(),
+		Status:           string(conversation.Status),
+		PreviousStatus:   string(previous),
+		ResolutionReason: string(conversation.ResolutionReason),
+		WhatsAppAccount:  conversation.WhatsAppAccount,
+	}
+	if conversation.SnoozedUntil != nil {
+		snoozedUntil := conversation.SnoozedUntil.Format(time.RFC3339)
+		event.SnoozedUntil = &snoozedUntil
+	}
+	if userID != nil {
+		changedBy := userID.String()
+		event.ChangedByUserID = &changedBy
+	}
+	var contact models.Contact
+	if err := a.DB.Select("phone_number", "profile_name").Where("id = ?", conversation.ContactID).
+		First(&contact).Error; err == nil {
+		event.ContactPhone = contact.PhoneNumber
+		event.ContactName = contact.ProfileName
+	}
+	a.DispatchWebhook(conversation.OrganizationID, models.WebhookEventConversationStatus, event)
+}
+
+// conversationToResponse converts a conversation to its API response
+func conversationToResponse(conversation *models.Conversation) ConversationResponse {
+	resp := ConversationResponse{
+		ID:               conversation.ID,
+		ContactID:        conversation.ContactID,
+		WhatsAppAccount:  conversation.WhatsAppAccount,
+		Status:           conversation.Status,
+		StatusChangedAt:  conversation.StatusChangedAt,
+		SnoozedUntil:     conversation.SnoozedUntil,
+		ReopenCount:      conversation.ReopenCount,
+		ResolutionReason: conversation.ResolutionReason,
+		ResolutionNote:   conversation.ResolutionNote,
+		ResolvedAt:       conversation.ResolvedAt,
+		ResolvedByID:     conversation.ResolvedByID,
+		CreatedAt:        conversation.CreatedAt,
+		UpdatedAt:        conversation.UpdatedAt,
+	}
+	if conversation.ResolvedBy != nil {
+		resp.ResolvedByName = conversation.ResolvedBy.FullName
+	}
+	return resp
+}
@@ -0,0 +1,176 @@
+package handlers
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shridarpatil/whatomate/internal/models"
+	"github.com/shridarpatil/whatomate/test/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConversationStatusValidation(t *testing.T) {
+	assert.True(t, isValidResolutionReason(models.ConversationResolutionSolved))
+	assert.True(t, isValidResolutionReason(models.ConversationResolutionSpam))
+	assert.False(t, isValidResolutionReason(models.ConversationResolutionAutoClosed), "only set by auto-close")
+	assert.False(t, isValidResolutionReason(""))
+	assert.True(t, isValidConversationStatus(models.ConversationStatusSnoozed))
+	assert.False(t, isValidConversationStatus("closed"))
+}
+
+func createTestConversation(t *testing.T, app *App, contact *models.Contact, status models.ConversationStatus, changedAt time.Time) *models.Conversation {
+	t.Helper()
+
+	conversation := &models.Conversation{
+		BaseModel:       models.BaseModel{ID: uuid.New()},
+		OrganizationID:  contact.OrganizationID,
+		ContactID:       contact.ID,
+		Status:          status,
+		StatusChangedAt: changedAt,
+	}
+	if status == models.ConversationStatusResolved {
+		conversation.ResolutionReason = models.ConversationResolutionSolved
+		conversation.ResolvedAt = &changedAt
+	}
+	if status == models.ConversationStatusSnoozed {
+		conversation.SnoozedUntil = &changedAt
+	}
+	require.NoError(t, app.DB.Create(conversation).Error)
+	return conversation
+}
+
+func TestOpenConversation(t *testing.T) {
+	app := newSLATestApp(t)
+	org := testutil.CreateTestOrganization(t, app.DB)
+	now := time.Now()
+
+	t.Run("reopens a recently resolved conversation", func(t *testing.T) {
+		contact := testutil.CreateTestContact(t, app.DB, org.ID)
+		resolved := createTestConversation(t, app, contact, models.ConversationStatusResolved, now.Add(-time.Hour))
+
+		conversation, err := app.openConversation(contact, nil, now)
+		require.NoError(t, err)
+		assert.Equal(t, resolved.ID, conversation.ID)
+		assert.Equal(t, models.ConversationStatusOpen, conversation.Status)
+		assert.Equal(t, 1, conversation.ReopenCount)
+		assert.Empty(t, conversation.ResolutionReason)
+		assert.Nil(t, conversation.ResolvedAt)
+	})
+
+	t.Run("starts a new conversation after the reopen window", func(t *testing.T) {
+		contact := testutil.CreateTestContact(t, app.DB, org.ID)
+		resolved := createTestConversation(t, app, contact, models.ConversationStatusResolved, now.Add(-48*time.Hour))
+
+		conversation, err := app.openConversation(contact, nil, now)
+		require.NoError(t, err)
+		assert.NotEqual(t, resolved.ID, conversation.ID)
+		assert.Equal(t, models.ConversationStatusOpen, conversation.Status)
+
+		var updated models.Contact
+		require.NoError(t, app.DB.Where("id = ?", contact.ID).First(&updated).Error)
+		require.NotNil(t, updated.ConversationID)
+		assert.Equal(t, conversation.ID, *updated.ConversationID)
+		assert.Equal(t, models.ConversationStatusOpen, updated.ConversationStatus)
+	})
+
+	t.Run("keeps an open conversation", func(t *testing.T) {
+		contact := testutil.CreateTestContact(t, app.DB, org.ID)
+		open := createTestConversation(t, app, contact, models.ConversationStatusOpen, now.Add(-time.Hour))
+
+		conversation, err := app.openConversation(contact, nil, now)
+		require.NoError(t, err)
+		assert.Equal(t, open.ID, conversation.ID)
+		assert.WithinDuration(t, now.Add(-time.Hour), conversation.StatusChangedAt, time.Second)
+	})
+}
+
+func TestReopenSnoozedConversations(t *testing.T) {
+	app := newSLATestApp(t)
+	org := testutil.CreateTestOrganization(t, app.DB)
+	now := time.Now()
+
+	due := createTestConversation(t, app, testutil.CreateTestContact(t, app.DB, org.ID), models.ConversationStatusSnoozed, now.Add(-time.Minute))
+	later := createTestConversation(t, app, testutil.CreateTestContact(t, app.DB, org.ID), models.ConversationStatusSnoozed, now.Add(time.Hour))
+
+	proc := NewSLAProcessor(app, time.Minute)
+	proc.reopenSnoozedConversations(now)
+
+	var updated models.Conversation
+	require.NoError(t, app.DB.Where("id = ?", due.ID).First(&updated).Error)
+	assert.Equal(t, models.ConversationStatusOpen, updated.Status)
+	assert.Nil(t, updated.SnoozedUntil)
+
+	var snoozed models.Conversation
+	require.NoError(t, app.DB.Where("id = ?", later.ID).First(&snoozed).Error)
+	assert.Equal(t, models.ConversationStatusSnoozed, snoozed.Status, "still snoozed")
+
+	var contact models.Contact
+	require.NoError(t, app.DB.Where("id = ?", due.ContactID).First(&contact).Error)
+	assert.Equal(t, models.ConversationStatusOpen, contact.ConversationStatus)
+}
+
+func TestCompleteFlow_ResolvesChatbotConversation(t *testing.T) {
+	app := newProcessorTestApp(t)
+	org, account := createProcessorTestOrg(t, app)
+	now := time.Now()
+
+	complete := func(contact *models.Contact) {
+		flow := &models.ChatbotFlow{
+			BaseModel:       models.BaseModel{ID: uuid.New()},
+			OrganizationID:  org.ID,
+			WhatsAppAccount: account.Name,
+			Name:            "Opening hours",
+			IsEnabled:       true,
+		}
+		require.NoError(t, app.DB.Create(flow).Error)
+		session := &models.ChatbotSession{
+			BaseModel:       models.BaseModel{ID: uuid.New()},
+			OrganizationID:  org.ID,
+			ContactID:       contact.ID,
+			WhatsAppAccount: account.Name,
+			PhoneNumber:     contact.PhoneNumber,
+			Status:          models.SessionStatusActive,
+			CurrentFlowID:   &flow.ID,
+			StartedAt:       now,
+			LastActivityAt:  now,
+		}
+		require.NoError(t, app.DB.Create(session).Error)
+		app.completeFlow(account, session, contact, flow)
+	}
+
+	t.Run("resolved when the chatbot handled the contact alone", func(t *testing.T) {
+		contact := testutil.CreateTestContact(t, app.DB, org.ID)
+		conversation := createTestConversation(t, app, contact, models.ConversationStatusOpen, now)
+
+		complete(contact)
+
+		var updated models.Conversation
+		require.NoError(t, app.DB.Where("id = ?", conversation.ID).First(&updated).Error)
+		assert.Equal(t, models.ConversationStatusResolved, updated.Status)
+		assert.Equal(t, models.ConversationResolutionSolved, updated.ResolutionReason)
+		assert.Nil(t, updated.ResolvedByID)
+	})
+
+	t.Run("kept open while the contact is transferred", func(t *testing.T) {
+		contact := testutil.CreateTestContact(t, app.DB, org.ID)
+		conversation := createTestConversation(t, app, contact, models.ConversationStatusOpen, now)
+		require.NoError(t, app.DB.Create(&models.AgentTransfer{
+			BaseModel:       models.BaseModel{ID: uuid.New()},
+			OrganizationID:  org.ID,
+			ContactID:       contact.ID,
+			WhatsAppAccount: account.Name,
+			PhoneNumber:     contact.PhoneNumber,
+			Status:          models.TransferStatusActive,
+			Source:          models.TransferSourceFlow,
+			TransferredAt:   now,
+		}).Error)
+
+		complete(contact)
+
+		var updated models.Conversation
+		require.NoError(t, app.DB.Where("id = ?", conversation.ID).First(&updated).Error)
+		assert.Equal(t, models.ConversationStatusOpen, updated.Status)
+	})
+}
@@ -0,0 +1,233 @@
+package handlers_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shridarpatil/whatomate/internal/handlers"
+	"github.com/shridarpatil/whatomate/internal/models"
+	"github.com/shridarpatil/whatomate/test/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/valyala/fasthttp"
+	"github.com/zerodha/fastglue"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func openTestConversation(t *testing.T, app *handlers.App, orgID, userID, contactID uuid.UUID) handlers.ConversationResponse {
+	t.Helper()
+
+	req := testutil.NewJSONRequest(t, nil)
+	testutil.SetAuthContext(req, orgID, userID)
+	testutil.SetPathParam(req, "id", contactID.String())
+	require.NoError(t, app.OpenContactConversation(req))
+	require.Equal(t, fasthttp.StatusOK, testutil.GetResponseStatusCode(req))
+
+	var resp handlers.ConversationResponse
+	testutil.ParseEnvelopeResponse(t, req, &resp)
+	return resp
+}
+
+func updateTestConversationStatus(t *testing.T, app *handlers.App, orgID, userID, conversationID uuid.UUID, body map[string]any) *fastglue.Request {
+	t.Helper()
+
+	req := testutil.NewJSONRequest(t, body)
+	testutil.SetAuthContext(req, orgID, userID)
+	testutil.SetPathParam(req, "id", conversationID.String())
+	require.NoError(t, app.UpdateConversationStatus(req))
+	return req
+}
+
+func TestApp_UpdateConversationStatus(t *testing.T) {
+	t.Parallel()
+
+	t.Run("resolve resumes the active transfer", func(t *testing.T) {
+		app := newTestApp(t)
+		org := testutil.CreateTestOrganization(t, app.DB)
+		admin := createAdminUser(t, app, org.ID)
+		account := testutil.CreateTestWhatsAppAccount(t, app.DB, org.ID)
+		contact := testutil.CreateTestContact(t, app.DB, org.ID)
+		transfer := createTestTransfer(t, app, org.ID, contact.ID, account.Name, models.TransferStatusActive, &admin.ID)
+		conversation := openTestConversation(t, app, org.ID, admin.ID, contact.ID)
+
+		req := updateTestConversationStatus(t, app, org.ID, admin.ID, conversation.ID, map[string]any{
+			"status":            "resolved",
+			"resolution_reason": "spam",
+			"resolution_note":   "Promotional messages",
+		})
+		require.Equal(t, fasthttp.StatusOK, testutil.GetResponseStatusCode(req))
+
+		var resp handlers.ConversationResponse
+		testutil.ParseEnvelopeResponse(t, req, &resp)
+		assert.Equal(t, models.ConversationStatusResolved, resp.Status)
+		assert.Equal(t, models.ConversationResolutionSpam, resp.ResolutionReason)
+		require.NotNil(t, resp.ResolvedByID)
+		assert.Equal(t, admin.ID, *resp.ResolvedByID)
+
+		var updated models.AgentTransfer
+		require.NoError(t, app.DB.Where("id = ?", transfer.ID).First(&updated).Error)
+		assert.Equal(t, models.TransferStatusResumed, updated.Status)
+
+		var updatedContact models.Contact
+		require.NoError(t, app.DB.Where("id = ?", contact.ID).First(&updatedContact).Error)
+		assert.Equal(t, models.ConversationStatusResolved, updatedContact.ConversationStatus)
+	})
+
+	t.Run("resolve keeps the conversation open when the transfer can't be resumed", func(t *testing.T) {
+		app := newTestApp(t)
+		org := testutil.CreateTestOrganization(t, app.DB)
+		admin := createAdminUser(t, app, org.ID)
+		account := testutil.CreateTestWhatsAppAccount(t, app.DB, org.ID)
+		contact := testutil.CreateTestContact(t, app.DB, org.ID)
+		transfer := createTestTransfer(t, app, org.ID, contact.ID, account.Name, models.TransferStatusActive, &admin.ID)
+		conversation := openTestConversation(t, app, org.ID, admin.ID, contact.ID)
+
+		// Fail every update of a transfer, on a connection of this test only
+		sqlDB, err := app.DB.DB()
+		require.NoError(t, err)
+		db, err := gorm.Open(postgres.New(postgres.Config{Conn: sqlDB}), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+		require.NoError(t, err)
+		require.NoError(t, db.Callback().Update().Before("gorm:update").Register("test:fail_transfer_update", func(tx *gorm.DB) {
+			if tx.Statement.Schema != nil && tx.Statement.Schema.Table == "agent_transfers" {
+				_ = tx.AddError(errors.New("update failed"))
+			}
+		}))
+		healthyDB := app.DB
+		app.DB = db
+
+		req := updateTestConversationStatus(t, app, org.ID, admin.ID, conversation.ID, map[string]any{"status": "resolved"})
+		testutil.AssertErrorResponse(t, req, fasthttp.StatusInternalServerError, "Failed to resume transfer")
+
+		var unchanged models.Conversation
+		require.NoError(t, healthyDB.Where("id = ?", conversation.ID).First(&unchanged).Error)
+		assert.Equal(t, models.ConversationStatusOpen, unchanged.Status)
+		var active models.AgentTransfer
+		require.NoError(t, healthyDB.Where("id = ?", transfer.ID).First(&active).Error)
+		assert.Equal(t, models.TransferStatusActive, active.Status)
+
+		// Once the transfer can be resumed, retrying resolves the conversation
+		app.DB = healthyDB
+		req = updateTestConversationStatus(t, app, org.ID, admin.ID, conversation.ID, map[string]any{"status": "resolved"})
+		require.Equal(t, fasthttp.StatusOK, testutil.GetResponseStatusCode(req))
+		require.NoError(t, app.DB.Where("id = ?", transfer.ID).First(&active).Error)
+		assert.Equal(t, models.TransferStatusResumed, active.Status)
+	})
+
+	t.Run("snooze", func(t *testing.T) {
+		app := newTestApp(t)
+		org := testutil.CreateTestOrganization(t, app.DB)
+		admin := createAdminUser(t, app, org.ID)
+		contact := testutil.CreateTestContact(t, app.DB, org.ID)
+		conversation := openTestConversation(t, app, org.ID, admin.ID, contact.ID)
+
+		until := time.Now().Add(2 * time.Hour).UTC().Truncate(time.Second)
+		req := updateTestConversationStatus(t, app, org.ID, admin.ID, conversation.ID, map[string]any{
+			"status":        "snoozed",
+			"snoozed_until": until,
+		})
+		require.Equal(t, fasthttp.StatusOK, testutil.GetResponseStatusCode(req))
+
+		var resp handlers.ConversationResponse
+		testutil.ParseEnvelopeResponse(t, req, &resp)
+		assert.Equal(t, models.ConversationStatusSnoozed, resp.Status)
+		require.NotNil(t, resp.SnoozedUntil)
+		assert.True(t, until.Equal(*resp.SnoozedUntil))
+	})
+
+	t.Run("validation", func(t *testing.T) {
+		app := newTestApp(t)
+		org := testutil.CreateTestOrganization(t, app.DB)
+		admin := createAdminUser(t, app, org.ID)
+		contact := testutil.CreateTestContact(t, app.DB, org.ID)
+		conversation := openTestConversation(t, app, org.ID, admin.ID, contact.ID)
+
+		for _, tc := range []struct {
+			body    map[string]any
+			message string
+		}{
+			{map[string]any{"status": "closed"}, "status must be one of open, pending, snoozed or resolved"},
+			{map[string]any{"status": "snoozed"}, "snoozed_until must be in the future"},
+			{map[string]any{"status": "snoozed", "snoozed_until": time.Now().Add(-time.Hour)}, "snoozed_until must be in the future"},
+			{map[string]any{"status": "resolved", "resolution_reason": "auto_closed"}, "Invalid resolution_reason"},
+		} {
+			req := updateTestConversationStatus(t, app, org.ID, admin.ID, conversation.ID, tc.body)
+			testutil.AssertErrorResponse(t, req, fasthttp.StatusBadRequest, tc.message)
+		}
+	})
+}
+
+func TestApp_ResumeFromTransfer_ResolvesConversation(t *testing.T) {
+	t.Parallel()
+
+	app := newTestApp(t)
+	org := testutil.CreateTestOrganization(t, app.DB)
+	admin := createAdminUser(t, app, org.ID)
+	account := testutil.CreateTestWhatsAppAccount(t, app.DB, org.ID)
+	contact := testutil.CreateTestContact(t, app.DB, org.ID)
+	transfer := createTestTransfer(t, app, org.ID, contact.ID, account.Name, models.TransferStatusActive, &admin.ID)
+	conversation := openTestConversation(t, app, org.ID, admin.ID, contact.ID)
+
+	req := testutil.NewJSONRequest(t, nil)
+	testutil.SetAuthContext(req, org.ID, admin.ID)
+	testutil.SetPathParam(req, "id", transfer.ID.String())
+	require.NoError(t, app.ResumeFromTransfer(req))
+	require.Equal(t, fasthttp.StatusOK, testutil.GetResponseStatusCode(req))
+
+	var updated models.Conversation
+	require.NoError(t, app.DB.Where("id = ?", conversation.ID).First(&updated).Error)
+	assert.Equal(t, models.ConversationStatusResolved, updated.Status)
+	assert.Equal(t, models.ConversationResolutionSolved, updated.ResolutionReason)
+	require.NotNil(t, updated.ResolvedByID)
+	assert.Equal(t, admin.ID, *updated.ResolvedByID)
+
+	var updatedContact models.Contact
+	require.NoError(t, app.DB.Where("id = ?", contact.ID).First(&updatedContact).Error)
+	assert.Equal(t, models.ConversationStatusResolved, updatedContact.ConversationStatus)
+}
+
+func TestApp_ListContacts_ConversationStatus(t *testing.T) {
+	t.Parallel()
+
+	app := newTestApp(t)
+	org := testutil.CreateTestOrganization(t, app.DB)
+	admin := createAdminUser(t, app, org.ID)
+	pending := testutil.CreateTestContact(t, app.DB, org.ID)
+	open := testutil.CreateTestContact(t, app.DB, org.ID)
+	testutil.CreateTestContact(t, app.DB, org.ID)
+
+	conversation := openTestConversation(t, app, org.ID, admin.ID, pending.ID)
+	req := updateTestConversationStatus(t, app, org.ID, admin.ID, conversation.ID, map[string]any{"status": "pending"})
+	require.Equal(t, fasthttp.StatusOK, testutil.GetResponseStatusCode(req))
+	openTestConversation(t, app, org.ID, admin.ID, open.ID)
+
+	list := func(status string) []handlers.ContactResponse {
+		req := testutil.NewGETRequest(t)
+		testutil.SetAuthContext(req, org.ID, admin.ID)
+		testutil.SetQueryParam(req, "conversation_status", status)
+		require.NoError(t, app.ListContacts(req))
+		require.Equal(t, fasthttp.StatusOK, testutil.GetResponseStatusCode(req))
+
+		var resp struct {
+			Contacts []handlers.ContactResponse `json:"contacts"`
+		}
+		testutil.ParseEnvelopeResponse(t, req, &resp)
+		return resp.Contacts
+	}
+
+	contacts := list("pending")
+	require.Len(t, contacts, 1)
+	assert.Equal(t, pending.ID, contacts[0].ID)
+	assert.Equal(t, models.ConversationStatusPending, contacts[0].ConversationStatus)
+
+	assert.Len(t, list("open,pending"), 2)
+
+	req = testutil.NewGETRequest(t)
+	testutil.SetAuthContext(req, org.ID, admin.ID)
+	testutil.SetQueryParam(req, "conversation_status", "closed")
+	require.NoError(t, app.ListContacts(req))
+	testutil.AssertErrorResponse(t, req, fasthttp.StatusBadRequest, "Invalid conversation_status")
+}
@@ -0,0 +1,192 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/shridarpatil/whatomate/internal/contactutil"
+	"github.com/shridarpatil/whatomate/internal/models"
+	"github.com/shridarpatil/whatomate/internal/templateutil"
+	"github.com/shridarpatil/whatomate/pkg/whatsapp"
+)
+
+// Template button types that take parameters
+const (
+	templateButtonQuickReply = "QUICK_REPLY"
+	templateButtonURL        = "URL"
+)
+
+// resolveTemplateStepParams fills the parameters found in content. Configured
+// values (by name, or by position for positional parameters) are rendered
+// against data, so they may reference session variables and contact fields;
+// parameters without a configured value use the session variable of the same name.
+func resolveTemplateStepParams(content string, configured map[string]interface{}, data map[string]interface{}) map[string]string {
+	names := templateutil.ExtParamNames(content)
+	params := make(map[string]string, len(names))
+	for i, name := range names {
+		raw, ok := configured[name]
+		if !ok {
+			raw, ok = configured[strconv.Itoa(i+1)]
+		}
+		if ok && raw != nil {
+			params[name] = processTemplate(fmt.Sprintf("%v", raw), data)
+			continue
+		}
+		if v, ok := data[name]; ok && v != nil {
+			params[name] = fmt.Sprintf("%v", v)
+		}
+	}
+	return params
+}
+
+// templateStepButtonParams builds the button parameters of a template step from
+// its "button_params" config, keyed by button index. Quick replies without a
+// configured payload are left out so WhatsApp returns the button text instead.
+func templateStepButtonParams(template *models.Template, config map[string]interface{}, data map[string]interface{}) ([]whatsapp.TemplateButtonParam, error) {
+	configured, _ := config["button_params"].(map[string]interface{})
+
+	var params []whatsapp.TemplateButtonParam
+	for i, btn := range template.Buttons {
+		btnMap, ok := btn.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		btnType, _ := btnMap["type"].(string)
+		raw, hasValue := configured[strconv.Itoa(i)]
+		value := ""
+		if hasValue && raw != nil {
+			value = processTemplate(fmt.Sprintf("%v", raw), data)
+		}
+
+		switch strings.ToUpper(btnType) {
+		case templateButtonQuickReply:
+			if value != "" {
+				params = append(params, whatsapp.TemplateButtonParam{Index: i, SubType: whatsapp.ButtonSubTypeQuickReply, Value: value})
+			}
+		case templateButtonURL:
+			btnURL, _ := btnMap["url"].(string)
+			if !strings.Contains(btnURL, "{{") {
+				continue
+			}
+			if value == "" {
+				return nil, fmt.Errorf("missing parameter for URL button %d", i)
+			}
+			params = append(params, whatsapp.TemplateButtonParam{Index: i, SubType: whatsapp.ButtonSubTypeURL, Value: value})
+		}
+	}
+	return params, nil
+}
+
+// templateStepQuickReplies returns the template's quick reply buttons as step
+// buttons ({id, title}), where the id is the payload WhatsApp sends back when
+// the button is tapped. This lets template steps route replies through
+// ConditionalNext the same way interactive button steps do.
+func templateStepQuickReplies(template *models.Template, buttonParams []whatsapp.TemplateButtonParam) models.JSONBArray {
+	payloads := make(map[int]string, len(buttonParams))
+	for _, bp := range buttonParams {
+		if bp.SubType == whatsapp.ButtonSubTypeQuickReply {
+			payloads[bp.Index] = bp.Value
+		}
+	}
+
+	var buttons models.JSONBArray
+	for i, btn := range template.Buttons {
+		btnMap, ok := btn.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		btnType, _ := btnMap["type"].(string)
+		text, _ := btnMap["text"].(string)
+		if !strings.EqualFold(btnType, templateButtonQuickReply) || text == "" {
+			continue
+		}
+		id := text
+		if payload, ok := payloads[i]; ok {
+			id = payload
+		}
+		buttons = append(buttons, map[string]interface{}{"id": id, "title": text})
+	}
+	return buttons
+}
+
+// loadTemplateStepTemplate loads the approved template linked to a template
+// step, in the session's language when that variant of the template exists
+func (a *App) loadTemplateStepTemplate(orgID uuid.UUID, step *models.ChatbotFlowStep, lang string) (*models.Template, error) {
+	if step.TemplateID == nil {
+		return nil, errors.New("template step has no template_id")
+	}
+	var template models.Template
+	if err := a.DB.Where("id = ? AND organization_id = ?", *step.TemplateID, orgID).First(&template).Error; err != nil {
+		return nil, fmt.Errorf("template not found: %w", err)
+	}
+	if template.Status != string(models.TemplateStatusApproved) {
+		return nil, fmt.Errorf("template %s is not approved (status: %s)", template.Name, template.Status)
+	}
+	return contactutil.TemplateInLanguage(a.DB, &template, lang), nil
+}
+
+// templateStepButtons returns the quick reply buttons a template step accepts
+// as replies, or nil if the template can't be loaded
+func (a *App) templateStepButtons(account *models.WhatsAppAccount, session *models.ChatbotSession, contact *models.Contact, step *models.ChatbotFlowStep) models.JSONBArray {
+	template, err := a.loadTemplateStepTemplate(account.OrganizationID, step, session.Language)
+	if err != nil {
+		a.Log.Warn("Failed to load template for step", "error", err, "step", step.StepName)
+		return nil
+	}
+	// URL buttons don't affect routing, so a missing URL parameter is not an error here
+	buttonParams, _ := templateStepButtonParams(template, step.TemplateConfig, keywordConditionData(contact, session))
+	return templateStepQuickReplies(template, buttonParams)
+}
+
+// sendTemplateStep sends the template linked to a flow step, filling its body,
+// header and button parameters from the session. Returns the rendered body.
+func (a *App) sendTemplateStep(account *models.WhatsAppAccount, session *models.ChatbotSession, contact *models.Contact, step *models.ChatbotFlowStep) (string, error) {
+	template, err := a.loadTemplateStepTemplate(account.OrganizationID, step, session.Language)
+	if err != nil {
+		return "", err
+	}
+
+	config := map[string]interface{}(step.TemplateConfig)
+	data := keywordConditionData(contact, session)
+
+	configuredBody, _ := config["params"].(map[string]interface{})
+	bodyParams := resolveTemplateStepParams(template.BodyContent, configuredBody, data)
+	if missing := missingTemplateParams(template, bodyParams); len(missing) > 0 {
+		return "", fmt.Errorf("missing template parameters: %s", strings.Join(missing, ", "))
+	}
+
+	buttonParams, err := templateStepButtonParams(template, config, data)
+	if err != nil {
+		return "", err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), keywordSendTimeout)
+	defer cancel()
+
+	req := OutgoingMessageRequest{
+		Account:      account,
+		Contact:      contact,
+		Type:         models.MessageTypeTemplate,
+		Template:     template,
+		BodyParams:   bodyParams,
+		ButtonParams: buttonParams,
+	}
+	switch {
+	case template.HeaderType == "TEXT":
+		configuredHeader, _ := config["header_params"].(map[string]interface{})
+		req.HeaderParams = resolveTemplateStepParams(template.HeaderContent, configuredHeader, data)
+	case isMediaHeader(template.HeaderType):
+		if err := a.loadKeywordMedia(ctx, config, "header_media_url", "header_media_key", &req); err != nil {
+			return "", err
+		}
+	}
+
+	if _, err := a.SendOutgoingMessage(ctx, req, ChatbotSendOptions()); err != nil {
+		return "", err
+	}
+	return templateutil.ReplaceWithStringParams(template.BodyContent, bodyParams), nil
+}
@@ -0,0 +1,184 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shridarpatil/whatomate/internal/models"
+	"github.com/shridarpatil/whatomate/pkg/whatsapp"
+	"github.com/shridarpatil/whatomate/test/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResolveTemplateStepParams(t *testing.T) {
+	data := map[string]interface{}{
+		"order_id": "A-42",
+		"contact":  map[string]interface{}{"name": "Alice"},
+	}
+
+	named := resolveTemplateStepParams("Hi {{name}}, order {{order_id}} ships {{when}}",
+		map[string]interface{}{"name": "{{contact.name}}"}, data)
+	assert.Equal(t, map[string]string{"name": "Alice", "order_id": "A-42"}, named,
+		"configured values are rendered, others come from session variables of the same name")
+
+	positional := resolveTemplateStepParams("Hi {{1}}, order {{2}}",
+		map[string]interface{}{"1": "{{contact.name}}", "2": "{{order_id}}"}, data)
+	assert.Equal(t, map[string]string{"1": "Alice", "2": "A-42"}, positional)
+
+	assert.Empty(t, resolveTemplateStepParams("No params", nil, data))
+}
+
+func TestTemplateStepButtonParams(t *testing.T) {
+	template := &models.Template{Buttons: models.JSONBArray{
+		map[string]interface{}{"type": "QUICK_REPLY", "text": "Yes"},
+		map[string]interface{}{"type": "QUICK_REPLY", "text": "No"},
+		map[string]interface{}{"type": "URL", "text": "Track", "url": "https://shop.test/track/{{1}}"},
+		map[string]interface{}{"type": "URL", "text": "Shop", "url": "https://shop.test"},
+	}}
+	data := map[string]interface{}{"order_id": "A-42"}
+
+	params, err := templateStepButtonParams(template, map[string]interface{}{
+		"button_params": map[string]interface{}{"0": "confirm", "2": "{{order_id}}"},
+	}, data)
+	require.NoError(t, err)
+	assert.Equal(t, []whatsapp.TemplateButtonParam{
+		{Index: 0, SubType: whatsapp.ButtonSubTypeQuickReply, Value: "confirm"},
+		{Index: 2, SubType: whatsapp.ButtonSubTypeURL, Value: "A-42"},
+	}, params)
+
+	_, err = templateStepButtonParams(template, nil, data)
+	assert.ErrorContains(t, err, "URL button 2", "dynamic URL buttons need a parameter")
+
+	buttons := templateStepQuickReplies(template, params)
+	assert.Equal(t, models.JSONBArray{
+		map[string]interface{}{"id": "confirm", "title": "Yes"},
+		map[string]interface{}{"id": "No", "title": "No"},
+	}, buttons, "quick replies without a payload are matched by their text")
+}
+
+func TestProcessFlowResponse_TemplateStepRoutesOnQuickReply(t *testing.T) {
+	app := newProcessorTestApp(t)
+	org, account := createProcessorTestOrg(t, app)
+	contact := testutil.CreateTestContact(t, app.DB, org.ID)
+
+	// Capture the messages sent to the WhatsApp API
+	var sentRequests []map[string]interface{}
+	waServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]interface{}
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		sentRequests = append(sentRequests, body)
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"messages": []map[string]string{{"id": "wamid.mock_" + uuid.New().String()[:8]}},
+		})
+	}))
+	t.Cleanup(waServer.Close)
+	app.WhatsApp = whatsapp.NewWithBaseURL(app.Log, waServer.URL)
+
+	template := testutil.CreateTestTemplate(t, app.DB, org.ID, account.Name)
+	template.Category = "UTILITY"
+	template.BodyContent = "Hi {{1}}, is your order {{order_id}} correct?"
+	template.Buttons = models.JSONBArray{
+		map[string]interface{}{"type": "QUICK_REPLY", "text": "Yes"},
+		map[string]interface{}{"type": "QUICK_REPLY", "text": "No"},
+	}
+	require.NoError(t, app.DB.Save(template).Error)
+
+	flowID := uuid.New()
+	flow := &models.ChatbotFlow{
+		BaseModel:       models.BaseModel{ID: flowID},
+		OrganizationID:  org.ID,
+		WhatsAppAccount: account.Name,
+		Name:            "Template Flow",
+		IsEnabled:       true,
+		Steps: []models.ChatbotFlowStep{
+			{
+				BaseModel:   models.BaseModel{ID: uuid.New()},
+				FlowID:      flowID,
+				StepName:    "ask_order",
+				StepOrder:   1,
+				Message:     "What is your order number?",
+				MessageType: models.FlowStepTypeText,
+				InputType:   models.InputTypeText,
+				StoreAs:     "order_id",
+				NextStep:    "confirm",
+			},
+			{
+				BaseModel:      models.BaseModel{ID: uuid.New()},
+				FlowID:         flowID,
+				StepName:       "confirm",
+				StepOrder:      2,
+				MessageType:    models.FlowStepTypeTemplate,
+				TemplateID:     &template.ID,
+				TemplateConfig: models.JSONB{"params": map[string]interface{}{"1": "{{contact.name}}"}, "button_params": map[string]interface{}{"0": "order_ok"}},
+				InputType:      models.InputTypeButton,
+				ConditionalNext: models.JSONB{
+					"order_ok": "thanks",
+					"No":       "fix",
+				},
+				MaxRetries: 3,
+			},
+			{BaseModel: models.BaseModel{ID: uuid.New()}, FlowID: flowID, StepName: "thanks", StepOrder: 3, Message: "Thanks!", MessageType: models.FlowStepTypeText, InputType: models.InputTypeText},
+			{BaseModel: models.BaseModel{ID: uuid.New()}, FlowID: flowID, StepName: "fix", StepOrder: 4, Message: "What should we change?", MessageType: models.FlowStepTypeText, InputType: models.InputTypeText},
+		},
+	}
+	require.NoError(t, app.DB.Create(flow).Error)
+
+	session := &models.ChatbotSession{
+		BaseModel:       models.BaseModel{ID: uuid.New()},
+		OrganizationID:  org.ID,
+		ContactID:       contact.ID,
+		WhatsAppAccount: account.Name,
+		PhoneNumber:     contact.PhoneNumber,
+		Status:          models.SessionStatusActive,
+		SessionData:     models.JSONB{},
+		StartedAt:       time.Now(),
+		LastActivityAt:  time.Now(),
+	}
+	require.NoError(t, app.DB.Create(session).Error)
+
+	app.startFlow(account, session, contact, flow)
+
+	// The order number is stored in the session and fills the template's
+	// {{order_id}} parameter
+	app.processFlowResponse(account, session, contact, "A-42", "", nil)
+
+	var sent models.Message
+	require.NoError(t, app.DB.Where("contact_id = ? AND message_type = ?", contact.ID, models.MessageTypeTemplate).First(&sent).Error)
+	assert.Equal(t, template.Name, sent.TemplateName)
+
+	require.NotEmpty(t, sentRequests)
+	templateRequest, _ := sentRequests[len(sentRequests)-1]["template"].(map[string]interface{})
+	require.NotNil(t, templateRequest, "the last message sent is the template")
+	var bodyParams []string
+	components, _ := templateRequest["components"].([]interface{})
+	for _, c := range components {
+		component, _ := c.(map[string]interface{})
+		if component["type"] != "body" {
+			continue
+		}
+		params, _ := component["parameters"].([]interface{})
+		for _, p := range params {
+			param, _ := p.(map[string]interface{})
+			text, _ := param["text"].(string)
+			bodyParams = append(bodyParams, text)
+		}
+	}
+	assert.Equal(t, []string{contact.ProfileName, "A-42"}, bodyParams)
+
+	// A reply that is not one of the template's quick replies is rejected
+	app.processFlowResponse(account, session, contact, "maybe", "", nil)
+	var dbSession models.ChatbotSession
+	require.NoError(t, app.DB.First(&dbSession, session.ID).Error)
+	assert.Equal(t, "confirm", dbSession.CurrentStep)
+	assert.Equal(t, 1, dbSession.StepRetries)
+
+	// Tapping "Yes" returns its configured payload, which routes via ConditionalNext
+	app.processFlowResponse(account, &dbSession, contact, "Yes", "order_ok", nil)
+	require.NoError(t, app.DB.First(&dbSession, session.ID).Error)
+	assert.Equal(t, "thanks", dbSession.CurrentStep)
+}
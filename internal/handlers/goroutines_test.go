@@ -315,13 +315,9 @@ func TestApp_DispatchWebhook_RetryOnFailure(t *testing.T) {
 
 	var requestCount atomic.Int32
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		count := requestCount.Add(1)
-		if count < 3 {
-			// Fail first 2 requests
-			w.WriteHeader(http.StatusInternalServerError)
-			return
-		}
-		w.WriteHeader(http.StatusOK)
+		requestCount.Add(1)
+		w.WriteHeader(http.StatusInternalServerError)
+		_, _ = w.Write([]byte("upstream down"))
 	}))
 	defer server.Close()
 
@@ -338,8 +334,17 @@ func TestApp_DispatchWebhook_RetryOnFailure(t *testing.T) {
 	app.DispatchWebhook(org.ID, models.WebhookEventMessageIncoming, map[string]string{"test": "data"})
 	app.WaitForBackgroundTasks()
 
-	// Should have retried (3 attempts total)
-	assert.Equal(t, int32(3), requestCount.Load(), "should retry on failure")
+	// One attempt is made in-process; the retry is left to the workers
+	assert.Equal(t, int32(1), requestCount.Load())
+
+	var delivery models.WebhookDelivery
+	require.NoError(t, app.DB.Where("webhook_id = ?", webhook.ID).First(&delivery).Error)
+	assert.Equal(t, models.WebhookDeliveryPending, delivery.Status)
+	assert.Equal(t, 1, delivery.Attempts)
+	assert.Equal(t, http.StatusInternalServerError, delivery.ResponseStatus)
+	assert.Equal(t, "upstream down", delivery.ResponseBody)
+	require.NotNil(t, delivery.NextAttemptAt)
+	assert.True(t, delivery.NextAttemptAt.After(time.Now()), "retry should be scheduled in the future")
 }
 
 func TestApp_DispatchWebhook_HTTPTimeout(t *testing.T) {
@@ -44,7 +44,7 @@ var exportConfigs = map[string]ExportConfig{
 		Resource: "contacts",
 		AllowedColumns: []string{
 			"phone_number", "profile_name", "whats_app_account", "tags",
-			"assigned_user_id", "last_message_at", "created_at", "updated_at",
+			"assigned_user_id", "last_message_at", "consent_status", "created_at", "updated_at",
 		},
 		DefaultColumns: []string{"phone_number", "profile_name", "tags"},
 		ColumnLabels: map[string]string{
@@ -54,6 +54,7 @@ var exportConfigs = map[string]ExportConfig{
 			"tags":              "Tags",
 			"assigned_user_id":  "Assigned User ID",
 			"last_message_at":   "Last Message At",
+			"consent_status":    "Consent Status",
 			"created_at":        "Created At",
 			"updated_at":        "Updated At",
 		},
@@ -117,6 +118,35 @@ var exportConfigs = map[string]ExportConfig{
 			"created_at":  "Created At",
 		},
 	},
+	"consent_events": {
+		Model:    &models.ConsentEvent{},
+		Resource: "contacts",
+		AllowedColumns: []string{
+			"phone_number", "status", "previous_status", "source", "keyword", "reason",
+			"whats_app_account", "user_id", "message_id", "created_at",
+		},
+		DefaultColumns: []string{"phone_number", "status", "previous_status", "source", "keyword", "created_at"},
+		ColumnLabels: map[string]string{
+			"phone_number":      "Phone Number",
+			"status":            "Status",
+			"previous_status":   "Previous Status",
+			"source":            "Source",
+			"keyword":           "Keyword",
+			"reason":            "Reason",
+			"whats_app_account": "WhatsApp Account",
+			"user_id":           "User ID",
+			"message_id":        "Message ID",
+			"created_at":        "Created At",
+		},
+		ColumnTransform: map[string]func(interface{}) string{
+			"created_at": func(v interface{}) string {
+				if t, ok := v.(time.Time); ok {
+					return t.Format(time.RFC3339)
+				}
+				return ""
+			},
+		},
+	},
 }
 
 var importConfigs = map[string]ImportConfig{
@@ -225,9 +255,15 @@ func (a *App) ExportData(r *fastglue.Request) error {
 			query = query.Where("phone_number LIKE ? OR profile_name ILIKE ?", searchPattern, searchPattern)
 		case "tags":
 			query = query.Where("name ILIKE ? OR description ILIKE ?", searchPattern, searchPattern)
+		case "consent_events":
+			query = query.Where("phone_number LIKE ? OR keyword ILIKE ?", searchPattern, searchPattern)
 		}
 	}
 
+	if status, ok := req.Filters["status"]; ok && status != "" && req.Table == "consent_events" {
+		query = query.Where("status = ?", status)
+	}
+
 	if tags, ok := req.Filters["tags"]; ok && tags != "" {
 		tagList := strings.Split(tags, ",")
 		conditions := make([]string, 0, len(tagList))
@@ -313,8 +349,8 @@ func (a *App) ExportData(r *fastglue.Request) error {
 				csvRow[i] = formatExportValue(val, colTypes[i+1])
 			}
 		}
-		// Apply phone masking for contacts export
-		if req.Table == "contacts" && a.ShouldMaskPhoneNumbers(orgID) {
+		// Apply phone masking for contact and consent exports
+		if (req.Table == "contacts" || req.Table == "consent_events") && a.ShouldMaskPhoneNumbers(orgID) {
 			for i, col := range safeColumns {
 				switch col {
 				case "phone_number":
@@ -2,6 +2,7 @@ package handlers
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -11,7 +12,9 @@ import (
 	"strings"
 
 	"github.com/google/uuid"
+	"github.com/shridarpatil/whatomate/internal/calling"
 	"github.com/shridarpatil/whatomate/internal/models"
+	"github.com/shridarpatil/whatomate/internal/storage"
 	"github.com/valyala/fasthttp"
 	"github.com/zerodha/fastglue"
 )
@@ -293,6 +296,33 @@ func (a *App) getAudioDir() string {
 	return dir
 }
 
+// remoteAudioStorage returns the media storage backend audio files are
+// mirrored to, or nil when media is stored on the local disk.
+func (a *App) remoteAudioStorage() storage.Backend {
+	if a.Storage == nil {
+		return nil
+	}
+	if _, ok := a.Storage.(*storage.Local); ok {
+		return nil
+	}
+	return a.Storage
+}
+
+// mirrorAudioFile copies an audio file from the audio directory to media
+// storage so call servers on other hosts can fetch it.
+func (a *App) mirrorAudioFile(ctx context.Context, filename string) error {
+	backend := a.remoteAudioStorage()
+	if backend == nil {
+		return nil
+	}
+	f, err := os.Open(filepath.Join(a.getAudioDir(), filename))
+	if err != nil {
+		return err
+	}
+	defer func() { _ = f.Close() }()
+	return backend.Upload(ctx, calling.AudioStorageKey(filename), f, "audio/ogg")
+}
+
 // UploadIVRAudio handles multipart audio file uploads for IVR greetings.
 func (a *App) UploadIVRAudio(r *fastglue.Request) error {
 	_, userID, err := a.getOrgAndUserID(r)
@@ -394,6 +424,11 @@ func (a *App) UploadIVRAudio(r *fastglue.Request) error {
 		return r.SendErrorEnvelope(fasthttp.StatusInternalServerError, "Failed to transcode audio to Opus format", nil, "")
 	}
 
+	if err := a.mirrorAudioFile(r.RequestCtx, filename); err != nil {
+		a.Log.Error("Failed to store IVR audio", "error", err, "filename", filename)
+		return r.SendErrorEnvelope(fasthttp.StatusInternalServerError, "Failed to store audio file", nil, "")
+	}
+
 	a.Log.Info("IVR audio uploaded", "filename", filename, "original_mime", mimeType, "size", len(data))
 
 	return r.SendEnvelope(map[string]any{
@@ -431,6 +466,10 @@ func (a *App) ServeIVRAudio(r *fastglue.Request) error {
 	// Reject symlinks
 	info, err := os.Lstat(fullPath)
 	if err != nil {
+		// Uploaded on another host - serve it from media storage
+		if a.remoteAudioStorage() != nil {
+			return a.serveStoredMedia(r, calling.AudioStorageKey(filename), "audio/ogg")
+		}
 		return r.SendErrorEnvelope(fasthttp.StatusNotFound, "File not found", nil, "")
 	}
 	if info.Mode()&os.ModeSymlink != 0 {
@@ -544,6 +583,11 @@ func (a *App) UploadOrgAudio(r *fastglue.Request) error {
 		return r.SendErrorEnvelope(fasthttp.StatusInternalServerError, "Failed to transcode audio to Opus format", nil, "")
 	}
 
+	if err := a.mirrorAudioFile(r.RequestCtx, filename); err != nil {
+		a.Log.Error("Failed to store org audio", "error", err, "org_id", orgID, "type", audioType)
+		return r.SendErrorEnvelope(fasthttp.StatusInternalServerError, "Failed to store audio file", nil, "")
+	}
+
 	// Update org settings with the new filename
 	var org models.Organization
 	if err := a.DB.Where("id = ?", orgID).First(&org).Error; err != nil {
@@ -632,6 +676,9 @@ func (a *App) generateIVRAudio(menu models.JSONB) error {
 		if err != nil {
 			return err
 		}
+		if err := a.mirrorAudioFile(context.Background(), filename); err != nil {
+			return err
+		}
 		config["audio_file"] = filename
 		nodeMap["config"] = config
 		nodesSlice[i] = nodeMap
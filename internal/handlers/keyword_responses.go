@@ -0,0 +1,373 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shridarpatil/whatomate/internal/contactutil"
+	"github.com/shridarpatil/whatomate/internal/models"
+)
+
+// keywordSendTimeout bounds media and template sends started by a keyword rule
+const keywordSendTimeout = 30 * time.Second
+
+// isKeywordRuleActive reports whether now falls within the rule's active window.
+// ActiveFrom is inclusive and ActiveUntil exclusive; unset bounds are open.
+func isKeywordRuleActive(rule *models.KeywordRule, now time.Time) bool {
+	if rule.ActiveFrom != nil && now.Before(*rule.ActiveFrom) {
+		return false
+	}
+	if rule.ActiveUntil != nil && !now.Before(*rule.ActiveUntil) {
+		return false
+	}
+	return true
+}
+
+// keywordConditionData builds the data keyword rule conditions and response
+// parameters are evaluated against: the session data at the top level, the
+// contact's fields under "contact" and its tags under "tag", so that
+// conditions such as `contact.metadata.plan == 'pro' AND tag.vip == true` work.
+func keywordConditionData(contact *models.Contact, session *models.ChatbotSession) map[string]interface{} {
+	data := make(map[string]interface{})
+	if session != nil {
+		for k, v := range session.SessionData {
+			data[k] = v
+		}
+	}
+	if contact == nil {
+		return data
+	}
+
+	tags := make(map[string]interface{}, len(contact.Tags))
+	tagNames := make([]string, 0, len(contact.Tags))
+	for _, t := range contact.Tags {
+		if name, ok := t.(string); ok {
+			tags[name] = true
+			tagNames = append(tagNames, name)
+		}
+	}
+	metadata := map[string]interface{}(contact.Metadata)
+	if metadata == nil {
+		metadata = map[string]interface{}{}
+	}
+	consent := contact.ConsentStatus
+	if consent == "" {
+		consent = models.ConsentStatusUnknown
+	}
+
+	data["contact"] = map[string]interface{}{
+		"id":               contact.ID.String(),
+		"phone_number":     contact.PhoneNumber,
+		"name":             contact.ProfileName,
+		"profile_name":     contact.ProfileName,
+		"whatsapp_account": contact.WhatsAppAccount,
+		"tags":             strings.Join(tagNames, ","),
+		"metadata":         metadata,
+		"consent_status":   string(consent),
+	}
+	data["tag"] = tags
+	return data
+}
+
+// sendKeywordResponse executes a matched keyword rule's response and logs it to the session
+func (a *App) sendKeywordResponse(account *models.WhatsAppAccount, session *models.ChatbotSession, contact *models.Contact, response *KeywordResponse, messageText string, data map[string]interface{}) {
+	var err error
+	switch response.ResponseType {
+	case models.ResponseTypeTemplate:
+		err = a.sendKeywordTemplate(account, contact, response, data)
+	case models.ResponseTypeMedia:
+		err = a.sendKeywordMedia(account, contact, response, data)
+	case models.ResponseTypeFlow:
+		// The flow logs its own messages to the session
+		if err = a.startKeywordFlow(account, session, contact, response); err == nil {
+			a.logKeywordSessionMessage(session.ID, response, "keyword_flow")
+			return
+		}
+	case models.ResponseTypeScript:
+		err = a.runKeywordScript(account, contact, response, messageText, data)
+	default:
+		if len(response.Buttons) > 0 {
+			err = a.sendAndSaveInteractiveButtons(account, contact, response.Body, response.Buttons)
+		} else {
+			err = a.sendAndSaveTextMessage(account, contact, response.Body)
+		}
+	}
+	if err != nil {
+		a.Log.Error("Failed to send keyword response", "error", err, "rule_id", response.RuleID,
+			"response_type", response.ResponseType, "contact", contact.PhoneNumber)
+		return
+	}
+	a.logKeywordSessionMessage(session.ID, response, "keyword_response")
+}
+
+// sendKeywordTemplate sends an approved template. Params values may reference
+// condition data, e.g. {"name": "{{contact.name}}"}.
+func (a *App) sendKeywordTemplate(account *models.WhatsAppAccount, contact *models.Contact, response *KeywordResponse, data map[string]interface{}) error {
+	query := a.DB.Where("organization_id = ?", account.OrganizationID)
+	if id, ok := response.Content["template_id"].(string); ok && id != "" {
+		templateID, err := uuid.Parse(id)
+		if err != nil {
+			return fmt.Errorf("invalid template_id %q", id)
+		}
+		query = query.Where("id = ?", templateID)
+	} else if name, ok := response.Content["template_name"].(string); ok && name != "" {
+		query = query.Where("name = ? AND (whats_app_account = ? OR whats_app_account = '')", name, account.Name)
+	} else {
+		return errors.New("template rule has no template_id or template_name")
+	}
+
+	var template models.Template
+	if err := query.First(&template).Error; err != nil {
+		return fmt.Errorf("template not found: %w", err)
+	}
+	if template.Status != string(models.TemplateStatusApproved) {
+		return fmt.Errorf("template %s is not approved (status: %s)", template.Name, template.Status)
+	}
+	template = *contactutil.TemplateInLanguage(a.DB, &template, contact.PreferredLanguage())
+
+	params := make(map[string]string)
+	if raw, ok := response.Content["params"].(map[string]interface{}); ok {
+		for k, v := range raw {
+			params[k] = processTemplate(fmt.Sprintf("%v", v), data)
+		}
+	}
+	if missing := missingTemplateParams(&template, params); len(missing) > 0 {
+		return fmt.Errorf("missing template parameters: %s", strings.Join(missing, ", "))
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), keywordSendTimeout)
+	defer cancel()
+
+	req := OutgoingMessageRequest{
+		Account:    account,
+		Contact:    contact,
+		Type:       models.MessageTypeTemplate,
+		Template:   &template,
+		BodyParams: params,
+	}
+	if isMediaHeader(template.HeaderType) {
+		if err := a.loadKeywordMedia(ctx, response.Content, "header_media_url", "header_media_key", &req); err != nil {
+			return err
+		}
+	}
+
+	response.Body = template.BodyContent
+	_, err := a.SendOutgoingMessage(ctx, req, ChatbotSendOptions())
+	return err
+}
+
+// sendKeywordMedia sends an image, video, audio or document from media storage or a URL
+func (a *App) sendKeywordMedia(account *models.WhatsAppAccount, contact *models.Contact, response *KeywordResponse, data map[string]interface{}) error {
+	mediaType, _ := response.Content["media_type"].(string)
+	switch models.MessageType(mediaType) {
+	case models.MessageTypeImage, models.MessageTypeVideo, models.MessageTypeAudio, models.MessageTypeDocument:
+	default:
+		return fmt.Errorf("unsupported media_type %q", mediaType)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), keywordSendTimeout)
+	defer cancel()
+
+	req := OutgoingMessageRequest{
+		Account: account,
+		Contact: contact,
+		Type:    models.MessageType(mediaType),
+	}
+	if err := a.loadKeywordMedia(ctx, response.Content, "media_url", "media_key", &req); err != nil {
+		return err
+	}
+	if req.MediaData == nil {
+		return errors.New("media rule has no media_url or media_key")
+	}
+	if caption, ok := response.Content["caption"].(string); ok {
+		req.Caption = processTemplate(caption, data)
+	}
+	if filename, ok := response.Content["filename"].(string); ok && filename != "" {
+		req.MediaFilename = filename
+	}
+
+	response.Body = req.Caption
+	_, err := a.SendOutgoingMessage(ctx, req, ChatbotSendOptions())
+	return err
+}
+
+// loadKeywordMedia loads the media referenced by urlKey or storageKey of a rule's
+// response content into req. A fetched URL is stored so the message can be
+// served from the chat history. Leaves req unchanged when neither key is set.
+func (a *App) loadKeywordMedia(ctx context.Context, content models.JSONB, urlKey, storageKey string, req *OutgoingMessageRequest) error {
+	if key, ok := content[storageKey].(string); ok && key != "" {
+		body, info, err := a.mediaStorage().Open(ctx, key)
+		if err != nil {
+			return fmt.Errorf("failed to open media %q: %w", key, err)
+		}
+		defer body.Close() //nolint:errcheck
+
+		data, err := io.ReadAll(io.LimitReader(body, maxNotificationAttachmentSize+1))
+		if err != nil {
+			return fmt.Errorf("failed to read media %q: %w", key, err)
+		}
+		if len(data) > maxNotificationAttachmentSize {
+			return fmt.Errorf("media %q exceeds %d bytes", key, maxNotificationAttachmentSize)
+		}
+		mimeType := info.ContentType
+		if mimeType == "" {
+			mimeType = mediaContentTypeFromExtension(key)
+		}
+		req.MediaData = data
+		req.MediaURL = key
+		req.MediaMimeType = mimeType
+		req.MediaFilename = path.Base(key)
+		return nil
+	}
+
+	if rawURL, ok := content[urlKey].(string); ok && rawURL != "" {
+		data, mimeType, err := a.fetchNotificationAttachment(ctx, rawURL)
+		if err != nil {
+			return err
+		}
+		if mimeType == "" {
+			mimeType = http.DetectContentType(data)
+		}
+		req.MediaData = data
+		req.MediaMimeType = mimeType
+		req.MediaFilename = path.Base(strings.SplitN(rawURL, "?", 2)[0])
+		if key, err := a.saveMedia(ctx, data, mimeType, req.MediaFilename); err != nil {
+			a.Log.Warn("Failed to store keyword media", "error", err, "url", rawURL)
+		} else {
+			req.MediaURL = key
+		}
+	}
+	return nil
+}
+
+// startKeywordFlow starts the chatbot flow configured on a flow rule
+func (a *App) startKeywordFlow(account *models.WhatsAppAccount, session *models.ChatbotSession, contact *models.Contact, response *KeywordResponse) error {
+	id, _ := response.Content["flow_id"].(string)
+	flowID, err := uuid.Parse(id)
+	if err != nil {
+		return fmt.Errorf("invalid flow_id %q", id)
+	}
+	flow, err := a.getChatbotFlowByIDCached(account.OrganizationID, flowID)
+	if err != nil {
+		return fmt.Errorf("flow not found or disabled: %w", err)
+	}
+
+	response.Body = flow.Name
+	a.startFlow(account, session, contact, flow)
+	return nil
+}
+
+// runKeywordScript runs a script rule and sends the message it returns. The
+// script receives the inbound text as `message`, the contact as `contact`,
+// a copy of the session data as `session` and the contact's tags as `tag`.
+func (a *App) runKeywordScript(account *models.WhatsAppAccount, contact *models.Contact, response *KeywordResponse, messageText string, data map[string]interface{}) error {
+	code, _ := response.Content["code"].(string)
+	if strings.TrimSpace(code) == "" {
+		return errors.New("script rule has no code")
+	}
+
+	session := make(map[string]interface{}, len(data))
+	for k, v := range data {
+		if k != "contact" && k != "tag" {
+			session[k] = v
+		}
+	}
+	result, err := runChatbotScript(code, map[string]interface{}{
+		"message": messageText,
+		"contact": data["contact"],
+		"session": session,
+		"tag":     data["tag"],
+	}, a.scriptOptions(response.Content))
+	if err != nil {
+		return err
+	}
+	if result.Message == "" {
+		return nil
+	}
+
+	response.Body = result.Message
+	if len(result.Buttons) > 0 {
+		return a.sendAndSaveInteractiveButtons(account, contact, result.Message, result.Buttons)
+	}
+	return a.sendAndSaveTextMessage(account, contact, result.Message)
+}
+
+// validateKeywordRule checks that a rule's response content has what its
+// response type needs and that its active window is well-formed
+func validateKeywordRule(responseType models.ResponseType, content map[string]interface{}, activeFrom, activeUntil *time.Time) error {
+	if activeFrom != nil && activeUntil != nil && !activeUntil.After(*activeFrom) {
+		return errors.New("active_until must be after active_from")
+	}
+
+	str := func(key string) string {
+		s, _ := content[key].(string)
+		return strings.TrimSpace(s)
+	}
+	switch responseType {
+	case models.ResponseTypeText:
+	case models.ResponseTypeTransfer:
+		if id := str("team_id"); id != "" {
+			if _, err := uuid.Parse(id); err != nil {
+				return errors.New("invalid team_id")
+			}
+		}
+	case models.ResponseTypeTemplate:
+		if str("template_id") == "" && str("template_name") == "" {
+			return errors.New("template responses require template_id or template_name")
+		}
+		if id := str("template_id"); id != "" {
+			if _, err := uuid.Parse(id); err != nil {
+				return errors.New("invalid template_id")
+			}
+		}
+	case models.ResponseTypeMedia:
+		switch models.MessageType(str("media_type")) {
+		case models.MessageTypeImage, models.MessageTypeVideo, models.MessageTypeAudio, models.MessageTypeDocument:
+		default:
+			return errors.New("media responses require media_type image, video, audio or document")
+		}
+		if str("media_url") == "" && str("media_key") == "" {
+			return errors.New("media responses require media_url or media_key")
+		}
+		if u := str("media_url"); u != "" {
+			if err := validateWebhookURL(u); err != nil {
+				return fmt.Errorf("invalid media_url: %w", err)
+			}
+		}
+	case models.ResponseTypeFlow:
+		if _, err := uuid.Parse(str("flow_id")); err != nil {
+			return errors.New("flow responses require a valid flow_id")
+		}
+	case models.ResponseTypeScript:
+		if err := validateScriptConfig(content); err != nil {
+			return fmt.Errorf("invalid script response: %w", err)
+		}
+	default:
+		return fmt.Errorf("invalid response_type %q", responseType)
+	}
+	return nil
+}
+
+// parseOptionalTime decodes a JSON timestamp, returning nil for null or ""
+func parseOptionalTime(raw json.RawMessage) (*time.Time, error) {
+	var s *string
+	if err := json.Unmarshal(raw, &s); err != nil {
+		return nil, err
+	}
+	if s == nil || *s == "" {
+		return nil, nil
+	}
+	t, err := time.Parse(time.RFC3339, *s)
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
@@ -0,0 +1,118 @@
+package handlers
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shridarpatil/whatomate/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIsKeywordRuleActive(t *testing.T) {
+	now := time.Now()
+	past, future := now.Add(-time.Hour), now.Add(time.Hour)
+
+	assert.True(t, isKeywordRuleActive(&models.KeywordRule{}, now))
+	assert.True(t, isKeywordRuleActive(&models.KeywordRule{ActiveFrom: &past, ActiveUntil: &future}, now))
+	assert.True(t, isKeywordRuleActive(&models.KeywordRule{ActiveFrom: &now}, now), "active_from is inclusive")
+	assert.False(t, isKeywordRuleActive(&models.KeywordRule{ActiveUntil: &now}, now), "active_until is exclusive")
+	assert.False(t, isKeywordRuleActive(&models.KeywordRule{ActiveFrom: &future}, now))
+	assert.False(t, isKeywordRuleActive(&models.KeywordRule{ActiveUntil: &past}, now))
+}
+
+func TestKeywordConditionData(t *testing.T) {
+	contact := &models.Contact{
+		BaseModel:   models.BaseModel{ID: uuid.New()},
+		PhoneNumber: "15550001111",
+		ProfileName: "Alice",
+		Tags:        models.JSONBArray{"vip", "beta"},
+		Metadata:    models.JSONB{"plan": "pro"},
+	}
+	session := &models.ChatbotSession{SessionData: models.JSONB{"order_id": "42"}}
+	data := keywordConditionData(contact, session)
+
+	assert.True(t, evaluateExpression("tag.vip == true AND contact.metadata.plan == 'pro'", data))
+	assert.True(t, evaluateExpression("order_id == '42' AND contact.name == 'Alice'", data))
+	assert.True(t, evaluateExpression("tag.churned != true", data))
+	assert.False(t, evaluateExpression("contact.consent_status == 'opted_out'", data))
+	assert.Equal(t, "Hi Alice", processTemplate("Hi {{contact.name}}", data))
+}
+
+func TestValidateKeywordRule(t *testing.T) {
+	now := time.Now()
+	later := now.Add(time.Hour)
+
+	assert.NoError(t, validateKeywordRule(models.ResponseTypeText, nil, &now, &later))
+	assert.Error(t, validateKeywordRule(models.ResponseTypeText, nil, &later, &now))
+	assert.Error(t, validateKeywordRule("bogus", nil, nil, nil))
+
+	assert.NoError(t, validateKeywordRule(models.ResponseTypeTemplate, map[string]interface{}{"template_name": "promo"}, nil, nil))
+	assert.Error(t, validateKeywordRule(models.ResponseTypeTemplate, map[string]interface{}{}, nil, nil))
+
+	assert.NoError(t, validateKeywordRule(models.ResponseTypeMedia, map[string]interface{}{"media_type": "image", "media_key": "images/a.jpg"}, nil, nil))
+	assert.Error(t, validateKeywordRule(models.ResponseTypeMedia, map[string]interface{}{"media_type": "sticker", "media_key": "images/a.jpg"}, nil, nil))
+	assert.Error(t, validateKeywordRule(models.ResponseTypeMedia, map[string]interface{}{"media_type": "image", "media_url": "http://127.0.0.1/a.jpg"}, nil, nil))
+
+	assert.NoError(t, validateKeywordRule(models.ResponseTypeFlow, map[string]interface{}{"flow_id": uuid.New().String()}, nil, nil))
+	assert.Error(t, validateKeywordRule(models.ResponseTypeFlow, map[string]interface{}{"flow_id": "nope"}, nil, nil))
+
+	assert.Error(t, validateKeywordRule(models.ResponseTypeScript, map[string]interface{}{"code": " "}, nil, nil))
+
+	assert.NoError(t, validateKeywordRule(models.ResponseTypeTransfer, map[string]interface{}{"team_id": uuid.New().String(), "required_skills": []interface{}{"spanish"}}, nil, nil))
+	assert.Error(t, validateKeywordRule(models.ResponseTypeTransfer, map[string]interface{}{"team_id": "support"}, nil, nil))
+}
+
+func TestMatchKeywordRules_ScheduleAndConditions(t *testing.T) {
+	app := newProcessorTestApp(t)
+	org, account := createProcessorTestOrg(t, app)
+
+	expired := time.Now().Add(-time.Hour)
+	rules := []*models.KeywordRule{
+		{
+			Name:            "expired-sale",
+			Keywords:        models.StringArray{"sale"},
+			ResponseType:    models.ResponseTypeText,
+			ResponseContent: models.JSONB{"body": "Sale is on"},
+			Priority:        30,
+			ActiveUntil:     &expired,
+		},
+		{
+			Name:            "vip-sale",
+			Keywords:        models.StringArray{"sale"},
+			ResponseType:    models.ResponseTypeFlow,
+			ResponseContent: models.JSONB{"flow_id": uuid.New().String()},
+			Conditions:      "tag.vip == true",
+			Priority:        20,
+		},
+		{
+			Name:            "default-sale",
+			Keywords:        models.StringArray{"sale"},
+			ResponseType:    models.ResponseTypeText,
+			ResponseContent: models.JSONB{"body": "No sale right now"},
+			Priority:        10,
+		},
+	}
+	for _, rule := range rules {
+		rule.ID = uuid.New()
+		rule.OrganizationID = org.ID
+		rule.WhatsAppAccount = account.Name
+		rule.MatchType = models.MatchTypeContains
+		rule.IsEnabled = true
+		require.NoError(t, app.DB.Create(rule).Error)
+	}
+
+	vip := keywordConditionData(&models.Contact{Tags: models.JSONBArray{"vip"}}, nil)
+	resp, matched := app.matchKeywordRules(org.ID, account.Name, "any sale?", vip)
+	require.True(t, matched)
+	assert.Equal(t, rules[1].ID, resp.RuleID)
+	assert.Equal(t, models.ResponseTypeFlow, resp.ResponseType)
+	assert.Equal(t, rules[1].ResponseContent["flow_id"], resp.Content["flow_id"])
+
+	regular := keywordConditionData(&models.Contact{}, nil)
+	resp, matched = app.matchKeywordRules(org.ID, account.Name, "any sale?", regular)
+	require.True(t, matched)
+	assert.Equal(t, rules[2].ID, resp.RuleID)
+	assert.Equal(t, "No sale right now", resp.Body)
+}
@@ -0,0 +1,720 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shridarpatil/whatomate/internal/ai"
+	"github.com/shridarpatil/whatomate/internal/knowledge"
+	"github.com/shridarpatil/whatomate/internal/models"
+	"github.com/valyala/fasthttp"
+	"github.com/zerodha/fastglue"
+	"gorm.io/gorm"
+)
+
+const (
+	// maxKnowledgeFileSize limits uploaded knowledge documents
+	maxKnowledgeFileSize = 10 << 20 // 10MB
+	// maxKnowledgeTopK limits the knowledge chunks added to an AI prompt
+	maxKnowledgeTopK = 20
+	// knowledgeEmbedBatchSize is the number of chunks embedded per API call
+	knowledgeEmbedBatchSize = 64
+	// knowledgeExcerptLength is the length of chunk excerpts in citations
+	knowledgeExcerptLength = 200
+	// knowledgeIndexTimeout bounds the embedding of one document
+	knowledgeIndexTimeout = 10 * time.Minute
+)
+
+// KnowledgeDocumentResponse represents a knowledge document in API responses
+type KnowledgeDocumentResponse struct {
+	ID              uuid.UUID                      `json:"id"`
+	Name            string                         `json:"name"`
+	FileName        string                         `json:"file_name"`
+	SourceType      string                         `json:"source_type"`
+	FileSize        int64                          `json:"file_size"`
+	WhatsAppAccount string                         `json:"whatsapp_account"`
+	Status          models.KnowledgeDocumentStatus `json:"status"`
+	Error           string                         `json:"error,omitempty"`
+	ChunkCount      int                            `json:"chunk_count"`
+	EmbeddingModel  string                         `json:"embedding_model"`
+	IsEnabled       bool                           `json:"is_enabled"`
+	CreatedAt       string                         `json:"created_at"`
+	UpdatedAt       string                         `json:"updated_at"`
+}
+
+// KnowledgeChunkResponse represents a chunk of a knowledge document
+type KnowledgeChunkResponse struct {
+	ID         uuid.UUID `json:"id"`
+	ChunkIndex int       `json:"chunk_index"`
+	Heading    string    `json:"heading"`
+	Content    string    `json:"content"`
+}
+
+// KnowledgeCitation identifies a knowledge chunk an AI answer was based on.
+// Citations are stored in the answer's message metadata under "ai_citations".
+type KnowledgeCitation struct {
+	DocumentID   uuid.UUID `json:"document_id"`
+	DocumentName string    `json:"document_name"`
+	ChunkID      uuid.UUID `json:"chunk_id"`
+	Heading      string    `json:"heading,omitempty"`
+	Score        float64   `json:"score"`
+	Excerpt      string    `json:"excerpt"`
+}
+
+// knowledgeMatch is a knowledge chunk scored against a query
+type knowledgeMatch struct {
+	ChunkID      uuid.UUID
+	DocumentID   uuid.UUID
+	DocumentName string
+	Heading      string
+	Content      string
+	Embedding    models.Vector
+	Score        float64
+}
+
+func (m knowledgeMatch) citation() KnowledgeCitation {
+	excerpt := []rune(m.Content)
+	if len(excerpt) > knowledgeExcerptLength {
+		excerpt = append(excerpt[:knowledgeExcerptLength], '…')
+	}
+	return KnowledgeCitation{
+		DocumentID:   m.DocumentID,
+		DocumentName: m.DocumentName,
+		ChunkID:      m.ChunkID,
+		Heading:      m.Heading,
+		Score:        float64(int(m.Score*1000)) / 1000,
+		Excerpt:      string(excerpt),
+	}
+}
+
+// ListKnowledgeDocuments returns the organization's knowledge documents
+func (a *App) ListKnowledgeDocuments(r *fastglue.Request) error {
+	orgID, userID, err := a.getOrgAndUserID(r)
+	if err != nil {
+		return r.SendErrorEnvelope(fasthttp.StatusUnauthorized, "Unauthorized", nil, "")
+	}
+	if err := a.requirePermission(r, userID, models.ResourceChatbotAI, models.ActionRead); err != nil {
+		return nil
+	}
+
+	pg := parsePagination(r)
+	search := string(r.RequestCtx.QueryArgs().Peek("search"))
+	status := string(r.RequestCtx.QueryArgs().Peek("status"))
+	account := string(r.RequestCtx.QueryArgs().Peek("whatsapp_account"))
+
+	query := a.DB.Model(&models.KnowledgeDocument{}).Where("organization_id = ?", orgID)
+	if search != "" {
+		searchPattern := "%" + search + "%"
+		query = query.Where("name ILIKE ? OR file_name ILIKE ?", searchPattern, searchPattern)
+	}
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+	if account != "" {
+		query = query.Where("whats_app_account = ?", account)
+	}
+
+	var total int64
+	query.Count(&total)
+
+	var docs []models.KnowledgeDocument
+	if err := pg.Apply(query.Order("created_at DESC")).Find(&docs).Error; err != nil {
+		a.Log.Error("Failed to list knowledge documents", "error", err)
+		return r.SendErrorEnvelope(fasthttp.StatusInternalServerError, "Failed to list knowledge documents", nil, "")
+	}
+
+	result := make([]KnowledgeDocumentResponse, len(docs))
+	for i, doc := range docs {
+		result[i] = knowledgeDocumentToResponse(doc)
+	}
+
+	return r.SendEnvelope(map[string]any{
+		"documents": result,
+		"total":     total,
+		"page":      pg.Page,
+		"limit":     pg.Limit,
+	})
+}
+
+// UploadKnowledgeDocument uploads a PDF, Markdown, CSV or text file to the
+// knowledge base. The text is extracted and chunked immediately; embedding
+// runs in the background and the document becomes ready when it finishes.
+func (a *App) UploadKnowledgeDocument(r *fastglue.Request) error {
+	orgID, userID, err := a.getOrgAndUserID(r)
+	if err != nil {
+		return r.SendErrorEnvelope(fasthttp.StatusUnauthorized, "Unauthorized", nil, "")
+	}
+	if err := a.requirePermission(r, userID, models.ResourceChatbotAI, models.ActionWrite); err != nil {
+		return nil
+	}
+
+	form, err := r.RequestCtx.MultipartForm()
+	if err != nil {
+		return r.SendErrorEnvelope(fasthttp.StatusBadRequest, "Invalid multipart form: "+err.Error(), nil, "")
+	}
+	files := form.File["file"]
+	if len(files) == 0 {
+		return r.SendErrorEnvelope(fasthttp.StatusBadRequest, "No file provided", nil, "")
+	}
+
+	fileHeader := files[0]
+	sourceType, err := knowledge.DetectSourceType(fileHeader.Filename, fileHeader.Header.Get("Content-Type"))
+	if err != nil {
+		return r.SendErrorEnvelope(fasthttp.StatusBadRequest, err.Error(), nil, "")
+	}
+
+	file, err := fileHeader.Open()
+	if err != nil {
+		return r.SendErrorEnvelope(fasthttp.StatusBadRequest, "Failed to open file", nil, "")
+	}
+	defer func() { _ = file.Close() }()
+
+	data, err := io.ReadAll(io.LimitReader(file, maxKnowledgeFileSize+1))
+	if err != nil {
+		a.Log.Error("Failed to read knowledge document", "error", err)
+		return r.SendErrorEnvelope(fasthttp.StatusInternalServerError, "Failed to read file", nil, "")
+	}
+	if len(data) > maxKnowledgeFileSize {
+		return r.SendErrorEnvelope(fasthttp.StatusBadRequest, "File too large. Maximum size is 10MB", nil, "")
+	}
+
+	sections, err := knowledge.Extract(sourceType, data)
+	if err != nil {
+		return r.SendErrorEnvelope(fasthttp.StatusBadRequest, err.Error(), nil, "")
+	}
+	chunks := knowledge.ChunkSections(sections, knowledge.DefaultChunkSize, knowledge.DefaultChunkOverlap)
+
+	name := strings.TrimSpace(formValue(form.Value, "name"))
+	if name == "" {
+		name = strings.TrimSuffix(filepath.Base(fileHeader.Filename), filepath.Ext(fileHeader.Filename))
+	}
+	doc := models.KnowledgeDocument{
+		BaseModel:       models.BaseModel{ID: uuid.New()},
+		OrganizationID:  orgID,
+		WhatsAppAccount: strings.TrimSpace(formValue(form.Value, "whatsapp_account")),
+		Name:            name,
+		FileName:        filepath.Base(fileHeader.Filename),
+		SourceType:      sourceType,
+		FileSize:        int64(len(data)),
+		Status:          models.KnowledgeDocumentProcessing,
+		ChunkCount:      len(chunks),
+		IsEnabled:       true,
+		CreatedByID:     userID,
+	}
+
+	if err := a.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&doc).Error; err != nil {
+			return err
+		}
+		rows := make([]models.KnowledgeChunk, len(chunks))
+		for i, c := range chunks {
+			rows[i] = models.KnowledgeChunk{
+				OrganizationID: orgID,
+				DocumentID:     doc.ID,
+				ChunkIndex:     c.Index,
+				Heading:        c.Heading,
+				Content:        c.Content,
+			}
+		}
+		return tx.CreateInBatches(rows, 100).Error
+	}); err != nil {
+		a.Log.Error("Failed to save knowledge document", "error", err)
+		return r.SendErrorEnvelope(fasthttp.StatusInternalServerError, "Failed to save knowledge document", nil, "")
+	}
+
+	a.indexKnowledgeDocumentAsync(doc)
+
+	return r.SendEnvelope(knowledgeDocumentToResponse(doc))
+}
+
+// GetKnowledgeDocument returns a knowledge document with its chunks
+func (a *App) GetKnowledgeDocument(r *fastglue.Request) error {
+	orgID, userID, err := a.getOrgAndUserID(r)
+	if err != nil {
+		return r.SendErrorEnvelope(fasthttp.StatusUnauthorized, "Unauthorized", nil, "")
+	}
+	if err := a.requirePermission(r, userID, models.ResourceChatbotAI, models.ActionRead); err != nil {
+		return nil
+	}
+
+	id, err := parsePathUUID(r, "id", "knowledge document")
+	if err != nil {
+		return nil
+	}
+	doc, err := findByIDAndOrg[models.KnowledgeDocument](a.DB, r, id, orgID, "Knowledge document")
+	if err != nil {
+		return nil
+	}
+
+	var chunks []models.KnowledgeChunk
+	if err := a.DB.Select("id", "chunk_index", "heading", "content").
+		Where("document_id = ?", doc.ID).Order("chunk_index ASC").Find(&chunks).Error; err != nil {
+		a.Log.Error("Failed to load knowledge chunks", "error", err, "document_id", doc.ID)
+		return r.SendErrorEnvelope(fasthttp.StatusInternalServerError, "Failed to load knowledge document", nil, "")
+	}
+
+	chunkResp := make([]KnowledgeChunkResponse, len(chunks))
+	for i, c := range chunks {
+		chunkResp[i] = KnowledgeChunkResponse{ID: c.ID, ChunkIndex: c.ChunkIndex, Heading: c.Heading, Content: c.Content}
+	}
+
+	return r.SendEnvelope(map[string]any{
+		"document": knowledgeDocumentToResponse(*doc),
+		"chunks":   chunkResp,
+	})
+}
+
+// UpdateKnowledgeDocument renames, enables/disables or re-scopes a knowledge document
+func (a *App) UpdateKnowledgeDocument(r *fastglue.Request) error {
+	orgID, userID, err := a.getOrgAndUserID(r)
+	if err != nil {
+		return r.SendErrorEnvelope(fasthttp.StatusUnauthorized, "Unauthorized", nil, "")
+	}
+	if err := a.requirePermission(r, userID, models.ResourceChatbotAI, models.ActionWrite); err != nil {
+		return nil
+	}
+
+	id, err := parsePathUUID(r, "id", "knowledge document")
+	if err != nil {
+		return nil
+	}
+	doc, err := findByIDAndOrg[models.KnowledgeDocument](a.DB, r, id, orgID, "Knowledge document")
+	if err != nil {
+		return nil
+	}
+
+	var req struct {
+		Name            *string `json:"name"`
+		IsEnabled       *bool   `json:"is_enabled"`
+		WhatsAppAccount *string `json:"whatsapp_account"`
+	}
+	if err := a.decodeRequest(r, &req); err != nil {
+		return nil
+	}
+
+	if req.Name != nil {
+		doc.Name = strings.TrimSpace(*req.Name)
+		if doc.Name == "" {
+			return r.SendErrorEnvelope(fasthttp.StatusBadRequest, "name cannot be empty", nil, "")
+		}
+	}
+	if req.IsEnabled != nil {
+		doc.IsEnabled = *req.IsEnabled
+	}
+	if req.WhatsAppAccount != nil {
+		doc.WhatsAppAccount = strings.TrimSpace(*req.WhatsAppAccount)
+	}
+	if err := a.DB.Model(doc).Select("name", "is_enabled", "whats_app_account").Updates(doc).Error; err != nil {
+		a.Log.Error("Failed to update knowledge document", "error", err, "document_id", doc.ID)
+		return r.SendErrorEnvelope(fasthttp.StatusInternalServerError, "Failed to update knowledge document", nil, "")
+	}
+
+	return r.SendEnvelope(knowledgeDocumentToResponse(*doc))
+}
+
+// DeleteKnowledgeDocument deletes a knowledge document and its chunks
+func (a *App) DeleteKnowledgeDocument(r *fastglue.Request) error {
+	orgID, userID, err := a.getOrgAndUserID(r)
+	if err != nil {
+		return r.SendErrorEnvelope(fasthttp.StatusUnauthorized, "Unauthorized", nil, "")
+	}
+	if err := a.requirePermission(r, userID, models.ResourceChatbotAI, models.ActionWrite); err != nil {
+		return nil
+	}
+
+	id, err := parsePathUUID(r, "id", "knowledge document")
+	if err != nil {
+		return nil
+	}
+	doc, err := findByIDAndOrg[models.KnowledgeDocument](a.DB, r, id, orgID, "Knowledge document")
+	if err != nil {
+		return nil
+	}
+
+	if err := a.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Unscoped().Where("document_id = ?", doc.ID).Delete(&models.KnowledgeChunk{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(doc).Error
+	}); err != nil {
+		a.Log.Error("Failed to delete knowledge document", "error", err, "document_id", doc.ID)
+		return r.SendErrorEnvelope(fasthttp.StatusInternalServerError, "Failed to delete knowledge document", nil, "")
+	}
+
+	return r.SendEnvelope(map[string]string{"message": "Knowledge document deleted"})
+}
+
+// ReindexKnowledgeDocument embeds a document's chunks again, e.g. after the
+// AI provider or embedding model changed
+func (a *App) ReindexKnowledgeDocument(r *fastglue.Request) error {
+	orgID, userID, err := a.getOrgAndUserID(r)
+	if err != nil {
+		return r.SendErrorEnvelope(fasthttp.StatusUnauthorized, "Unauthorized", nil, "")
+	}
+	if err := a.requirePermission(r, userID, models.ResourceChatbotAI, models.ActionWrite); err != nil {
+		return nil
+	}
+
+	id, err := parsePathUUID(r, "id", "knowledge document")
+	if err != nil {
+		return nil
+	}
+	doc, err := findByIDAndOrg[models.KnowledgeDocument](a.DB, r, id, orgID, "Knowledge document")
+	if err != nil {
+		return nil
+	}
+
+	if err := a.DB.Model(doc).Updates(map[string]any{
+		"status": models.KnowledgeDocumentProcessing,
+		"error":  "",
+	}).Error; err != nil {
+		a.Log.Error("Failed to reindex knowledge document", "error", err, "document_id", doc.ID)
+		return r.SendErrorEnvelope(fasthttp.StatusInternalServerError, "Failed to reindex knowledge document", nil, "")
+	}
+	doc.Status = models.KnowledgeDocumentProcessing
+	doc.Error = ""
+
+	a.indexKnowledgeDocumentAsync(*doc)
+
+	return r.SendEnvelope(knowledgeDocumentToResponse(*doc))
+}
+
+// SearchKnowledge returns the knowledge chunks the AI would use for a query,
+// so the knowledge base can be checked without messaging the bot
+func (a *App) SearchKnowledge(r *fastglue.Request) error {
+	orgID, userID, err := a.getOrgAndUserID(r)
+	if err != nil {
+		return r.SendErrorEnvelope(fasthttp.StatusUnauthorized, "Unauthorized", nil, "")
+	}
+	if err := a.requirePermission(r, userID, models.ResourceChatbotAI, models.ActionRead); err != nil {
+		return nil
+	}
+
+	var req struct {
+		Query           string `json:"query"`
+		WhatsAppAccount string `json:"whatsapp_account"`
+		Limit           int    `json:"limit"`
+	}
+	if err := a.decodeRequest(r, &req); err != nil {
+		return nil
+	}
+	if strings.TrimSpace(req.Query) == "" {
+		return r.SendErrorEnvelope(fasthttp.StatusBadRequest, "query is required", nil, "")
+	}
+
+	settings, err := a.getChatbotSettingsCached(orgID, req.WhatsAppAccount)
+	if err != nil {
+		return r.SendErrorEnvelope(fasthttp.StatusBadRequest, "Chatbot settings not found", nil, "")
+	}
+	limit := req.Limit
+	if limit <= 0 {
+		limit = settings.AI.KnowledgeTopK
+	}
+	limit = min(max(limit, 1), maxKnowledgeTopK)
+
+	matches, _, err := a.searchKnowledge(r.RequestCtx, settings.AI, orgID, req.WhatsAppAccount, req.Query, limit, settings.AI.KnowledgeMinScore)
+	if err != nil {
+		a.Log.Error("Knowledge search failed", "error", err)
+		return r.SendErrorEnvelope(fasthttp.StatusBadRequest, "Knowledge search failed: "+err.Error(), nil, "")
+	}
+
+	results := make([]map[string]any, len(matches))
+	for i, m := range matches {
+		results[i] = map[string]any{
+			"citation": m.citation(),
+			"content":  m.Content,
+		}
+	}
+	return r.SendEnvelope(map[string]any{"results": results})
+}
+
+// indexKnowledgeDocumentAsync embeds a document's chunks in the background
+func (a *App) indexKnowledgeDocumentAsync(doc models.KnowledgeDocument) {
+	a.wg.Add(1)
+	go func() {
+		defer a.wg.Done()
+		if err := a.indexKnowledgeDocument(&doc); err != nil {
+			a.Log.Error("Failed to index knowledge document", "error", err, "document_id", doc.ID)
+			a.DB.Model(&models.KnowledgeDocument{}).Where("id = ?", doc.ID).Updates(map[string]any{
+				"status": models.KnowledgeDocumentFailed,
+				"error":  err.Error(),
+			})
+		}
+	}()
+}
+
+// indexKnowledgeDocument embeds a document's chunks with the embedding model
+// of the chatbot settings the document belongs to and marks it ready
+func (a *App) indexKnowledgeDocument(doc *models.KnowledgeDocument) error {
+	settings, err := a.getChatbotSettingsCached(doc.OrganizationID, doc.WhatsAppAccount)
+	if err != nil {
+		return errors.New("chatbot settings not found; configure an AI provider first")
+	}
+	embedder, modelKey, err := a.newKnowledgeEmbedder(settings.AI)
+	if err != nil {
+		return err
+	}
+
+	var chunks []models.KnowledgeChunk
+	if err := a.DB.Select("id", "content").Where("document_id = ?", doc.ID).
+		Order("chunk_index ASC").Find(&chunks).Error; err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), knowledgeIndexTimeout)
+	defer cancel()
+
+	vectorSearch := a.hasVectorSearch()
+	for batch := range slices.Chunk(chunks, knowledgeEmbedBatchSize) {
+		texts := make([]string, len(batch))
+		for i, c := range batch {
+			texts[i] = c.Content
+		}
+		result, err := embedder.Embed(ctx, texts)
+		if err != nil {
+			return fmt.Errorf("embedding failed: %w", err)
+		}
+		for i, c := range batch {
+			vec := models.Vector(result.Vectors[i])
+			if err := a.DB.Model(&models.KnowledgeChunk{}).Where("id = ?", c.ID).Updates(map[string]any{
+				"embedding":       vec,
+				"embedding_model": modelKey,
+			}).Error; err != nil {
+				return err
+			}
+			if vectorSearch {
+				if err := a.DB.Exec("UPDATE knowledge_chunks SET embedding_vector = ?::vector WHERE id = ?",
+					vectorLiteral(vec), c.ID).Error; err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	return a.DB.Model(&models.KnowledgeDocument{}).Where("id = ?", doc.ID).Updates(map[string]any{
+		"status":          models.KnowledgeDocumentReady,
+		"error":           "",
+		"chunk_count":     len(chunks),
+		"embedding_model": modelKey,
+	}).Error
+}
+
+// newKnowledgeEmbedder returns the embedder for an AI config: the primary
+// provider, or the first fallback with an embeddings API. The returned key
+// ("provider:model") is stored with each chunk so queries only compare
+// vectors from the same model.
+func (a *App) newKnowledgeEmbedder(cfg models.AIConfig) (ai.Embedder, string, error) {
+	candidates := []ai.Config{{
+		Provider: string(cfg.Provider),
+		APIKey:   cfg.APIKey,
+		BaseURL:  cfg.BaseURL,
+		Headers:  aiHeadersFromJSONB(cfg.Headers),
+	}}
+	fallbacks, _ := parseAIFallbacks(cfg.Fallbacks)
+	for _, fb := range fallbacks {
+		candidates = append(candidates, ai.Config{
+			Provider: string(fb.Provider),
+			APIKey:   fb.APIKey,
+			BaseURL:  fb.BaseURL,
+			Headers:  fb.Headers,
+		})
+	}
+
+	var errs []error
+	for _, c := range candidates {
+		if c.Provider == "" || !ai.SupportsEmbeddings(c.Provider) {
+			continue
+		}
+		c.Model = cfg.EmbeddingModel
+		if c.Model == "" {
+			c.Model = ai.DefaultEmbeddingModel(c.Provider)
+		}
+		embedder, err := ai.NewEmbedder(c, a.HTTPClient)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", c.Provider, err))
+			continue
+		}
+		return embedder, c.Provider + ":" + c.Model, nil
+	}
+	if len(errs) > 0 {
+		return nil, "", errors.Join(errs...)
+	}
+	return nil, "", errors.New("no configured AI provider supports embeddings")
+}
+
+// searchKnowledge returns the chunks most similar to query from the ready,
+// enabled documents of the organization that apply to the WhatsApp account.
+// It also returns the token usage of embedding the query.
+func (a *App) searchKnowledge(ctx context.Context, cfg models.AIConfig, orgID uuid.UUID, whatsAppAccount, query string, limit int, minScore float64) ([]knowledgeMatch, ai.Usage, error) {
+	embedder, modelKey, err := a.newKnowledgeEmbedder(cfg)
+	if err != nil {
+		return nil, ai.Usage{}, err
+	}
+
+	chunks := func() *gorm.DB {
+		return a.DB.Table("knowledge_chunks kc").
+			Joins("JOIN knowledge_documents kd ON kd.id = kc.document_id").
+			Where("kc.organization_id = ? AND kc.embedding_model = ? AND kc.deleted_at IS NULL", orgID, modelKey).
+			Where("kd.deleted_at IS NULL AND kd.is_enabled = ? AND kd.status = ?", true, models.KnowledgeDocumentReady).
+			Where("kd.whats_app_account = '' OR kd.whats_app_account = ?", whatsAppAccount)
+	}
+
+	// Skip the embedding call when there is nothing to search
+	var count int64
+	if err := chunks().Count(&count).Error; err != nil {
+		return nil, ai.Usage{}, err
+	}
+	if count == 0 {
+		return nil, ai.Usage{}, nil
+	}
+
+	result, err := embedder.Embed(ctx, []string{query})
+	if err != nil {
+		return nil, ai.Usage{}, fmt.Errorf("embedding failed: %w", err)
+	}
+	queryVec := result.Vectors[0]
+
+	columns := "kc.id AS chunk_id, kc.document_id, kd.name AS document_name, kc.heading, kc.content"
+	var matches []knowledgeMatch
+	if a.hasVectorSearch() {
+		literal := vectorLiteral(queryVec)
+		err = chunks().Select(columns+", 1 - (kc.embedding_vector <=> ?::vector) AS score", literal).
+			Where("kc.embedding_vector IS NOT NULL").
+			Order(gorm.Expr("kc.embedding_vector <=> ?::vector", literal)).
+			Limit(limit).
+			Scan(&matches).Error
+	} else {
+		err = chunks().Select(columns + ", kc.embedding").Scan(&matches).Error
+		for i := range matches {
+			matches[i].Score = ai.CosineSimilarity(queryVec, matches[i].Embedding)
+			matches[i].Embedding = nil
+		}
+		slices.SortStableFunc(matches, func(x, y knowledgeMatch) int {
+			switch {
+			case x.Score > y.Score:
+				return -1
+			case x.Score < y.Score:
+				return 1
+			}
+			return 0
+		})
+		if len(matches) > limit {
+			matches = matches[:limit]
+		}
+	}
+	if err != nil {
+		return nil, result.Usage, err
+	}
+
+	return slices.DeleteFunc(matches, func(m knowledgeMatch) bool {
+		return m.Score < minScore
+	}), result.Usage, nil
+}
+
+// buildKnowledgeContext retrieves the knowledge chunks relevant to a user
+// message and formats them as numbered sources for the AI prompt
+func (a *App) buildKnowledgeContext(settings *models.ChatbotSettings, session *models.ChatbotSession, userMessage string) (string, []KnowledgeCitation) {
+	if settings.AI.KnowledgeTopK <= 0 || strings.TrimSpace(userMessage) == "" {
+		return "", nil
+	}
+	whatsAppAccount := settings.WhatsAppAccount
+	if session != nil {
+		whatsAppAccount = session.WhatsAppAccount
+	}
+
+	// Most organizations have no knowledge base; don't require an embeddings
+	// capable provider for them
+	var ready int64
+	a.DB.Model(&models.KnowledgeDocument{}).
+		Where("organization_id = ? AND status = ? AND is_enabled = ?", settings.OrganizationID, models.KnowledgeDocumentReady, true).
+		Count(&ready)
+	if ready == 0 {
+		return "", nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+	limit := min(settings.AI.KnowledgeTopK, maxKnowledgeTopK)
+	matches, usage, err := a.searchKnowledge(ctx, settings.AI, settings.OrganizationID, whatsAppAccount, userMessage, limit, settings.AI.KnowledgeMinScore)
+	if session != nil {
+		a.recordAIUsage(session, usage)
+	}
+	if err != nil {
+		a.Log.Error("Knowledge retrieval failed", "error", err, "organization_id", settings.OrganizationID)
+		return "", nil
+	}
+	if len(matches) == 0 {
+		return "", nil
+	}
+
+	var sb strings.Builder
+	sb.WriteString("## Knowledge Base\n\n")
+	sb.WriteString("Answer from these excerpts when they are relevant. If they don't cover the question, say you don't know rather than guessing.")
+	citations := make([]KnowledgeCitation, len(matches))
+	for i, m := range matches {
+		citations[i] = m.citation()
+		source := m.DocumentName
+		if m.Heading != "" {
+			source += " — " + m.Heading
+		}
+		fmt.Fprintf(&sb, "\n\n[%d] %s\n%s", i+1, source, m.Content)
+	}
+	return sb.String(), citations
+}
+
+// hasVectorSearch reports whether knowledge chunks have a pgvector column.
+// The result is checked once per App.
+func (a *App) hasVectorSearch() bool {
+	a.vectorSearchOnce.Do(func() {
+		var count int64
+		if err := a.DB.Raw(`SELECT COUNT(*) FROM information_schema.columns
+			WHERE table_schema = current_schema() AND table_name = 'knowledge_chunks' AND column_name = 'embedding_vector'`).
+			Scan(&count).Error; err == nil {
+			a.vectorSearch = count > 0
+		}
+	})
+	return a.vectorSearch
+}
+
+// vectorLiteral formats an embedding as a pgvector literal
+func vectorLiteral(v []float32) string {
+	parts := make([]string, len(v))
+	for i, f := range v {
+		parts[i] = strconv.FormatFloat(float64(f), 'f', -1, 32)
+	}
+	return "[" + strings.Join(parts, ",") + "]"
+}
+
+// formValue returns the first value of a multipart form field
+func formValue(values map[string][]string, key string) string {
+	if v := values[key]; len(v) > 0 {
+		return v[0]
+	}
+	return ""
+}
+
+func knowledgeDocumentToResponse(doc models.KnowledgeDocument) KnowledgeDocumentResponse {
+	return KnowledgeDocumentResponse{
+		ID:              doc.ID,
+		Name:            doc.Name,
+		FileName:        doc.FileName,
+		SourceType:      doc.SourceType,
+		FileSize:        doc.FileSize,
+		WhatsAppAccount: doc.WhatsAppAccount,
+		Status:          doc.Status,
+		Error:           doc.Error,
+		ChunkCount:      doc.ChunkCount,
+		EmbeddingModel:  doc.EmbeddingModel,
+		IsEnabled:       doc.IsEnabled,
+		CreatedAt:       doc.CreatedAt.Format(time.RFC3339),
+		UpdatedAt:       doc.UpdatedAt.Format(time.RFC3339),
+	}
+}
@@ -0,0 +1,165 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/shridarpatil/whatomate/internal/models"
+	"github.com/shridarpatil/whatomate/test/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewKnowledgeEmbedder(t *testing.T) {
+	app := &App{Log: testutil.NopLogger()}
+
+	// Anthropic has no embeddings API, so the first capable fallback is used
+	fallbacks, err := mergeAIFallbacks(nil, []models.AIFallback{
+		{Provider: models.AIProviderOpenAI, Model: "gpt-4o-mini", APIKey: "sk-test"},
+	})
+	require.NoError(t, err)
+	_, key, err := app.newKnowledgeEmbedder(models.AIConfig{
+		Provider:  models.AIProviderAnthropic,
+		APIKey:    "sk-ant",
+		Fallbacks: fallbacks,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "openai:text-embedding-3-small", key)
+
+	// Self-hosted endpoints need an explicit embedding model
+	cfg := models.AIConfig{Provider: models.AIProviderOpenAICompatible, BaseURL: "http://localhost:11434/v1"}
+	_, _, err = app.newKnowledgeEmbedder(cfg)
+	assert.ErrorContains(t, err, "embedding model is required")
+
+	cfg.EmbeddingModel = "nomic-embed-text"
+	_, key, err = app.newKnowledgeEmbedder(cfg)
+	require.NoError(t, err)
+	assert.Equal(t, "openai_compatible:nomic-embed-text", key)
+
+	_, _, err = app.newKnowledgeEmbedder(models.AIConfig{Provider: models.AIProviderAnthropic, APIKey: "sk-ant"})
+	assert.ErrorContains(t, err, "no configured AI provider supports embeddings")
+}
+
+func TestVectorLiteral(t *testing.T) {
+	assert.Equal(t, "[0.5,-1,0.25]", vectorLiteral([]float32{0.5, -1, 0.25}))
+	assert.Equal(t, "[]", vectorLiteral(nil))
+}
+
+func TestKnowledgeMatchCitation(t *testing.T) {
+	m := knowledgeMatch{
+		ChunkID:      uuid.New(),
+		DocumentID:   uuid.New(),
+		DocumentName: "Returns policy",
+		Heading:      "Page 2",
+		Content:      strings.Repeat("é", knowledgeExcerptLength+10),
+		Score:        0.87654,
+	}
+	c := m.citation()
+	assert.Equal(t, "Returns policy", c.DocumentName)
+	assert.Equal(t, 0.876, c.Score)
+	assert.Equal(t, knowledgeExcerptLength+1, len([]rune(c.Excerpt)), "excerpts are cut on rune boundaries")
+	assert.True(t, strings.HasSuffix(c.Excerpt, "…"))
+}
+
+// newEmbeddingServer returns an OpenAI-compatible embeddings endpoint that
+// embeds every input as vector
+func newEmbeddingServer(t *testing.T, vector []float32) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Input []string `json:"input"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		data := make([]map[string]any, len(req.Input))
+		for i := range req.Input {
+			data[i] = map[string]any{"index": i, "embedding": vector}
+		}
+		_ = json.NewEncoder(w).Encode(map[string]any{"data": data, "usage": map[string]int{"prompt_tokens": 3}})
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestSearchKnowledge_RanksAndScopesChunks(t *testing.T) {
+	app := newProcessorTestApp(t)
+	org, account := createProcessorTestOrg(t, app)
+	srv := newEmbeddingServer(t, []float32{1, 0})
+
+	settings := &models.ChatbotSettings{
+		OrganizationID: org.ID,
+		AI: models.AIConfig{
+			Enabled:           true,
+			Provider:          models.AIProviderOpenAICompatible,
+			BaseURL:           srv.URL,
+			EmbeddingModel:    "embed",
+			KnowledgeTopK:     2,
+			KnowledgeMinScore: 0.5,
+		},
+	}
+	const modelKey = "openai_compatible:embed"
+
+	createDoc := func(name, whatsAppAccount string, status models.KnowledgeDocumentStatus, chunks map[string][]float32) {
+		doc := models.KnowledgeDocument{
+			BaseModel:       models.BaseModel{ID: uuid.New()},
+			OrganizationID:  org.ID,
+			WhatsAppAccount: whatsAppAccount,
+			Name:            name,
+			Status:          status,
+			IsEnabled:       true,
+			EmbeddingModel:  modelKey,
+		}
+		require.NoError(t, app.DB.Create(&doc).Error)
+		i := 0
+		for content, vec := range chunks {
+			require.NoError(t, app.DB.Create(&models.KnowledgeChunk{
+				OrganizationID: org.ID,
+				DocumentID:     doc.ID,
+				ChunkIndex:     i,
+				Heading:        "Page 1",
+				Content:        content,
+				Embedding:      vec,
+				EmbeddingModel: modelKey,
+			}).Error)
+			i++
+		}
+	}
+	createDoc("Returns", "", models.KnowledgeDocumentReady, map[string][]float32{
+		"Returns are accepted within 30 days.": {1, 0},
+		"Our office cat is called Mochi.":      {0, 1},
+	})
+	createDoc("Shipping", account.Name, models.KnowledgeDocumentReady, map[string][]float32{
+		"Shipping takes 3 days.": {0.8, 0.6},
+	})
+	createDoc("Other account", "other", models.KnowledgeDocumentReady, map[string][]float32{
+		"Only for the other account.": {1, 0},
+	})
+	createDoc("Still indexing", "", models.KnowledgeDocumentProcessing, map[string][]float32{
+		"Not ready yet.": {1, 0},
+	})
+
+	matches, usage, err := app.searchKnowledge(context.Background(), settings.AI, org.ID, account.Name, "how do returns work", 5, 0.5)
+	require.NoError(t, err)
+	assert.Equal(t, 3, usage.PromptTokens)
+	require.Len(t, matches, 2)
+	assert.Equal(t, "Returns are accepted within 30 days.", matches[0].Content)
+	assert.InDelta(t, 1.0, matches[0].Score, 1e-6)
+	assert.Equal(t, "Shipping", matches[1].DocumentName)
+	assert.InDelta(t, 0.8, matches[1].Score, 1e-6)
+
+	session := &models.ChatbotSession{WhatsAppAccount: account.Name}
+	prompt, citations := app.buildKnowledgeContext(settings, session, "how do returns work")
+	require.Len(t, citations, 2)
+	assert.Equal(t, "Returns", citations[0].DocumentName)
+	assert.Contains(t, prompt, "[1] Returns — Page 1\nReturns are accepted within 30 days.")
+	assert.NotContains(t, prompt, "Mochi")
+
+	settings.AI.KnowledgeTopK = 0
+	prompt, citations = app.buildKnowledgeContext(settings, session, "how do returns work")
+	assert.Empty(t, prompt, "retrieval is disabled when top k is 0")
+	assert.Nil(t, citations)
+}
@@ -1,20 +1,25 @@
 package handlers
 
 import (
+	"bytes"
 	"context"
+	"errors"
 	"fmt"
-	"os"
+	"mime"
+	"path"
 	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/shridarpatil/whatomate/internal/models"
+	"github.com/shridarpatil/whatomate/internal/storage"
 	"github.com/shridarpatil/whatomate/pkg/whatsapp"
 	"github.com/valyala/fasthttp"
 	"github.com/zerodha/fastglue"
 )
 
-// getMediaStoragePath returns the base path for media storage
+// getMediaStoragePath returns the base path for local media storage
 func (a *App) getMediaStoragePath() string {
 	basePath := a.Config.Storage.LocalPath
 	if basePath == "" {
@@ -23,10 +28,117 @@ func (a *App) getMediaStoragePath() string {
 	return basePath
 }
 
-// ensureMediaDir ensures the media directory exists
-func (a *App) ensureMediaDir(subdir string) error {
-	path := filepath.Join(a.getMediaStoragePath(), subdir)
-	return os.MkdirAll(path, 0755)
+// mediaStorage returns the configured media storage backend, falling back to
+// the local media directory when none is set.
+func (a *App) mediaStorage() storage.Backend {
+	if a.Storage != nil {
+		return a.Storage
+	}
+	return storage.NewLocal(a.getMediaStoragePath())
+}
+
+// getMediaSubdir returns the storage subdirectory for a media type
+func getMediaSubdir(mimeType string) string {
+	switch {
+	case strings.HasPrefix(mimeType, "image/"):
+		return "images"
+	case strings.HasPrefix(mimeType, "video/"):
+		return "videos"
+	case strings.HasPrefix(mimeType, "audio/"):
+		return "audio"
+	default:
+		return "documents"
+	}
+}
+
+// storeMedia uploads data to media storage at key (e.g. "images/<uuid>.jpg")
+func (a *App) storeMedia(ctx context.Context, key string, data []byte, mimeType string) error {
+	if err := a.mediaStorage().Upload(ctx, key, bytes.NewReader(data), mimeType); err != nil {
+		return fmt.Errorf("failed to save media file: %w", err)
+	}
+	return nil
+}
+
+// inlineMediaTypes are the content types media is served inline with. The
+// type of received media is declared by the sender, so anything else (HTML,
+// SVG, PDF, ...) is served as a download to keep it from running in the app's
+// origin.
+var inlineMediaTypes = map[string]bool{
+	"image/jpeg": true,
+	"image/png":  true,
+	"image/gif":  true,
+	"image/webp": true,
+	"video/mp4":  true,
+	"video/3gpp": true,
+	"audio/mpeg": true,
+	"audio/aac":  true,
+	"audio/mp4":  true,
+	"audio/ogg":  true,
+	"audio/amr":  true,
+}
+
+// mediaResponseHeaders returns the Content-Type and Content-Disposition to
+// serve stored media with. Types that are not safe to render inline are sent
+// as an octet-stream attachment.
+func mediaResponseHeaders(key, contentType string) (string, string) {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err == nil && inlineMediaTypes[mediaType] {
+		return mediaType, ""
+	}
+	return "application/octet-stream", mime.FormatMediaType("attachment", map[string]string{"filename": path.Base(key)})
+}
+
+// serveStoredMedia writes the object at key to the response. Backends that
+// support presigned URLs redirect to the object unless serve_mode is "stream".
+func (a *App) serveStoredMedia(r *fastglue.Request, key, contentType string) error {
+	key = path.Clean("/" + filepath.ToSlash(key))[1:]
+	if key == "" {
+		return r.SendErrorEnvelope(fasthttp.StatusBadRequest, "Invalid file path", nil, "")
+	}
+	if contentType == "" {
+		contentType = getMimeTypeFromExtension(strings.ToLower(path.Ext(key)))
+	}
+	contentType, disposition := mediaResponseHeaders(key, contentType)
+
+	backend := a.mediaStorage()
+	ctx := r.RequestCtx
+
+	if presigner, ok := backend.(storage.Presigner); ok && a.Config.Storage.ServeMode != "stream" {
+		expiry := time.Duration(a.Config.Storage.PresignExpiry) * time.Second
+		if expiry <= 0 {
+			expiry = 15 * time.Minute
+		}
+		url, err := presigner.GetPresignedDownloadURL(ctx, key, expiry, contentType, disposition)
+		if err != nil {
+			a.Log.Error("Failed to presign media URL", "key", key, "error", err)
+			return r.SendErrorEnvelope(fasthttp.StatusInternalServerError, "Failed to read file", nil, "")
+		}
+		r.RequestCtx.Response.Header.Set("Cache-Control", "no-store")
+		r.RequestCtx.Redirect(url, fasthttp.StatusFound)
+		return nil
+	}
+
+	body, info, err := backend.Open(ctx, key)
+	if err != nil {
+		switch {
+		case errors.Is(err, storage.ErrNotFound):
+			return r.SendErrorEnvelope(fasthttp.StatusNotFound, "File not found", nil, "")
+		case errors.Is(err, storage.ErrInvalidKey):
+			return r.SendErrorEnvelope(fasthttp.StatusBadRequest, "Invalid file path", nil, "")
+		}
+		a.Log.Error("Failed to read media file", "key", key, "error", err)
+		return r.SendErrorEnvelope(fasthttp.StatusInternalServerError, "Failed to read file", nil, "")
+	}
+
+	r.RequestCtx.Response.Header.Set("Content-Type", contentType)
+	r.RequestCtx.Response.Header.Set("X-Content-Type-Options", "nosniff")
+	if disposition != "" {
+		r.RequestCtx.Response.Header.Set("Content-Disposition", disposition)
+	}
+	r.RequestCtx.Response.Header.Set("Cache-Control", "private, max-age=3600") // Cache for 1 hour, private
+	r.RequestCtx.SetBodyStream(body, int(info.Size))
+
+	return nil
 }
 
 // getExtensionFromMimeType returns file extension based on mime type
@@ -75,8 +187,8 @@ func getExtensionFromMimeType(mimeType string) string {
 	}
 }
 
-// DownloadAndSaveMedia downloads media from Meta and saves it locally
-// Returns the local file path (relative to media storage) or error
+// DownloadAndSaveMedia downloads media from Meta and saves it to media storage
+// Returns the storage key (path relative to media storage) or error
 func (a *App) DownloadAndSaveMedia(ctx context.Context, mediaID string, mimeType string, account *whatsapp.Account) (string, error) {
 	// Get the media URL from Meta
 	mediaURL, err := a.WhatsApp.GetMediaURL(ctx, mediaID, account)
@@ -99,38 +211,20 @@ func (a *App) DownloadAndSaveMedia(ctx context.Context, mediaID string, mimeType
 	// Generate unique filename
 	filename := uuid.New().String() + ext
 
-	// Determine subdirectory based on media type
-	var subdir string
-	switch {
-	case strings.HasPrefix(mimeType, "image/"):
-		subdir = "images"
-	case strings.HasPrefix(mimeType, "video/"):
-		subdir = "videos"
-	case strings.HasPrefix(mimeType, "audio/"):
-		subdir = "audio"
-	default:
-		subdir = "documents"
-	}
-
-	// Ensure directory exists
-	if err := a.ensureMediaDir(subdir); err != nil {
-		return "", fmt.Errorf("failed to create media directory: %w", err)
-	}
-
 	// Save file
-	filePath := filepath.Join(a.getMediaStoragePath(), subdir, filename)
-	if err := os.WriteFile(filePath, data, 0644); err != nil {
-		return "", fmt.Errorf("failed to save media file: %w", err)
+	subdir := getMediaSubdir(mimeType)
+	if err := a.storeMedia(ctx, path.Join(subdir, filename), data, mimeType); err != nil {
+		return "", err
 	}
 
 	// Return relative path for storage in database
-	relativePath := filepath.Join(subdir, filename)
+	relativePath := path.Join(subdir, filename)
 	a.Log.Info("Media saved", "path", relativePath, "size", len(data))
 
 	return relativePath, nil
 }
 
-// ServeMedia serves media files from local storage
+// ServeMedia serves media files from media storage
 // Only authorized users who have access to the message can view the media
 func (a *App) ServeMedia(r *fastglue.Request) error {
 	// Get auth context
@@ -176,77 +270,35 @@ func (a *App) ServeMedia(r *fastglue.Request) error {
 		return r.SendErrorEnvelope(fasthttp.StatusNotFound, "No media found", nil, "")
 	}
 
-	// Security: prevent directory traversal and symlink attacks
-	filePath := filepath.Clean(message.MediaURL)
-	baseDir, err := filepath.Abs(a.getMediaStoragePath())
-	if err != nil {
-		a.Log.Error("Storage configuration error", "error", err)
-		return r.SendErrorEnvelope(fasthttp.StatusInternalServerError, "Storage configuration error", nil, "")
-	}
-	fullPath, err := filepath.Abs(filepath.Join(baseDir, filePath))
-	if err != nil || !strings.HasPrefix(fullPath, baseDir+string(os.PathSeparator)) {
-		return r.SendErrorEnvelope(fasthttp.StatusBadRequest, "Invalid file path", nil, "")
-	}
-
-	// Reject symlinks
-	info, err := os.Lstat(fullPath)
-	if err != nil {
-		return r.SendErrorEnvelope(fasthttp.StatusNotFound, "File not found", nil, "")
-	}
-	if info.Mode()&os.ModeSymlink != 0 {
-		return r.SendErrorEnvelope(fasthttp.StatusBadRequest, "Invalid file path", nil, "")
+	// Prefer the stored mime type, otherwise determine from extension
+	contentType := message.MediaMimeType
+	if contentType == "" {
+		contentType = mediaContentTypeFromExtension(strings.ToLower(filepath.Ext(message.MediaURL)))
 	}
 
-	// Read file
-	data, err := os.ReadFile(fullPath)
-	if err != nil {
-		a.Log.Error("Failed to read media file", "path", fullPath, "error", err)
-		return r.SendErrorEnvelope(fasthttp.StatusInternalServerError, "Failed to read file", nil, "")
-	}
+	return a.serveStoredMedia(r, message.MediaURL, contentType)
+}
 
-	// Determine content type from extension
-	ext := strings.ToLower(filepath.Ext(filePath))
-	contentType := "application/octet-stream"
+// mediaContentTypeFromExtension returns the MIME type for a message media file extension
+func mediaContentTypeFromExtension(ext string) string {
 	switch ext {
-	case ".jpg", ".jpeg":
-		contentType = "image/jpeg"
-	case ".png":
-		contentType = "image/png"
-	case ".gif":
-		contentType = "image/gif"
-	case ".webp":
-		contentType = "image/webp"
-	case ".mp4":
-		contentType = "video/mp4"
-	case ".3gp":
-		contentType = "video/3gpp"
 	case ".mp3":
-		contentType = "audio/mpeg"
+		return "audio/mpeg"
 	case ".aac":
-		contentType = "audio/aac"
+		return "audio/aac"
 	case ".m4a":
-		contentType = "audio/mp4"
+		return "audio/mp4"
 	case ".ogg":
-		contentType = "audio/ogg"
+		return "audio/ogg"
 	case ".amr":
-		contentType = "audio/amr"
-	case ".pdf":
-		contentType = "application/pdf"
-	case ".doc":
-		contentType = "application/msword"
-	case ".docx":
-		contentType = "application/vnd.openxmlformats-officedocument.wordprocessingml.document"
+		return "audio/amr"
 	case ".xls":
-		contentType = "application/vnd.ms-excel"
+		return "application/vnd.ms-excel"
 	case ".xlsx":
-		contentType = "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+		return "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
 	case ".txt":
-		contentType = "text/plain"
+		return "text/plain"
+	default:
+		return getMimeTypeFromExtension(ext)
 	}
-
-	r.RequestCtx.Response.Header.Set("Content-Type", contentType)
-	r.RequestCtx.Response.Header.Set("Cache-Control", "private, max-age=3600") // Cache for 1 hour, private
-	r.RequestCtx.SetBody(data)
-
-	return nil
 }
@@ -0,0 +1,25 @@
+package handlers
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMediaResponseHeaders(t *testing.T) {
+	for _, tc := range []struct {
+		key, contentType          string
+		wantType, wantDisposition string
+	}{
+		{"images/a.jpg", "image/jpeg", "image/jpeg", ""},
+		{"audio/a.ogg", "audio/ogg; codecs=opus", "audio/ogg", ""},
+		{"documents/a.html", "text/html", "application/octet-stream", `attachment; filename=a.html`},
+		{"images/a.svg", "image/svg+xml", "application/octet-stream", `attachment; filename=a.svg`},
+		{"documents/report 1.pdf", "application/pdf", "application/octet-stream", `attachment; filename="report 1.pdf"`},
+		{"documents/a.bin", "not a type", "application/octet-stream", `attachment; filename=a.bin`},
+	} {
+		contentType, disposition := mediaResponseHeaders(tc.key, tc.contentType)
+		assert.Equal(t, tc.wantType, contentType, tc.contentType)
+		assert.Equal(t, tc.wantDisposition, disposition, tc.contentType)
+	}
+}
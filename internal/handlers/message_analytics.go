@@ -0,0 +1,267 @@
+package handlers
+
+import (
+	"time"
+
+	"github.com/shridarpatil/whatomate/internal/models"
+	"github.com/valyala/fasthttp"
+	"github.com/zerodha/fastglue"
+	"gorm.io/gorm"
+)
+
+// maxFailureReasons caps the failure reasons breakdown
+const maxFailureReasons = 20
+
+// analyticsGroupBy maps the group_by query param to a DATE_TRUNC unit and the
+// format used for bucket labels
+var analyticsGroupBy = map[string]string{
+	"hour":  "2006-01-02T15:00:00Z07:00",
+	"day":   "2006-01-02",
+	"week":  "2006-01-02",
+	"month": "2006-01",
+}
+
+// MessageVolumePoint is the number of messages in one time bucket
+type MessageVolumePoint struct {
+	Date     string `json:"date"`
+	Incoming int64  `json:"incoming"`
+	Outgoing int64  `json:"outgoing"`
+	Total    int64  `json:"total"`
+}
+
+// DeliveryStats holds delivery and read rates for outgoing messages.
+// DeliveryRate is delivered (or read) over sent; ReadRate is read over delivered.
+type DeliveryStats struct {
+	Key          string  `json:"key"`
+	Total        int64   `json:"total"`
+	Sent         int64   `json:"sent"`
+	Delivered    int64   `json:"delivered"`
+	Read         int64   `json:"read"`
+	Failed       int64   `json:"failed"`
+	Pending      int64   `json:"pending"`
+	DeliveryRate float64 `json:"delivery_rate"`
+	ReadRate     float64 `json:"read_rate"`
+	FailureRate  float64 `json:"failure_rate"`
+}
+
+// FailureReason is the number of failed messages with one error message
+type FailureReason struct {
+	Reason string `json:"reason"`
+	Count  int64  `json:"count"`
+}
+
+// MessageAnalyticsResponse is the response of GetMessageAnalytics
+type MessageAnalyticsResponse struct {
+	Total          int64                `json:"total"`
+	ByDirection    map[string]int64     `json:"by_direction"`
+	ByType         map[string]int64     `json:"by_type"`
+	ByStatus       map[string]int64     `json:"by_status"`
+	Volume         []MessageVolumePoint `json:"volume"`
+	Delivery       DeliveryStats        `json:"delivery"`
+	ByAccount      []DeliveryStats      `json:"by_account"`
+	ByTemplate     []DeliveryStats      `json:"by_template"`
+	FailureReasons []FailureReason      `json:"failure_reasons"`
+	GroupBy        string               `json:"group_by"`
+	From           string               `json:"from"`
+	To             string               `json:"to"`
+}
+
+// GetMessageAnalytics returns message volume, delivery and read rates and
+// failure reasons for the organization
+func (a *App) GetMessageAnalytics(r *fastglue.Request) error {
+	orgID, userID, err := a.getOrgAndUserID(r)
+	if err != nil {
+		return r.SendErrorEnvelope(fasthttp.StatusUnauthorized, "Unauthorized", nil, "")
+	}
+
+	if err := a.requirePermission(r, userID, models.ResourceAnalytics, models.ActionRead); err != nil {
+		return nil
+	}
+
+	periodStart, periodEnd, err := parseAnalyticsPeriod(r)
+	if err != nil {
+		return nil
+	}
+	groupBy, err := parseAnalyticsGroupBy(r)
+	if err != nil {
+		return nil
+	}
+	account := string(r.RequestCtx.QueryArgs().Peek("whatsapp_account"))
+
+	scope := func() *gorm.DB {
+		q := a.DB.Model(&models.Message{}).
+			Where("organization_id = ? AND created_at >= ? AND created_at <= ?", orgID, periodStart, periodEnd)
+		if account != "" {
+			q = q.Where("whats_app_account = ?", account)
+		}
+		return q
+	}
+
+	response := MessageAnalyticsResponse{
+		ByDirection:    map[string]int64{},
+		ByType:         map[string]int64{},
+		ByStatus:       map[string]int64{},
+		Volume:         []MessageVolumePoint{},
+		ByAccount:      []DeliveryStats{},
+		ByTemplate:     []DeliveryStats{},
+		FailureReasons: []FailureReason{},
+		GroupBy:        groupBy,
+		From:           periodStart.Format(time.RFC3339),
+		To:             periodEnd.Format(time.RFC3339),
+	}
+
+	type countRow struct {
+		Key   string
+		Count int64
+	}
+	for column, target := range map[string]map[string]int64{
+		"direction":    response.ByDirection,
+		"message_type": response.ByType,
+		"status":       response.ByStatus,
+	} {
+		var rows []countRow
+		if err := scope().Select(column + " AS key, COUNT(*) AS count").Group(column).Scan(&rows).Error; err != nil {
+			a.Log.Error("Failed to load message analytics", "error", err, "group", column)
+			return r.SendErrorEnvelope(fasthttp.StatusInternalServerError, "Failed to load message analytics", nil, "")
+		}
+		for _, row := range rows {
+			target[row.Key] = row.Count
+		}
+	}
+	for _, count := range response.ByDirection {
+		response.Total += count
+	}
+
+	// Volume over time
+	type volumeRow struct {
+		Bucket    time.Time
+		Direction models.Direction
+		Count     int64
+	}
+	var volume []volumeRow
+	bucket := "DATE_TRUNC('" + groupBy + "', created_at)"
+	if err := scope().Select(bucket + " AS bucket, direction, COUNT(*) AS count").
+		Group(bucket + ", direction").
+		Order("bucket ASC").
+		Scan(&volume).Error; err != nil {
+		a.Log.Error("Failed to load message analytics", "error", err, "group", "volume")
+		return r.SendErrorEnvelope(fasthttp.StatusInternalServerError, "Failed to load message analytics", nil, "")
+	}
+	for _, row := range volume {
+		label := row.Bucket.Format(analyticsGroupBy[groupBy])
+		n := len(response.Volume)
+		if n == 0 || response.Volume[n-1].Date != label {
+			response.Volume = append(response.Volume, MessageVolumePoint{Date: label})
+			n++
+		}
+		point := &response.Volume[n-1]
+		switch row.Direction {
+		case models.DirectionIncoming:
+			point.Incoming += row.Count
+		case models.DirectionOutgoing:
+			point.Outgoing += row.Count
+		}
+		point.Total += row.Count
+	}
+
+	// Delivery and read rates of outgoing messages
+	deliverySelect := `COUNT(*) AS total,
+		COUNT(*) FILTER (WHERE status IN ('sent', 'delivered', 'read')) AS sent,
+		COUNT(*) FILTER (WHERE status IN ('delivered', 'read')) AS delivered,
+		COUNT(*) FILTER (WHERE status = 'read') AS read,
+		COUNT(*) FILTER (WHERE status = 'failed') AS failed,
+		COUNT(*) FILTER (WHERE status = 'pending') AS pending`
+	outgoing := func() *gorm.DB {
+		return scope().Where("direction = ?", models.DirectionOutgoing)
+	}
+
+	if err := outgoing().Select("'' AS key, " + deliverySelect).Scan(&response.Delivery).Error; err != nil {
+		a.Log.Error("Failed to load message analytics", "error", err, "group", "delivery")
+		return r.SendErrorEnvelope(fasthttp.StatusInternalServerError, "Failed to load message analytics", nil, "")
+	}
+	response.Delivery.computeRates()
+
+	if err := outgoing().Select("whats_app_account AS key, " + deliverySelect).
+		Group("whats_app_account").
+		Order("total DESC").
+		Scan(&response.ByAccount).Error; err != nil {
+		a.Log.Error("Failed to load message analytics", "error", err, "group", "whats_app_account")
+		return r.SendErrorEnvelope(fasthttp.StatusInternalServerError, "Failed to load message analytics", nil, "")
+	}
+	for i := range response.ByAccount {
+		response.ByAccount[i].computeRates()
+	}
+
+	if err := outgoing().Where("template_name <> ''").
+		Select("template_name AS key, " + deliverySelect).
+		Group("template_name").
+		Order("total DESC").
+		Scan(&response.ByTemplate).Error; err != nil {
+		a.Log.Error("Failed to load message analytics", "error", err, "group", "template_name")
+		return r.SendErrorEnvelope(fasthttp.StatusInternalServerError, "Failed to load message analytics", nil, "")
+	}
+	for i := range response.ByTemplate {
+		response.ByTemplate[i].computeRates()
+	}
+
+	// Failure reasons
+	if err := scope().Where("status = ?", models.MessageStatusFailed).
+		Select("COALESCE(NULLIF(error_message, ''), 'Unknown') AS reason, COUNT(*) AS count").
+		Group("reason").
+		Order("count DESC").
+		Limit(maxFailureReasons).
+		Scan(&response.FailureReasons).Error; err != nil {
+		a.Log.Error("Failed to load message analytics", "error", err, "group", "failure_reason")
+		return r.SendErrorEnvelope(fasthttp.StatusInternalServerError, "Failed to load message analytics", nil, "")
+	}
+
+	return r.SendEnvelope(response)
+}
+
+// computeRates fills in the rates from the counts
+func (s *DeliveryStats) computeRates() {
+	s.DeliveryRate = percentage(s.Delivered, s.Sent)
+	s.ReadRate = percentage(s.Read, s.Delivered)
+	s.FailureRate = percentage(s.Failed, s.Total)
+}
+
+// percentage returns part as a percentage of whole, or 0 when whole is 0
+func percentage(part, whole int64) float64 {
+	if whole == 0 {
+		return 0
+	}
+	return float64(part) / float64(whole) * 100.0
+}
+
+// parseAnalyticsPeriod parses the from/to query params, defaulting to the
+// current month. Sends the error response itself.
+func parseAnalyticsPeriod(r *fastglue.Request) (time.Time, time.Time, error) {
+	fromStr := string(r.RequestCtx.QueryArgs().Peek("from"))
+	toStr := string(r.RequestCtx.QueryArgs().Peek("to"))
+
+	if fromStr != "" && toStr != "" {
+		start, end, errMsg := parseDateRange(fromStr, toStr)
+		if errMsg != "" {
+			_ = r.SendErrorEnvelope(fasthttp.StatusBadRequest, errMsg, nil, "")
+			return time.Time{}, time.Time{}, errEnvelopeSent
+		}
+		return start, end, nil
+	}
+
+	now := time.Now()
+	return time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC), now, nil
+}
+
+// parseAnalyticsGroupBy parses the group_by query param (hour, day, week or
+// month; default day). Sends the error response itself.
+func parseAnalyticsGroupBy(r *fastglue.Request) (string, error) {
+	groupBy := string(r.RequestCtx.QueryArgs().Peek("group_by"))
+	if groupBy == "" {
+		return "day", nil
+	}
+	if _, ok := analyticsGroupBy[groupBy]; !ok {
+		_ = r.SendErrorEnvelope(fasthttp.StatusBadRequest, "Invalid group_by. Use hour, day, week or month", nil, "")
+		return "", errEnvelopeSent
+	}
+	return groupBy, nil
+}
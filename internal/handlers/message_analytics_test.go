@@ -0,0 +1,92 @@
+package handlers_test
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/shridarpatil/whatomate/internal/handlers"
+	"github.com/shridarpatil/whatomate/internal/models"
+	"github.com/shridarpatil/whatomate/test/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/valyala/fasthttp"
+)
+
+func TestApp_GetMessageAnalytics_Success(t *testing.T) {
+	app := newTestApp(t)
+	org := testutil.CreateTestOrganization(t, app.DB)
+	adminRole := testutil.CreateAdminRole(t, app.DB, org.ID)
+	user := testutil.CreateTestUser(t, app.DB, org.ID, testutil.WithRoleID(&adminRole.ID))
+	contact := testutil.CreateTestContact(t, app.DB, org.ID)
+
+	now := time.Now().UTC()
+	createTestMessage(t, app, org.ID, contact.ID, models.DirectionIncoming, now.Add(-time.Hour))
+	delivered := createTestMessage(t, app, org.ID, contact.ID, models.DirectionOutgoing, now.Add(-time.Hour))
+	read := createTestMessage(t, app, org.ID, contact.ID, models.DirectionOutgoing, now.Add(-time.Hour))
+	failed := createTestMessage(t, app, org.ID, contact.ID, models.DirectionOutgoing, now.Add(-time.Hour))
+	require.NoError(t, app.DB.Model(delivered).Updates(map[string]any{"status": models.MessageStatusDelivered, "template_name": "welcome"}).Error)
+	require.NoError(t, app.DB.Model(read).Updates(map[string]any{"status": models.MessageStatusRead, "template_name": "welcome"}).Error)
+	require.NoError(t, app.DB.Model(failed).Updates(map[string]any{"status": models.MessageStatusFailed, "error_message": "Re-engagement message"}).Error)
+
+	req := testutil.NewGETRequest(t)
+	testutil.SetAuthContext(req, org.ID, user.ID)
+
+	require.NoError(t, app.GetMessageAnalytics(req))
+	require.Equal(t, fasthttp.StatusOK, testutil.GetResponseStatusCode(req))
+
+	var resp struct {
+		Data handlers.MessageAnalyticsResponse `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(testutil.GetResponseBody(req), &resp))
+
+	assert.Equal(t, int64(4), resp.Data.Total)
+	assert.Equal(t, int64(1), resp.Data.ByDirection["incoming"])
+	assert.Equal(t, int64(3), resp.Data.ByDirection["outgoing"])
+	assert.Equal(t, int64(4), resp.Data.ByType["text"])
+	assert.Equal(t, int64(1), resp.Data.ByStatus["failed"])
+	assert.Equal(t, "day", resp.Data.GroupBy)
+	require.Len(t, resp.Data.Volume, 1)
+	assert.Equal(t, int64(4), resp.Data.Volume[0].Total)
+
+	assert.Equal(t, int64(3), resp.Data.Delivery.Total)
+	assert.Equal(t, int64(2), resp.Data.Delivery.Delivered)
+	assert.Equal(t, int64(1), resp.Data.Delivery.Read)
+	assert.Equal(t, 50.0, resp.Data.Delivery.ReadRate)
+
+	require.Len(t, resp.Data.ByAccount, 1)
+	assert.Equal(t, "test-account", resp.Data.ByAccount[0].Key)
+	require.Len(t, resp.Data.ByTemplate, 1)
+	assert.Equal(t, "welcome", resp.Data.ByTemplate[0].Key)
+	assert.Equal(t, 100.0, resp.Data.ByTemplate[0].DeliveryRate)
+
+	require.Len(t, resp.Data.FailureReasons, 1)
+	assert.Equal(t, "Re-engagement message", resp.Data.FailureReasons[0].Reason)
+	assert.Equal(t, int64(1), resp.Data.FailureReasons[0].Count)
+}
+
+func TestApp_GetMessageAnalytics_InvalidGroupBy(t *testing.T) {
+	app := newTestApp(t)
+	org := testutil.CreateTestOrganization(t, app.DB)
+	adminRole := testutil.CreateAdminRole(t, app.DB, org.ID)
+	user := testutil.CreateTestUser(t, app.DB, org.ID, testutil.WithRoleID(&adminRole.ID))
+
+	req := testutil.NewGETRequest(t)
+	testutil.SetAuthContext(req, org.ID, user.ID)
+	testutil.SetQueryParam(req, "group_by", "year")
+
+	require.NoError(t, app.GetMessageAnalytics(req))
+	assert.Equal(t, fasthttp.StatusBadRequest, testutil.GetResponseStatusCode(req))
+}
+
+func TestApp_GetMessageAnalytics_NoPermission(t *testing.T) {
+	app := newTestApp(t)
+	org := testutil.CreateTestOrganization(t, app.DB)
+	user := testutil.CreateTestUser(t, app.DB, org.ID)
+
+	req := testutil.NewGETRequest(t)
+	testutil.SetAuthContext(req, org.ID, user.ID)
+
+	require.NoError(t, app.GetMessageAnalytics(req))
+	assert.Equal(t, fasthttp.StatusForbidden, testutil.GetResponseStatusCode(req))
+}
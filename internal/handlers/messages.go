@@ -2,13 +2,17 @@ package handlers
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"maps"
 	"strings"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/shridarpatil/whatomate/internal/contactutil"
 	"github.com/shridarpatil/whatomate/internal/models"
 	"github.com/shridarpatil/whatomate/internal/templateutil"
+	"github.com/shridarpatil/whatomate/internal/webhookutil"
 	"github.com/shridarpatil/whatomate/internal/websocket"
 	"github.com/shridarpatil/whatomate/pkg/whatsapp"
 	"github.com/valyala/fasthttp"
@@ -46,9 +50,11 @@ type OutgoingMessageRequest struct {
 	ButtonText      string            // For CTA URL button
 	URL             string            // For CTA URL button
 
-	// Template messages
-	Template   *models.Template
-	BodyParams map[string]string // Parameter name -> value (supports both named and positional)
+	// Template messages (IMAGE/VIDEO/DOCUMENT headers use the media fields above)
+	Template     *models.Template
+	BodyParams   map[string]string              // Parameter name -> value (supports both named and positional)
+	HeaderParams map[string]string              // TEXT header parameter name -> value
+	ButtonParams []whatsapp.TemplateButtonParam // Quick reply payloads and dynamic URL suffixes
 
 	// WhatsApp Flow messages
 	FlowID          string // Meta Flow ID
@@ -59,6 +65,9 @@ type OutgoingMessageRequest struct {
 
 	// Reply context
 	ReplyToMessage *models.Message
+
+	// Extra metadata stored on the message (e.g. AI answer citations)
+	Metadata models.JSONB
 }
 
 // MessageSendOptions configures optional behaviors for message sending
@@ -110,6 +119,16 @@ func APISendOptions() MessageSendOptions {
 	}
 }
 
+// NotificationSendOptions returns options suitable for notification rule sends
+func NotificationSendOptions() MessageSendOptions {
+	return MessageSendOptions{
+		BroadcastWebSocket: true,
+		DispatchWebhook:    true,
+		TrackSLA:           false,
+		Async:              false, // Sync so the execution log records the API result
+	}
+}
+
 // SLASendOptions returns options suitable for SLA system notifications
 func SLASendOptions() MessageSendOptions {
 	return MessageSendOptions{
@@ -123,6 +142,11 @@ func SLASendOptions() MessageSendOptions {
 // SendOutgoingMessage is the unified method for sending all types of WhatsApp messages.
 // It handles: text, media (image/video/audio/document), interactive (buttons/list/cta_url), and template messages.
 func (a *App) SendOutgoingMessage(ctx context.Context, req OutgoingMessageRequest, opts MessageSendOptions) (*models.Message, error) {
+	// Marketing templates are never sent to contacts that opted out
+	if req.Type == models.MessageTypeTemplate && req.Template.IsMarketing() && req.Contact.IsOptedOut() {
+		return nil, errContactOptedOut
+	}
+
 	// 1. Create message record
 	msg := a.createOutgoingMessage(req, opts)
 
@@ -180,7 +204,18 @@ func (a *App) SendOutgoingMessage(ctx context.Context, req OutgoingMessageReques
 			if req.Template == nil {
 				return "", fmt.Errorf("template is required for template messages")
 			}
-			components := whatsapp.BodyParamsToComponents(req.BodyParams)
+			var components []map[string]interface{}
+			header, err := a.templateHeaderComponent(sendCtx, waAccount, req)
+			if err != nil {
+				return "", err
+			}
+			if header != nil {
+				components = append(components, header)
+			} else if req.Template.HeaderType == "TEXT" {
+				components = append(components, whatsapp.HeaderParamsToComponents(req.HeaderParams)...)
+			}
+			components = append(components, whatsapp.BodyParamsToComponents(req.BodyParams)...)
+			components = append(components, whatsapp.ButtonParamsToComponents(req.ButtonParams)...)
 			return a.WhatsApp.SendTemplateMessage(sendCtx, waAccount, req.Contact.PhoneNumber, req.Template.Name, req.Template.Language, components)
 
 		case models.MessageTypeFlow:
@@ -235,6 +270,51 @@ func (a *App) toWhatsAppAccount(account *models.WhatsAppAccount) *whatsapp.Accou
 	return account.ToWAAccount()
 }
 
+// templateHeaderComponent builds the header component for media templates.
+// Uses the request's media (uploading MediaData if needed), falling back to the
+// template's own header link. Returns nil for templates without a media header.
+func (a *App) templateHeaderComponent(ctx context.Context, waAccount *whatsapp.Account, req OutgoingMessageRequest) (map[string]interface{}, error) {
+	var mediaType string
+	switch req.Template.HeaderType {
+	case "IMAGE":
+		mediaType = "image"
+	case "VIDEO":
+		mediaType = "video"
+	case "DOCUMENT":
+		mediaType = "document"
+	default:
+		return nil, nil
+	}
+
+	media := map[string]interface{}{}
+	mediaID := req.MediaID
+	if mediaID == "" && len(req.MediaData) > 0 {
+		var err error
+		mediaID, err = a.WhatsApp.UploadMedia(ctx, waAccount, req.MediaData, req.MediaMimeType, req.MediaFilename)
+		if err != nil {
+			return nil, fmt.Errorf("failed to upload header media: %w", err)
+		}
+	}
+	switch {
+	case mediaID != "":
+		media["id"] = mediaID
+	case strings.HasPrefix(req.Template.HeaderContent, "http"):
+		media["link"] = req.Template.HeaderContent
+	default:
+		return nil, nil
+	}
+	if mediaType == "document" && req.MediaFilename != "" {
+		media["filename"] = req.MediaFilename
+	}
+
+	return map[string]interface{}{
+		"type": "header",
+		"parameters": []map[string]interface{}{
+			{"type": mediaType, mediaType: media},
+		},
+	}, nil
+}
+
 // createOutgoingMessage creates a Message model from the request
 func (a *App) createOutgoingMessage(req OutgoingMessageRequest, opts MessageSendOptions) *models.Message {
 	msg := &models.Message{
@@ -290,6 +370,13 @@ func (a *App) createOutgoingMessage(req OutgoingMessageRequest, opts MessageSend
 		msg.ReplyToMessageID = &replyID
 	}
 
+	if len(req.Metadata) > 0 {
+		if msg.Metadata == nil {
+			msg.Metadata = models.JSONB{}
+		}
+		maps.Copy(msg.Metadata, req.Metadata)
+	}
+
 	return msg
 }
 
@@ -469,7 +556,7 @@ func (a *App) dispatchMessageSentWebhook(account *models.WhatsAppAccount, contac
 		sentByUserID = msg.SentByUserID.String()
 	}
 
-	a.DispatchWebhook(account.OrganizationID, models.WebhookEventMessageSent, MessageEventData{
+	a.DispatchWebhook(account.OrganizationID, models.WebhookEventMessageSent, webhookutil.MessageEventData{
 		MessageID:       msg.ID.String(),
 		ContactID:       contact.ID.String(),
 		ContactPhone:    contact.PhoneNumber,
@@ -536,6 +623,7 @@ type SendTemplateMessageRequest struct {
 	TemplateID     string            `json:"template_id"`     // Alternative: template UUID
 	TemplateParams map[string]string `json:"template_params"` // Named or positional params
 	AccountName    string            `json:"account_name"`    // Optional: specific WhatsApp account
+	Language       string            `json:"language"`        // Optional: language variant of the template to send
 }
 
 // SendTemplateMessage sends a template message to a contact or phone number
@@ -617,6 +705,14 @@ func (a *App) SendTemplateMessage(r *fastglue.Request) error {
 		contact = &c
 	}
 
+	// Send the template in the requested language, or in the contact's
+	// preferred language when the template was picked by name
+	lang := req.Language
+	if lang == "" && req.TemplateID == "" {
+		lang = contact.PreferredLanguage()
+	}
+	template = *contactutil.TemplateInLanguage(a.DB, &template, lang)
+
 	// Determine which WhatsApp account to use (explicit > template > contact > default)
 	accountName := req.AccountName
 	if accountName == "" {
@@ -664,6 +760,9 @@ func (a *App) SendTemplateMessage(r *fastglue.Request) error {
 
 	ctx := context.Background()
 	message, err := a.SendOutgoingMessage(ctx, msgReq, opts)
+	if errors.Is(err, errContactOptedOut) {
+		return r.SendErrorEnvelope(fasthttp.StatusBadRequest, "Contact has opted out of marketing messages", nil, "")
+	}
 	if err != nil {
 		a.Log.Error("Failed to send template message", "error", err)
 		return r.SendErrorEnvelope(fasthttp.StatusInternalServerError, "Failed to send template message", nil, "")
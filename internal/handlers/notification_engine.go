@@ -0,0 +1,505 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shridarpatil/whatomate/internal/contactutil"
+	"github.com/shridarpatil/whatomate/internal/cronutil"
+	"github.com/shridarpatil/whatomate/internal/models"
+	"github.com/shridarpatil/whatomate/internal/templateutil"
+)
+
+// Notification rules are configured through JSONB fields on the rule:
+//
+//	trigger_config    (scheduler) {"cron": "0 9 * * *", "timezone": "Asia/Kolkata",
+//	                  "source_url": "https://...", "source_headers": {...}, "items_path": "$.data",
+//	                  "payload": {...}}
+//	field_mappings    {"phone_number": "$.customer.phone", "contact_name": "$.customer.name",
+//	                  "params": {"name": "$.customer.name", "order_id": "$.order.id"}}
+//	conditions        {"expression": "order.status == 'shipped' AND order.total > 100"}
+//	attachment_config {"url": "$.invoice.url", "filename": "$.invoice.number", "mime_type": "application/pdf"}
+//
+// Mapping values starting with "$" are JSONPath lookups into the trigger payload
+// ("$.a.b[0].c"); anything else is used literally. Condition expressions use the
+// chatbot expression syntax with dotted payload paths as variable names.
+
+const (
+	// maxNotificationAttachmentSize caps fetched header attachments
+	maxNotificationAttachmentSize = 16 << 20
+
+	// maxNotificationSourceSize caps the JSON fetched from a scheduler source_url
+	maxNotificationSourceSize = 8 << 20
+
+	// maxNotificationItems caps how many payloads a single trigger may fan out to
+	maxNotificationItems = 1000
+
+	// notificationFetchTimeout bounds attachment and source fetches
+	notificationFetchTimeout = 30 * time.Second
+)
+
+// NotificationPreview is the resolved form of a rule for a single payload
+type NotificationPreview struct {
+	ConditionMet   bool              `json:"condition_met"`
+	PhoneNumber    string            `json:"phone_number"`
+	ContactName    string            `json:"contact_name,omitempty"`
+	TemplateParams map[string]string `json:"template_params"`
+	AttachmentURL  string            `json:"attachment_url,omitempty"`
+	AttachmentName string            `json:"attachment_filename,omitempty"`
+	Error          string            `json:"error,omitempty"`
+}
+
+// notificationRun holds what is shared by every execution of a rule in one trigger
+type notificationRun struct {
+	rule     *models.NotificationRule
+	template *models.Template
+	account  *models.WhatsAppAccount
+}
+
+// prepareNotificationRun loads the template and account a rule sends with
+func (a *App) prepareNotificationRun(rule *models.NotificationRule) (*notificationRun, error) {
+	var template models.Template
+	if err := a.DB.Where("id = ? AND organization_id = ?", rule.TemplateID, rule.OrganizationID).First(&template).Error; err != nil {
+		return nil, fmt.Errorf("template not found")
+	}
+	if template.Status != string(models.TemplateStatusApproved) {
+		return nil, fmt.Errorf("template is not approved (status: %s)", template.Status)
+	}
+
+	account, err := a.resolveWhatsAppAccount(rule.OrganizationID, rule.WhatsAppAccount)
+	if err != nil {
+		return nil, err
+	}
+
+	return &notificationRun{rule: rule, template: &template, account: account}, nil
+}
+
+// executeNotificationRule evaluates the rule against each payload, sends the
+// template where the conditions match and records an execution per payload.
+func (a *App) executeNotificationRule(ctx context.Context, rule *models.NotificationRule, payloads []map[string]interface{}, source string) []models.NotificationRuleExecution {
+	run, prepErr := a.prepareNotificationRun(rule)
+
+	executions := make([]models.NotificationRuleExecution, 0, len(payloads))
+	for _, payload := range payloads {
+		var exec models.NotificationRuleExecution
+		if prepErr != nil {
+			exec = newNotificationExecution(rule, payload, source)
+			exec.Status = models.NotificationExecutionFailed
+			exec.Error = prepErr.Error()
+		} else {
+			exec = a.executeNotification(ctx, run, payload, source)
+		}
+
+		if err := a.DB.Create(&exec).Error; err != nil {
+			a.Log.Error("Failed to record notification execution", "error", err, "rule_id", rule.ID)
+		}
+		executions = append(executions, exec)
+	}
+
+	now := time.Now()
+	a.DB.Model(&models.NotificationRule{}).Where("id = ?", rule.ID).Update("last_triggered_at", now)
+	rule.LastTriggeredAt = &now
+
+	return executions
+}
+
+// executeNotification runs a prepared rule against a single payload
+func (a *App) executeNotification(ctx context.Context, run *notificationRun, payload map[string]interface{}, source string) (exec models.NotificationRuleExecution) {
+	start := time.Now()
+	exec = newNotificationExecution(run.rule, payload, source)
+	defer func() { exec.DurationMs = time.Since(start).Milliseconds() }()
+
+	preview := resolveNotification(run.rule, run.template, payload)
+	exec.PhoneNumber = preview.PhoneNumber
+	exec.TemplateParams = stringMapToJSONB(preview.TemplateParams)
+
+	if !preview.ConditionMet {
+		exec.Status = models.NotificationExecutionSkipped
+		return exec
+	}
+	if preview.Error != "" {
+		exec.Status = models.NotificationExecutionFailed
+		exec.Error = preview.Error
+		return exec
+	}
+
+	msgReq := OutgoingMessageRequest{
+		Account:    run.account,
+		Type:       models.MessageTypeTemplate,
+		Template:   run.template,
+		BodyParams: preview.TemplateParams,
+	}
+
+	if preview.AttachmentURL != "" && isMediaHeader(run.template.HeaderType) {
+		data, mimeType, err := a.fetchNotificationAttachment(ctx, preview.AttachmentURL)
+		if err != nil {
+			exec.Status = models.NotificationExecutionFailed
+			exec.Error = err.Error()
+			return exec
+		}
+		if mt, _ := run.rule.AttachmentConfig["mime_type"].(string); mt != "" {
+			mimeType = mt
+		}
+		msgReq.MediaData = data
+		msgReq.MediaMimeType = mimeType
+		msgReq.MediaFilename = preview.AttachmentName
+	}
+
+	contact, _, err := contactutil.GetOrCreateContact(a.DB, run.rule.OrganizationID, preview.PhoneNumber, preview.ContactName)
+	if err != nil {
+		exec.Status = models.NotificationExecutionFailed
+		exec.Error = "failed to get or create contact: " + err.Error()
+		return exec
+	}
+	msgReq.Contact = contact
+
+	msg, err := a.SendOutgoingMessage(ctx, msgReq, NotificationSendOptions())
+	if err != nil {
+		exec.Status = models.NotificationExecutionFailed
+		exec.Error = err.Error()
+		return exec
+	}
+	exec.MessageID = &msg.ID
+
+	// Sends are synchronous, so the stored status reflects the API result
+	var sent models.Message
+	if err := a.DB.Select("status", "error_message").Where("id = ?", msg.ID).First(&sent).Error; err == nil &&
+		sent.Status == models.MessageStatusFailed {
+		exec.Status = models.NotificationExecutionFailed
+		exec.Error = sent.ErrorMessage
+		return exec
+	}
+
+	exec.Status = models.NotificationExecutionSent
+	return exec
+}
+
+// newNotificationExecution creates an unsaved execution record for a payload
+func newNotificationExecution(rule *models.NotificationRule, payload map[string]interface{}, source string) models.NotificationRuleExecution {
+	return models.NotificationRuleExecution{
+		BaseModel:      models.BaseModel{ID: uuid.New()},
+		OrganizationID: rule.OrganizationID,
+		RuleID:         rule.ID,
+		Source:         source,
+		Payload:        models.JSONB(payload),
+	}
+}
+
+// resolveNotification evaluates conditions and field mappings for a payload without sending
+func resolveNotification(rule *models.NotificationRule, template *models.Template, payload map[string]interface{}) NotificationPreview {
+	preview := NotificationPreview{
+		ConditionMet:   evaluateNotificationConditions(rule.Conditions, payload),
+		TemplateParams: map[string]string{},
+	}
+
+	mappings := rule.FieldMappings
+	preview.PhoneNumber = normalizeNotificationPhone(resolveMappingValue(mappings["phone_number"], payload))
+	preview.ContactName = resolveMappingValue(mappings["contact_name"], payload)
+
+	if params, ok := mappings["params"].(map[string]interface{}); ok {
+		for name, spec := range params {
+			preview.TemplateParams[name] = resolveMappingValue(spec, payload)
+		}
+	}
+
+	if rule.AttachmentConfig != nil {
+		preview.AttachmentURL = resolveMappingValue(rule.AttachmentConfig["url"], payload)
+		preview.AttachmentName = resolveMappingValue(rule.AttachmentConfig["filename"], payload)
+		if preview.AttachmentName == "" && preview.AttachmentURL != "" {
+			preview.AttachmentName = path.Base(strings.SplitN(preview.AttachmentURL, "?", 2)[0])
+		}
+	}
+
+	switch {
+	case preview.PhoneNumber == "":
+		preview.Error = "phone number not found in payload"
+	case template != nil:
+		if missing := missingTemplateParams(template, preview.TemplateParams); len(missing) > 0 {
+			preview.Error = "missing template parameters: " + strings.Join(missing, ", ")
+		} else if isMediaHeader(template.HeaderType) && preview.AttachmentURL == "" && !strings.HasPrefix(template.HeaderContent, "http") {
+			preview.Error = "template has a media header but no attachment is configured"
+		}
+	}
+
+	return preview
+}
+
+// evaluateNotificationConditions returns true when the rule has no expression or it matches
+func evaluateNotificationConditions(conditions models.JSONB, payload map[string]interface{}) bool {
+	expr, _ := conditions["expression"].(string)
+	if strings.TrimSpace(expr) == "" {
+		return true
+	}
+	flat := make(map[string]interface{})
+	flattenPayload("", payload, flat)
+	return evaluateExpression(expr, flat)
+}
+
+// flattenPayload writes every leaf of v into out keyed by its dotted path (a.b[0].c)
+func flattenPayload(prefix string, v interface{}, out map[string]interface{}) {
+	switch val := v.(type) {
+	case map[string]interface{}:
+		for k, child := range val {
+			key := k
+			if prefix != "" {
+				key = prefix + "." + k
+			}
+			flattenPayload(key, child, out)
+		}
+	case []interface{}:
+		for i, child := range val {
+			flattenPayload(fmt.Sprintf("%s[%d]", prefix, i), child, out)
+		}
+	default:
+		if prefix != "" {
+			out[prefix] = formatPayloadValue(val)
+		}
+	}
+}
+
+// lookupJSONPath resolves a "$.a.b[0].c" path against a payload
+func lookupJSONPath(payload map[string]interface{}, jsonPath string) interface{} {
+	p := strings.TrimPrefix(strings.TrimSpace(jsonPath), "$")
+	p = strings.TrimPrefix(p, ".")
+	if p == "" {
+		return payload
+	}
+	return getNestedValue(payload, p)
+}
+
+// resolveMappingValue resolves a mapping spec: JSONPath when it starts with "$", literal otherwise
+func resolveMappingValue(spec interface{}, payload map[string]interface{}) string {
+	s, ok := spec.(string)
+	if !ok {
+		if spec == nil {
+			return ""
+		}
+		return formatPayloadValue(spec)
+	}
+	if !strings.HasPrefix(s, "$") {
+		return s
+	}
+	v := lookupJSONPath(payload, s)
+	if v == nil {
+		return ""
+	}
+	return formatPayloadValue(v)
+}
+
+// formatPayloadValue renders a JSON value as a template parameter string.
+// Whole numbers are rendered without exponent or decimals (order IDs, amounts).
+func formatPayloadValue(v interface{}) string {
+	switch val := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return val
+	case float64:
+		return strconv.FormatFloat(val, 'f', -1, 64)
+	case map[string]interface{}, []interface{}:
+		b, _ := json.Marshal(val)
+		return string(b)
+	default:
+		return fmt.Sprintf("%v", val)
+	}
+}
+
+// normalizeNotificationPhone strips formatting characters from a mapped phone number
+func normalizeNotificationPhone(phone string) string {
+	return strings.Map(func(r rune) rune {
+		if r >= '0' && r <= '9' {
+			return r
+		}
+		return -1
+	}, phone)
+}
+
+// missingTemplateParams returns the template's body parameters without a value
+func missingTemplateParams(template *models.Template, params map[string]string) []string {
+	names := templateutil.ExtParamNames(template.BodyContent)
+	values := templateutil.ResolveParamsFromMap(names, params)
+
+	var missing []string
+	for i, name := range names {
+		if i >= len(values) || values[i] == "" {
+			missing = append(missing, name)
+		}
+	}
+	return missing
+}
+
+// isMediaHeader reports whether a template header type carries media
+func isMediaHeader(headerType string) bool {
+	switch headerType {
+	case "IMAGE", "VIDEO", "DOCUMENT":
+		return true
+	}
+	return false
+}
+
+// fetchNotificationAttachment downloads a header attachment, returning its bytes and content type
+func (a *App) fetchNotificationAttachment(ctx context.Context, rawURL string) ([]byte, string, error) {
+	body, contentType, err := a.fetchNotificationURL(ctx, rawURL, nil, maxNotificationAttachmentSize)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to fetch attachment: %w", err)
+	}
+	if contentType == "" || strings.HasPrefix(contentType, "application/octet-stream") {
+		contentType = http.DetectContentType(body)
+	}
+	if i := strings.Index(contentType, ";"); i >= 0 {
+		contentType = strings.TrimSpace(contentType[:i])
+	}
+	return body, contentType, nil
+}
+
+// fetchNotificationURL performs a size-limited GET against an external URL
+func (a *App) fetchNotificationURL(ctx context.Context, rawURL string, headers map[string]interface{}, maxSize int64) ([]byte, string, error) {
+	if err := validateWebhookURL(rawURL); err != nil {
+		return nil, "", err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, notificationFetchTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return nil, "", err
+	}
+	for k, v := range headers {
+		if s, ok := v.(string); ok {
+			req.Header.Set(k, s)
+		}
+	}
+
+	resp, err := a.HTTPClient.Do(req)
+	if err != nil {
+		return nil, "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, "", fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxSize+1))
+	if err != nil {
+		return nil, "", err
+	}
+	if int64(len(body)) > maxSize {
+		return nil, "", fmt.Errorf("response exceeds %d bytes", maxSize)
+	}
+
+	return body, resp.Header.Get("Content-Type"), nil
+}
+
+// parseNotificationPayloads accepts a JSON object or an array of objects
+func parseNotificationPayloads(body []byte) ([]map[string]interface{}, error) {
+	var raw interface{}
+	if len(strings.TrimSpace(string(body))) == 0 {
+		return []map[string]interface{}{{}}, nil
+	}
+	if err := json.Unmarshal(body, &raw); err != nil {
+		return nil, fmt.Errorf("invalid JSON payload")
+	}
+	return payloadItems(raw)
+}
+
+// payloadItems converts a decoded JSON value into a list of payload objects
+func payloadItems(raw interface{}) ([]map[string]interface{}, error) {
+	switch v := raw.(type) {
+	case map[string]interface{}:
+		return []map[string]interface{}{v}, nil
+	case []interface{}:
+		if len(v) > maxNotificationItems {
+			return nil, fmt.Errorf("too many items (max %d)", maxNotificationItems)
+		}
+		items := make([]map[string]interface{}, 0, len(v))
+		for _, item := range v {
+			obj, ok := item.(map[string]interface{})
+			if !ok {
+				return nil, fmt.Errorf("payload items must be JSON objects")
+			}
+			items = append(items, obj)
+		}
+		return items, nil
+	default:
+		return nil, fmt.Errorf("payload must be a JSON object or array of objects")
+	}
+}
+
+// scheduledNotificationPayloads builds the payloads for a scheduler run: the items
+// fetched from source_url when configured, otherwise the static payload.
+func (a *App) scheduledNotificationPayloads(ctx context.Context, config models.JSONB) ([]map[string]interface{}, error) {
+	sourceURL, _ := config["source_url"].(string)
+	if sourceURL == "" {
+		payload, _ := config["payload"].(map[string]interface{})
+		if payload == nil {
+			payload = map[string]interface{}{}
+		}
+		return []map[string]interface{}{payload}, nil
+	}
+
+	headers, _ := config["source_headers"].(map[string]interface{})
+	body, _, err := a.fetchNotificationURL(ctx, sourceURL, headers, maxNotificationSourceSize)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch source: %w", err)
+	}
+
+	var raw interface{}
+	if err := json.Unmarshal(body, &raw); err != nil {
+		return nil, fmt.Errorf("source did not return JSON")
+	}
+
+	if itemsPath, _ := config["items_path"].(string); itemsPath != "" {
+		obj, ok := raw.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("items_path requires the source to return a JSON object")
+		}
+		raw = lookupJSONPath(obj, itemsPath)
+	}
+	return payloadItems(raw)
+}
+
+// nextNotificationRun computes the next scheduler run after t, or nil if the
+// cron expression never fires again.
+func nextNotificationRun(config models.JSONB, t time.Time) (*time.Time, error) {
+	expr, _ := config["cron"].(string)
+	if expr == "" {
+		return nil, fmt.Errorf("cron expression is required for scheduler rules")
+	}
+	schedule, err := cronutil.Parse(expr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid cron expression: %w", err)
+	}
+
+	loc := time.UTC
+	if tz, _ := config["timezone"].(string); tz != "" {
+		if loc, err = time.LoadLocation(tz); err != nil {
+			return nil, fmt.Errorf("invalid timezone: %s", tz)
+		}
+	}
+
+	next := schedule.Next(t.In(loc))
+	if next.IsZero() {
+		return nil, nil
+	}
+	next = next.UTC()
+	return &next, nil
+}
+
+// stringMapToJSONB converts resolved params for storage
+func stringMapToJSONB(m map[string]string) models.JSONB {
+	out := make(models.JSONB, len(m))
+	for k, v := range m {
+		out[k] = v
+	}
+	return out
+}
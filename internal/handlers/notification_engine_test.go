@@ -0,0 +1,116 @@
+package handlers
+
+import (
+	"testing"
+	"time"
+
+	"github.com/shridarpatil/whatomate/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func notificationTestPayload() map[string]interface{} {
+	return map[string]interface{}{
+		"order": map[string]interface{}{
+			"id":     float64(1234567),
+			"status": "shipped",
+			"total":  float64(149.5),
+		},
+		"customer": map[string]interface{}{
+			"name":  "Alice",
+			"phone": "+1 (555) 010-2030",
+		},
+		"items": []interface{}{
+			map[string]interface{}{"sku": "A-1"},
+		},
+	}
+}
+
+func TestResolveMappingValue(t *testing.T) {
+	payload := notificationTestPayload()
+
+	assert.Equal(t, "Alice", resolveMappingValue("$.customer.name", payload))
+	assert.Equal(t, "1234567", resolveMappingValue("$.order.id", payload))
+	assert.Equal(t, "149.5", resolveMappingValue("$.order.total", payload))
+	assert.Equal(t, "A-1", resolveMappingValue("$.items[0].sku", payload))
+	assert.Equal(t, "", resolveMappingValue("$.missing.field", payload))
+	assert.Equal(t, "literal", resolveMappingValue("literal", payload))
+	assert.Equal(t, "", resolveMappingValue(nil, payload))
+}
+
+func TestEvaluateNotificationConditions(t *testing.T) {
+	payload := notificationTestPayload()
+
+	assert.True(t, evaluateNotificationConditions(nil, payload))
+	assert.True(t, evaluateNotificationConditions(models.JSONB{"expression": ""}, payload))
+	assert.True(t, evaluateNotificationConditions(models.JSONB{"expression": "order.status == 'shipped' AND order.total > 100"}, payload))
+	assert.False(t, evaluateNotificationConditions(models.JSONB{"expression": "order.status == 'pending'"}, payload))
+	assert.True(t, evaluateNotificationConditions(models.JSONB{"expression": "items[0].sku == 'A-1'"}, payload))
+}
+
+func TestResolveNotification(t *testing.T) {
+	template := &models.Template{BodyContent: "Hi {{name}}, order {{order_id}} has shipped."}
+	rule := &models.NotificationRule{
+		FieldMappings: models.JSONB{
+			"phone_number": "$.customer.phone",
+			"contact_name": "$.customer.name",
+			"params": map[string]interface{}{
+				"name":     "$.customer.name",
+				"order_id": "$.order.id",
+			},
+		},
+		AttachmentConfig: models.JSONB{"url": "https://example.com/invoices/inv-1.pdf?sig=x"},
+	}
+
+	preview := resolveNotification(rule, template, notificationTestPayload())
+	assert.True(t, preview.ConditionMet)
+	assert.Empty(t, preview.Error)
+	assert.Equal(t, "15550102030", preview.PhoneNumber)
+	assert.Equal(t, "Alice", preview.ContactName)
+	assert.Equal(t, map[string]string{"name": "Alice", "order_id": "1234567"}, preview.TemplateParams)
+	assert.Equal(t, "inv-1.pdf", preview.AttachmentName)
+
+	// Missing params are reported
+	rule.FieldMappings["params"] = map[string]interface{}{"name": "$.customer.name"}
+	preview = resolveNotification(rule, template, notificationTestPayload())
+	assert.Equal(t, "missing template parameters: order_id", preview.Error)
+
+	// Missing phone is reported
+	preview = resolveNotification(rule, template, map[string]interface{}{})
+	assert.Equal(t, "phone number not found in payload", preview.Error)
+}
+
+func TestParseNotificationPayloads(t *testing.T) {
+	items, err := parseNotificationPayloads([]byte(`{"a": 1}`))
+	require.NoError(t, err)
+	assert.Len(t, items, 1)
+
+	items, err = parseNotificationPayloads([]byte(`[{"a": 1}, {"a": 2}]`))
+	require.NoError(t, err)
+	assert.Len(t, items, 2)
+
+	items, err = parseNotificationPayloads(nil)
+	require.NoError(t, err)
+	assert.Len(t, items, 1)
+
+	_, err = parseNotificationPayloads([]byte(`[1, 2]`))
+	assert.Error(t, err)
+
+	_, err = parseNotificationPayloads([]byte(`not json`))
+	assert.Error(t, err)
+}
+
+func TestNextNotificationRun(t *testing.T) {
+	now := time.Date(2026, 3, 4, 4, 0, 0, 0, time.UTC)
+
+	next, err := nextNotificationRun(models.JSONB{"cron": "0 9 * * *", "timezone": "Asia/Kolkata"}, now)
+	require.NoError(t, err)
+	require.NotNil(t, next)
+	assert.Equal(t, time.Date(2026, 3, 5, 3, 30, 0, 0, time.UTC), *next)
+
+	_, err = nextNotificationRun(models.JSONB{}, now)
+	assert.Error(t, err)
+
+	_, err = nextNotificationRun(models.JSONB{"cron": "0 9 * * *", "timezone": "Mars/Olympus"}, now)
+	assert.Error(t, err)
+}
@@ -0,0 +1,538 @@
+package handlers
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shridarpatil/whatomate/internal/models"
+	"github.com/valyala/fasthttp"
+	"github.com/zerodha/fastglue"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// NotificationRuleRequest represents the request body for creating/updating a notification rule
+type NotificationRuleRequest struct {
+	WhatsAppAccount  string       `json:"whatsapp_account"`
+	Name             string       `json:"name"`
+	IsEnabled        *bool        `json:"is_enabled"`
+	TriggerType      string       `json:"trigger_type"`
+	TriggerConfig    models.JSONB `json:"trigger_config"`
+	TemplateID       string       `json:"template_id"`
+	FieldMappings    models.JSONB `json:"field_mappings"`
+	Conditions       models.JSONB `json:"conditions"`
+	AttachmentConfig models.JSONB `json:"attachment_config"`
+}
+
+// NotificationRuleResponse is a rule plus its trigger key, which is only
+// included when the key is generated.
+type NotificationRuleResponse struct {
+	models.NotificationRule
+	TriggerKey string `json:"trigger_key,omitempty"`
+}
+
+// NotificationTriggerResponse summarises a trigger call
+type NotificationTriggerResponse struct {
+	Sent       int                                `json:"sent"`
+	Skipped    int                                `json:"skipped"`
+	Failed     int                                `json:"failed"`
+	Executions []models.NotificationRuleExecution `json:"executions"`
+}
+
+// generateNotificationTriggerKey generates a per-rule trigger key
+func generateNotificationTriggerKey() (string, error) {
+	bytes := make([]byte, 16) // 32 hex chars
+	if _, err := rand.Read(bytes); err != nil {
+		return "", err
+	}
+	return "whn_" + hex.EncodeToString(bytes), nil
+}
+
+// assignNotificationTriggerKey generates a new trigger key for the rule, returning the plain key
+func assignNotificationTriggerKey(rule *models.NotificationRule) (string, error) {
+	key, err := generateNotificationTriggerKey()
+	if err != nil {
+		return "", err
+	}
+	hashed, err := bcrypt.GenerateFromPassword([]byte(key), bcrypt.DefaultCost)
+	if err != nil {
+		return "", err
+	}
+	rule.TriggerKeyPrefix = key[4:20]
+	rule.TriggerKeyHash = string(hashed)
+	return key, nil
+}
+
+// ListNotificationRules returns all notification rules for the organization
+func (a *App) ListNotificationRules(r *fastglue.Request) error {
+	orgID, userID, err := a.getOrgAndUserID(r)
+	if err != nil {
+		return r.SendErrorEnvelope(fasthttp.StatusUnauthorized, "Unauthorized", nil, "")
+	}
+	if err := a.requirePermission(r, userID, models.ResourceNotifications, models.ActionRead); err != nil {
+		return nil
+	}
+
+	pg := parsePagination(r)
+	triggerType := string(r.RequestCtx.QueryArgs().Peek("trigger_type"))
+
+	query := a.DB.Model(&models.NotificationRule{}).Where("organization_id = ?", orgID)
+	if triggerType != "" {
+		query = query.Where("trigger_type = ?", triggerType)
+	}
+
+	var total int64
+	query.Count(&total)
+
+	var rules []models.NotificationRule
+	if err := pg.Apply(query.Preload("Template").Order("created_at DESC")).Find(&rules).Error; err != nil {
+		a.Log.Error("Failed to list notification rules", "error", err)
+		return r.SendErrorEnvelope(fasthttp.StatusInternalServerError, "Failed to list notification rules", nil, "")
+	}
+
+	return r.SendEnvelope(map[string]any{
+		"rules": rules,
+		"total": total,
+		"page":  pg.Page,
+		"limit": pg.Limit,
+	})
+}
+
+// GetNotificationRule returns a single notification rule
+func (a *App) GetNotificationRule(r *fastglue.Request) error {
+	orgID, userID, err := a.getOrgAndUserID(r)
+	if err != nil {
+		return r.SendErrorEnvelope(fasthttp.StatusUnauthorized, "Unauthorized", nil, "")
+	}
+	if err := a.requirePermission(r, userID, models.ResourceNotifications, models.ActionRead); err != nil {
+		return nil
+	}
+
+	ruleID, err := parsePathUUID(r, "id", "notification rule")
+	if err != nil {
+		return nil
+	}
+
+	rule, err := findByIDAndOrg[models.NotificationRule](a.DB, r, ruleID, orgID, "Notification rule")
+	if err != nil {
+		return nil
+	}
+
+	return r.SendEnvelope(rule)
+}
+
+// CreateNotificationRule creates a new notification rule
+func (a *App) CreateNotificationRule(r *fastglue.Request) error {
+	orgID, userID, err := a.getOrgAndUserID(r)
+	if err != nil {
+		return r.SendErrorEnvelope(fasthttp.StatusUnauthorized, "Unauthorized", nil, "")
+	}
+	if err := a.requirePermission(r, userID, models.ResourceNotifications, models.ActionWrite); err != nil {
+		return nil
+	}
+
+	var req NotificationRuleRequest
+	if err := a.decodeRequest(r, &req); err != nil {
+		return nil
+	}
+
+	rule := models.NotificationRule{
+		BaseModel:        models.BaseModel{ID: uuid.New()},
+		OrganizationID:   orgID,
+		WhatsAppAccount:  req.WhatsAppAccount,
+		Name:             req.Name,
+		IsEnabled:        true,
+		TriggerType:      req.TriggerType,
+		TriggerConfig:    req.TriggerConfig,
+		FieldMappings:    req.FieldMappings,
+		Conditions:       req.Conditions,
+		AttachmentConfig: req.AttachmentConfig,
+	}
+	if req.IsEnabled != nil {
+		rule.IsEnabled = *req.IsEnabled
+	}
+	if req.TemplateID != "" {
+		if rule.TemplateID, err = uuid.Parse(req.TemplateID); err != nil {
+			return r.SendErrorEnvelope(fasthttp.StatusBadRequest, "Invalid template_id", nil, "")
+		}
+	}
+
+	if msg := a.validateNotificationRule(&rule); msg != "" {
+		return r.SendErrorEnvelope(fasthttp.StatusBadRequest, msg, nil, "")
+	}
+
+	var triggerKey string
+	if rule.TriggerType == models.NotificationTriggerWebhook {
+		if triggerKey, err = assignNotificationTriggerKey(&rule); err != nil {
+			a.Log.Error("Failed to generate notification trigger key", "error", err)
+			return r.SendErrorEnvelope(fasthttp.StatusInternalServerError, "Failed to create notification rule", nil, "")
+		}
+	}
+
+	if err := a.DB.Create(&rule).Error; err != nil {
+		a.Log.Error("Failed to create notification rule", "error", err)
+		return r.SendErrorEnvelope(fasthttp.StatusInternalServerError, "Failed to create notification rule", nil, "")
+	}
+
+	return r.SendEnvelope(NotificationRuleResponse{NotificationRule: rule, TriggerKey: triggerKey})
+}
+
+// UpdateNotificationRule updates an existing notification rule
+func (a *App) UpdateNotificationRule(r *fastglue.Request) error {
+	orgID, userID, err := a.getOrgAndUserID(r)
+	if err != nil {
+		return r.SendErrorEnvelope(fasthttp.StatusUnauthorized, "Unauthorized", nil, "")
+	}
+	if err := a.requirePermission(r, userID, models.ResourceNotifications, models.ActionWrite); err != nil {
+		return nil
+	}
+
+	ruleID, err := parsePathUUID(r, "id", "notification rule")
+	if err != nil {
+		return nil
+	}
+
+	rule, err := findByIDAndOrg[models.NotificationRule](a.DB, r, ruleID, orgID, "Notification rule")
+	if err != nil {
+		return nil
+	}
+
+	var req NotificationRuleRequest
+	if err := a.decodeRequest(r, &req); err != nil {
+		return nil
+	}
+
+	// Only overwrite fields that were provided
+	if req.WhatsAppAccount != "" {
+		rule.WhatsAppAccount = req.WhatsAppAccount
+	}
+	if req.Name != "" {
+		rule.Name = req.Name
+	}
+	if req.IsEnabled != nil {
+		rule.IsEnabled = *req.IsEnabled
+	}
+	if req.TriggerType != "" {
+		rule.TriggerType = req.TriggerType
+	}
+	if req.TriggerConfig != nil {
+		rule.TriggerConfig = req.TriggerConfig
+	}
+	if req.TemplateID != "" {
+		if rule.TemplateID, err = uuid.Parse(req.TemplateID); err != nil {
+			return r.SendErrorEnvelope(fasthttp.StatusBadRequest, "Invalid template_id", nil, "")
+		}
+	}
+	if req.FieldMappings != nil {
+		rule.FieldMappings = req.FieldMappings
+	}
+	if req.Conditions != nil {
+		rule.Conditions = req.Conditions
+	}
+	if req.AttachmentConfig != nil {
+		rule.AttachmentConfig = req.AttachmentConfig
+	}
+
+	if msg := a.validateNotificationRule(rule); msg != "" {
+		return r.SendErrorEnvelope(fasthttp.StatusBadRequest, msg, nil, "")
+	}
+
+	// Rules switched to the webhook trigger need a key
+	var triggerKey string
+	if rule.TriggerType == models.NotificationTriggerWebhook && rule.TriggerKeyHash == "" {
+		if triggerKey, err = assignNotificationTriggerKey(rule); err != nil {
+			a.Log.Error("Failed to generate notification trigger key", "error", err)
+			return r.SendErrorEnvelope(fasthttp.StatusInternalServerError, "Failed to update notification rule", nil, "")
+		}
+	}
+
+	if err := a.DB.Model(rule).Select(
+		"whats_app_account", "name", "is_enabled", "trigger_type", "trigger_config", "template_id",
+		"field_mappings", "conditions", "attachment_config", "trigger_key_prefix", "trigger_key_hash", "next_run_at",
+	).Updates(rule).Error; err != nil {
+		a.Log.Error("Failed to update notification rule", "error", err)
+		return r.SendErrorEnvelope(fasthttp.StatusInternalServerError, "Failed to update notification rule", nil, "")
+	}
+
+	return r.SendEnvelope(NotificationRuleResponse{NotificationRule: *rule, TriggerKey: triggerKey})
+}
+
+// DeleteNotificationRule deletes a notification rule
+func (a *App) DeleteNotificationRule(r *fastglue.Request) error {
+	orgID, userID, err := a.getOrgAndUserID(r)
+	if err != nil {
+		return r.SendErrorEnvelope(fasthttp.StatusUnauthorized, "Unauthorized", nil, "")
+	}
+	if err := a.requirePermission(r, userID, models.ResourceNotifications, models.ActionDelete); err != nil {
+		return nil
+	}
+
+	ruleID, err := parsePathUUID(r, "id", "notification rule")
+	if err != nil {
+		return nil
+	}
+
+	rule, err := findByIDAndOrg[models.NotificationRule](a.DB, r, ruleID, orgID, "Notification rule")
+	if err != nil {
+		return nil
+	}
+
+	if err := a.DB.Delete(rule).Error; err != nil {
+		a.Log.Error("Failed to delete notification rule", "error", err)
+		return r.SendErrorEnvelope(fasthttp.StatusInternalServerError, "Failed to delete notification rule", nil, "")
+	}
+
+	return r.SendEnvelope(map[string]string{"message": "Notification rule deleted"})
+}
+
+// RotateNotificationRuleKey issues a new trigger key, invalidating the old one
+func (a *App) RotateNotificationRuleKey(r *fastglue.Request) error {
+	orgID, userID, err := a.getOrgAndUserID(r)
+	if err != nil {
+		return r.SendErrorEnvelope(fasthttp.StatusUnauthorized, "Unauthorized", nil, "")
+	}
+	if err := a.requirePermission(r, userID, models.ResourceNotifications, models.ActionWrite); err != nil {
+		return nil
+	}
+
+	ruleID, err := parsePathUUID(r, "id", "notification rule")
+	if err != nil {
+		return nil
+	}
+
+	rule, err := findByIDAndOrg[models.NotificationRule](a.DB, r, ruleID, orgID, "Notification rule")
+	if err != nil {
+		return nil
+	}
+	if rule.TriggerType != models.NotificationTriggerWebhook {
+		return r.SendErrorEnvelope(fasthttp.StatusBadRequest, "Only webhook rules have a trigger key", nil, "")
+	}
+
+	triggerKey, err := assignNotificationTriggerKey(rule)
+	if err != nil {
+		a.Log.Error("Failed to generate notification trigger key", "error", err)
+		return r.SendErrorEnvelope(fasthttp.StatusInternalServerError, "Failed to rotate trigger key", nil, "")
+	}
+
+	if err := a.DB.Model(rule).Updates(map[string]any{
+		"trigger_key_prefix": rule.TriggerKeyPrefix,
+		"trigger_key_hash":   rule.TriggerKeyHash,
+	}).Error; err != nil {
+		a.Log.Error("Failed to rotate notification trigger key", "error", err)
+		return r.SendErrorEnvelope(fasthttp.StatusInternalServerError, "Failed to rotate trigger key", nil, "")
+	}
+
+	return r.SendEnvelope(NotificationRuleResponse{NotificationRule: *rule, TriggerKey: triggerKey})
+}
+
+// ListNotificationRuleExecutions returns the execution log of a rule
+func (a *App) ListNotificationRuleExecutions(r *fastglue.Request) error {
+	orgID, userID, err := a.getOrgAndUserID(r)
+	if err != nil {
+		return r.SendErrorEnvelope(fasthttp.StatusUnauthorized, "Unauthorized", nil, "")
+	}
+	if err := a.requirePermission(r, userID, models.ResourceNotifications, models.ActionRead); err != nil {
+		return nil
+	}
+
+	ruleID, err := parsePathUUID(r, "id", "notification rule")
+	if err != nil {
+		return nil
+	}
+
+	if _, err := findByIDAndOrg[models.NotificationRule](a.DB, r, ruleID, orgID, "Notification rule"); err != nil {
+		return nil
+	}
+
+	pg := parsePagination(r)
+	status := string(r.RequestCtx.QueryArgs().Peek("status"))
+
+	query := a.DB.Model(&models.NotificationRuleExecution{}).Where("rule_id = ? AND organization_id = ?", ruleID, orgID)
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+
+	var total int64
+	query.Count(&total)
+
+	var executions []models.NotificationRuleExecution
+	if err := pg.Apply(query.Order("created_at DESC")).Find(&executions).Error; err != nil {
+		a.Log.Error("Failed to list notification executions", "error", err)
+		return r.SendErrorEnvelope(fasthttp.StatusInternalServerError, "Failed to list executions", nil, "")
+	}
+
+	return r.SendEnvelope(map[string]any{
+		"executions": executions,
+		"total":      total,
+		"page":       pg.Page,
+		"limit":      pg.Limit,
+	})
+}
+
+// ExecuteNotificationRule runs a rule with the posted payload on behalf of an
+// authenticated user or org API key. With ?dry_run=true it returns the resolved
+// phone numbers and params without sending or logging.
+func (a *App) ExecuteNotificationRule(r *fastglue.Request) error {
+	orgID, userID, err := a.getOrgAndUserID(r)
+	if err != nil {
+		return r.SendErrorEnvelope(fasthttp.StatusUnauthorized, "Unauthorized", nil, "")
+	}
+	if err := a.requirePermission(r, userID, models.ResourceNotifications, models.ActionWrite); err != nil {
+		return nil
+	}
+
+	ruleID, err := parsePathUUID(r, "id", "notification rule")
+	if err != nil {
+		return nil
+	}
+
+	rule, err := findByIDAndOrg[models.NotificationRule](a.DB, r, ruleID, orgID, "Notification rule")
+	if err != nil {
+		return nil
+	}
+
+	payloads, err := parseNotificationPayloads(r.RequestCtx.PostBody())
+	if err != nil {
+		return r.SendErrorEnvelope(fasthttp.StatusBadRequest, err.Error(), nil, "")
+	}
+
+	if string(r.RequestCtx.QueryArgs().Peek("dry_run")) == "true" {
+		var template *models.Template
+		var t models.Template
+		if err := a.DB.Where("id = ? AND organization_id = ?", rule.TemplateID, orgID).First(&t).Error; err == nil {
+			template = &t
+		}
+		previews := make([]NotificationPreview, len(payloads))
+		for i, payload := range payloads {
+			previews[i] = resolveNotification(rule, template, payload)
+		}
+		return r.SendEnvelope(map[string]any{"previews": previews})
+	}
+
+	if !rule.IsEnabled {
+		return r.SendErrorEnvelope(fasthttp.StatusConflict, "Notification rule is disabled", nil, "")
+	}
+
+	return r.SendEnvelope(a.runNotificationTrigger(rule, payloads, models.NotificationTriggerAPI))
+}
+
+// TriggerNotificationRule is the public inbound endpoint for webhook rules.
+// It is authenticated with the rule's own trigger key in the X-API-Key header.
+func (a *App) TriggerNotificationRule(r *fastglue.Request) error {
+	ruleID, err := parsePathUUID(r, "id", "notification rule")
+	if err != nil {
+		return nil
+	}
+
+	key := string(r.RequestCtx.Request.Header.Peek("X-API-Key"))
+	if key == "" {
+		return r.SendErrorEnvelope(fasthttp.StatusUnauthorized, "Missing trigger key", nil, "")
+	}
+
+	var rule models.NotificationRule
+	if err := a.DB.Where("id = ? AND trigger_type = ?", ruleID, models.NotificationTriggerWebhook).First(&rule).Error; err != nil ||
+		rule.TriggerKeyHash == "" ||
+		bcrypt.CompareHashAndPassword([]byte(rule.TriggerKeyHash), []byte(key)) != nil {
+		return r.SendErrorEnvelope(fasthttp.StatusUnauthorized, "Invalid trigger key", nil, "")
+	}
+
+	if !rule.IsEnabled {
+		return r.SendErrorEnvelope(fasthttp.StatusConflict, "Notification rule is disabled", nil, "")
+	}
+
+	payloads, err := parseNotificationPayloads(r.RequestCtx.PostBody())
+	if err != nil {
+		return r.SendErrorEnvelope(fasthttp.StatusBadRequest, err.Error(), nil, "")
+	}
+
+	return r.SendEnvelope(a.runNotificationTrigger(&rule, payloads, models.NotificationTriggerWebhook))
+}
+
+// runNotificationTrigger executes a rule for inbound payloads and summarises the result
+func (a *App) runNotificationTrigger(rule *models.NotificationRule, payloads []map[string]interface{}, source string) NotificationTriggerResponse {
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+
+	resp := NotificationTriggerResponse{Executions: a.executeNotificationRule(ctx, rule, payloads, source)}
+	for _, exec := range resp.Executions {
+		switch exec.Status {
+		case models.NotificationExecutionSent:
+			resp.Sent++
+		case models.NotificationExecutionSkipped:
+			resp.Skipped++
+		default:
+			resp.Failed++
+		}
+	}
+	return resp
+}
+
+// validateNotificationRule checks a rule before it is saved and computes its
+// next scheduler run. Returns an error message, or "" when valid.
+func (a *App) validateNotificationRule(rule *models.NotificationRule) string {
+	if rule.Name == "" {
+		return "Name is required"
+	}
+	if rule.WhatsAppAccount == "" {
+		return "WhatsApp account is required"
+	}
+	if rule.TemplateID == uuid.Nil {
+		return "template_id is required"
+	}
+
+	var count int64
+	a.DB.Model(&models.Template{}).Where("id = ? AND organization_id = ?", rule.TemplateID, rule.OrganizationID).Count(&count)
+	if count == 0 {
+		return "Template not found"
+	}
+	a.DB.Model(&models.WhatsAppAccount{}).Where("name = ? AND organization_id = ?", rule.WhatsAppAccount, rule.OrganizationID).Count(&count)
+	if count == 0 {
+		return "WhatsApp account not found"
+	}
+
+	if phone, _ := rule.FieldMappings["phone_number"].(string); phone == "" {
+		return "field_mappings.phone_number is required"
+	}
+	if params, ok := rule.FieldMappings["params"]; ok {
+		if _, ok := params.(map[string]interface{}); !ok {
+			return "field_mappings.params must be an object"
+		}
+	}
+
+	if rule.AttachmentConfig != nil {
+		if url, _ := rule.AttachmentConfig["url"].(string); url != "" && url[0] != '$' {
+			if err := validateWebhookURL(url); err != nil {
+				return "Invalid attachment url: " + err.Error()
+			}
+		}
+	}
+
+	if rule.TriggerConfig == nil {
+		rule.TriggerConfig = models.JSONB{}
+	}
+	rule.NextRunAt = nil
+
+	switch rule.TriggerType {
+	case models.NotificationTriggerWebhook, models.NotificationTriggerAPI:
+	case models.NotificationTriggerScheduler:
+		next, err := nextNotificationRun(rule.TriggerConfig, time.Now())
+		if err != nil {
+			return err.Error()
+		}
+		if next == nil {
+			return "Cron expression never fires"
+		}
+		if source, _ := rule.TriggerConfig["source_url"].(string); source != "" {
+			if err := validateWebhookURL(source); err != nil {
+				return "Invalid source_url: " + err.Error()
+			}
+		}
+		rule.NextRunAt = next
+	default:
+		return "trigger_type must be one of: webhook, scheduler, api"
+	}
+
+	return ""
+}
@@ -0,0 +1,297 @@
+package handlers_test
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shridarpatil/whatomate/internal/handlers"
+	"github.com/shridarpatil/whatomate/internal/models"
+	"github.com/shridarpatil/whatomate/test/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/valyala/fasthttp"
+)
+
+// notificationRuleFixture creates an org, admin user, account and template for rule tests.
+func notificationRuleFixture(t *testing.T, app *handlers.App) (*models.Organization, *models.User, *models.WhatsAppAccount, *models.Template) {
+	t.Helper()
+	org := testutil.CreateTestOrganization(t, app.DB)
+	adminRole := testutil.CreateAdminRole(t, app.DB, org.ID)
+	user := testutil.CreateTestUser(t, app.DB, org.ID, testutil.WithRoleID(&adminRole.ID))
+	account := createTestAccount(t, app, org.ID)
+	tpl := createTestTemplate(t, app, org.ID, account.Name)
+	return org, user, account, tpl
+}
+
+// createWebhookRule creates a webhook rule through the API and returns it with its trigger key.
+func createWebhookRule(t *testing.T, app *handlers.App, orgID, userID uuid.UUID, accountName string, templateID uuid.UUID, conditions map[string]any) handlers.NotificationRuleResponse {
+	t.Helper()
+	req := testutil.NewJSONRequest(t, map[string]any{
+		"name":             "Order shipped",
+		"whatsapp_account": accountName,
+		"trigger_type":     models.NotificationTriggerWebhook,
+		"template_id":      templateID.String(),
+		"field_mappings": map[string]any{
+			"phone_number": "$.customer.phone",
+			"contact_name": "$.customer.name",
+			"params": map[string]any{
+				"name":     "$.customer.name",
+				"order_id": "$.order.id",
+			},
+		},
+		"conditions": conditions,
+	})
+	testutil.SetAuthContext(req, orgID, userID)
+
+	require.NoError(t, app.CreateNotificationRule(req))
+	require.Equal(t, fasthttp.StatusOK, testutil.GetResponseStatusCode(req), string(testutil.GetResponseBody(req)))
+
+	var resp struct {
+		Data handlers.NotificationRuleResponse `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(testutil.GetResponseBody(req), &resp))
+	return resp.Data
+}
+
+func orderPayload(status string) map[string]any {
+	return map[string]any{
+		"order":    map[string]any{"id": 42, "status": status},
+		"customer": map[string]any{"name": "Alice", "phone": "+15550102030"},
+	}
+}
+
+func TestApp_CreateNotificationRule_Validation(t *testing.T) {
+	app := newTestApp(t)
+	org, user, account, tpl := notificationRuleFixture(t, app)
+
+	tests := []struct {
+		name string
+		body map[string]any
+	}{
+		{"missing phone mapping", map[string]any{
+			"name": "r", "whatsapp_account": account.Name, "trigger_type": "webhook", "template_id": tpl.ID.String(),
+		}},
+		{"bad trigger type", map[string]any{
+			"name": "r", "whatsapp_account": account.Name, "trigger_type": "email", "template_id": tpl.ID.String(),
+			"field_mappings": map[string]any{"phone_number": "$.phone"},
+		}},
+		{"bad cron", map[string]any{
+			"name": "r", "whatsapp_account": account.Name, "trigger_type": "scheduler", "template_id": tpl.ID.String(),
+			"field_mappings": map[string]any{"phone_number": "$.phone"},
+			"trigger_config": map[string]any{"cron": "every day"},
+		}},
+		{"unknown template", map[string]any{
+			"name": "r", "whatsapp_account": account.Name, "trigger_type": "webhook", "template_id": uuid.New().String(),
+			"field_mappings": map[string]any{"phone_number": "$.phone"},
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := testutil.NewJSONRequest(t, tt.body)
+			testutil.SetAuthContext(req, org.ID, user.ID)
+			require.NoError(t, app.CreateNotificationRule(req))
+			assert.Equal(t, fasthttp.StatusBadRequest, testutil.GetResponseStatusCode(req))
+		})
+	}
+}
+
+func TestApp_CreateNotificationRule_SchedulerSetsNextRun(t *testing.T) {
+	app := newTestApp(t)
+	org, user, account, tpl := notificationRuleFixture(t, app)
+
+	req := testutil.NewJSONRequest(t, map[string]any{
+		"name":             "Payment due",
+		"whatsapp_account": account.Name,
+		"trigger_type":     models.NotificationTriggerScheduler,
+		"template_id":      tpl.ID.String(),
+		"trigger_config":   map[string]any{"cron": "0 9 * * *", "timezone": "Asia/Kolkata"},
+		"field_mappings":   map[string]any{"phone_number": "15550102030"},
+	})
+	testutil.SetAuthContext(req, org.ID, user.ID)
+
+	require.NoError(t, app.CreateNotificationRule(req))
+	require.Equal(t, fasthttp.StatusOK, testutil.GetResponseStatusCode(req))
+
+	var resp struct {
+		Data handlers.NotificationRuleResponse `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(testutil.GetResponseBody(req), &resp))
+	assert.Empty(t, resp.Data.TriggerKey)
+	require.NotNil(t, resp.Data.NextRunAt)
+	assert.True(t, resp.Data.NextRunAt.After(time.Now()))
+}
+
+func TestApp_TriggerNotificationRule(t *testing.T) {
+	mockServer := newMockWhatsAppServer()
+	defer mockServer.close()
+
+	app := newMsgTestApp(t, mockServer)
+	org, user, account, tpl := notificationRuleFixture(t, app)
+	rule := createWebhookRule(t, app, org.ID, user.ID, account.Name, tpl.ID, map[string]any{"expression": "order.status == 'shipped'"})
+	require.NotEmpty(t, rule.TriggerKey)
+
+	trigger := func(key string, payload any) *handlers.NotificationTriggerResponse {
+		req := testutil.NewJSONRequest(t, payload)
+		req.RequestCtx.Request.Header.Set("X-API-Key", key)
+		testutil.SetPathParam(req, "id", rule.ID.String())
+		require.NoError(t, app.TriggerNotificationRule(req))
+		if testutil.GetResponseStatusCode(req) != fasthttp.StatusOK {
+			return nil
+		}
+		var resp struct {
+			Data handlers.NotificationTriggerResponse `json:"data"`
+		}
+		require.NoError(t, json.Unmarshal(testutil.GetResponseBody(req), &resp))
+		return &resp.Data
+	}
+
+	t.Run("rejects invalid key", func(t *testing.T) {
+		assert.Nil(t, trigger("whn_wrong", orderPayload("shipped")))
+		assert.Empty(t, mockServer.sentMessages)
+	})
+
+	t.Run("sends when condition matches", func(t *testing.T) {
+		resp := trigger(rule.TriggerKey, orderPayload("shipped"))
+		require.NotNil(t, resp)
+		assert.Equal(t, 1, resp.Sent)
+		require.Len(t, resp.Executions, 1)
+		assert.Equal(t, "15550102030", resp.Executions[0].PhoneNumber)
+		require.NotNil(t, resp.Executions[0].MessageID)
+
+		require.Len(t, mockServer.sentMessages, 1)
+		assert.Equal(t, "template", mockServer.sentMessages[0]["type"])
+
+		var msg models.Message
+		require.NoError(t, app.DB.First(&msg, *resp.Executions[0].MessageID).Error)
+		assert.Equal(t, "Hello Alice! Your order 42 has been confirmed.", msg.Content)
+	})
+
+	t.Run("skips when condition fails", func(t *testing.T) {
+		resp := trigger(rule.TriggerKey, []any{orderPayload("pending")})
+		require.NotNil(t, resp)
+		assert.Equal(t, 0, resp.Sent)
+		assert.Equal(t, 1, resp.Skipped)
+		assert.Len(t, mockServer.sentMessages, 1)
+	})
+
+	t.Run("execution log is recorded", func(t *testing.T) {
+		req := testutil.NewRequest(t)
+		testutil.SetAuthContext(req, org.ID, user.ID)
+		testutil.SetPathParam(req, "id", rule.ID.String())
+		require.NoError(t, app.ListNotificationRuleExecutions(req))
+		require.Equal(t, fasthttp.StatusOK, testutil.GetResponseStatusCode(req))
+
+		var resp struct {
+			Data struct {
+				Executions []models.NotificationRuleExecution `json:"executions"`
+				Total      int64                              `json:"total"`
+			} `json:"data"`
+		}
+		require.NoError(t, json.Unmarshal(testutil.GetResponseBody(req), &resp))
+		assert.Equal(t, int64(2), resp.Data.Total)
+	})
+}
+
+func TestApp_RotateNotificationRuleKey(t *testing.T) {
+	app := newTestApp(t)
+	org, user, account, tpl := notificationRuleFixture(t, app)
+	rule := createWebhookRule(t, app, org.ID, user.ID, account.Name, tpl.ID, nil)
+
+	req := testutil.NewRequest(t)
+	testutil.SetAuthContext(req, org.ID, user.ID)
+	testutil.SetPathParam(req, "id", rule.ID.String())
+	require.NoError(t, app.RotateNotificationRuleKey(req))
+	require.Equal(t, fasthttp.StatusOK, testutil.GetResponseStatusCode(req))
+
+	var resp struct {
+		Data handlers.NotificationRuleResponse `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(testutil.GetResponseBody(req), &resp))
+	assert.NotEmpty(t, resp.Data.TriggerKey)
+	assert.NotEqual(t, rule.TriggerKey, resp.Data.TriggerKey)
+
+	// Old key no longer works
+	trig := testutil.NewJSONRequest(t, orderPayload("shipped"))
+	trig.RequestCtx.Request.Header.Set("X-API-Key", rule.TriggerKey)
+	testutil.SetPathParam(trig, "id", rule.ID.String())
+	require.NoError(t, app.TriggerNotificationRule(trig))
+	assert.Equal(t, fasthttp.StatusUnauthorized, testutil.GetResponseStatusCode(trig))
+}
+
+func TestApp_ExecuteNotificationRule_DryRun(t *testing.T) {
+	mockServer := newMockWhatsAppServer()
+	defer mockServer.close()
+
+	app := newMsgTestApp(t, mockServer)
+	org, user, account, tpl := notificationRuleFixture(t, app)
+	rule := createWebhookRule(t, app, org.ID, user.ID, account.Name, tpl.ID, nil)
+
+	req := testutil.NewJSONRequest(t, orderPayload("shipped"))
+	req.RequestCtx.QueryArgs().Set("dry_run", "true")
+	testutil.SetAuthContext(req, org.ID, user.ID)
+	testutil.SetPathParam(req, "id", rule.ID.String())
+	require.NoError(t, app.ExecuteNotificationRule(req))
+	require.Equal(t, fasthttp.StatusOK, testutil.GetResponseStatusCode(req))
+
+	var resp struct {
+		Data struct {
+			Previews []handlers.NotificationPreview `json:"previews"`
+		} `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(testutil.GetResponseBody(req), &resp))
+	require.Len(t, resp.Data.Previews, 1)
+	assert.Equal(t, "15550102030", resp.Data.Previews[0].PhoneNumber)
+	assert.Equal(t, "42", resp.Data.Previews[0].TemplateParams["order_id"])
+	assert.Empty(t, mockServer.sentMessages)
+
+	var count int64
+	app.DB.Model(&models.NotificationRuleExecution{}).Where("rule_id = ?", rule.ID).Count(&count)
+	assert.Zero(t, count)
+}
+
+func TestNotificationScheduler_RunDue(t *testing.T) {
+	mockServer := newMockWhatsAppServer()
+	defer mockServer.close()
+
+	app := newMsgTestApp(t, mockServer)
+	org, _, account, tpl := notificationRuleFixture(t, app)
+
+	due := time.Now().Add(-time.Minute).UTC().Truncate(time.Microsecond)
+	rule := models.NotificationRule{
+		BaseModel:       models.BaseModel{ID: uuid.New()},
+		OrganizationID:  org.ID,
+		WhatsAppAccount: account.Name,
+		Name:            "Daily reminder",
+		IsEnabled:       true,
+		TriggerType:     models.NotificationTriggerScheduler,
+		TriggerConfig: models.JSONB{
+			"cron":    "*/5 * * * *",
+			"payload": map[string]any{"name": "Bob", "order_id": "7", "phone": "15550109999"},
+		},
+		TemplateID: tpl.ID,
+		FieldMappings: models.JSONB{
+			"phone_number": "$.phone",
+			"params":       map[string]any{"name": "$.name", "order_id": "$.order_id"},
+		},
+		NextRunAt: &due,
+	}
+	require.NoError(t, app.DB.Create(&rule).Error)
+
+	scheduler := handlers.NewNotificationScheduler(app, time.Minute)
+	assert.Equal(t, 1, scheduler.RunDue(context.Background(), time.Now()))
+	require.Len(t, mockServer.sentMessages, 1)
+
+	var updated models.NotificationRule
+	require.NoError(t, app.DB.First(&updated, rule.ID).Error)
+	require.NotNil(t, updated.NextRunAt)
+	assert.True(t, updated.NextRunAt.After(time.Now()))
+	assert.NotNil(t, updated.LastTriggeredAt)
+
+	// Not due again until the next slot
+	assert.Equal(t, 0, scheduler.RunDue(context.Background(), time.Now()))
+	assert.Len(t, mockServer.sentMessages, 1)
+}
@@ -0,0 +1,119 @@
+package handlers
+
+import (
+	"context"
+	"time"
+
+	"github.com/shridarpatil/whatomate/internal/models"
+)
+
+// notificationSchedulerBatchSize caps how many due rules are run per tick
+const notificationSchedulerBatchSize = 50
+
+// NotificationScheduler runs scheduler-triggered notification rules when their
+// cron expression comes due. Each run is claimed by advancing next_run_at with a
+// conditional update, so multiple server instances never fire a rule twice.
+type NotificationScheduler struct {
+	app      *App
+	interval time.Duration
+	stopCh   chan struct{}
+}
+
+// NewNotificationScheduler creates a new notification scheduler
+func NewNotificationScheduler(app *App, interval time.Duration) *NotificationScheduler {
+	return &NotificationScheduler{
+		app:      app,
+		interval: interval,
+		stopCh:   make(chan struct{}),
+	}
+}
+
+// Start begins the scheduling loop
+func (s *NotificationScheduler) Start(ctx context.Context) {
+	s.app.Log.Info("Notification scheduler started", "interval", s.interval)
+
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			s.app.Log.Info("Notification scheduler stopped by context")
+			return
+		case <-s.stopCh:
+			s.app.Log.Info("Notification scheduler stopped")
+			return
+		case <-ticker.C:
+			s.RunDue(ctx, time.Now())
+		}
+	}
+}
+
+// Stop stops the notification scheduler
+func (s *NotificationScheduler) Stop() {
+	select {
+	case <-s.stopCh:
+	default:
+		close(s.stopCh)
+	}
+}
+
+// RunDue claims and runs every enabled scheduler rule due at or before now.
+// Returns the number of rules run.
+func (s *NotificationScheduler) RunDue(ctx context.Context, now time.Time) int {
+	var rules []models.NotificationRule
+	if err := s.app.DB.Where("trigger_type = ? AND is_enabled = ? AND next_run_at <= ?",
+		models.NotificationTriggerScheduler, true, now).
+		Order("next_run_at ASC").
+		Limit(notificationSchedulerBatchSize).
+		Find(&rules).Error; err != nil {
+		s.app.Log.Error("Failed to load due notification rules", "error", err)
+		return 0
+	}
+
+	ran := 0
+	for i := range rules {
+		if ctx.Err() != nil {
+			break
+		}
+		rule := &rules[i]
+
+		next, err := nextNotificationRun(rule.TriggerConfig, now)
+		if err != nil {
+			s.app.Log.Error("Invalid notification schedule", "error", err, "rule_id", rule.ID)
+		}
+
+		// Claim this run; another instance may already have advanced it
+		result := s.app.DB.Model(&models.NotificationRule{}).
+			Where("id = ? AND next_run_at = ?", rule.ID, rule.NextRunAt).
+			Update("next_run_at", next)
+		if result.Error != nil {
+			s.app.Log.Error("Failed to claim notification rule", "error", result.Error, "rule_id", rule.ID)
+			continue
+		}
+		if result.RowsAffected == 0 || err != nil {
+			continue
+		}
+
+		s.runRule(ctx, rule)
+		ran++
+	}
+
+	return ran
+}
+
+// runRule fetches the payloads for a scheduled rule and executes it
+func (s *NotificationScheduler) runRule(ctx context.Context, rule *models.NotificationRule) {
+	payloads, err := s.app.scheduledNotificationPayloads(ctx, rule.TriggerConfig)
+	if err != nil {
+		s.app.Log.Error("Failed to build scheduled notification payloads", "error", err, "rule_id", rule.ID)
+		exec := newNotificationExecution(rule, nil, models.NotificationTriggerScheduler)
+		exec.Status = models.NotificationExecutionFailed
+		exec.Error = err.Error()
+		s.app.DB.Create(&exec)
+		return
+	}
+
+	executions := s.app.executeNotificationRule(ctx, rule, payloads, models.NotificationTriggerScheduler)
+	s.app.Log.Info("Scheduled notification rule ran", "rule_id", rule.ID, "executions", len(executions))
+}
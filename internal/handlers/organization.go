@@ -21,6 +21,7 @@ type OrganizationSettings struct {
 	TransferTimeoutSecs int    `json:"transfer_timeout_secs"`
 	HoldMusicFile       string `json:"hold_music_file"`
 	RingbackFile        string `json:"ringback_file"`
+	ConsentSettings
 }
 
 // GetOrganizationSettings returns the organization settings
@@ -45,6 +46,7 @@ func (a *App) GetOrganizationSettings(r *fastglue.Request) error {
 		TransferTimeoutSecs: callingConfigDefault(a.Config.Calling.TransferTimeoutSecs, 60),
 		HoldMusicFile:       a.Config.Calling.HoldMusicFile,
 		RingbackFile:        a.Config.Calling.RingbackFile,
+		ConsentSettings:     consentSettingsFromOrg(org.Settings),
 	}
 
 	if org.Settings != nil {
@@ -88,15 +90,19 @@ func (a *App) UpdateOrganizationSettings(r *fastglue.Request) error {
 	}
 
 	var req struct {
-		MaskPhoneNumbers    *bool   `json:"mask_phone_numbers"`
-		Timezone            *string `json:"timezone"`
-		DateFormat          *string `json:"date_format"`
-		Name                *string `json:"name"`
-		CallingEnabled      *bool   `json:"calling_enabled"`
-		MaxCallDuration     *int    `json:"max_call_duration"`
-		TransferTimeoutSecs *int    `json:"transfer_timeout_secs"`
-		HoldMusicFile       *string `json:"hold_music_file"`
-		RingbackFile        *string `json:"ringback_file"`
+		MaskPhoneNumbers    *bool     `json:"mask_phone_numbers"`
+		Timezone            *string   `json:"timezone"`
+		DateFormat          *string   `json:"date_format"`
+		Name                *string   `json:"name"`
+		CallingEnabled      *bool     `json:"calling_enabled"`
+		MaxCallDuration     *int      `json:"max_call_duration"`
+		TransferTimeoutSecs *int      `json:"transfer_timeout_secs"`
+		HoldMusicFile       *string   `json:"hold_music_file"`
+		RingbackFile        *string   `json:"ringback_file"`
+		OptOutKeywords      *[]string `json:"opt_out_keywords"`
+		OptInKeywords       *[]string `json:"opt_in_keywords"`
+		OptOutReply         *string   `json:"opt_out_reply"`
+		OptInReply          *string   `json:"opt_in_reply"`
 	}
 
 	if err := json.Unmarshal(r.RequestCtx.PostBody(), &req); err != nil {
@@ -137,6 +143,18 @@ func (a *App) UpdateOrganizationSettings(r *fastglue.Request) error {
 	if req.RingbackFile != nil {
 		org.Settings["ringback_file"] = *req.RingbackFile
 	}
+	if req.OptOutKeywords != nil {
+		org.Settings["opt_out_keywords"] = normalizeConsentKeywords(*req.OptOutKeywords)
+	}
+	if req.OptInKeywords != nil {
+		org.Settings["opt_in_keywords"] = normalizeConsentKeywords(*req.OptInKeywords)
+	}
+	if req.OptOutReply != nil {
+		org.Settings["opt_out_reply"] = *req.OptOutReply
+	}
+	if req.OptInReply != nil {
+		org.Settings["opt_in_reply"] = *req.OptInReply
+	}
 	if req.Name != nil && *req.Name != "" {
 		org.Name = *req.Name
 	}
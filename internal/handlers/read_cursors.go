@@ -0,0 +1,229 @@
+package handlers
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shridarpatil/whatomate/internal/models"
+	"github.com/shridarpatil/whatomate/internal/websocket"
+	"github.com/valyala/fasthttp"
+	"github.com/zerodha/fastglue"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// MarkMessageReadRequest represents a mark read request
+type MarkMessageReadRequest struct {
+	SendReadReceipt bool `json:"send_read_receipt"`
+}
+
+// ReadCursorResponse represents a user's read position in a conversation
+type ReadCursorResponse struct {
+	ContactID         uuid.UUID  `json:"contact_id"`
+	UserID            uuid.UUID  `json:"user_id"`
+	LastReadMessageID *uuid.UUID `json:"last_read_message_id,omitempty"`
+	LastReadAt        time.Time  `json:"last_read_at"`
+	UnreadCount       int        `json:"unread_count"`
+	ReadReceiptSent   bool       `json:"read_receipt_sent"`
+}
+
+// MarkMessageRead marks a conversation as read by the current user up to
+// the message, optionally sending a WhatsApp read receipt for it
+func (a *App) MarkMessageRead(r *fastglue.Request) error {
+	orgID, userID, err := a.getOrgAndUserID(r)
+	if err != nil {
+		return r.SendErrorEnvelope(fasthttp.StatusUnauthorized, "Unauthorized", nil, "")
+	}
+	messageID, err := parsePathUUID(r, "id", "message")
+	if err != nil {
+		return nil
+	}
+
+	var req MarkMessageReadRequest
+	if len(r.RequestCtx.PostBody()) > 0 {
+		if err := a.decodeRequest(r, &req); err != nil {
+			return nil
+		}
+	}
+
+	message, err := findByIDAndOrg[models.Message](a.DB, r, messageID, orgID, "Message")
+	if err != nil {
+		return nil
+	}
+
+	// Users without contacts:read permission can only read their assigned contacts
+	var contact models.Contact
+	query := a.DB.Where("id = ? AND organization_id = ?", message.ContactID, orgID)
+	if !a.HasPermission(userID, models.ResourceContacts, models.ActionRead, orgID) {
+		query = query.Where("assigned_user_id = ?", userID)
+	}
+	if err := query.First(&contact).Error; err != nil {
+		return r.SendErrorEnvelope(fasthttp.StatusNotFound, "Message not found", nil, "")
+	}
+
+	cursor, err := a.advanceReadCursor(orgID, userID, message)
+	if err != nil {
+		a.Log.Error("Failed to update read cursor", "error", err, "message_id", message.ID)
+		return r.SendErrorEnvelope(fasthttp.StatusInternalServerError, "Failed to mark message as read", nil, "")
+	}
+
+	response := ReadCursorResponse{
+		ContactID:         contact.ID,
+		UserID:            userID,
+		LastReadMessageID: cursor.LastReadMessageID,
+		LastReadAt:        cursor.LastReadAt,
+		UnreadCount:       int(a.unreadCount(userID, contact.ID)),
+	}
+
+	if req.SendReadReceipt && message.Direction == models.DirectionIncoming && message.WhatsAppMessageID != "" {
+		account, err := a.resolveWhatsAppAccount(orgID, message.WhatsAppAccount)
+		if err != nil {
+			return r.SendErrorEnvelope(fasthttp.StatusBadRequest, "WhatsApp account not found", nil, "")
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		if err := a.WhatsApp.MarkMessageRead(ctx, a.toWhatsAppAccount(account), message.WhatsAppMessageID); err != nil {
+			a.Log.Error("Failed to send read receipt", "error", err, "message_id", message.WhatsAppMessageID)
+		} else {
+			response.ReadReceiptSent = true
+			// WhatsApp marks every earlier message of the conversation as read too
+			a.DB.Model(&models.Message{}).
+				Where("contact_id = ? AND direction = ? AND created_at <= ? AND status != ?",
+					contact.ID, models.DirectionIncoming, message.CreatedAt, models.MessageStatusRead).
+				Update("status", models.MessageStatusRead)
+		}
+	}
+
+	a.broadcastConversationRead(orgID, response)
+
+	return r.SendEnvelope(response)
+}
+
+// advanceReadCursor moves the user's read cursor for the message's contact
+// to the message. The cursor never moves backwards.
+func (a *App) advanceReadCursor(orgID, userID uuid.UUID, message *models.Message) (*models.ConversationReadCursor, error) {
+	cursor := models.ConversationReadCursor{
+		OrganizationID:    orgID,
+		ContactID:         message.ContactID,
+		UserID:            userID,
+		LastReadMessageID: &message.ID,
+		LastReadAt:        message.CreatedAt,
+	}
+	err := a.DB.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "contact_id"}, {Name: "user_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"last_read_message_id", "last_read_at", "updated_at", "deleted_at"}),
+		Where: clause.Where{Exprs: []clause.Expression{
+			clause.Expr{SQL: "conversation_read_cursors.last_read_at < excluded.last_read_at OR conversation_read_cursors.deleted_at IS NOT NULL"},
+		}},
+	}).Create(&cursor).Error
+	if err != nil {
+		return nil, err
+	}
+
+	// Reload, the stored cursor may be ahead of this message
+	if err := a.DB.Where("contact_id = ? AND user_id = ?", message.ContactID, userID).First(&cursor).Error; err != nil {
+		return nil, err
+	}
+	return &cursor, nil
+}
+
+// unreadMessagesQuery returns a query over incoming messages the user has not
+// read. Conversations the user has no read cursor for fall back to the
+// message read status.
+func (a *App) unreadMessagesQuery(userID uuid.UUID) *gorm.DB {
+	return a.DB.Model(&models.Message{}).
+		Joins("LEFT JOIN conversation_read_cursors rc ON rc.contact_id = messages.contact_id AND rc.user_id = ? AND rc.deleted_at IS NULL", userID).
+		Where("messages.direction = ?", models.DirectionIncoming).
+		Where("(rc.id IS NULL AND messages.status != ?) OR (rc.id IS NOT NULL AND messages.created_at > rc.last_read_at)", models.MessageStatusRead)
+}
+
+// unreadCount returns the number of messages of a contact unread by the user
+func (a *App) unreadCount(userID, contactID uuid.UUID) int64 {
+	var count int64
+	a.unreadMessagesQuery(userID).Where("messages.contact_id = ?", contactID).Count(&count)
+	return count
+}
+
+// unreadCounts returns the number of unread messages per contact for the user
+func (a *App) unreadCounts(userID uuid.UUID, contactIDs []uuid.UUID) map[uuid.UUID]int64 {
+	counts := make(map[uuid.UUID]int64, len(contactIDs))
+	if len(contactIDs) == 0 {
+		return counts
+	}
+
+	var rows []struct {
+		ContactID uuid.UUID
+		Count     int64
+	}
+	a.unreadMessagesQuery(userID).
+		Select("messages.contact_id AS contact_id, COUNT(*) AS count").
+		Where("messages.contact_id IN ?", contactIDs).
+		Group("messages.contact_id").
+		Scan(&rows)
+	for _, row := range rows {
+		counts[row.ContactID] = row.Count
+	}
+	return counts
+}
+
+// InboxUnread is the unread summary of a user's inbox
+type InboxUnread struct {
+	Messages      int64 `json:"messages"`
+	Conversations int64 `json:"conversations"`
+}
+
+// inboxUnread returns the unread messages and conversations across the
+// contacts the user can see
+func (a *App) inboxUnread(orgID, userID uuid.UUID, assignedOnly bool) InboxUnread {
+	query := a.unreadMessagesQuery(userID).
+		Joins("JOIN contacts ON contacts.id = messages.contact_id AND contacts.deleted_at IS NULL").
+		Where("contacts.organization_id = ?", orgID)
+	if assignedOnly {
+		query = query.Where("contacts.assigned_user_id = ?", userID)
+	}
+
+	var unread InboxUnread
+	query.Select("COUNT(*) AS messages, COUNT(DISTINCT messages.contact_id) AS conversations").Scan(&unread)
+	return unread
+}
+
+// markConversationRead moves the user's read cursor to the latest message of
+// the contact and notifies the user's other sessions and teammates
+func (a *App) markConversationRead(orgID, userID, contactID uuid.UUID) {
+	var latest models.Message
+	if err := a.DB.Where("contact_id = ?", contactID).Order("created_at DESC").First(&latest).Error; err != nil {
+		return
+	}
+
+	var previous models.ConversationReadCursor
+	hadCursor := a.DB.Where("contact_id = ? AND user_id = ?", contactID, userID).First(&previous).Error == nil
+
+	cursor, err := a.advanceReadCursor(orgID, userID, &latest)
+	if err != nil {
+		a.Log.Error("Failed to update read cursor", "error", err, "contact_id", contactID)
+		return
+	}
+	if hadCursor && !cursor.LastReadAt.After(previous.LastReadAt) {
+		return
+	}
+
+	a.broadcastConversationRead(orgID, ReadCursorResponse{
+		ContactID:         contactID,
+		UserID:            userID,
+		LastReadMessageID: cursor.LastReadMessageID,
+		LastReadAt:        cursor.LastReadAt,
+	})
+}
+
+// broadcastConversationRead notifies clients that a user read a conversation
+func (a *App) broadcastConversationRead(orgID uuid.UUID, cursor ReadCursorResponse) {
+	if a.WSHub == nil {
+		return
+	}
+	a.WSHub.BroadcastToOrg(orgID, websocket.WSMessage{
+		Type:    websocket.TypeConversationRead,
+		Payload: cursor,
+	})
+}
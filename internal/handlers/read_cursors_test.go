@@ -0,0 +1,128 @@
+package handlers_test
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shridarpatil/whatomate/internal/handlers"
+	"github.com/shridarpatil/whatomate/internal/models"
+	"github.com/shridarpatil/whatomate/test/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/valyala/fasthttp"
+)
+
+func markRead(t *testing.T, app *handlers.App, orgID, userID, messageID uuid.UUID) handlers.ReadCursorResponse {
+	t.Helper()
+	req := testutil.NewJSONRequest(t, map[string]any{})
+	testutil.SetAuthContext(req, orgID, userID)
+	testutil.SetPathParam(req, "id", messageID.String())
+
+	require.NoError(t, app.MarkMessageRead(req))
+	require.Equal(t, fasthttp.StatusOK, testutil.GetResponseStatusCode(req))
+
+	var resp struct {
+		Data handlers.ReadCursorResponse `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(testutil.GetResponseBody(req), &resp))
+	return resp.Data
+}
+
+func listContactsUnread(t *testing.T, app *handlers.App, orgID, userID uuid.UUID) (map[uuid.UUID]int, handlers.InboxUnread) {
+	t.Helper()
+	req := testutil.NewGETRequest(t)
+	testutil.SetAuthContext(req, orgID, userID)
+
+	require.NoError(t, app.ListContacts(req))
+	require.Equal(t, fasthttp.StatusOK, testutil.GetResponseStatusCode(req))
+
+	var resp struct {
+		Data struct {
+			Contacts []handlers.ContactResponse `json:"contacts"`
+			Unread   handlers.InboxUnread       `json:"unread"`
+		} `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(testutil.GetResponseBody(req), &resp))
+
+	counts := map[uuid.UUID]int{}
+	for _, c := range resp.Data.Contacts {
+		counts[c.ID] = c.UnreadCount
+	}
+	return counts, resp.Data.Unread
+}
+
+func TestApp_MarkMessageRead_PerUserCursor(t *testing.T) {
+	app := newTestApp(t)
+	org := testutil.CreateTestOrganization(t, app.DB)
+	adminRole := testutil.CreateAdminRole(t, app.DB, org.ID)
+	alice := testutil.CreateTestUser(t, app.DB, org.ID, testutil.WithRoleID(&adminRole.ID))
+	bob := testutil.CreateTestUser(t, app.DB, org.ID, testutil.WithRoleID(&adminRole.ID))
+	contact := testutil.CreateTestContact(t, app.DB, org.ID)
+
+	now := time.Now().UTC()
+	first := createTestMessage(t, app, org.ID, contact.ID, models.DirectionIncoming, now.Add(-3*time.Minute))
+	second := createTestMessage(t, app, org.ID, contact.ID, models.DirectionIncoming, now.Add(-2*time.Minute))
+	createTestMessage(t, app, org.ID, contact.ID, models.DirectionIncoming, now.Add(-time.Minute))
+
+	cursor := markRead(t, app, org.ID, alice.ID, second.ID)
+	assert.Equal(t, contact.ID, cursor.ContactID)
+	require.NotNil(t, cursor.LastReadMessageID)
+	assert.Equal(t, second.ID, *cursor.LastReadMessageID)
+	assert.Equal(t, 1, cursor.UnreadCount)
+	assert.False(t, cursor.ReadReceiptSent)
+
+	// Marking an older message does not move the cursor back
+	cursor = markRead(t, app, org.ID, alice.ID, first.ID)
+	assert.Equal(t, second.ID, *cursor.LastReadMessageID)
+	assert.Equal(t, 1, cursor.UnreadCount)
+
+	counts, inbox := listContactsUnread(t, app, org.ID, alice.ID)
+	assert.Equal(t, 1, counts[contact.ID])
+	assert.Equal(t, handlers.InboxUnread{Messages: 1, Conversations: 1}, inbox)
+
+	// Bob has not read anything
+	counts, inbox = listContactsUnread(t, app, org.ID, bob.ID)
+	assert.Equal(t, 3, counts[contact.ID])
+	assert.Equal(t, handlers.InboxUnread{Messages: 3, Conversations: 1}, inbox)
+}
+
+func TestApp_GetMessages_AdvancesReadCursor(t *testing.T) {
+	app := newTestApp(t)
+	org := testutil.CreateTestOrganization(t, app.DB)
+	adminRole := testutil.CreateAdminRole(t, app.DB, org.ID)
+	user := testutil.CreateTestUser(t, app.DB, org.ID, testutil.WithRoleID(&adminRole.ID))
+	contact := testutil.CreateTestContact(t, app.DB, org.ID)
+	latest := createTestMessage(t, app, org.ID, contact.ID, models.DirectionIncoming, time.Now().UTC())
+
+	req := testutil.NewGETRequest(t)
+	testutil.SetAuthContext(req, org.ID, user.ID)
+	testutil.SetPathParam(req, "id", contact.ID.String())
+	require.NoError(t, app.GetMessages(req))
+	require.Equal(t, fasthttp.StatusOK, testutil.GetResponseStatusCode(req))
+
+	var cursor models.ConversationReadCursor
+	require.NoError(t, app.DB.Where("contact_id = ? AND user_id = ?", contact.ID, user.ID).First(&cursor).Error)
+	require.NotNil(t, cursor.LastReadMessageID)
+	assert.Equal(t, latest.ID, *cursor.LastReadMessageID)
+
+	counts, _ := listContactsUnread(t, app, org.ID, user.ID)
+	assert.Equal(t, 0, counts[contact.ID])
+}
+
+func TestApp_MarkMessageRead_UnassignedContact(t *testing.T) {
+	app := newTestApp(t)
+	org := testutil.CreateTestOrganization(t, app.DB)
+	// User without contacts:read permission
+	agent := testutil.CreateTestUser(t, app.DB, org.ID)
+	contact := testutil.CreateTestContact(t, app.DB, org.ID)
+	msg := createTestMessage(t, app, org.ID, contact.ID, models.DirectionIncoming, time.Now().UTC())
+
+	req := testutil.NewJSONRequest(t, map[string]any{})
+	testutil.SetAuthContext(req, org.ID, agent.ID)
+	testutil.SetPathParam(req, "id", msg.ID.String())
+
+	require.NoError(t, app.MarkMessageRead(req))
+	assert.Equal(t, fasthttp.StatusNotFound, testutil.GetResponseStatusCode(req))
+}
@@ -0,0 +1,418 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shridarpatil/whatomate/internal/models"
+	"github.com/valyala/fasthttp"
+	"github.com/zerodha/fastglue"
+	"gorm.io/gorm"
+)
+
+// AgentSkillEntry is a skill and the agent's proficiency in it
+type AgentSkillEntry struct {
+	Skill       string `json:"skill"`
+	Proficiency int    `json:"proficiency"` // 1 (basic) to 5 (expert)
+}
+
+// AgentRoutingRequest updates an agent's routing profile. Omitted fields are
+// left unchanged; skills replaces the agent's skills.
+type AgentRoutingRequest struct {
+	Skills                     *[]AgentSkillEntry `json:"skills"`
+	MaxConcurrentConversations *int               `json:"max_concurrent_conversations"` // 0 = unlimited
+}
+
+// AgentRoutingResponse is an agent's routing profile in an organization
+type AgentRoutingResponse struct {
+	UserID                     uuid.UUID         `json:"user_id"`
+	Skills                     []AgentSkillEntry `json:"skills"`
+	MaxConcurrentConversations int               `json:"max_concurrent_conversations"`
+	ActiveConversations        int64             `json:"active_conversations"`
+}
+
+// GetAgentRouting returns an agent's skills and conversation capacity
+func (a *App) GetAgentRouting(r *fastglue.Request) error {
+	orgID, userID, err := a.getOrgAndUserID(r)
+	if err != nil {
+		return r.SendErrorEnvelope(fasthttp.StatusUnauthorized, "Unauthorized", nil, "")
+	}
+
+	id, err := parsePathUUID(r, "id", "user")
+	if err != nil {
+		return nil
+	}
+
+	// Agents can see their own profile, others need users:read permission
+	if id != userID && !a.HasPermission(userID, models.ResourceUsers, models.ActionRead, orgID) {
+		return r.SendErrorEnvelope(fasthttp.StatusForbidden, "Insufficient permissions", nil, "")
+	}
+
+	var membership models.UserOrganization
+	if err := a.DB.Where("user_id = ? AND organization_id = ?", id, orgID).First(&membership).Error; err != nil {
+		return r.SendErrorEnvelope(fasthttp.StatusNotFound, "User not found", nil, "")
+	}
+
+	return r.SendEnvelope(a.agentRoutingResponse(orgID, membership))
+}
+
+// UpdateAgentRouting sets an agent's skills and conversation capacity
+func (a *App) UpdateAgentRouting(r *fastglue.Request) error {
+	orgID, userID, err := a.getOrgAndUserID(r)
+	if err != nil {
+		return r.SendErrorEnvelope(fasthttp.StatusUnauthorized, "Unauthorized", nil, "")
+	}
+
+	if err := a.requirePermission(r, userID, models.ResourceUsers, models.ActionWrite); err != nil {
+		return nil
+	}
+
+	id, err := parsePathUUID(r, "id", "user")
+	if err != nil {
+		return nil
+	}
+
+	var membership models.UserOrganization
+	if err := a.DB.Where("user_id = ? AND organization_id = ?", id, orgID).First(&membership).Error; err != nil {
+		return r.SendErrorEnvelope(fasthttp.StatusNotFound, "User not found", nil, "")
+	}
+
+	var req AgentRoutingRequest
+	if err := a.decodeRequest(r, &req); err != nil {
+		return nil
+	}
+
+	if req.MaxConcurrentConversations != nil && *req.MaxConcurrentConversations < 0 {
+		return r.SendErrorEnvelope(fasthttp.StatusBadRequest, "max_concurrent_conversations cannot be negative", nil, "")
+	}
+
+	var skills []models.AgentSkill
+	if req.Skills != nil {
+		skills, err = buildAgentSkills(orgID, id, *req.Skills)
+		if err != nil {
+			return r.SendErrorEnvelope(fasthttp.StatusBadRequest, err.Error(), nil, "")
+		}
+	}
+
+	if err := a.DB.Transaction(func(tx *gorm.DB) error {
+		if req.MaxConcurrentConversations != nil {
+			membership.MaxConcurrentConversations = *req.MaxConcurrentConversations
+			if err := tx.Model(&membership).Update("max_concurrent_conversations", membership.MaxConcurrentConversations).Error; err != nil {
+				return err
+			}
+		}
+		if req.Skills == nil {
+			return nil
+		}
+		if err := tx.Unscoped().Where("organization_id = ? AND user_id = ?", orgID, id).Delete(&models.AgentSkill{}).Error; err != nil {
+			return err
+		}
+		if len(skills) == 0 {
+			return nil
+		}
+		return tx.Create(&skills).Error
+	}); err != nil {
+		a.Log.Error("Failed to update agent routing", "error", err, "user_id", id)
+		return r.SendErrorEnvelope(fasthttp.StatusInternalServerError, "Failed to update agent routing", nil, "")
+	}
+
+	return r.SendEnvelope(a.agentRoutingResponse(orgID, membership))
+}
+
+// buildAgentSkills validates and normalizes an agent's skills. A missing
+// proficiency defaults to basic.
+func buildAgentSkills(orgID, userID uuid.UUID, entries []AgentSkillEntry) ([]models.AgentSkill, error) {
+	skills := make([]models.AgentSkill, 0, len(entries))
+	seen := make(map[string]bool, len(entries))
+	for _, e := range entries {
+		name := models.NormalizeSkill(e.Skill)
+		if name == "" {
+			return nil, errors.New("skill name is required")
+		}
+		if len(name) > 100 {
+			return nil, fmt.Errorf("skill %q is too long", name)
+		}
+		if seen[name] {
+			return nil, fmt.Errorf("duplicate skill %q", name)
+		}
+		seen[name] = true
+
+		proficiency := e.Proficiency
+		if proficiency == 0 {
+			proficiency = models.MinSkillProficiency
+		}
+		if proficiency < models.MinSkillProficiency || proficiency > models.MaxSkillProficiency {
+			return nil, fmt.Errorf("proficiency of %q must be between %d and %d", name, models.MinSkillProficiency, models.MaxSkillProficiency)
+		}
+
+		skills = append(skills, models.AgentSkill{
+			OrganizationID: orgID,
+			UserID:         userID,
+			Skill:          name,
+			Proficiency:    proficiency,
+		})
+	}
+	return skills, nil
+}
+
+func (a *App) agentRoutingResponse(orgID uuid.UUID, membership models.UserOrganization) AgentRoutingResponse {
+	resp := AgentRoutingResponse{
+		UserID:                     membership.UserID,
+		Skills:                     []AgentSkillEntry{},
+		MaxConcurrentConversations: membership.MaxConcurrentConversations,
+	}
+
+	var skills []models.AgentSkill
+	a.DB.Where("organization_id = ? AND user_id = ?", orgID, membership.UserID).Order("skill ASC").Find(&skills)
+	for _, s := range skills {
+		resp.Skills = append(resp.Skills, AgentSkillEntry{Skill: s.Skill, Proficiency: s.Proficiency})
+	}
+
+	a.DB.Model(&models.AgentTransfer{}).
+		Where("organization_id = ? AND agent_id = ? AND status = ?", orgID, membership.UserID, models.TransferStatusActive).
+		Count(&resp.ActiveConversations)
+
+	return resp
+}
+
+// routingCandidate is an available team agent that auto-assignment can pick
+type routingCandidate struct {
+	MemberID       uuid.UUID
+	UserID         uuid.UUID
+	LastAssignedAt *time.Time
+	Capacity       int            // Max concurrent conversations, 0 = unlimited
+	Load           int64          `gorm:"-"` // Active transfers assigned to the agent
+	Skills         map[string]int `gorm:"-"` // Skill -> proficiency
+}
+
+// skillScore sums the agent's proficiency in the required skills, reporting
+// false when the agent lacks one of them
+func (c *routingCandidate) skillScore(required []string) (int, bool) {
+	score := 0
+	for _, skill := range required {
+		proficiency, ok := c.Skills[skill]
+		if !ok {
+			return 0, false
+		}
+		score += proficiency
+	}
+	return score, true
+}
+
+// teamRoutingCandidates returns the team's available agents that are below
+// their conversation capacity, least recently assigned first
+func (a *App) teamRoutingCandidates(teamID, orgID uuid.UUID) []routingCandidate {
+	members := a.routingCandidates(orgID, &teamID)
+	candidates := make([]routingCandidate, 0, len(members))
+	for _, m := range members {
+		if m.Capacity > 0 && m.Load >= int64(m.Capacity) {
+			continue
+		}
+		candidates = append(candidates, m)
+	}
+	return candidates
+}
+
+// routingCandidates returns the available agents of a team, or of every
+// active team of the organization when teamID is nil, with their current
+// load, least recently assigned first
+func (a *App) routingCandidates(orgID uuid.UUID, teamID *uuid.UUID) []routingCandidate {
+	query := a.DB.Table("team_members").
+		Select("team_members.id AS member_id, team_members.user_id, team_members.last_assigned_at, COALESCE(user_organizations.max_concurrent_conversations, 0) AS capacity").
+		Joins("JOIN users ON users.id = team_members.user_id").
+		Joins("LEFT JOIN user_organizations ON user_organizations.user_id = team_members.user_id AND user_organizations.organization_id = ? AND user_organizations.deleted_at IS NULL", orgID).
+		Where("team_members.role = ? AND team_members.deleted_at IS NULL AND users.is_available = ? AND users.is_active = ? AND users.deleted_at IS NULL",
+			models.TeamRoleAgent, true, true).
+		Order("team_members.last_assigned_at ASC NULLS FIRST")
+	if teamID != nil {
+		query = query.Where("team_members.team_id = ?", *teamID)
+	} else {
+		query = query.Joins("JOIN teams ON teams.id = team_members.team_id").
+			Where("teams.organization_id = ? AND teams.is_active = ? AND teams.deleted_at IS NULL", orgID, true)
+	}
+
+	var rows []routingCandidate
+	if err := query.Scan(&rows).Error; err != nil {
+		a.Log.Error("Failed to load team agents for assignment", "error", err, "team_id", teamID)
+		return nil
+	}
+
+	// An agent in several teams is listed once, by its least recent assignment
+	seen := make(map[uuid.UUID]bool, len(rows))
+	members := make([]routingCandidate, 0, len(rows))
+	for _, m := range rows {
+		if !seen[m.UserID] {
+			seen[m.UserID] = true
+			members = append(members, m)
+		}
+	}
+	if len(members) == 0 {
+		return nil
+	}
+
+	memberIDs := make([]uuid.UUID, len(members))
+	for i, m := range members {
+		memberIDs[i] = m.UserID
+	}
+
+	// Count active transfers for all members in a single query
+	type agentLoad struct {
+		AgentID uuid.UUID `gorm:"column:agent_id"`
+		Count   int64     `gorm:"column:count"`
+	}
+	var loads []agentLoad
+	a.DB.Model(&models.AgentTransfer{}).
+		Select("agent_id, COUNT(*) as count").
+		Where("organization_id = ? AND agent_id IN ? AND status = ?", orgID, memberIDs, models.TransferStatusActive).
+		Group("agent_id").
+		Scan(&loads)
+	loadMap := make(map[uuid.UUID]int64, len(loads))
+	for _, l := range loads {
+		loadMap[l.AgentID] = l.Count
+	}
+	for i := range members {
+		members[i].Load = loadMap[members[i].UserID]
+	}
+	return members
+}
+
+// leastLoadedCandidate returns the candidate with the fewest active
+// transfers; ties go to the earlier, least recently assigned, candidate
+func leastLoadedCandidate(candidates []routingCandidate) *routingCandidate {
+	var selected *routingCandidate
+	for i := range candidates {
+		if selected == nil || candidates[i].Load < selected.Load {
+			selected = &candidates[i]
+		}
+	}
+	return selected
+}
+
+// loadCandidateSkills fills in the skills of the candidates
+func (a *App) loadCandidateSkills(orgID uuid.UUID, candidates []routingCandidate) {
+	if len(candidates) == 0 {
+		return
+	}
+	userIDs := make([]uuid.UUID, len(candidates))
+	index := make(map[uuid.UUID]int, len(candidates))
+	for i, c := range candidates {
+		userIDs[i] = c.UserID
+		index[c.UserID] = i
+		candidates[i].Skills = map[string]int{}
+	}
+
+	var skills []models.AgentSkill
+	if err := a.DB.Where("organization_id = ? AND user_id IN ?", orgID, userIDs).Find(&skills).Error; err != nil {
+		a.Log.Error("Failed to load agent skills", "error", err, "org_id", orgID)
+		return
+	}
+	for _, s := range skills {
+		candidates[index[s.UserID]].Skills[s.Skill] = s.Proficiency
+	}
+}
+
+// bestSkilledCandidate picks the candidate with every required skill and the
+// highest total proficiency in them, then the lowest load. Candidates are
+// ordered least recently assigned first, which breaks the remaining ties.
+func bestSkilledCandidate(candidates []routingCandidate, required []string) *routingCandidate {
+	var best *routingCandidate
+	bestScore := 0
+	for i := range candidates {
+		c := &candidates[i]
+		score, ok := c.skillScore(required)
+		if !ok {
+			continue
+		}
+		if best == nil || score > bestScore || (score == bestScore && c.Load < best.Load) {
+			best, bestScore = c, score
+		}
+	}
+	return best
+}
+
+// requiredSkillsList returns a transfer's required skills for API responses
+// and events, as an empty list rather than null
+func requiredSkillsList(skills models.StringArray) []string {
+	if skills == nil {
+		return []string{}
+	}
+	return skills
+}
+
+// markAssigned records the assignment time used for round-robin ordering
+func (a *App) markAssigned(candidate *routingCandidate) {
+	a.DB.Model(&models.TeamMember{}).Where("id = ?", candidate.MemberID).Update("last_assigned_at", time.Now())
+}
+
+// overflowTransfer moves a transfer that waited too long in its team queue to
+// the overflow team and applies that team's assignment strategy
+func (a *App) overflowTransfer(transfer *models.AgentTransfer, target *models.Team, now time.Time) error {
+	var agentID *uuid.UUID
+	if closed, _ := a.teamOutsideBusinessHours(transfer.OrganizationID, target.ID); !closed {
+		agentID = a.assignToTeam(target.ID, transfer.OrganizationID, transfer.RequiredSkills)
+	}
+	if agentID == nil && transfer.Priority == models.TransferPriorityVIP {
+		agentID = a.preemptiveAgent(transfer.OrganizationID, &target.ID)
+	}
+
+	fromTeamID := transfer.TeamID
+	transfer.TeamID = &target.ID
+	transfer.TeamQueuedAt = &now
+	transfer.AgentID = agentID
+	if agentID != nil {
+		a.UpdateSLAOnPickup(transfer)
+	}
+
+	if err := a.DB.Model(transfer).Updates(map[string]any{
+		"team_id":        transfer.TeamID,
+		"team_queued_at": transfer.TeamQueuedAt,
+		"agent_id":       transfer.AgentID,
+		"picked_up_at":   transfer.SLA.PickedUpAt,
+	}).Error; err != nil {
+		return err
+	}
+
+	if agentID != nil {
+		a.DB.Model(&models.Contact{}).Where("id = ?", transfer.ContactID).Update("assigned_user_id", agentID)
+	}
+
+	a.broadcastTransferAssigned(transfer)
+
+	a.Log.Info("Transfer overflowed to another team",
+		"transfer_id", transfer.ID,
+		"from_team_id", fromTeamID,
+		"team_id", target.ID,
+		"agent_id", agentID,
+	)
+	return nil
+}
+
+// validateOverflowTeam checks that a team can overflow to overflowTeamID:
+// the target must be another team of the organization, and following the
+// overflow chain from it must not lead back to the team
+func (a *App) validateOverflowTeam(orgID, teamID, overflowTeamID uuid.UUID) error {
+	if overflowTeamID == teamID {
+		return errors.New("A team cannot overflow to itself")
+	}
+
+	visited := map[uuid.UUID]bool{teamID: true}
+	next := &overflowTeamID
+	for next != nil {
+		if visited[*next] {
+			return errors.New("Overflow teams cannot form a loop")
+		}
+		visited[*next] = true
+
+		var team models.Team
+		if err := a.DB.Where("id = ? AND organization_id = ?", *next, orgID).First(&team).Error; err != nil {
+			if *next == overflowTeamID {
+				return errors.New("Overflow team not found")
+			}
+			return nil
+		}
+		next = team.OverflowTeamID
+	}
+	return nil
+}
@@ -0,0 +1,109 @@
+package handlers
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shridarpatil/whatomate/internal/models"
+	"github.com/shridarpatil/whatomate/test/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBestSkilledCandidate(t *testing.T) {
+	recent, older, oldest := uuid.New(), uuid.New(), uuid.New()
+	// Ordered least recently assigned first, as teamRoutingCandidates returns them
+	candidates := []routingCandidate{
+		{UserID: oldest, Load: 2, Skills: map[string]int{"spanish": 3}},
+		{UserID: older, Load: 1, Skills: map[string]int{"spanish": 3, "billing": 1}},
+		{UserID: recent, Load: 0, Skills: map[string]int{"spanish": 5}},
+	}
+
+	assert.Equal(t, recent, bestSkilledCandidate(candidates, []string{"spanish"}).UserID, "highest proficiency wins")
+	assert.Equal(t, older, bestSkilledCandidate(candidates, []string{"spanish", "billing"}).UserID, "every required skill is needed")
+	assert.Nil(t, bestSkilledCandidate(candidates, []string{"french"}))
+	assert.Equal(t, recent, bestSkilledCandidate(candidates, nil).UserID, "without required skills the lowest load wins")
+
+	candidates[2].Load = 1
+	candidates[2].Skills["spanish"] = 3
+	assert.Equal(t, older, bestSkilledCandidate(candidates, []string{"spanish"}).UserID,
+		"equal matches go to the lower load, then the least recently assigned")
+}
+
+func TestBuildAgentSkills(t *testing.T) {
+	orgID, userID := uuid.New(), uuid.New()
+
+	skills, err := buildAgentSkills(orgID, userID, []AgentSkillEntry{{Skill: "Spanish ", Proficiency: 5}, {Skill: "billing"}})
+	require.NoError(t, err)
+	require.Len(t, skills, 2)
+	assert.Equal(t, "spanish", skills[0].Skill)
+	assert.Equal(t, models.MinSkillProficiency, skills[1].Proficiency)
+
+	_, err = buildAgentSkills(orgID, userID, []AgentSkillEntry{{Skill: "spanish"}, {Skill: "SPANISH"}})
+	assert.EqualError(t, err, `duplicate skill "spanish"`)
+	_, err = buildAgentSkills(orgID, userID, []AgentSkillEntry{{Skill: " "}})
+	assert.Error(t, err)
+	_, err = buildAgentSkills(orgID, userID, []AgentSkillEntry{{Skill: "spanish", Proficiency: 6}})
+	assert.Error(t, err)
+}
+
+func TestSLAProcessor_OverflowQueuedTransfers(t *testing.T) {
+	app := newSLATestApp(t)
+	org := testutil.CreateTestOrganization(t, app.DB)
+	contact := testutil.CreateTestContact(t, app.DB, org.ID)
+	agent := testutil.CreateTestUser(t, app.DB, org.ID)
+
+	overflow := models.Team{
+		BaseModel:          models.BaseModel{ID: uuid.New()},
+		OrganizationID:     org.ID,
+		Name:               "Overflow",
+		AssignmentStrategy: models.AssignmentStrategyRoundRobin,
+		IsActive:           true,
+	}
+	require.NoError(t, app.DB.Create(&overflow).Error)
+	require.NoError(t, app.DB.Create(&models.TeamMember{TeamID: overflow.ID, UserID: agent.ID, Role: models.TeamRoleAgent}).Error)
+
+	primary := models.Team{
+		BaseModel:            models.BaseModel{ID: uuid.New()},
+		OrganizationID:       org.ID,
+		Name:                 "Primary",
+		AssignmentStrategy:   models.AssignmentStrategyRoundRobin,
+		IsActive:             true,
+		OverflowTeamID:       &overflow.ID,
+		OverflowAfterMinutes: 10,
+	}
+	require.NoError(t, app.DB.Create(&primary).Error)
+
+	now := time.Now()
+	queued := func(waited time.Duration) *models.AgentTransfer {
+		transfer := &models.AgentTransfer{
+			BaseModel:       models.BaseModel{ID: uuid.New()},
+			OrganizationID:  org.ID,
+			ContactID:       contact.ID,
+			WhatsAppAccount: "test",
+			PhoneNumber:     contact.PhoneNumber,
+			Status:          models.TransferStatusActive,
+			TeamID:          &primary.ID,
+			TransferredAt:   now.Add(-waited),
+		}
+		require.NoError(t, app.DB.Create(transfer).Error)
+		return transfer
+	}
+	stale := queued(15 * time.Minute)
+	fresh := queued(5 * time.Minute)
+
+	NewSLAProcessor(app, time.Minute).overflowQueuedTransfers(now)
+
+	var reloaded models.AgentTransfer
+	require.NoError(t, app.DB.First(&reloaded, stale.ID).Error)
+	require.NotNil(t, reloaded.TeamID)
+	assert.Equal(t, overflow.ID, *reloaded.TeamID)
+	require.NotNil(t, reloaded.AgentID, "the overflow team's strategy assigns an agent")
+	assert.Equal(t, agent.ID, *reloaded.AgentID)
+	require.NotNil(t, reloaded.TeamQueuedAt)
+
+	require.NoError(t, app.DB.First(&reloaded, fresh.ID).Error)
+	assert.Equal(t, primary.ID, *reloaded.TeamID, "transfers within the wait stay in their team")
+	assert.Nil(t, reloaded.AgentID)
+}
@@ -0,0 +1,150 @@
+package handlers_test
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/shridarpatil/whatomate/internal/handlers"
+	"github.com/shridarpatil/whatomate/internal/models"
+	"github.com/shridarpatil/whatomate/test/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/valyala/fasthttp"
+)
+
+// setAgentRouting sets an agent's skills and capacity through the API.
+func setAgentRouting(t *testing.T, app *handlers.App, orgID, adminID, agentID uuid.UUID, body map[string]any) handlers.AgentRoutingResponse {
+	t.Helper()
+
+	req := testutil.NewJSONRequest(t, body)
+	testutil.SetAuthContext(req, orgID, adminID)
+	testutil.SetPathParam(req, "id", agentID.String())
+	require.NoError(t, app.UpdateAgentRouting(req))
+	require.Equal(t, fasthttp.StatusOK, testutil.GetResponseStatusCode(req))
+
+	var resp handlers.AgentRoutingResponse
+	testutil.ParseEnvelopeResponse(t, req, &resp)
+	return resp
+}
+
+// createSkillsTransfer creates a transfer to a team through the API and
+// returns the assigned agent.
+func createSkillsTransfer(t *testing.T, app *handlers.App, orgID, adminID, teamID uuid.UUID, accountName string, skills []string) *string {
+	t.Helper()
+
+	contact := testutil.CreateTestContact(t, app.DB, orgID)
+	req := testutil.NewJSONRequest(t, map[string]any{
+		"contact_id":       contact.ID.String(),
+		"whatsapp_account": accountName,
+		"team_id":          teamID.String(),
+		"required_skills":  skills,
+	})
+	testutil.SetAuthContext(req, orgID, adminID)
+	require.NoError(t, app.CreateAgentTransfer(req))
+	require.Equal(t, fasthttp.StatusOK, testutil.GetResponseStatusCode(req))
+
+	var resp struct {
+		Transfer handlers.AgentTransferResponse `json:"transfer"`
+	}
+	testutil.ParseEnvelopeResponse(t, req, &resp)
+	return resp.Transfer.AgentID
+}
+
+func TestApp_UpdateAgentRouting(t *testing.T) {
+	t.Parallel()
+
+	t.Run("success", func(t *testing.T) {
+		app := newTestApp(t)
+		org := testutil.CreateTestOrganization(t, app.DB)
+		admin := createAdminUser(t, app, org.ID)
+		agent := testutil.CreateTestUser(t, app.DB, org.ID)
+
+		resp := setAgentRouting(t, app, org.ID, admin.ID, agent.ID, map[string]any{
+			"skills": []map[string]any{
+				{"skill": " Spanish", "proficiency": 4},
+				{"skill": "billing"},
+			},
+			"max_concurrent_conversations": 3,
+		})
+		assert.Equal(t, []handlers.AgentSkillEntry{
+			{Skill: "billing", Proficiency: 1},
+			{Skill: "spanish", Proficiency: 4},
+		}, resp.Skills)
+		assert.Equal(t, 3, resp.MaxConcurrentConversations)
+
+		// Omitted fields are kept
+		resp = setAgentRouting(t, app, org.ID, admin.ID, agent.ID, map[string]any{"max_concurrent_conversations": 0})
+		assert.Len(t, resp.Skills, 2)
+		assert.Equal(t, 0, resp.MaxConcurrentConversations)
+	})
+
+	t.Run("invalid proficiency", func(t *testing.T) {
+		app := newTestApp(t)
+		org := testutil.CreateTestOrganization(t, app.DB)
+		admin := createAdminUser(t, app, org.ID)
+		agent := testutil.CreateTestUser(t, app.DB, org.ID)
+
+		req := testutil.NewJSONRequest(t, map[string]any{
+			"skills": []map[string]any{{"skill": "spanish", "proficiency": 9}},
+		})
+		testutil.SetAuthContext(req, org.ID, admin.ID)
+		testutil.SetPathParam(req, "id", agent.ID.String())
+		require.NoError(t, app.UpdateAgentRouting(req))
+		testutil.AssertErrorResponse(t, req, fasthttp.StatusBadRequest, "must be between 1 and 5")
+	})
+}
+
+func TestApp_CreateAgentTransfer_SkillsBasedTeam(t *testing.T) {
+	t.Parallel()
+
+	app := newTestApp(t)
+	org := testutil.CreateTestOrganization(t, app.DB)
+	admin := createAdminUser(t, app, org.ID)
+	account := testutil.CreateTestWhatsAppAccount(t, app.DB, org.ID)
+
+	basic := testutil.CreateTestUser(t, app.DB, org.ID)
+	expert := testutil.CreateTestUser(t, app.DB, org.ID)
+	unskilled := testutil.CreateTestUser(t, app.DB, org.ID)
+	team := createTestTeam(t, app, org.ID, basic.ID, expert.ID, unskilled.ID)
+	require.NoError(t, app.DB.Model(team).Update("assignment_strategy", models.AssignmentStrategySkillsBased).Error)
+
+	setAgentRouting(t, app, org.ID, admin.ID, basic.ID, map[string]any{
+		"skills": []map[string]any{{"skill": "spanish", "proficiency": 2}},
+	})
+	setAgentRouting(t, app, org.ID, admin.ID, expert.ID, map[string]any{
+		"skills":                       []map[string]any{{"skill": "spanish", "proficiency": 5}},
+		"max_concurrent_conversations": 1,
+	})
+
+	agentID := createSkillsTransfer(t, app, org.ID, admin.ID, team.ID, account.Name, []string{"Spanish"})
+	require.NotNil(t, agentID)
+	assert.Equal(t, expert.ID.String(), *agentID, "the most proficient agent is picked")
+
+	agentID = createSkillsTransfer(t, app, org.ID, admin.ID, team.ID, account.Name, []string{"spanish"})
+	require.NotNil(t, agentID)
+	assert.Equal(t, basic.ID.String(), *agentID, "agents at capacity are skipped")
+
+	agentID = createSkillsTransfer(t, app, org.ID, admin.ID, team.ID, account.Name, []string{"spanish", "billing"})
+	assert.Nil(t, agentID, "the transfer waits in the queue when no agent has every skill")
+}
+
+func TestApp_UpdateTeam_OverflowLoop(t *testing.T) {
+	t.Parallel()
+
+	app := newTestApp(t)
+	org := testutil.CreateTestOrganization(t, app.DB)
+	admin := createAdminUser(t, app, org.ID)
+	first := createTeam(t, app, org.ID, "First line")
+	second := createTeam(t, app, org.ID, "Second line")
+	require.NoError(t, app.DB.Model(first).Update("overflow_team_id", second.ID).Error)
+
+	req := testutil.NewJSONRequest(t, map[string]any{
+		"name":             second.Name,
+		"is_active":        true,
+		"overflow_team_id": first.ID.String(),
+	})
+	testutil.SetAuthContext(req, org.ID, admin.ID)
+	testutil.SetPathParam(req, "id", second.ID.String())
+	require.NoError(t, app.UpdateTeam(req))
+	testutil.AssertErrorResponse(t, req, fasthttp.StatusBadRequest, "Overflow teams cannot form a loop")
+}
@@ -0,0 +1,802 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shridarpatil/whatomate/internal/models"
+	"github.com/shridarpatil/whatomate/internal/schedule"
+	"github.com/valyala/fasthttp"
+	"github.com/zerodha/fastglue"
+)
+
+// maxSLAEscalationTiers caps the escalation tiers of an SLA policy
+const maxSLAEscalationTiers = 10
+
+// SLAPolicyRequest represents the request body for creating an SLA policy
+type SLAPolicyRequest struct {
+	Name                    string                     `json:"name"`
+	Description             string                     `json:"description"`
+	IsActive                *bool                      `json:"is_active"` // Defaults to true
+	Priority                int                        `json:"priority"`
+	TeamID                  *string                    `json:"team_id"`
+	MinTransferPriority     models.TransferPriority    `json:"min_transfer_priority"`
+	Tags                    []string                   `json:"tags"`
+	ResponseMinutes         int                        `json:"response_minutes"`
+	ResolutionMinutes       int                        `json:"resolution_minutes"`
+	BusinessHoursOnly       bool                       `json:"business_hours_only"`
+	BusinessHoursScheduleID *string                    `json:"business_hours_schedule_id"`
+	PauseOnCustomer         bool                       `json:"pause_on_customer"`
+	EscalationTiers         []models.SLAEscalationTier `json:"escalation_tiers"`
+}
+
+// SLAPolicyResponse represents an SLA policy in API responses
+type SLAPolicyResponse struct {
+	ID                      uuid.UUID                  `json:"id"`
+	Name                    string                     `json:"name"`
+	Description             string                     `json:"description"`
+	IsActive                bool                       `json:"is_active"`
+	Priority                int                        `json:"priority"`
+	TeamID                  *uuid.UUID                 `json:"team_id"`
+	MinTransferPriority     models.TransferPriority    `json:"min_transfer_priority"`
+	Tags                    []string                   `json:"tags"`
+	ResponseMinutes         int                        `json:"response_minutes"`
+	ResolutionMinutes       int                        `json:"resolution_minutes"`
+	BusinessHoursOnly       bool                       `json:"business_hours_only"`
+	BusinessHoursScheduleID *uuid.UUID                 `json:"business_hours_schedule_id"`
+	PauseOnCustomer         bool                       `json:"pause_on_customer"`
+	EscalationTiers         []models.SLAEscalationTier `json:"escalation_tiers"`
+	CreatedAt               time.Time                  `json:"created_at"`
+	UpdatedAt               time.Time                  `json:"updated_at"`
+}
+
+// SLAComplianceEntry reports how the transfers of an SLA policy met its
+// targets. Compliance is the percentage of measured transfers that met the
+// target, null when the policy has no such target or nothing was measured.
+type SLAComplianceEntry struct {
+	PolicyID             uuid.UUID `json:"policy_id"`
+	PolicyName           string    `json:"policy_name"`
+	Deleted              bool      `json:"deleted"`
+	Transfers            int64     `json:"transfers"`
+	Active               int64     `json:"active"`
+	Escalated            int64     `json:"escalated"`
+	ResponseMet          int64     `json:"response_met"`
+	ResponseBreached     int64     `json:"response_breached"`
+	ResponseCompliance   *float64  `json:"response_compliance"`
+	ResolutionMet        int64     `json:"resolution_met"`
+	ResolutionBreached   int64     `json:"resolution_breached"`
+	ResolutionCompliance *float64  `json:"resolution_compliance"`
+}
+
+// ListSLAPolicies returns the organization's SLA policies in matching order
+func (a *App) ListSLAPolicies(r *fastglue.Request) error {
+	orgID, userID, err := a.getOrgAndUserID(r)
+	if err != nil {
+		return r.SendErrorEnvelope(fasthttp.StatusUnauthorized, "Unauthorized", nil, "")
+	}
+	if err := a.requirePermission(r, userID, models.ResourceSettingsChatbot, models.ActionRead); err != nil {
+		return nil
+	}
+
+	pg := parsePagination(r)
+	search := string(r.RequestCtx.QueryArgs().Peek("search"))
+
+	query := a.DB.Model(&models.SLAPolicy{}).Where("organization_id = ?", orgID)
+	if search != "" {
+		query = query.Where("name ILIKE ?", "%"+search+"%")
+	}
+
+	var total int64
+	query.Count(&total)
+
+	var policies []models.SLAPolicy
+	if err := pg.Apply(query.Order("priority DESC, name ASC")).Find(&policies).Error; err != nil {
+		a.Log.Error("Failed to list SLA policies", "error", err)
+		return r.SendErrorEnvelope(fasthttp.StatusInternalServerError, "Failed to list SLA policies", nil, "")
+	}
+
+	result := make([]SLAPolicyResponse, len(policies))
+	for i := range policies {
+		result[i] = a.slaPolicyToResponse(&policies[i])
+	}
+
+	return r.SendEnvelope(map[string]any{
+		"policies": result,
+		"total":    total,
+		"page":     pg.Page,
+		"limit":    pg.Limit,
+	})
+}
+
+// GetSLAPolicy returns an SLA policy
+func (a *App) GetSLAPolicy(r *fastglue.Request) error {
+	orgID, userID, err := a.getOrgAndUserID(r)
+	if err != nil {
+		return r.SendErrorEnvelope(fasthttp.StatusUnauthorized, "Unauthorized", nil, "")
+	}
+	if err := a.requirePermission(r, userID, models.ResourceSettingsChatbot, models.ActionRead); err != nil {
+		return nil
+	}
+
+	id, err := parsePathUUID(r, "id", "SLA policy")
+	if err != nil {
+		return nil
+	}
+	policy, err := findByIDAndOrg[models.SLAPolicy](a.DB, r, id, orgID, "SLA policy")
+	if err != nil {
+		return nil
+	}
+
+	return r.SendEnvelope(a.slaPolicyToResponse(policy))
+}
+
+// CreateSLAPolicy creates an SLA policy
+func (a *App) CreateSLAPolicy(r *fastglue.Request) error {
+	orgID, userID, err := a.getOrgAndUserID(r)
+	if err != nil {
+		return r.SendErrorEnvelope(fasthttp.StatusUnauthorized, "Unauthorized", nil, "")
+	}
+	if err := a.requirePermission(r, userID, models.ResourceSettingsChatbot, models.ActionWrite); err != nil {
+		return nil
+	}
+
+	var req SLAPolicyRequest
+	if err := a.decodeRequest(r, &req); err != nil {
+		return nil
+	}
+
+	policy := models.SLAPolicy{
+		BaseModel:           models.BaseModel{ID: uuid.New()},
+		OrganizationID:      orgID,
+		Name:                strings.TrimSpace(req.Name),
+		Description:         req.Description,
+		IsActive:            req.IsActive == nil || *req.IsActive,
+		Priority:            req.Priority,
+		MinTransferPriority: req.MinTransferPriority,
+		Tags:                normalizeSLAPolicyTags(req.Tags),
+		ResponseMinutes:     req.ResponseMinutes,
+		ResolutionMinutes:   req.ResolutionMinutes,
+		BusinessHoursOnly:   req.BusinessHoursOnly,
+		PauseOnCustomer:     req.PauseOnCustomer,
+	}
+	if policy.TeamID, err = a.parseSLAPolicyTeamID(orgID, req.TeamID); err != nil {
+		return r.SendErrorEnvelope(fasthttp.StatusBadRequest, err.Error(), nil, "")
+	}
+	if policy.BusinessHoursScheduleID, err = a.parseTeamScheduleID(orgID, req.BusinessHoursScheduleID); err != nil {
+		return r.SendErrorEnvelope(fasthttp.StatusBadRequest, err.Error(), nil, "")
+	}
+	if policy.EscalationTiers, err = slaEscalationTiersToJSONB(req.EscalationTiers); err != nil {
+		return r.SendErrorEnvelope(fasthttp.StatusBadRequest, err.Error(), nil, "")
+	}
+	if err := a.validateSLAPolicy(&policy); err != nil {
+		return r.SendErrorEnvelope(fasthttp.StatusBadRequest, err.Error(), nil, "")
+	}
+
+	// Insert every column so an inactive policy is not created active by the column default
+	if err := a.DB.Select("*").Create(&policy).Error; err != nil {
+		a.Log.Error("Failed to create SLA policy", "error", err)
+		return r.SendErrorEnvelope(fasthttp.StatusInternalServerError, "Failed to create SLA policy", nil, "")
+	}
+
+	return r.SendEnvelope(a.slaPolicyToResponse(&policy))
+}
+
+// UpdateSLAPolicy updates the fields of an SLA policy present in the request.
+// Escalation tiers are replaced as a whole. Active transfers following the
+// policy use the new targets from the next SLA check.
+func (a *App) UpdateSLAPolicy(r *fastglue.Request) error {
+	orgID, userID, err := a.getOrgAndUserID(r)
+	if err != nil {
+		return r.SendErrorEnvelope(fasthttp.StatusUnauthorized, "Unauthorized", nil, "")
+	}
+	if err := a.requirePermission(r, userID, models.ResourceSettingsChatbot, models.ActionWrite); err != nil {
+		return nil
+	}
+
+	id, err := parsePathUUID(r, "id", "SLA policy")
+	if err != nil {
+		return nil
+	}
+	policy, err := findByIDAndOrg[models.SLAPolicy](a.DB, r, id, orgID, "SLA policy")
+	if err != nil {
+		return nil
+	}
+
+	var req struct {
+		Name                    *string                     `json:"name"`
+		Description             *string                     `json:"description"`
+		IsActive                *bool                       `json:"is_active"`
+		Priority                *int                        `json:"priority"`
+		TeamID                  *string                     `json:"team_id"` // Empty clears the condition
+		MinTransferPriority     *models.TransferPriority    `json:"min_transfer_priority"`
+		Tags                    *[]string                   `json:"tags"`
+		ResponseMinutes         *int                        `json:"response_minutes"`
+		ResolutionMinutes       *int                        `json:"resolution_minutes"`
+		BusinessHoursOnly       *bool                       `json:"business_hours_only"`
+		BusinessHoursScheduleID *string                     `json:"business_hours_schedule_id"` // Empty clears the schedule
+		PauseOnCustomer         *bool                       `json:"pause_on_customer"`
+		EscalationTiers         *[]models.SLAEscalationTier `json:"escalation_tiers"`
+	}
+	if err := a.decodeRequest(r, &req); err != nil {
+		return nil
+	}
+
+	if req.Name != nil {
+		policy.Name = strings.TrimSpace(*req.Name)
+	}
+	if req.Description != nil {
+		policy.Description = *req.Description
+	}
+	if req.IsActive != nil {
+		policy.IsActive = *req.IsActive
+	}
+	if req.Priority != nil {
+		policy.Priority = *req.Priority
+	}
+	if req.TeamID != nil {
+		if policy.TeamID, err = a.parseSLAPolicyTeamID(orgID, req.TeamID); err != nil {
+			return r.SendErrorEnvelope(fasthttp.StatusBadRequest, err.Error(), nil, "")
+		}
+	}
+	if req.MinTransferPriority != nil {
+		policy.MinTransferPriority = *req.MinTransferPriority
+	}
+	if req.Tags != nil {
+		policy.Tags = normalizeSLAPolicyTags(*req.Tags)
+	}
+	if req.ResponseMinutes != nil {
+		policy.ResponseMinutes = *req.ResponseMinutes
+	}
+	if req.ResolutionMinutes != nil {
+		policy.ResolutionMinutes = *req.ResolutionMinutes
+	}
+	if req.BusinessHoursOnly != nil {
+		policy.BusinessHoursOnly = *req.BusinessHoursOnly
+	}
+	if req.BusinessHoursScheduleID != nil {
+		if policy.BusinessHoursScheduleID, err = a.parseTeamScheduleID(orgID, req.BusinessHoursScheduleID); err != nil {
+			return r.SendErrorEnvelope(fasthttp.StatusBadRequest, err.Error(), nil, "")
+		}
+	}
+	if req.PauseOnCustomer != nil {
+		policy.PauseOnCustomer = *req.PauseOnCustomer
+	}
+	if req.EscalationTiers != nil {
+		if policy.EscalationTiers, err = slaEscalationTiersToJSONB(*req.EscalationTiers); err != nil {
+			return r.SendErrorEnvelope(fasthttp.StatusBadRequest, err.Error(), nil, "")
+		}
+	}
+	if err := a.validateSLAPolicy(policy); err != nil {
+		return r.SendErrorEnvelope(fasthttp.StatusBadRequest, err.Error(), nil, "")
+	}
+
+	if err := a.DB.Save(policy).Error; err != nil {
+		a.Log.Error("Failed to update SLA policy", "error", err, "policy_id", policy.ID)
+		return r.SendErrorEnvelope(fasthttp.StatusInternalServerError, "Failed to update SLA policy", nil, "")
+	}
+
+	return r.SendEnvelope(a.slaPolicyToResponse(policy))
+}
+
+// DeleteSLAPolicy deletes an SLA policy. New transfers no longer match it;
+// active transfers keep following it until they close, and it stays in the
+// compliance report.
+func (a *App) DeleteSLAPolicy(r *fastglue.Request) error {
+	orgID, userID, err := a.getOrgAndUserID(r)
+	if err != nil {
+		return r.SendErrorEnvelope(fasthttp.StatusUnauthorized, "Unauthorized", nil, "")
+	}
+	if err := a.requirePermission(r, userID, models.ResourceSettingsChatbot, models.ActionWrite); err != nil {
+		return nil
+	}
+
+	id, err := parsePathUUID(r, "id", "SLA policy")
+	if err != nil {
+		return nil
+	}
+	policy, err := findByIDAndOrg[models.SLAPolicy](a.DB, r, id, orgID, "SLA policy")
+	if err != nil {
+		return nil
+	}
+
+	if err := a.DB.Delete(policy).Error; err != nil {
+		a.Log.Error("Failed to delete SLA policy", "error", err, "policy_id", policy.ID)
+		return r.SendErrorEnvelope(fasthttp.StatusInternalServerError, "Failed to delete SLA policy", nil, "")
+	}
+
+	return r.SendEnvelope(map[string]string{"message": "SLA policy deleted"})
+}
+
+// GetSLAComplianceReport reports per SLA policy how the transfers created in
+// the period met the policy's response and resolution targets. The period is
+// given by the from and to query parameters (YYYY-MM-DD) and defaults to the
+// current month.
+func (a *App) GetSLAComplianceReport(r *fastglue.Request) error {
+	orgID, userID, err := a.getOrgAndUserID(r)
+	if err != nil {
+		return r.SendErrorEnvelope(fasthttp.StatusUnauthorized, "Unauthorized", nil, "")
+	}
+	if err := a.requirePermission(r, userID, models.ResourceAnalytics, models.ActionRead); err != nil {
+		return nil
+	}
+
+	fromStr := string(r.RequestCtx.QueryArgs().Peek("from"))
+	toStr := string(r.RequestCtx.QueryArgs().Peek("to"))
+	now := time.Now()
+	periodStart := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+	periodEnd := now
+	if fromStr != "" && toStr != "" {
+		var errMsg string
+		periodStart, periodEnd, errMsg = parseDateRange(fromStr, toStr)
+		if errMsg != "" {
+			return r.SendErrorEnvelope(fasthttp.StatusBadRequest, errMsg, nil, "")
+		}
+	}
+
+	type complianceRow struct {
+		PolicyID           uuid.UUID `gorm:"column:sla_policy_id"`
+		Transfers          int64     `gorm:"column:transfers"`
+		Active             int64     `gorm:"column:active"`
+		Escalated          int64     `gorm:"column:escalated"`
+		ResponseMet        int64     `gorm:"column:response_met"`
+		ResponseBreached   int64     `gorm:"column:response_breached"`
+		ResolutionMet      int64     `gorm:"column:resolution_met"`
+		ResolutionBreached int64     `gorm:"column:resolution_breached"`
+	}
+	var rows []complianceRow
+	if err := a.DB.Model(&models.AgentTransfer{}).
+		Select(`sla_policy_id,
+			COUNT(*) AS transfers,
+			COUNT(*) FILTER (WHERE status = ?) AS active,
+			COUNT(*) FILTER (WHERE escalation_level > 0) AS escalated,
+			COUNT(*) FILTER (WHERE picked_up_at IS NOT NULL AND NOT sla_breached) AS response_met,
+			COUNT(*) FILTER (WHERE sla_breached) AS response_breached,
+			COUNT(*) FILTER (WHERE status = ? AND NOT sla_resolution_breached) AS resolution_met,
+			COUNT(*) FILTER (WHERE sla_resolution_breached) AS resolution_breached`,
+			models.TransferStatusActive, models.TransferStatusResumed).
+		Where("organization_id = ? AND sla_policy_id IS NOT NULL AND transferred_at >= ? AND transferred_at <= ?", orgID, periodStart, periodEnd).
+		Group("sla_policy_id").
+		Scan(&rows).Error; err != nil {
+		a.Log.Error("Failed to calculate SLA compliance", "error", err, "org_id", orgID)
+		return r.SendErrorEnvelope(fasthttp.StatusInternalServerError, "Failed to calculate SLA compliance", nil, "")
+	}
+	stats := make(map[uuid.UUID]complianceRow, len(rows))
+	policyIDs := make([]uuid.UUID, 0, len(rows))
+	for _, row := range rows {
+		stats[row.PolicyID] = row
+		policyIDs = append(policyIDs, row.PolicyID)
+	}
+
+	// Current policies, plus deleted ones that still have transfers in the period
+	var policies []models.SLAPolicy
+	if err := a.DB.Unscoped().
+		Where("organization_id = ? AND (deleted_at IS NULL OR id IN ?)", orgID, append(policyIDs, uuid.Nil)).
+		Order("priority DESC, name ASC").Find(&policies).Error; err != nil {
+		a.Log.Error("Failed to load SLA policies", "error", err, "org_id", orgID)
+		return r.SendErrorEnvelope(fasthttp.StatusInternalServerError, "Failed to calculate SLA compliance", nil, "")
+	}
+
+	entries := make([]SLAComplianceEntry, len(policies))
+	for i, policy := range policies {
+		row := stats[policy.ID]
+		entries[i] = SLAComplianceEntry{
+			PolicyID:           policy.ID,
+			PolicyName:         policy.Name,
+			Deleted:            policy.DeletedAt.Valid,
+			Transfers:          row.Transfers,
+			Active:             row.Active,
+			Escalated:          row.Escalated,
+			ResponseMet:        row.ResponseMet,
+			ResponseBreached:   row.ResponseBreached,
+			ResolutionMet:      row.ResolutionMet,
+			ResolutionBreached: row.ResolutionBreached,
+		}
+		if policy.ResponseMinutes > 0 {
+			entries[i].ResponseCompliance = compliancePercentage(row.ResponseMet, row.ResponseBreached)
+		}
+		if policy.ResolutionMinutes > 0 {
+			entries[i].ResolutionCompliance = compliancePercentage(row.ResolutionMet, row.ResolutionBreached)
+		}
+	}
+
+	return r.SendEnvelope(map[string]any{
+		"policies": entries,
+		"from":     periodStart.Format(time.RFC3339),
+		"to":       periodEnd.Format(time.RFC3339),
+	})
+}
+
+// compliancePercentage returns the share of met targets in percent, or nil
+// when no target was measured
+func compliancePercentage(met, breached int64) *float64 {
+	if met+breached == 0 {
+		return nil
+	}
+	pct := float64(met) / float64(met+breached) * 100
+	return &pct
+}
+
+// parseSLAPolicyTeamID parses the team condition of an SLA policy request,
+// returning nil for any team
+func (a *App) parseSLAPolicyTeamID(orgID uuid.UUID, id *string) (*uuid.UUID, error) {
+	if id == nil || *id == "" {
+		return nil, nil
+	}
+	teamID, err := uuid.Parse(*id)
+	if err != nil {
+		return nil, errors.New("Team not found")
+	}
+	var count int64
+	a.DB.Model(&models.Team{}).Where("id = ? AND organization_id = ?", teamID, orgID).Count(&count)
+	if count == 0 {
+		return nil, errors.New("Team not found")
+	}
+	return &teamID, nil
+}
+
+// normalizeSLAPolicyTags trims the tag condition and drops empty and
+// repeated tags
+func normalizeSLAPolicyTags(tags []string) models.StringArray {
+	result := models.StringArray{}
+	for _, tag := range tags {
+		tag = strings.TrimSpace(tag)
+		if tag != "" && !slices.Contains(result, tag) {
+			result = append(result, tag)
+		}
+	}
+	return result
+}
+
+// validateSLAPolicy checks the name, conditions and targets of a policy
+func (a *App) validateSLAPolicy(policy *models.SLAPolicy) error {
+	if policy.Name == "" {
+		return errors.New("name is required")
+	}
+	if len(policy.Name) > 100 {
+		return errors.New("name must be at most 100 characters")
+	}
+	var count int64
+	a.DB.Model(&models.SLAPolicy{}).
+		Where("organization_id = ? AND name = ? AND id != ?", policy.OrganizationID, policy.Name, policy.ID).Count(&count)
+	if count > 0 {
+		return fmt.Errorf("an SLA policy named %q already exists", policy.Name)
+	}
+
+	if policy.MinTransferPriority != 0 && !isValidTransferPriority(policy.MinTransferPriority) {
+		return fmt.Errorf("min_transfer_priority must be 0 (any) or between %d and %d", models.TransferPriorityLow, models.TransferPriorityVIP)
+	}
+	if policy.ResponseMinutes < 0 || policy.ResolutionMinutes < 0 {
+		return errors.New("response_minutes and resolution_minutes cannot be negative")
+	}
+	if policy.ResponseMinutes > 0 && policy.ResolutionMinutes > 0 && policy.ResolutionMinutes < policy.ResponseMinutes {
+		return errors.New("resolution_minutes cannot be less than response_minutes")
+	}
+	return nil
+}
+
+// isValidSLAEscalationAction reports whether action is one of the defined
+// escalation actions
+func isValidSLAEscalationAction(action models.SLAEscalationAction) bool {
+	switch action {
+	case models.SLAEscalationReassign, models.SLAEscalationNotifyManager, models.SLAEscalationWebhook:
+		return true
+	}
+	return false
+}
+
+// parseSLAEscalationTiers reads the escalation tiers stored on a policy
+func parseSLAEscalationTiers(stored models.JSONBArray) ([]models.SLAEscalationTier, error) {
+	tiers := []models.SLAEscalationTier{}
+	if len(stored) == 0 {
+		return tiers, nil
+	}
+	data, err := json.Marshal(stored)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &tiers); err != nil {
+		return nil, fmt.Errorf("invalid SLA escalation tiers: %w", err)
+	}
+	return tiers, nil
+}
+
+// slaEscalationTiersToJSONB validates escalation tiers and converts them for
+// storage, ordered by after_minutes
+func slaEscalationTiersToJSONB(tiers []models.SLAEscalationTier) (models.JSONBArray, error) {
+	if len(tiers) > maxSLAEscalationTiers {
+		return nil, fmt.Errorf("at most %d escalation tiers are allowed", maxSLAEscalationTiers)
+	}
+	tiers = slices.Clone(tiers)
+	slices.SortStableFunc(tiers, func(x, y models.SLAEscalationTier) int { return x.AfterMinutes - y.AfterMinutes })
+
+	stored := models.JSONBArray{}
+	for i, tier := range tiers {
+		if tier.AfterMinutes <= 0 {
+			return nil, fmt.Errorf("escalation tier %d: after_minutes must be positive", i+1)
+		}
+		if i > 0 && tier.AfterMinutes == tiers[i-1].AfterMinutes {
+			return nil, fmt.Errorf("escalation tier %d: another tier is also reached after %d minutes", i+1, tier.AfterMinutes)
+		}
+		notifyIDs := []string{}
+		for _, id := range tier.NotifyUserIDs {
+			id = strings.TrimSpace(id)
+			if _, err := uuid.Parse(id); err != nil {
+				return nil, fmt.Errorf("escalation tier %d: invalid user ID %q", i+1, id)
+			}
+			if !slices.Contains(notifyIDs, id) {
+				notifyIDs = append(notifyIDs, id)
+			}
+		}
+		actions := []models.SLAEscalationAction{}
+		for _, action := range tier.Actions {
+			if !isValidSLAEscalationAction(action) {
+				return nil, fmt.Errorf("escalation tier %d: invalid action %q", i+1, action)
+			}
+			if !slices.Contains(actions, action) {
+				actions = append(actions, action)
+			}
+		}
+		tier.NotifyUserIDs = notifyIDs
+		tier.Actions = actions
+
+		data, err := json.Marshal(tier)
+		if err != nil {
+			return nil, err
+		}
+		var entry map[string]interface{}
+		if err := json.Unmarshal(data, &entry); err != nil {
+			return nil, err
+		}
+		stored = append(stored, entry)
+	}
+	return stored, nil
+}
+
+// slaPolicyMatches reports whether a transfer meets every condition of the
+// policy
+func slaPolicyMatches(policy *models.SLAPolicy, teamID *uuid.UUID, priority models.TransferPriority, contactTags []string) bool {
+	if policy.TeamID != nil && (teamID == nil || *teamID != *policy.TeamID) {
+		return false
+	}
+	if priority < policy.MinTransferPriority {
+		return false
+	}
+	if len(policy.Tags) > 0 && !slices.ContainsFunc(contactTags, func(tag string) bool { return slices.Contains(policy.Tags, tag) }) {
+		return false
+	}
+	return true
+}
+
+// matchSLAPolicy returns the active SLA policy of the transfer's organization
+// with the highest priority whose conditions the transfer meets, or nil
+func (a *App) matchSLAPolicy(transfer *models.AgentTransfer) *models.SLAPolicy {
+	var policies []models.SLAPolicy
+	if err := a.DB.Where("organization_id = ? AND is_active = ?", transfer.OrganizationID, true).
+		Order("priority DESC, created_at ASC").Find(&policies).Error; err != nil {
+		a.Log.Error("Failed to load SLA policies", "error", err, "org_id", transfer.OrganizationID)
+		return nil
+	}
+	if len(policies) == 0 {
+		return nil
+	}
+
+	var tags []string
+	var contact models.Contact
+	if err := a.DB.Select("id", "tags").Where("id = ?", transfer.ContactID).First(&contact).Error; err == nil {
+		tags = contactTagNames(contact.Tags)
+	}
+	for i := range policies {
+		if slaPolicyMatches(&policies[i], transfer.TeamID, transfer.Priority, tags) {
+			return &policies[i]
+		}
+	}
+	return nil
+}
+
+// slaPolicySchedule returns the business hours an SLA policy's clock counts
+// in: the policy's own schedule, else the team's, else the chatbot's. It
+// returns nil when there are no hours to follow.
+func (a *App) slaPolicySchedule(transfer *models.AgentTransfer, policy *models.SLAPolicy, settings *models.ChatbotSettings) *schedule.Schedule {
+	if policy.BusinessHoursScheduleID != nil {
+		sched, err := a.loadBusinessHoursSchedule(transfer.OrganizationID, *policy.BusinessHoursScheduleID)
+		if err == nil {
+			return sched
+		}
+		a.Log.Error("Failed to load SLA policy business hours schedule", "error", err, "policy_id", policy.ID)
+	}
+	if transfer.TeamID != nil {
+		if _, sched := a.teamOutsideBusinessHours(transfer.OrganizationID, *transfer.TeamID); sched != nil {
+			return sched
+		}
+	}
+	if settings != nil {
+		return a.chatbotSchedule(transfer.OrganizationID, settings.BusinessHours)
+	}
+	return nil
+}
+
+// slaOutsideHours reports whether the clock of a policy that counts only
+// business hours stands still at now
+func (a *App) slaOutsideHours(transfer *models.AgentTransfer, policy *models.SLAPolicy, settings *models.ChatbotSettings, now time.Time) bool {
+	if !policy.BusinessHoursOnly {
+		return false
+	}
+	sched := a.slaPolicySchedule(transfer, policy, settings)
+	return sched != nil && !sched.IsOpen(now)
+}
+
+// waitingOnCustomer reports whether the latest message of the transfer's
+// conversation since the transfer is a reply from an agent
+func (a *App) waitingOnCustomer(transfer *models.AgentTransfer) bool {
+	var last models.Message
+	if err := a.DB.Select("direction").
+		Where("organization_id = ? AND contact_id = ? AND created_at >= ? AND (direction = ? OR sent_by_user_id IS NOT NULL)",
+			transfer.OrganizationID, transfer.ContactID, transfer.TransferredAt, models.DirectionIncoming).
+		Order("created_at DESC").First(&last).Error; err != nil {
+		return false
+	}
+	return last.Direction == models.DirectionOutgoing
+}
+
+// countSLATime adds the time since the SLA clock was last counted to the
+// transfer's SLA time, unless the clock was paused meanwhile
+func countSLATime(transfer *models.AgentTransfer, now time.Time) {
+	since := transfer.TransferredAt
+	if transfer.SLA.ClockAt != nil {
+		since = *transfer.SLA.ClockAt
+	}
+	if !transfer.SLA.Paused && now.After(since) {
+		transfer.SLA.ElapsedSeconds += int64(now.Sub(since) / time.Second)
+	}
+	transfer.SLA.ClockAt = &now
+}
+
+// projectSLAPolicyDeadlines sets the response, resolution and next escalation
+// deadlines of a policy transfer to when they fall if the clock runs from now
+// on. A paused clock pushes them back on every SLA check.
+func projectSLAPolicyDeadlines(transfer *models.AgentTransfer, policy *models.SLAPolicy, tiers []models.SLAEscalationTier, now time.Time) {
+	elapsed := time.Duration(transfer.SLA.ElapsedSeconds) * time.Second
+	deadline := func(minutes int) *time.Time {
+		if minutes <= 0 {
+			return nil
+		}
+		t := now.Add(time.Duration(minutes)*time.Minute - elapsed)
+		return &t
+	}
+	transfer.SLA.ResponseDeadline = deadline(policy.ResponseMinutes)
+	transfer.SLA.ResolutionDeadline = deadline(policy.ResolutionMinutes)
+	transfer.SLA.EscalationAt = nil
+	if level := transfer.SLA.EscalationLevel; level >= 0 && level < len(tiers) {
+		transfer.SLA.EscalationAt = deadline(tiers[level].AfterMinutes)
+	}
+}
+
+// applySLAPolicy makes a new transfer follow an SLA policy and starts its clock
+func (a *App) applySLAPolicy(transfer *models.AgentTransfer, policy *models.SLAPolicy, settings *models.ChatbotSettings, now time.Time) {
+	tiers, err := parseSLAEscalationTiers(policy.EscalationTiers)
+	if err != nil {
+		a.Log.Error("Failed to parse SLA escalation tiers", "error", err, "policy_id", policy.ID)
+	}
+	transfer.SLA.PolicyID = &policy.ID
+	transfer.SLA.ClockAt = &now
+	transfer.SLA.Paused = a.slaOutsideHours(transfer, policy, settings, now)
+	projectSLAPolicyDeadlines(transfer, policy, tiers, now)
+}
+
+// stopSLAPolicyClock counts the last SLA time of a policy transfer that is
+// being closed and records whether it missed the resolution target
+func (a *App) stopSLAPolicyClock(transfer *models.AgentTransfer, now time.Time) {
+	if transfer.SLA.PolicyID == nil {
+		return
+	}
+	var policy models.SLAPolicy
+	if err := a.DB.Unscoped().Where("id = ?", *transfer.SLA.PolicyID).First(&policy).Error; err != nil {
+		return
+	}
+	countSLATime(transfer, now)
+	if policy.ResolutionMinutes > 0 && transfer.SLA.ElapsedSeconds >= int64(policy.ResolutionMinutes)*60 {
+		transfer.SLA.ResolutionBreached = true
+	}
+}
+
+// slaTeamManagers returns the managers of a team as notification targets
+func (a *App) slaTeamManagers(teamID *uuid.UUID) []string {
+	if teamID == nil {
+		return nil
+	}
+	var userIDs []uuid.UUID
+	a.DB.Model(&models.TeamMember{}).Where("team_id = ? AND role = ?", *teamID, models.TeamRoleManager).Pluck("user_id", &userIDs)
+	ids := make([]string, len(userIDs))
+	for i, id := range userIDs {
+		ids[i] = id.String()
+	}
+	return ids
+}
+
+// reassignEscalatedTransfer hands a transfer that reached an SLA escalation
+// tier to the available agent with the fewest active transfers other than
+// its current agent, among the team's agents below their capacity (any
+// active team's when the transfer has no team). Reports whether it was
+// reassigned.
+func (a *App) reassignEscalatedTransfer(transfer *models.AgentTransfer) bool {
+	if transfer.TeamID != nil {
+		if closed, _ := a.teamOutsideBusinessHours(transfer.OrganizationID, *transfer.TeamID); closed {
+			return false
+		}
+	}
+	var others []routingCandidate
+	for _, c := range a.routingCandidates(transfer.OrganizationID, transfer.TeamID) {
+		if transfer.AgentID != nil && c.UserID == *transfer.AgentID {
+			continue
+		}
+		if c.Capacity > 0 && c.Load >= int64(c.Capacity) {
+			continue
+		}
+		others = append(others, c)
+	}
+	selected := leastLoadedCandidate(others)
+	if selected == nil {
+		return false
+	}
+	a.markAssigned(selected)
+
+	previous := transfer.AgentID
+	transfer.AgentID = &selected.UserID
+	if transfer.SLA.PickedUpAt == nil {
+		a.UpdateSLAOnPickup(transfer)
+	}
+	if err := a.DB.Model(transfer).Updates(map[string]any{
+		"agent_id":        transfer.AgentID,
+		"picked_up_at":    transfer.SLA.PickedUpAt,
+		"sla_breached":    transfer.SLA.Breached,
+		"sla_breached_at": transfer.SLA.BreachedAt,
+	}).Error; err != nil {
+		a.Log.Error("Failed to reassign escalated transfer", "error", err, "transfer_id", transfer.ID)
+		transfer.AgentID = previous
+		return false
+	}
+	a.DB.Model(&models.Contact{}).Where("id = ?", transfer.ContactID).Update("assigned_user_id", transfer.AgentID)
+	a.broadcastTransferAssigned(transfer)
+
+	a.Log.Info("Escalated transfer reassigned",
+		"transfer_id", transfer.ID,
+		"from_agent_id", previous,
+		"agent_id", transfer.AgentID,
+	)
+	return true
+}
+
+func (a *App) slaPolicyToResponse(policy *models.SLAPolicy) SLAPolicyResponse {
+	tiers, err := parseSLAEscalationTiers(policy.EscalationTiers)
+	if err != nil {
+		a.Log.Error("Failed to parse SLA escalation tiers", "error", err, "policy_id", policy.ID)
+		tiers = []models.SLAEscalationTier{}
+	}
+	tags := []string(policy.Tags)
+	if tags == nil {
+		tags = []string{}
+	}
+	return SLAPolicyResponse{
+		ID:                      policy.ID,
+		Name:                    policy.Name,
+		Description:             policy.Description,
+		IsActive:                policy.IsActive,
+		Priority:                policy.Priority,
+		TeamID:                  policy.TeamID,
+		MinTransferPriority:     policy.MinTransferPriority,
+		Tags:                    tags,
+		ResponseMinutes:         policy.ResponseMinutes,
+		ResolutionMinutes:       policy.ResolutionMinutes,
+		BusinessHoursOnly:       policy.BusinessHoursOnly,
+		BusinessHoursScheduleID: policy.BusinessHoursScheduleID,
+		PauseOnCustomer:         policy.PauseOnCustomer,
+		EscalationTiers:         tiers,
+		CreatedAt:               policy.CreatedAt,
+		UpdatedAt:               policy.UpdatedAt,
+	}
+}
@@ -0,0 +1,165 @@
+package handlers
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shridarpatil/whatomate/internal/models"
+	"github.com/shridarpatil/whatomate/test/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSLAEscalationTiersToJSONB(t *testing.T) {
+	manager := uuid.New().String()
+	stored, err := slaEscalationTiersToJSONB([]models.SLAEscalationTier{
+		{AfterMinutes: 60, Actions: []models.SLAEscalationAction{models.SLAEscalationReassign, models.SLAEscalationReassign}},
+		{AfterMinutes: 15, NotifyUserIDs: []string{manager, manager}, Actions: []models.SLAEscalationAction{models.SLAEscalationWebhook}},
+	})
+	require.NoError(t, err)
+	tiers, err := parseSLAEscalationTiers(stored)
+	require.NoError(t, err)
+	assert.Equal(t, []models.SLAEscalationTier{
+		{AfterMinutes: 15, NotifyUserIDs: []string{manager}, Actions: []models.SLAEscalationAction{models.SLAEscalationWebhook}},
+		{AfterMinutes: 60, NotifyUserIDs: []string{}, Actions: []models.SLAEscalationAction{models.SLAEscalationReassign}},
+	}, tiers, "tiers are sorted by time and deduplicated")
+
+	for name, tiers := range map[string][]models.SLAEscalationTier{
+		"no time":        {{AfterMinutes: 0}},
+		"same time":      {{AfterMinutes: 30}, {AfterMinutes: 30}},
+		"invalid user":   {{AfterMinutes: 30, NotifyUserIDs: []string{"nobody"}}},
+		"unknown action": {{AfterMinutes: 30, Actions: []models.SLAEscalationAction{"page"}}},
+	} {
+		_, err := slaEscalationTiersToJSONB(tiers)
+		assert.Error(t, err, name)
+	}
+}
+
+func TestSLAPolicyMatches(t *testing.T) {
+	teamID := uuid.New()
+	policy := &models.SLAPolicy{
+		TeamID:              &teamID,
+		MinTransferPriority: models.TransferPriorityHigh,
+		Tags:                models.StringArray{"enterprise", "vip"},
+	}
+
+	assert.True(t, slaPolicyMatches(policy, &teamID, models.TransferPriorityHigh, []string{"vip"}))
+	assert.True(t, slaPolicyMatches(policy, &teamID, models.TransferPriorityVIP, []string{"new", "enterprise"}))
+	assert.False(t, slaPolicyMatches(policy, nil, models.TransferPriorityHigh, []string{"vip"}), "general queue")
+	assert.False(t, slaPolicyMatches(policy, &teamID, models.TransferPriorityNormal, []string{"vip"}), "priority too low")
+	assert.False(t, slaPolicyMatches(policy, &teamID, models.TransferPriorityHigh, []string{"new"}), "no matching tag")
+	assert.True(t, slaPolicyMatches(&models.SLAPolicy{}, nil, models.TransferPriorityLow, nil), "a policy without conditions matches every transfer")
+}
+
+func TestSLAPolicyClock(t *testing.T) {
+	start := time.Date(2026, 3, 2, 9, 0, 0, 0, time.UTC)
+	transfer := &models.AgentTransfer{TransferredAt: start}
+	policy := &models.SLAPolicy{ResponseMinutes: 30, ResolutionMinutes: 120}
+	tiers := []models.SLAEscalationTier{{AfterMinutes: 45}, {AfterMinutes: 90}}
+
+	countSLATime(transfer, start.Add(10*time.Minute))
+	assert.Equal(t, int64(600), transfer.SLA.ElapsedSeconds)
+
+	// Time passing while the clock is paused is not counted
+	transfer.SLA.Paused = true
+	countSLATime(transfer, start.Add(40*time.Minute))
+	assert.Equal(t, int64(600), transfer.SLA.ElapsedSeconds)
+	transfer.SLA.Paused = false
+	now := start.Add(50 * time.Minute)
+	countSLATime(transfer, now)
+	assert.Equal(t, int64(1200), transfer.SLA.ElapsedSeconds)
+	assert.Equal(t, now, *transfer.SLA.ClockAt)
+
+	projectSLAPolicyDeadlines(transfer, policy, tiers, now)
+	assert.Equal(t, now.Add(10*time.Minute), *transfer.SLA.ResponseDeadline)
+	assert.Equal(t, now.Add(100*time.Minute), *transfer.SLA.ResolutionDeadline)
+	assert.Equal(t, now.Add(25*time.Minute), *transfer.SLA.EscalationAt)
+
+	transfer.SLA.EscalationLevel = 2
+	projectSLAPolicyDeadlines(transfer, &models.SLAPolicy{ResolutionMinutes: 120}, tiers, now)
+	assert.Nil(t, transfer.SLA.ResponseDeadline, "no response target")
+	assert.Nil(t, transfer.SLA.EscalationAt, "every tier was reached")
+}
+
+// --- processSLAPolicies ---
+
+func TestProcessSLAPoliciesEscalatesTiers(t *testing.T) {
+	app := newSLATestApp(t)
+	org := testutil.CreateTestOrganization(t, app.DB)
+	contact := testutil.CreateTestContact(t, app.DB, org.ID)
+	agent := testutil.CreateTestUser(t, app.DB, org.ID)
+	account := testutil.CreateTestWhatsAppAccount(t, app.DB, org.ID)
+
+	tiers, err := slaEscalationTiersToJSONB([]models.SLAEscalationTier{
+		{AfterMinutes: 10},
+		{AfterMinutes: 20},
+		{AfterMinutes: 60},
+	})
+	require.NoError(t, err)
+	policy := &models.SLAPolicy{
+		BaseModel:         models.BaseModel{ID: uuid.New()},
+		OrganizationID:    org.ID,
+		Name:              "Standard",
+		IsActive:          true,
+		ResponseMinutes:   15,
+		ResolutionMinutes: 60,
+		EscalationTiers:   tiers,
+	}
+	require.NoError(t, app.DB.Create(policy).Error)
+
+	now := time.Now()
+	clockAt := now.Add(-25 * time.Minute)
+	transfer := createSLATestTransfer(t, app, org.ID, contact.ID, agent.ID, account.Name, models.SLATracking{
+		PolicyID: &policy.ID,
+		ClockAt:  &clockAt,
+	})
+
+	proc := NewSLAProcessor(app, time.Minute)
+	proc.processSLAPolicies(org.ID, models.ChatbotSettings{OrganizationID: org.ID}, now)
+
+	var updated models.AgentTransfer
+	require.NoError(t, app.DB.Where("id = ?", transfer.ID).First(&updated).Error)
+	assert.Equal(t, int64(25*60), updated.SLA.ElapsedSeconds)
+	assert.Equal(t, 2, updated.SLA.EscalationLevel, "both tiers due are reached in one check")
+	require.NotNil(t, updated.SLA.EscalatedAt)
+	assert.True(t, updated.SLA.Breached, "nobody picked the transfer up within 15 minutes")
+	assert.False(t, updated.SLA.ResolutionBreached)
+	require.NotNil(t, updated.SLA.EscalationAt)
+	assert.WithinDuration(t, now.Add(35*time.Minute), *updated.SLA.EscalationAt, time.Second)
+}
+
+func TestProcessSLAPoliciesPausesWhileWaitingOnCustomer(t *testing.T) {
+	app := newSLATestApp(t)
+	org := testutil.CreateTestOrganization(t, app.DB)
+	contact := testutil.CreateTestContact(t, app.DB, org.ID)
+	agent := testutil.CreateTestUser(t, app.DB, org.ID)
+	account := testutil.CreateTestWhatsAppAccount(t, app.DB, org.ID)
+
+	policy := &models.SLAPolicy{
+		BaseModel:         models.BaseModel{ID: uuid.New()},
+		OrganizationID:    org.ID,
+		Name:              "Patient",
+		IsActive:          true,
+		ResolutionMinutes: 60,
+		PauseOnCustomer:   true,
+	}
+	require.NoError(t, app.DB.Create(policy).Error)
+
+	now := time.Now()
+	clockAt := now.Add(-5 * time.Minute)
+	transfer := createSLATestTransfer(t, app, org.ID, contact.ID, agent.ID, account.Name, models.SLATracking{
+		PolicyID: &policy.ID,
+		ClockAt:  &clockAt,
+	})
+	require.NoError(t, app.DB.Model(transfer).Update("transferred_at", now.Add(-10*time.Minute)).Error)
+	createTestAgentMessage(t, app, org.ID, contact.ID, agent.ID, account.Name, now.Add(-time.Minute))
+
+	proc := NewSLAProcessor(app, time.Minute)
+	proc.processSLAPolicies(org.ID, models.ChatbotSettings{OrganizationID: org.ID}, now)
+
+	var updated models.AgentTransfer
+	require.NoError(t, app.DB.Where("id = ?", transfer.ID).First(&updated).Error)
+	assert.Equal(t, int64(5*60), updated.SLA.ElapsedSeconds)
+	assert.True(t, updated.SLA.Paused, "the agent replied last")
+}
@@ -0,0 +1,194 @@
+package handlers_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shridarpatil/whatomate/internal/handlers"
+	"github.com/shridarpatil/whatomate/internal/models"
+	"github.com/shridarpatil/whatomate/test/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/valyala/fasthttp"
+)
+
+func createSLAPolicy(t *testing.T, app *handlers.App, orgID, userID uuid.UUID, body map[string]any) handlers.SLAPolicyResponse {
+	t.Helper()
+
+	req := testutil.NewJSONRequest(t, body)
+	testutil.SetAuthContext(req, orgID, userID)
+	require.NoError(t, app.CreateSLAPolicy(req))
+	require.Equal(t, fasthttp.StatusOK, testutil.GetResponseStatusCode(req))
+
+	var resp handlers.SLAPolicyResponse
+	testutil.ParseEnvelopeResponse(t, req, &resp)
+	return resp
+}
+
+func TestApp_CreateSLAPolicy(t *testing.T) {
+	t.Parallel()
+
+	t.Run("success", func(t *testing.T) {
+		app := newTestApp(t)
+		org := testutil.CreateTestOrganization(t, app.DB)
+		admin := createAdminUser(t, app, org.ID)
+		team := createTestTeam(t, app, org.ID)
+
+		resp := createSLAPolicy(t, app, org.ID, admin.ID, map[string]any{
+			"name":                "Enterprise",
+			"team_id":             team.ID.String(),
+			"tags":                []string{" enterprise ", "enterprise"},
+			"response_minutes":    15,
+			"resolution_minutes":  240,
+			"business_hours_only": true,
+			"escalation_tiers": []map[string]any{
+				{"after_minutes": 60, "actions": []string{"reassign", "webhook"}},
+				{"after_minutes": 30, "actions": []string{"notify_manager"}},
+			},
+		})
+		assert.Equal(t, "Enterprise", resp.Name)
+		assert.True(t, resp.IsActive)
+		require.NotNil(t, resp.TeamID)
+		assert.Equal(t, team.ID, *resp.TeamID)
+		assert.Equal(t, []string{"enterprise"}, resp.Tags)
+		require.Len(t, resp.EscalationTiers, 2)
+		assert.Equal(t, 30, resp.EscalationTiers[0].AfterMinutes, "tiers are ordered by time")
+	})
+
+	t.Run("validation", func(t *testing.T) {
+		app := newTestApp(t)
+		org := testutil.CreateTestOrganization(t, app.DB)
+		admin := createAdminUser(t, app, org.ID)
+		createSLAPolicy(t, app, org.ID, admin.ID, map[string]any{"name": "Standard", "response_minutes": 30})
+
+		for _, tc := range []struct {
+			body    map[string]any
+			message string
+		}{
+			{map[string]any{"name": ""}, "name is required"},
+			{map[string]any{"name": "Standard"}, `an SLA policy named "Standard" already exists`},
+			{map[string]any{"name": "Fast", "response_minutes": 60, "resolution_minutes": 30}, "resolution_minutes cannot be less than response_minutes"},
+			{map[string]any{"name": "Fast", "team_id": uuid.New().String()}, "Team not found"},
+			{map[string]any{"name": "Fast", "escalation_tiers": []map[string]any{{"after_minutes": 0}}}, "escalation tier 1: after_minutes must be positive"},
+		} {
+			req := testutil.NewJSONRequest(t, tc.body)
+			testutil.SetAuthContext(req, org.ID, admin.ID)
+			require.NoError(t, app.CreateSLAPolicy(req))
+			testutil.AssertErrorResponse(t, req, fasthttp.StatusBadRequest, tc.message)
+		}
+	})
+}
+
+func TestApp_UpdateSLAPolicy(t *testing.T) {
+	t.Parallel()
+
+	app := newTestApp(t)
+	org := testutil.CreateTestOrganization(t, app.DB)
+	admin := createAdminUser(t, app, org.ID)
+	team := createTestTeam(t, app, org.ID)
+	policy := createSLAPolicy(t, app, org.ID, admin.ID, map[string]any{
+		"name":    "Standard",
+		"team_id": team.ID.String(),
+	})
+
+	req := testutil.NewJSONRequest(t, map[string]any{
+		"team_id":           "",
+		"is_active":         false,
+		"pause_on_customer": true,
+	})
+	testutil.SetAuthContext(req, org.ID, admin.ID)
+	testutil.SetPathParam(req, "id", policy.ID.String())
+	require.NoError(t, app.UpdateSLAPolicy(req))
+	require.Equal(t, fasthttp.StatusOK, testutil.GetResponseStatusCode(req))
+
+	var resp handlers.SLAPolicyResponse
+	testutil.ParseEnvelopeResponse(t, req, &resp)
+	assert.Equal(t, "Standard", resp.Name)
+	assert.Nil(t, resp.TeamID, "an empty team_id clears the team")
+	assert.False(t, resp.IsActive)
+	assert.True(t, resp.PauseOnCustomer)
+}
+
+func TestApp_SetSLADeadlines_Policy(t *testing.T) {
+	t.Parallel()
+
+	app := newTestApp(t)
+	org := testutil.CreateTestOrganization(t, app.DB)
+	admin := createAdminUser(t, app, org.ID)
+	contact := testutil.CreateTestContact(t, app.DB, org.ID)
+	createSLAPolicy(t, app, org.ID, admin.ID, map[string]any{"name": "Everyone", "priority": 1, "response_minutes": 60})
+	vip := createSLAPolicy(t, app, org.ID, admin.ID, map[string]any{
+		"name":                  "VIP",
+		"priority":              5,
+		"min_transfer_priority": models.TransferPriorityVIP,
+		"response_minutes":      5,
+	})
+
+	settings := &models.ChatbotSettings{
+		SLA: models.SLAConfig{Enabled: true, ResponseMinutes: 30, AutoCloseHours: 24},
+	}
+	transfer := &models.AgentTransfer{
+		OrganizationID: org.ID,
+		ContactID:      contact.ID,
+		Priority:       models.TransferPriorityVIP,
+		TransferredAt:  time.Now(),
+	}
+	before := time.Now()
+	app.SetSLADeadlines(transfer, settings)
+
+	require.NotNil(t, transfer.SLA.PolicyID)
+	assert.Equal(t, vip.ID, *transfer.SLA.PolicyID, "the matching policy with the highest priority applies")
+	require.NotNil(t, transfer.SLA.ResponseDeadline)
+	assert.WithinDuration(t, before.Add(5*time.Minute), *transfer.SLA.ResponseDeadline, time.Second)
+	assert.Nil(t, transfer.SLA.EscalationAt, "the policy has no escalation tiers")
+	assert.NotNil(t, transfer.SLA.ExpiresAt, "auto-close still applies")
+}
+
+func TestApp_GetSLAComplianceReport(t *testing.T) {
+	t.Parallel()
+
+	app := newTestApp(t)
+	org := testutil.CreateTestOrganization(t, app.DB)
+	admin := createAdminUser(t, app, org.ID)
+	account := testutil.CreateTestWhatsAppAccount(t, app.DB, org.ID)
+	contact := testutil.CreateTestContact(t, app.DB, org.ID)
+	policy := createSLAPolicy(t, app, org.ID, admin.ID, map[string]any{
+		"name":               "Standard",
+		"response_minutes":   15,
+		"resolution_minutes": 60,
+	})
+
+	now := time.Now()
+	for _, sla := range []models.SLATracking{
+		{PolicyID: &policy.ID, PickedUpAt: &now},
+		{PolicyID: &policy.ID, PickedUpAt: &now, Breached: true, ResolutionBreached: true},
+		{PolicyID: &policy.ID, Breached: true},
+	} {
+		transfer := createTestTransfer(t, app, org.ID, contact.ID, account.Name, models.TransferStatusResumed, nil)
+		require.NoError(t, app.DB.Model(transfer).Updates(map[string]any{
+			"sla_policy_id":           sla.PolicyID,
+			"picked_up_at":            sla.PickedUpAt,
+			"sla_breached":            sla.Breached,
+			"sla_resolution_breached": sla.ResolutionBreached,
+		}).Error)
+	}
+
+	req := testutil.NewJSONRequest(t, nil)
+	testutil.SetAuthContext(req, org.ID, admin.ID)
+	require.NoError(t, app.GetSLAComplianceReport(req))
+	require.Equal(t, fasthttp.StatusOK, testutil.GetResponseStatusCode(req))
+
+	var resp struct {
+		Policies []handlers.SLAComplianceEntry `json:"policies"`
+	}
+	testutil.ParseEnvelopeResponse(t, req, &resp)
+	require.Len(t, resp.Policies, 1)
+	entry := resp.Policies[0]
+	assert.Equal(t, "Standard", entry.PolicyName)
+	assert.Equal(t, int64(3), entry.Transfers)
+	assert.Equal(t, int64(1), entry.ResponseMet)
+	assert.Equal(t, int64(2), entry.ResponseBreached)
+	require.NotNil(t, entry.ResolutionCompliance)
+	assert.InDelta(t, 66.7, *entry.ResolutionCompliance, 0.1)
+}
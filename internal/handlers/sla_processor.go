@@ -2,10 +2,12 @@ package handlers
 
 import (
 	"context"
+	"slices"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/shridarpatil/whatomate/internal/models"
+	"github.com/shridarpatil/whatomate/internal/webhookutil"
 	"github.com/shridarpatil/whatomate/internal/websocket"
 )
 
@@ -59,6 +61,10 @@ func (p *SLAProcessor) Stop() {
 func (p *SLAProcessor) processStaleTransfers() {
 	now := time.Now()
 
+	// Overflow and snoozed conversations do not depend on SLA settings
+	p.overflowQueuedTransfers(now)
+	p.reopenSnoozedConversations(now)
+
 	// Get all organizations with SLA enabled (use cache)
 	settings, err := p.app.getSLAEnabledSettingsCached()
 	if err != nil {
@@ -90,7 +96,10 @@ func (p *SLAProcessor) processOrganizationSLA(settings models.ChatbotSettings, n
 		p.markSLABreached(orgID, settings, now)
 	}
 
-	// 4. Handle client inactivity (reminders and auto-close)
+	// 4. Advance the clocks of transfers that follow an SLA policy
+	p.processSLAPolicies(orgID, settings, now)
+
+	// 5. Handle client inactivity (reminders and auto-close)
 	if settings.ClientInactivity.ReminderEnabled {
 		p.processClientInactivity(orgID, settings, now)
 	}
@@ -145,6 +154,8 @@ func (p *SLAProcessor) autoCloseExpiredTransfers(orgID uuid.UUID, settings model
 			continue
 		}
 
+		p.app.resolveConversationAutomatically(transfer.ContactID, "No agent response within SLA", now)
+
 		closedCount++
 		p.app.Log.Info("Transfer auto-closed due to expiry",
 			"transfer_id", transfer.ID,
@@ -161,11 +172,12 @@ func (p *SLAProcessor) autoCloseExpiredTransfers(orgID uuid.UUID, settings model
 	}
 }
 
-// escalateTransfers escalates transfers past their escalation deadline
+// escalateTransfers escalates transfers past their escalation deadline.
+// Transfers following an SLA policy escalate by its tiers instead.
 func (p *SLAProcessor) escalateTransfers(orgID uuid.UUID, settings models.ChatbotSettings, now time.Time) {
 	var transfers []models.AgentTransfer
 	if err := p.app.DB.Where(
-		"organization_id = ? AND status = ? AND sla_escalation_at IS NOT NULL AND sla_escalation_at < ? AND escalation_level < 2",
+		"organization_id = ? AND status = ? AND sla_policy_id IS NULL AND sla_escalation_at IS NOT NULL AND sla_escalation_at < ? AND escalation_level < 2",
 		orgID, models.TransferStatusActive, now,
 	).Find(&transfers).Error; err != nil {
 		p.app.Log.Error("Failed to find transfers for escalation", "error", err, "org_id", orgID)
@@ -213,6 +225,17 @@ func (p *SLAProcessor) escalateTransfers(orgID uuid.UUID, settings models.Chatbo
 			p.app.Log.Error("Failed to escalate transfer", "error", err, "transfer_id", transfer.ID)
 			continue
 		}
+		transfer.SLA.EscalationLevel = newLevel
+		transfer.SLA.EscalatedAt = &now
+		if breached, ok := updates["sla_breached"].(bool); ok {
+			transfer.SLA.Breached = breached
+			transfer.SLA.BreachedAt = &now
+		}
+
+		// The new escalation level can raise the transfer's priority
+		if err := p.app.raiseTransferPriority(&transfer, &settings); err != nil {
+			p.app.Log.Error("Failed to update transfer priority", "error", err, "transfer_id", transfer.ID)
+		}
 
 		escalatedCount++
 		p.app.Log.Warn("Transfer escalated",
@@ -239,10 +262,67 @@ func (p *SLAProcessor) escalateTransfers(orgID uuid.UUID, settings models.Chatbo
 	}
 }
 
-// markSLABreached marks transfers as SLA breached when past response deadline
+// overflowQueuedTransfers moves transfers that waited in a team queue longer
+// than the team's overflow wait to its overflow team
+func (p *SLAProcessor) overflowQueuedTransfers(now time.Time) {
+	var teams []models.Team
+	if err := p.app.DB.Where("is_active = ? AND overflow_team_id IS NOT NULL AND overflow_after_minutes > 0", true).
+		Find(&teams).Error; err != nil {
+		p.app.Log.Error("Failed to load teams with overflow", "error", err)
+		return
+	}
+
+	for _, team := range teams {
+		var target models.Team
+		if err := p.app.DB.Where("id = ? AND organization_id = ? AND is_active = ?", team.OverflowTeamID, team.OrganizationID, true).
+			First(&target).Error; err != nil {
+			continue
+		}
+
+		cutoff := now.Add(-time.Duration(team.OverflowAfterMinutes) * time.Minute)
+		var transfers []models.AgentTransfer
+		if err := p.app.DB.Where(
+			"organization_id = ? AND team_id = ? AND status = ? AND agent_id IS NULL AND COALESCE(team_queued_at, transferred_at) < ?",
+			team.OrganizationID, team.ID, models.TransferStatusActive, cutoff,
+		).Order("priority DESC, transferred_at ASC").Find(&transfers).Error; err != nil {
+			p.app.Log.Error("Failed to find transfers to overflow", "error", err, "team_id", team.ID)
+			continue
+		}
+
+		for i := range transfers {
+			if err := p.app.overflowTransfer(&transfers[i], &target, now); err != nil {
+				p.app.Log.Error("Failed to overflow transfer", "error", err, "transfer_id", transfers[i].ID)
+			}
+		}
+	}
+}
+
+// reopenSnoozedConversations reopens snoozed conversations whose snooze has
+// ended
+func (p *SLAProcessor) reopenSnoozedConversations(now time.Time) {
+	var conversations []models.Conversation
+	if err := p.app.DB.Where("status = ? AND snoozed_until <= ?", models.ConversationStatusSnoozed, now).
+		Find(&conversations).Error; err != nil {
+		p.app.Log.Error("Failed to find snoozed conversations", "error", err)
+		return
+	}
+
+	reopened := 0
+	for i := range conversations {
+		if err := p.app.setConversationStatus(&conversations[i], models.ConversationStatusOpen, nil, now); err == nil {
+			reopened++
+		}
+	}
+	if reopened > 0 {
+		p.app.Log.Info("Reopened snoozed conversations", "count", reopened)
+	}
+}
+
+// markSLABreached marks transfers as SLA breached when past response deadline.
+// Transfers following an SLA policy are checked against its clock instead.
 func (p *SLAProcessor) markSLABreached(orgID uuid.UUID, settings models.ChatbotSettings, now time.Time) {
 	result := p.app.DB.Model(&models.AgentTransfer{}).Where(
-		"organization_id = ? AND status = ? AND sla_breached = ? AND sla_response_deadline IS NOT NULL AND sla_response_deadline < ? AND agent_id IS NULL",
+		"organization_id = ? AND status = ? AND sla_policy_id IS NULL AND sla_breached = ? AND sla_response_deadline IS NOT NULL AND sla_response_deadline < ? AND agent_id IS NULL",
 		orgID, models.TransferStatusActive, false, now,
 	).Updates(map[string]interface{}{
 		"sla_breached":    true,
@@ -259,9 +339,210 @@ func (p *SLAProcessor) markSLABreached(orgID uuid.UUID, settings models.ChatbotS
 	}
 }
 
+// processSLAPolicies advances the SLA clocks of active transfers that follow
+// an SLA policy, marks missed targets and runs the escalation tiers reached.
+// Transfers keep their policy until they close, even when it is deactivated
+// or deleted.
+func (p *SLAProcessor) processSLAPolicies(orgID uuid.UUID, settings models.ChatbotSettings, now time.Time) {
+	var transfers []models.AgentTransfer
+	if err := p.app.DB.Where(
+		"organization_id = ? AND status = ? AND sla_policy_id IS NOT NULL",
+		orgID, models.TransferStatusActive,
+	).Find(&transfers).Error; err != nil {
+		p.app.Log.Error("Failed to find transfers with SLA policies", "error", err, "org_id", orgID)
+		return
+	}
+	if len(transfers) == 0 {
+		return
+	}
+
+	policyIDs := make([]uuid.UUID, 0, len(transfers))
+	for _, t := range transfers {
+		policyIDs = append(policyIDs, *t.SLA.PolicyID)
+	}
+	var policies []models.SLAPolicy
+	if err := p.app.DB.Unscoped().Where("organization_id = ? AND id IN ?", orgID, policyIDs).Find(&policies).Error; err != nil {
+		p.app.Log.Error("Failed to load SLA policies", "error", err, "org_id", orgID)
+		return
+	}
+	policyMap := make(map[uuid.UUID]*models.SLAPolicy, len(policies))
+	for i := range policies {
+		policyMap[policies[i].ID] = &policies[i]
+	}
+
+	for i := range transfers {
+		if policy := policyMap[*transfers[i].SLA.PolicyID]; policy != nil {
+			p.advanceSLAPolicyClock(&transfers[i], policy, settings, now)
+		}
+	}
+}
+
+// advanceSLAPolicyClock counts the SLA time a policy transfer used since the
+// last check, decides whether the clock runs until the next one, and applies
+// the policy's targets and escalation tiers
+func (p *SLAProcessor) advanceSLAPolicyClock(transfer *models.AgentTransfer, policy *models.SLAPolicy, settings models.ChatbotSettings, now time.Time) {
+	tiers, err := parseSLAEscalationTiers(policy.EscalationTiers)
+	if err != nil {
+		p.app.Log.Error("Failed to parse SLA escalation tiers", "error", err, "policy_id", policy.ID)
+	}
+
+	countSLATime(transfer, now)
+	transfer.SLA.Paused = p.app.slaOutsideHours(transfer, policy, &settings, now) ||
+		(policy.PauseOnCustomer && p.app.waitingOnCustomer(transfer))
+	elapsed := time.Duration(transfer.SLA.ElapsedSeconds) * time.Second
+
+	// The response target is missed when nobody picked the transfer up in time
+	responseBreached := !transfer.SLA.Breached && policy.ResponseMinutes > 0 && transfer.SLA.PickedUpAt == nil &&
+		elapsed >= time.Duration(policy.ResponseMinutes)*time.Minute
+	if responseBreached {
+		transfer.SLA.Breached = true
+		transfer.SLA.BreachedAt = &now
+	}
+	resolutionBreached := !transfer.SLA.ResolutionBreached && policy.ResolutionMinutes > 0 &&
+		elapsed >= time.Duration(policy.ResolutionMinutes)*time.Minute
+	if resolutionBreached {
+		transfer.SLA.ResolutionBreached = true
+	}
+	projectSLAPolicyDeadlines(transfer, policy, tiers, now)
+
+	if err := p.app.DB.Model(transfer).Updates(map[string]interface{}{
+		"sla_elapsed_seconds":     transfer.SLA.ElapsedSeconds,
+		"sla_clock_at":            transfer.SLA.ClockAt,
+		"sla_paused":              transfer.SLA.Paused,
+		"sla_breached":            transfer.SLA.Breached,
+		"sla_breached_at":         transfer.SLA.BreachedAt,
+		"sla_resolution_breached": transfer.SLA.ResolutionBreached,
+		"sla_response_deadline":   transfer.SLA.ResponseDeadline,
+		"sla_resolution_deadline": transfer.SLA.ResolutionDeadline,
+		"sla_escalation_at":       transfer.SLA.EscalationAt,
+	}).Error; err != nil {
+		p.app.Log.Error("Failed to update SLA clock", "error", err, "transfer_id", transfer.ID)
+		return
+	}
+	if responseBreached || resolutionBreached {
+		p.app.Log.Warn("Transfer missed SLA policy target",
+			"transfer_id", transfer.ID,
+			"policy_id", policy.ID,
+			"response", responseBreached,
+			"resolution", resolutionBreached,
+		)
+	}
+
+	level := transfer.SLA.EscalationLevel
+	for transfer.SLA.EscalationLevel < len(tiers) &&
+		elapsed >= time.Duration(tiers[transfer.SLA.EscalationLevel].AfterMinutes)*time.Minute {
+		next := transfer.SLA.EscalationLevel + 1
+		if !p.escalateSLATier(transfer, policy, tiers[next-1], next, settings, now) {
+			break
+		}
+	}
+	if transfer.SLA.EscalationLevel != level {
+		// The next escalation is now the one of the tier after those reached
+		projectSLAPolicyDeadlines(transfer, policy, tiers, now)
+		if err := p.app.DB.Model(transfer).Update("sla_escalation_at", transfer.SLA.EscalationAt).Error; err != nil {
+			p.app.Log.Error("Failed to update SLA escalation time", "error", err, "transfer_id", transfer.ID)
+		}
+	}
+}
+
+// escalateSLATier raises a policy transfer to the given escalation tier, runs
+// the tier's actions and notifies its users. Reports whether the transfer
+// was escalated.
+func (p *SLAProcessor) escalateSLATier(transfer *models.AgentTransfer, policy *models.SLAPolicy, tier models.SLAEscalationTier, level int, settings models.ChatbotSettings, now time.Time) bool {
+	if err := p.app.DB.Model(transfer).Updates(map[string]interface{}{
+		"escalation_level": level,
+		"escalated_at":     now,
+	}).Error; err != nil {
+		p.app.Log.Error("Failed to escalate transfer", "error", err, "transfer_id", transfer.ID)
+		return false
+	}
+	transfer.SLA.EscalationLevel = level
+	transfer.SLA.EscalatedAt = &now
+
+	notifyIDs := slices.Clone(tier.NotifyUserIDs)
+	for _, action := range tier.Actions {
+		switch action {
+		case models.SLAEscalationReassign:
+			if !p.app.reassignEscalatedTransfer(transfer) {
+				p.app.Log.Warn("No agent to reassign escalated transfer to", "transfer_id", transfer.ID)
+			}
+		case models.SLAEscalationNotifyManager:
+			for _, id := range p.app.slaTeamManagers(transfer.TeamID) {
+				if !slices.Contains(notifyIDs, id) {
+					notifyIDs = append(notifyIDs, id)
+				}
+			}
+		case models.SLAEscalationWebhook:
+			p.dispatchSLAEscalated(transfer, policy, level)
+		}
+	}
+
+	// The new escalation level can raise the transfer's priority
+	if err := p.app.raiseTransferPriority(transfer, &settings); err != nil {
+		p.app.Log.Error("Failed to update transfer priority", "error", err, "transfer_id", transfer.ID)
+	}
+
+	p.app.Log.Warn("Transfer escalated by SLA policy",
+		"transfer_id", transfer.ID,
+		"policy_id", policy.ID,
+		"tier", level,
+		"elapsed_seconds", transfer.SLA.ElapsedSeconds,
+	)
+
+	p.sendEscalationNotification(*transfer, level, notifyIDs)
+	p.broadcastTransferUpdate(*transfer, websocket.TypeTransferEscalated)
+
+	if level == 1 && settings.SLA.WarningMessage != "" {
+		p.sendSLATextToCustomer(*transfer, "SLA warning message", settings.SLA.WarningMessage)
+	}
+	return true
+}
+
+// dispatchSLAEscalated sends the transfer.sla_escalated webhook event
+func (p *SLAProcessor) dispatchSLAEscalated(transfer *models.AgentTransfer, policy *models.SLAPolicy, level int) {
+	var contact models.Contact
+	p.app.DB.Where("id = ?", transfer.ContactID).First(&contact)
+
+	var agentID, agentName *string
+	if transfer.AgentID != nil {
+		id := transfer.AgentID.String()
+		agentID = &id
+		var agent models.User
+		if err := p.app.DB.Select("id", "full_name").Where("id = ?", *transfer.AgentID).First(&agent).Error; err == nil {
+			agentName = &agent.FullName
+		}
+	}
+
+	p.app.DispatchWebhook(transfer.OrganizationID, models.WebhookEventTransferSLAEscalated, webhookutil.TransferSLAEscalatedEventData{
+		TransferEventData: webhookutil.TransferEventData{
+			TransferID:      transfer.ID.String(),
+			ContactID:       contact.ID.String(),
+			ContactPhone:    contact.PhoneNumber,
+			ContactName:     contact.ProfileName,
+			Source:          transfer.Source,
+			Priority:        transfer.Priority,
+			AgentID:         agentID,
+			AgentName:       agentName,
+			WhatsAppAccount: transfer.WhatsAppAccount,
+		},
+		SLAPolicyID:        policy.ID.String(),
+		SLAPolicyName:      policy.Name,
+		Tier:               level,
+		ElapsedMinutes:     int(transfer.SLA.ElapsedSeconds / 60),
+		ResponseBreached:   transfer.SLA.Breached,
+		ResolutionBreached: transfer.SLA.ResolutionBreached,
+	})
+}
+
 // notifyEscalation sends notifications to escalation contacts via WebSocket broadcast
 func (p *SLAProcessor) notifyEscalation(transfer models.AgentTransfer, settings models.ChatbotSettings, level int) {
-	if len(settings.SLA.EscalationNotifyIDs) == 0 {
+	p.sendEscalationNotification(transfer, level, settings.SLA.EscalationNotifyIDs)
+}
+
+// sendEscalationNotification broadcasts an escalation notification for the
+// given users to the organization
+func (p *SLAProcessor) sendEscalationNotification(transfer models.AgentTransfer, level int, notifyIDs []string) {
+	if len(notifyIDs) == 0 {
 		return
 	}
 
@@ -290,11 +571,14 @@ func (p *SLAProcessor) notifyEscalation(transfer models.AgentTransfer, settings
 		"escalation_level":      level,
 		"level_name":            levelName,
 		"waiting_since":         transfer.TransferredAt.Format(time.RFC3339),
-		"escalation_notify_ids": settings.SLA.EscalationNotifyIDs,
+		"escalation_notify_ids": notifyIDs,
 	}
 	if transfer.TeamID != nil {
 		payload["team_id"] = transfer.TeamID.String()
 	}
+	if transfer.SLA.PolicyID != nil {
+		payload["sla_policy_id"] = transfer.SLA.PolicyID.String()
+	}
 	p.app.WSHub.BroadcastToOrg(transfer.OrganizationID, websocket.WSMessage{
 		Type:    websocket.TypeTransferEscalation,
 		Payload: payload,
@@ -303,7 +587,7 @@ func (p *SLAProcessor) notifyEscalation(transfer models.AgentTransfer, settings
 	p.app.Log.Info("Escalation notification sent",
 		"transfer_id", transfer.ID,
 		"level", level,
-		"notify_count", len(settings.SLA.EscalationNotifyIDs),
+		"notify_count", len(notifyIDs),
 	)
 }
 
@@ -355,6 +639,7 @@ func (p *SLAProcessor) broadcastTransferUpdate(transfer models.AgentTransfer, ws
 			"status":           transfer.Status,
 			"escalation_level": transfer.SLA.EscalationLevel,
 			"sla_breached":     transfer.SLA.Breached,
+			"priority":         transfer.Priority,
 		},
 	})
 }
@@ -384,22 +669,27 @@ func (a *App) SetSLADeadlines(transfer *models.AgentTransfer, settings *models.C
 
 	now := time.Now()
 
-	// Response deadline (time to pick up)
-	if settings.SLA.ResponseMinutes > 0 {
-		deadline := now.Add(time.Duration(settings.SLA.ResponseMinutes) * time.Minute)
-		transfer.SLA.ResponseDeadline = &deadline
-	}
+	// Transfers matching an SLA policy follow its targets instead
+	if policy := a.matchSLAPolicy(transfer); policy != nil {
+		a.applySLAPolicy(transfer, policy, settings, now)
+	} else {
+		// Response deadline (time to pick up)
+		if settings.SLA.ResponseMinutes > 0 {
+			deadline := now.Add(time.Duration(settings.SLA.ResponseMinutes) * time.Minute)
+			transfer.SLA.ResponseDeadline = &deadline
+		}
 
-	// Resolution deadline
-	if settings.SLA.ResolutionMinutes > 0 {
-		deadline := now.Add(time.Duration(settings.SLA.ResolutionMinutes) * time.Minute)
-		transfer.SLA.ResolutionDeadline = &deadline
-	}
+		// Resolution deadline
+		if settings.SLA.ResolutionMinutes > 0 {
+			deadline := now.Add(time.Duration(settings.SLA.ResolutionMinutes) * time.Minute)
+			transfer.SLA.ResolutionDeadline = &deadline
+		}
 
-	// Escalation deadline
-	if settings.SLA.EscalationMinutes > 0 {
-		deadline := now.Add(time.Duration(settings.SLA.EscalationMinutes) * time.Minute)
-		transfer.SLA.EscalationAt = &deadline
+		// Escalation deadline
+		if settings.SLA.EscalationMinutes > 0 {
+			deadline := now.Add(time.Duration(settings.SLA.EscalationMinutes) * time.Minute)
+			transfer.SLA.EscalationAt = &deadline
+		}
 	}
 
 	// Expiry deadline (auto-close)
@@ -410,6 +700,7 @@ func (a *App) SetSLADeadlines(transfer *models.AgentTransfer, settings *models.C
 
 	a.Log.Debug("SLA deadlines set",
 		"transfer_id", transfer.ID,
+		"sla_policy_id", transfer.SLA.PolicyID,
 		"response_deadline", transfer.SLA.ResponseDeadline,
 		"escalation_at", transfer.SLA.EscalationAt,
 		"expires_at", transfer.SLA.ExpiresAt,
@@ -555,6 +846,8 @@ func (p *SLAProcessor) autoCloseChatbotSession(contact models.Contact, settings
 		return
 	}
 
+	p.app.resolveConversationAutomatically(contact.ID, "Customer inactive", time.Now())
+
 	p.app.Log.Info("Chatbot session closed due to client inactivity",
 		"contact_id", contact.ID,
 		"phone", contact.PhoneNumber,
@@ -1,22 +0,0 @@
-package handlers
-
-import (
-	"github.com/valyala/fasthttp"
-	"github.com/zerodha/fastglue"
-)
-
-// Stub handlers - not yet implemented
-
-// Message handlers
-func (a *App) MarkMessageRead(r *fastglue.Request) error {
-	return r.SendErrorEnvelope(fasthttp.StatusNotImplemented, "Not implemented yet", nil, "")
-}
-
-// Analytics handlers
-func (a *App) GetMessageAnalytics(r *fastglue.Request) error {
-	return r.SendErrorEnvelope(fasthttp.StatusNotImplemented, "Not implemented yet", nil, "")
-}
-
-func (a *App) GetChatbotAnalytics(r *fastglue.Request) error {
-	return r.SendErrorEnvelope(fasthttp.StatusNotImplemented, "Not implemented yet", nil, "")
-}
@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"errors"
 	"time"
 
 	"github.com/google/uuid"
@@ -13,8 +14,11 @@ import (
 type TeamRequest struct {
 	Name               string                   `json:"name" validate:"required"`
 	Description        string                   `json:"description"`
-	AssignmentStrategy models.AssignmentStrategy `json:"assignment_strategy"` // round_robin, load_balanced, manual
+	AssignmentStrategy models.AssignmentStrategy `json:"assignment_strategy"` // round_robin, load_balanced, skills_based, manual
 	IsActive           bool                     `json:"is_active"`
+	BusinessHoursScheduleID *string             `json:"business_hours_schedule_id"` // Hours the team takes transfers; omit to keep, empty = always
+	OverflowTeamID          *string             `json:"overflow_team_id"`           // Team for transfers left unassigned; omit to keep, empty = none
+	OverflowAfterMinutes    *int                `json:"overflow_after_minutes"`     // Queue wait before overflowing, 0 = never
 }
 
 // TeamMemberRequest represents add member request
@@ -30,6 +34,9 @@ type TeamResponse struct {
 	Description        string                    `json:"description"`
 	AssignmentStrategy models.AssignmentStrategy `json:"assignment_strategy"`
 	IsActive           bool                      `json:"is_active"`
+	BusinessHoursScheduleID *uuid.UUID           `json:"business_hours_schedule_id"`
+	OverflowTeamID          *uuid.UUID           `json:"overflow_team_id"`
+	OverflowAfterMinutes    int                  `json:"overflow_after_minutes"`
 	MemberCount        int                       `json:"member_count"`
 	Members            []TeamMemberResponse      `json:"members,omitempty"`
 	CreatedAt          time.Time                 `json:"created_at"`
@@ -163,9 +170,24 @@ func (a *App) CreateTeam(r *fastglue.Request) error {
 	if strategy == "" {
 		strategy = models.AssignmentStrategyRoundRobin
 	}
-	if strategy != models.AssignmentStrategyRoundRobin && strategy != models.AssignmentStrategyLoadBalanced && strategy != models.AssignmentStrategyManual {
+	if !isValidAssignmentStrategy(strategy) {
 		return r.SendErrorEnvelope(fasthttp.StatusBadRequest, "Invalid assignment strategy", nil, "")
 	}
+	scheduleID, err := a.parseTeamScheduleID(orgID, req.BusinessHoursScheduleID)
+	if err != nil {
+		return r.SendErrorEnvelope(fasthttp.StatusBadRequest, err.Error(), nil, "")
+	}
+	overflowTeamID, err := a.parseOverflowTeamID(orgID, uuid.Nil, req.OverflowTeamID)
+	if err != nil {
+		return r.SendErrorEnvelope(fasthttp.StatusBadRequest, err.Error(), nil, "")
+	}
+	var overflowAfter int
+	if req.OverflowAfterMinutes != nil {
+		if *req.OverflowAfterMinutes < 0 {
+			return r.SendErrorEnvelope(fasthttp.StatusBadRequest, "overflow_after_minutes cannot be negative", nil, "")
+		}
+		overflowAfter = *req.OverflowAfterMinutes
+	}
 
 	team := models.Team{
 		OrganizationID:     orgID,
@@ -173,6 +195,9 @@ func (a *App) CreateTeam(r *fastglue.Request) error {
 		Description:        req.Description,
 		AssignmentStrategy: strategy,
 		IsActive:           true,
+		BusinessHoursScheduleID: scheduleID,
+		OverflowTeamID:          overflowTeamID,
+		OverflowAfterMinutes:    overflowAfter,
 	}
 
 	if err := a.DB.Create(&team).Error; err != nil {
@@ -228,12 +253,34 @@ func (a *App) UpdateTeam(r *fastglue.Request) error {
 	team.IsActive = req.IsActive
 
 	if req.AssignmentStrategy != "" {
-		if req.AssignmentStrategy != models.AssignmentStrategyRoundRobin && req.AssignmentStrategy != models.AssignmentStrategyLoadBalanced && req.AssignmentStrategy != models.AssignmentStrategyManual {
+		if !isValidAssignmentStrategy(req.AssignmentStrategy) {
 			return r.SendErrorEnvelope(fasthttp.StatusBadRequest, "Invalid assignment strategy", nil, "")
 		}
 		team.AssignmentStrategy = req.AssignmentStrategy
 	}
 
+	if req.BusinessHoursScheduleID != nil {
+		scheduleID, err := a.parseTeamScheduleID(orgID, req.BusinessHoursScheduleID)
+		if err != nil {
+			return r.SendErrorEnvelope(fasthttp.StatusBadRequest, err.Error(), nil, "")
+		}
+		team.BusinessHoursScheduleID = scheduleID
+	}
+
+	if req.OverflowTeamID != nil {
+		overflowTeamID, err := a.parseOverflowTeamID(orgID, team.ID, req.OverflowTeamID)
+		if err != nil {
+			return r.SendErrorEnvelope(fasthttp.StatusBadRequest, err.Error(), nil, "")
+		}
+		team.OverflowTeamID = overflowTeamID
+	}
+	if req.OverflowAfterMinutes != nil {
+		if *req.OverflowAfterMinutes < 0 {
+			return r.SendErrorEnvelope(fasthttp.StatusBadRequest, "overflow_after_minutes cannot be negative", nil, "")
+		}
+		team.OverflowAfterMinutes = *req.OverflowAfterMinutes
+	}
+
 	if err := a.DB.Save(&team).Error; err != nil {
 		a.Log.Error("Failed to update team", "error", err)
 		return r.SendErrorEnvelope(fasthttp.StatusInternalServerError, "Failed to update team", nil, "")
@@ -275,6 +322,11 @@ func (a *App) DeleteTeam(r *fastglue.Request) error {
 		return r.SendErrorEnvelope(fasthttp.StatusNotFound, "Team not found", nil, "")
 	}
 
+	// Teams that overflowed to this team no longer overflow
+	a.DB.Model(&models.Team{}).
+		Where("organization_id = ? AND overflow_team_id = ?", orgID, teamID).
+		Update("overflow_team_id", nil)
+
 	return r.SendEnvelope(map[string]string{"message": "Team deleted"})
 }
 
@@ -480,6 +532,45 @@ func (a *App) RemoveTeamMember(r *fastglue.Request) error {
 	return r.SendEnvelope(map[string]string{"message": "Member removed from team"})
 }
 
+// parseTeamScheduleID parses the business hours schedule of a team request,
+// returning nil for no schedule
+func (a *App) parseTeamScheduleID(orgID uuid.UUID, id *string) (*uuid.UUID, error) {
+	if id == nil || *id == "" {
+		return nil, nil
+	}
+	scheduleID, err := uuid.Parse(*id)
+	if err != nil || !a.businessHoursScheduleExists(orgID, scheduleID) {
+		return nil, errors.New("Business hours schedule not found")
+	}
+	return &scheduleID, nil
+}
+
+// parseOverflowTeamID parses the overflow team of a team request, returning
+// nil for no overflow team
+func (a *App) parseOverflowTeamID(orgID, teamID uuid.UUID, id *string) (*uuid.UUID, error) {
+	if id == nil || *id == "" {
+		return nil, nil
+	}
+	overflowTeamID, err := uuid.Parse(*id)
+	if err != nil {
+		return nil, errors.New("Overflow team not found")
+	}
+	if err := a.validateOverflowTeam(orgID, teamID, overflowTeamID); err != nil {
+		return nil, err
+	}
+	return &overflowTeamID, nil
+}
+
+// isValidAssignmentStrategy reports whether s is a known assignment strategy
+func isValidAssignmentStrategy(s models.AssignmentStrategy) bool {
+	switch s {
+	case models.AssignmentStrategyRoundRobin, models.AssignmentStrategyLoadBalanced,
+		models.AssignmentStrategySkillsBased, models.AssignmentStrategyManual:
+		return true
+	}
+	return false
+}
+
 // Helper function to build team response
 func buildTeamResponse(team *models.Team, includeMembers bool) TeamResponse {
 	resp := TeamResponse{
@@ -488,6 +579,9 @@ func buildTeamResponse(team *models.Team, includeMembers bool) TeamResponse {
 		Description:        team.Description,
 		AssignmentStrategy: team.AssignmentStrategy,
 		IsActive:           team.IsActive,
+		BusinessHoursScheduleID: team.BusinessHoursScheduleID,
+		OverflowTeamID:          team.OverflowTeamID,
+		OverflowAfterMinutes:    team.OverflowAfterMinutes,
 		MemberCount:        len(team.Members),
 		CreatedAt:          team.CreatedAt,
 		UpdatedAt:          team.UpdatedAt,
@@ -0,0 +1,208 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/shridarpatil/whatomate/internal/models"
+)
+
+// maxTransferPriorityRules caps the priority rules of an organization
+const maxTransferPriorityRules = 50
+
+// isValidTransferPriority reports whether p is one of the defined priorities
+func isValidTransferPriority(p models.TransferPriority) bool {
+	return p >= models.TransferPriorityLow && p <= models.TransferPriorityVIP
+}
+
+// parseTransferPriorityRules reads the priority rules stored in the agent
+// assignment settings
+func parseTransferPriorityRules(stored models.JSONBArray) ([]models.TransferPriorityRule, error) {
+	rules := []models.TransferPriorityRule{}
+	if len(stored) == 0 {
+		return rules, nil
+	}
+	data, err := json.Marshal(stored)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &rules); err != nil {
+		return nil, fmt.Errorf("invalid transfer priority rules: %w", err)
+	}
+	return rules, nil
+}
+
+// transferPriorityRulesToJSONB validates priority rules and converts them for
+// storage
+func transferPriorityRulesToJSONB(rules []models.TransferPriorityRule) (models.JSONBArray, error) {
+	if len(rules) > maxTransferPriorityRules {
+		return nil, fmt.Errorf("at most %d transfer priority rules are allowed", maxTransferPriorityRules)
+	}
+	stored := models.JSONBArray{}
+	for i, rule := range rules {
+		rule.Tag = strings.TrimSpace(rule.Tag)
+		rule.MetadataKey = strings.TrimSpace(rule.MetadataKey)
+		rule.MetadataValue = strings.TrimSpace(rule.MetadataValue)
+		if !isValidTransferPriority(rule.Priority) {
+			return nil, fmt.Errorf("transfer priority rule %d: priority must be between %d and %d", i+1, models.TransferPriorityLow, models.TransferPriorityVIP)
+		}
+		if rule.MetadataValue != "" && rule.MetadataKey == "" {
+			return nil, fmt.Errorf("transfer priority rule %d: metadata_value requires metadata_key", i+1)
+		}
+		if rule.Source != "" && !isValidTransferSource(rule.Source) {
+			return nil, fmt.Errorf("transfer priority rule %d: invalid source %q", i+1, rule.Source)
+		}
+		if rule.MinEscalationLevel < 0 {
+			return nil, fmt.Errorf("transfer priority rule %d: min_escalation_level cannot be negative", i+1)
+		}
+
+		data, err := json.Marshal(rule)
+		if err != nil {
+			return nil, err
+		}
+		var entry map[string]interface{}
+		if err := json.Unmarshal(data, &entry); err != nil {
+			return nil, err
+		}
+		stored = append(stored, entry)
+	}
+	return stored, nil
+}
+
+// isValidTransferSource reports whether s is one of the defined transfer sources
+func isValidTransferSource(s models.TransferSource) bool {
+	switch s {
+	case models.TransferSourceManual, models.TransferSourceFlow, models.TransferSourceKeyword,
+		models.TransferSourceChatbotDisabled, models.TransferSourceAI:
+		return true
+	}
+	return false
+}
+
+// priorityRuleMatches reports whether a transfer of the contact meets every
+// condition of the rule
+func priorityRuleMatches(rule models.TransferPriorityRule, contact *models.Contact, source models.TransferSource, escalationLevel int) bool {
+	if rule.Source != "" && rule.Source != source {
+		return false
+	}
+	if escalationLevel < rule.MinEscalationLevel {
+		return false
+	}
+	if rule.Tag != "" && (contact == nil || !slices.Contains(contactTagNames(contact.Tags), rule.Tag)) {
+		return false
+	}
+	if rule.MetadataKey != "" {
+		if contact == nil {
+			return false
+		}
+		value, ok := contact.Metadata[rule.MetadataKey]
+		if !ok || value == nil {
+			return false
+		}
+		actual := strings.TrimSpace(fmt.Sprint(value))
+		if actual == "" || (rule.MetadataValue != "" && !strings.EqualFold(actual, rule.MetadataValue)) {
+			return false
+		}
+	}
+	return true
+}
+
+// transferPriority returns the highest priority among the rules a transfer
+// matches, or normal priority when it matches none
+func transferPriority(rules []models.TransferPriorityRule, contact *models.Contact, source models.TransferSource, escalationLevel int) models.TransferPriority {
+	priority := models.TransferPriority(0)
+	for _, rule := range rules {
+		if rule.Priority > priority && priorityRuleMatches(rule, contact, source, escalationLevel) {
+			priority = rule.Priority
+		}
+	}
+	if priority == 0 {
+		return models.TransferPriorityNormal
+	}
+	return priority
+}
+
+// transferPriorityRules returns the priority rules of the chatbot settings
+func (a *App) transferPriorityRules(settings *models.ChatbotSettings) []models.TransferPriorityRule {
+	if settings == nil {
+		return nil
+	}
+	rules, err := parseTransferPriorityRules(settings.AgentAssignment.PriorityRules)
+	if err != nil {
+		a.Log.Error("Failed to parse transfer priority rules", "error", err, "org_id", settings.OrganizationID)
+		return nil
+	}
+	return rules
+}
+
+// preemptiveAgent picks an agent for a VIP transfer that would otherwise wait
+// in a queue: the available agent with the fewest active transfers, even if
+// it is at its conversation capacity. Transfers to a team go to the team's
+// agents, other transfers to the agents of any active team. Returns nil when
+// the team is outside its business hours or nobody is available.
+func (a *App) preemptiveAgent(orgID uuid.UUID, teamID *uuid.UUID) *uuid.UUID {
+	if teamID != nil {
+		if closed, _ := a.teamOutsideBusinessHours(orgID, *teamID); closed {
+			return nil
+		}
+	}
+
+	candidates := a.routingCandidates(orgID, teamID)
+	selected := leastLoadedCandidate(candidates)
+	if selected == nil {
+		return nil
+	}
+	a.markAssigned(selected)
+
+	a.Log.Info("VIP transfer assigned preemptively", "org_id", orgID, "team_id", teamID, "user_id", selected.UserID, "current_load", selected.Load)
+	return &selected.UserID
+}
+
+// raiseTransferPriority re-evaluates the priority rules of an active transfer,
+// e.g. after an SLA escalation, and raises its priority when a rule now gives
+// it a higher one. A transfer that becomes VIP while queued is assigned
+// preemptively.
+func (a *App) raiseTransferPriority(transfer *models.AgentTransfer, settings *models.ChatbotSettings) error {
+	rules := a.transferPriorityRules(settings)
+	if len(rules) == 0 {
+		return nil
+	}
+
+	var contact models.Contact
+	if err := a.DB.Where("id = ?", transfer.ContactID).First(&contact).Error; err != nil {
+		return err
+	}
+	priority := transferPriority(rules, &contact, transfer.Source, transfer.SLA.EscalationLevel)
+	if priority <= transfer.Priority {
+		return nil
+	}
+
+	transfer.Priority = priority
+	updates := map[string]any{"priority": priority}
+	var preemptedBy *uuid.UUID
+	if priority == models.TransferPriorityVIP && transfer.AgentID == nil {
+		preemptedBy = a.preemptiveAgent(transfer.OrganizationID, transfer.TeamID)
+	}
+	if preemptedBy != nil {
+		transfer.AgentID = preemptedBy
+		a.UpdateSLAOnPickup(transfer)
+		updates["agent_id"] = preemptedBy
+		updates["picked_up_at"] = transfer.SLA.PickedUpAt
+		updates["sla_breached"] = transfer.SLA.Breached
+		updates["sla_breached_at"] = transfer.SLA.BreachedAt
+	}
+	if err := a.DB.Model(transfer).Updates(updates).Error; err != nil {
+		return err
+	}
+
+	if preemptedBy != nil {
+		a.DB.Model(&contact).Update("assigned_user_id", preemptedBy)
+	}
+	a.broadcastTransferAssigned(transfer)
+
+	a.Log.Info("Transfer priority raised", "transfer_id", transfer.ID, "priority", priority, "agent_id", transfer.AgentID)
+	return nil
+}
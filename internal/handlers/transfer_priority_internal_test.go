@@ -0,0 +1,58 @@
+package handlers
+
+import (
+	"testing"
+
+	"github.com/shridarpatil/whatomate/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTransferPriority(t *testing.T) {
+	rules := []models.TransferPriorityRule{
+		{Tag: "vip", Priority: models.TransferPriorityVIP},
+		{MetadataKey: "tier", MetadataValue: "gold", Priority: models.TransferPriorityHigh},
+		{Source: models.TransferSourceAI, Priority: models.TransferPriorityLow},
+		{MinEscalationLevel: 1, Priority: models.TransferPriorityHigh},
+	}
+	contact := &models.Contact{
+		Tags:     models.JSONBArray{"new"},
+		Metadata: models.JSONB{"tier": "Gold"},
+	}
+	plain := &models.Contact{}
+
+	assert.Equal(t, models.TransferPriorityNormal, transferPriority(nil, contact, models.TransferSourceManual, 0))
+	assert.Equal(t, models.TransferPriorityNormal, transferPriority(rules, plain, models.TransferSourceManual, 0), "no rule matches")
+	assert.Equal(t, models.TransferPriorityHigh, transferPriority(rules, contact, models.TransferSourceManual, 0), "metadata values match case-insensitively")
+	assert.Equal(t, models.TransferPriorityLow, transferPriority(rules, plain, models.TransferSourceAI, 0))
+	assert.Equal(t, models.TransferPriorityHigh, transferPriority(rules, plain, models.TransferSourceAI, 1), "the highest matching priority wins")
+
+	contact.Tags = append(contact.Tags, "vip")
+	assert.Equal(t, models.TransferPriorityVIP, transferPriority(rules, contact, models.TransferSourceManual, 0))
+
+	anyTier := []models.TransferPriorityRule{{MetadataKey: "tier", Priority: models.TransferPriorityHigh}}
+	assert.Equal(t, models.TransferPriorityHigh, transferPriority(anyTier, contact, models.TransferSourceManual, 0))
+	assert.Equal(t, models.TransferPriorityNormal, transferPriority(anyTier, &models.Contact{Metadata: models.JSONB{"tier": ""}}, models.TransferSourceManual, 0),
+		"an empty metadata value does not match")
+}
+
+func TestTransferPriorityRulesToJSONB(t *testing.T) {
+	stored, err := transferPriorityRulesToJSONB([]models.TransferPriorityRule{
+		{Tag: " vip ", Priority: models.TransferPriorityVIP},
+	})
+	require.NoError(t, err)
+	rules, err := parseTransferPriorityRules(stored)
+	require.NoError(t, err)
+	assert.Equal(t, []models.TransferPriorityRule{{Tag: "vip", Priority: models.TransferPriorityVIP}}, rules)
+
+	for name, rule := range map[string]models.TransferPriorityRule{
+		"missing priority":      {Tag: "vip"},
+		"priority out of range": {Tag: "vip", Priority: 5},
+		"value without key":     {MetadataValue: "gold", Priority: models.TransferPriorityHigh},
+		"unknown source":        {Source: "email", Priority: models.TransferPriorityHigh},
+		"negative escalation":   {MinEscalationLevel: -1, Priority: models.TransferPriorityHigh},
+	} {
+		_, err := transferPriorityRulesToJSONB([]models.TransferPriorityRule{rule})
+		assert.Error(t, err, name)
+	}
+}
@@ -0,0 +1,127 @@
+package handlers_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shridarpatil/whatomate/internal/handlers"
+	"github.com/shridarpatil/whatomate/internal/models"
+	"github.com/shridarpatil/whatomate/test/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/valyala/fasthttp"
+)
+
+func TestApp_CreateAgentTransfer_VIPPreemption(t *testing.T) {
+	t.Parallel()
+
+	app := newTestApp(t)
+	org := testutil.CreateTestOrganization(t, app.DB)
+	admin := createAdminUser(t, app, org.ID)
+	account := testutil.CreateTestWhatsAppAccount(t, app.DB, org.ID)
+
+	settingsReq := testutil.NewJSONRequest(t, map[string]any{
+		"transfer_priority_rules": []map[string]any{
+			{"metadata_key": "tier", "metadata_value": "platinum", "priority": 4},
+		},
+	})
+	testutil.SetAuthContext(settingsReq, org.ID, admin.ID)
+	require.NoError(t, app.UpdateChatbotSettings(settingsReq))
+	require.Equal(t, fasthttp.StatusOK, testutil.GetResponseStatusCode(settingsReq))
+
+	busy := testutil.CreateTestUser(t, app.DB, org.ID)
+	idle := testutil.CreateTestUser(t, app.DB, org.ID)
+	team := createTestTeam(t, app, org.ID, busy.ID, idle.ID)
+	require.NoError(t, app.DB.Model(team).Update("assignment_strategy", models.AssignmentStrategyManual).Error)
+	busyContact := testutil.CreateTestContact(t, app.DB, org.ID)
+	createTestTransfer(t, app, org.ID, busyContact.ID, account.Name, models.TransferStatusActive, &busy.ID)
+
+	createTransfer := func(metadata models.JSONB) handlers.AgentTransferResponse {
+		contact := testutil.CreateTestContact(t, app.DB, org.ID)
+		require.NoError(t, app.DB.Model(contact).Update("metadata", metadata).Error)
+
+		req := testutil.NewJSONRequest(t, map[string]any{
+			"contact_id":       contact.ID.String(),
+			"whatsapp_account": account.Name,
+			"team_id":          team.ID.String(),
+		})
+		testutil.SetAuthContext(req, org.ID, admin.ID)
+		require.NoError(t, app.CreateAgentTransfer(req))
+		require.Equal(t, fasthttp.StatusOK, testutil.GetResponseStatusCode(req))
+
+		var resp struct {
+			Transfer handlers.AgentTransferResponse `json:"transfer"`
+		}
+		testutil.ParseEnvelopeResponse(t, req, &resp)
+		return resp.Transfer
+	}
+
+	regular := createTransfer(models.JSONB{"tier": "silver"})
+	assert.Equal(t, models.TransferPriorityNormal, regular.Priority)
+	assert.Nil(t, regular.AgentID, "the manual team leaves regular transfers in the queue")
+
+	vip := createTransfer(models.JSONB{"tier": "Platinum"})
+	assert.Equal(t, models.TransferPriorityVIP, vip.Priority)
+	require.NotNil(t, vip.AgentID, "VIP transfers are assigned preemptively")
+	assert.Equal(t, idle.ID.String(), *vip.AgentID, "to the least loaded agent")
+}
+
+func TestApp_CreateAgentTransfer_InvalidPriority(t *testing.T) {
+	t.Parallel()
+
+	app := newTestApp(t)
+	org := testutil.CreateTestOrganization(t, app.DB)
+	admin := createAdminUser(t, app, org.ID)
+	contact := testutil.CreateTestContact(t, app.DB, org.ID)
+
+	req := testutil.NewJSONRequest(t, map[string]any{
+		"contact_id": contact.ID.String(),
+		"priority":   7,
+	})
+	testutil.SetAuthContext(req, org.ID, admin.ID)
+	require.NoError(t, app.CreateAgentTransfer(req))
+	testutil.AssertErrorResponse(t, req, fasthttp.StatusBadRequest, "priority must be between 1 (low) and 4 (VIP)")
+}
+
+func TestApp_PickNextTransfer_Priority(t *testing.T) {
+	t.Parallel()
+
+	app := newTestApp(t)
+	org := testutil.CreateTestOrganization(t, app.DB)
+	account := testutil.CreateTestWhatsAppAccount(t, app.DB, org.ID)
+	contact := testutil.CreateTestContact(t, app.DB, org.ID)
+	agent := createTestAgent(t, app, org.ID)
+
+	queue := func(priority models.TransferPriority, waited time.Duration) *models.AgentTransfer {
+		transfer := &models.AgentTransfer{
+			BaseModel:       models.BaseModel{ID: uuid.New()},
+			OrganizationID:  org.ID,
+			ContactID:       contact.ID,
+			WhatsAppAccount: account.Name,
+			PhoneNumber:     contact.PhoneNumber,
+			Status:          models.TransferStatusActive,
+			Source:          models.TransferSourceManual,
+			Priority:        priority,
+			TransferredAt:   time.Now().Add(-waited),
+		}
+		require.NoError(t, app.DB.Create(transfer).Error)
+		return transfer
+	}
+	queue(models.TransferPriorityNormal, 2*time.Hour)
+	olderHigh := queue(models.TransferPriorityHigh, time.Hour)
+	queue(models.TransferPriorityHigh, 30*time.Minute)
+
+	req := testutil.NewJSONRequest(t, nil)
+	testutil.SetAuthContext(req, org.ID, agent.ID)
+	require.NoError(t, app.PickNextTransfer(req))
+	require.Equal(t, fasthttp.StatusOK, testutil.GetResponseStatusCode(req))
+
+	var resp struct {
+		Transfer *handlers.AgentTransferResponse `json:"transfer"`
+	}
+	testutil.ParseEnvelopeResponse(t, req, &resp)
+	require.NotNil(t, resp.Transfer)
+	assert.Equal(t, olderHigh.ID.String(), resp.Transfer.ID, "the highest priority is picked first, then the oldest")
+	assert.Equal(t, models.TransferPriorityHigh, resp.Transfer.Priority)
+}
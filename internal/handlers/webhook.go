@@ -10,6 +10,7 @@ import (
 	"time"
 
 	"github.com/shridarpatil/whatomate/internal/models"
+	"github.com/shridarpatil/whatomate/internal/webhookutil"
 	"github.com/shridarpatil/whatomate/internal/websocket"
 	"github.com/valyala/fasthttp"
 	"github.com/zerodha/fastglue"
@@ -162,6 +163,10 @@ type WebhookPayload struct {
 							ResponseSource      string      `json:"response_source"`
 						} `json:"call_permission_reply,omitempty"`
 					} `json:"interactive,omitempty"`
+					Button *struct {
+						Payload string `json:"payload"`
+						Text    string `json:"text"`
+					} `json:"button,omitempty"`
 					Reaction *struct {
 						MessageID string `json:"message_id"`
 						Emoji     string `json:"emoji"`
@@ -466,24 +471,23 @@ func (a *App) updateMessageStatus(whatsappMsgID, statusValue string, errors []We
 	a.Log.Info("Updated message status", "message_id", message.ID, "status", statusValue)
 
 	// Update campaign stats and recipient status if this is a campaign message
-	if message.Metadata != nil {
-		if campaignID, ok := message.Metadata["campaign_id"].(string); ok && campaignID != "" {
-			a.incrementCampaignStat(campaignID, statusValue)
+	campaignID, _ := message.Metadata["campaign_id"].(string)
+	if campaignID != "" {
+		a.incrementCampaignStat(campaignID, statusValue)
 
-			// Update the BulkMessageRecipient status and timestamps
-			recipientUpdates := map[string]interface{}{
-				"status": newStatus,
-			}
-			switch newStatus {
-			case models.MessageStatusDelivered:
-				recipientUpdates["delivered_at"] = time.Now()
-			case models.MessageStatusRead:
-				recipientUpdates["read_at"] = time.Now()
-			}
-			a.DB.Model(&models.BulkMessageRecipient{}).
-				Where("whats_app_message_id = ?", whatsappMsgID).
-				Updates(recipientUpdates)
+		// Update the BulkMessageRecipient status and timestamps
+		recipientUpdates := map[string]interface{}{
+			"status": newStatus,
 		}
+		switch newStatus {
+		case models.MessageStatusDelivered:
+			recipientUpdates["delivered_at"] = time.Now()
+		case models.MessageStatusRead:
+			recipientUpdates["read_at"] = time.Now()
+		}
+		a.DB.Model(&models.BulkMessageRecipient{}).
+			Where("whats_app_message_id = ?", whatsappMsgID).
+			Updates(recipientUpdates)
 	}
 
 	// Broadcast status update via WebSocket
@@ -500,6 +504,21 @@ func (a *App) updateMessageStatus(whatsappMsgID, statusValue string, errors []We
 			Payload: wsPayload,
 		})
 	}
+
+	statusEvent := webhookutil.MessageStatusEventData{
+		MessageID:         message.ID.String(),
+		WhatsAppMessageID: whatsappMsgID,
+		ContactID:         message.ContactID.String(),
+		Status:            newStatus,
+		CampaignID:        campaignID,
+		WhatsAppAccount:   message.WhatsAppAccount,
+	}
+	if newStatus == models.MessageStatusFailed && len(errors) > 0 {
+		statusEvent.ErrorCode = errors[0].Code
+		statusEvent.ErrorTitle = errors[0].Title
+		statusEvent.ErrorMessage, _ = updates["error_message"].(string)
+	}
+	a.DispatchWebhook(message.OrganizationID, models.WebhookEventMessageStatus, statusEvent)
 }
 
 // processTemplateStatusUpdate updates template status when Meta sends a status update webhook
@@ -527,14 +546,11 @@ func (a *App) processTemplateStatusUpdate(wabaID, event, templateName, templateL
 
 	// Update template for each account that has it
 	for _, account := range accounts {
-		// Find and update the template
-		result := a.DB.Model(&models.Template{}).
-			Where("whats_app_account = ? AND name = ? AND language = ?", account.Name, templateName, templateLanguage).
-			Update("status", status)
-
-		if result.Error != nil {
-			a.Log.Error("Failed to update template status",
-				"error", result.Error,
+		var templates []models.Template
+		if err := a.DB.Where("whats_app_account = ? AND name = ? AND language = ?", account.Name, templateName, templateLanguage).
+			Find(&templates).Error; err != nil {
+			a.Log.Error("Failed to load template for status update",
+				"error", err,
 				"account", account.Name,
 				"template", templateName,
 				"language", templateLanguage,
@@ -542,7 +558,21 @@ func (a *App) processTemplateStatusUpdate(wabaID, event, templateName, templateL
 			continue
 		}
 
-		if result.RowsAffected > 0 {
+		for _, template := range templates {
+			if template.Status == status {
+				continue
+			}
+
+			if err := a.DB.Model(&template).Update("status", status).Error; err != nil {
+				a.Log.Error("Failed to update template status",
+					"error", err,
+					"account", account.Name,
+					"template", templateName,
+					"language", templateLanguage,
+				)
+				continue
+			}
+
 			a.Log.Info("Updated template status from webhook",
 				"account", account.Name,
 				"template", templateName,
@@ -550,6 +580,17 @@ func (a *App) processTemplateStatusUpdate(wabaID, event, templateName, templateL
 				"status", status,
 				"reason", reason,
 			)
+
+			a.DispatchWebhook(template.OrganizationID, models.WebhookEventTemplateStatusChanged, webhookutil.TemplateStatusEventData{
+				TemplateID:      template.ID.String(),
+				MetaTemplateID:  template.MetaTemplateID,
+				Name:            template.Name,
+				Language:        template.Language,
+				PreviousStatus:  template.Status,
+				Status:          status,
+				Reason:          reason,
+				WhatsAppAccount: account.Name,
+			})
 		}
 	}
 }
@@ -0,0 +1,175 @@
+package handlers
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shridarpatil/whatomate/internal/models"
+	"github.com/valyala/fasthttp"
+	"github.com/zerodha/fastglue"
+)
+
+// WebhookDeliverySummary is a delivery as listed in the history, without
+// request and response bodies
+type WebhookDeliverySummary struct {
+	ID             uuid.UUID                    `json:"id"`
+	Event          string                       `json:"event"`
+	Status         models.WebhookDeliveryStatus `json:"status"`
+	ResponseStatus int                          `json:"response_status"`
+	Error          string                       `json:"error,omitempty"`
+	Attempts       int                          `json:"attempts"`
+	DurationMs     int64                        `json:"duration_ms"`
+	NextAttemptAt  *time.Time                   `json:"next_attempt_at,omitempty"`
+	LastAttemptAt  *time.Time                   `json:"last_attempt_at,omitempty"`
+	DeliveredAt    *time.Time                   `json:"delivered_at,omitempty"`
+	RedeliveryOf   *uuid.UUID                   `json:"redelivery_of,omitempty"`
+	CreatedAt      time.Time                    `json:"created_at"`
+}
+
+// ListWebhookDeliveries returns the delivery history of a webhook, newest first
+func (a *App) ListWebhookDeliveries(r *fastglue.Request) error {
+	orgID, userID, err := a.getOrgAndUserID(r)
+	if err != nil {
+		return r.SendErrorEnvelope(fasthttp.StatusUnauthorized, "Unauthorized", nil, "")
+	}
+
+	if err := a.requirePermission(r, userID, models.ResourceWebhooks, models.ActionRead); err != nil {
+		return nil
+	}
+
+	webhookID, err := parsePathUUID(r, "id", "webhook")
+	if err != nil {
+		return nil
+	}
+
+	if _, err := findByIDAndOrg[models.Webhook](a.DB, r, webhookID, orgID, "Webhook"); err != nil {
+		return nil
+	}
+
+	pg := parsePagination(r)
+	status := string(r.RequestCtx.QueryArgs().Peek("status"))
+	event := string(r.RequestCtx.QueryArgs().Peek("event"))
+
+	query := a.DB.Model(&models.WebhookDelivery{}).
+		Where("webhook_id = ? AND organization_id = ?", webhookID, orgID)
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+	if event != "" {
+		query = query.Where("event = ?", event)
+	}
+
+	var total int64
+	query.Count(&total)
+
+	var deliveries []models.WebhookDelivery
+	if err := pg.Apply(query.Omit("request_body", "response_body", "request_headers").Order("created_at DESC")).
+		Find(&deliveries).Error; err != nil {
+		a.Log.Error("Failed to list webhook deliveries", "error", err)
+		return r.SendErrorEnvelope(fasthttp.StatusInternalServerError, "Failed to list webhook deliveries", nil, "")
+	}
+
+	result := make([]WebhookDeliverySummary, len(deliveries))
+	for i, d := range deliveries {
+		result[i] = WebhookDeliverySummary{
+			ID:             d.ID,
+			Event:          d.Event,
+			Status:         d.Status,
+			ResponseStatus: d.ResponseStatus,
+			Error:          d.Error,
+			Attempts:       d.Attempts,
+			DurationMs:     d.DurationMs,
+			NextAttemptAt:  d.NextAttemptAt,
+			LastAttemptAt:  d.LastAttemptAt,
+			DeliveredAt:    d.DeliveredAt,
+			RedeliveryOf:   d.RedeliveryOf,
+			CreatedAt:      d.CreatedAt,
+		}
+	}
+
+	return r.SendEnvelope(map[string]any{
+		"deliveries": result,
+		"total":      total,
+		"page":       pg.Page,
+		"limit":      pg.Limit,
+	})
+}
+
+// GetWebhookDelivery returns a single delivery including the request and
+// response bodies of its latest attempt
+func (a *App) GetWebhookDelivery(r *fastglue.Request) error {
+	orgID, userID, err := a.getOrgAndUserID(r)
+	if err != nil {
+		return r.SendErrorEnvelope(fasthttp.StatusUnauthorized, "Unauthorized", nil, "")
+	}
+
+	if err := a.requirePermission(r, userID, models.ResourceWebhooks, models.ActionRead); err != nil {
+		return nil
+	}
+
+	delivery, err := a.findWebhookDelivery(r, orgID)
+	if err != nil {
+		return nil
+	}
+
+	return r.SendEnvelope(delivery)
+}
+
+// RedeliverWebhookDelivery sends the payload of an earlier delivery again as
+// a new delivery and returns it
+func (a *App) RedeliverWebhookDelivery(r *fastglue.Request) error {
+	orgID, userID, err := a.getOrgAndUserID(r)
+	if err != nil {
+		return r.SendErrorEnvelope(fasthttp.StatusUnauthorized, "Unauthorized", nil, "")
+	}
+
+	if err := a.requirePermission(r, userID, models.ResourceWebhooks, models.ActionWrite); err != nil {
+		return nil
+	}
+
+	original, err := a.findWebhookDelivery(r, orgID)
+	if err != nil {
+		return nil
+	}
+
+	webhook, err := findByIDAndOrg[models.Webhook](a.DB, r, original.WebhookID, orgID, "Webhook")
+	if err != nil {
+		return nil
+	}
+	if !webhook.IsActive {
+		return r.SendErrorEnvelope(fasthttp.StatusConflict, "Webhook is disabled; enable it before redelivering", nil, "")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	delivery, err := a.webhookDeliverer().Redeliver(ctx, webhook, original)
+	if err != nil {
+		a.Log.Error("Failed to redeliver webhook", "error", err, "delivery_id", original.ID)
+		return r.SendErrorEnvelope(fasthttp.StatusInternalServerError, "Failed to redeliver webhook", nil, "")
+	}
+
+	return r.SendEnvelope(delivery)
+}
+
+// findWebhookDelivery loads the delivery named by the delivery_id path param,
+// scoped to the webhook in the id path param. Sends the error response itself.
+func (a *App) findWebhookDelivery(r *fastglue.Request, orgID uuid.UUID) (*models.WebhookDelivery, error) {
+	webhookID, err := parsePathUUID(r, "id", "webhook")
+	if err != nil {
+		return nil, err
+	}
+	deliveryID, err := parsePathUUID(r, "delivery_id", "delivery")
+	if err != nil {
+		return nil, err
+	}
+
+	var delivery models.WebhookDelivery
+	if err := a.DB.Where("id = ? AND webhook_id = ? AND organization_id = ?", deliveryID, webhookID, orgID).
+		First(&delivery).Error; err != nil {
+		_ = r.SendErrorEnvelope(fasthttp.StatusNotFound, "Webhook delivery not found", nil, "")
+		return nil, errEnvelopeSent
+	}
+	return &delivery, nil
+}
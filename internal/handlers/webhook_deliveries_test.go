@@ -0,0 +1,193 @@
+package handlers_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/shridarpatil/whatomate/internal/handlers"
+	"github.com/shridarpatil/whatomate/internal/models"
+	"github.com/shridarpatil/whatomate/test/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/valyala/fasthttp"
+)
+
+// createTestDelivery inserts a finished delivery for a webhook.
+func createTestDelivery(t *testing.T, app *handlers.App, wh *models.Webhook, status models.WebhookDeliveryStatus) *models.WebhookDelivery {
+	t.Helper()
+	d := &models.WebhookDelivery{
+		OrganizationID: wh.OrganizationID,
+		WebhookID:      wh.ID,
+		Event:          "message.incoming",
+		Status:         status,
+		URL:            wh.URL,
+		RequestBody:    `{"event":"message.incoming","data":{"id":1}}`,
+		ResponseStatus: http.StatusInternalServerError,
+		ResponseBody:   "boom",
+		Attempts:       7,
+	}
+	require.NoError(t, app.DB.Create(d).Error)
+	return d
+}
+
+func TestApp_ListWebhookDeliveries(t *testing.T) {
+	t.Parallel()
+
+	app := newTestApp(t)
+	org := testutil.CreateTestOrganization(t, app.DB)
+	adminRole := testutil.CreateAdminRole(t, app.DB, org.ID)
+	user := testutil.CreateTestUser(t, app.DB, org.ID, testutil.WithRoleID(&adminRole.ID))
+	wh := createTestWebhook(t, app, org.ID, "Hook", "https://example.com/hook", []string{"message.incoming"})
+
+	createTestDelivery(t, app, wh, models.WebhookDeliveryFailed)
+	delivered := createTestDelivery(t, app, wh, models.WebhookDeliveryDelivered)
+
+	req := testutil.NewGETRequest(t)
+	testutil.SetAuthContext(req, org.ID, user.ID)
+	testutil.SetPathParam(req, "id", wh.ID.String())
+	req.RequestCtx.QueryArgs().Set("status", "delivered")
+
+	require.NoError(t, app.ListWebhookDeliveries(req))
+	require.Equal(t, fasthttp.StatusOK, testutil.GetResponseStatusCode(req))
+
+	var resp struct {
+		Data struct {
+			Deliveries []handlers.WebhookDeliverySummary `json:"deliveries"`
+			Total      int64                             `json:"total"`
+		} `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(testutil.GetResponseBody(req), &resp))
+	assert.Equal(t, int64(1), resp.Data.Total)
+	require.Len(t, resp.Data.Deliveries, 1)
+	assert.Equal(t, delivered.ID, resp.Data.Deliveries[0].ID)
+}
+
+func TestApp_GetWebhookDelivery_IncludesBodies(t *testing.T) {
+	t.Parallel()
+
+	app := newTestApp(t)
+	org := testutil.CreateTestOrganization(t, app.DB)
+	adminRole := testutil.CreateAdminRole(t, app.DB, org.ID)
+	user := testutil.CreateTestUser(t, app.DB, org.ID, testutil.WithRoleID(&adminRole.ID))
+	wh := createTestWebhook(t, app, org.ID, "Hook", "https://example.com/hook", []string{"message.incoming"})
+	d := createTestDelivery(t, app, wh, models.WebhookDeliveryFailed)
+
+	req := testutil.NewGETRequest(t)
+	testutil.SetAuthContext(req, org.ID, user.ID)
+	testutil.SetPathParam(req, "id", wh.ID.String())
+	testutil.SetPathParam(req, "delivery_id", d.ID.String())
+
+	require.NoError(t, app.GetWebhookDelivery(req))
+	require.Equal(t, fasthttp.StatusOK, testutil.GetResponseStatusCode(req))
+
+	var resp struct {
+		Data models.WebhookDelivery `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(testutil.GetResponseBody(req), &resp))
+	assert.Equal(t, d.RequestBody, resp.Data.RequestBody)
+	assert.Equal(t, "boom", resp.Data.ResponseBody)
+	assert.Equal(t, http.StatusInternalServerError, resp.Data.ResponseStatus)
+
+	// A delivery of another webhook is not found through this one
+	other := createTestWebhook(t, app, org.ID, "Other", "https://example.com/other", []string{"message.incoming"})
+	req = testutil.NewGETRequest(t)
+	testutil.SetAuthContext(req, org.ID, user.ID)
+	testutil.SetPathParam(req, "id", other.ID.String())
+	testutil.SetPathParam(req, "delivery_id", d.ID.String())
+	require.NoError(t, app.GetWebhookDelivery(req))
+	assert.Equal(t, fasthttp.StatusNotFound, testutil.GetResponseStatusCode(req))
+}
+
+func TestApp_RedeliverWebhookDelivery(t *testing.T) {
+	t.Parallel()
+
+	var requestCount atomic.Int32
+	var deliveryHeader atomic.Value
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requestCount.Add(1)
+		deliveryHeader.Store(r.Header.Get("X-Webhook-Delivery"))
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	app := newTestApp(t, withHTTPClient(&http.Client{Timeout: 5 * time.Second}))
+	org := testutil.CreateTestOrganization(t, app.DB)
+	adminRole := testutil.CreateAdminRole(t, app.DB, org.ID)
+	user := testutil.CreateTestUser(t, app.DB, org.ID, testutil.WithRoleID(&adminRole.ID))
+	wh := createTestWebhook(t, app, org.ID, "Hook", server.URL, []string{"message.incoming"})
+	original := createTestDelivery(t, app, wh, models.WebhookDeliveryFailed)
+
+	req := testutil.NewJSONRequest(t, nil)
+	testutil.SetAuthContext(req, org.ID, user.ID)
+	testutil.SetPathParam(req, "id", wh.ID.String())
+	testutil.SetPathParam(req, "delivery_id", original.ID.String())
+
+	require.NoError(t, app.RedeliverWebhookDelivery(req))
+	require.Equal(t, fasthttp.StatusOK, testutil.GetResponseStatusCode(req), string(testutil.GetResponseBody(req)))
+
+	var resp struct {
+		Data models.WebhookDelivery `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(testutil.GetResponseBody(req), &resp))
+	assert.NotEqual(t, original.ID, resp.Data.ID)
+	assert.Equal(t, models.WebhookDeliveryDelivered, resp.Data.Status)
+	assert.Equal(t, original.RequestBody, resp.Data.RequestBody)
+	require.NotNil(t, resp.Data.RedeliveryOf)
+	assert.Equal(t, original.ID, *resp.Data.RedeliveryOf)
+	assert.Equal(t, int32(1), requestCount.Load())
+	assert.Equal(t, resp.Data.ID.String(), deliveryHeader.Load())
+}
+
+func TestApp_RedeliverWebhookDelivery_DisabledWebhook(t *testing.T) {
+	t.Parallel()
+
+	app := newTestApp(t)
+	org := testutil.CreateTestOrganization(t, app.DB)
+	adminRole := testutil.CreateAdminRole(t, app.DB, org.ID)
+	user := testutil.CreateTestUser(t, app.DB, org.ID, testutil.WithRoleID(&adminRole.ID))
+	wh := createTestWebhook(t, app, org.ID, "Hook", "https://example.com/hook", []string{"message.incoming"})
+	original := createTestDelivery(t, app, wh, models.WebhookDeliveryFailed)
+	require.NoError(t, app.DB.Model(wh).Update("is_active", false).Error)
+
+	req := testutil.NewJSONRequest(t, nil)
+	testutil.SetAuthContext(req, org.ID, user.ID)
+	testutil.SetPathParam(req, "id", wh.ID.String())
+	testutil.SetPathParam(req, "delivery_id", original.ID.String())
+
+	require.NoError(t, app.RedeliverWebhookDelivery(req))
+	assert.Equal(t, fasthttp.StatusConflict, testutil.GetResponseStatusCode(req))
+}
+
+func TestApp_UpdateWebhook_ReenableClearsFailures(t *testing.T) {
+	t.Parallel()
+
+	app := newTestApp(t)
+	org := testutil.CreateTestOrganization(t, app.DB)
+	user := testutil.CreateTestUser(t, app.DB, org.ID)
+	wh := createTestWebhook(t, app, org.ID, "Hook", "https://example.com/hook", []string{"message.incoming"})
+	require.NoError(t, app.DB.Model(wh).Updates(map[string]any{
+		"is_active":       false,
+		"failure_count":   5,
+		"disabled_at":     time.Now(),
+		"disabled_reason": "Disabled after 5 consecutive failed deliveries",
+	}).Error)
+
+	req := testutil.NewJSONRequest(t, map[string]any{"is_active": true})
+	testutil.SetAuthContext(req, org.ID, user.ID)
+	testutil.SetPathParam(req, "id", wh.ID.String())
+
+	require.NoError(t, app.UpdateWebhook(req))
+	require.Equal(t, fasthttp.StatusOK, testutil.GetResponseStatusCode(req))
+
+	var updated models.Webhook
+	require.NoError(t, app.DB.Where("id = ?", wh.ID).First(&updated).Error)
+	assert.True(t, updated.IsActive)
+	assert.Equal(t, 0, updated.FailureCount)
+	assert.Nil(t, updated.DisabledAt)
+	assert.Empty(t, updated.DisabledReason)
+}
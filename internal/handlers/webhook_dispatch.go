@@ -1,11 +1,7 @@
 package handlers
 
 import (
-	"bytes"
 	"context"
-	"crypto/hmac"
-	"crypto/sha256"
-	"encoding/hex"
 	"encoding/json"
 	"net/http"
 	"sync"
@@ -13,55 +9,18 @@ import (
 
 	"github.com/google/uuid"
 	"github.com/shridarpatil/whatomate/internal/models"
+	"github.com/shridarpatil/whatomate/internal/webhookutil"
 )
 
-
-// OutboundWebhookPayload represents the structure sent to external webhook endpoints
-type OutboundWebhookPayload struct {
-	Event     string      `json:"event"`
-	Timestamp time.Time   `json:"timestamp"`
-	Data      interface{} `json:"data"`
-}
-
-// MessageEventData represents data for message events
-type MessageEventData struct {
-	MessageID       string             `json:"message_id"`
-	ContactID       string             `json:"contact_id"`
-	ContactPhone    string             `json:"contact_phone"`
-	ContactName     string             `json:"contact_name"`
-	MessageType     models.MessageType `json:"message_type"`
-	Content         string             `json:"content"`
-	WhatsAppAccount string             `json:"whatsapp_account"`
-	Direction       models.Direction   `json:"direction,omitempty"`
-	SentByUserID    string             `json:"sent_by_user_id,omitempty"`
-}
-
-// ContactEventData represents data for contact events
-type ContactEventData struct {
-	ContactID       string `json:"contact_id"`
-	ContactPhone    string `json:"contact_phone"`
-	ContactName     string `json:"contact_name"`
-	WhatsAppAccount string `json:"whatsapp_account"`
-}
-
-// TransferEventData represents data for transfer events
-type TransferEventData struct {
-	TransferID      string                `json:"transfer_id"`
-	ContactID       string                `json:"contact_id"`
-	ContactPhone    string                `json:"contact_phone"`
-	ContactName     string                `json:"contact_name"`
-	Source          models.TransferSource `json:"source"`
-	Reason          string                `json:"reason,omitempty"`
-	AgentID         *string               `json:"agent_id,omitempty"`
-	AgentName       *string               `json:"agent_name,omitempty"`
-	WhatsAppAccount string                `json:"whatsapp_account"`
-}
-
 // maxConcurrentWebhooks limits the number of concurrent webhook deliveries per dispatch
 const maxConcurrentWebhooks = 10
 
 // DispatchWebhook sends an event to all matching webhooks for the organization
 func (a *App) DispatchWebhook(orgID uuid.UUID, eventType models.WebhookEvent, data interface{}) {
+	if a.simulation != nil {
+		a.simulation.captureWebhook(string(eventType), data)
+		return
+	}
 	a.wg.Add(1)
 	go func() {
 		defer a.wg.Done()
@@ -119,48 +78,28 @@ func containsEvent(events models.StringArray, event string) bool {
 }
 
 func (a *App) sendWebhook(ctx context.Context, webhook models.Webhook, eventType string, data interface{}) {
-	payload := OutboundWebhookPayload{
-		Event:     eventType,
-		Timestamp: time.Now().UTC(),
-		Data:      data,
-	}
-
-	jsonData, err := json.Marshal(payload)
+	jsonData, err := json.Marshal(webhookutil.NewPayload(models.WebhookEvent(eventType), data))
 	if err != nil {
 		a.Log.Error("failed to marshal webhook payload", "error", err, "webhook_id", webhook.ID)
 		return
 	}
 
-	// Retry logic with exponential backoff
-	maxRetries := 3
-	for attempt := 0; attempt < maxRetries; attempt++ {
-		// Check if context was cancelled before retry
-		if ctx.Err() != nil {
-			a.Log.Warn("webhook delivery cancelled", "reason", ctx.Err(), "webhook_id", webhook.ID)
-			return
-		}
-
-		if attempt > 0 {
-			// Exponential backoff: 1s, 2s, 4s
-			select {
-			case <-ctx.Done():
-				a.Log.Warn("webhook delivery cancelled during backoff", "reason", ctx.Err(), "webhook_id", webhook.ID)
-				return
-			case <-time.After(time.Duration(1<<attempt) * time.Second):
-			}
-		}
+	// Persist the delivery first so that retries survive restarts; the first
+	// attempt is made here and any retries are picked up by the workers
+	deliverer := a.webhookDeliverer()
+	delivery, err := deliverer.Enqueue(&webhook, eventType, jsonData)
+	if err != nil {
+		a.Log.Error("failed to record webhook delivery", "error", err, "webhook_id", webhook.ID)
+		return
+	}
 
-		if err := a.sendWebhookRequest(ctx, webhook, jsonData); err != nil {
-			a.Log.Warn("webhook delivery failed",
-				"error", err,
-				"webhook_id", webhook.ID,
-				"attempt", attempt+1,
-				"max_retries", maxRetries,
-			)
-			continue
-		}
+	delivery, err = deliverer.Deliver(ctx, delivery.ID)
+	if err != nil {
+		a.Log.Error("webhook delivery attempt failed", "error", err, "webhook_id", webhook.ID)
+		return
+	}
 
-		// Success
+	if delivery.Status == models.WebhookDeliveryDelivered {
 		a.Log.Debug("webhook delivered",
 			"webhook_id", webhook.ID,
 			"event", eventType,
@@ -169,39 +108,37 @@ func (a *App) sendWebhook(ctx context.Context, webhook models.Webhook, eventType
 		return
 	}
 
-	a.Log.Error("webhook delivery failed after all retries",
+	a.Log.Warn("webhook delivery failed",
+		"error", delivery.Error,
 		"webhook_id", webhook.ID,
-		"event", eventType,
-		"url", webhook.URL,
+		"delivery_id", delivery.ID,
+		"next_attempt_at", delivery.NextAttemptAt,
 	)
 }
 
-func (a *App) sendWebhookRequest(ctx context.Context, webhook models.Webhook, jsonData []byte) error {
-	req, err := http.NewRequestWithContext(ctx, "POST", webhook.URL, bytes.NewBuffer(jsonData))
+// webhookDeliverer returns a deliverer using the app's database, Redis and
+// SSRF-safe HTTP client
+func (a *App) webhookDeliverer() *webhookutil.Deliverer {
+	policy, err := webhookutil.NewPolicy(a.Config.Webhooks)
 	if err != nil {
-		return err
+		a.Log.Warn("Invalid webhook retry schedule, using defaults", "error", err)
 	}
-
-	// Set headers
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("User-Agent", "Whatomate-Webhook/1.0")
-
-	// Add custom headers from webhook config
-	if webhook.Headers != nil {
-		for key, value := range webhook.Headers {
-			if strValue, ok := value.(string); ok {
-				req.Header.Set(key, strValue)
-			}
-		}
+	return &webhookutil.Deliverer{
+		DB:         a.DB,
+		Redis:      a.Redis,
+		HTTPClient: a.HTTPClient,
+		Log:        a.Log,
+		Policy:     policy,
 	}
+}
 
-	// Add HMAC signature if secret is configured
-	if webhook.Secret != "" {
-		signature := computeHMACSignature(jsonData, webhook.Secret)
-		req.Header.Set("X-Webhook-Signature", signature)
+// sendWebhookRequest sends a single, unrecorded request to a webhook
+func (a *App) sendWebhookRequest(ctx context.Context, webhook models.Webhook, event string, jsonData []byte) error {
+	req, err := webhookutil.NewRequest(ctx, &webhook, event, "", jsonData)
+	if err != nil {
+		return err
 	}
 
-	// Send request
 	resp, err := a.HTTPClient.Do(req)
 	if err != nil {
 		return err
@@ -216,12 +153,6 @@ func (a *App) sendWebhookRequest(ctx context.Context, webhook models.Webhook, js
 	return nil
 }
 
-func computeHMACSignature(data []byte, secret string) string {
-	h := hmac.New(sha256.New, []byte(secret))
-	h.Write(data)
-	return "sha256=" + hex.EncodeToString(h.Sum(nil))
-}
-
 // WebhookError represents a webhook delivery error
 type WebhookError struct {
 	StatusCode int
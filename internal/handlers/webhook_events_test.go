@@ -0,0 +1,145 @@
+package handlers_test
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/shridarpatil/whatomate/internal/models"
+	"github.com/shridarpatil/whatomate/test/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/valyala/fasthttp"
+)
+
+// webhookRecorder is an endpoint that records the payloads it receives
+type webhookRecorder struct {
+	mu       sync.Mutex
+	payloads []map[string]any
+}
+
+func newWebhookRecorder(t *testing.T) (*webhookRecorder, *httptest.Server) {
+	t.Helper()
+	rec := &webhookRecorder{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		var payload map[string]any
+		_ = json.Unmarshal(body, &payload)
+		rec.mu.Lock()
+		rec.payloads = append(rec.payloads, payload)
+		rec.mu.Unlock()
+		w.WriteHeader(http.StatusOK)
+	}))
+	t.Cleanup(server.Close)
+	return rec, server
+}
+
+func (rec *webhookRecorder) events() []map[string]any {
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+	return append([]map[string]any(nil), rec.payloads...)
+}
+
+func TestApp_UpdateContactTags_DispatchesContactTagged(t *testing.T) {
+	t.Parallel()
+
+	rec, server := newWebhookRecorder(t)
+	app := newTestApp(t, withHTTPClient(&http.Client{Timeout: 5 * time.Second}))
+	org := testutil.CreateTestOrganization(t, app.DB)
+	adminRole := testutil.CreateAdminRole(t, app.DB, org.ID)
+	user := testutil.CreateTestUser(t, app.DB, org.ID, testutil.WithRoleID(&adminRole.ID))
+	contact := testutil.CreateTestContact(t, app.DB, org.ID)
+	require.NoError(t, app.DB.Model(contact).Update("tags", models.JSONBArray{"vip", "lead"}).Error)
+	createTestWebhook(t, app, org.ID, "Hook", server.URL, []string{string(models.WebhookEventContactTagged)})
+	clearWebhookCache(t, app.Redis, org.ID)
+
+	req := testutil.NewJSONRequest(t, map[string]any{"tags": []string{"vip", "customer"}})
+	testutil.SetAuthContext(req, org.ID, user.ID)
+	testutil.SetPathParam(req, "id", contact.ID.String())
+
+	require.NoError(t, app.UpdateContactTags(req))
+	require.Equal(t, fasthttp.StatusOK, testutil.GetResponseStatusCode(req))
+	app.WaitForBackgroundTasks()
+
+	events := rec.events()
+	require.Len(t, events, 1)
+	assert.Equal(t, "contact.tagged", events[0]["event"])
+	data := events[0]["data"].(map[string]any)
+	assert.Equal(t, contact.ID.String(), data["contact_id"])
+	assert.Equal(t, []any{"vip", "customer"}, data["tags"])
+	assert.Equal(t, []any{"customer"}, data["added"])
+	assert.Equal(t, []any{"lead"}, data["removed"])
+
+	// Setting the same tags again is not a change
+	req = testutil.NewJSONRequest(t, map[string]any{"tags": []string{"customer", "vip"}})
+	testutil.SetAuthContext(req, org.ID, user.ID)
+	testutil.SetPathParam(req, "id", contact.ID.String())
+	require.NoError(t, app.UpdateContactTags(req))
+	app.WaitForBackgroundTasks()
+	assert.Len(t, rec.events(), 1)
+}
+
+func TestApp_AssignContact_DispatchesContactAssigned(t *testing.T) {
+	t.Parallel()
+
+	rec, server := newWebhookRecorder(t)
+	app := newTestApp(t, withHTTPClient(&http.Client{Timeout: 5 * time.Second}))
+	org := testutil.CreateTestOrganization(t, app.DB)
+	adminRole := testutil.CreateAdminRole(t, app.DB, org.ID)
+	user := testutil.CreateTestUser(t, app.DB, org.ID, testutil.WithRoleID(&adminRole.ID))
+	agent := testutil.CreateTestUser(t, app.DB, org.ID, testutil.WithFullName("Agent Smith"))
+	contact := testutil.CreateTestContact(t, app.DB, org.ID)
+	createTestWebhook(t, app, org.ID, "Hook", server.URL, []string{string(models.WebhookEventContactAssigned)})
+	clearWebhookCache(t, app.Redis, org.ID)
+
+	req := testutil.NewJSONRequest(t, map[string]any{"user_id": agent.ID})
+	testutil.SetAuthContext(req, org.ID, user.ID)
+	testutil.SetPathParam(req, "id", contact.ID.String())
+
+	require.NoError(t, app.AssignContact(req))
+	require.Equal(t, fasthttp.StatusOK, testutil.GetResponseStatusCode(req))
+	app.WaitForBackgroundTasks()
+
+	events := rec.events()
+	require.Len(t, events, 1)
+	assert.Equal(t, "contact.assigned", events[0]["event"])
+	data := events[0]["data"].(map[string]any)
+	assert.Equal(t, agent.ID.String(), data["assigned_user_id"])
+	assert.Equal(t, "Agent Smith", data["assigned_user_name"])
+	assert.Equal(t, user.ID.String(), data["assigned_by_user_id"])
+	assert.NotContains(t, data, "previous_user_id")
+}
+
+func TestApp_CreateConversationNote_DispatchesNoteCreated(t *testing.T) {
+	t.Parallel()
+
+	rec, server := newWebhookRecorder(t)
+	app := newTestApp(t, withHTTPClient(&http.Client{Timeout: 5 * time.Second}))
+	org := testutil.CreateTestOrganization(t, app.DB)
+	adminRole := testutil.CreateAdminRole(t, app.DB, org.ID)
+	user := testutil.CreateTestUser(t, app.DB, org.ID, testutil.WithRoleID(&adminRole.ID))
+	contact := testutil.CreateTestContact(t, app.DB, org.ID)
+	createTestWebhook(t, app, org.ID, "Hook", server.URL, []string{string(models.WebhookEventNoteCreated)})
+	clearWebhookCache(t, app.Redis, org.ID)
+
+	req := testutil.NewJSONRequest(t, map[string]any{"content": "Called back, waiting on invoice"})
+	testutil.SetAuthContext(req, org.ID, user.ID)
+	testutil.SetPathParam(req, "id", contact.ID.String())
+
+	require.NoError(t, app.CreateConversationNote(req))
+	require.Equal(t, fasthttp.StatusOK, testutil.GetResponseStatusCode(req))
+	app.WaitForBackgroundTasks()
+
+	events := rec.events()
+	require.Len(t, events, 1)
+	assert.Equal(t, "note.created", events[0]["event"])
+	data := events[0]["data"].(map[string]any)
+	assert.Equal(t, contact.ID.String(), data["contact_id"])
+	assert.Equal(t, contact.PhoneNumber, data["contact_phone"])
+	assert.Equal(t, "Called back, waiting on invoice", data["content"])
+	assert.Equal(t, user.ID.String(), data["created_by_id"])
+}
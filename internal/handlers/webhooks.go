@@ -11,6 +11,7 @@ import (
 
 	"github.com/google/uuid"
 	"github.com/shridarpatil/whatomate/internal/models"
+	"github.com/shridarpatil/whatomate/internal/webhookutil"
 	"github.com/valyala/fasthttp"
 	"github.com/zerodha/fastglue"
 )
@@ -95,15 +96,18 @@ type WebhookRequest struct {
 
 // WebhookResponse represents the API response for a webhook
 type WebhookResponse struct {
-	ID        uuid.UUID         `json:"id"`
-	Name      string            `json:"name"`
-	URL       string            `json:"url"`
-	Events    []string          `json:"events"`
-	Headers   map[string]string `json:"headers"`
-	IsActive  bool              `json:"is_active"`
-	HasSecret bool              `json:"has_secret"`
-	CreatedAt string            `json:"created_at"`
-	UpdatedAt string            `json:"updated_at"`
+	ID             uuid.UUID         `json:"id"`
+	Name           string            `json:"name"`
+	URL            string            `json:"url"`
+	Events         []string          `json:"events"`
+	Headers        map[string]string `json:"headers"`
+	IsActive       bool              `json:"is_active"`
+	HasSecret      bool              `json:"has_secret"`
+	FailureCount   int               `json:"failure_count"`
+	DisabledAt     *time.Time        `json:"disabled_at,omitempty"`
+	DisabledReason string            `json:"disabled_reason,omitempty"`
+	CreatedAt      string            `json:"created_at"`
+	UpdatedAt      string            `json:"updated_at"`
 }
 
 // AvailableWebhookEvents returns the list of available webhook event types
@@ -114,6 +118,18 @@ var AvailableWebhookEvents = []map[string]string{
 	{"value": string(models.WebhookEventTransferCreated), "label": "Transfer Created", "description": "When a transfer to human agent is requested"},
 	{"value": string(models.WebhookEventTransferAssigned), "label": "Transfer Assigned", "description": "When a transfer is assigned to an agent"},
 	{"value": string(models.WebhookEventTransferResumed), "label": "Transfer Resumed", "description": "When chatbot is resumed (transfer closed)"},
+	{"value": string(models.WebhookEventConversationStatus), "label": "Conversation Status Changed", "description": "When a conversation is opened, set pending, snoozed, reopened or resolved"},
+	{"value": string(models.WebhookEventTransferSLAEscalated), "label": "Transfer SLA Escalated", "description": "When a transfer reaches an SLA policy escalation tier with the webhook action"},
+	{"value": string(models.WebhookEventMessageStatus), "label": "Message Status", "description": "When a sent message is delivered, read or fails"},
+	{"value": string(models.WebhookEventCampaignStarted), "label": "Campaign Started", "description": "When a campaign starts sending"},
+	{"value": string(models.WebhookEventCampaignCompleted), "label": "Campaign Completed", "description": "When all recipients of a campaign have been processed"},
+	{"value": string(models.WebhookEventCallStarted), "label": "Call Started", "description": "When an incoming or outgoing call starts"},
+	{"value": string(models.WebhookEventCallEnded), "label": "Call Ended", "description": "When a call ends, with duration and recording"},
+	{"value": string(models.WebhookEventChatbotSessionCompleted), "label": "Chatbot Session Completed", "description": "When a contact completes a chatbot flow"},
+	{"value": string(models.WebhookEventContactTagged), "label": "Contact Tagged", "description": "When tags are added to or removed from a contact"},
+	{"value": string(models.WebhookEventContactAssigned), "label": "Contact Assigned", "description": "When a contact is assigned or unassigned"},
+	{"value": string(models.WebhookEventNoteCreated), "label": "Note Created", "description": "When a conversation note is added"},
+	{"value": string(models.WebhookEventTemplateStatusChanged), "label": "Template Status Changed", "description": "When Meta approves, rejects or pauses a template"},
 }
 
 // ListWebhooks returns all webhooks for the organization
@@ -284,6 +300,12 @@ func (a *App) UpdateWebhook(r *fastglue.Request) error {
 		webhook.Secret = req.Secret
 	}
 
+	// Re-enabling an endpoint clears its failure history
+	if req.IsActive && (!webhook.IsActive || webhook.FailureCount > 0) {
+		webhook.FailureCount = 0
+		webhook.DisabledAt = nil
+		webhook.DisabledReason = ""
+	}
 	webhook.IsActive = req.IsActive
 
 	if err := a.DB.Save(webhook).Error; err != nil {
@@ -348,7 +370,7 @@ func (a *App) TestWebhook(r *fastglue.Request) error {
 		"timestamp": time.Now().UTC().Format(time.RFC3339),
 	}
 
-	payload := OutboundWebhookPayload{
+	payload := webhookutil.Payload{
 		Event:     "test",
 		Timestamp: time.Now().UTC(),
 		Data:      testData,
@@ -364,7 +386,7 @@ func (a *App) TestWebhook(r *fastglue.Request) error {
 	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
 	defer cancel()
 
-	if err := a.sendWebhookRequest(ctx, *webhook, jsonData); err != nil {
+	if err := a.sendWebhookRequest(ctx, *webhook, payload.Event, jsonData); err != nil {
 		a.Log.Error("Webhook test failed", "error", err, "webhook_id", webhook.ID)
 		return r.SendErrorEnvelope(fasthttp.StatusBadGateway, "Webhook test failed", nil, "")
 	}
@@ -386,14 +408,17 @@ func webhookToResponse(wh models.Webhook) WebhookResponse {
 	}
 
 	return WebhookResponse{
-		ID:        wh.ID,
-		Name:      wh.Name,
-		URL:       wh.URL,
-		Events:    events,
-		Headers:   headers,
-		IsActive:  wh.IsActive,
-		HasSecret: wh.Secret != "",
-		CreatedAt: wh.CreatedAt.Format(time.RFC3339),
-		UpdatedAt: wh.UpdatedAt.Format(time.RFC3339),
+		ID:             wh.ID,
+		Name:           wh.Name,
+		URL:            wh.URL,
+		Events:         events,
+		Headers:        headers,
+		IsActive:       wh.IsActive,
+		HasSecret:      wh.Secret != "",
+		FailureCount:   wh.FailureCount,
+		DisabledAt:     wh.DisabledAt,
+		DisabledReason: wh.DisabledReason,
+		CreatedAt:      wh.CreatedAt.Format(time.RFC3339),
+		UpdatedAt:      wh.UpdatedAt.Format(time.RFC3339),
 	}
 }
@@ -0,0 +1,119 @@
+package knowledge
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Chunk sizes in characters. Roughly 250 tokens per chunk keeps several
+// chunks within a prompt while still carrying enough context to answer from.
+const (
+	DefaultChunkSize    = 1000
+	DefaultChunkOverlap = 150
+)
+
+// Chunk is a piece of a section sized for embedding
+type Chunk struct {
+	Index   int
+	Heading string
+	Content string
+}
+
+// ChunkSections splits sections into chunks of at most size characters.
+// Paragraphs are kept together where possible; when a chunk is full the next
+// one starts with the last overlap characters of the previous one so text
+// cut at a boundary is still retrievable.
+func ChunkSections(sections []Section, size, overlap int) []Chunk {
+	if size <= 0 {
+		size = DefaultChunkSize
+	}
+	if overlap < 0 || overlap >= size {
+		overlap = 0
+	}
+
+	var chunks []Chunk
+	for _, section := range sections {
+		for _, content := range chunkText(section.Text, size, overlap) {
+			chunks = append(chunks, Chunk{Index: len(chunks), Heading: section.Label, Content: content})
+		}
+	}
+	return chunks
+}
+
+func chunkText(text string, size, overlap int) []string {
+	var chunks []string
+	var current []rune
+	pending := false // current holds text not yet in a chunk
+
+	for _, paragraph := range splitParagraphs(text) {
+		for _, piece := range splitLong([]rune(paragraph), size-overlap) {
+			if pending && len(current)+len(piece)+2 > size {
+				chunks = append(chunks, string(current))
+				current = tail(current, overlap)
+			}
+			if len(current) > 0 {
+				current = append(current, '\n', '\n')
+			}
+			current = append(current, piece...)
+			pending = true
+		}
+	}
+	if pending {
+		chunks = append(chunks, string(current))
+	}
+	return chunks
+}
+
+// splitParagraphs splits text at blank lines and collapses runs of
+// whitespace inside each paragraph
+func splitParagraphs(text string) []string {
+	var paragraphs []string
+	for _, block := range strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n\n") {
+		if p := strings.Join(strings.Fields(block), " "); p != "" {
+			paragraphs = append(paragraphs, p)
+		}
+	}
+	return paragraphs
+}
+
+// splitLong breaks a paragraph longer than max runes, preferring to cut at a
+// space near the end of each piece
+func splitLong(paragraph []rune, max int) [][]rune {
+	if max <= 0 {
+		max = 1
+	}
+	var pieces [][]rune
+	for len(paragraph) > max {
+		cut := max
+		for i := max; i > max/2; i-- {
+			if unicode.IsSpace(paragraph[i]) {
+				cut = i
+				break
+			}
+		}
+		pieces = append(pieces, paragraph[:cut])
+		paragraph = []rune(strings.TrimLeftFunc(string(paragraph[cut:]), unicode.IsSpace))
+	}
+	if len(paragraph) > 0 {
+		pieces = append(pieces, paragraph)
+	}
+	return pieces
+}
+
+// tail returns the last n runes of s, starting at a word boundary
+func tail(s []rune, n int) []rune {
+	if n <= 0 || len(s) == 0 {
+		return nil
+	}
+	if len(s) <= n {
+		return append([]rune(nil), s...)
+	}
+	start := len(s) - n
+	for i := start; i < len(s); i++ {
+		if unicode.IsSpace(s[i]) {
+			start = i + 1
+			break
+		}
+	}
+	return []rune(strings.TrimSpace(string(s[start:])))
+}
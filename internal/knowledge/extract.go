@@ -0,0 +1,236 @@
+// Package knowledge turns uploaded documents into text chunks for the
+// chatbot's knowledge base. Documents are split into sections that keep a
+// label (page, heading or row range) so answers can cite where a chunk came
+// from, and sections are split into overlapping chunks sized for embedding.
+package knowledge
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/ledongthuc/pdf"
+)
+
+// Source types
+const (
+	SourcePDF      = "pdf"
+	SourceMarkdown = "markdown"
+	SourceCSV      = "csv"
+	SourceText     = "text"
+)
+
+// csvRowsPerSection is how many CSV rows are grouped under one citation label
+const csvRowsPerSection = 20
+
+// ErrUnsupportedType is returned for files that can't be extracted
+var ErrUnsupportedType = errors.New("unsupported file type; upload a PDF, Markdown, CSV or text file")
+
+// Section is a labelled part of a document
+type Section struct {
+	Label string // e.g. "Page 3", "Returns > Refunds" or "Rows 2-21"
+	Text  string
+}
+
+// DetectSourceType returns the source type for a file name and content type
+func DetectSourceType(fileName, contentType string) (string, error) {
+	switch strings.ToLower(filepath.Ext(fileName)) {
+	case ".pdf":
+		return SourcePDF, nil
+	case ".md", ".markdown":
+		return SourceMarkdown, nil
+	case ".csv":
+		return SourceCSV, nil
+	case ".txt", ".text":
+		return SourceText, nil
+	}
+
+	switch strings.ToLower(strings.TrimSpace(strings.Split(contentType, ";")[0])) {
+	case "application/pdf":
+		return SourcePDF, nil
+	case "text/markdown", "text/x-markdown":
+		return SourceMarkdown, nil
+	case "text/csv":
+		return SourceCSV, nil
+	case "text/plain":
+		return SourceText, nil
+	}
+	return "", ErrUnsupportedType
+}
+
+// Extract returns the text sections of a document
+func Extract(sourceType string, data []byte) ([]Section, error) {
+	var sections []Section
+	var err error
+	switch sourceType {
+	case SourcePDF:
+		sections, err = extractPDF(data)
+	case SourceMarkdown:
+		sections, err = extractMarkdown(data)
+	case SourceCSV:
+		sections, err = extractCSV(data)
+	case SourceText:
+		sections, err = extractText(data)
+	default:
+		return nil, ErrUnsupportedType
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// Drop empty sections
+	result := sections[:0]
+	for _, s := range sections {
+		s.Text = strings.TrimSpace(s.Text)
+		if s.Text != "" {
+			result = append(result, s)
+		}
+	}
+	if len(result) == 0 {
+		return nil, errors.New("no text found in the document")
+	}
+	return result, nil
+}
+
+func extractPDF(data []byte) (sections []Section, err error) {
+	// The PDF reader panics on some malformed files
+	defer func() {
+		if r := recover(); r != nil {
+			sections, err = nil, fmt.Errorf("failed to read PDF: %v", r)
+		}
+	}()
+
+	reader, err := pdf.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read PDF: %w", err)
+	}
+
+	fonts := make(map[string]*pdf.Font)
+	for i := 1; i <= reader.NumPage(); i++ {
+		page := reader.Page(i)
+		if page.V.IsNull() {
+			continue
+		}
+		for _, name := range page.Fonts() {
+			if _, ok := fonts[name]; !ok {
+				font := page.Font(name)
+				fonts[name] = &font
+			}
+		}
+		text, err := page.GetPlainText(fonts)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read PDF page %d: %w", i, err)
+		}
+		sections = append(sections, Section{Label: fmt.Sprintf("Page %d", i), Text: text})
+	}
+	return sections, nil
+}
+
+// extractMarkdown splits a Markdown document at its headings. Section labels
+// are the heading path, e.g. "Shipping > International".
+func extractMarkdown(data []byte) ([]Section, error) {
+	if !utf8.Valid(data) {
+		return nil, errors.New("file is not valid UTF-8 text")
+	}
+
+	var sections []Section
+	var headings []string
+	var body strings.Builder
+	inCode := false
+
+	flush := func() {
+		sections = append(sections, Section{Label: strings.Join(headings, " > "), Text: body.String()})
+		body.Reset()
+	}
+
+	for _, line := range strings.Split(string(data), "\n") {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "```") {
+			inCode = !inCode
+		}
+		if !inCode && strings.HasPrefix(trimmed, "#") {
+			level := len(trimmed) - len(strings.TrimLeft(trimmed, "#"))
+			title := strings.TrimSpace(trimmed[level:])
+			if level <= 6 && title != "" && (len(trimmed) == level || trimmed[level] == ' ') {
+				flush()
+				if level > len(headings) {
+					level = len(headings) + 1
+				}
+				headings = append(headings[:level-1], title)
+				continue
+			}
+		}
+		body.WriteString(line)
+		body.WriteString("\n")
+	}
+	flush()
+	return sections, nil
+}
+
+// extractCSV renders each row as "column: value" pairs, grouping rows into
+// sections labelled with their row numbers (the header is row 1)
+func extractCSV(data []byte) ([]Section, error) {
+	reader := csv.NewReader(bytes.NewReader(data))
+	reader.FieldsPerRecord = -1
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CSV header: %w", err)
+	}
+
+	var sections []Section
+	var body strings.Builder
+	first, row := 2, 1
+	flush := func() {
+		if body.Len() > 0 {
+			sections = append(sections, Section{Label: fmt.Sprintf("Rows %d-%d", first, row), Text: body.String()})
+			body.Reset()
+		}
+		first = row + 1
+	}
+
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CSV: %w", err)
+		}
+		row++
+
+		var fields []string
+		for i, value := range record {
+			value = strings.TrimSpace(value)
+			if value == "" {
+				continue
+			}
+			if i < len(header) && strings.TrimSpace(header[i]) != "" {
+				fields = append(fields, strings.TrimSpace(header[i])+": "+value)
+			} else {
+				fields = append(fields, value)
+			}
+		}
+		if len(fields) > 0 {
+			body.WriteString(strings.Join(fields, "; "))
+			body.WriteString("\n\n")
+		}
+		if row-first+1 >= csvRowsPerSection {
+			flush()
+		}
+	}
+	flush()
+	return sections, nil
+}
+
+func extractText(data []byte) ([]Section, error) {
+	if !utf8.Valid(data) {
+		return nil, errors.New("file is not valid UTF-8 text")
+	}
+	return []Section{{Text: string(data)}}, nil
+}
@@ -0,0 +1,158 @@
+package knowledge
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// buildPDF returns a minimal PDF with one page per text, using a standard
+// font so no font program needs to be embedded
+func buildPDF(pages ...string) []byte {
+	var objects []string
+	kids := make([]string, len(pages))
+	for i := range pages {
+		kids[i] = fmt.Sprintf("%d 0 R", 4+i*2)
+	}
+	objects = append(objects,
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(pages)),
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica >>",
+	)
+	for i, text := range pages {
+		stream := fmt.Sprintf("BT /F1 12 Tf 72 720 Td (%s) Tj ET", text)
+		objects = append(objects,
+			fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 612 792] /Resources << /Font << /F1 3 0 R >> >> /Contents %d 0 R >>", 5+i*2),
+			fmt.Sprintf("<< /Length %d >>\nstream\n%s\nendstream", len(stream), stream),
+		)
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString("%PDF-1.4\n")
+	offsets := make([]int, len(objects))
+	for i, obj := range objects {
+		offsets[i] = buf.Len()
+		fmt.Fprintf(&buf, "%d 0 obj\n%s\nendobj\n", i+1, obj)
+	}
+	xref := buf.Len()
+	fmt.Fprintf(&buf, "xref\n0 %d\n0000000000 65535 f \n", len(objects)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&buf, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&buf, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(objects)+1, xref)
+	return buf.Bytes()
+}
+
+func TestDetectSourceType(t *testing.T) {
+	tests := []struct {
+		fileName    string
+		contentType string
+		want        string
+	}{
+		{"handbook.PDF", "", SourcePDF},
+		{"faq.md", "application/octet-stream", SourceMarkdown},
+		{"prices.csv", "", SourceCSV},
+		{"notes.txt", "", SourceText},
+		{"upload", "application/pdf", SourcePDF},
+		{"upload", "text/plain; charset=utf-8", SourceText},
+	}
+	for _, tt := range tests {
+		got, err := DetectSourceType(tt.fileName, tt.contentType)
+		require.NoError(t, err, tt.fileName)
+		assert.Equal(t, tt.want, got, tt.fileName)
+	}
+
+	_, err := DetectSourceType("slides.pptx", "application/vnd.ms-powerpoint")
+	assert.ErrorIs(t, err, ErrUnsupportedType)
+}
+
+func TestExtractPDF(t *testing.T) {
+	sections, err := Extract(SourcePDF, buildPDF("Returns are accepted within 30 days.", "Shipping takes 3 days."))
+	require.NoError(t, err)
+	require.Len(t, sections, 2)
+	assert.Equal(t, "Page 1", sections[0].Label)
+	assert.Contains(t, sections[0].Text, "Returns are accepted within 30 days.")
+	assert.Equal(t, "Page 2", sections[1].Label)
+	assert.Contains(t, sections[1].Text, "Shipping takes 3 days.")
+
+	_, err = Extract(SourcePDF, []byte("not a pdf"))
+	assert.ErrorContains(t, err, "failed to read PDF")
+}
+
+func TestExtractMarkdown(t *testing.T) {
+	doc := "Intro text.\n\n# Shipping\nWe ship worldwide.\n\n## International\nCustoms may apply.\n\n```\n# not a heading\n```\n\n# Returns\n#hashtag is not a heading\n30 days.\n"
+	sections, err := Extract(SourceMarkdown, []byte(doc))
+	require.NoError(t, err)
+
+	labels := make([]string, len(sections))
+	for i, s := range sections {
+		labels[i] = s.Label
+	}
+	assert.Equal(t, []string{"", "Shipping", "Shipping > International", "Returns"}, labels)
+	assert.Contains(t, sections[2].Text, "# not a heading")
+	assert.Contains(t, sections[3].Text, "#hashtag is not a heading")
+}
+
+func TestExtractCSV(t *testing.T) {
+	var doc strings.Builder
+	doc.WriteString("product,price,notes\n")
+	for i := 1; i <= 25; i++ {
+		fmt.Fprintf(&doc, "Item %d,%d,\n", i, i*10)
+	}
+
+	sections, err := Extract(SourceCSV, []byte(doc.String()))
+	require.NoError(t, err)
+	require.Len(t, sections, 2)
+	assert.Equal(t, "Rows 2-21", sections[0].Label)
+	assert.Equal(t, "Rows 22-26", sections[1].Label)
+	assert.True(t, strings.HasPrefix(sections[0].Text, "product: Item 1; price: 10\n"))
+	assert.NotContains(t, sections[0].Text, "notes:", "empty cells are skipped")
+}
+
+func TestExtractRejectsEmptyAndBinary(t *testing.T) {
+	_, err := Extract(SourceText, []byte("  \n\n "))
+	assert.ErrorContains(t, err, "no text found")
+
+	_, err = Extract(SourceText, []byte{0xff, 0xfe, 0x00})
+	assert.ErrorContains(t, err, "not valid UTF-8")
+
+	_, err = Extract("docx", []byte("x"))
+	assert.ErrorIs(t, err, ErrUnsupportedType)
+}
+
+func TestChunkSections(t *testing.T) {
+	paragraph := strings.Repeat("word ", 60) // 300 chars
+	sections := []Section{
+		{Label: "Page 1", Text: paragraph + "\n\n" + paragraph + "\n\n" + paragraph},
+		{Label: "Page 2", Text: "Short page."},
+	}
+
+	chunks := ChunkSections(sections, 700, 100)
+	require.Len(t, chunks, 3)
+	for i, c := range chunks {
+		assert.Equal(t, i, c.Index)
+		assert.LessOrEqual(t, len(c.Content), 702)
+	}
+	assert.Equal(t, "Page 1", chunks[1].Heading)
+	assert.True(t, strings.HasPrefix(chunks[1].Content, "word word"), "second chunk starts with the overlap")
+	assert.Greater(t, len(chunks[1].Content), 300)
+	assert.Equal(t, Chunk{Index: 2, Heading: "Page 2", Content: "Short page."}, chunks[2])
+}
+
+func TestChunkSectionsSplitsLongParagraphs(t *testing.T) {
+	text := strings.Repeat("abcdefghij ", 300) // 3300 chars, no blank lines
+	chunks := ChunkSections([]Section{{Text: text}}, 1000, 0)
+	require.Len(t, chunks, 4)
+
+	var total int
+	for _, c := range chunks {
+		assert.LessOrEqual(t, len(c.Content), 1000)
+		assert.False(t, strings.HasPrefix(c.Content, " "))
+		total += len(strings.Fields(c.Content))
+	}
+	assert.Equal(t, 300, total, "no words are lost or duplicated without overlap")
+}
@@ -0,0 +1,245 @@
+// Package language detects the language of short chat messages and matches
+// language codes such as "es", "es-MX" and WhatsApp's "pt_BR".
+package language
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// Normalize lowercases a language code and uses "_" between language and
+// region, the form WhatsApp templates use
+func Normalize(code string) string {
+	code = strings.ToLower(strings.TrimSpace(code))
+	return strings.ReplaceAll(code, "-", "_")
+}
+
+// Base returns the language part of a code ("pt" for "pt_BR")
+func Base(code string) string {
+	code = Normalize(code)
+	if i := strings.IndexByte(code, '_'); i >= 0 {
+		return code[:i]
+	}
+	return code
+}
+
+// Match returns the code in available that best matches code: the same code,
+// then the same base language. It returns "" if there is no match.
+func Match(code string, available []string) string {
+	if code == "" {
+		return ""
+	}
+	norm := Normalize(code)
+	for _, a := range available {
+		if Normalize(a) == norm {
+			return a
+		}
+	}
+	base := Base(code)
+	for _, a := range available {
+		if Normalize(a) == base {
+			return a
+		}
+	}
+	for _, a := range available {
+		if Base(a) == base {
+			return a
+		}
+	}
+	return ""
+}
+
+// names are the native names of common languages, shown in language pickers
+var names = map[string]string{
+	"ar": "العربية",
+	"bn": "বাংলা",
+	"de": "Deutsch",
+	"el": "Ελληνικά",
+	"en": "English",
+	"es": "Español",
+	"fa": "فارسی",
+	"fr": "Français",
+	"gu": "ગુજરાતી",
+	"he": "עברית",
+	"hi": "हिन्दी",
+	"id": "Bahasa Indonesia",
+	"it": "Italiano",
+	"ja": "日本語",
+	"kn": "ಕನ್ನಡ",
+	"ko": "한국어",
+	"ml": "മലയാളം",
+	"mr": "मराठी",
+	"nl": "Nederlands",
+	"pa": "ਪੰਜਾਬੀ",
+	"pt": "Português",
+	"ru": "Русский",
+	"ta": "தமிழ்",
+	"te": "తెలుగు",
+	"th": "ไทย",
+	"tr": "Türkçe",
+	"uk": "Українська",
+	"ur": "اردو",
+	"zh": "中文",
+}
+
+// Name returns the native name of a language, or the code if it is unknown
+func Name(code string) string {
+	if name, ok := names[Base(code)]; ok {
+		return name
+	}
+	return code
+}
+
+// scripts maps writing systems used by a single common language to it
+var scripts = []struct {
+	table *unicode.RangeTable
+	lang  string
+}{
+	{unicode.Hangul, "ko"},
+	{unicode.Hiragana, "ja"},
+	{unicode.Katakana, "ja"},
+	{unicode.Thai, "th"},
+	{unicode.Greek, "el"},
+	{unicode.Hebrew, "he"},
+	{unicode.Bengali, "bn"},
+	{unicode.Tamil, "ta"},
+	{unicode.Telugu, "te"},
+	{unicode.Gujarati, "gu"},
+	{unicode.Kannada, "kn"},
+	{unicode.Malayalam, "ml"},
+	{unicode.Gurmukhi, "pa"},
+	{unicode.Han, "zh"},
+	{unicode.Devanagari, "hi"},
+	{unicode.Arabic, "ar"},
+	{unicode.Cyrillic, "ru"},
+}
+
+// scriptAlternatives are other languages written in the same script, picked
+// when they are among the candidates and the script's default is not
+var scriptAlternatives = map[string][]string{
+	"hi": {"mr"},
+	"ar": {"fa", "ur"},
+	"ru": {"uk"},
+}
+
+// stopwords are frequent words that identify languages written in Latin script
+var stopwords = map[string][]string{
+	"en": {"the", "and", "is", "are", "you", "your", "my", "i", "to", "of", "for", "with", "what", "how", "can", "please", "hello", "hi", "thanks", "thank", "want", "need", "have", "this", "that", "it", "not", "do", "does", "where", "when", "order", "yes"},
+	"es": {"el", "la", "los", "las", "y", "es", "son", "de", "que", "en", "un", "una", "por", "para", "con", "mi", "tu", "quiero", "necesito", "hola", "gracias", "cómo", "como", "qué", "dónde", "donde", "cuando", "pedido", "sí", "está", "estoy", "tengo", "buenos", "buenas", "días", "favor"},
+	"pt": {"o", "a", "os", "as", "e", "é", "são", "de", "do", "da", "que", "em", "um", "uma", "para", "com", "meu", "minha", "você", "quero", "preciso", "olá", "oi", "obrigado", "obrigada", "como", "onde", "quando", "pedido", "sim", "não", "estou", "tenho", "bom", "dia"},
+	"fr": {"le", "la", "les", "et", "est", "sont", "de", "des", "du", "que", "en", "un", "une", "pour", "avec", "mon", "ma", "vous", "je", "veux", "besoin", "bonjour", "salut", "merci", "comment", "où", "quand", "commande", "oui", "non", "suis", "ai", "pas", "ce", "ne"},
+	"de": {"der", "die", "das", "und", "ist", "sind", "zu", "von", "mit", "für", "ein", "eine", "mein", "meine", "sie", "ich", "du", "will", "möchte", "brauche", "hallo", "danke", "wie", "wo", "wann", "bestellung", "ja", "nein", "bin", "habe", "nicht", "guten", "tag"},
+	"it": {"il", "lo", "la", "gli", "le", "e", "è", "sono", "di", "che", "in", "un", "una", "per", "con", "mio", "mia", "voglio", "ho", "bisogno", "ciao", "grazie", "come", "dove", "quando", "ordine", "sì", "non", "buongiorno"},
+	"nl": {"de", "het", "een", "en", "is", "zijn", "van", "met", "voor", "mijn", "ik", "wil", "heb", "nodig", "hallo", "dank", "bedankt", "hoe", "waar", "wanneer", "bestelling", "ja", "nee", "niet", "goedemorgen"},
+	"id": {"yang", "dan", "di", "ke", "dari", "ini", "itu", "untuk", "dengan", "saya", "anda", "kamu", "mau", "ingin", "perlu", "halo", "terima", "kasih", "bagaimana", "dimana", "kapan", "pesanan", "ya", "tidak", "ada", "selamat", "pagi"},
+	"tr": {"ve", "bir", "bu", "için", "ile", "ben", "sen", "siz", "benim", "istiyorum", "lazım", "merhaba", "teşekkürler", "teşekkür", "nasıl", "nerede", "ne", "zaman", "sipariş", "evet", "hayır", "değil", "var", "yok", "günaydın"},
+}
+
+// letterHints are letters only found in some Latin script languages
+var letterHints = map[rune][]string{
+	'ñ': {"es"}, '¿': {"es"}, '¡': {"es"},
+	'ã': {"pt"}, 'õ': {"pt"}, 'ç': {"pt", "fr", "tr"},
+	'ß': {"de"}, 'ä': {"de"}, 'ö': {"de", "tr"}, 'ü': {"de", "tr"},
+	'ğ': {"tr"}, 'ş': {"tr"}, 'ı': {"tr"},
+	'è': {"fr", "it"}, 'ê': {"fr", "pt"}, 'à': {"fr", "it", "pt"}, 'œ': {"fr"},
+}
+
+// Detect returns the language of text, choosing among candidates when any
+// are given. It returns "" when the text is too short or ambiguous.
+func Detect(text string, candidates []string) string {
+	allowed := func(lang string) string {
+		if len(candidates) == 0 {
+			return lang
+		}
+		return Match(lang, candidates)
+	}
+
+	// Non-Latin scripts identify the language on their own
+	counts := map[string]int{}
+	letters := 0
+	for _, r := range text {
+		if !unicode.IsLetter(r) {
+			continue
+		}
+		letters++
+		for _, s := range scripts {
+			if unicode.Is(s.table, r) {
+				counts[s.lang]++
+				break
+			}
+		}
+	}
+	if letters == 0 {
+		return ""
+	}
+	if counts["ja"] > 0 {
+		counts["ja"] += counts["zh"] // Japanese mixes kana with kanji
+		delete(counts, "zh")
+	}
+	best, bestCount := "", 0
+	for lang, n := range counts {
+		if n > bestCount || n == bestCount && lang < best {
+			best, bestCount = lang, n
+		}
+	}
+	if bestCount*2 >= letters {
+		if match := allowed(best); match != "" {
+			return match
+		}
+		for _, alt := range scriptAlternatives[best] {
+			if match := allowed(alt); match != "" {
+				return match
+			}
+		}
+		return ""
+	}
+
+	return detectLatin(strings.ToLower(text), allowed)
+}
+
+// detectLatin scores languages written in Latin script by their stopwords and
+// distinctive letters
+func detectLatin(text string, allowed func(string) string) string {
+	words := strings.FieldsFunc(text, func(r rune) bool { return !unicode.IsLetter(r) && r != '\'' })
+	scores := map[string]int{}
+	for _, w := range words {
+		for lang, list := range stopwords {
+			for _, s := range list {
+				if w == s {
+					scores[lang] += 2
+					break
+				}
+			}
+		}
+	}
+	for _, r := range text {
+		for _, lang := range letterHints[r] {
+			scores[lang]++
+		}
+	}
+
+	type scored struct {
+		lang  string
+		score int
+	}
+	var ranked []scored
+	for lang, score := range scores {
+		if match := allowed(lang); match != "" {
+			ranked = append(ranked, scored{match, score})
+		}
+	}
+	if len(ranked) == 0 {
+		return ""
+	}
+	sort.Slice(ranked, func(i, j int) bool {
+		if ranked[i].score != ranked[j].score {
+			return ranked[i].score > ranked[j].score
+		}
+		return ranked[i].lang < ranked[j].lang
+	})
+	if len(ranked) > 1 && ranked[0].score == ranked[1].score && ranked[0].lang != ranked[1].lang {
+		return ""
+	}
+	return ranked[0].lang
+}
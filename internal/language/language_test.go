@@ -0,0 +1,60 @@
+package language
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNormalizeAndBase(t *testing.T) {
+	assert.Equal(t, "pt_br", Normalize(" pt-BR "))
+	assert.Equal(t, "pt", Base("pt_BR"))
+	assert.Equal(t, "en", Base("EN"))
+}
+
+func TestMatch(t *testing.T) {
+	available := []string{"en_US", "es", "pt_BR"}
+	assert.Equal(t, "en_US", Match("en-us", available))
+	assert.Equal(t, "es", Match("es_MX", available), "falls back to the base language")
+	assert.Equal(t, "pt_BR", Match("pt", available), "matches a regional variant of the base language")
+	assert.Equal(t, "", Match("fr", available))
+	assert.Equal(t, "", Match("", available))
+}
+
+func TestName(t *testing.T) {
+	assert.Equal(t, "Español", Name("es_MX"))
+	assert.Equal(t, "xx", Name("xx"))
+}
+
+func TestDetect(t *testing.T) {
+	tests := []struct {
+		text string
+		want string
+	}{
+		{"Hello, where is my order?", "en"},
+		{"Hola, ¿dónde está mi pedido?", "es"},
+		{"Olá, onde está o meu pedido?", "pt"},
+		{"Bonjour, je veux annuler ma commande", "fr"},
+		{"Hallo, wo ist meine Bestellung?", "de"},
+		{"Ciao, dove è il mio ordine?", "it"},
+		{"Merhaba, siparişim nerede?", "tr"},
+		{"Halo, di mana pesanan saya?", "id"},
+		{"Привет, где мой заказ?", "ru"},
+		{"مرحبا، أين طلبي؟", "ar"},
+		{"नमस्ते, मेरा ऑर्डर कहाँ है?", "hi"},
+		{"我的订单在哪里", "zh"},
+		{"注文はどこですか", "ja"},
+		{"주문이 어디에 있나요", "ko"},
+		{"12345", ""},
+		{"ok", ""},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, Detect(tt.text, nil), tt.text)
+	}
+}
+
+func TestDetect_Candidates(t *testing.T) {
+	assert.Equal(t, "es_MX", Detect("Hola, quiero hacer un pedido", []string{"en", "es_MX"}))
+	assert.Equal(t, "", Detect("Bonjour, je veux annuler ma commande", []string{"en", "es"}), "languages outside the candidates are not returned")
+	assert.Equal(t, "mr", Detect("नमस्कार, माझी ऑर्डर कुठे आहे?", []string{"en", "mr"}), "Devanagari picks Marathi when Hindi is not a candidate")
+}
@@ -0,0 +1,72 @@
+package models
+
+import (
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// Skill proficiency bounds, from basic to expert
+const (
+	MinSkillProficiency = 1
+	MaxSkillProficiency = 5
+)
+
+// AgentSkill is a skill (a language, product line, ...) an agent has in an
+// organization, used by skills-based routing
+type AgentSkill struct {
+	BaseModel
+	OrganizationID uuid.UUID `gorm:"type:uuid;index;not null" json:"organization_id"`
+	UserID         uuid.UUID `gorm:"type:uuid;index;not null" json:"user_id"`
+	Skill          string    `gorm:"size:100;not null" json:"skill"`        // Normalized with NormalizeSkill
+	Proficiency    int       `gorm:"default:1;not null" json:"proficiency"` // 1 (basic) to 5 (expert)
+
+	// Relations
+	User *User `gorm:"foreignKey:UserID" json:"user,omitempty"`
+}
+
+func (AgentSkill) TableName() string {
+	return "agent_skills"
+}
+
+// NormalizeSkill trims and lower-cases a skill name so "Spanish " and
+// "spanish" match
+func NormalizeSkill(skill string) string {
+	return strings.ToLower(strings.TrimSpace(skill))
+}
+
+// NormalizeSkills normalizes a list of skill names, dropping empty and
+// duplicate entries
+func NormalizeSkills(skills []string) StringArray {
+	seen := make(map[string]bool, len(skills))
+	out := StringArray{}
+	for _, s := range skills {
+		s = NormalizeSkill(s)
+		if s == "" || seen[s] {
+			continue
+		}
+		seen[s] = true
+		out = append(out, s)
+	}
+	return out
+}
+
+// SkillsFromConfig reads the required_skills of a transfer configuration
+// (flow step, keyword rule or IVR node), given as a list or a comma
+// separated string
+func SkillsFromConfig(config map[string]interface{}) StringArray {
+	var skills []string
+	switch v := config["required_skills"].(type) {
+	case []interface{}:
+		for _, s := range v {
+			if str, ok := s.(string); ok {
+				skills = append(skills, str)
+			}
+		}
+	case []string:
+		skills = v
+	case string:
+		skills = strings.Split(v, ",")
+	}
+	return NormalizeSkills(skills)
+}
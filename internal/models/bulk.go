@@ -24,7 +24,10 @@ type BulkMessageCampaign struct {
 	ReadCount       int        `gorm:"default:0" json:"read_count"`
 	FailedCount     int        `gorm:"default:0" json:"failed_count"`
 	ScheduledAt     *time.Time `json:"scheduled_at,omitempty"`
+	ScheduleTimezone string    `gorm:"size:64" json:"schedule_timezone,omitempty"` // IANA timezone the schedule was entered in
 	StartedAt       *time.Time `json:"started_at,omitempty"`
+	StartedBy       *uuid.UUID `gorm:"type:uuid" json:"started_by,omitempty"`          // nil when started by the scheduler
+	StartTrigger    CampaignTrigger `gorm:"size:20" json:"start_trigger,omitempty"` // manual, scheduler
 	CompletedAt     *time.Time `json:"completed_at,omitempty"`
 	CreatedBy       uuid.UUID  `gorm:"type:uuid;not null" json:"created_by"`
 
@@ -66,16 +69,20 @@ func (BulkMessageRecipient) TableName() string {
 // NotificationRule defines automated notification rules
 type NotificationRule struct {
 	BaseModel
-	OrganizationID   uuid.UUID `gorm:"type:uuid;index;not null" json:"organization_id"`
-	WhatsAppAccount  string    `gorm:"size:100;index;not null" json:"whatsapp_account"` // References WhatsAppAccount.Name
-	Name             string    `gorm:"size:255;not null" json:"name"`
-	IsEnabled        bool      `gorm:"default:true" json:"is_enabled"`
-	TriggerType      string    `gorm:"size:50;not null" json:"trigger_type"` // webhook, scheduler, api
-	TriggerConfig    JSONB     `gorm:"type:jsonb;not null" json:"trigger_config"`
-	TemplateID       uuid.UUID `gorm:"type:uuid;not null" json:"template_id"`
-	FieldMappings    JSONB     `gorm:"type:jsonb;default:'{}'" json:"field_mappings"`
-	Conditions       JSONB     `gorm:"type:jsonb;default:'{}'" json:"conditions"`
-	AttachmentConfig JSONB     `gorm:"type:jsonb" json:"attachment_config"`
+	OrganizationID   uuid.UUID  `gorm:"type:uuid;index;not null" json:"organization_id"`
+	WhatsAppAccount  string     `gorm:"size:100;index;not null" json:"whatsapp_account"` // References WhatsAppAccount.Name
+	Name             string     `gorm:"size:255;not null" json:"name"`
+	IsEnabled        bool       `gorm:"default:true" json:"is_enabled"`
+	TriggerType      string     `gorm:"size:50;not null" json:"trigger_type"` // webhook, scheduler, api
+	TriggerConfig    JSONB      `gorm:"type:jsonb;not null" json:"trigger_config"`
+	TemplateID       uuid.UUID  `gorm:"type:uuid;not null" json:"template_id"`
+	FieldMappings    JSONB      `gorm:"type:jsonb;default:'{}'" json:"field_mappings"`
+	Conditions       JSONB      `gorm:"type:jsonb;default:'{}'" json:"conditions"`
+	AttachmentConfig JSONB      `gorm:"type:jsonb" json:"attachment_config"`
+	TriggerKeyPrefix string     `gorm:"size:16" json:"trigger_key_prefix,omitempty"` // First chars of the trigger key, for display
+	TriggerKeyHash   string     `gorm:"size:255" json:"-"`                           // bcrypt hash of the trigger key
+	NextRunAt        *time.Time `json:"next_run_at,omitempty"`                       // Scheduler rules only
+	LastTriggeredAt  *time.Time `json:"last_triggered_at,omitempty"`
 
 	// Relations
 	Organization *Organization `gorm:"foreignKey:OrganizationID" json:"organization,omitempty"`
@@ -85,3 +92,25 @@ type NotificationRule struct {
 func (NotificationRule) TableName() string {
 	return "notification_rules"
 }
+
+// NotificationRuleExecution records a single evaluation of a notification rule
+type NotificationRuleExecution struct {
+	BaseModel
+	OrganizationID uuid.UUID                   `gorm:"type:uuid;index;not null" json:"organization_id"`
+	RuleID         uuid.UUID                   `gorm:"type:uuid;not null" json:"rule_id"`
+	Source         string                      `gorm:"size:20;not null" json:"source"` // webhook, scheduler, api
+	Status         NotificationExecutionStatus `gorm:"size:20;not null" json:"status"` // sent, skipped, failed
+	Payload        JSONB                       `gorm:"type:jsonb" json:"payload"`
+	PhoneNumber    string                      `gorm:"size:50" json:"phone_number"`
+	TemplateParams JSONB                       `gorm:"type:jsonb" json:"template_params"`
+	MessageID      *uuid.UUID                  `gorm:"type:uuid" json:"message_id,omitempty"`
+	Error          string                      `gorm:"type:text" json:"error,omitempty"`
+	DurationMs     int64                       `json:"duration_ms"`
+
+	// Relations
+	Rule *NotificationRule `gorm:"foreignKey:RuleID" json:"rule,omitempty"`
+}
+
+func (NotificationRuleExecution) TableName() string {
+	return "notification_rule_executions"
+}
@@ -0,0 +1,37 @@
+package models
+
+import (
+	"github.com/google/uuid"
+	"github.com/shridarpatil/whatomate/internal/schedule"
+)
+
+// BusinessHoursSchedule is a named, reusable set of opening hours that chatbot
+// settings, teams and IVR timing nodes can reference
+type BusinessHoursSchedule struct {
+	BaseModel
+	OrganizationID uuid.UUID  `gorm:"type:uuid;index;not null" json:"organization_id"`
+	Name           string     `gorm:"size:100;not null" json:"name"`
+	Description    string     `gorm:"size:500" json:"description"`
+	Timezone       string     `gorm:"size:64" json:"timezone"`                 // IANA timezone, empty = server timezone
+	Hours          JSONBArray `gorm:"type:jsonb;default:'[]'" json:"hours"`    // [{day, enabled, intervals: [{start_time, end_time}]}]
+	Holidays       JSONBArray `gorm:"type:jsonb;default:'[]'" json:"holidays"` // [{name, date, end_date, recurring, intervals}]
+
+	// Relations
+	Organization *Organization `gorm:"foreignKey:OrganizationID" json:"organization,omitempty"`
+}
+
+func (BusinessHoursSchedule) TableName() string {
+	return "business_hours_schedules"
+}
+
+// Schedule decodes the stored hours and holidays
+func (s *BusinessHoursSchedule) Schedule() (*schedule.Schedule, error) {
+	sched := &schedule.Schedule{Timezone: s.Timezone}
+	if err := schedule.Decode(s.Hours, &sched.Days); err != nil {
+		return nil, err
+	}
+	if err := schedule.Decode(s.Holidays, &sched.Holidays); err != nil {
+		return nil, err
+	}
+	return sched, nil
+}
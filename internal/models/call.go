@@ -113,6 +113,7 @@ type CallTransfer struct {
 	WhatsAppAccount string             `gorm:"size:100;not null" json:"whatsapp_account"`
 	Status          CallTransferStatus `gorm:"size:20;not null;default:'waiting'" json:"status"`
 	TeamID          *uuid.UUID         `gorm:"type:uuid;index" json:"team_id,omitempty"`
+	RequiredSkills  StringArray        `gorm:"type:jsonb;default:'[]'" json:"required_skills"`
 	AgentID           *uuid.UUID         `gorm:"type:uuid" json:"agent_id,omitempty"`
 	InitiatingAgentID *uuid.UUID         `gorm:"type:uuid" json:"initiating_agent_id,omitempty"`
 	TransferredAt   time.Time          `gorm:"autoCreateTime" json:"transferred_at"`
@@ -14,6 +14,7 @@ type CannedResponse struct {
 	Category       string    `gorm:"size:50" json:"category"`
 	IsActive       bool      `gorm:"default:true" json:"is_active"`
 	UsageCount     int       `gorm:"default:0" json:"usage_count"`
+	Translations   JSONB     `gorm:"type:jsonb;default:'{}'" json:"translations"` // {"es": {"content": "..."}}
 	CreatedByID    uuid.UUID `gorm:"type:uuid" json:"created_by_id"`
 
 	// Relations
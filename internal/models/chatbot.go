@@ -10,6 +10,8 @@ import (
 type BusinessHoursConfig struct {
 	Enabled              bool       `gorm:"column:business_hours_enabled;default:false" json:"business_hours_enabled"`
 	Hours                JSONBArray `gorm:"column:business_hours;type:jsonb;default:'[]'" json:"business_hours"` // [{day, enabled, start_time, end_time}]
+	Timezone             string     `gorm:"column:business_hours_timezone;size:64" json:"business_hours_timezone"`     // IANA timezone of Hours, empty = server timezone
+	ScheduleID           *uuid.UUID `gorm:"column:business_hours_schedule_id;type:uuid" json:"business_hours_schedule_id"` // Named schedule used instead of Hours
 	OutOfHoursMessage    string     `gorm:"column:out_of_hours_message;type:text" json:"out_of_hours_message"`
 	AllowAutomatedOutside bool      `gorm:"column:allow_automated_outside_hours;default:true" json:"allow_automated_outside_hours"` // Allow flows/keywords/AI outside business hours
 }
@@ -19,6 +21,7 @@ type AgentAssignmentConfig struct {
 	AllowQueuePickup        bool `gorm:"column:allow_agent_queue_pickup;default:true" json:"allow_agent_queue_pickup"`           // Allow agents to pick transfers from queue
 	AssignToSameAgent       bool `gorm:"column:assign_to_same_agent;default:true" json:"assign_to_same_agent"`                   // Auto-assign transfers to contact's existing agent
 	CurrentConversationOnly bool `gorm:"column:agent_current_conversation_only;default:false" json:"agent_current_conversation_only"` // Agents see only current session messages
+	PriorityRules           JSONBArray `gorm:"column:transfer_priority_rules;type:jsonb;default:'[]'" json:"transfer_priority_rules"` // []TransferPriorityRule
 }
 
 // SLAConfig holds SLA tracking settings
@@ -42,17 +45,54 @@ type ClientInactivityConfig struct {
 	AutoCloseMessage string `gorm:"column:client_auto_close_message;type:text" json:"client_auto_close_message"`   // Message when closing due to client inactivity
 }
 
+// LanguageConfig holds multilingual chatbot settings
+type LanguageConfig struct {
+	DefaultLanguage string      `gorm:"column:default_language;size:10" json:"default_language"`                       // Language of the untranslated texts
+	Languages       StringArray `gorm:"column:languages;type:jsonb;default:'[]'" json:"languages"`                       // Languages the chatbot talks in; empty disables multilingual replies
+	DetectLanguage  bool        `gorm:"column:detect_language;default:false" json:"detect_language"`                    // Detect the language of inbound messages
+	SwitchKeywords  StringArray `gorm:"column:language_switch_keywords;type:jsonb;default:'[]'" json:"language_switch_keywords"` // Keywords that show a language picker
+	SwitchPrompt    string      `gorm:"column:language_switch_prompt;type:text" json:"language_switch_prompt"`        // Body text of the language picker
+}
+
 // AIConfig holds AI provider settings
 type AIConfig struct {
 	Enabled        bool    `gorm:"column:ai_enabled;default:false" json:"ai_enabled"`
-	Provider       AIProvider `gorm:"column:ai_provider;size:20" json:"ai_provider"`                     // openai, anthropic, google
+	Provider       AIProvider `gorm:"column:ai_provider;size:20" json:"ai_provider"`                     // openai, anthropic, google, openai_compatible
 	APIKey         string  `gorm:"column:ai_api_key;type:text" json:"-"`                                 // encrypted
+	BaseURL        string  `gorm:"column:ai_base_url;size:500" json:"ai_base_url"`                       // Overrides the provider's API URL; required for openai_compatible
+	Headers        JSONB   `gorm:"column:ai_headers;type:jsonb;default:'{}'" json:"-"`                 // Extra HTTP headers sent to the provider (may hold credentials)
+	Fallbacks      JSONBArray `gorm:"column:ai_fallbacks;type:jsonb;default:'[]'" json:"-"`            // []AIFallback tried in order when the provider fails
 	Model          string  `gorm:"column:ai_model;size:100" json:"ai_model"`
 	MaxTokens      int     `gorm:"column:ai_max_tokens;default:500" json:"ai_max_tokens"`
 	Temperature    float64 `gorm:"column:ai_temperature;type:decimal(3,2);default:0.7" json:"ai_temperature"`
 	SystemPrompt   string  `gorm:"column:ai_system_prompt;type:text" json:"ai_system_prompt"`
 	IncludeHistory bool    `gorm:"column:ai_include_history;default:true" json:"ai_include_history"`
 	HistoryLimit   int     `gorm:"column:ai_history_limit;default:4" json:"ai_history_limit"`
+	EmbeddingModel    string  `gorm:"column:ai_embedding_model;size:100" json:"ai_embedding_model"`                        // Defaults to the provider's embedding model
+	KnowledgeTopK     int     `gorm:"column:ai_knowledge_top_k;default:4" json:"ai_knowledge_top_k"`                     // Knowledge chunks added to the prompt; 0 disables retrieval
+	KnowledgeMinScore float64 `gorm:"column:ai_knowledge_min_score;type:decimal(3,2);default:0.3" json:"ai_knowledge_min_score"` // Minimum cosine similarity for a chunk to be used
+	TransferSummary   bool    `gorm:"column:ai_transfer_summary;default:false" json:"ai_transfer_summary"`                // Summarize the conversation into the notes of new transfers
+	Tools             StringArray `gorm:"column:ai_tools;type:jsonb;default:'[]'" json:"ai_tools"`                             // Allow-listed AITool names the AI may call
+}
+
+// AIFallback is a provider tried when the primary AI provider fails
+type AIFallback struct {
+	Provider AIProvider        `json:"provider"`
+	Model    string            `json:"model"`
+	BaseURL  string            `json:"base_url,omitempty"`
+	APIKey   string            `json:"api_key,omitempty"`
+	Headers  map[string]string `json:"headers,omitempty"`
+}
+
+// TransferPriorityRule gives agent transfers that meet all of its conditions
+// a priority. Conditions left empty match every transfer.
+type TransferPriorityRule struct {
+	Tag                string           `json:"tag,omitempty"`                  // Contact has this tag
+	MetadataKey        string           `json:"metadata_key,omitempty"`         // Contact metadata field, e.g. "tier"
+	MetadataValue      string           `json:"metadata_value,omitempty"`       // Value of MetadataKey; empty matches any non-empty value
+	Source             TransferSource   `json:"source,omitempty"`               // How the transfer was created
+	MinEscalationLevel int              `json:"min_escalation_level,omitempty"` // SLA escalation level reached
+	Priority           TransferPriority `json:"priority"`
 }
 
 // PanelFieldConfig defines a field to display in the contact info panel
@@ -100,6 +140,12 @@ type ChatbotSettings struct {
 	SLA              SLAConfig              `gorm:"embedded"`
 	ClientInactivity ClientInactivityConfig `gorm:"embedded"`
 	AI               AIConfig               `gorm:"embedded"`
+	Language         LanguageConfig         `gorm:"embedded"`
+
+	// Per-language variants of the response texts:
+	// {"es": {"greeting_message": "...", "fallback_message": "...", "out_of_hours_message": "...",
+	//         "language_switch_prompt": "...", "greeting_buttons": {"<button id>": "<title>"}, "fallback_buttons": {...}}}
+	Translations JSONB `gorm:"type:jsonb;default:'{}'" json:"translations"`
 
 	// Session settings
 	SessionTimeoutMins int        `gorm:"default:30" json:"session_timeout_minutes"`
@@ -157,6 +203,9 @@ type ChatbotFlow struct {
 	TimeoutMessage     string      `gorm:"type:text" json:"timeout_message"`
 	CancelKeywords     StringArray `gorm:"type:jsonb" json:"cancel_keywords"`
 	PanelConfig        JSONB       `gorm:"type:jsonb;default:'{}'" json:"panel_config"` // Contact info panel configuration
+	PublishedVersion   int         `gorm:"default:0" json:"published_version"`           // Version new sessions run; 0 runs the draft until the flow is first edited
+	TestCases          JSONBArray  `gorm:"type:jsonb;default:'[]'" json:"test_cases"`   // Scripted conversations run by the flow simulator on save
+	Translations       JSONB       `gorm:"type:jsonb;default:'{}'" json:"translations"` // {"es": {"initial_message": "...", "completion_message": "..."}}
 
 	// Relations
 	Organization    *Organization     `gorm:"foreignKey:OrganizationID" json:"organization,omitempty"`
@@ -177,9 +226,11 @@ type ChatbotFlowStep struct {
 	Message         string       `gorm:"type:text;not null" json:"message"`
 	MessageType     FlowStepType `gorm:"size:20;default:'text'" json:"message_type"` // text, template, script, api_fetch, buttons, transfer
 	TemplateID      *uuid.UUID `gorm:"type:uuid" json:"template_id,omitempty"`
+	TemplateConfig  JSONB      `gorm:"type:jsonb" json:"template_config"` // {params, header_params, header_media_url, header_media_key, button_params, fallback_message} - for template message type
 	ApiConfig       JSONB      `gorm:"type:jsonb" json:"api_config"`      // {url, method, headers, body, response_path, fallback_message}
 	Buttons         JSONBArray `gorm:"type:jsonb" json:"buttons"`         // [{id, title}] - max 10 options (3=buttons, 4-10=list)
 	TransferConfig  JSONB      `gorm:"type:jsonb" json:"transfer_config"` // {team_id: uuid, notes: string} - for transfer message type
+	ScriptConfig    JSONB      `gorm:"type:jsonb" json:"script_config"`   // {code, timeout_ms, memory_limit_mb, allowed_hosts, fallback_message} - for script message type
 	InputType       InputType  `gorm:"size:20" json:"input_type"`         // none, text, number, email, phone, date, select, button, whatsapp_flow
 	InputConfig     JSONB      `gorm:"type:jsonb" json:"input_config"`
 	ValidationRegex string     `gorm:"size:255" json:"validation_regex"`
@@ -190,6 +241,7 @@ type ChatbotFlowStep struct {
 	SkipCondition   string     `gorm:"type:text" json:"skip_condition"`
 	RetryOnInvalid  bool       `gorm:"default:true" json:"retry_on_invalid"`
 	MaxRetries      int        `gorm:"default:3" json:"max_retries"`
+	Translations    JSONB      `gorm:"type:jsonb;default:'{}'" json:"translations"` // {"es": {"message": "...", "validation_error": "...", "buttons": {"<button id>": "<title>"}}}
 
 	// Relations
 	Flow     *ChatbotFlow `gorm:"foreignKey:FlowID" json:"flow,omitempty"`
@@ -200,6 +252,27 @@ func (ChatbotFlowStep) TableName() string {
 	return "chatbot_flow_steps"
 }
 
+// ChatbotFlowVersion is an immutable published snapshot of a chatbot flow and
+// its steps. The ChatbotFlow and ChatbotFlowStep rows are the editable draft.
+type ChatbotFlowVersion struct {
+	BaseModel
+	OrganizationID uuid.UUID  `gorm:"type:uuid;index;not null" json:"organization_id"`
+	FlowID         uuid.UUID  `gorm:"type:uuid;uniqueIndex:idx_chatbot_flow_versions_flow_version;not null" json:"flow_id"`
+	Version        int        `gorm:"uniqueIndex:idx_chatbot_flow_versions_flow_version;not null" json:"version"`
+	Snapshot       JSONB      `gorm:"type:jsonb;not null" json:"snapshot"` // Flow definition including steps
+	Notes          string     `gorm:"type:text" json:"notes"`
+	RolledBackFrom *int       `json:"rolled_back_from,omitempty"` // Version this one restored, for rollbacks
+	PublishedByID  *uuid.UUID `gorm:"type:uuid" json:"published_by_id,omitempty"`
+
+	// Relations
+	Flow        *ChatbotFlow `gorm:"foreignKey:FlowID" json:"flow,omitempty"`
+	PublishedBy *User        `gorm:"foreignKey:PublishedByID" json:"published_by,omitempty"`
+}
+
+func (ChatbotFlowVersion) TableName() string {
+	return "chatbot_flow_versions"
+}
+
 // ChatbotSession tracks active conversation sessions
 type ChatbotSession struct {
 	BaseModel
@@ -210,7 +283,11 @@ type ChatbotSession struct {
 	Status          SessionStatus `gorm:"size:20;default:'active'" json:"status"` // active, completed, cancelled, timeout
 	CurrentFlowID   *uuid.UUID `gorm:"type:uuid" json:"current_flow_id,omitempty"`
 	CurrentStep     string     `gorm:"size:100" json:"current_step"`
+	FlowVersion     int        `gorm:"default:0" json:"flow_version"` // Published flow version the session is pinned to; 0 follows the draft
+	Language        string     `gorm:"size:10" json:"language"`       // Language the chatbot talks to the contact in
 	StepRetries     int        `gorm:"default:0" json:"step_retries"`
+	AIPromptTokens     int        `gorm:"default:0" json:"ai_prompt_tokens"`     // Tokens sent to AI providers during the session
+	AICompletionTokens int        `gorm:"default:0" json:"ai_completion_tokens"` // Tokens generated by AI providers during the session
 	SessionData     JSONB      `gorm:"type:jsonb;default:'{}'" json:"session_data"`
 	StartedAt       time.Time  `gorm:"autoCreateTime" json:"started_at"`
 	LastActivityAt  time.Time  `json:"last_activity_at"`
@@ -235,6 +312,9 @@ type ChatbotSessionMessage struct {
 	Message   string    `gorm:"type:text" json:"message"`
 	StepName  string    `gorm:"size:100" json:"step_name"`
 
+	KeywordRuleID *uuid.UUID `gorm:"type:uuid;index" json:"keyword_rule_id,omitempty"` // Rule that produced a keyword response
+	ToolCall      JSONB      `gorm:"type:jsonb" json:"tool_call,omitempty"`            // Name, arguments and result of an AI tool invocation
+
 	// Relations
 	Session *ChatbotSession `gorm:"foreignKey:SessionID" json:"session,omitempty"`
 }
@@ -272,10 +352,18 @@ type SLATracking struct {
 	ExpiresAt          *time.Time `gorm:"column:expires_at;index" json:"expires_at,omitempty"`                          // Auto-close deadline
 	PickedUpAt         *time.Time `gorm:"column:picked_up_at" json:"picked_up_at,omitempty"`                            // When agent first picked up
 	FirstResponseAt    *time.Time `gorm:"column:first_response_at" json:"first_response_at,omitempty"`                  // When agent first responded
-	EscalationLevel    int        `gorm:"column:escalation_level;default:0" json:"escalation_level"`                    // 0=normal, 1=warning, 2=escalated, 3=critical
+	EscalationLevel    int        `gorm:"column:escalation_level;default:0" json:"escalation_level"`                    // 0=normal, 1=warning, 2=escalated, 3=critical; with a policy, the escalation tier reached
 	EscalatedAt        *time.Time `gorm:"column:escalated_at" json:"escalated_at,omitempty"`                            // When escalation occurred
 	Breached           bool       `gorm:"column:sla_breached;default:false" json:"sla_breached"`                        // Whether SLA was breached
 	BreachedAt         *time.Time `gorm:"column:sla_breached_at" json:"sla_breached_at,omitempty"`                      // When SLA was breached
+
+	// SLA policy clock. Policy transfers count the SLA time they used instead
+	// of comparing fixed deadlines, so the clock can pause.
+	PolicyID           *uuid.UUID `gorm:"column:sla_policy_id;type:uuid;index" json:"sla_policy_id,omitempty"`         // Policy the transfer follows, nil = chatbot SLA settings
+	ElapsedSeconds     int64      `gorm:"column:sla_elapsed_seconds;default:0" json:"sla_elapsed_seconds"`             // SLA time used so far
+	ClockAt            *time.Time `gorm:"column:sla_clock_at" json:"sla_clock_at,omitempty"`                           // When the SLA time was last counted
+	Paused             bool       `gorm:"column:sla_paused;default:false" json:"sla_paused"`                           // Clock stopped outside business hours or while waiting on the customer
+	ResolutionBreached bool       `gorm:"column:sla_resolution_breached;default:false" json:"sla_resolution_breached"` // Whether the resolution target was missed
 }
 
 // AgentTransfer tracks when conversations are transferred to human agents
@@ -286,12 +374,15 @@ type AgentTransfer struct {
 	WhatsAppAccount     string     `gorm:"size:100;index;not null" json:"whatsapp_account"` // References WhatsAppAccount.Name
 	PhoneNumber         string     `gorm:"size:50;not null" json:"phone_number"`
 	Status              TransferStatus `gorm:"size:20;default:'active'" json:"status"` // active, resumed
-	Source              TransferSource `gorm:"size:20;default:'manual'" json:"source"` // manual, flow, keyword, chatbot_disabled
+	Source              TransferSource `gorm:"size:20;default:'manual'" json:"source"` // manual, flow, keyword, chatbot_disabled, ai
+	Priority            TransferPriority `gorm:"default:2;not null" json:"priority"` // 1 (low) to 4 (VIP), from the priority rules
 	AgentID             *uuid.UUID `gorm:"type:uuid" json:"agent_id,omitempty"`
 	TeamID              *uuid.UUID `gorm:"type:uuid;index" json:"team_id,omitempty"` // Team queue (null = general queue)
 	TransferredByUserID *uuid.UUID `gorm:"type:uuid" json:"transferred_by_user_id,omitempty"` // User who initiated the transfer (null for system)
 	Notes               string     `gorm:"type:text" json:"notes"`
+	RequiredSkills      StringArray `gorm:"type:jsonb;default:'[]'" json:"required_skills"` // Skills the assigned agent needs (skills-based routing)
 	TransferredAt       time.Time  `gorm:"autoCreateTime" json:"transferred_at"`
+	TeamQueuedAt        *time.Time `json:"team_queued_at,omitempty"` // When the transfer entered its current team queue, nil = transferred_at
 	ResumedAt           *time.Time `json:"resumed_at,omitempty"`
 	ResumedBy           *uuid.UUID `gorm:"type:uuid" json:"resumed_by,omitempty"`
 
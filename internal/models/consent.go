@@ -0,0 +1,46 @@
+package models
+
+import (
+	"github.com/google/uuid"
+)
+
+// ConsentStatus represents a contact's marketing consent state
+type ConsentStatus string
+
+const (
+	ConsentStatusUnknown  ConsentStatus = "unknown"
+	ConsentStatusOptedIn  ConsentStatus = "opted_in"
+	ConsentStatusOptedOut ConsentStatus = "opted_out"
+)
+
+// ConsentSource records how a contact's consent was changed
+type ConsentSource string
+
+const (
+	ConsentSourceKeyword ConsentSource = "keyword" // Contact sent an opt-out/opt-in keyword
+	ConsentSourceManual  ConsentSource = "manual"  // Changed by a user via the UI or API
+)
+
+// ConsentEvent is an append-only audit record of a contact's consent changes.
+type ConsentEvent struct {
+	BaseModel
+	OrganizationID  uuid.UUID     `gorm:"type:uuid;index;not null" json:"organization_id"`
+	ContactID       uuid.UUID     `gorm:"type:uuid;index;not null" json:"contact_id"`
+	PhoneNumber     string        `gorm:"size:50;not null" json:"phone_number"`
+	Status          ConsentStatus `gorm:"size:20;not null" json:"status"`
+	PreviousStatus  ConsentStatus `gorm:"size:20" json:"previous_status"`
+	Source          ConsentSource `gorm:"size:20;not null" json:"source"`
+	Keyword         string        `gorm:"size:100" json:"keyword,omitempty"`     // Keyword that triggered the change
+	Reason          string        `gorm:"type:text" json:"reason,omitempty"`     // Free-text note for manual changes
+	MessageID       *uuid.UUID    `gorm:"type:uuid" json:"message_id,omitempty"` // Inbound message that triggered the change
+	UserID          *uuid.UUID    `gorm:"type:uuid" json:"user_id,omitempty"`    // User who made a manual change
+	WhatsAppAccount string        `gorm:"size:100" json:"whatsapp_account,omitempty"`
+
+	// Relations
+	Contact *Contact `gorm:"foreignKey:ContactID" json:"contact,omitempty"`
+	User    *User    `gorm:"foreignKey:UserID" json:"user,omitempty"`
+}
+
+func (ConsentEvent) TableName() string {
+	return "consent_events"
+}
@@ -49,11 +49,35 @@ const (
 type AIProvider string
 
 const (
-	AIProviderOpenAI    AIProvider = "openai"
-	AIProviderAnthropic AIProvider = "anthropic"
-	AIProviderGoogle    AIProvider = "google"
+	AIProviderOpenAI           AIProvider = "openai"
+	AIProviderAnthropic        AIProvider = "anthropic"
+	AIProviderGoogle           AIProvider = "google"
+	AIProviderOpenAICompatible AIProvider = "openai_compatible" // Self-hosted or third-party servers speaking the OpenAI API (Ollama, vLLM, ...)
 )
 
+// AITool is an action the AI may take during a conversation when it is
+// allow-listed in the AI settings
+type AITool string
+
+const (
+	AIToolTransferToTeam  AITool = "transfer_to_team"
+	AIToolAddTag          AITool = "add_tag"
+	AIToolSetContactField AITool = "set_contact_field"
+	AIToolStartFlow       AITool = "start_flow"
+	AIToolLookupProduct   AITool = "lookup_product"
+	AIToolCallContextAPI  AITool = "call_context_api"
+)
+
+// AITools lists every tool the AI can be allowed to use
+var AITools = []AITool{
+	AIToolTransferToTeam,
+	AIToolAddTag,
+	AIToolSetContactField,
+	AIToolStartFlow,
+	AIToolLookupProduct,
+	AIToolCallContextAPI,
+}
+
 // MatchType represents keyword matching strategies
 type MatchType string
 
@@ -116,6 +140,48 @@ const (
 	TransferSourceFlow            TransferSource = "flow"
 	TransferSourceKeyword         TransferSource = "keyword"
 	TransferSourceChatbotDisabled TransferSource = "chatbot_disabled"
+	TransferSourceAI              TransferSource = "ai"
+)
+
+// TransferPriority orders agent transfer queues, higher priorities first
+type TransferPriority int
+
+const (
+	TransferPriorityLow    TransferPriority = 1
+	TransferPriorityNormal TransferPriority = 2
+	TransferPriorityHigh   TransferPriority = 3
+	TransferPriorityVIP    TransferPriority = 4 // Assigned preemptively when no agent is free
+)
+
+// SLAEscalationAction is an action an SLA escalation tier runs when reached
+type SLAEscalationAction string
+
+const (
+	SLAEscalationReassign      SLAEscalationAction = "reassign"       // Hand the transfer to the least loaded other agent
+	SLAEscalationNotifyManager SLAEscalationAction = "notify_manager" // Notify the managers of the transfer's team
+	SLAEscalationWebhook       SLAEscalationAction = "webhook"        // Send the transfer.sla_escalated webhook event
+)
+
+// ConversationStatus represents the states of a contact's conversation
+type ConversationStatus string
+
+const (
+	ConversationStatusOpen     ConversationStatus = "open"
+	ConversationStatusPending  ConversationStatus = "pending" // Waiting on the customer
+	ConversationStatusSnoozed  ConversationStatus = "snoozed" // Reopened at snoozed_until
+	ConversationStatusResolved ConversationStatus = "resolved"
+)
+
+// ConversationResolutionReason records why a conversation was resolved
+type ConversationResolutionReason string
+
+const (
+	ConversationResolutionSolved     ConversationResolutionReason = "solved"
+	ConversationResolutionNoResponse ConversationResolutionReason = "no_response" // The customer stopped replying
+	ConversationResolutionSpam       ConversationResolutionReason = "spam"
+	ConversationResolutionDuplicate  ConversationResolutionReason = "duplicate"
+	ConversationResolutionOther      ConversationResolutionReason = "other"
+	ConversationResolutionAutoClosed ConversationResolutionReason = "auto_closed" // Closed by the SLA or client inactivity auto-close
 )
 
 // CampaignStatus represents bulk message campaign states
@@ -132,6 +198,30 @@ const (
 	CampaignStatusFailed     CampaignStatus = "failed"
 )
 
+// CampaignTrigger records what started a bulk message campaign
+type CampaignTrigger string
+
+const (
+	CampaignTriggerManual    CampaignTrigger = "manual"
+	CampaignTriggerScheduler CampaignTrigger = "scheduler"
+)
+
+// Notification rule trigger types
+const (
+	NotificationTriggerWebhook   = "webhook"
+	NotificationTriggerScheduler = "scheduler"
+	NotificationTriggerAPI       = "api"
+)
+
+// NotificationExecutionStatus represents the outcome of a notification rule run
+type NotificationExecutionStatus string
+
+const (
+	NotificationExecutionSent    NotificationExecutionStatus = "sent"
+	NotificationExecutionSkipped NotificationExecutionStatus = "skipped"
+	NotificationExecutionFailed  NotificationExecutionStatus = "failed"
+)
+
 // TemplateStatus represents WhatsApp template approval states
 type TemplateStatus string
 
@@ -179,6 +269,7 @@ type AssignmentStrategy string
 const (
 	AssignmentStrategyRoundRobin   AssignmentStrategy = "round_robin"
 	AssignmentStrategyLoadBalanced AssignmentStrategy = "load_balanced"
+	AssignmentStrategySkillsBased  AssignmentStrategy = "skills_based"
 	AssignmentStrategyManual       AssignmentStrategy = "manual"
 )
 
@@ -204,6 +295,28 @@ const (
 	WebhookEventTransferCreated  WebhookEvent = "transfer.created"
 	WebhookEventTransferResumed  WebhookEvent = "transfer.resumed"
 	WebhookEventTransferAssigned WebhookEvent = "transfer.assigned"
+
+	WebhookEventMessageStatus           WebhookEvent = "message.status"
+	WebhookEventCampaignStarted         WebhookEvent = "campaign.started"
+	WebhookEventCampaignCompleted       WebhookEvent = "campaign.completed"
+	WebhookEventCallStarted             WebhookEvent = "call.started"
+	WebhookEventCallEnded               WebhookEvent = "call.ended"
+	WebhookEventChatbotSessionCompleted WebhookEvent = "chatbot.session.completed"
+	WebhookEventContactTagged           WebhookEvent = "contact.tagged"
+	WebhookEventContactAssigned         WebhookEvent = "contact.assigned"
+	WebhookEventNoteCreated             WebhookEvent = "note.created"
+	WebhookEventTemplateStatusChanged   WebhookEvent = "template.status_changed"
+	WebhookEventTransferSLAEscalated    WebhookEvent = "transfer.sla_escalated"
+	WebhookEventConversationStatus      WebhookEvent = "conversation.status_changed"
+)
+
+// WebhookDeliveryStatus represents outbound webhook delivery states
+type WebhookDeliveryStatus string
+
+const (
+	WebhookDeliveryPending   WebhookDeliveryStatus = "pending"
+	WebhookDeliveryDelivered WebhookDeliveryStatus = "delivered"
+	WebhookDeliveryFailed    WebhookDeliveryStatus = "failed"
 )
 
 // ActionType represents custom action types
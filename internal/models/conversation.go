@@ -0,0 +1,37 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Conversation is one episode of a contact's conversation, from the message
+// that opens it until it is resolved. A contact has at most one conversation
+// that is not resolved; the contact's ConversationID and ConversationStatus
+// mirror its latest conversation.
+type Conversation struct {
+	BaseModel
+	OrganizationID  uuid.UUID          `gorm:"type:uuid;index;not null" json:"organization_id"`
+	ContactID       uuid.UUID          `gorm:"type:uuid;index;not null" json:"contact_id"`
+	WhatsAppAccount string             `gorm:"size:100" json:"whatsapp_account"` // References WhatsAppAccount.Name
+	Status          ConversationStatus `gorm:"size:20;not null" json:"status"`
+	StatusChangedAt time.Time          `json:"status_changed_at"`
+	SnoozedUntil    *time.Time         `json:"snoozed_until,omitempty"` // Reopened at this time while snoozed
+	ReopenCount     int                `gorm:"default:0" json:"reopen_count"`
+
+	// Resolution, cleared when the conversation is reopened
+	ResolutionReason ConversationResolutionReason `gorm:"size:30" json:"resolution_reason,omitempty"`
+	ResolutionNote   string                       `gorm:"type:text" json:"resolution_note,omitempty"`
+	ResolvedAt       *time.Time                   `json:"resolved_at,omitempty"`
+	ResolvedByID     *uuid.UUID                   `gorm:"type:uuid" json:"resolved_by_id,omitempty"` // nil when resolved automatically
+
+	// Relations
+	Organization *Organization `gorm:"foreignKey:OrganizationID" json:"organization,omitempty"`
+	Contact      *Contact      `gorm:"foreignKey:ContactID" json:"contact,omitempty"`
+	ResolvedBy   *User         `gorm:"foreignKey:ResolvedByID" json:"resolved_by,omitempty"`
+}
+
+func (Conversation) TableName() string {
+	return "conversations"
+}
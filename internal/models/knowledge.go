@@ -0,0 +1,98 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/binary"
+	"errors"
+	"math"
+
+	"github.com/google/uuid"
+)
+
+// KnowledgeDocumentStatus tracks a knowledge document through indexing
+type KnowledgeDocumentStatus string
+
+const (
+	KnowledgeDocumentProcessing KnowledgeDocumentStatus = "processing"
+	KnowledgeDocumentReady      KnowledgeDocumentStatus = "ready"
+	KnowledgeDocumentFailed     KnowledgeDocumentStatus = "failed"
+)
+
+// KnowledgeDocument is an uploaded file the AI answers questions from
+type KnowledgeDocument struct {
+	BaseModel
+	OrganizationID  uuid.UUID               `gorm:"type:uuid;index;not null" json:"organization_id"`
+	WhatsAppAccount string                  `gorm:"size:100;index" json:"whatsapp_account"` // Empty means all accounts
+	Name            string                  `gorm:"size:255;not null" json:"name"`
+	FileName        string                  `gorm:"size:255" json:"file_name"`
+	SourceType      string                  `gorm:"size:20" json:"source_type"` // pdf, markdown, csv, text
+	FileSize        int64                   `json:"file_size"`
+	Status          KnowledgeDocumentStatus `gorm:"size:20;default:'processing'" json:"status"`
+	Error           string                  `gorm:"type:text" json:"error,omitempty"`
+	ChunkCount      int                     `gorm:"default:0" json:"chunk_count"`
+	EmbeddingModel  string                  `gorm:"size:150" json:"embedding_model"` // provider:model the chunks were embedded with
+	IsEnabled       bool                    `gorm:"default:true" json:"is_enabled"`
+	CreatedByID     uuid.UUID               `gorm:"type:uuid" json:"created_by_id"`
+
+	// Relations
+	Organization *Organization `gorm:"foreignKey:OrganizationID" json:"organization,omitempty"`
+	CreatedBy    *User         `gorm:"foreignKey:CreatedByID" json:"created_by,omitempty"`
+}
+
+func (KnowledgeDocument) TableName() string {
+	return "knowledge_documents"
+}
+
+// KnowledgeChunk is an embedded piece of a knowledge document
+type KnowledgeChunk struct {
+	BaseModel
+	OrganizationID uuid.UUID `gorm:"type:uuid;index;not null" json:"organization_id"`
+	DocumentID     uuid.UUID `gorm:"type:uuid;index;not null" json:"document_id"`
+	ChunkIndex     int       `gorm:"not null" json:"chunk_index"`
+	Heading        string    `gorm:"type:text" json:"heading"` // Page, heading path or row range the chunk came from
+	Content        string    `gorm:"type:text;not null" json:"content"`
+	Embedding      Vector    `gorm:"type:bytea" json:"-"`
+	EmbeddingModel string    `gorm:"size:150;index" json:"embedding_model"`
+
+	// Relations
+	Document *KnowledgeDocument `gorm:"foreignKey:DocumentID" json:"document,omitempty"`
+}
+
+func (KnowledgeChunk) TableName() string {
+	return "knowledge_chunks"
+}
+
+// Vector is an embedding stored as little-endian float32 bytes so it works
+// without database extensions
+type Vector []float32
+
+func (v Vector) Value() (driver.Value, error) {
+	if v == nil {
+		return nil, nil
+	}
+	buf := make([]byte, len(v)*4)
+	for i, f := range v {
+		binary.LittleEndian.PutUint32(buf[i*4:], math.Float32bits(f))
+	}
+	return buf, nil
+}
+
+func (v *Vector) Scan(value interface{}) error {
+	if value == nil {
+		*v = nil
+		return nil
+	}
+	buf, ok := value.([]byte)
+	if !ok {
+		return errors.New("type assertion to []byte failed")
+	}
+	if len(buf)%4 != 0 {
+		return errors.New("invalid vector length")
+	}
+	vec := make(Vector, len(buf)/4)
+	for i := range vec {
+		vec[i] = math.Float32frombits(binary.LittleEndian.Uint32(buf[i*4:]))
+	}
+	*v = vec
+	return nil
+}
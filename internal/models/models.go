@@ -4,6 +4,7 @@ import (
 	"database/sql/driver"
 	"encoding/json"
 	"errors"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
@@ -137,6 +138,7 @@ type UserOrganization struct {
 	OrganizationID uuid.UUID  `gorm:"type:uuid;uniqueIndex:idx_user_org;not null" json:"organization_id"`
 	RoleID         *uuid.UUID `gorm:"type:uuid;index" json:"role_id,omitempty"`
 	IsDefault      bool       `gorm:"default:false" json:"is_default"`
+	MaxConcurrentConversations int `gorm:"default:0" json:"max_concurrent_conversations"` // Active transfers auto-assignment stops at, 0 = unlimited
 
 	// Relations
 	User         *User         `gorm:"foreignKey:UserID" json:"user,omitempty"`
@@ -171,8 +173,11 @@ type Team struct {
 	OrganizationID     uuid.UUID `gorm:"type:uuid;index;not null" json:"organization_id"`
 	Name               string    `gorm:"size:100;not null" json:"name"`
 	Description        string    `gorm:"size:500" json:"description"`
-	AssignmentStrategy AssignmentStrategy `gorm:"size:50;default:'round_robin'" json:"assignment_strategy"` // round_robin, load_balanced, manual
+	AssignmentStrategy AssignmentStrategy `gorm:"size:50;default:'round_robin'" json:"assignment_strategy"` // round_robin, load_balanced, skills_based, manual
 	IsActive           bool      `gorm:"default:true" json:"is_active"`
+	BusinessHoursScheduleID *uuid.UUID `gorm:"type:uuid" json:"business_hours_schedule_id,omitempty"` // Hours the team takes transfers
+	OverflowTeamID          *uuid.UUID `gorm:"type:uuid" json:"overflow_team_id,omitempty"`           // Team that takes transfers left unassigned too long
+	OverflowAfterMinutes    int        `gorm:"default:0" json:"overflow_after_minutes"`               // Queue wait before overflowing, 0 = never
 
 	// Relations
 	Organization *Organization `gorm:"foreignKey:OrganizationID" json:"organization,omitempty"`
@@ -256,6 +261,9 @@ type Webhook struct {
 	Headers        JSONB       `gorm:"type:jsonb;default:'{}'" json:"headers"`
 	Secret         string      `gorm:"size:255" json:"-"` // For HMAC signature
 	IsActive       bool        `gorm:"default:true" json:"is_active"`
+	FailureCount   int         `gorm:"default:0" json:"failure_count"` // Consecutive deliveries that exhausted their retries
+	DisabledAt     *time.Time  `json:"disabled_at,omitempty"`          // Set when automatically disabled
+	DisabledReason string      `gorm:"type:text" json:"disabled_reason,omitempty"`
 
 	// Relations
 	Organization *Organization `gorm:"foreignKey:OrganizationID" json:"organization,omitempty"`
@@ -265,6 +273,31 @@ func (Webhook) TableName() string {
 	return "webhooks"
 }
 
+// WebhookDelivery records an outbound webhook delivery and its latest attempt
+type WebhookDelivery struct {
+	BaseModel
+	OrganizationID uuid.UUID             `gorm:"type:uuid;index;not null" json:"organization_id"`
+	WebhookID      uuid.UUID             `gorm:"type:uuid;not null" json:"webhook_id"`
+	Event          string                `gorm:"size:100;not null" json:"event"`
+	Status         WebhookDeliveryStatus `gorm:"size:20;not null;default:'pending'" json:"status"` // pending, delivered, failed
+	URL            string                `gorm:"type:text" json:"url"`
+	RequestHeaders JSONB                 `gorm:"type:jsonb" json:"request_headers,omitempty"`
+	RequestBody    string                `gorm:"type:text" json:"request_body,omitempty"`
+	ResponseStatus int                   `json:"response_status"`
+	ResponseBody   string                `gorm:"type:text" json:"response_body,omitempty"`
+	Error          string                `gorm:"type:text" json:"error,omitempty"`
+	Attempts       int                   `gorm:"default:0" json:"attempts"`
+	DurationMs     int64                 `json:"duration_ms"`
+	NextAttemptAt  *time.Time            `json:"next_attempt_at,omitempty"`
+	LastAttemptAt  *time.Time            `json:"last_attempt_at,omitempty"`
+	DeliveredAt    *time.Time            `json:"delivered_at,omitempty"`
+	RedeliveryOf   *uuid.UUID            `gorm:"type:uuid" json:"redelivery_of,omitempty"` // Original delivery for manual redeliveries
+}
+
+func (WebhookDelivery) TableName() string {
+	return "webhook_deliveries"
+}
+
 // CustomAction represents a custom action button for chat integrations
 type CustomAction struct {
 	BaseModel
@@ -301,6 +334,11 @@ type WhatsAppAccount struct {
 	AutoReadReceipt    bool      `gorm:"default:false" json:"auto_read_receipt"`
 	Status             string    `gorm:"size:20;default:'active'" json:"status"`
 
+	// Send-rate throttling
+	MessagesPerSecond   int    `gorm:"default:0" json:"messages_per_second"`   // 0 = use campaign.messages_per_second from config
+	MessagingLimitTier  string `gorm:"size:20" json:"messaging_limit_tier"`    // Meta tier, e.g. TIER_1K (refreshed on connection test)
+	DailyRecipientLimit int    `gorm:"default:0" json:"daily_recipient_limit"` // Overrides the tier cap when > 0
+
 	// Relations
 	Organization *Organization `gorm:"foreignKey:OrganizationID" json:"organization,omitempty"`
 }
@@ -325,6 +363,27 @@ func (a *WhatsAppAccount) DecryptSecrets(encryptionKey string) {
 	crypto.DecryptFields(encryptionKey, &a.AccessToken, &a.AppSecret)
 }
 
+// messagingTierLimits maps Meta messaging limit tiers to the number of unique
+// business-initiated recipients allowed in a rolling 24 hour window.
+var messagingTierLimits = map[string]int{
+	"TIER_50":   50,
+	"TIER_250":  250,
+	"TIER_1K":   1000,
+	"TIER_2K":   2000,
+	"TIER_10K":  10000,
+	"TIER_100K": 100000,
+}
+
+// DailyRecipientCap returns the maximum number of unique recipients the account
+// may message in a rolling 24 hour window. 0 means no cap is enforced
+// (unlimited tier, or tier not yet known).
+func (a *WhatsAppAccount) DailyRecipientCap() int {
+	if a.DailyRecipientLimit > 0 {
+		return a.DailyRecipientLimit
+	}
+	return messagingTierLimits[a.MessagingLimitTier]
+}
+
 // Contact represents a WhatsApp contact/profile
 type Contact struct {
 	BaseModel
@@ -340,10 +399,19 @@ type Contact struct {
 	Metadata           JSONB      `gorm:"type:jsonb;default:'{}'" json:"metadata"`
 	LastInboundAt      *time.Time `json:"last_inbound_at,omitempty"` // When customer last sent a message (for 24h window tracking)
 
+	// Marketing consent (see ConsentEvent for the audit trail)
+	ConsentStatus    ConsentStatus `gorm:"size:20;default:'unknown';index" json:"consent_status"`
+	ConsentSource    ConsentSource `gorm:"size:20" json:"consent_source,omitempty"`
+	ConsentUpdatedAt *time.Time    `json:"consent_updated_at,omitempty"`
+
 	// Chatbot SLA tracking
 	ChatbotLastMessageAt *time.Time `json:"chatbot_last_message_at,omitempty"` // When chatbot last sent a message
 	ChatbotReminderSent  bool       `gorm:"default:false" json:"chatbot_reminder_sent"`
 
+	// Latest conversation (see Conversation), empty before the first one
+	ConversationID     *uuid.UUID         `gorm:"type:uuid" json:"conversation_id,omitempty"`
+	ConversationStatus ConversationStatus `gorm:"size:20" json:"conversation_status,omitempty"`
+
 	// Relations
 	Organization *Organization `gorm:"foreignKey:OrganizationID" json:"organization,omitempty"`
 	AssignedUser *User         `gorm:"foreignKey:AssignedUserID" json:"assigned_user,omitempty"`
@@ -354,6 +422,32 @@ func (Contact) TableName() string {
 	return "contacts"
 }
 
+// IsOptedOut reports whether the contact has opted out of marketing messages
+func (c *Contact) IsOptedOut() bool {
+	return c != nil && c.ConsentStatus == ConsentStatusOptedOut
+}
+
+// Contact metadata keys holding the contact's preferred language
+const (
+	ContactMetadataLanguage       = "preferred_language"
+	ContactMetadataLanguageSource = "preferred_language_source" // detected or selected
+)
+
+// Sources of a contact's preferred language
+const (
+	LanguageSourceDetected = "detected"
+	LanguageSourceSelected = "selected"
+)
+
+// PreferredLanguage returns the contact's preferred language code, if known
+func (c *Contact) PreferredLanguage() string {
+	if c == nil {
+		return ""
+	}
+	lang, _ := c.Metadata[ContactMetadataLanguage].(string)
+	return lang
+}
+
 // Message represents a WhatsApp message
 type Message struct {
 	BaseModel
@@ -416,6 +510,12 @@ func (Template) TableName() string {
 	return "templates"
 }
 
+// IsMarketing reports whether the template is in the MARKETING category,
+// which may only be sent to contacts that have not opted out
+func (t *Template) IsMarketing() bool {
+	return t != nil && strings.EqualFold(t.Category, string(TemplateCategoryMarketing))
+}
+
 // WhatsAppFlow represents a WhatsApp interactive flow
 type WhatsAppFlow struct {
 	BaseModel
@@ -318,3 +318,46 @@ func TestJSONBArray_Scan(t *testing.T) {
 		})
 	}
 }
+
+func TestWhatsAppAccount_DailyRecipientCap(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		account  models.WhatsAppAccount
+		expected int
+	}{
+		{"unknown tier", models.WhatsAppAccount{}, 0},
+		{"tier 1K", models.WhatsAppAccount{MessagingLimitTier: "TIER_1K"}, 1000},
+		{"tier 250", models.WhatsAppAccount{MessagingLimitTier: "TIER_250"}, 250},
+		{"unlimited", models.WhatsAppAccount{MessagingLimitTier: "TIER_UNLIMITED"}, 0},
+		{"override wins", models.WhatsAppAccount{MessagingLimitTier: "TIER_1K", DailyRecipientLimit: 500}, 500},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, tt.account.DailyRecipientCap())
+		})
+	}
+}
+
+func TestSkillsFromConfig(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		config   map[string]interface{}
+		expected models.StringArray
+	}{
+		{"missing", map[string]interface{}{}, models.StringArray{}},
+		{"list", map[string]interface{}{"required_skills": []interface{}{"Spanish", " billing ", "spanish", ""}}, models.StringArray{"spanish", "billing"}},
+		{"comma separated", map[string]interface{}{"required_skills": "french, VIP"}, models.StringArray{"french", "vip"}},
+		{"wrong type", map[string]interface{}{"required_skills": 3}, models.StringArray{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, models.SkillsFromConfig(tt.config))
+		})
+	}
+}
@@ -0,0 +1,27 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ConversationReadCursor tracks the last message a user has read in a
+// conversation. Incoming messages created after LastReadAt are unread for
+// that user.
+type ConversationReadCursor struct {
+	BaseModel
+	OrganizationID    uuid.UUID  `gorm:"type:uuid;index;not null" json:"organization_id"`
+	ContactID         uuid.UUID  `gorm:"type:uuid;not null;uniqueIndex:idx_read_cursors_contact_user" json:"contact_id"`
+	UserID            uuid.UUID  `gorm:"type:uuid;not null;uniqueIndex:idx_read_cursors_contact_user;index" json:"user_id"`
+	LastReadMessageID *uuid.UUID `gorm:"type:uuid" json:"last_read_message_id,omitempty"`
+	LastReadAt        time.Time  `gorm:"not null" json:"last_read_at"`
+
+	// Relations
+	Contact *Contact `gorm:"foreignKey:ContactID" json:"contact,omitempty"`
+	User    *User    `gorm:"foreignKey:UserID" json:"user,omitempty"`
+}
+
+func (ConversationReadCursor) TableName() string {
+	return "conversation_read_cursors"
+}
@@ -57,6 +57,7 @@ const (
 	ResourceFlowsWhatsApp   = "flows.whatsapp"
 	ResourceFlowsChatbot    = "flows.chatbot"
 	ResourceCampaigns       = "campaigns"
+	ResourceNotifications   = "notification_rules"
 	ResourceChatbotKeywords = "chatbot.keywords"
 	ResourceChatbotAI       = "chatbot.ai"
 	ResourceChat            = "chat"
@@ -143,6 +144,11 @@ func DefaultPermissions() []Permission {
 		{Resource: ResourceCampaigns, Action: ActionDelete, Description: "Delete campaigns"},
 		{Resource: ResourceCampaigns, Action: ActionExecute, Description: "Execute campaigns"},
 
+		// Notification Rules
+		{Resource: ResourceNotifications, Action: ActionRead, Description: "View notification rules"},
+		{Resource: ResourceNotifications, Action: ActionWrite, Description: "Create and edit notification rules"},
+		{Resource: ResourceNotifications, Action: ActionDelete, Description: "Delete notification rules"},
+
 		// Chatbot Keywords
 		{Resource: ResourceChatbotKeywords, Action: ActionRead, Description: "View keyword rules"},
 		{Resource: ResourceChatbotKeywords, Action: ActionWrite, Description: "Create and edit keyword rules"},
@@ -247,6 +253,8 @@ func SystemRolePermissions() map[string][]string {
 		"flows.chatbot:read", "flows.chatbot:write", "flows.chatbot:delete",
 		// Campaigns
 		"campaigns:read", "campaigns:write", "campaigns:delete", "campaigns:execute",
+		// Notification Rules
+		"notification_rules:read", "notification_rules:write", "notification_rules:delete",
 		// Chatbot
 		"chatbot.keywords:read", "chatbot.keywords:write", "chatbot.keywords:delete",
 		"chatbot.ai:read", "chatbot.ai:write",
@@ -0,0 +1,50 @@
+package models
+
+import (
+	"github.com/google/uuid"
+)
+
+// SLAPolicy is a named set of SLA targets for the agent transfers that meet
+// all of its conditions. Conditions left empty match every transfer. The
+// active policy with the highest priority that matches a new transfer
+// applies to it; transfers matching none follow the chatbot SLA settings.
+type SLAPolicy struct {
+	BaseModel
+	OrganizationID uuid.UUID `gorm:"type:uuid;index;not null" json:"organization_id"`
+	Name           string    `gorm:"size:100;not null" json:"name"`
+	Description    string    `gorm:"size:500" json:"description"`
+	IsActive       bool      `gorm:"default:true" json:"is_active"`
+	Priority       int       `gorm:"default:0" json:"priority"` // Policies are matched highest priority first
+
+	// Conditions
+	TeamID              *uuid.UUID       `gorm:"type:uuid" json:"team_id,omitempty"`     // Transfer is queued for this team
+	MinTransferPriority TransferPriority `gorm:"default:0" json:"min_transfer_priority"` // Transfer priority is at least this, 0 = any
+	Tags                StringArray      `gorm:"type:jsonb;default:'[]'" json:"tags"`    // Contact has any of these tags
+
+	// Targets in minutes of SLA time, 0 = no target
+	ResponseMinutes   int `gorm:"default:0" json:"response_minutes"`   // Time to pick up the transfer
+	ResolutionMinutes int `gorm:"default:0" json:"resolution_minutes"` // Time to close the transfer
+
+	// SLA clock
+	BusinessHoursOnly       bool       `gorm:"default:false" json:"business_hours_only"`              // Count time only within business hours
+	BusinessHoursScheduleID *uuid.UUID `gorm:"type:uuid" json:"business_hours_schedule_id,omitempty"` // Hours to count; nil = the team's, else the chatbot's
+	PauseOnCustomer         bool       `gorm:"default:false" json:"pause_on_customer"`                // Stop the clock while an agent waits on the customer's reply
+
+	EscalationTiers JSONBArray `gorm:"type:jsonb;default:'[]'" json:"escalation_tiers"` // []SLAEscalationTier, by after_minutes
+
+	// Relations
+	Organization *Organization `gorm:"foreignKey:OrganizationID" json:"organization,omitempty"`
+}
+
+func (SLAPolicy) TableName() string {
+	return "sla_policies"
+}
+
+// SLAEscalationTier is reached when a transfer has used AfterMinutes of SLA
+// time. Reaching the n-th tier sets the transfer's escalation level to n,
+// notifies NotifyUserIDs and runs the tier's actions.
+type SLAEscalationTier struct {
+	AfterMinutes  int                   `json:"after_minutes"`
+	NotifyUserIDs []string              `json:"notify_user_ids"`
+	Actions       []SLAEscalationAction `json:"actions"`
+}
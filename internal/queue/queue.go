@@ -25,6 +25,15 @@ type RecipientJob struct {
 	RecipientName  string        `json:"recipient_name"`
 	TemplateParams models.JSONB  `json:"template_params"`
 	EnqueuedAt     time.Time     `json:"enqueued_at"`
+	Attempt        int           `json:"attempt,omitempty"` // Number of failed send attempts so far
+}
+
+// DeadLetter is a recipient job that exhausted its send retries
+type DeadLetter struct {
+	ID       string        `json:"id"`
+	Job      *RecipientJob `json:"job"`
+	Error    string        `json:"error"`
+	FailedAt time.Time     `json:"failed_at"`
 }
 
 // Queue defines the interface for job queue operations
@@ -35,6 +44,24 @@ type Queue interface {
 	// EnqueueRecipients adds multiple recipient jobs to the queue
 	EnqueueRecipients(ctx context.Context, jobs []*RecipientJob) error
 
+	// ScheduleRecipient adds a recipient job that only becomes available
+	// to consumers at the given time (used to defer throttled sends)
+	ScheduleRecipient(ctx context.Context, job *RecipientJob, at time.Time) error
+
+	// DeadLetterRecipient records a job that exhausted its retries in the
+	// campaign's dead-letter stream
+	DeadLetterRecipient(ctx context.Context, job *RecipientJob, reason string) error
+
+	// ListDeadLetters returns up to limit dead-lettered jobs for a campaign,
+	// oldest first, skipping the first offset entries
+	ListDeadLetters(ctx context.Context, campaignID uuid.UUID, offset, limit int) ([]DeadLetter, error)
+
+	// CountDeadLetters returns the number of dead-lettered jobs for a campaign
+	CountDeadLetters(ctx context.Context, campaignID uuid.UUID) (int64, error)
+
+	// RemoveDeadLetters deletes dead-letter entries from a campaign's stream
+	RemoveDeadLetters(ctx context.Context, campaignID uuid.UUID, ids ...string) error
+
 	// Close closes the queue connection
 	Close() error
 }
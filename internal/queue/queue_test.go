@@ -535,3 +535,105 @@ func TestPublishCampaignStats_InvalidRedis(t *testing.T) {
 	err := pub.PublishCampaignStats(ctx, update)
 	assert.Error(t, err)
 }
+
+// --- Delayed job tests ---
+
+func TestRedisQueue_ScheduleRecipient_PromotesWhenDue(t *testing.T) {
+	client := skipIfNoRedis(t)
+	cleanStream(t, client)
+	ctx := context.Background()
+	client.Del(ctx, queue.DelayedSetName)
+	t.Cleanup(func() { client.Del(ctx, queue.DelayedSetName) })
+
+	q := queue.NewRedisQueue(client, testutil.NopLogger())
+	now := time.Now()
+
+	dueJob := makeRecipientJob()
+	laterJob := makeRecipientJob()
+	require.NoError(t, q.ScheduleRecipient(ctx, dueJob, now.Add(-time.Second)))
+	require.NoError(t, q.ScheduleRecipient(ctx, laterJob, now.Add(time.Hour)))
+
+	n, err := queue.PromoteDueJobs(ctx, client, now)
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+
+	// Only the due job moved to the stream
+	msgs, err := client.XRange(ctx, queue.StreamName, "-", "+").Result()
+	require.NoError(t, err)
+	require.Len(t, msgs, 1)
+
+	var got queue.RecipientJob
+	require.NoError(t, json.Unmarshal([]byte(msgs[0].Values["payload"].(string)), &got))
+	assert.Equal(t, dueJob.RecipientID, got.RecipientID)
+
+	remaining, err := client.ZCard(ctx, queue.DelayedSetName).Result()
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), remaining)
+
+	// Promoting again is a no-op until the later job is due
+	n, err = queue.PromoteDueJobs(ctx, client, now)
+	require.NoError(t, err)
+	assert.Equal(t, 0, n)
+}
+
+func TestRedisQueue_DeadLetters(t *testing.T) {
+	client := skipIfNoRedis(t)
+	ctx := context.Background()
+	q := queue.NewRedisQueue(client, testutil.NopLogger())
+
+	job := makeRecipientJob()
+	job.Attempt = 6
+	t.Cleanup(func() { client.Del(ctx, queue.DeadLetterStream(job.CampaignID)) })
+
+	require.NoError(t, q.DeadLetterRecipient(ctx, job, "API returned status 503"))
+
+	letters, err := q.ListDeadLetters(ctx, job.CampaignID, 0, 10)
+	require.NoError(t, err)
+	require.Len(t, letters, 1)
+	assert.Equal(t, job.RecipientID, letters[0].Job.RecipientID)
+	assert.Equal(t, 6, letters[0].Job.Attempt)
+	assert.Equal(t, "API returned status 503", letters[0].Error)
+	assert.False(t, letters[0].FailedAt.IsZero())
+
+	ttl, err := client.TTL(ctx, queue.DeadLetterStream(job.CampaignID)).Result()
+	require.NoError(t, err)
+	assert.Greater(t, ttl, time.Duration(0))
+
+	n, err := q.CountDeadLetters(ctx, job.CampaignID)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), n)
+	page, err := q.ListDeadLetters(ctx, job.CampaignID, 1, 10)
+	require.NoError(t, err)
+	assert.Empty(t, page, "offset past the end")
+
+	require.NoError(t, q.RemoveDeadLetters(ctx, job.CampaignID, letters[0].ID))
+	letters, err = q.ListDeadLetters(ctx, job.CampaignID, 0, 10)
+	require.NoError(t, err)
+	assert.Empty(t, letters)
+	n, err = q.CountDeadLetters(ctx, job.CampaignID)
+	require.NoError(t, err)
+	assert.Zero(t, n)
+}
+
+func TestPopDueWebhookDeliveries(t *testing.T) {
+	client := skipIfNoRedis(t)
+	ctx := context.Background()
+	client.Del(ctx, queue.WebhookDeliveriesKey)
+	t.Cleanup(func() { client.Del(ctx, queue.WebhookDeliveriesKey) })
+
+	now := time.Now()
+	due := uuid.New()
+	later := uuid.New()
+	require.NoError(t, queue.ScheduleWebhookDelivery(ctx, client, due, now.Add(-time.Second)))
+	require.NoError(t, queue.ScheduleWebhookDelivery(ctx, client, later, now.Add(time.Hour)))
+
+	ids, err := queue.PopDueWebhookDeliveries(ctx, client, now, 10)
+	require.NoError(t, err)
+	assert.Equal(t, []uuid.UUID{due}, ids)
+
+	// Popped deliveries are removed; future ones stay queued
+	ids, err = queue.PopDueWebhookDeliveries(ctx, client, now, 10)
+	require.NoError(t, err)
+	assert.Empty(t, ids)
+	assert.Equal(t, int64(1), client.ZCard(ctx, queue.WebhookDeliveriesKey).Val())
+}
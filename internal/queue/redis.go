@@ -7,6 +7,7 @@ import (
 	"os"
 	"time"
 
+	"github.com/google/uuid"
 	"github.com/redis/go-redis/v9"
 	"github.com/zerodha/logf"
 )
@@ -23,8 +24,42 @@ const (
 
 	// ClaimMinIdleTime is the minimum idle time before claiming a pending message
 	ClaimMinIdleTime = 5 * time.Minute
+
+	// DelayedSetName is the Redis sorted set holding deferred jobs, scored by
+	// the unix millisecond timestamp at which they become due
+	DelayedSetName = "whatomate:campaigns:delayed"
+
+	// PromoteInterval is how often consumers move due deferred jobs into the stream
+	PromoteInterval = time.Second
+
+	// promoteBatchSize caps how many deferred jobs are moved per promotion
+	promoteBatchSize = 500
+
+	// DeadLetterStreamPrefix prefixes the per-campaign dead-letter streams
+	DeadLetterStreamPrefix = "whatomate:campaigns:dead:"
+
+	// DeadLetterMaxLen caps each dead-letter stream (approximate trimming)
+	DeadLetterMaxLen = 100000
+
+	// DeadLetterTTL is how long a campaign's dead-letter stream is kept after its last entry
+	DeadLetterTTL = 30 * 24 * time.Hour
 )
 
+// DeadLetterStream returns the dead-letter stream key for a campaign
+func DeadLetterStream(campaignID uuid.UUID) string {
+	return DeadLetterStreamPrefix + campaignID.String()
+}
+
+// promoteScript atomically moves due jobs from the delayed set into the stream,
+// so concurrent consumers never promote the same job twice.
+var promoteScript = redis.NewScript(`
+local due = redis.call("ZRANGEBYSCORE", KEYS[1], "-inf", ARGV[1], "LIMIT", 0, ARGV[2])
+for _, payload in ipairs(due) do
+	redis.call("XADD", KEYS[2], "*", "type", ARGV[3], "payload", payload)
+	redis.call("ZREM", KEYS[1], payload)
+end
+return #due`)
+
 // RedisQueue implements the Queue interface using Redis Streams
 type RedisQueue struct {
 	client *redis.Client
@@ -102,6 +137,117 @@ func (q *RedisQueue) EnqueueRecipients(ctx context.Context, jobs []*RecipientJob
 	return nil
 }
 
+// ScheduleRecipient adds a recipient job to the delayed set. Consumers move it
+// into the stream once the given time has passed.
+func (q *RedisQueue) ScheduleRecipient(ctx context.Context, job *RecipientJob, at time.Time) error {
+	job.EnqueuedAt = time.Now()
+
+	payload, err := json.Marshal(job)
+	if err != nil {
+		return fmt.Errorf("failed to marshal recipient job: %w", err)
+	}
+
+	if err := q.client.ZAdd(ctx, DelayedSetName, redis.Z{
+		Score:  float64(at.UnixMilli()),
+		Member: string(payload),
+	}).Err(); err != nil {
+		return fmt.Errorf("failed to schedule recipient job: %w", err)
+	}
+
+	return nil
+}
+
+// PromoteDueJobs moves deferred jobs whose time has come into the stream.
+// Returns the number of jobs moved.
+func PromoteDueJobs(ctx context.Context, client *redis.Client, now time.Time) (int, error) {
+	n, err := promoteScript.Run(ctx, client, []string{DelayedSetName, StreamName},
+		now.UnixMilli(), promoteBatchSize, string(JobTypeRecipient)).Int()
+	if err != nil {
+		return 0, fmt.Errorf("failed to promote delayed jobs: %w", err)
+	}
+	return n, nil
+}
+
+// DeadLetterRecipient appends an exhausted job to its campaign's dead-letter stream
+func (q *RedisQueue) DeadLetterRecipient(ctx context.Context, job *RecipientJob, reason string) error {
+	payload, err := json.Marshal(job)
+	if err != nil {
+		return fmt.Errorf("failed to marshal recipient job: %w", err)
+	}
+
+	stream := DeadLetterStream(job.CampaignID)
+	pipe := q.client.TxPipeline()
+	pipe.XAdd(ctx, &redis.XAddArgs{
+		Stream: stream,
+		MaxLen: DeadLetterMaxLen,
+		Approx: true,
+		Values: map[string]interface{}{
+			"payload":   string(payload),
+			"error":     reason,
+			"failed_at": time.Now().UTC().Format(time.RFC3339),
+		},
+	})
+	pipe.Expire(ctx, stream, DeadLetterTTL)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("failed to dead-letter recipient job: %w", err)
+	}
+
+	return nil
+}
+
+// ListDeadLetters returns up to limit dead-lettered jobs for a campaign, oldest first
+func (q *RedisQueue) ListDeadLetters(ctx context.Context, campaignID uuid.UUID, offset, limit int) ([]DeadLetter, error) {
+	// Streams cannot be read from a position, so read through the page and
+	// drop the entries before it
+	msgs, err := q.client.XRangeN(ctx, DeadLetterStream(campaignID), "-", "+", int64(offset+limit)).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read dead-letter stream: %w", err)
+	}
+	if offset >= len(msgs) {
+		return []DeadLetter{}, nil
+	}
+	msgs = msgs[offset:]
+
+	letters := make([]DeadLetter, 0, len(msgs))
+	for _, msg := range msgs {
+		payload, _ := msg.Values["payload"].(string)
+		var job RecipientJob
+		if err := json.Unmarshal([]byte(payload), &job); err != nil {
+			q.log.Error("Skipping malformed dead letter", "error", err, "id", msg.ID)
+			continue
+		}
+
+		letter := DeadLetter{ID: msg.ID, Job: &job}
+		letter.Error, _ = msg.Values["error"].(string)
+		if failedAt, ok := msg.Values["failed_at"].(string); ok {
+			letter.FailedAt, _ = time.Parse(time.RFC3339, failedAt)
+		}
+		letters = append(letters, letter)
+	}
+
+	return letters, nil
+}
+
+// CountDeadLetters returns the length of a campaign's dead-letter stream
+func (q *RedisQueue) CountDeadLetters(ctx context.Context, campaignID uuid.UUID) (int64, error) {
+	n, err := q.client.XLen(ctx, DeadLetterStream(campaignID)).Result()
+	if err != nil {
+		return 0, fmt.Errorf("failed to count dead letters: %w", err)
+	}
+	return n, nil
+}
+
+// RemoveDeadLetters deletes dead-letter entries from a campaign's stream
+func (q *RedisQueue) RemoveDeadLetters(ctx context.Context, campaignID uuid.UUID, ids ...string) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	if err := q.client.XDel(ctx, DeadLetterStream(campaignID), ids...).Err(); err != nil {
+		return fmt.Errorf("failed to remove dead letters: %w", err)
+	}
+	return nil
+}
+
 // Close closes the queue connection
 func (q *RedisQueue) Close() error {
 	return nil // Redis client is managed externally
@@ -146,6 +292,7 @@ func (c *RedisConsumer) Consume(ctx context.Context, handler JobHandler) error {
 		c.log.Warn("Failed to claim pending messages", "error", err)
 	}
 
+	var lastPromote time.Time
 	for {
 		select {
 		case <-ctx.Done():
@@ -154,6 +301,18 @@ func (c *RedisConsumer) Consume(ctx context.Context, handler JobHandler) error {
 		default:
 		}
 
+		// Move deferred jobs that are now due back into the stream
+		if time.Since(lastPromote) >= PromoteInterval {
+			lastPromote = time.Now()
+			if n, err := PromoteDueJobs(ctx, c.client, lastPromote); err != nil {
+				if ctx.Err() == nil {
+					c.log.Error("Failed to promote delayed jobs", "error", err)
+				}
+			} else if n > 0 {
+				c.log.Debug("Promoted delayed jobs", "count", n)
+			}
+		}
+
 		// Read new messages from the stream
 		streams, err := c.client.XReadGroup(ctx, &redis.XReadGroupArgs{
 			Group:    ConsumerGroup,
@@ -0,0 +1,54 @@
+package queue
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+)
+
+// WebhookDeliveriesKey is the Redis sorted set of pending webhook delivery IDs,
+// scored by the unix millisecond timestamp of their next attempt
+const WebhookDeliveriesKey = "whatomate:webhooks:scheduled"
+
+// popDueScript atomically removes and returns due members of a sorted set, so
+// concurrent workers never pick up the same delivery twice.
+var popDueScript = redis.NewScript(`
+local due = redis.call("ZRANGEBYSCORE", KEYS[1], "-inf", ARGV[1], "LIMIT", 0, ARGV[2])
+if #due > 0 then
+	redis.call("ZREM", KEYS[1], unpack(due))
+end
+return due`)
+
+// ScheduleWebhookDelivery queues a webhook delivery attempt for the given time.
+// Scheduling an already queued delivery moves it to the new time.
+func ScheduleWebhookDelivery(ctx context.Context, client *redis.Client, deliveryID uuid.UUID, at time.Time) error {
+	if err := client.ZAdd(ctx, WebhookDeliveriesKey, redis.Z{
+		Score:  float64(at.UnixMilli()),
+		Member: deliveryID.String(),
+	}).Err(); err != nil {
+		return fmt.Errorf("failed to schedule webhook delivery: %w", err)
+	}
+	return nil
+}
+
+// PopDueWebhookDeliveries removes and returns up to limit deliveries that are
+// due at or before now, oldest first
+func PopDueWebhookDeliveries(ctx context.Context, client *redis.Client, now time.Time, limit int) ([]uuid.UUID, error) {
+	members, err := popDueScript.Run(ctx, client, []string{WebhookDeliveriesKey}, now.UnixMilli(), limit).StringSlice()
+	if err != nil {
+		return nil, fmt.Errorf("failed to pop due webhook deliveries: %w", err)
+	}
+
+	ids := make([]uuid.UUID, 0, len(members))
+	for _, m := range members {
+		id, err := uuid.Parse(m)
+		if err != nil {
+			continue
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
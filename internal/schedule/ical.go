@@ -0,0 +1,132 @@
+package schedule
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+const (
+	icalDateLayout = "20060102"
+	// maxICalEvents limits the holidays imported from one calendar
+	maxICalEvents = 1000
+)
+
+// ParseICalendar reads the events of an iCalendar (.ics) file as holidays.
+// Each event closes the whole days it covers; all-day events end the day
+// before DTEND, as the format specifies. Events with a yearly RRULE become
+// recurring holidays.
+func ParseICalendar(data []byte) ([]Holiday, error) {
+	lines := unfoldICal(string(data))
+	if len(lines) == 0 || !strings.EqualFold(strings.TrimSpace(lines[0]), "BEGIN:VCALENDAR") {
+		return nil, errors.New("not an iCalendar file")
+	}
+
+	var (
+		holidays   []Holiday
+		inEvent    bool
+		holiday    Holiday
+		start, end string
+	)
+	for _, line := range lines {
+		name, value := splitICalProperty(line)
+		switch {
+		case name == "BEGIN" && strings.EqualFold(value, "VEVENT"):
+			inEvent = true
+			holiday, start, end = Holiday{}, "", ""
+		case name == "END" && strings.EqualFold(value, "VEVENT"):
+			inEvent = false
+			h, err := icalHoliday(holiday, start, end)
+			if err != nil {
+				return nil, err
+			}
+			holidays = append(holidays, h)
+			if len(holidays) > maxICalEvents {
+				return nil, fmt.Errorf("calendar has more than %d events", maxICalEvents)
+			}
+		case !inEvent:
+		case name == "DTSTART":
+			start = value
+		case name == "DTEND":
+			end = value
+		case name == "SUMMARY":
+			holiday.Name = unescapeICalText(value)
+		case name == "RRULE":
+			holiday.Recurring = strings.Contains(strings.ToUpper(value), "FREQ=YEARLY")
+		}
+	}
+	if len(holidays) == 0 {
+		return nil, errors.New("no events found in calendar")
+	}
+	return holidays, nil
+}
+
+// icalHoliday fills in the dates of an event
+func icalHoliday(h Holiday, start, end string) (Holiday, error) {
+	startDate, _, err := parseICalDate(start)
+	if err != nil {
+		return h, fmt.Errorf("event %q: invalid DTSTART %q", h.Name, start)
+	}
+	endDate := startDate
+	if end != "" {
+		var timed bool
+		if endDate, timed, err = parseICalDate(end); err != nil {
+			return h, fmt.Errorf("event %q: invalid DTEND %q", h.Name, end)
+		}
+		// All-day events and events ending at midnight end the day before
+		if !timed || strings.HasSuffix(strings.TrimSuffix(end, "Z"), "T000000") {
+			endDate = endDate.AddDate(0, 0, -1)
+		}
+		if endDate.Before(startDate) {
+			endDate = startDate
+		}
+	}
+
+	h.Date = startDate.Format(DateLayout)
+	if !endDate.Equal(startDate) {
+		h.EndDate = endDate.Format(DateLayout)
+	}
+	return h, nil
+}
+
+// parseICalDate parses the date of a DATE or DATE-TIME value and reports
+// whether it had a time
+func parseICalDate(value string) (date time.Time, timed bool, err error) {
+	if len(value) < len(icalDateLayout) {
+		return date, false, errors.New("too short")
+	}
+	date, err = time.Parse(icalDateLayout, value[:len(icalDateLayout)])
+	return date, len(value) > len(icalDateLayout), err
+}
+
+// unfoldICal splits the calendar into lines, joining continuation lines
+// (which start with a space or tab) to the line before
+func unfoldICal(data string) []string {
+	var lines []string
+	for _, line := range strings.Split(strings.ReplaceAll(data, "\r\n", "\n"), "\n") {
+		if (strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")) && len(lines) > 0 {
+			lines[len(lines)-1] += line[1:]
+			continue
+		}
+		if strings.TrimSpace(line) != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
+
+// splitICalProperty returns the upper-cased property name (without
+// parameters) and the value of a content line
+func splitICalProperty(line string) (name, value string) {
+	head, value, _ := strings.Cut(line, ":")
+	name, _, _ = strings.Cut(head, ";")
+	return strings.ToUpper(strings.TrimSpace(name)), strings.TrimSpace(value)
+}
+
+var icalTextReplacer = strings.NewReplacer(`\n`, " ", `\N`, " ", `\,`, ",", `\;`, ";", `\\`, `\`)
+
+// unescapeICalText decodes an iCalendar TEXT value
+func unescapeICalText(s string) string {
+	return strings.TrimSpace(icalTextReplacer.Replace(s))
+}
@@ -0,0 +1,56 @@
+package schedule
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseICalendar(t *testing.T) {
+	ics := "BEGIN:VCALENDAR\r\n" +
+		"VERSION:2.0\r\n" +
+		"BEGIN:VEVENT\r\n" +
+		"DTSTART;VALUE=DATE:20251225\r\n" +
+		"DTEND;VALUE=DATE:20251226\r\n" +
+		"RRULE:FREQ=YEARLY\r\n" +
+		"SUMMARY:Christmas Day\r\n" +
+		"END:VEVENT\r\n" +
+		"BEGIN:VEVENT\r\n" +
+		"DTSTART;VALUE=DATE:20250418\r\n" +
+		"DTEND;VALUE=DATE:20250422\r\n" +
+		"SUMMARY:Easter\\, long\r\n" +
+		"  weekend\r\n" +
+		"END:VEVENT\r\n" +
+		"BEGIN:VEVENT\r\n" +
+		"DTSTART;TZID=Europe/London:20250305T120000\r\n" +
+		"DTEND;TZID=Europe/London:20250305T150000\r\n" +
+		"SUMMARY:Stocktake\r\n" +
+		"END:VEVENT\r\n" +
+		"BEGIN:VEVENT\r\n" +
+		"DTSTART:20250601T000000Z\r\n" +
+		"DTEND:20250603T000000Z\r\n" +
+		"SUMMARY:Offsite\r\n" +
+		"END:VEVENT\r\n" +
+		"END:VCALENDAR\r\n"
+
+	holidays, err := ParseICalendar([]byte(ics))
+	require.NoError(t, err)
+	assert.Equal(t, []Holiday{
+		{Name: "Christmas Day", Date: "2025-12-25", Recurring: true},
+		{Name: "Easter, long weekend", Date: "2025-04-18", EndDate: "2025-04-21"},
+		{Name: "Stocktake", Date: "2025-03-05"},
+		{Name: "Offsite", Date: "2025-06-01", EndDate: "2025-06-02"},
+	}, holidays)
+}
+
+func TestParseICalendar_Errors(t *testing.T) {
+	_, err := ParseICalendar([]byte("hello"))
+	assert.EqualError(t, err, "not an iCalendar file")
+
+	_, err = ParseICalendar([]byte("BEGIN:VCALENDAR\nEND:VCALENDAR\n"))
+	assert.EqualError(t, err, "no events found in calendar")
+
+	_, err = ParseICalendar([]byte("BEGIN:VCALENDAR\nBEGIN:VEVENT\nSUMMARY:Broken\nDTSTART:2025\nEND:VEVENT\nEND:VCALENDAR\n"))
+	assert.EqualError(t, err, `event "Broken": invalid DTSTART "2025"`)
+}
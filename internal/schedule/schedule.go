@@ -0,0 +1,294 @@
+// Package schedule evaluates business hours: weekly opening hours with any
+// number of intervals per day in an IANA timezone, plus dated holidays and
+// closures that replace the weekly hours on the days they cover.
+package schedule
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DateLayout is the layout of holiday dates
+const DateLayout = "2006-01-02"
+
+// maxSearchDays bounds the search for the next opening time
+const maxSearchDays = 367
+
+const minutesPerDay = 24 * 60
+
+// Interval is an opening interval within a day, in "HH:MM" local time. The end
+// is exclusive and may be "24:00". An end at or before the start runs past
+// midnight into the next day.
+type Interval struct {
+	Start string `json:"start_time"`
+	End   string `json:"end_time"`
+}
+
+// Day holds the opening hours of a weekday. Hours are given as Intervals, or
+// as a single StartTime/EndTime pair when Intervals is empty.
+type Day struct {
+	Day       int        `json:"day"` // 0 = Sunday ... 6 = Saturday
+	Enabled   bool       `json:"enabled"`
+	StartTime string     `json:"start_time,omitempty"`
+	EndTime   string     `json:"end_time,omitempty"`
+	Intervals []Interval `json:"intervals,omitempty"`
+}
+
+// OpenIntervals returns the day's opening intervals
+func (d Day) OpenIntervals() []Interval {
+	if !d.Enabled {
+		return nil
+	}
+	if len(d.Intervals) > 0 {
+		return d.Intervals
+	}
+	if d.StartTime == "" && d.EndTime == "" {
+		return nil
+	}
+	return []Interval{{Start: d.StartTime, End: d.EndTime}}
+}
+
+// Holiday replaces the weekly hours from Date to EndDate (inclusive). It is
+// closed all day unless it has special opening Intervals. Recurring holidays
+// repeat every year on the same dates.
+type Holiday struct {
+	Name      string     `json:"name"`
+	Date      string     `json:"date"`
+	EndDate   string     `json:"end_date,omitempty"`
+	Recurring bool       `json:"recurring,omitempty"`
+	Intervals []Interval `json:"intervals,omitempty"`
+}
+
+// Schedule is a set of business hours. An empty Timezone uses the server's
+// local timezone.
+type Schedule struct {
+	Timezone string    `json:"timezone"`
+	Days     []Day     `json:"hours"`
+	Holidays []Holiday `json:"holidays"`
+}
+
+// Decode converts JSON-like data (such as a JSONB column) into out
+func Decode(data interface{}, out interface{}) error {
+	if data == nil {
+		return nil
+	}
+	b, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(b, out)
+}
+
+// ParseClock parses "HH:MM" into minutes after midnight. "24:00" is allowed.
+func ParseClock(s string) (int, error) {
+	h, m, ok := strings.Cut(strings.TrimSpace(s), ":")
+	hour, err1 := strconv.Atoi(h)
+	minute, err2 := strconv.Atoi(m)
+	if !ok || err1 != nil || err2 != nil || len(m) != 2 || hour < 0 || minute < 0 || minute > 59 ||
+		hour > 24 || (hour == 24 && minute > 0) {
+		return 0, fmt.Errorf("invalid time %q, expected HH:MM", s)
+	}
+	return hour*60 + minute, nil
+}
+
+// bounds returns the start and end of the interval in minutes after the
+// midnight of its day; the end is past minutesPerDay for overnight intervals
+func (iv Interval) bounds() (start, end int, err error) {
+	if start, err = ParseClock(iv.Start); err != nil {
+		return 0, 0, err
+	}
+	if end, err = ParseClock(iv.End); err != nil {
+		return 0, 0, err
+	}
+	if start == minutesPerDay {
+		return 0, 0, fmt.Errorf("interval cannot start at %s", iv.Start)
+	}
+	if start == end {
+		return 0, 0, fmt.Errorf("interval %s-%s is empty", iv.Start, iv.End)
+	}
+	if end < start {
+		end += minutesPerDay
+	}
+	return start, end, nil
+}
+
+// Location returns the schedule's timezone
+func (s *Schedule) Location() (*time.Location, error) {
+	if s.Timezone == "" {
+		return time.Local, nil
+	}
+	loc, err := time.LoadLocation(s.Timezone)
+	if err != nil {
+		return nil, fmt.Errorf("invalid timezone %q", s.Timezone)
+	}
+	return loc, nil
+}
+
+// Validate checks the timezone, days, intervals and holiday dates
+func (s *Schedule) Validate() error {
+	if _, err := s.Location(); err != nil {
+		return err
+	}
+
+	seen := make(map[int]bool, len(s.Days))
+	for _, d := range s.Days {
+		if d.Day < 0 || d.Day > 6 {
+			return fmt.Errorf("invalid day %d, expected 0 (Sunday) to 6 (Saturday)", d.Day)
+		}
+		if seen[d.Day] {
+			return fmt.Errorf("day %d is listed more than once", d.Day)
+		}
+		seen[d.Day] = true
+		if d.Enabled && len(d.OpenIntervals()) == 0 {
+			return fmt.Errorf("%s is enabled but has no hours", time.Weekday(d.Day))
+		}
+		for _, iv := range d.OpenIntervals() {
+			if _, _, err := iv.bounds(); err != nil {
+				return fmt.Errorf("%s: %w", time.Weekday(d.Day), err)
+			}
+		}
+	}
+
+	for _, h := range s.Holidays {
+		start, end, err := h.dates()
+		if err != nil {
+			return err
+		}
+		if end.Before(start) {
+			return fmt.Errorf("holiday %s ends before it starts", h.Date)
+		}
+		for _, iv := range h.Intervals {
+			if _, _, err := iv.bounds(); err != nil {
+				return fmt.Errorf("holiday %s: %w", h.Date, err)
+			}
+		}
+	}
+	return nil
+}
+
+// dates returns the first and last day of the holiday
+func (h Holiday) dates() (start, end time.Time, err error) {
+	start, err = time.Parse(DateLayout, h.Date)
+	if err != nil {
+		return start, end, fmt.Errorf("invalid holiday date %q, expected YYYY-MM-DD", h.Date)
+	}
+	if h.EndDate == "" {
+		return start, start, nil
+	}
+	end, err = time.Parse(DateLayout, h.EndDate)
+	if err != nil {
+		return start, end, fmt.Errorf("invalid holiday end_date %q, expected YYYY-MM-DD", h.EndDate)
+	}
+	return start, end, nil
+}
+
+// covers reports whether the holiday includes the given date (at UTC midnight)
+func (h Holiday) covers(date time.Time) bool {
+	start, end, err := h.dates()
+	if err != nil {
+		return false
+	}
+	if !h.Recurring {
+		return !date.Before(start) && !date.After(end)
+	}
+	// Shift the holiday into this year and the previous one, so ranges across
+	// New Year match on both sides
+	span := end.Year() - start.Year()
+	for _, year := range []int{date.Year(), date.Year() - 1} {
+		s := time.Date(year, start.Month(), start.Day(), 0, 0, 0, 0, time.UTC)
+		e := time.Date(year+span, end.Month(), end.Day(), 0, 0, 0, 0, time.UTC)
+		if !date.Before(s) && !date.After(e) {
+			return true
+		}
+	}
+	return false
+}
+
+// intervalsOn returns the opening intervals of a date: the hours of the first
+// holiday covering it, else the weekday's hours
+func (s *Schedule) intervalsOn(year int, month time.Month, day int) []Interval {
+	date := time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+	for _, h := range s.Holidays {
+		if h.covers(date) {
+			return h.Intervals
+		}
+	}
+	for _, d := range s.Days {
+		if d.Day == int(date.Weekday()) {
+			return d.OpenIntervals()
+		}
+	}
+	return nil
+}
+
+// span is an opening interval resolved to absolute times
+type span struct {
+	start, end time.Time
+}
+
+// spansOn returns the opening spans starting on the given date, in order
+func (s *Schedule) spansOn(loc *time.Location, year int, month time.Month, day int) []span {
+	var spans []span
+	for _, iv := range s.intervalsOn(year, month, day) {
+		start, end, err := iv.bounds()
+		if err != nil {
+			continue
+		}
+		spans = append(spans, span{
+			start: time.Date(year, month, day, start/60, start%60, 0, 0, loc),
+			end:   time.Date(year, month, day, end/60, end%60, 0, 0, loc),
+		})
+	}
+	slices.SortFunc(spans, func(a, b span) int { return a.start.Compare(b.start) })
+	return spans
+}
+
+// IsOpen reports whether the schedule is open at t
+func (s *Schedule) IsOpen(t time.Time) bool {
+	loc, err := s.Location()
+	if err != nil {
+		return false
+	}
+	t = t.In(loc)
+	year, month, day := t.Date()
+	// Overnight intervals of the previous day may still be open
+	for _, d := range []int{day - 1, day} {
+		for _, sp := range s.spansOn(loc, year, month, d) {
+			if !t.Before(sp.start) && t.Before(sp.end) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// ErrNeverOpens is returned by NextOpening when the schedule has no opening
+// hours within a year
+var ErrNeverOpens = errors.New("the schedule does not open within a year")
+
+// NextOpening returns t when the schedule is open at t, else the time it
+// next opens, in the schedule's timezone
+func (s *Schedule) NextOpening(t time.Time) (time.Time, error) {
+	loc, err := s.Location()
+	if err != nil {
+		return time.Time{}, err
+	}
+	t = t.In(loc)
+	if s.IsOpen(t) {
+		return t, nil
+	}
+	year, month, day := t.Date()
+	for i := 0; i < maxSearchDays; i++ {
+		for _, sp := range s.spansOn(loc, year, month, day+i) {
+			if sp.start.After(t) {
+				return sp.start, nil
+			}
+		}
+	}
+	return time.Time{}, ErrNeverOpens
+}
@@ -0,0 +1,138 @@
+package schedule
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func weekdays(intervals ...Interval) []Day {
+	days := make([]Day, 0, 5)
+	for d := 1; d <= 5; d++ {
+		days = append(days, Day{Day: d, Enabled: true, Intervals: intervals})
+	}
+	return days
+}
+
+func TestIsOpen_SplitShiftsInTimezone(t *testing.T) {
+	s := &Schedule{
+		Timezone: "Asia/Kolkata",
+		Days:     weekdays(Interval{"09:00", "13:00"}, Interval{"14:00", "18:00"}),
+	}
+	require.NoError(t, s.Validate())
+
+	kolkata, _ := time.LoadLocation("Asia/Kolkata")
+	at := func(hour, minute int) time.Time {
+		// Wednesday 2025-01-15 in Kolkata, given in UTC
+		return time.Date(2025, 1, 15, hour, minute, 0, 0, kolkata).UTC()
+	}
+	assert.False(t, s.IsOpen(at(8, 59)))
+	assert.True(t, s.IsOpen(at(9, 0)))
+	assert.False(t, s.IsOpen(at(13, 0)), "the end is exclusive")
+	assert.False(t, s.IsOpen(at(13, 30)), "closed between shifts")
+	assert.True(t, s.IsOpen(at(17, 59)))
+	assert.False(t, s.IsOpen(time.Date(2025, 1, 18, 10, 0, 0, 0, kolkata)), "Saturday is not listed")
+}
+
+func TestIsOpen_Overnight(t *testing.T) {
+	s := &Schedule{Timezone: "UTC", Days: []Day{{Day: 5, Enabled: true, StartTime: "22:00", EndTime: "02:00"}}}
+
+	assert.True(t, s.IsOpen(time.Date(2025, 1, 17, 23, 0, 0, 0, time.UTC)), "Friday night")
+	assert.True(t, s.IsOpen(time.Date(2025, 1, 18, 1, 30, 0, 0, time.UTC)), "spills into Saturday")
+	assert.False(t, s.IsOpen(time.Date(2025, 1, 18, 2, 0, 0, 0, time.UTC)))
+	assert.False(t, s.IsOpen(time.Date(2025, 1, 17, 1, 0, 0, 0, time.UTC)), "Thursday has no overnight shift")
+}
+
+func TestIsOpen_Holidays(t *testing.T) {
+	s := &Schedule{
+		Timezone: "UTC",
+		Days:     weekdays(Interval{"09:00", "17:00"}),
+		Holidays: []Holiday{
+			{Name: "Christmas", Date: "2024-12-25", Recurring: true},
+			{Name: "New Year", Date: "2024-12-31", EndDate: "2025-01-01", Recurring: true},
+			{Name: "Stocktake", Date: "2025-03-05", Intervals: []Interval{{"12:00", "15:00"}}},
+		},
+	}
+	require.NoError(t, s.Validate())
+
+	assert.False(t, s.IsOpen(time.Date(2025, 12, 25, 10, 0, 0, 0, time.UTC)), "recurring holiday")
+	assert.False(t, s.IsOpen(time.Date(2026, 1, 1, 10, 0, 0, 0, time.UTC)), "recurring range across New Year")
+	assert.True(t, s.IsOpen(time.Date(2025, 12, 30, 10, 0, 0, 0, time.UTC)))
+	assert.False(t, s.IsOpen(time.Date(2025, 3, 5, 10, 0, 0, 0, time.UTC)), "special hours replace the weekly hours")
+	assert.True(t, s.IsOpen(time.Date(2025, 3, 5, 12, 30, 0, 0, time.UTC)))
+	assert.True(t, s.IsOpen(time.Date(2026, 3, 5, 10, 0, 0, 0, time.UTC)), "one-off closures do not repeat")
+}
+
+func TestNextOpening(t *testing.T) {
+	s := &Schedule{
+		Timezone: "America/New_York",
+		Days:     weekdays(Interval{"09:00", "12:00"}, Interval{"13:00", "17:00"}),
+		Holidays: []Holiday{{Name: "Holiday", Date: "2025-01-20"}},
+	}
+	ny, _ := time.LoadLocation("America/New_York")
+
+	lunch := time.Date(2025, 1, 15, 12, 30, 0, 0, ny)
+	next, err := s.NextOpening(lunch)
+	require.NoError(t, err)
+	assert.Equal(t, time.Date(2025, 1, 15, 13, 0, 0, 0, ny), next)
+
+	open := time.Date(2025, 1, 15, 10, 0, 0, 0, ny)
+	next, err = s.NextOpening(open)
+	require.NoError(t, err)
+	assert.True(t, next.Equal(open), "returns t when open")
+
+	friday := time.Date(2025, 1, 17, 18, 0, 0, 0, ny)
+	next, err = s.NextOpening(friday)
+	require.NoError(t, err)
+	assert.Equal(t, time.Date(2025, 1, 21, 9, 0, 0, 0, ny), next, "skips the weekend and the Monday holiday")
+	assert.Equal(t, ny, next.Location())
+
+	_, err = (&Schedule{}).NextOpening(friday)
+	assert.ErrorIs(t, err, ErrNeverOpens)
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		schedule Schedule
+		err      string
+	}{
+		{Schedule{Timezone: "Mars/Base"}, `invalid timezone "Mars/Base"`},
+		{Schedule{Days: []Day{{Day: 7}}}, "invalid day 7, expected 0 (Sunday) to 6 (Saturday)"},
+		{Schedule{Days: []Day{{Day: 1}, {Day: 1}}}, "day 1 is listed more than once"},
+		{Schedule{Days: []Day{{Day: 1, Enabled: true}}}, "Monday is enabled but has no hours"},
+		{Schedule{Days: []Day{{Day: 2, Enabled: true, StartTime: "9am", EndTime: "17:00"}}}, `Tuesday: invalid time "9am", expected HH:MM`},
+		{Schedule{Days: []Day{{Day: 2, Enabled: true, StartTime: "09:00", EndTime: "09:00"}}}, "Tuesday: interval 09:00-09:00 is empty"},
+		{Schedule{Holidays: []Holiday{{Date: "25/12/2025"}}}, `invalid holiday date "25/12/2025", expected YYYY-MM-DD`},
+		{Schedule{Holidays: []Holiday{{Date: "2025-12-25", EndDate: "2025-12-24"}}}, "holiday 2025-12-25 ends before it starts"},
+	}
+	for _, tt := range tests {
+		assert.EqualError(t, tt.schedule.Validate(), tt.err)
+	}
+
+	ok := Schedule{Timezone: "Europe/Berlin", Days: []Day{{Day: 0, Enabled: true, StartTime: "00:00", EndTime: "24:00"}, {Day: 1}}}
+	assert.NoError(t, ok.Validate())
+}
+
+func TestParseClock(t *testing.T) {
+	m, err := ParseClock("9:30")
+	require.NoError(t, err)
+	assert.Equal(t, 570, m)
+	m, err = ParseClock("24:00")
+	require.NoError(t, err)
+	assert.Equal(t, 1440, m)
+	for _, s := range []string{"", "24:01", "12:60", "12", "12:5"} {
+		_, err := ParseClock(s)
+		assert.Error(t, err, s)
+	}
+}
+
+func TestDecode(t *testing.T) {
+	var days []Day
+	require.NoError(t, Decode([]interface{}{
+		map[string]interface{}{"day": float64(1), "enabled": true, "start_time": "09:00", "end_time": "17:00"},
+	}, &days))
+	assert.Equal(t, []Day{{Day: 1, Enabled: true, StartTime: "09:00", EndTime: "17:00"}}, days)
+	assert.Equal(t, []Interval{{"09:00", "17:00"}}, days[0].OpenIntervals())
+}
@@ -0,0 +1,56 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/shridarpatil/whatomate/internal/config"
+)
+
+// ErrNotFound is returned when an object does not exist.
+var ErrNotFound = errors.New("object not found")
+
+// ErrInvalidKey is returned for keys that do not name a file inside the store.
+var ErrInvalidKey = errors.New("invalid key")
+
+// ObjectInfo describes a stored object.
+type ObjectInfo struct {
+	Size        int64
+	ContentType string
+}
+
+// Backend stores media objects by key. Keys are slash-separated paths
+// relative to the root of the store, e.g. "images/<uuid>.jpg".
+type Backend interface {
+	// Upload stores the body at key, replacing any existing object.
+	Upload(ctx context.Context, key string, body io.Reader, contentType string) error
+	// Open returns a reader for the object at key. The caller must close it.
+	Open(ctx context.Context, key string) (io.ReadCloser, *ObjectInfo, error)
+	// Delete removes the object at key. Deleting a missing object is not an error.
+	Delete(ctx context.Context, key string) error
+	// Exists reports whether an object exists at key.
+	Exists(ctx context.Context, key string) (bool, error)
+}
+
+// Presigner is implemented by backends that can hand out time-limited
+// download URLs, so clients fetch objects without going through the API.
+// The URL serves the object with the given Content-Type and
+// Content-Disposition instead of the ones stored with it.
+type Presigner interface {
+	GetPresignedDownloadURL(ctx context.Context, key string, expiry time.Duration, contentType, contentDisposition string) (string, error)
+}
+
+// New creates the media storage backend configured by cfg.Type.
+func New(cfg *config.StorageConfig) (Backend, error) {
+	switch cfg.Type {
+	case "", "local":
+		return NewLocal(cfg.LocalPath), nil
+	case "s3":
+		return NewS3Client(cfg)
+	default:
+		return nil, fmt.Errorf("unknown storage type %q", cfg.Type)
+	}
+}
@@ -0,0 +1,154 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"mime"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Local stores objects as files under a root directory.
+type Local struct {
+	root string
+}
+
+// NewLocal creates a backend storing files under root.
+func NewLocal(root string) *Local {
+	if root == "" {
+		root = "./media"
+	}
+	return &Local{root: root}
+}
+
+// Root returns the directory files are stored under.
+func (l *Local) Root() string {
+	return l.root
+}
+
+// path resolves key to a file under the root, rejecting keys that escape it.
+func (l *Local) path(key string) (string, error) {
+	baseDir, err := filepath.Abs(l.root)
+	if err != nil {
+		return "", fmt.Errorf("invalid storage root: %w", err)
+	}
+	fullPath, err := filepath.Abs(filepath.Join(baseDir, filepath.Clean(filepath.FromSlash(key))))
+	if err != nil || !strings.HasPrefix(fullPath, baseDir+string(os.PathSeparator)) {
+		return "", fmt.Errorf("%w %q", ErrInvalidKey, key)
+	}
+	return fullPath, nil
+}
+
+// Upload writes the body to the file for key, creating directories as needed.
+func (l *Local) Upload(_ context.Context, key string, body io.Reader, _ string) error {
+	fullPath, err := l.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(fullPath), 0755); err != nil {
+		return fmt.Errorf("failed to create directory: %w", err)
+	}
+
+	// Write to a temp file first so readers never see a partial file
+	tmp, err := os.CreateTemp(filepath.Dir(fullPath), ".upload-*")
+	if err != nil {
+		return fmt.Errorf("failed to create file: %w", err)
+	}
+	defer func() { _ = os.Remove(tmp.Name()) }()
+
+	if _, err := io.Copy(tmp, body); err != nil {
+		_ = tmp.Close()
+		return fmt.Errorf("failed to write file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write file: %w", err)
+	}
+	if err := os.Chmod(tmp.Name(), 0644); err != nil {
+		return fmt.Errorf("failed to write file: %w", err)
+	}
+	return os.Rename(tmp.Name(), fullPath)
+}
+
+// Open opens the file for key. Symlinks are rejected.
+func (l *Local) Open(_ context.Context, key string) (io.ReadCloser, *ObjectInfo, error) {
+	fullPath, err := l.path(key)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	info, err := os.Lstat(fullPath)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, nil, ErrNotFound
+		}
+		return nil, nil, err
+	}
+	if info.Mode()&os.ModeSymlink != 0 || info.IsDir() {
+		return nil, nil, fmt.Errorf("%w %q", ErrInvalidKey, key)
+	}
+
+	f, err := os.Open(fullPath)
+	if err != nil {
+		return nil, nil, err
+	}
+	return f, &ObjectInfo{
+		Size:        info.Size(),
+		ContentType: mime.TypeByExtension(strings.ToLower(filepath.Ext(fullPath))),
+	}, nil
+}
+
+// Delete removes the file for key.
+func (l *Local) Delete(_ context.Context, key string) error {
+	fullPath, err := l.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(fullPath); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// Exists reports whether a regular file exists for key.
+func (l *Local) Exists(_ context.Context, key string) (bool, error) {
+	fullPath, err := l.path(key)
+	if err != nil {
+		return false, err
+	}
+	info, err := os.Lstat(fullPath)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return false, nil
+		}
+		return false, err
+	}
+	return info.Mode().IsRegular(), nil
+}
+
+// Walk calls fn with the key of every file under the root.
+func (l *Local) Walk(fn func(key string) error) error {
+	baseDir, err := filepath.Abs(l.root)
+	if err != nil {
+		return err
+	}
+	return filepath.WalkDir(baseDir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if path == baseDir && errors.Is(err, fs.ErrNotExist) {
+				return nil
+			}
+			return err
+		}
+		if !d.Type().IsRegular() || strings.HasPrefix(d.Name(), ".") {
+			return nil
+		}
+		rel, err := filepath.Rel(baseDir, path)
+		if err != nil {
+			return err
+		}
+		return fn(filepath.ToSlash(rel))
+	})
+}
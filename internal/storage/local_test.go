@@ -0,0 +1,89 @@
+package storage
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLocal_UploadOpenDelete(t *testing.T) {
+	ctx := context.Background()
+	l := NewLocal(t.TempDir())
+
+	require.NoError(t, l.Upload(ctx, "images/a.jpg", strings.NewReader("hello"), "image/jpeg"))
+
+	exists, err := l.Exists(ctx, "images/a.jpg")
+	require.NoError(t, err)
+	assert.True(t, exists)
+
+	body, info, err := l.Open(ctx, "images/a.jpg")
+	require.NoError(t, err)
+	data, err := io.ReadAll(body)
+	require.NoError(t, body.Close())
+	require.NoError(t, err)
+	assert.Equal(t, "hello", string(data))
+	assert.Equal(t, int64(5), info.Size)
+	assert.Equal(t, "image/jpeg", info.ContentType)
+
+	require.NoError(t, l.Delete(ctx, "images/a.jpg"))
+	require.NoError(t, l.Delete(ctx, "images/a.jpg"), "deleting a missing file is not an error")
+
+	_, _, err = l.Open(ctx, "images/a.jpg")
+	assert.ErrorIs(t, err, ErrNotFound)
+}
+
+func TestLocal_RejectsTraversal(t *testing.T) {
+	ctx := context.Background()
+	root := t.TempDir()
+	l := NewLocal(filepath.Join(root, "media"))
+
+	err := l.Upload(ctx, "../outside.txt", strings.NewReader("x"), "text/plain")
+	assert.ErrorIs(t, err, ErrInvalidKey)
+	_, statErr := os.Stat(filepath.Join(root, "outside.txt"))
+	assert.True(t, os.IsNotExist(statErr))
+
+	_, _, err = l.Open(ctx, "../../etc/passwd")
+	assert.ErrorIs(t, err, ErrInvalidKey)
+}
+
+func TestLocal_RejectsSymlinks(t *testing.T) {
+	ctx := context.Background()
+	root := t.TempDir()
+	secret := filepath.Join(root, "secret.txt")
+	require.NoError(t, os.WriteFile(secret, []byte("secret"), 0600))
+
+	l := NewLocal(filepath.Join(root, "media"))
+	require.NoError(t, os.MkdirAll(l.Root(), 0755))
+	require.NoError(t, os.Symlink(secret, filepath.Join(l.Root(), "link.txt")))
+
+	_, _, err := l.Open(ctx, "link.txt")
+	assert.ErrorIs(t, err, ErrInvalidKey)
+}
+
+func TestLocal_Walk(t *testing.T) {
+	ctx := context.Background()
+	l := NewLocal(t.TempDir())
+	require.NoError(t, l.Upload(ctx, "images/a.jpg", strings.NewReader("a"), ""))
+	require.NoError(t, l.Upload(ctx, "campaigns/b.pdf", strings.NewReader("b"), ""))
+	require.NoError(t, os.WriteFile(filepath.Join(l.Root(), ".hidden"), []byte("c"), 0644))
+
+	var keys []string
+	require.NoError(t, l.Walk(func(key string) error {
+		keys = append(keys, key)
+		return nil
+	}))
+	assert.ElementsMatch(t, []string{"images/a.jpg", "campaigns/b.pdf"}, keys)
+
+	// A missing root has no files
+	missing := NewLocal(filepath.Join(t.TempDir(), "missing"))
+	assert.NoError(t, missing.Walk(func(string) error {
+		t.Fatal("unexpected file")
+		return nil
+	}))
+}
@@ -2,6 +2,7 @@ package storage
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"time"
@@ -9,23 +10,29 @@ import (
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/credentials"
 	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
 	"github.com/shridarpatil/whatomate/internal/config"
 )
 
-// S3Client provides upload and presigned URL operations for call recordings.
+// S3Client stores objects in an S3-compatible bucket (AWS S3, MinIO, ...).
 type S3Client struct {
 	client *s3.Client
 	bucket string
 }
 
 // NewS3Client creates a new S3 client from the application's StorageConfig.
+// Set S3Endpoint (and usually S3UsePathStyle) for S3-compatible stores.
 func NewS3Client(cfg *config.StorageConfig) (*S3Client, error) {
 	if cfg.S3Bucket == "" || cfg.S3Region == "" {
 		return nil, fmt.Errorf("s3_bucket and s3_region are required")
 	}
 
 	opts := s3.Options{
-		Region: cfg.S3Region,
+		Region:       cfg.S3Region,
+		UsePathStyle: cfg.S3UsePathStyle,
+	}
+	if cfg.S3Endpoint != "" {
+		opts.BaseEndpoint = aws.String(cfg.S3Endpoint)
 	}
 
 	if cfg.S3Key != "" && cfg.S3Secret != "" {
@@ -47,13 +54,71 @@ func (s *S3Client) Upload(ctx context.Context, key string, body io.Reader, conte
 	return err
 }
 
+// Open returns a reader for the object at key.
+func (s *S3Client) Open(ctx context.Context, key string) (io.ReadCloser, *ObjectInfo, error) {
+	out, err := s.client.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		var noSuchKey *types.NoSuchKey
+		if errors.As(err, &noSuchKey) {
+			return nil, nil, ErrNotFound
+		}
+		return nil, nil, err
+	}
+	return out.Body, &ObjectInfo{
+		Size:        aws.ToInt64(out.ContentLength),
+		ContentType: aws.ToString(out.ContentType),
+	}, nil
+}
+
+// Delete removes the object at key.
+func (s *S3Client) Delete(ctx context.Context, key string) error {
+	_, err := s.client.DeleteObject(ctx, &s3.DeleteObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(key),
+	})
+	return err
+}
+
+// Exists reports whether an object exists at key.
+func (s *S3Client) Exists(ctx context.Context, key string) (bool, error) {
+	_, err := s.client.HeadObject(ctx, &s3.HeadObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		var notFound *types.NotFound
+		if errors.As(err, &notFound) {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
+
 // GetPresignedURL returns a time-limited download URL for the given S3 key.
 func (s *S3Client) GetPresignedURL(ctx context.Context, key string, expiry time.Duration) (string, error) {
-	presigner := s3.NewPresignClient(s.client)
-	req, err := presigner.PresignGetObject(ctx, &s3.GetObjectInput{
+	return s.GetPresignedDownloadURL(ctx, key, expiry, "", "")
+}
+
+// GetPresignedDownloadURL returns a time-limited download URL for the given
+// S3 key that overrides the response headers when they are not empty.
+func (s *S3Client) GetPresignedDownloadURL(ctx context.Context, key string, expiry time.Duration, contentType, contentDisposition string) (string, error) {
+	input := &s3.GetObjectInput{
 		Bucket: aws.String(s.bucket),
 		Key:    aws.String(key),
-	}, s3.WithPresignExpires(expiry))
+	}
+	if contentType != "" {
+		input.ResponseContentType = aws.String(contentType)
+	}
+	if contentDisposition != "" {
+		input.ResponseContentDisposition = aws.String(contentDisposition)
+	}
+
+	presigner := s3.NewPresignClient(s.client)
+	req, err := presigner.PresignGetObject(ctx, input, s3.WithPresignExpires(expiry))
 	if err != nil {
 		return "", err
 	}
@@ -0,0 +1,289 @@
+package webhookutil
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+	"github.com/shridarpatil/whatomate/internal/models"
+	"github.com/shridarpatil/whatomate/internal/queue"
+	"gorm.io/gorm"
+)
+
+// Payload represents the structure sent to external webhook endpoints
+type Payload struct {
+	Event     string      `json:"event"`
+	Timestamp time.Time   `json:"timestamp"`
+	Data      interface{} `json:"data"`
+}
+
+// NewPayload wraps event data in the outbound envelope
+func NewPayload(event models.WebhookEvent, data interface{}) Payload {
+	return Payload{
+		Event:     string(event),
+		Timestamp: time.Now().UTC(),
+		Data:      data,
+	}
+}
+
+// MessageEventData represents data for message events
+type MessageEventData struct {
+	MessageID       string             `json:"message_id"`
+	ContactID       string             `json:"contact_id"`
+	ContactPhone    string             `json:"contact_phone"`
+	ContactName     string             `json:"contact_name"`
+	MessageType     models.MessageType `json:"message_type"`
+	Content         string             `json:"content"`
+	WhatsAppAccount string             `json:"whatsapp_account"`
+	Direction       models.Direction   `json:"direction,omitempty"`
+	SentByUserID    string             `json:"sent_by_user_id,omitempty"`
+}
+
+// MessageStatusEventData represents data for message.status events
+type MessageStatusEventData struct {
+	MessageID         string               `json:"message_id"`
+	WhatsAppMessageID string               `json:"whatsapp_message_id"`
+	ContactID         string               `json:"contact_id"`
+	Status            models.MessageStatus `json:"status"`
+	ErrorCode         int                  `json:"error_code,omitempty"`
+	ErrorTitle        string               `json:"error_title,omitempty"`
+	ErrorMessage      string               `json:"error_message,omitempty"`
+	CampaignID        string               `json:"campaign_id,omitempty"`
+	WhatsAppAccount   string               `json:"whatsapp_account"`
+}
+
+// ContactEventData represents data for contact events
+type ContactEventData struct {
+	ContactID       string `json:"contact_id"`
+	ContactPhone    string `json:"contact_phone"`
+	ContactName     string `json:"contact_name"`
+	WhatsAppAccount string `json:"whatsapp_account"`
+}
+
+// ContactTaggedEventData represents data for contact.tagged events
+type ContactTaggedEventData struct {
+	ContactID    string   `json:"contact_id"`
+	ContactPhone string   `json:"contact_phone"`
+	ContactName  string   `json:"contact_name"`
+	Tags         []string `json:"tags"`
+	Added        []string `json:"added"`
+	Removed      []string `json:"removed"`
+	UserID       string   `json:"user_id,omitempty"`
+}
+
+// ContactAssignedEventData represents data for contact.assigned events.
+// AssignedUserID is empty when the contact was unassigned.
+type ContactAssignedEventData struct {
+	ContactID        string `json:"contact_id"`
+	ContactPhone     string `json:"contact_phone"`
+	ContactName      string `json:"contact_name"`
+	AssignedUserID   string `json:"assigned_user_id,omitempty"`
+	AssignedUserName string `json:"assigned_user_name,omitempty"`
+	PreviousUserID   string `json:"previous_user_id,omitempty"`
+	AssignedByUserID string `json:"assigned_by_user_id,omitempty"`
+}
+
+// NoteEventData represents data for note events
+type NoteEventData struct {
+	NoteID        string `json:"note_id"`
+	ContactID     string `json:"contact_id"`
+	ContactPhone  string `json:"contact_phone"`
+	ContactName   string `json:"contact_name"`
+	Content       string `json:"content"`
+	CreatedByID   string `json:"created_by_id"`
+	CreatedByName string `json:"created_by_name"`
+}
+
+// TransferEventData represents data for transfer events
+type TransferEventData struct {
+	TransferID      string                  `json:"transfer_id"`
+	ContactID       string                  `json:"contact_id"`
+	ContactPhone    string                  `json:"contact_phone"`
+	ContactName     string                  `json:"contact_name"`
+	Source          models.TransferSource   `json:"source"`
+	Priority        models.TransferPriority `json:"priority"`
+	Reason          string                  `json:"reason,omitempty"`
+	AgentID         *string                 `json:"agent_id,omitempty"`
+	AgentName       *string                 `json:"agent_name,omitempty"`
+	WhatsAppAccount string                  `json:"whatsapp_account"`
+}
+
+// TransferSLAEscalatedEventData represents data for transfer.sla_escalated
+// events
+type TransferSLAEscalatedEventData struct {
+	TransferEventData
+	SLAPolicyID        string `json:"sla_policy_id"`
+	SLAPolicyName      string `json:"sla_policy_name"`
+	Tier               int    `json:"tier"`
+	ElapsedMinutes     int    `json:"elapsed_minutes"`
+	ResponseBreached   bool   `json:"response_breached"`
+	ResolutionBreached bool   `json:"resolution_breached"`
+}
+
+// ConversationEventData represents data for conversation.status_changed
+// events
+type ConversationEventData struct {
+	ConversationID   string  `json:"conversation_id"`
+	ContactID        string  `json:"contact_id"`
+	ContactPhone     string  `json:"contact_phone"`
+	ContactName      string  `json:"contact_name"`
+	Status           string  `json:"status"`
+	PreviousStatus   string  `json:"previous_status,omitempty"` // Empty for a new conversation
+	SnoozedUntil     *string `json:"snoozed_until,omitempty"`
+	ResolutionReason string  `json:"resolution_reason,omitempty"`
+	ChangedByUserID  *string `json:"changed_by_user_id,omitempty"` // nil for automatic changes
+	WhatsAppAccount  string  `json:"whatsapp_account,omitempty"`
+}
+
+// CampaignEventData represents data for campaign events
+type CampaignEventData struct {
+	CampaignID      string                 `json:"campaign_id"`
+	Name            string                 `json:"name"`
+	Status          models.CampaignStatus  `json:"status"`
+	TemplateID      string                 `json:"template_id"`
+	TotalRecipients int                    `json:"total_recipients"`
+	SentCount       int                    `json:"sent_count"`
+	DeliveredCount  int                    `json:"delivered_count"`
+	ReadCount       int                    `json:"read_count"`
+	FailedCount     int                    `json:"failed_count"`
+	StartTrigger    models.CampaignTrigger `json:"start_trigger,omitempty"`
+	StartedBy       string                 `json:"started_by,omitempty"`
+	StartedAt       *time.Time             `json:"started_at,omitempty"`
+	CompletedAt     *time.Time             `json:"completed_at,omitempty"`
+	WhatsAppAccount string                 `json:"whatsapp_account"`
+}
+
+// NewCampaignEventData builds campaign event data from a campaign
+func NewCampaignEventData(campaign *models.BulkMessageCampaign) CampaignEventData {
+	data := CampaignEventData{
+		CampaignID:      campaign.ID.String(),
+		Name:            campaign.Name,
+		Status:          campaign.Status,
+		TemplateID:      campaign.TemplateID.String(),
+		TotalRecipients: campaign.TotalRecipients,
+		SentCount:       campaign.SentCount,
+		DeliveredCount:  campaign.DeliveredCount,
+		ReadCount:       campaign.ReadCount,
+		FailedCount:     campaign.FailedCount,
+		StartTrigger:    campaign.StartTrigger,
+		StartedAt:       campaign.StartedAt,
+		CompletedAt:     campaign.CompletedAt,
+		WhatsAppAccount: campaign.WhatsAppAccount,
+	}
+	if campaign.StartedBy != nil {
+		data.StartedBy = campaign.StartedBy.String()
+	}
+	return data
+}
+
+// CallEventData represents data for call events. Duration and the recording
+// fields are only set on call.ended.
+type CallEventData struct {
+	CallLogID         string                `json:"call_log_id"`
+	WhatsAppCallID    string                `json:"whatsapp_call_id"`
+	ContactID         string                `json:"contact_id"`
+	CallerPhone       string                `json:"caller_phone"`
+	Direction         models.CallDirection  `json:"direction"`
+	Status            models.CallStatus     `json:"status"`
+	AgentID           string                `json:"agent_id,omitempty"`
+	Duration          int                   `json:"duration"`
+	RecordingS3Key    string                `json:"recording_s3_key,omitempty"`
+	RecordingDuration int                   `json:"recording_duration,omitempty"`
+	DisconnectedBy    models.DisconnectedBy `json:"disconnected_by,omitempty"`
+	StartedAt         *time.Time            `json:"started_at,omitempty"`
+	AnsweredAt        *time.Time            `json:"answered_at,omitempty"`
+	EndedAt           *time.Time            `json:"ended_at,omitempty"`
+	WhatsAppAccount   string                `json:"whatsapp_account"`
+}
+
+// NewCallEventData builds call event data from a call log
+func NewCallEventData(callLog *models.CallLog) CallEventData {
+	data := CallEventData{
+		CallLogID:         callLog.ID.String(),
+		WhatsAppCallID:    callLog.WhatsAppCallID,
+		ContactID:         callLog.ContactID.String(),
+		CallerPhone:       callLog.CallerPhone,
+		Direction:         callLog.Direction,
+		Status:            callLog.Status,
+		Duration:          callLog.Duration,
+		RecordingS3Key:    callLog.RecordingS3Key,
+		RecordingDuration: callLog.RecordingDuration,
+		DisconnectedBy:    callLog.DisconnectedBy,
+		StartedAt:         callLog.StartedAt,
+		AnsweredAt:        callLog.AnsweredAt,
+		EndedAt:           callLog.EndedAt,
+		WhatsAppAccount:   callLog.WhatsAppAccount,
+	}
+	if callLog.AgentID != nil {
+		data.AgentID = callLog.AgentID.String()
+	}
+	return data
+}
+
+// ChatbotSessionEventData represents data for chatbot session events
+type ChatbotSessionEventData struct {
+	SessionID       string                 `json:"session_id"`
+	ContactID       string                 `json:"contact_id"`
+	ContactPhone    string                 `json:"contact_phone"`
+	FlowID          string                 `json:"flow_id,omitempty"`
+	FlowName        string                 `json:"flow_name,omitempty"`
+	Status          models.SessionStatus   `json:"status"`
+	SessionData     map[string]interface{} `json:"session_data"`
+	StartedAt       time.Time              `json:"started_at"`
+	CompletedAt     *time.Time             `json:"completed_at,omitempty"`
+	WhatsAppAccount string                 `json:"whatsapp_account"`
+}
+
+// TemplateStatusEventData represents data for template.status_changed events
+type TemplateStatusEventData struct {
+	TemplateID      string `json:"template_id"`
+	MetaTemplateID  string `json:"meta_template_id"`
+	Name            string `json:"name"`
+	Language        string `json:"language"`
+	PreviousStatus  string `json:"previous_status"`
+	Status          string `json:"status"`
+	Reason          string `json:"reason,omitempty"`
+	WhatsAppAccount string `json:"whatsapp_account"`
+}
+
+// Dispatch records a delivery of an event to every active webhook of the
+// organization subscribed to it and queues them for the delivery workers.
+// It is used by processes that do not deliver webhooks themselves, such as
+// the campaign workers. Returns the number of deliveries queued.
+func Dispatch(ctx context.Context, db *gorm.DB, rdb *redis.Client, orgID uuid.UUID, event models.WebhookEvent, data interface{}) (int, error) {
+	var webhooks []models.Webhook
+	if err := db.Where("organization_id = ? AND is_active = ?", orgID, true).Find(&webhooks).Error; err != nil {
+		return 0, fmt.Errorf("failed to load webhooks: %w", err)
+	}
+
+	var body []byte
+	d := &Deliverer{DB: db, Redis: rdb}
+	queued := 0
+	for i := range webhooks {
+		webhook := &webhooks[i]
+		if !slices.Contains(webhook.Events, string(event)) {
+			continue
+		}
+		if body == nil {
+			var err error
+			if body, err = json.Marshal(NewPayload(event, data)); err != nil {
+				return queued, fmt.Errorf("failed to marshal webhook payload: %w", err)
+			}
+		}
+
+		delivery, err := d.Enqueue(webhook, string(event), body)
+		if err != nil {
+			return queued, err
+		}
+		// Deliveries missing from the queue are still picked up by the sweeper
+		if err := queue.ScheduleWebhookDelivery(ctx, rdb, delivery.ID, *delivery.NextAttemptAt); err != nil {
+			return queued, err
+		}
+		queued++
+	}
+	return queued, nil
+}
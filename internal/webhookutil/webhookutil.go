@@ -0,0 +1,375 @@
+// Package webhookutil delivers outbound webhooks. Every delivery is persisted
+// as a models.WebhookDelivery row; failed attempts are rescheduled on a Redis
+// sorted set that workers drain, following a configurable retry schedule.
+// Endpoints whose deliveries keep exhausting their retries are disabled.
+package webhookutil
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+	"github.com/shridarpatil/whatomate/internal/config"
+	"github.com/shridarpatil/whatomate/internal/models"
+	"github.com/shridarpatil/whatomate/internal/queue"
+	"github.com/zerodha/logf"
+	"gorm.io/gorm"
+)
+
+const (
+	// CacheKeyPrefix prefixes the per-organization active webhooks cache
+	CacheKeyPrefix = "webhooks:"
+
+	// UserAgent is sent with every webhook request
+	UserAgent = "Whatomate-Webhook/1.0"
+
+	// MaxResponseBody caps how much of an endpoint's response body is stored
+	MaxResponseBody = 64 * 1024
+
+	// AttemptLease is how long a claimed delivery is held before another
+	// worker may retry it (covers attempts interrupted by a crash)
+	AttemptLease = 2 * time.Minute
+)
+
+// DefaultRetrySchedule is used when no retry schedule is configured
+var DefaultRetrySchedule = []time.Duration{
+	time.Minute, 5 * time.Minute, 30 * time.Minute, 2 * time.Hour, 6 * time.Hour, 12 * time.Hour,
+}
+
+// ErrNotDue is returned by Deliver when the delivery is not pending, not yet
+// due, or already claimed by another worker
+var ErrNotDue = errors.New("webhook delivery is not due")
+
+// Policy controls retries and automatic disabling of failing endpoints
+type Policy struct {
+	RetrySchedule []time.Duration // Delay before each retry; its length is the retry count
+	DisableAfter  int             // Consecutive failed deliveries before disabling; 0 or less never
+}
+
+// NewPolicy builds a delivery policy from config
+func NewPolicy(cfg config.WebhooksConfig) (Policy, error) {
+	policy := Policy{
+		RetrySchedule: DefaultRetrySchedule,
+		DisableAfter:  cfg.DisableAfterFailures,
+	}
+	if len(cfg.RetrySchedule) == 0 {
+		return policy, nil
+	}
+
+	schedule := make([]time.Duration, 0, len(cfg.RetrySchedule))
+	for _, s := range cfg.RetrySchedule {
+		d, err := time.ParseDuration(s)
+		if err != nil || d <= 0 {
+			return policy, fmt.Errorf("invalid webhook retry delay %q", s)
+		}
+		schedule = append(schedule, d)
+	}
+	policy.RetrySchedule = schedule
+	return policy, nil
+}
+
+// NextRetry returns the delay before the retry that follows the given number
+// of attempts, or false when retries are exhausted
+func (p Policy) NextRetry(attempts int) (time.Duration, bool) {
+	if attempts < 1 || attempts > len(p.RetrySchedule) {
+		return 0, false
+	}
+	return p.RetrySchedule[attempts-1], true
+}
+
+// Sign returns the X-Webhook-Signature value for a body
+func Sign(body []byte, secret string) string {
+	h := hmac.New(sha256.New, []byte(secret))
+	h.Write(body)
+	return "sha256=" + hex.EncodeToString(h.Sum(nil))
+}
+
+// NewRequest builds the signed POST request for a webhook. deliveryID may be
+// empty for requests that are not persisted (e.g. test events).
+func NewRequest(ctx context.Context, webhook *models.Webhook, event, deliveryID string, body []byte) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhook.URL, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", UserAgent)
+
+	// Add custom headers from webhook config
+	for key, value := range webhook.Headers {
+		if strValue, ok := value.(string); ok {
+			req.Header.Set(key, strValue)
+		}
+	}
+
+	req.Header.Set("X-Webhook-Event", event)
+	if deliveryID != "" {
+		req.Header.Set("X-Webhook-Delivery", deliveryID)
+	}
+	if webhook.Secret != "" {
+		req.Header.Set("X-Webhook-Signature", Sign(body, webhook.Secret))
+	}
+
+	return req, nil
+}
+
+// Deliverer persists and sends webhook deliveries
+type Deliverer struct {
+	DB         *gorm.DB
+	Redis      *redis.Client
+	HTTPClient *http.Client
+	Log        logf.Logger
+	Policy     Policy
+}
+
+// Enqueue records a pending delivery of an event to a webhook. The delivery is
+// due immediately; callers either attempt it straight away with Deliver or
+// leave it to the workers.
+func (d *Deliverer) Enqueue(webhook *models.Webhook, event string, body []byte) (*models.WebhookDelivery, error) {
+	return d.enqueue(webhook, event, body, nil)
+}
+
+// Redeliver records a new delivery with the same event and body as an earlier
+// one and attempts it immediately
+func (d *Deliverer) Redeliver(ctx context.Context, webhook *models.Webhook, original *models.WebhookDelivery) (*models.WebhookDelivery, error) {
+	delivery, err := d.enqueue(webhook, original.Event, []byte(original.RequestBody), &original.ID)
+	if err != nil {
+		return nil, err
+	}
+	return d.Deliver(ctx, delivery.ID)
+}
+
+func (d *Deliverer) enqueue(webhook *models.Webhook, event string, body []byte, redeliveryOf *uuid.UUID) (*models.WebhookDelivery, error) {
+	now := time.Now()
+	delivery := &models.WebhookDelivery{
+		OrganizationID: webhook.OrganizationID,
+		WebhookID:      webhook.ID,
+		Event:          event,
+		Status:         models.WebhookDeliveryPending,
+		URL:            webhook.URL,
+		RequestBody:    string(body),
+		NextAttemptAt:  &now,
+		RedeliveryOf:   redeliveryOf,
+	}
+	if err := d.DB.Create(delivery).Error; err != nil {
+		return nil, fmt.Errorf("failed to create webhook delivery: %w", err)
+	}
+	return delivery, nil
+}
+
+// Deliver makes one attempt at a pending, due delivery and records the
+// outcome. Failed attempts are rescheduled according to the policy; once the
+// schedule is exhausted the delivery is marked failed and counted against the
+// endpoint. Returns ErrNotDue if the delivery could not be claimed.
+func (d *Deliverer) Deliver(ctx context.Context, deliveryID uuid.UUID) (*models.WebhookDelivery, error) {
+	now := time.Now()
+
+	// Claim the attempt by pushing next_attempt_at past the lease, so that a
+	// concurrent worker (or the sweeper) skips it until this attempt is done
+	leaseUntil := now.Add(AttemptLease)
+	result := d.DB.Model(&models.WebhookDelivery{}).
+		Where("id = ? AND status = ? AND next_attempt_at <= ?", deliveryID, models.WebhookDeliveryPending, now).
+		Update("next_attempt_at", leaseUntil)
+	if result.Error != nil {
+		return nil, fmt.Errorf("failed to claim webhook delivery: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return nil, ErrNotDue
+	}
+
+	var delivery models.WebhookDelivery
+	if err := d.DB.Where("id = ?", deliveryID).First(&delivery).Error; err != nil {
+		return nil, fmt.Errorf("failed to load webhook delivery: %w", err)
+	}
+
+	// Always load the endpoint fresh: cached webhooks omit the secret and may
+	// be stale after the endpoint was disabled or edited
+	var webhook models.Webhook
+	if err := d.DB.Where("id = ?", delivery.WebhookID).First(&webhook).Error; err != nil {
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			d.release(&delivery)
+			return nil, fmt.Errorf("failed to load webhook: %w", err)
+		}
+		return d.abandon(&delivery, "webhook was deleted")
+	}
+	if !webhook.IsActive {
+		return d.abandon(&delivery, "webhook is disabled")
+	}
+
+	d.attempt(ctx, &webhook, &delivery)
+
+	if delivery.Error == "" {
+		delivery.Status = models.WebhookDeliveryDelivered
+		delivery.DeliveredAt = delivery.LastAttemptAt
+		delivery.NextAttemptAt = nil
+	} else if delay, ok := d.Policy.NextRetry(delivery.Attempts); ok {
+		next := time.Now().Add(delay)
+		delivery.NextAttemptAt = &next
+	} else {
+		delivery.Status = models.WebhookDeliveryFailed
+		delivery.NextAttemptAt = nil
+	}
+
+	if err := d.DB.Save(&delivery).Error; err != nil {
+		return nil, fmt.Errorf("failed to save webhook delivery: %w", err)
+	}
+
+	switch delivery.Status {
+	case models.WebhookDeliveryDelivered:
+		d.recordSuccess(&webhook)
+	case models.WebhookDeliveryFailed:
+		d.Log.Warn("Webhook delivery failed after all retries",
+			"webhook_id", webhook.ID, "delivery_id", delivery.ID, "event", delivery.Event, "attempts", delivery.Attempts)
+		d.recordFailure(&webhook)
+	default:
+		if err := queue.ScheduleWebhookDelivery(context.Background(), d.Redis, delivery.ID, *delivery.NextAttemptAt); err != nil {
+			// The sweeper picks up pending deliveries missing from the queue
+			d.Log.Error("Failed to schedule webhook retry", "error", err, "delivery_id", delivery.ID)
+		}
+	}
+
+	return &delivery, nil
+}
+
+// attempt sends the delivery once and records the request and response on it
+func (d *Deliverer) attempt(ctx context.Context, webhook *models.Webhook, delivery *models.WebhookDelivery) {
+	start := time.Now()
+	delivery.Attempts++
+	delivery.LastAttemptAt = &start
+	delivery.URL = webhook.URL
+	delivery.ResponseStatus = 0
+	delivery.ResponseBody = ""
+	delivery.Error = ""
+
+	req, err := NewRequest(ctx, webhook, delivery.Event, delivery.ID.String(), []byte(delivery.RequestBody))
+	if err != nil {
+		delivery.Error = err.Error()
+		return
+	}
+
+	headers := models.JSONB{}
+	for key := range req.Header {
+		headers[key] = req.Header.Get(key)
+	}
+	delivery.RequestHeaders = headers
+
+	resp, err := d.HTTPClient.Do(req)
+	delivery.DurationMs = time.Since(start).Milliseconds()
+	if err != nil {
+		delivery.Error = err.Error()
+		return
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, MaxResponseBody))
+	delivery.ResponseStatus = resp.StatusCode
+	delivery.ResponseBody = string(bytes.ToValidUTF8(body, nil))
+	delivery.DurationMs = time.Since(start).Milliseconds()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		delivery.Error = "webhook returned non-2xx status: " + http.StatusText(resp.StatusCode)
+	}
+}
+
+// abandon marks a delivery failed without attempting it
+func (d *Deliverer) abandon(delivery *models.WebhookDelivery, reason string) (*models.WebhookDelivery, error) {
+	delivery.Status = models.WebhookDeliveryFailed
+	delivery.Error = reason
+	delivery.NextAttemptAt = nil
+	if err := d.DB.Save(delivery).Error; err != nil {
+		return nil, fmt.Errorf("failed to save webhook delivery: %w", err)
+	}
+	return delivery, nil
+}
+
+// release gives up a claim so the delivery is retried by the sweeper
+func (d *Deliverer) release(delivery *models.WebhookDelivery) {
+	now := time.Now()
+	d.DB.Model(delivery).Update("next_attempt_at", now)
+}
+
+// recordSuccess resets the endpoint's consecutive failure count
+func (d *Deliverer) recordSuccess(webhook *models.Webhook) {
+	if webhook.FailureCount == 0 {
+		return
+	}
+	d.DB.Model(&models.Webhook{}).Where("id = ?", webhook.ID).Update("failure_count", 0)
+}
+
+// recordFailure counts an exhausted delivery against the endpoint and
+// disables it once the policy threshold is reached
+func (d *Deliverer) recordFailure(webhook *models.Webhook) {
+	if err := d.DB.Model(&models.Webhook{}).Where("id = ?", webhook.ID).
+		Update("failure_count", gorm.Expr("failure_count + 1")).Error; err != nil {
+		d.Log.Error("Failed to record webhook failure", "error", err, "webhook_id", webhook.ID)
+		return
+	}
+	if d.Policy.DisableAfter <= 0 {
+		return
+	}
+
+	var failures int
+	d.DB.Model(&models.Webhook{}).Where("id = ?", webhook.ID).Select("failure_count").Scan(&failures)
+	if failures < d.Policy.DisableAfter {
+		return
+	}
+
+	now := time.Now()
+	reason := fmt.Sprintf("Disabled after %d consecutive failed deliveries", failures)
+	result := d.DB.Model(&models.Webhook{}).Where("id = ? AND is_active = ?", webhook.ID, true).
+		Updates(map[string]any{
+			"is_active":       false,
+			"disabled_at":     now,
+			"disabled_reason": reason,
+		})
+	if result.Error != nil {
+		d.Log.Error("Failed to disable webhook", "error", result.Error, "webhook_id", webhook.ID)
+		return
+	}
+	if result.RowsAffected > 0 {
+		d.Log.Warn("Webhook disabled after repeated failures", "webhook_id", webhook.ID, "failures", failures)
+		d.Redis.Del(context.Background(), CacheKeyPrefix+webhook.OrganizationID.String())
+	}
+}
+
+// RequeueDue adds pending deliveries that are due to the Redis queue. It
+// recovers retries whose queue entry was lost and attempts interrupted
+// before they finished. Returns the number of deliveries queued.
+func (d *Deliverer) RequeueDue(ctx context.Context, now time.Time, limit int) (int, error) {
+	var deliveries []models.WebhookDelivery
+	if err := d.DB.Select("id", "next_attempt_at").
+		Where("status = ? AND next_attempt_at <= ?", models.WebhookDeliveryPending, now).
+		Order("next_attempt_at ASC").
+		Limit(limit).
+		Find(&deliveries).Error; err != nil {
+		return 0, fmt.Errorf("failed to load due webhook deliveries: %w", err)
+	}
+
+	for _, delivery := range deliveries {
+		if err := queue.ScheduleWebhookDelivery(ctx, d.Redis, delivery.ID, *delivery.NextAttemptAt); err != nil {
+			return 0, err
+		}
+	}
+	return len(deliveries), nil
+}
+
+// Prune deletes finished deliveries created before the cutoff. Returns the
+// number of rows deleted.
+func (d *Deliverer) Prune(before time.Time) (int64, error) {
+	result := d.DB.Unscoped().
+		Where("created_at < ? AND status <> ?", before, models.WebhookDeliveryPending).
+		Delete(&models.WebhookDelivery{})
+	if result.Error != nil {
+		return 0, fmt.Errorf("failed to prune webhook deliveries: %w", result.Error)
+	}
+	return result.RowsAffected, nil
+}
@@ -0,0 +1,221 @@
+package webhookutil
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shridarpatil/whatomate/internal/config"
+	"github.com/shridarpatil/whatomate/internal/models"
+	"github.com/shridarpatil/whatomate/internal/queue"
+	"github.com/shridarpatil/whatomate/test/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewPolicy(t *testing.T) {
+	policy, err := NewPolicy(config.WebhooksConfig{RetrySchedule: []string{"30s", "2h"}, DisableAfterFailures: 3})
+	require.NoError(t, err)
+	assert.Equal(t, []time.Duration{30 * time.Second, 2 * time.Hour}, policy.RetrySchedule)
+	assert.Equal(t, 3, policy.DisableAfter)
+
+	policy, err = NewPolicy(config.WebhooksConfig{})
+	require.NoError(t, err)
+	assert.Equal(t, DefaultRetrySchedule, policy.RetrySchedule)
+
+	// Invalid entries fall back to the default schedule
+	policy, err = NewPolicy(config.WebhooksConfig{RetrySchedule: []string{"1m", "soon"}})
+	assert.Error(t, err)
+	assert.Equal(t, DefaultRetrySchedule, policy.RetrySchedule)
+}
+
+func TestPolicy_NextRetry(t *testing.T) {
+	policy := Policy{RetrySchedule: []time.Duration{time.Minute, time.Hour}}
+
+	d, ok := policy.NextRetry(1)
+	assert.True(t, ok)
+	assert.Equal(t, time.Minute, d)
+
+	d, ok = policy.NextRetry(2)
+	assert.True(t, ok)
+	assert.Equal(t, time.Hour, d)
+
+	_, ok = policy.NextRetry(3)
+	assert.False(t, ok)
+}
+
+func TestNewRequest_Headers(t *testing.T) {
+	body := []byte(`{"event":"message.incoming"}`)
+	webhook := &models.Webhook{
+		URL:     "https://example.com/hook",
+		Headers: models.JSONB{"Authorization": "Bearer abc"},
+		Secret:  "s3cret",
+	}
+
+	req, err := NewRequest(context.Background(), webhook, "message.incoming", "d-1", body)
+	require.NoError(t, err)
+	assert.Equal(t, "application/json", req.Header.Get("Content-Type"))
+	assert.Equal(t, UserAgent, req.Header.Get("User-Agent"))
+	assert.Equal(t, "Bearer abc", req.Header.Get("Authorization"))
+	assert.Equal(t, "message.incoming", req.Header.Get("X-Webhook-Event"))
+	assert.Equal(t, "d-1", req.Header.Get("X-Webhook-Delivery"))
+	assert.Equal(t, Sign(body, "s3cret"), req.Header.Get("X-Webhook-Signature"))
+}
+
+func newTestDeliverer(t *testing.T, policy Policy) *Deliverer {
+	t.Helper()
+	db := testutil.SetupTestDB(t)
+	rdb := testutil.SetupTestRedis(t)
+	if rdb == nil {
+		t.Skip("TEST_REDIS_URL not set, skipping test")
+	}
+	return &Deliverer{
+		DB:         db,
+		Redis:      rdb,
+		HTTPClient: &http.Client{Timeout: 5 * time.Second},
+		Log:        testutil.NopLogger(),
+		Policy:     policy,
+	}
+}
+
+func createWebhook(t *testing.T, d *Deliverer, url string) *models.Webhook {
+	t.Helper()
+	org := testutil.CreateTestOrganization(t, d.DB)
+	webhook := &models.Webhook{
+		BaseModel:      models.BaseModel{ID: uuid.New()},
+		OrganizationID: org.ID,
+		Name:           "hook",
+		URL:            url,
+		Events:         models.StringArray{"message.incoming"},
+		IsActive:       true,
+	}
+	require.NoError(t, d.DB.Create(webhook).Error)
+	return webhook
+}
+
+// makeDue rewinds a pending delivery so it can be attempted again now
+func makeDue(t *testing.T, d *Deliverer, id uuid.UUID) {
+	t.Helper()
+	require.NoError(t, d.DB.Model(&models.WebhookDelivery{}).Where("id = ?", id).
+		Update("next_attempt_at", time.Now().Add(-time.Second)).Error)
+}
+
+func TestDeliverer_RetriesThenDisables(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	d := newTestDeliverer(t, Policy{RetrySchedule: []time.Duration{time.Minute}, DisableAfter: 1})
+	webhook := createWebhook(t, d, server.URL)
+	ctx := context.Background()
+
+	delivery, err := d.Enqueue(webhook, "message.incoming", []byte(`{}`))
+	require.NoError(t, err)
+
+	// First attempt fails and schedules a retry
+	delivery, err = d.Deliver(ctx, delivery.ID)
+	require.NoError(t, err)
+	assert.Equal(t, models.WebhookDeliveryPending, delivery.Status)
+	assert.Equal(t, http.StatusServiceUnavailable, delivery.ResponseStatus)
+	require.NotNil(t, delivery.NextAttemptAt)
+	score, err := d.Redis.ZScore(ctx, queue.WebhookDeliveriesKey, delivery.ID.String()).Result()
+	require.NoError(t, err)
+	assert.Equal(t, float64(delivery.NextAttemptAt.UnixMilli()), score)
+	d.Redis.ZRem(ctx, queue.WebhookDeliveriesKey, delivery.ID.String())
+
+	// Not due yet
+	_, err = d.Deliver(ctx, delivery.ID)
+	assert.ErrorIs(t, err, ErrNotDue)
+
+	// The retry exhausts the schedule and disables the endpoint
+	makeDue(t, d, delivery.ID)
+	delivery, err = d.Deliver(ctx, delivery.ID)
+	require.NoError(t, err)
+	assert.Equal(t, models.WebhookDeliveryFailed, delivery.Status)
+	assert.Equal(t, 2, delivery.Attempts)
+	assert.Nil(t, delivery.NextAttemptAt)
+
+	var updated models.Webhook
+	require.NoError(t, d.DB.Where("id = ?", webhook.ID).First(&updated).Error)
+	assert.False(t, updated.IsActive)
+	assert.Equal(t, 1, updated.FailureCount)
+	assert.NotNil(t, updated.DisabledAt)
+	assert.NotEmpty(t, updated.DisabledReason)
+}
+
+func TestDeliverer_SuccessResetsFailures(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusAccepted)
+		_, _ = w.Write([]byte(`{"ok":true}`))
+	}))
+	defer server.Close()
+
+	d := newTestDeliverer(t, Policy{RetrySchedule: []time.Duration{time.Minute}, DisableAfter: 5})
+	webhook := createWebhook(t, d, server.URL)
+	require.NoError(t, d.DB.Model(webhook).Update("failure_count", 3).Error)
+	webhook.FailureCount = 3
+
+	delivery, err := d.Enqueue(webhook, "message.incoming", []byte(`{}`))
+	require.NoError(t, err)
+	delivery, err = d.Deliver(context.Background(), delivery.ID)
+	require.NoError(t, err)
+	assert.Equal(t, models.WebhookDeliveryDelivered, delivery.Status)
+	assert.Equal(t, `{"ok":true}`, delivery.ResponseBody)
+	assert.NotNil(t, delivery.DeliveredAt)
+
+	var updated models.Webhook
+	require.NoError(t, d.DB.Where("id = ?", webhook.ID).First(&updated).Error)
+	assert.Equal(t, 0, updated.FailureCount)
+}
+
+func TestDeliverer_InactiveWebhookFails(t *testing.T) {
+	d := newTestDeliverer(t, Policy{RetrySchedule: []time.Duration{time.Minute}})
+	webhook := createWebhook(t, d, "https://example.com/hook")
+
+	delivery, err := d.Enqueue(webhook, "message.incoming", []byte(`{}`))
+	require.NoError(t, err)
+	require.NoError(t, d.DB.Model(webhook).Update("is_active", false).Error)
+
+	delivery, err = d.Deliver(context.Background(), delivery.ID)
+	require.NoError(t, err)
+	assert.Equal(t, models.WebhookDeliveryFailed, delivery.Status)
+	assert.Equal(t, 0, delivery.Attempts)
+	assert.Equal(t, "webhook is disabled", delivery.Error)
+}
+
+func TestDispatch_QueuesSubscribedWebhooks(t *testing.T) {
+	d := newTestDeliverer(t, Policy{})
+	subscribed := createWebhook(t, d, "https://example.com/hook")
+	require.NoError(t, d.DB.Model(subscribed).Update("events", models.StringArray{"campaign.completed"}).Error)
+
+	other := &models.Webhook{
+		OrganizationID: subscribed.OrganizationID,
+		Name:           "other",
+		URL:            "https://example.com/other",
+		Events:         models.StringArray{"message.incoming"},
+		IsActive:       true,
+	}
+	require.NoError(t, d.DB.Create(other).Error)
+
+	ctx := context.Background()
+	data := CampaignEventData{CampaignID: uuid.NewString(), Name: "Launch", Status: models.CampaignStatusCompleted}
+	n, err := Dispatch(ctx, d.DB, d.Redis, subscribed.OrganizationID, models.WebhookEventCampaignCompleted, data)
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+
+	var deliveries []models.WebhookDelivery
+	require.NoError(t, d.DB.Where("organization_id = ?", subscribed.OrganizationID).Find(&deliveries).Error)
+	require.Len(t, deliveries, 1)
+	assert.Equal(t, subscribed.ID, deliveries[0].WebhookID)
+	assert.Equal(t, models.WebhookDeliveryPending, deliveries[0].Status)
+	assert.Contains(t, deliveries[0].RequestBody, `"event":"campaign.completed"`)
+	assert.Contains(t, deliveries[0].RequestBody, `"name":"Launch"`)
+
+	_, err = d.Redis.ZScore(ctx, queue.WebhookDeliveriesKey, deliveries[0].ID.String()).Result()
+	assert.NoError(t, err)
+	d.Redis.ZRem(ctx, queue.WebhookDeliveriesKey, deliveries[0].ID.String())
+}
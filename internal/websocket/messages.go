@@ -37,6 +37,12 @@ const (
 	TypeConversationNoteUpdated = "conversation_note_updated"
 	TypeConversationNoteDeleted = "conversation_note_deleted"
 
+	// Conversation status types
+	TypeConversationStatus = "conversation_status"
+
+	// Read cursor types
+	TypeConversationRead = "conversation_read"
+
 	// Call types
 	TypeCallIncoming = "call_incoming"
 	TypeCallAnswered = "call_answered"
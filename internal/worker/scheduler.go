@@ -0,0 +1,158 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/shridarpatil/whatomate/internal/campaignutil"
+	"github.com/shridarpatil/whatomate/internal/models"
+	"github.com/shridarpatil/whatomate/internal/queue"
+	"github.com/shridarpatil/whatomate/internal/webhookutil"
+	"github.com/zerodha/logf"
+	"gorm.io/gorm"
+)
+
+const (
+	// SchedulerLockKey is the Redis key holding the scheduler leader lease
+	SchedulerLockKey = "whatomate:scheduler:leader"
+
+	// schedulerBatchSize caps how many due campaigns are dispatched per tick
+	schedulerBatchSize = 50
+)
+
+// renewLeaseScript extends the lease only if it is still held by this instance.
+var renewLeaseScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("PEXPIRE", KEYS[1], ARGV[2])
+end
+return 0`)
+
+// releaseLeaseScript deletes the lease only if it is still held by this instance.
+var releaseLeaseScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0`)
+
+// CampaignScheduler starts scheduled campaigns once their ScheduledAt arrives.
+// Any number of schedulers may run; a Redis lease elects a single leader so
+// that a campaign is only dispatched once.
+type CampaignScheduler struct {
+	DB       *gorm.DB
+	Redis    *redis.Client
+	Queue    queue.Queue
+	Log      logf.Logger
+	Interval time.Duration
+	LeaseTTL time.Duration
+
+	instanceID string
+}
+
+// NewCampaignScheduler creates a new campaign scheduler
+func NewCampaignScheduler(db *gorm.DB, rdb *redis.Client, log logf.Logger, interval time.Duration) *CampaignScheduler {
+	hostname, _ := os.Hostname()
+	return &CampaignScheduler{
+		DB:         db,
+		Redis:      rdb,
+		Queue:      queue.NewRedisQueue(rdb, log),
+		Log:        log,
+		Interval:   interval,
+		LeaseTTL:   3 * interval,
+		instanceID: fmt.Sprintf("scheduler-%s-%d", hostname, os.Getpid()),
+	}
+}
+
+// Run polls for due campaigns until the context is cancelled
+func (s *CampaignScheduler) Run(ctx context.Context) {
+	s.Log.Info("Campaign scheduler started", "interval", s.Interval, "instance", s.instanceID)
+
+	ticker := time.NewTicker(s.Interval)
+	defer ticker.Stop()
+
+	for {
+		if s.acquireLease(ctx) {
+			s.DispatchDue(ctx, time.Now())
+		}
+
+		select {
+		case <-ctx.Done():
+			s.releaseLease()
+			s.Log.Info("Campaign scheduler stopped")
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// acquireLease takes or renews the leader lease. Returns true if this
+// instance is the leader for the current tick.
+func (s *CampaignScheduler) acquireLease(ctx context.Context) bool {
+	ok, err := s.Redis.SetNX(ctx, SchedulerLockKey, s.instanceID, s.LeaseTTL).Result()
+	if err != nil {
+		if ctx.Err() == nil {
+			s.Log.Error("Failed to acquire scheduler lease", "error", err)
+		}
+		return false
+	}
+	if ok {
+		s.Log.Info("Campaign scheduler acquired leadership", "instance", s.instanceID)
+		return true
+	}
+
+	renewed, err := renewLeaseScript.Run(ctx, s.Redis, []string{SchedulerLockKey}, s.instanceID, s.LeaseTTL.Milliseconds()).Int()
+	if err != nil {
+		if ctx.Err() == nil {
+			s.Log.Error("Failed to renew scheduler lease", "error", err)
+		}
+		return false
+	}
+	return renewed == 1
+}
+
+// releaseLease gives up leadership so another instance can take over immediately
+func (s *CampaignScheduler) releaseLease() {
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	if err := releaseLeaseScript.Run(ctx, s.Redis, []string{SchedulerLockKey}, s.instanceID).Err(); err != nil && !errors.Is(err, redis.Nil) {
+		s.Log.Warn("Failed to release scheduler lease", "error", err)
+	}
+}
+
+// DispatchDue starts every scheduled campaign whose ScheduledAt is at or before now
+func (s *CampaignScheduler) DispatchDue(ctx context.Context, now time.Time) {
+	var campaigns []models.BulkMessageCampaign
+	if err := s.DB.Where("status = ? AND scheduled_at IS NOT NULL AND scheduled_at <= ?", models.CampaignStatusScheduled, now).
+		Order("scheduled_at ASC").
+		Limit(schedulerBatchSize).
+		Find(&campaigns).Error; err != nil {
+		s.Log.Error("Failed to load due campaigns", "error", err)
+		return
+	}
+
+	for i := range campaigns {
+		campaign := &campaigns[i]
+		count, err := campaignutil.Start(ctx, s.DB, s.Queue, campaign, models.CampaignTriggerScheduler, nil)
+		switch {
+		case err == nil:
+			s.Log.Info("Scheduled campaign started", "campaign_id", campaign.ID, "recipients", count, "scheduled_at", campaign.ScheduledAt)
+			if _, err := webhookutil.Dispatch(ctx, s.DB, s.Redis, campaign.OrganizationID, models.WebhookEventCampaignStarted, webhookutil.NewCampaignEventData(campaign)); err != nil {
+				s.Log.Error("Failed to dispatch campaign webhook", "campaign_id", campaign.ID, "error", err)
+			}
+		case errors.Is(err, campaignutil.ErrNotStartable):
+			// Another process changed the status between load and start
+			s.Log.Debug("Scheduled campaign no longer startable", "campaign_id", campaign.ID)
+		case errors.Is(err, campaignutil.ErrNoPendingRecipients), errors.Is(err, campaignutil.ErrTemplateNotFound):
+			s.Log.Warn("Scheduled campaign failed validation", "campaign_id", campaign.ID, "error", err)
+			s.DB.Model(&models.BulkMessageCampaign{}).
+				Where("id = ? AND status = ?", campaign.ID, models.CampaignStatusScheduled).
+				Update("status", models.CampaignStatusFailed)
+		default:
+			// Left as scheduled so the next tick retries it
+			s.Log.Error("Failed to start scheduled campaign", "campaign_id", campaign.ID, "error", err)
+		}
+	}
+}
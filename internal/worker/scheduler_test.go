@@ -0,0 +1,105 @@
+package worker
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/shridarpatil/whatomate/internal/models"
+	"github.com/shridarpatil/whatomate/test/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testScheduler(t *testing.T, w *Worker) (*CampaignScheduler, *testutil.MockQueue) {
+	t.Helper()
+	q := testutil.NewMockQueue()
+	s := &CampaignScheduler{
+		DB:         w.DB,
+		Redis:      w.Redis,
+		Queue:      q,
+		Log:        w.Log,
+		Interval:   time.Second,
+		LeaseTTL:   3 * time.Second,
+		instanceID: "test-scheduler",
+	}
+	return s, q
+}
+
+func TestCampaignScheduler_DispatchDue_StartsDueCampaign(t *testing.T) {
+	w := testWorker(t)
+	_, _, _, campaign, _ := createTestCampaignData(t, w)
+
+	past := time.Now().Add(-time.Minute)
+	require.NoError(t, w.DB.Model(campaign).Updates(map[string]interface{}{
+		"status":       models.CampaignStatusScheduled,
+		"scheduled_at": past,
+	}).Error)
+
+	s, q := testScheduler(t, w)
+	s.DispatchDue(context.Background(), time.Now())
+
+	var updated models.BulkMessageCampaign
+	require.NoError(t, w.DB.First(&updated, campaign.ID).Error)
+	assert.Equal(t, models.CampaignStatusProcessing, updated.Status)
+	assert.Equal(t, models.CampaignTriggerScheduler, updated.StartTrigger)
+	assert.Len(t, q.Jobs, 1)
+}
+
+func TestCampaignScheduler_DispatchDue_SkipsFutureCampaign(t *testing.T) {
+	w := testWorker(t)
+	_, _, _, campaign, _ := createTestCampaignData(t, w)
+
+	future := time.Now().Add(time.Hour)
+	require.NoError(t, w.DB.Model(campaign).Updates(map[string]interface{}{
+		"status":       models.CampaignStatusScheduled,
+		"scheduled_at": future,
+	}).Error)
+
+	s, q := testScheduler(t, w)
+	s.DispatchDue(context.Background(), time.Now())
+
+	var updated models.BulkMessageCampaign
+	require.NoError(t, w.DB.First(&updated, campaign.ID).Error)
+	assert.Equal(t, models.CampaignStatusScheduled, updated.Status)
+	assert.Empty(t, q.Jobs)
+}
+
+func TestCampaignScheduler_DispatchDue_FailsCampaignWithoutRecipients(t *testing.T) {
+	w := testWorker(t)
+	_, _, _, campaign, recipient := createTestCampaignData(t, w)
+	require.NoError(t, w.DB.Delete(recipient).Error)
+
+	require.NoError(t, w.DB.Model(campaign).Updates(map[string]interface{}{
+		"status":       models.CampaignStatusScheduled,
+		"scheduled_at": time.Now().Add(-time.Minute),
+	}).Error)
+
+	s, _ := testScheduler(t, w)
+	s.DispatchDue(context.Background(), time.Now())
+
+	var updated models.BulkMessageCampaign
+	require.NoError(t, w.DB.First(&updated, campaign.ID).Error)
+	assert.Equal(t, models.CampaignStatusFailed, updated.Status)
+}
+
+func TestCampaignScheduler_LeaseIsExclusive(t *testing.T) {
+	rdb := testutil.SetupTestRedis(t)
+	if rdb == nil {
+		t.Skip("TEST_REDIS_URL not set, skipping test")
+	}
+	ctx := context.Background()
+	rdb.Del(ctx, SchedulerLockKey)
+	t.Cleanup(func() { rdb.Del(ctx, SchedulerLockKey) })
+
+	log := testutil.NopLogger()
+	a := &CampaignScheduler{Redis: rdb, Log: log, LeaseTTL: 5 * time.Second, instanceID: "a"}
+	b := &CampaignScheduler{Redis: rdb, Log: log, LeaseTTL: 5 * time.Second, instanceID: "b"}
+
+	assert.True(t, a.acquireLease(ctx))
+	assert.False(t, b.acquireLease(ctx))
+	assert.True(t, a.acquireLease(ctx), "leader should renew its own lease")
+
+	a.releaseLease()
+	assert.True(t, b.acquireLease(ctx))
+}
@@ -0,0 +1,178 @@
+package worker
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/shridarpatil/whatomate/internal/models"
+)
+
+const (
+	// throttleRateKeyPrefix is the Redis key prefix for per-account token buckets
+	throttleRateKeyPrefix = "whatomate:throttle:mps:"
+
+	// throttleRecipientsKeyPrefix is the Redis key prefix for the rolling set of
+	// unique recipients messaged per account in the last 24 hours
+	throttleRecipientsKeyPrefix = "whatomate:throttle:recipients:"
+
+	// recipientWindow is Meta's rolling window for messaging limit tiers
+	recipientWindow = 24 * time.Hour
+)
+
+// tokenBucketScript refills and takes one token from an account's bucket.
+// Returns 0 when a token was taken, otherwise the milliseconds until one is available.
+// KEYS[1] bucket key; ARGV: rate (tokens/s), burst, now (ms)
+var tokenBucketScript = redis.NewScript(`
+local rate = tonumber(ARGV[1])
+local burst = tonumber(ARGV[2])
+local now = tonumber(ARGV[3])
+
+local state = redis.call("HMGET", KEYS[1], "tokens", "ts")
+local tokens = tonumber(state[1])
+local ts = tonumber(state[2])
+if tokens == nil then
+	tokens = burst
+	ts = now
+end
+
+local elapsed = math.max(0, now - ts)
+tokens = math.min(burst, tokens + elapsed * rate / 1000)
+
+local wait = 0
+if tokens >= 1 then
+	tokens = tokens - 1
+else
+	wait = math.ceil((1 - tokens) * 1000 / rate)
+end
+
+redis.call("HSET", KEYS[1], "tokens", tostring(tokens), "ts", now)
+redis.call("PEXPIRE", KEYS[1], math.ceil(burst * 1000 / rate) + 1000)
+return wait`)
+
+// recipientCapScript records a recipient in an account's rolling 24h window.
+// Recipients already in the window are always allowed (Meta counts unique recipients).
+// Returns -1 when the recipient took a new slot, 0 when it already had one,
+// otherwise the unix ms when the oldest entry leaves the window.
+// KEYS[1] window key; ARGV: recipient, limit, now (ms), window (ms)
+var recipientCapScript = redis.NewScript(`
+local limit = tonumber(ARGV[2])
+local now = tonumber(ARGV[3])
+local window = tonumber(ARGV[4])
+
+redis.call("ZREMRANGEBYSCORE", KEYS[1], "-inf", now - window)
+
+if redis.call("ZSCORE", KEYS[1], ARGV[1]) then
+	return 0
+end
+
+if redis.call("ZCARD", KEYS[1]) >= limit then
+	local oldest = redis.call("ZRANGE", KEYS[1], 0, 0, "WITHSCORES")
+	return tonumber(oldest[2]) + window
+end
+
+redis.call("ZADD", KEYS[1], now, ARGV[1])
+redis.call("PEXPIRE", KEYS[1], window)
+return -1`)
+
+// AccountThrottle enforces per-account send limits shared by all workers:
+// a messages-per-second token bucket and Meta's daily unique-recipient cap.
+type AccountThrottle struct {
+	Redis                    *redis.Client
+	DefaultMessagesPerSecond int
+	DefaultBurst             int
+}
+
+// NewAccountThrottle creates a throttle with the given default rate and burst
+func NewAccountThrottle(rdb *redis.Client, messagesPerSecond, burst int) *AccountThrottle {
+	return &AccountThrottle{
+		Redis:                    rdb,
+		DefaultMessagesPerSecond: messagesPerSecond,
+		DefaultBurst:             burst,
+	}
+}
+
+// ThrottleResult describes whether a send may proceed now
+type ThrottleResult struct {
+	Allowed    bool
+	RetryAfter time.Duration // How long to wait before trying again when not allowed
+	CapReached bool          // True when the daily recipient cap (not the rate) blocked the send
+	Reserved   bool          // True when the recipient took a new slot of the daily cap
+}
+
+// Acquire checks the daily recipient cap and takes a token from the account's
+// rate bucket. When not allowed, the caller should defer the send by RetryAfter.
+// When the result is Reserved and the send then fails, the caller should give
+// the slot back with ReleaseRecipient.
+func (t *AccountThrottle) Acquire(ctx context.Context, account *models.WhatsAppAccount, phoneNumber string, now time.Time) (ThrottleResult, error) {
+	reserved := false
+	if limit := account.DailyRecipientCap(); limit > 0 {
+		until, err := recipientCapScript.Run(ctx, t.Redis,
+			[]string{throttleRecipientsKeyPrefix + account.PhoneID},
+			phoneNumber, limit, now.UnixMilli(), recipientWindow.Milliseconds()).Int64()
+		if err != nil {
+			return ThrottleResult{}, fmt.Errorf("failed to check recipient cap: %w", err)
+		}
+		if until > 0 {
+			return ThrottleResult{
+				RetryAfter: time.UnixMilli(until).Sub(now),
+				CapReached: true,
+			}, nil
+		}
+		reserved = until < 0
+	}
+
+	rate, burst := t.limits(account)
+	if rate <= 0 {
+		return ThrottleResult{Allowed: true, Reserved: reserved}, nil
+	}
+
+	wait, err := tokenBucketScript.Run(ctx, t.Redis,
+		[]string{throttleRateKeyPrefix + account.PhoneID},
+		rate, burst, now.UnixMilli()).Int64()
+	if err != nil {
+		_ = t.release(ctx, account, phoneNumber, reserved)
+		return ThrottleResult{}, fmt.Errorf("failed to take rate token: %w", err)
+	}
+	if wait > 0 {
+		// Nothing is sent yet, so the recipient's new slot is given back meanwhile
+		if err := t.release(ctx, account, phoneNumber, reserved); err != nil {
+			return ThrottleResult{}, err
+		}
+		return ThrottleResult{RetryAfter: time.Duration(wait) * time.Millisecond}, nil
+	}
+
+	return ThrottleResult{Allowed: true, Reserved: reserved}, nil
+}
+
+// ReleaseRecipient gives back the daily cap slot a recipient took in Acquire,
+// for a send that Meta didn't accept
+func (t *AccountThrottle) ReleaseRecipient(ctx context.Context, account *models.WhatsAppAccount, phoneNumber string) error {
+	return t.release(ctx, account, phoneNumber, true)
+}
+
+// release removes a recipient from the account's window when reserved is set
+func (t *AccountThrottle) release(ctx context.Context, account *models.WhatsAppAccount, phoneNumber string, reserved bool) error {
+	if !reserved {
+		return nil
+	}
+	if err := t.Redis.ZRem(ctx, throttleRecipientsKeyPrefix+account.PhoneID, phoneNumber).Err(); err != nil {
+		return fmt.Errorf("failed to release recipient: %w", err)
+	}
+	return nil
+}
+
+// limits returns the effective rate and burst for an account
+func (t *AccountThrottle) limits(account *models.WhatsAppAccount) (rate, burst int) {
+	rate = t.DefaultMessagesPerSecond
+	if account.MessagesPerSecond > 0 {
+		rate = account.MessagesPerSecond
+	}
+	burst = t.DefaultBurst
+	if burst <= 0 || account.MessagesPerSecond > 0 {
+		burst = rate
+	}
+	return rate, int(math.Max(1, float64(burst)))
+}
@@ -0,0 +1,248 @@
+package worker
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+	"github.com/shridarpatil/whatomate/internal/models"
+	"github.com/shridarpatil/whatomate/internal/queue"
+	"github.com/shridarpatil/whatomate/pkg/whatsapp"
+	"github.com/shridarpatil/whatomate/test/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testThrottle returns a throttle backed by the test Redis, or skips the test.
+func testThrottle(t *testing.T, mps, burst int) (*AccountThrottle, *redis.Client) {
+	t.Helper()
+	rdb := testutil.SetupTestRedis(t)
+	if rdb == nil {
+		t.Skip("Redis not available, skipping test")
+	}
+	return NewAccountThrottle(rdb, mps, burst), rdb
+}
+
+// throttleAccount returns an account with a unique PhoneID and cleans up its keys.
+func throttleAccount(t *testing.T, rdb *redis.Client) *models.WhatsAppAccount {
+	t.Helper()
+	account := &models.WhatsAppAccount{Name: "throttle", PhoneID: "phone-" + uuid.New().String()[:8]}
+	t.Cleanup(func() {
+		rdb.Del(context.Background(), throttleRateKeyPrefix+account.PhoneID, throttleRecipientsKeyPrefix+account.PhoneID)
+	})
+	return account
+}
+
+func TestAccountThrottle_RateLimit(t *testing.T) {
+	throttle, rdb := testThrottle(t, 2, 2)
+	account := throttleAccount(t, rdb)
+	ctx := context.Background()
+	now := time.Now()
+
+	// Burst of 2 is allowed immediately
+	for i := 0; i < 2; i++ {
+		res, err := throttle.Acquire(ctx, account, "111", now)
+		require.NoError(t, err)
+		assert.True(t, res.Allowed)
+	}
+
+	// Third send must wait roughly one token interval (500ms at 2/s)
+	res, err := throttle.Acquire(ctx, account, "111", now)
+	require.NoError(t, err)
+	assert.False(t, res.Allowed)
+	assert.False(t, res.CapReached)
+	assert.InDelta(t, 500, res.RetryAfter.Milliseconds(), 50)
+
+	// Tokens refill over time
+	res, err = throttle.Acquire(ctx, account, "111", now.Add(600*time.Millisecond))
+	require.NoError(t, err)
+	assert.True(t, res.Allowed)
+}
+
+func TestAccountThrottle_AccountOverridesRate(t *testing.T) {
+	throttle, rdb := testThrottle(t, 1, 1)
+	account := throttleAccount(t, rdb)
+	account.MessagesPerSecond = 5
+	ctx := context.Background()
+	now := time.Now()
+
+	for i := 0; i < 5; i++ {
+		res, err := throttle.Acquire(ctx, account, "111", now)
+		require.NoError(t, err)
+		assert.True(t, res.Allowed, "send %d should be allowed", i)
+	}
+
+	res, err := throttle.Acquire(ctx, account, "111", now)
+	require.NoError(t, err)
+	assert.False(t, res.Allowed)
+}
+
+func TestAccountThrottle_DailyRecipientCap(t *testing.T) {
+	throttle, rdb := testThrottle(t, 1000, 1000)
+	account := throttleAccount(t, rdb)
+	account.DailyRecipientLimit = 2
+	ctx := context.Background()
+	now := time.Now()
+
+	for _, phone := range []string{"111", "222"} {
+		res, err := throttle.Acquire(ctx, account, phone, now)
+		require.NoError(t, err)
+		assert.True(t, res.Allowed)
+	}
+
+	// Already-messaged recipients don't count again
+	res, err := throttle.Acquire(ctx, account, "111", now.Add(time.Minute))
+	require.NoError(t, err)
+	assert.True(t, res.Allowed)
+
+	// A new recipient is blocked until the oldest entry leaves the 24h window
+	res, err = throttle.Acquire(ctx, account, "333", now.Add(time.Hour))
+	require.NoError(t, err)
+	assert.False(t, res.Allowed)
+	assert.True(t, res.CapReached)
+	assert.InDelta(t, (23 * time.Hour).Seconds(), res.RetryAfter.Seconds(), 1)
+
+	res, err = throttle.Acquire(ctx, account, "333", now.Add(recipientWindow+time.Second))
+	require.NoError(t, err)
+	assert.True(t, res.Allowed)
+}
+
+func TestAccountThrottle_ReleaseRecipient(t *testing.T) {
+	throttle, rdb := testThrottle(t, 1000, 1000)
+	account := throttleAccount(t, rdb)
+	account.DailyRecipientLimit = 1
+	ctx := context.Background()
+	now := time.Now()
+
+	res, err := throttle.Acquire(ctx, account, "111", now)
+	require.NoError(t, err)
+	assert.True(t, res.Allowed)
+	assert.True(t, res.Reserved)
+
+	// A recipient already in the window doesn't take a new slot
+	res, err = throttle.Acquire(ctx, account, "111", now)
+	require.NoError(t, err)
+	assert.True(t, res.Allowed)
+	assert.False(t, res.Reserved)
+
+	// The send failed, so another recipient can have the slot
+	require.NoError(t, throttle.ReleaseRecipient(ctx, account, "111"))
+	res, err = throttle.Acquire(ctx, account, "222", now)
+	require.NoError(t, err)
+	assert.True(t, res.Allowed)
+	assert.True(t, res.Reserved)
+}
+
+func TestAccountThrottle_RateWaitReleasesRecipient(t *testing.T) {
+	throttle, rdb := testThrottle(t, 1, 1)
+	account := throttleAccount(t, rdb)
+	ctx := context.Background()
+	now := time.Now()
+
+	// Use up the only rate token, with no cap configured yet
+	_, err := throttle.Acquire(ctx, account, "111", now)
+	require.NoError(t, err)
+
+	// Waiting for a token doesn't hold on to the cap slot
+	account.DailyRecipientLimit = 1
+	res, err := throttle.Acquire(ctx, account, "222", now)
+	require.NoError(t, err)
+	assert.False(t, res.Allowed)
+	assert.False(t, res.CapReached)
+	count, err := rdb.ZCard(ctx, throttleRecipientsKeyPrefix+account.PhoneID).Result()
+	require.NoError(t, err)
+	assert.Zero(t, count)
+}
+
+func TestWorker_HandleRecipientJob_RejectedSendReleasesCapSlot(t *testing.T) {
+	w := testWorker(t)
+	if w.Redis == nil {
+		t.Skip("Redis not available, skipping test")
+	}
+	org, account, _, campaign, recipient := createTestCampaignData(t, w)
+	t.Cleanup(func() {
+		w.Redis.Del(context.Background(), throttleRateKeyPrefix+account.PhoneID, throttleRecipientsKeyPrefix+account.PhoneID)
+	})
+
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		rw.Header().Set("Content-Type", "application/json")
+		rw.WriteHeader(http.StatusBadRequest)
+		_ = json.NewEncoder(rw).Encode(map[string]interface{}{
+			"error": map[string]interface{}{"message": "Invalid phone number", "code": 100},
+		})
+	}))
+	defer server.Close()
+
+	require.NoError(t, w.DB.Model(account).Updates(map[string]interface{}{
+		"daily_recipient_limit": 1,
+		"api_version":           "v21.0",
+	}).Error)
+	w.WhatsApp = whatsapp.NewWithBaseURL(w.Log, server.URL)
+	w.Queue = &testutil.MockQueue{}
+	w.Throttle = NewAccountThrottle(w.Redis, 100, 100)
+
+	job := &queue.RecipientJob{
+		CampaignID:     campaign.ID,
+		RecipientID:    recipient.ID,
+		OrganizationID: org.ID,
+		PhoneNumber:    recipient.PhoneNumber,
+		RecipientName:  recipient.RecipientName,
+		TemplateParams: recipient.TemplateParams,
+	}
+	require.NoError(t, w.HandleRecipientJob(context.Background(), job))
+
+	var updated models.BulkMessageRecipient
+	require.NoError(t, w.DB.First(&updated, recipient.ID).Error)
+	assert.Equal(t, models.MessageStatusFailed, updated.Status)
+
+	// Meta didn't accept the message, so today's only slot is still free
+	account.DailyRecipientLimit = 1
+	res, err := w.Throttle.Acquire(context.Background(), account, "9998887777", time.Now())
+	require.NoError(t, err)
+	assert.True(t, res.Allowed)
+}
+
+func TestWorker_HandleRecipientJob_DefersWhenDailyCapReached(t *testing.T) {
+	w := testWorker(t)
+	if w.Redis == nil {
+		t.Skip("Redis not available, skipping test")
+	}
+	org, account, _, campaign, recipient := createTestCampaignData(t, w)
+	t.Cleanup(func() {
+		w.Redis.Del(context.Background(), throttleRateKeyPrefix+account.PhoneID, throttleRecipientsKeyPrefix+account.PhoneID)
+	})
+
+	require.NoError(t, w.DB.Model(account).Update("daily_recipient_limit", 1).Error)
+
+	mockQueue := &testutil.MockQueue{}
+	w.Queue = mockQueue
+	w.Throttle = NewAccountThrottle(w.Redis, 100, 100)
+
+	// Another recipient already used today's only slot
+	_, err := w.Throttle.Acquire(context.Background(), account, "9998887777", time.Now())
+	require.NoError(t, err)
+
+	job := &queue.RecipientJob{
+		CampaignID:     campaign.ID,
+		RecipientID:    recipient.ID,
+		OrganizationID: org.ID,
+		PhoneNumber:    recipient.PhoneNumber,
+		RecipientName:  recipient.RecipientName,
+		TemplateParams: recipient.TemplateParams,
+	}
+	require.NoError(t, w.HandleRecipientJob(context.Background(), job))
+
+	// Job deferred, not failed
+	require.Len(t, mockQueue.Scheduled, 1)
+	assert.Equal(t, recipient.ID, mockQueue.Scheduled[0].Job.RecipientID)
+	assert.True(t, mockQueue.Scheduled[0].At.After(time.Now().Add(23*time.Hour)))
+
+	var updated models.BulkMessageRecipient
+	require.NoError(t, w.DB.First(&updated, recipient.ID).Error)
+	assert.Equal(t, models.MessageStatusPending, updated.Status)
+}
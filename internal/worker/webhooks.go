@@ -0,0 +1,122 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shridarpatil/whatomate/internal/queue"
+	"github.com/shridarpatil/whatomate/internal/webhookutil"
+)
+
+const (
+	// webhookPollInterval is how often the queue is checked for due retries
+	webhookPollInterval = time.Second
+
+	// webhookSweepInterval is how often pending deliveries missing from the
+	// queue are requeued and old history is pruned
+	webhookSweepInterval = time.Minute
+
+	// webhookBatchSize caps how many deliveries are taken from the queue per poll
+	webhookBatchSize = 50
+
+	// webhookConcurrency caps concurrent delivery attempts per processor
+	webhookConcurrency = 10
+)
+
+// WebhookProcessor retries failed outbound webhook deliveries as they come
+// due. Deliveries are popped from the Redis queue atomically and claimed in
+// the database, so any number of processors may run side by side.
+type WebhookProcessor struct {
+	Deliverer *webhookutil.Deliverer
+	Retention time.Duration // Finished deliveries older than this are deleted; 0 keeps them
+}
+
+// NewWebhookProcessor creates a new webhook retry processor
+func NewWebhookProcessor(deliverer *webhookutil.Deliverer, retentionDays int) *WebhookProcessor {
+	return &WebhookProcessor{
+		Deliverer: deliverer,
+		Retention: time.Duration(retentionDays) * 24 * time.Hour,
+	}
+}
+
+// Run processes due deliveries until the context is cancelled
+func (p *WebhookProcessor) Run(ctx context.Context) {
+	log := p.Deliverer.Log
+	log.Info("Webhook processor started")
+
+	poll := time.NewTicker(webhookPollInterval)
+	defer poll.Stop()
+	sweep := time.NewTicker(webhookSweepInterval)
+	defer sweep.Stop()
+
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, webhookConcurrency)
+
+	p.Sweep(ctx, time.Now())
+	for {
+		select {
+		case <-ctx.Done():
+			wg.Wait()
+			log.Info("Webhook processor stopped")
+			return
+		case <-sweep.C:
+			p.Sweep(ctx, time.Now())
+		case <-poll.C:
+			ids, err := queue.PopDueWebhookDeliveries(ctx, p.Deliverer.Redis, time.Now(), webhookBatchSize)
+			if err != nil {
+				if ctx.Err() == nil {
+					log.Error("Failed to poll webhook deliveries", "error", err)
+				}
+				continue
+			}
+			for _, id := range ids {
+				sem <- struct{}{}
+				wg.Add(1)
+				go func(id uuid.UUID) {
+					defer wg.Done()
+					defer func() { <-sem }()
+					p.deliver(ctx, id)
+				}(id)
+			}
+		}
+	}
+}
+
+// deliver attempts a single queued delivery
+func (p *WebhookProcessor) deliver(ctx context.Context, id uuid.UUID) {
+	delivery, err := p.Deliverer.Deliver(ctx, id)
+	if err != nil {
+		if !errors.Is(err, webhookutil.ErrNotDue) {
+			p.Deliverer.Log.Error("Webhook delivery attempt failed", "error", err, "delivery_id", id)
+		}
+		return
+	}
+	p.Deliverer.Log.Debug("Webhook delivery attempted",
+		"delivery_id", id, "status", delivery.Status, "attempts", delivery.Attempts)
+}
+
+// Sweep requeues pending deliveries that are due but missing from the queue
+// and deletes finished deliveries past the retention period
+func (p *WebhookProcessor) Sweep(ctx context.Context, now time.Time) {
+	log := p.Deliverer.Log
+
+	if n, err := p.Deliverer.RequeueDue(ctx, now, 500); err != nil {
+		if ctx.Err() == nil {
+			log.Error("Failed to requeue webhook deliveries", "error", err)
+		}
+	} else if n > 0 {
+		log.Info("Requeued webhook deliveries", "count", n)
+	}
+
+	if p.Retention <= 0 {
+		return
+	}
+	if n, err := p.Deliverer.Prune(now.Add(-p.Retention)); err != nil {
+		log.Error("Failed to prune webhook deliveries", "error", err)
+	} else if n > 0 {
+		log.Info("Pruned webhook delivery history", "count", n)
+	}
+}
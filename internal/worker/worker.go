@@ -3,6 +3,7 @@ package worker
 import (
 	"context"
 	"fmt"
+	"math/rand/v2"
 	"time"
 
 	"github.com/google/uuid"
@@ -12,6 +13,7 @@ import (
 	"github.com/shridarpatil/whatomate/internal/models"
 	"github.com/shridarpatil/whatomate/internal/queue"
 	"github.com/shridarpatil/whatomate/internal/templateutil"
+	"github.com/shridarpatil/whatomate/internal/webhookutil"
 	"github.com/shridarpatil/whatomate/pkg/whatsapp"
 	"github.com/zerodha/logf"
 	"gorm.io/gorm"
@@ -26,8 +28,14 @@ type Worker struct {
 	WhatsApp  *whatsapp.Client
 	Consumer  *queue.RedisConsumer
 	Publisher *queue.Publisher
+	Queue     queue.Queue      // Used to defer throttled jobs
+	Throttle  *AccountThrottle // nil disables send-rate throttling
 }
 
+// maxThrottleWait is the longest a worker blocks for a rate token before
+// deferring the job back to the queue instead
+const maxThrottleWait = time.Second
+
 // Ensure Worker implements JobHandler interface
 var _ queue.JobHandler = (*Worker)(nil)
 
@@ -48,10 +56,11 @@ func New(cfg *config.Config, db *gorm.DB, rdb *redis.Client, log logf.Logger) (*
 		WhatsApp:  whatsapp.New(log),
 		Consumer:  consumer,
 		Publisher: publisher,
+		Queue:     queue.NewRedisQueue(rdb, log),
+		Throttle:  NewAccountThrottle(rdb, cfg.Campaign.MessagesPerSecond, cfg.Campaign.Burst),
 	}, nil
 }
 
-
 // Run starts the worker and processes jobs until context is cancelled
 func (w *Worker) Run(ctx context.Context) error {
 	w.Log.Info("Worker starting")
@@ -90,15 +99,42 @@ func (w *Worker) HandleRecipientJob(ctx context.Context, job *queue.RecipientJob
 	}
 	w.decryptAccountSecrets(&account)
 
+	// Skip contacts that opted out after the campaign was started
+	if campaign.Template.IsMarketing() {
+		optedOut, err := contactutil.OptedOutPhones(w.DB, job.OrganizationID, []string{job.PhoneNumber})
+		if err != nil {
+			w.Log.Error("Failed to check opt-out", "error", err, "phone", job.PhoneNumber)
+			return fmt.Errorf("failed to check opt-out: %w", err)
+		}
+		if optedOut[job.PhoneNumber] {
+			w.Log.Info("Recipient opted out, skipping", "campaign_id", job.CampaignID, "recipient_id", job.RecipientID)
+			w.updateRecipientStatus(job.RecipientID, models.MessageStatusFailed, "", contactutil.OptedOutError)
+			w.incrementCampaignCount(job.CampaignID, "failed_count")
+			w.checkCampaignCompletion(ctx, job.CampaignID, job.OrganizationID)
+			return nil // Don't retry
+		}
+	}
+
+	// Enforce the account's send rate and daily recipient cap
+	reserved, deferred, err := w.throttle(ctx, &account, job)
+	if err != nil || deferred {
+		return err
+	}
+
 	// Get or create contact for this recipient
 	contact, _, err := contactutil.GetOrCreateContact(w.DB, job.OrganizationID, job.PhoneNumber, job.RecipientName)
 	if err != nil || contact == nil {
 		w.Log.Error("Failed to get or create contact", "error", err, "phone", job.PhoneNumber)
+		w.releaseRecipient(ctx, &account, job, reserved)
 		w.updateRecipientStatus(job.RecipientID, models.MessageStatusFailed, "", "Failed to create contact")
 		w.incrementCampaignCount(job.CampaignID, "failed_count")
 		return nil // Don't retry
 	}
 
+	// Send the template in the contact's preferred language when it has a
+	// variant in that language
+	template := contactutil.TemplateInLanguage(w.DB, campaign.Template, contact.PreferredLanguage())
+
 	// Build recipient for sending
 	recipient := &models.BulkMessageRecipient{
 		PhoneNumber:    job.PhoneNumber,
@@ -107,7 +143,17 @@ func (w *Worker) HandleRecipientJob(ctx context.Context, job *queue.RecipientJob
 	}
 
 	// Send template message
-	waMessageID, err := w.sendTemplateMessage(ctx, &account, campaign.Template, recipient, campaign.HeaderMediaID)
+	waMessageID, err := w.sendTemplateMessage(ctx, &account, template, recipient, campaign.HeaderMediaID)
+
+	// Transient failures are retried later; the recipient stays pending meanwhile.
+	// Either way Meta didn't accept the message, so it doesn't count towards the
+	// daily recipient cap.
+	if err != nil {
+		w.releaseRecipient(ctx, &account, job, reserved)
+		if retried, retryErr := w.retryJob(ctx, job, err); retried || retryErr != nil {
+			return retryErr
+		}
+	}
 
 	// Create Message record
 	message := models.Message{
@@ -120,12 +166,13 @@ func (w *Worker) HandleRecipientJob(ctx context.Context, job *queue.RecipientJob
 		TemplateParams:    job.TemplateParams,
 		Metadata: models.JSONB{
 			"campaign_id":    job.CampaignID.String(),
+			"recipient_id":   job.RecipientID.String(),
 			"recipient_name": job.RecipientName,
 		},
 	}
-	if campaign.Template != nil {
-		message.TemplateName = campaign.Template.Name
-		content := templateutil.ReplaceWithJSONBParams(campaign.Template.BodyContent, campaign.Template.BodyContent, job.TemplateParams)
+	if template != nil {
+		message.TemplateName = template.Name
+		content := templateutil.ReplaceWithJSONBParams(template.BodyContent, template.BodyContent, job.TemplateParams)
 		message.Content = content
 	}
 
@@ -153,6 +200,130 @@ func (w *Worker) HandleRecipientJob(ctx context.Context, job *queue.RecipientJob
 	return nil
 }
 
+// throttle blocks briefly for a rate token, or defers the job back to the queue
+// when the account is over its limits. Returns whether the recipient took a new
+// slot of the daily cap, and true if the job was deferred.
+// Deferred recipients stay pending, so the campaign keeps processing.
+func (w *Worker) throttle(ctx context.Context, account *models.WhatsAppAccount, job *queue.RecipientJob) (reserved, deferred bool, err error) {
+	if w.Throttle == nil || w.Queue == nil {
+		return false, false, nil
+	}
+
+	for {
+		result, err := w.Throttle.Acquire(ctx, account, job.PhoneNumber, time.Now())
+		if err != nil {
+			w.Log.Error("Failed to check send throttle", "error", err, "account", account.Name)
+			return false, true, err // Not acked, so the job is reclaimed and retried
+		}
+		if result.Allowed {
+			return result.Reserved, false, nil
+		}
+
+		if !result.CapReached && result.RetryAfter <= maxThrottleWait {
+			select {
+			case <-ctx.Done():
+				return false, true, ctx.Err()
+			case <-time.After(result.RetryAfter):
+			}
+			continue
+		}
+
+		at := time.Now().Add(result.RetryAfter)
+		if err := w.Queue.ScheduleRecipient(ctx, job, at); err != nil {
+			w.Log.Error("Failed to defer throttled job", "error", err, "recipient_id", job.RecipientID)
+			return false, true, err
+		}
+
+		w.Log.Info("Send throttled, job deferred", "account", account.Name, "recipient_id", job.RecipientID,
+			"retry_at", at, "daily_cap_reached", result.CapReached)
+		return false, true, nil
+	}
+}
+
+// releaseRecipient gives back the daily cap slot the recipient took in throttle
+// when nothing was sent to them
+func (w *Worker) releaseRecipient(ctx context.Context, account *models.WhatsAppAccount, job *queue.RecipientJob, reserved bool) {
+	if !reserved {
+		return
+	}
+	if err := w.Throttle.ReleaseRecipient(context.WithoutCancel(ctx), account, job.PhoneNumber); err != nil {
+		w.Log.Error("Failed to release recipient cap slot", "error", err, "recipient_id", job.RecipientID)
+	}
+}
+
+// retryJob re-enqueues a job whose send failed with a transient error, using
+// exponential backoff. Returns true if the job was rescheduled. Once retries are
+// exhausted the job is dead-lettered and false is returned so the caller marks
+// the recipient failed. Sends that may have reached Meta, such as timeouts, are
+// never retried so the recipient doesn't get the message twice.
+func (w *Worker) retryJob(ctx context.Context, job *queue.RecipientJob, sendErr error) (bool, error) {
+	if !whatsapp.IsRetryable(sendErr) {
+		return false, nil
+	}
+	// Shutting down: leave the job un-acked so it is reclaimed, rather than failing it
+	if ctx.Err() != nil {
+		return true, ctx.Err()
+	}
+	if w.Queue == nil {
+		return false, nil
+	}
+
+	maxRetries, base, maxDelay := w.retryPolicy()
+	next := *job
+	next.Attempt++
+
+	if next.Attempt > maxRetries {
+		if err := w.Queue.DeadLetterRecipient(ctx, &next, sendErr.Error()); err != nil {
+			w.Log.Error("Failed to dead-letter job", "error", err, "recipient_id", job.RecipientID)
+		}
+		w.Log.Warn("Retries exhausted, job dead-lettered", "recipient_id", job.RecipientID, "attempts", next.Attempt)
+		return false, nil
+	}
+
+	delay := backoffDelay(next.Attempt, base, maxDelay)
+	if err := w.Queue.ScheduleRecipient(ctx, &next, time.Now().Add(delay)); err != nil {
+		w.Log.Error("Failed to schedule retry", "error", err, "recipient_id", job.RecipientID)
+		return true, err
+	}
+
+	w.DB.Model(&models.BulkMessageRecipient{}).Where("id = ?", job.RecipientID).
+		Update("error_message", fmt.Sprintf("Retry %d/%d scheduled: %s", next.Attempt, maxRetries, sendErr.Error()))
+
+	w.Log.Info("Transient send failure, retry scheduled", "recipient_id", job.RecipientID,
+		"attempt", next.Attempt, "delay", delay, "error", sendErr)
+	return true, nil
+}
+
+// retryPolicy returns the max retries and backoff bounds from config, with defaults
+func (w *Worker) retryPolicy() (maxRetries int, base, maxDelay time.Duration) {
+	maxRetries, base, maxDelay = 5, 30*time.Second, time.Hour
+	if w.Config != nil {
+		c := w.Config.Campaign
+		if c.MaxRetries != 0 {
+			maxRetries = max(c.MaxRetries, 0)
+		}
+		if c.RetryBaseSeconds > 0 {
+			base = time.Duration(c.RetryBaseSeconds) * time.Second
+		}
+		if c.RetryMaxSeconds > 0 {
+			maxDelay = time.Duration(c.RetryMaxSeconds) * time.Second
+		}
+	}
+	return maxRetries, base, maxDelay
+}
+
+// backoffDelay returns base * 2^(attempt-1), capped at maxDelay, plus up to 10%
+// jitter so retries from a rate-limited burst don't all land together.
+func backoffDelay(attempt int, base, maxDelay time.Duration) time.Duration {
+	delay := maxDelay
+	if attempt < 31 {
+		if d := base << (attempt - 1); d > 0 && d < maxDelay {
+			delay = d
+		}
+	}
+	return delay + time.Duration(rand.Int64N(int64(delay)/10+1))
+}
+
 // updateRecipientStatus updates the recipient's status in the database
 func (w *Worker) updateRecipientStatus(recipientID uuid.UUID, status models.MessageStatus, waMessageID, errorMsg string) {
 	updates := map[string]interface{}{
@@ -213,14 +384,26 @@ func (w *Worker) checkCampaignCompletion(ctx context.Context, campaignID, organi
 			return
 		}
 
+		// Conditional so that only one of several concurrent workers completes it
 		now := time.Now()
-		w.DB.Model(&campaign).Updates(map[string]interface{}{
-			"status":       models.CampaignStatusCompleted,
-			"completed_at": now,
-		})
+		result := w.DB.Model(&models.BulkMessageCampaign{}).
+			Where("id = ? AND status = ?", campaignID, models.CampaignStatusProcessing).
+			Updates(map[string]interface{}{
+				"status":       models.CampaignStatusCompleted,
+				"completed_at": now,
+			})
+		if result.Error != nil || result.RowsAffected == 0 {
+			return
+		}
+		campaign.Status = models.CampaignStatusCompleted
+		campaign.CompletedAt = &now
 
 		w.Log.Info("Campaign completed", "campaign_id", campaignID, "sent", campaign.SentCount, "failed", campaign.FailedCount)
 
+		if _, err := webhookutil.Dispatch(ctx, w.DB, w.Redis, organizationID, models.WebhookEventCampaignCompleted, webhookutil.NewCampaignEventData(&campaign)); err != nil {
+			w.Log.Error("Failed to dispatch campaign webhook", "campaign_id", campaignID, "error", err)
+		}
+
 		// Publish completion status
 		_ = w.Publisher.PublishCampaignStats(ctx, &queue.CampaignStatsUpdate{
 			CampaignID:     campaignID.String(),
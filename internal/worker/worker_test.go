@@ -6,6 +6,7 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/shridarpatil/whatomate/internal/config"
@@ -1013,3 +1014,164 @@ func TestReplaceTemplateContent_NoParams(t *testing.T) {
 
 	assert.Equal(t, "Hello, your order is ready!", result)
 }
+
+func TestBackoffDelay(t *testing.T) {
+	base, maxDelay := 30*time.Second, 10*time.Minute
+
+	tests := []struct {
+		attempt int
+		want    time.Duration
+	}{
+		{1, 30 * time.Second},
+		{2, time.Minute},
+		{3, 2 * time.Minute},
+		{5, 8 * time.Minute},
+		{6, 10 * time.Minute}, // capped
+		{64, 10 * time.Minute},
+	}
+
+	for _, tt := range tests {
+		got := backoffDelay(tt.attempt, base, maxDelay)
+		assert.GreaterOrEqual(t, got, tt.want, "attempt %d", tt.attempt)
+		assert.LessOrEqual(t, got, tt.want+tt.want/10, "attempt %d", tt.attempt)
+	}
+}
+
+func TestWorker_HandleRecipientJob_RetriesTransientError(t *testing.T) {
+	w := testWorker(t)
+	org, _, _, campaign, recipient := createTestCampaignData(t, w)
+
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		rw.WriteHeader(http.StatusBadRequest)
+		_ = json.NewEncoder(rw).Encode(map[string]interface{}{
+			"error": map[string]interface{}{"message": "Rate limit hit", "code": 130429},
+		})
+	}))
+	defer server.Close()
+
+	w.WhatsApp = whatsapp.NewWithBaseURL(w.Log, server.URL)
+	mockQueue := testutil.NewMockQueue()
+	w.Queue = mockQueue
+
+	job := &queue.RecipientJob{
+		CampaignID:     campaign.ID,
+		RecipientID:    recipient.ID,
+		OrganizationID: org.ID,
+		PhoneNumber:    recipient.PhoneNumber,
+		TemplateParams: recipient.TemplateParams,
+	}
+	require.NoError(t, w.HandleRecipientJob(context.Background(), job))
+
+	// Rescheduled with backoff rather than failed
+	require.Len(t, mockQueue.Scheduled, 1)
+	assert.Equal(t, 1, mockQueue.Scheduled[0].Job.Attempt)
+	assert.True(t, mockQueue.Scheduled[0].At.After(time.Now().Add(20*time.Second)))
+	assert.Empty(t, mockQueue.DeadLetters)
+
+	var updated models.BulkMessageRecipient
+	require.NoError(t, w.DB.First(&updated, recipient.ID).Error)
+	assert.Equal(t, models.MessageStatusPending, updated.Status)
+
+	var updatedCampaign models.BulkMessageCampaign
+	require.NoError(t, w.DB.First(&updatedCampaign, campaign.ID).Error)
+	assert.Equal(t, 0, updatedCampaign.FailedCount)
+}
+
+func TestWorker_HandleRecipientJob_DeadLettersExhaustedJob(t *testing.T) {
+	w := testWorker(t)
+	w.Config = &config.Config{Campaign: config.CampaignConfig{MaxRetries: 2}}
+	org, _, _, campaign, recipient := createTestCampaignData(t, w)
+
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		rw.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	w.WhatsApp = whatsapp.NewWithBaseURL(w.Log, server.URL)
+	mockQueue := testutil.NewMockQueue()
+	w.Queue = mockQueue
+
+	job := &queue.RecipientJob{
+		CampaignID:     campaign.ID,
+		RecipientID:    recipient.ID,
+		OrganizationID: org.ID,
+		PhoneNumber:    recipient.PhoneNumber,
+		TemplateParams: recipient.TemplateParams,
+		Attempt:        2,
+	}
+	require.NoError(t, w.HandleRecipientJob(context.Background(), job))
+
+	assert.Empty(t, mockQueue.Scheduled)
+	require.Len(t, mockQueue.DeadLetters, 1)
+	assert.Equal(t, 3, mockQueue.DeadLetters[0].Job.Attempt)
+
+	var updated models.BulkMessageRecipient
+	require.NoError(t, w.DB.First(&updated, recipient.ID).Error)
+	assert.Equal(t, models.MessageStatusFailed, updated.Status)
+}
+
+func TestWorker_HandleRecipientJob_PermanentErrorNotRetried(t *testing.T) {
+	w := testWorker(t)
+	org, _, _, campaign, recipient := createTestCampaignData(t, w)
+
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		rw.WriteHeader(http.StatusBadRequest)
+		_ = json.NewEncoder(rw).Encode(map[string]interface{}{
+			"error": map[string]interface{}{"message": "Template params mismatch", "code": 132000},
+		})
+	}))
+	defer server.Close()
+
+	w.WhatsApp = whatsapp.NewWithBaseURL(w.Log, server.URL)
+	mockQueue := testutil.NewMockQueue()
+	w.Queue = mockQueue
+
+	job := &queue.RecipientJob{
+		CampaignID:     campaign.ID,
+		RecipientID:    recipient.ID,
+		OrganizationID: org.ID,
+		PhoneNumber:    recipient.PhoneNumber,
+		TemplateParams: recipient.TemplateParams,
+	}
+	require.NoError(t, w.HandleRecipientJob(context.Background(), job))
+
+	assert.Empty(t, mockQueue.Scheduled)
+	assert.Empty(t, mockQueue.DeadLetters)
+
+	var updated models.BulkMessageRecipient
+	require.NoError(t, w.DB.First(&updated, recipient.ID).Error)
+	assert.Equal(t, models.MessageStatusFailed, updated.Status)
+}
+
+func TestWorker_HandleRecipientJob_TimeoutNotRetried(t *testing.T) {
+	w := testWorker(t)
+	org, _, _, campaign, recipient := createTestCampaignData(t, w)
+
+	// Meta may have accepted a message whose response never arrived
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		time.Sleep(200 * time.Millisecond)
+	}))
+	defer server.Close()
+
+	w.WhatsApp = whatsapp.NewWithBaseURL(w.Log, server.URL)
+	mockQueue := testutil.NewMockQueue()
+	w.Queue = mockQueue
+
+	job := &queue.RecipientJob{
+		CampaignID:     campaign.ID,
+		RecipientID:    recipient.ID,
+		OrganizationID: org.ID,
+		PhoneNumber:    recipient.PhoneNumber,
+		TemplateParams: recipient.TemplateParams,
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	require.NoError(t, w.HandleRecipientJob(ctx, job))
+
+	assert.Empty(t, mockQueue.Scheduled)
+	assert.Empty(t, mockQueue.DeadLetters)
+
+	var updated models.BulkMessageRecipient
+	require.NoError(t, w.DB.First(&updated, recipient.ID).Error)
+	assert.Equal(t, models.MessageStatusFailed, updated.Status)
+}
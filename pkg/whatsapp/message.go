@@ -229,13 +229,24 @@ type TemplateParam struct {
 // BodyParamsToComponents converts a bodyParams map into WhatsApp template components.
 // Supports both positional (numeric keys) and named parameters.
 func BodyParamsToComponents(bodyParams map[string]string) []map[string]interface{} {
-	if len(bodyParams) == 0 {
+	return textParamsToComponents("body", bodyParams)
+}
+
+// HeaderParamsToComponents converts the parameters of a TEXT header into
+// WhatsApp template components. Supports both positional and named parameters.
+func HeaderParamsToComponents(headerParams map[string]string) []map[string]interface{} {
+	return textParamsToComponents("header", headerParams)
+}
+
+// textParamsToComponents builds a component of the given type from text parameters
+func textParamsToComponents(componentType string, textParams map[string]string) []map[string]interface{} {
+	if len(textParams) == 0 {
 		return nil
 	}
 
 	// Check if using named parameters (non-numeric keys like "name", "order_id")
 	isNamedParams := false
-	for key := range bodyParams {
+	for key := range textParams {
 		if _, err := strconv.Atoi(key); err != nil {
 			isNamedParams = true
 			break
@@ -243,17 +254,17 @@ func BodyParamsToComponents(bodyParams map[string]string) []map[string]interface
 	}
 
 	// Get sorted keys for deterministic ordering
-	keys := make([]string, 0, len(bodyParams))
-	for k := range bodyParams {
+	keys := make([]string, 0, len(textParams))
+	for k := range textParams {
 		keys = append(keys, k)
 	}
 	sort.Strings(keys)
 
-	params := make([]map[string]interface{}, 0, len(bodyParams))
+	params := make([]map[string]interface{}, 0, len(textParams))
 	for _, key := range keys {
 		param := map[string]interface{}{
 			"type": "text",
-			"text": bodyParams[key],
+			"text": textParams[key],
 		}
 		if isNamedParams {
 			param["parameter_name"] = key
@@ -263,12 +274,53 @@ func BodyParamsToComponents(bodyParams map[string]string) []map[string]interface
 
 	return []map[string]interface{}{
 		{
-			"type":       "body",
+			"type":       componentType,
 			"parameters": params,
 		},
 	}
 }
 
+// Template button sub-types that accept parameters
+const (
+	ButtonSubTypeQuickReply = "quick_reply"
+	ButtonSubTypeURL        = "url"
+)
+
+// TemplateButtonParam is the parameter of a template button: the payload
+// returned when a quick reply is tapped, or the dynamic suffix of a URL button
+type TemplateButtonParam struct {
+	Index   int    // Position of the button in the template
+	SubType string // ButtonSubTypeQuickReply or ButtonSubTypeURL
+	Value   string
+}
+
+// ButtonParamsToComponents converts button parameters into WhatsApp template
+// components, one per button, ordered by button index
+func ButtonParamsToComponents(buttonParams []TemplateButtonParam) []map[string]interface{} {
+	if len(buttonParams) == 0 {
+		return nil
+	}
+
+	sorted := make([]TemplateButtonParam, len(buttonParams))
+	copy(sorted, buttonParams)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Index < sorted[j].Index })
+
+	components := make([]map[string]interface{}, 0, len(sorted))
+	for _, bp := range sorted {
+		param := map[string]interface{}{"type": "text", "text": bp.Value}
+		if bp.SubType == ButtonSubTypeQuickReply {
+			param = map[string]interface{}{"type": "payload", "payload": bp.Value}
+		}
+		components = append(components, map[string]interface{}{
+			"type":       "button",
+			"sub_type":   bp.SubType,
+			"index":      strconv.Itoa(bp.Index),
+			"parameters": []map[string]interface{}{param},
+		})
+	}
+	return components
+}
+
 // SendFlowMessage sends an interactive WhatsApp Flow message
 // flowID is the Meta Flow ID, headerText is optional header, bodyText is the message body,
 // ctaText is the button text, flowToken is a unique token for tracking the flow response,
@@ -462,3 +462,29 @@ func TestClient_SendTemplateMessage_WithComponents(t *testing.T) {
 	assert.Len(t, sentComponents, 2)
 }
 
+
+func TestHeaderAndButtonParamsToComponents(t *testing.T) {
+	t.Parallel()
+
+	header := whatsapp.HeaderParamsToComponents(map[string]string{"1": "Order 42"})
+	require.Len(t, header, 1)
+	assert.Equal(t, "header", header[0]["type"])
+	params := header[0]["parameters"].([]map[string]interface{})
+	require.Len(t, params, 1)
+	assert.Equal(t, "Order 42", params[0]["text"])
+	assert.NotContains(t, params[0], "parameter_name")
+
+	assert.Nil(t, whatsapp.ButtonParamsToComponents(nil))
+
+	buttons := whatsapp.ButtonParamsToComponents([]whatsapp.TemplateButtonParam{
+		{Index: 2, SubType: whatsapp.ButtonSubTypeURL, Value: "abc123"},
+		{Index: 0, SubType: whatsapp.ButtonSubTypeQuickReply, Value: "confirm"},
+	})
+	require.Len(t, buttons, 2)
+	assert.Equal(t, "quick_reply", buttons[0]["sub_type"])
+	assert.Equal(t, "0", buttons[0]["index"])
+	assert.Equal(t, []map[string]interface{}{{"type": "payload", "payload": "confirm"}}, buttons[0]["parameters"])
+	assert.Equal(t, "url", buttons[1]["sub_type"])
+	assert.Equal(t, "2", buttons[1]["index"])
+	assert.Equal(t, []map[string]interface{}{{"type": "text", "text": "abc123"}}, buttons[1]["parameters"])
+}
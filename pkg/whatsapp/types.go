@@ -2,7 +2,10 @@ package whatsapp
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
+	"net"
+	"net/http"
 	"time"
 )
 
@@ -45,22 +48,100 @@ type MetaAPIError struct {
 	} `json:"error"`
 }
 
-// ParseError attempts to parse respBody as a Meta API error. If successful,
-// it returns a formatted error including code, message, details, and user message.
-// If parsing fails, it returns a generic error with the status code and raw body.
+// APIError is a non-200 response from the Meta API. Retryable reports whether
+// the same request may succeed if sent again later (rate limits, outages).
+type APIError struct {
+	StatusCode int
+	Code       int
+	Subcode    int
+	Message    string
+	Retryable  bool
+	text       string
+}
+
+func (e *APIError) Error() string {
+	return e.text
+}
+
+// retryableErrorCodes are Meta error codes for throttling and temporary
+// outages. See https://developers.facebook.com/docs/whatsapp/cloud-api/support/error-codes
+var retryableErrorCodes = map[int]bool{
+	1:      true, // API Unknown
+	2:      true, // API Service
+	4:      true, // API Too Many Calls
+	17:     true, // API User Too Many Calls
+	341:    true, // Application limit reached
+	80007:  true, // Rate limit issues
+	130429: true, // Rate limit hit (throughput)
+	131000: true, // Something went wrong
+	131016: true, // Service unavailable
+	131056: true, // Pair rate limit hit
+	133004: true, // Server temporarily unavailable
+}
+
+// ParseMetaAPIError parses respBody as a Meta API error and returns an *APIError
+// whose message includes code, message, details, and user message. If parsing
+// fails, the message carries the status code and raw body. 429 and 5xx responses,
+// known throttling/outage codes and errors Meta flags as transient are retryable.
 func ParseMetaAPIError(statusCode int, respBody []byte) error {
-	var apiErr MetaAPIError
-	if err := json.Unmarshal(respBody, &apiErr); err == nil && apiErr.Error.Message != "" {
-		errMsg := fmt.Sprintf("API error %d: %s", apiErr.Error.Code, apiErr.Error.Message)
-		if apiErr.Error.ErrorData.Details != "" {
-			errMsg += " - Details: " + apiErr.Error.ErrorData.Details
+	apiErr := &APIError{
+		StatusCode: statusCode,
+		Retryable:  statusCode == http.StatusTooManyRequests || statusCode >= 500,
+	}
+
+	var metaErr MetaAPIError
+	if err := json.Unmarshal(respBody, &metaErr); err == nil && metaErr.Error.Message != "" {
+		var transient struct {
+			Error struct {
+				IsTransient bool `json:"is_transient"`
+			} `json:"error"`
 		}
-		if apiErr.Error.ErrorUserMsg != "" {
-			errMsg += " - " + apiErr.Error.ErrorUserMsg
+		_ = json.Unmarshal(respBody, &transient)
+
+		apiErr.Code = metaErr.Error.Code
+		apiErr.Subcode = metaErr.Error.ErrorSubcode
+		apiErr.Message = metaErr.Error.Message
+		if retryableErrorCodes[metaErr.Error.Code] || transient.Error.IsTransient {
+			apiErr.Retryable = true
 		}
-		return fmt.Errorf("%s", errMsg)
+
+		errMsg := fmt.Sprintf("API error %d: %s", metaErr.Error.Code, metaErr.Error.Message)
+		if metaErr.Error.ErrorData.Details != "" {
+			errMsg += " - Details: " + metaErr.Error.ErrorData.Details
+		}
+		if metaErr.Error.ErrorUserMsg != "" {
+			errMsg += " - " + metaErr.Error.ErrorUserMsg
+		}
+		apiErr.text = errMsg
+		return apiErr
+	}
+
+	apiErr.Message = string(respBody)
+	apiErr.text = fmt.Sprintf("API returned status %d: %s", statusCode, string(respBody))
+	return apiErr
+}
+
+// IsRetryable reports whether err from a Meta API call is transient and the
+// request is known not to have been carried out: a retryable *APIError, or a
+// connection that could not be made. Timeouts and other network errors are not
+// retryable, since Meta may have received the request and sending it again
+// could deliver the same message twice.
+func IsRetryable(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.Retryable
+	}
+
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) {
+		return true
 	}
-	return fmt.Errorf("API returned status %d: %s", statusCode, string(respBody))
+	var opErr *net.OpError
+	return errors.As(err, &opErr) && opErr.Op == "dial"
 }
 
 // TemplateResponse represents response from template submission
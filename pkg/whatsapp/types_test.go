@@ -0,0 +1,95 @@
+package whatsapp_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/shridarpatil/whatomate/pkg/whatsapp"
+	"github.com/shridarpatil/whatomate/test/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseMetaAPIError_Classification(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name      string
+		status    int
+		body      string
+		retryable bool
+		code      int
+	}{
+		{"rate limit code", 400, `{"error":{"message":"Rate limit hit","code":130429}}`, true, 130429},
+		{"http 429", 429, `{"error":{"message":"Too many calls","code":80008}}`, true, 80008},
+		{"server error", 503, `upstream unavailable`, true, 0},
+		{"transient flag", 400, `{"error":{"message":"Try again","code":999,"is_transient":true}}`, true, 999},
+		{"invalid recipient", 400, `{"error":{"message":"Recipient not on WhatsApp","code":131026}}`, false, 131026},
+		{"bad template params", 400, `{"error":{"message":"Param mismatch","code":132000}}`, false, 132000},
+		{"auth failure", 401, `{"error":{"message":"Invalid OAuth token","code":190}}`, false, 190},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := whatsapp.ParseMetaAPIError(tt.status, []byte(tt.body))
+
+			var apiErr *whatsapp.APIError
+			require.True(t, errors.As(err, &apiErr))
+			assert.Equal(t, tt.status, apiErr.StatusCode)
+			assert.Equal(t, tt.code, apiErr.Code)
+			assert.Equal(t, tt.retryable, apiErr.Retryable)
+			assert.Equal(t, tt.retryable, whatsapp.IsRetryable(fmt.Errorf("wrapped: %w", err)))
+		})
+	}
+}
+
+func TestParseMetaAPIError_Message(t *testing.T) {
+	t.Parallel()
+
+	err := whatsapp.ParseMetaAPIError(400, []byte(`{"error":{"message":"Invalid parameter","code":100,"error_user_msg":"Check the number","error_data":{"details":"bad phone"}}}`))
+	assert.Equal(t, "API error 100: Invalid parameter - Details: bad phone - Check the number", err.Error())
+
+	err = whatsapp.ParseMetaAPIError(502, []byte("bad gateway"))
+	assert.Equal(t, "API returned status 502: bad gateway", err.Error())
+}
+
+func TestIsRetryable_TransportErrors(t *testing.T) {
+	t.Parallel()
+
+	assert.False(t, whatsapp.IsRetryable(nil))
+	assert.False(t, whatsapp.IsRetryable(errors.New("failed to marshal request body")))
+	assert.False(t, whatsapp.IsRetryable(fmt.Errorf("request failed: %w", context.Canceled)))
+	assert.False(t, whatsapp.IsRetryable(fmt.Errorf("request failed: %w", context.DeadlineExceeded)))
+
+	// Connection refused surfaces as a net.Error from the HTTP client
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	url := server.URL
+	server.Close()
+
+	client := whatsapp.NewWithBaseURL(testutil.NopLogger(), url)
+	_, err := client.SendTemplateMessage(context.Background(), testAccount(url), "1234567890", "hello", "en", nil)
+	require.Error(t, err)
+	assert.True(t, whatsapp.IsRetryable(err))
+}
+
+func TestIsRetryable_Timeout(t *testing.T) {
+	t.Parallel()
+
+	// Meta may have received a request that timed out, so it isn't sent again
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(200 * time.Millisecond)
+	}))
+	defer server.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	client := whatsapp.NewWithBaseURL(testutil.NopLogger(), server.URL)
+	_, err := client.SendTemplateMessage(ctx, testAccount(server.URL), "1234567890", "hello", "en", nil)
+	require.Error(t, err)
+	assert.False(t, whatsapp.IsRetryable(err))
+}
@@ -90,6 +90,7 @@ func runMigrations(db *gorm.DB) error {
 		&models.APIKey{},
 		&models.SSOProvider{},
 		&models.Webhook{},
+		&models.WebhookDelivery{},
 		&models.CustomAction{},
 		&models.UserAvailabilityLog{},
 		// WhatsApp models
@@ -104,21 +105,34 @@ func runMigrations(db *gorm.DB) error {
 		&models.KeywordRule{},
 		&models.ChatbotFlow{},
 		&models.ChatbotFlowStep{},
+		&models.ChatbotFlowVersion{},
 		&models.ChatbotSession{},
 		&models.ChatbotSessionMessage{},
 		&models.AIContext{},
 		&models.AgentTransfer{},
+		&models.BusinessHoursSchedule{},
+		&models.SLAPolicy{},
+		&models.AgentSkill{},
 		// Bulk message models
 		&models.BulkMessageCampaign{},
 		&models.BulkMessageRecipient{},
 		&models.NotificationRule{},
+		&models.NotificationRuleExecution{},
 		// Catalog models
 		&models.Catalog{},
 		&models.CatalogProduct{},
 		// Canned responses
 		&models.CannedResponse{},
+		// Knowledge base
+		&models.KnowledgeDocument{},
+		&models.KnowledgeChunk{},
 		// Dashboard
 		&models.Widget{},
+		// Conversations
+		&models.Conversation{},
+		&models.ConversationNote{},
+		&models.ConversationReadCursor{},
+		&models.ConsentEvent{},
 	)
 }
 
@@ -128,24 +142,37 @@ func cleanupTables(db *gorm.DB) {
 	tables := []string{
 		// Dashboard tables
 		"widgets",
+		// Conversation tables
+		"conversations",
+		"conversation_read_cursors",
+		"consent_events",
+		"conversation_notes",
 		// Catalog tables
 		"catalog_products",
 		"catalogs",
 		// Canned responses
 		"canned_responses",
+		// Knowledge base
+		"knowledge_chunks",
+		"knowledge_documents",
 		// Bulk message tables
 		"bulk_message_recipients",
 		"bulk_message_campaigns",
+		"notification_rule_executions",
 		"notification_rules",
 		// Chatbot tables
 		"chatbot_session_messages",
 		"chatbot_sessions",
+		"chatbot_flow_versions",
 		"chatbot_flow_steps",
 		"chatbot_flows",
 		"keyword_rules",
 		"chatbot_settings",
 		"ai_contexts",
 		"agent_transfers",
+		"business_hours_schedules",
+		"sla_policies",
+		"agent_skills",
 		// WhatsApp tables
 		"messages",
 		"tags",
@@ -162,6 +189,7 @@ func cleanupTables(db *gorm.DB) {
 		"teams",
 		"api_keys",
 		"sso_providers",
+		"webhook_deliveries",
 		"webhooks",
 		"custom_actions",
 		"user_availability_logs",
@@ -179,20 +207,31 @@ func cleanupTables(db *gorm.DB) {
 func TruncateTables(db *gorm.DB) {
 	tables := []string{
 		"widgets",
+		"conversations",
+		"conversation_read_cursors",
+		"consent_events",
+		"conversation_notes",
 		"catalog_products",
 		"catalogs",
 		"canned_responses",
+		"knowledge_chunks",
+		"knowledge_documents",
 		"bulk_message_recipients",
 		"bulk_message_campaigns",
+		"notification_rule_executions",
 		"notification_rules",
 		"chatbot_session_messages",
 		"chatbot_sessions",
+		"chatbot_flow_versions",
 		"chatbot_flow_steps",
 		"chatbot_flows",
 		"keyword_rules",
 		"chatbot_settings",
 		"ai_contexts",
 		"agent_transfers",
+		"business_hours_schedules",
+		"sla_policies",
+		"agent_skills",
 		"messages",
 		"tags",
 		"contacts",
@@ -206,6 +245,7 @@ func TruncateTables(db *gorm.DB) {
 		"teams",
 		"api_keys",
 		"sso_providers",
+		"webhook_deliveries",
 		"webhooks",
 		"custom_actions",
 		"user_availability_logs",
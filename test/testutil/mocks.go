@@ -2,7 +2,9 @@ package testutil
 
 import (
 	"context"
+	"fmt"
 	"sync"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/shridarpatil/whatomate/internal/queue"
@@ -255,10 +257,20 @@ func (m *MockWhatsAppClient) GetMessagesSentTo(phone string) []MockSentMessage {
 	return messages
 }
 
+// ScheduledJob is a deferred job recorded by MockQueue.
+type ScheduledJob struct {
+	Job *queue.RecipientJob
+	At  time.Time
+}
+
 // MockQueue is a mock implementation of queue.Queue.
 type MockQueue struct {
-	mu   sync.Mutex
-	Jobs []*queue.RecipientJob
+	mu        sync.Mutex
+	Jobs      []*queue.RecipientJob
+	Scheduled []ScheduledJob
+
+	DeadLetters   []queue.DeadLetter
+	deadLetterSeq int
 
 	// Configurable behavior
 	EnqueueFunc  func(ctx context.Context, job *queue.RecipientJob) error
@@ -309,6 +321,101 @@ func (m *MockQueue) EnqueueRecipients(ctx context.Context, jobs []*queue.Recipie
 	return nil
 }
 
+// ScheduleRecipient mocks deferring a job; the job and its due time are recorded.
+func (m *MockQueue) ScheduleRecipient(ctx context.Context, job *queue.RecipientJob, at time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.Error != nil {
+		return m.Error
+	}
+
+	m.Scheduled = append(m.Scheduled, ScheduledJob{Job: job, At: at})
+	return nil
+}
+
+// DeadLetterRecipient mocks dead-lettering a job; entries get sequential IDs.
+func (m *MockQueue) DeadLetterRecipient(ctx context.Context, job *queue.RecipientJob, reason string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.Error != nil {
+		return m.Error
+	}
+
+	m.deadLetterSeq++
+	m.DeadLetters = append(m.DeadLetters, queue.DeadLetter{
+		ID:       fmt.Sprintf("%d-0", m.deadLetterSeq),
+		Job:      job,
+		Error:    reason,
+		FailedAt: time.Now(),
+	})
+	return nil
+}
+
+// ListDeadLetters returns the mock's dead letters for a campaign.
+func (m *MockQueue) ListDeadLetters(ctx context.Context, campaignID uuid.UUID, offset, limit int) ([]queue.DeadLetter, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.Error != nil {
+		return nil, m.Error
+	}
+
+	var letters []queue.DeadLetter
+	skipped := 0
+	for _, dl := range m.DeadLetters {
+		if dl.Job.CampaignID != campaignID {
+			continue
+		}
+		if skipped < offset {
+			skipped++
+			continue
+		}
+		if len(letters) < limit {
+			letters = append(letters, dl)
+		}
+	}
+	return letters, nil
+}
+
+// CountDeadLetters returns the number of the mock's dead letters for a campaign.
+func (m *MockQueue) CountDeadLetters(ctx context.Context, campaignID uuid.UUID) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.Error != nil {
+		return 0, m.Error
+	}
+
+	var n int64
+	for _, dl := range m.DeadLetters {
+		if dl.Job.CampaignID == campaignID {
+			n++
+		}
+	}
+	return n, nil
+}
+
+// RemoveDeadLetters removes dead letters by ID from the mock.
+func (m *MockQueue) RemoveDeadLetters(ctx context.Context, campaignID uuid.UUID, ids ...string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	remove := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		remove[id] = true
+	}
+	kept := m.DeadLetters[:0]
+	for _, dl := range m.DeadLetters {
+		if !(dl.Job.CampaignID == campaignID && remove[dl.ID]) {
+			kept = append(kept, dl)
+		}
+	}
+	m.DeadLetters = kept
+	return nil
+}
+
 // Close is a no-op for the mock.
 func (m *MockQueue) Close() error {
 	return nil